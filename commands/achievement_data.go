@@ -0,0 +1,32 @@
+package commands
+
+import "luna/storage"
+
+// 実績の進捗として数えるカウンター
+const (
+	counterBlackjackNatural = "blackjack_natural" // ブラックジャック (最初の2枚で21) で勝った回数
+	counterBlackjackWins    = "blackjack_wins"    // ブラックジャックで勝った手札の数
+	counterSlotsSpins       = "slots_spins"       // スロットを回した回数
+	counterSlotsJackpots    = "slots_jackpots"    // ジャックポットを当てた回数
+	counterFishCaught       = "fish_caught"       // 釣った魚の数
+	counterFishLegendary    = "fish_legendary"    // レジェンダリーを釣った数
+	counterHorseRaceWins    = "horse_race_wins"   // 競馬で的中した回数
+	counterCoinflipWins     = "coinflip_wins"     // コインフリップで勝った回数
+	counterChipsWon         = "chips_won"         // ゲームの配当で受け取ったチップの合計
+)
+
+// achievements は、すべての実績の定義です。/achievements ではこの順に表示されます。
+var achievements = []storage.Achievement{
+	{ID: "first_blackjack", Name: "ナチュラル", Description: "ブラックジャックで初めてナチュラル21を出して勝つ", Emoji: "🃏", Counter: counterBlackjackNatural, Goal: 1, Reward: 500},
+	{ID: "blackjack_veteran", Name: "カードカウンター", Description: "ブラックジャックで50回勝つ", Emoji: "♠️", Counter: counterBlackjackWins, Goal: 50, Reward: 2000},
+	{ID: "first_spin", Name: "はじめてのスロット", Description: "スロットを初めて回す", Emoji: "🎰", Counter: counterSlotsSpins, Goal: 1, Reward: 50},
+	{ID: "slots_regular", Name: "スロット常連", Description: "スロットを500回回す", Emoji: "🎡", Counter: counterSlotsSpins, Goal: 500, Reward: 3000},
+	{ID: "jackpot", Name: "ジャックポット！", Description: "スロットでジャックポットを当てる", Emoji: "👑", Counter: counterSlotsJackpots, Goal: 1, Reward: 5000},
+	{ID: "first_catch", Name: "はじめての釣果", Description: "魚を初めて釣り上げる", Emoji: "🎣", Counter: counterFishCaught, Goal: 1, Reward: 50},
+	{ID: "angler", Name: "釣り名人", Description: "魚を100匹釣り上げる", Emoji: "🐟", Counter: counterFishCaught, Goal: 100, Reward: 1000},
+	{ID: "legendary_catch", Name: "伝説の釣り人", Description: "宝箱などのレジェンダリーを釣り上げる", Emoji: "💎", Counter: counterFishLegendary, Goal: 1, Reward: 2000},
+	{ID: "first_horse_win", Name: "本命的中", Description: "競馬で初めて勝ち馬を当てる", Emoji: "🏇", Counter: counterHorseRaceWins, Goal: 1, Reward: 300},
+	{ID: "horse_whisperer", Name: "馬を見る目", Description: "競馬で10回勝ち馬を当てる", Emoji: "🐎", Counter: counterHorseRaceWins, Goal: 10, Reward: 2000},
+	{ID: "coin_master", Name: "表か裏か", Description: "コインフリップで25回勝つ", Emoji: "🪙", Counter: counterCoinflipWins, Goal: 25, Reward: 500},
+	{ID: "high_roller", Name: "ハイローラー", Description: "ゲームの配当で累計100,000チップを獲得する", Emoji: "💰", Counter: counterChipsWon, Goal: 100000, Reward: 5000},
+}
@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"fmt"
+	"luna/interfaces"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// AchievementsCommand handles the /achievements command.
+type AchievementsCommand struct {
+	Store interfaces.DataStore
+	Log   interfaces.Logger
+}
+
+func (c *AchievementsCommand) GetCommandDef() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "achievements",
+		Description: "獲得した実績とバッジを表示します。",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user",
+				Description: "実績を表示するユーザー (デフォルト: 自分)",
+			},
+		},
+	}
+}
+
+func (c *AchievementsCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	user := i.Member.User
+	if options := i.ApplicationCommandData().Options; len(options) > 0 {
+		user = options[0].UserValue(s)
+	}
+
+	progress, err := c.Store.GetUserAchievements(i.GuildID, user.ID, achievements)
+	if err != nil {
+		c.Log.Error("Failed to get achievements", "error", err)
+		sendErrorResponse(s, i, "実績の取得中にエラーが発生しました。")
+		return
+	}
+
+	var badges []string
+	var unlocked, locked strings.Builder
+	for _, a := range progress {
+		if a.Unlocked() {
+			badges = append(badges, a.Emoji)
+			fmt.Fprintf(&unlocked, "%s **%s** — %s (<t:%d:d>)\n", a.Emoji, a.Name, a.Description, a.UnlockedAt.Time.Unix())
+			continue
+		}
+		fmt.Fprintf(&locked, "🔒 **%s** — %s `%d/%d` (報酬: %d チップ)\n", a.Name, a.Description, a.Progress, a.Goal, a.Reward)
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("🏆 %s の実績", user.Username),
+		Description: fmt.Sprintf("解除済み: **%d / %d**", len(badges), len(progress)),
+		Color:       0xffd700, // Gold
+		Thumbnail:   &discordgo.MessageEmbedThumbnail{URL: user.AvatarURL("")},
+	}
+	if len(badges) > 0 {
+		embed.Description += "\nバッジ: " + strings.Join(badges, " ")
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "✅ 解除済み", Value: unlocked.String()})
+	}
+	if locked.Len() > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "未解除", Value: locked.String()})
+	}
+	sendEmbedResponse(s, i, embed)
+}
+
+// recordAchievements は、ゲームの結果を実績の進捗に加算し、新しく解除された実績を channelID に発表します。
+// 実績の記録に失敗してもゲームの結果には影響させないため、エラーはログに残すだけにします。
+func recordAchievements(s *discordgo.Session, store interfaces.DataStore, log interfaces.Logger, channelID, guildID, userID string, progress map[string]int64) {
+	unlocked, err := store.RecordAchievementProgress(guildID, userID, progress, achievements)
+	if err != nil {
+		log.Error("Failed to record achievement progress", "error", err, "userID", userID)
+		return
+	}
+	for _, a := range unlocked {
+		embed := &discordgo.MessageEmbed{
+			Title:       "🏆 実績解除！",
+			Description: fmt.Sprintf("<@%s> が実績 %s **%s** を解除しました！\n%s", userID, a.Emoji, a.Name, a.Description),
+			Color:       0xffd700, // Gold
+		}
+		if a.Reward > 0 {
+			embed.Footer = &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("報酬: %d チップ", a.Reward)}
+		}
+		if _, err := s.ChannelMessageSendEmbed(channelID, embed); err != nil {
+			log.Warn("Failed to announce achievement", "error", err, "channelID", channelID)
+		}
+	}
+}
+
+func (c *AchievementsCommand) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {}
+func (c *AchievementsCommand) HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate)     {}
+func (c *AchievementsCommand) GetComponentIDs() []string                                            { return []string{} }
+func (c *AchievementsCommand) GetCategory() string                                                  { return "カジノ" }
@@ -45,13 +45,31 @@ func (c *BalanceCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCr
 		return
 	}
 
-		embed := &discordgo.MessageEmbed{
+	account, err := c.Store.GetBankAccount(i.GuildID, targetUser.ID)
+	if err != nil {
+		c.Log.Error("Failed to get bank account for balance command", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。後でもう一度お試しください。")
+		return
+	}
+
+	embed := &discordgo.MessageEmbed{
 		Title:       fmt.Sprintf("💰 %s のチップ残高", targetUser.Username),
 		Description: fmt.Sprintf("現在のチップ: **%d**\n🐸 **PepeCoin (PPC)**: `%d`", casinoData.Chips, casinoData.PepeCoinBalance),
 		Color:       0x3498db, // Blue
 		Thumbnail: &discordgo.MessageEmbedThumbnail{
 			URL: targetUser.AvatarURL(""),
 		},
+		Fields: bankAccountFields(account),
+	}
+
+	// 取引履歴は本人の残高を表示したときだけ載せる
+	if targetUser.ID == i.Member.User.ID {
+		history, err := c.Store.GetTransactions(i.GuildID, targetUser.ID, 5)
+		if err != nil {
+			c.Log.Error("Failed to get transactions for balance command", "error", err)
+		} else if len(history) > 0 {
+			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "📒 最近の取引", Value: formatTransactions(history)})
+		}
 	}
 	sendEmbedResponse(s, i, embed)
 }
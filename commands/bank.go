@@ -0,0 +1,279 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"luna/interfaces"
+	"luna/storage"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// BankCommand handles the /bank command.
+type BankCommand struct {
+	Store interfaces.DataStore
+	Log   interfaces.Logger
+}
+
+// NewBankCommand creates a new BankCommand.
+func NewBankCommand(store interfaces.DataStore, log interfaces.Logger) *BankCommand {
+	return &BankCommand{Store: store, Log: log}
+}
+
+func (c *BankCommand) GetCommandDef() *discordgo.ApplicationCommand {
+	amountOption := func(description string, required bool) *discordgo.ApplicationCommandOption {
+		return &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        "amount",
+			Description: description,
+			Required:    required,
+			MinValue:    &[]float64{1}[0],
+		}
+	}
+	return &discordgo.ApplicationCommand{
+		Name:        "bank",
+		Description: "銀行でチップを預けたり、借りたりします。",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "deposit",
+				Description: "チップを預金します。預金には毎日利息が付き、ゲームで失うことはありません。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options:     []*discordgo.ApplicationCommandOption{amountOption("預けるチップの額", true)},
+			},
+			{
+				Name:        "withdraw",
+				Description: "預金からチップを引き出します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options:     []*discordgo.ApplicationCommandOption{amountOption("引き出すチップの額", true)},
+			},
+			{
+				Name:        "loan",
+				Description: "与信枠の範囲内でチップを借ります。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options:     []*discordgo.ApplicationCommandOption{amountOption("借りるチップの額", true)},
+			},
+			{
+				Name:        "repay",
+				Description: "ローンを返済します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options:     []*discordgo.ApplicationCommandOption{amountOption("返済する額 (省略すると全額)", false)},
+			},
+			{
+				Name:        "history",
+				Description: "取引履歴を表示します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+		},
+	}
+}
+
+func (c *BankCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	subcommand := i.ApplicationCommandData().Options[0]
+	var amount int64
+	if len(subcommand.Options) > 0 {
+		amount = subcommand.Options[0].IntValue()
+	}
+
+	switch subcommand.Name {
+	case "deposit":
+		c.handleDeposit(s, i, amount)
+	case "withdraw":
+		c.handleWithdraw(s, i, amount)
+	case "loan":
+		c.handleLoan(s, i, amount)
+	case "repay":
+		c.handleRepay(s, i, amount)
+	case "history":
+		c.handleHistory(s, i)
+	}
+}
+
+func (c *BankCommand) handleDeposit(s *discordgo.Session, i *discordgo.InteractionCreate, amount int64) {
+	if err := c.Store.BankDeposit(i.GuildID, i.Member.User.ID, amount); err != nil {
+		if errors.Is(err, storage.ErrInsufficientFunds) {
+			sendErrorResponse(s, i, "チップが足りません。")
+			return
+		}
+		c.Log.Error("Failed to deposit chips", "error", err)
+		sendErrorResponse(s, i, "預金中にエラーが発生しました。")
+		return
+	}
+	c.sendAccountEmbed(s, i, "🏦 預金しました", fmt.Sprintf("**%d** チップを預金しました。", amount))
+}
+
+func (c *BankCommand) handleWithdraw(s *discordgo.Session, i *discordgo.InteractionCreate, amount int64) {
+	if err := c.Store.BankWithdraw(i.GuildID, i.Member.User.ID, amount); err != nil {
+		if errors.Is(err, storage.ErrInsufficientFunds) {
+			sendErrorResponse(s, i, "預金残高が足りません。")
+			return
+		}
+		c.Log.Error("Failed to withdraw chips", "error", err)
+		sendErrorResponse(s, i, "引き出し中にエラーが発生しました。")
+		return
+	}
+	c.sendAccountEmbed(s, i, "🏦 引き出しました", fmt.Sprintf("**%d** チップを引き出しました。", amount))
+}
+
+func (c *BankCommand) handleLoan(s *discordgo.Session, i *discordgo.InteractionCreate, amount int64) {
+	loan, err := c.Store.TakeLoan(i.GuildID, i.Member.User.ID, amount)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrLoanExists):
+			sendErrorResponse(s, i, "返済中のローンがあります。先に `/bank repay` で返済してください。")
+		case errors.Is(err, storage.ErrCreditLimit):
+			sendErrorResponse(s, i, "与信枠を超えています。`/balance` で現在の与信枠を確認してください。")
+		default:
+			c.Log.Error("Failed to take loan", "error", err)
+			sendErrorResponse(s, i, "借入中にエラーが発生しました。")
+		}
+		return
+	}
+	c.sendAccountEmbed(s, i, "💳 借入しました",
+		fmt.Sprintf("**%d** チップを借りました。\n<t:%d:f> までに **%d** チップを返済してください。\n期限を過ぎると、ゲームの勝ち分や配当などの入金から自動的に回収されます。",
+			loan.Principal, loan.DueAt.Unix(), loan.AmountDue))
+}
+
+func (c *BankCommand) handleRepay(s *discordgo.Session, i *discordgo.InteractionCreate, amount int64) {
+	before, err := c.Store.GetBankAccount(i.GuildID, i.Member.User.ID)
+	if err != nil {
+		c.Log.Error("Failed to get bank account", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。後でもう一度お試しください。")
+		return
+	}
+
+	loan, err := c.Store.RepayLoan(i.GuildID, i.Member.User.ID, amount)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNoLoan):
+			sendErrorResponse(s, i, "返済中のローンはありません。")
+		case errors.Is(err, storage.ErrInsufficientFunds):
+			sendErrorResponse(s, i, "チップが足りません。")
+		default:
+			c.Log.Error("Failed to repay loan", "error", err)
+			sendErrorResponse(s, i, "返済中にエラーが発生しました。")
+		}
+		return
+	}
+
+	paid := loan.Repaid
+	if before.Loan != nil {
+		paid -= before.Loan.Repaid
+	}
+	message := fmt.Sprintf("**%d** チップを返済しました。残り **%d** チップです。", paid, loan.Outstanding())
+	if loan.Status == storage.LoanStatusRepaid {
+		message = fmt.Sprintf("**%d** チップを返済し、ローンを完済しました！", paid)
+	}
+	c.sendAccountEmbed(s, i, "💳 返済しました", message)
+}
+
+// sendAccountEmbed は、操作結果と最新の口座の状況を表示します。
+func (c *BankCommand) sendAccountEmbed(s *discordgo.Session, i *discordgo.InteractionCreate, title, description string) {
+	embed := &discordgo.MessageEmbed{
+		Title:       title,
+		Description: description,
+		Color:       0x2ecc71, // Green
+	}
+	if account, err := c.Store.GetBankAccount(i.GuildID, i.Member.User.ID); err == nil {
+		embed.Fields = bankAccountFields(account)
+	} else {
+		c.Log.Error("Failed to get bank account", "error", err)
+	}
+	sendEmbedResponse(s, i, embed)
+}
+
+func (c *BankCommand) handleHistory(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	history, err := c.Store.GetTransactions(i.GuildID, i.Member.User.ID, 15)
+	if err != nil {
+		c.Log.Error("Failed to get transactions", "error", err)
+		sendErrorResponse(s, i, "取引履歴の取得に失敗しました。")
+		return
+	}
+	description := "取引履歴はまだありません。"
+	if len(history) > 0 {
+		description = formatTransactions(history)
+	}
+	embed := &discordgo.MessageEmbed{
+		Title:       "📒 取引履歴",
+		Description: description,
+		Color:       0x3498db, // Blue
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// PayInterest は、預金の利息を支払います。スケジューラから1日1回呼び出されます。
+func (c *BankCommand) PayInterest() {
+	if err := c.Store.PayBankInterest(); err != nil {
+		c.Log.Error("Failed to pay bank interest", "error", err)
+	}
+}
+
+// bankAccountFields は、預金・ローン・与信枠を埋め込みのフィールドにします。
+func bankAccountFields(account *storage.BankAccount) []*discordgo.MessageEmbedField {
+	loan := "なし"
+	if account.Loan != nil {
+		loan = fmt.Sprintf("残り `%d` チップ\n期限 <t:%d:R>", account.Loan.Outstanding(), account.Loan.DueAt.Unix())
+		if account.Loan.Overdue(time.Now()) {
+			loan += "\n⚠️ **延滞中** (入金から自動回収)"
+		}
+	}
+	return []*discordgo.MessageEmbedField{
+		{Name: "🏦 預金", Value: fmt.Sprintf("`%d` チップ", account.Balance), Inline: true},
+		{Name: "💳 ローン", Value: loan, Inline: true},
+		{Name: "📈 与信枠", Value: fmt.Sprintf("`%d` チップ", account.CreditLimit), Inline: true},
+	}
+}
+
+// transactionLabel は、取引の種類の表示名を返します。
+func transactionLabel(kind string) string {
+	switch kind {
+	case storage.TxBankDeposit:
+		return "預金"
+	case storage.TxBankWithdraw:
+		return "引き出し"
+	case storage.TxBankInterest:
+		return "預金利息"
+	case storage.TxLoanIssued:
+		return "借入"
+	case storage.TxLoanRepayment:
+		return "ローン返済"
+	case storage.TxLoanCollection:
+		return "延滞ローンの自動回収"
+	case storage.TxAdminAdjust:
+		return "管理者による調整"
+	case storage.TxExchange:
+		return "両替"
+	case storage.TxLotteryTicket:
+		return "宝くじ購入"
+	case storage.TxLotteryWin:
+		return "宝くじ当選"
+	case storage.TxAchievementReward:
+		return "実績の報酬"
+	}
+	return kind
+}
+
+// formatTransactions は、取引履歴を1件1行の文字列にします。
+func formatTransactions(history []storage.Transaction) string {
+	var sb strings.Builder
+	for _, t := range history {
+		fmt.Fprintf(&sb, "<t:%d:d> %s `%+d` %s", t.CreatedAt.Unix(), transactionLabel(t.Kind), t.Amount, currencyLabel(t.Currency))
+		if t.Note != "" {
+			fmt.Fprintf(&sb, " (%s)", t.Note)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func (c *BankCommand) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {}
+func (c *BankCommand) HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate)     {}
+func (c *BankCommand) GetComponentIDs() []string                                            { return []string{} }
+func (c *BankCommand) GetCategory() string                                                  { return "カジノ" }
@@ -2,7 +2,9 @@ package commands
 
 import (
 	"fmt"
+	"luna/fair"
 	"luna/interfaces"
+	"luna/storage"
 	"math/rand"
 	"strconv"
 	"strings"
@@ -14,12 +16,14 @@ import (
 
 // --- Constants ---
 const (
-	BlackjackHitButton       = "bj_hit"
-	BlackjackStandButton     = "bj_stand"
-	BlackjackDoubleDownButton  = "bj_double_down"
-	BlackjackSplitButton     = "bj_split"
-	BlackjackInsuranceButton = "bj_insurance"
-	BlackjackSurrenderButton = "bj_surrender"
+	BlackjackHitButton        = "bj_hit"
+	BlackjackStandButton      = "bj_stand"
+	BlackjackDoubleDownButton = "bj_double_down"
+	BlackjackSplitButton      = "bj_split"
+	BlackjackInsuranceButton  = "bj_insurance"
+	BlackjackSurrenderButton  = "bj_surrender"
+	BlackjackHintButton       = "bj_hint"
+	BlackjackIdleTimeout      = 5 * time.Minute
 )
 
 // --- Data Structures ---
@@ -39,39 +43,50 @@ const (
 	BJStateFinished
 )
 
+// BlackjackHand は、プレイヤーの1つの手札です。スプリットするたびに手札が増えます。
+type BlackjackHand struct {
+	Cards    []Card
+	Bet      int64
+	Doubled  bool
+	FromAces bool // エースのスプリットでできた手札 (1枚しか引けない)
+	Done     bool
+}
+
 // BlackjackGame holds the state of a single game.
 type BlackjackGame struct {
 	State         BlackjackGameState
 	PlayerID      string
 	Interaction   *discordgo.Interaction
+	Rules         *storage.BlackjackRules
 	Deck          []Card
-	PlayerHand    []Card
-	PlayerHand2   []Card // For split
+	Hands         []*BlackjackHand
 	DealerHand    []Card
-	BetAmount     int64
-	BetAmount2    int64 // For split
 	InsuranceBet  int64
-	CurrentHand   int // 1 or 2, for split
-	CanDoubleDown bool
-	CanSplit      bool
-	CanSurrender  bool
-	rand          *rand.Rand // Game-specific random source
+	CurrentHand   int   // 行動中の手札の番号 (0から)
+	Peeked        bool  // ディーラーがブラックジャックかを確認したか
+	SideBet       int64 // サイドベットの合計
+	SideBetPayout int64
+	SideBetResult string
+	Round         *fair.Round // 山札を決めた乱数と検証用の情報
+	timer         *time.Timer // しばらく操作がなければ残りの手札をスタンドする
 }
 
 // BlackjackCommand handles the /blackjack command.
 type BlackjackCommand struct {
 	Store interfaces.DataStore
 	Log   interfaces.Logger
+	Fair  *fair.Service
 	games map[string]*BlackjackGame // userID -> game
 	mu    sync.Mutex
 }
 
 // --- Constructor ---
 
-func NewBlackjackCommand(store interfaces.DataStore, log interfaces.Logger) *BlackjackCommand {
+func NewBlackjackCommand(store interfaces.DataStore, log interfaces.Logger, fairRNG *fair.Service) *BlackjackCommand {
 	return &BlackjackCommand{
 		Store: store,
 		Log:   log,
+		Fair:  fairRNG,
 		games: make(map[string]*BlackjackGame),
 	}
 }
@@ -90,10 +105,32 @@ func (c *BlackjackCommand) GetCommandDef() *discordgo.ApplicationCommand {
 				Required:    true,
 				MinValue:    &[]float64{1}[0],
 			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "perfect_pairs",
+				Description: "サイドベット: 最初の2枚がペアになるかに賭けるチップの額",
+				MinValue:    &[]float64{1}[0],
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "twenty_one_plus_three",
+				Description: "サイドベット: 最初の2枚とディーラーの表向きのカードの役 (21+3) に賭けるチップの額",
+				MinValue:    &[]float64{1}[0],
+			},
 		},
 	}
 }
 
+// loadBlackjackRules は、既定値を補ったサーバーのブラックジャックのルールを返します。
+func loadBlackjackRules(store interfaces.DataStore, guildID string) (*storage.BlackjackRules, error) {
+	var rules storage.BlackjackRules
+	if err := store.GetConfig(guildID, "blackjack_rules", &rules); err != nil {
+		return nil, err
+	}
+	rules.ApplyDefaults()
+	return &rules, nil
+}
+
 // --- Handlers ---
 
 func (c *BlackjackCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
@@ -108,7 +145,33 @@ func (c *BlackjackCommand) Handle(s *discordgo.Session, i *discordgo.Interaction
 	}
 	c.mu.Unlock()
 
-	betAmount := i.ApplicationCommandData().Options[0].IntValue()
+	var betAmount, perfectPairsBet, twentyOnePlusThreeBet int64
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "bet":
+			betAmount = opt.IntValue()
+		case "perfect_pairs":
+			perfectPairsBet = opt.IntValue()
+		case "twenty_one_plus_three":
+			twentyOnePlusThreeBet = opt.IntValue()
+		}
+	}
+
+	rules, err := loadBlackjackRules(c.Store, i.GuildID)
+	if err != nil {
+		c.Log.Error("Failed to get blackjack rules", "error", err)
+		sendBlackjackErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+	sideBet := perfectPairsBet + twentyOnePlusThreeBet
+	if sideBet > 0 && rules.NoSideBets {
+		sendBlackjackErrorResponse(s, i, "このサーバーではサイドベットは無効になっています。")
+		return
+	}
+	totalBet := betAmount + sideBet
+	if rejectGamblingBet(s, i, c.Store, c.Log, totalBet) {
+		return
+	}
 
 	// Check user's balance
 	casinoData, err := c.Store.GetCasinoData(i.GuildID, userID)
@@ -117,49 +180,64 @@ func (c *BlackjackCommand) Handle(s *discordgo.Session, i *discordgo.Interaction
 		sendBlackjackErrorResponse(s, i, "エラーが発生しました。")
 		return
 	}
-	if casinoData.Chips < betAmount {
+	if casinoData.Chips < totalBet {
 		sendBlackjackErrorResponse(s, i, fmt.Sprintf("チップが足りません！現在の所持チップ: %d", casinoData.Chips))
 		return
 	}
 
+	round, err := c.Fair.NewRound(i.GuildID, userID)
+	if err != nil {
+		c.Log.Error("Failed to start fair round for blackjack", "error", err)
+		sendBlackjackErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+
 	// Deduct bet amount
-	casinoData.Chips -= betAmount
+	casinoData.Chips -= totalBet
 	if err := c.Store.UpdateCasinoData(casinoData); err != nil {
 		c.Log.Error("Failed to update casino data on bet", "error", err)
+		round.Close()
 		sendBlackjackErrorResponse(s, i, "ベット処理中にエラーが発生しました。")
 		return
 	}
+	pendingBets.add(i.GuildID, userID, storage.GameBlackjack, totalBet)
 
 	// Create a new game
-	gameRand := rand.New(rand.NewSource(time.Now().UnixNano()))
-	deck := NewDeck()
-	ShuffleDeck(deck, gameRand)
+	deck := NewDeck(rules.Decks)
+	ShuffleDeck(deck, round.Rand)
 
+	hand := &BlackjackHand{Cards: make([]Card, 0, 5), Bet: betAmount}
 	game := &BlackjackGame{
-		State:         BJStatePlayerTurn,
-		PlayerID:      userID,
-		Interaction:   i.Interaction,
-		Deck:          deck,
-		PlayerHand:    make([]Card, 0, 5),
-		DealerHand:    make([]Card, 0, 5),
-		BetAmount:     betAmount,
-		CurrentHand:   1,
-		rand:          gameRand,
+		State:       BJStatePlayerTurn,
+		PlayerID:    userID,
+		Interaction: i.Interaction,
+		Rules:       rules,
+		Deck:        deck,
+		Hands:       []*BlackjackHand{hand},
+		DealerHand:  make([]Card, 0, 5),
+		SideBet:     sideBet,
+		Round:       round,
 	}
 
 	// Deal initial cards
-	dealCard(game, &game.PlayerHand)
+	dealCard(game, &hand.Cards)
 	dealCard(game, &game.DealerHand)
-	dealCard(game, &game.PlayerHand)
+	dealCard(game, &hand.Cards)
 	dealCard(game, &game.DealerHand)
 
-	// Check for split and double down options
-	game.CanSplit = len(game.PlayerHand) == 2 && game.PlayerHand[0].Rank == game.PlayerHand[1].Rank && casinoData.Chips >= betAmount
-	playerValue, _ := CalculateHandValue(game.PlayerHand)
-	game.CanDoubleDown = len(game.PlayerHand) == 2 && (playerValue == 9 || playerValue == 10 || playerValue == 11) && casinoData.Chips >= betAmount
-	game.CanSurrender = len(game.PlayerHand) == 2
+	// サイドベットは最初の配札で決まるため、すぐに精算する
+	if sideBet > 0 {
+		game.SideBetPayout, game.SideBetResult = settleSideBets(hand.Cards, game.upCard(), perfectPairsBet, twentyOnePlusThreeBet)
+		if game.SideBetPayout > 0 {
+			casinoData.Chips += game.SideBetPayout
+			if err := c.Store.UpdateCasinoData(casinoData); err != nil {
+				c.Log.Error("Failed to pay out blackjack side bets", "error", err)
+			}
+		}
+	}
 
 	c.mu.Lock()
+	game.timer = time.AfterFunc(BlackjackIdleTimeout, func() { c.timeout(s, game) })
 	c.games[userID] = game
 	c.mu.Unlock()
 
@@ -172,30 +250,29 @@ func (c *BlackjackCommand) Handle(s *discordgo.Session, i *discordgo.Interaction
 		Data: &discordgo.InteractionResponseData{
 			Embeds:     []*discordgo.MessageEmbed{embed},
 			Components: components,
-			// Flags:      discordgo.MessageFlagsEphemeral, // Removed this line to make it public
 		},
 	})
 	if err != nil {
 		c.Log.Error("Failed to send blackjack initial message", "error", err)
 		// Rollback bet if initial message fails
-		casinoData.Chips += betAmount
+		casinoData.Chips += totalBet - game.SideBetPayout
 		c.Store.UpdateCasinoData(casinoData)
+		pendingBets.release(i.GuildID, userID, storage.GameBlackjack, totalBet)
+		c.mu.Lock()
+		game.timer.Stop()
+		delete(c.games, userID)
+		c.mu.Unlock()
+		round.Close()
 		return
 	}
 
-	// Check for insurance option or initial blackjacks
-	dealerUpCardIsAce := game.DealerHand[1].Rank == "A"
-	_, playerBlackjack := CalculateHandValue(game.PlayerHand)
-	_, dealerBlackjack := CalculateHandValue(game.DealerHand)
-
-	if !dealerUpCardIsAce && (playerBlackjack || dealerBlackjack) {
-		// If no insurance is offered and someone has blackjack, end the game immediately.
-		time.AfterFunc(1*time.Second, func() {
-			c.determineWinner(s, game)
-		})
-	} else if dealerUpCardIsAce {
-		// If dealer has an Ace up, the game continues to allow for insurance bets.
-		// If the player also has blackjack, it's an even money situation, but we handle that in determineWinner.
+	// ディーラーの表向きのカードがエースの場合はインシュランスの判断を待ってから確認する
+	if game.upCard().Rank != "A" {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if !c.peek(s, game) {
+			c.checkNatural(s, game)
+		}
 	}
 }
 
@@ -209,8 +286,6 @@ func (c *BlackjackCommand) HandleComponent(s *discordgo.Session, i *discordgo.In
 
 	// If the game doesn't exist for this user, it might be another user's game.
 	if !exists {
-		// Check if this interaction is for ANY active game by checking the original message author.
-		// This is a bit complex, so for now, we will just send an ephemeral error.
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
@@ -221,10 +296,25 @@ func (c *BlackjackCommand) HandleComponent(s *discordgo.Session, i *discordgo.In
 		return
 	}
 
+	customID := i.MessageComponentData().CustomID
+	if customID == BlackjackHintButton {
+		c.handleHint(s, i, game)
+		return
+	}
+
 	// Defer the response to avoid timeout
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate})
 
-	customID := i.MessageComponentData().CustomID
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if game.State != BJStatePlayerTurn {
+		return
+	}
+	game.timer.Reset(BlackjackIdleTimeout)
+	// インシュランス以外の操作は、インシュランスを断ったものとしてディーラーの手札を確認してから行う
+	if customID != BlackjackInsuranceButton && c.peek(s, game) {
+		return
+	}
 
 	switch customID {
 	case BlackjackHitButton:
@@ -242,294 +332,126 @@ func (c *BlackjackCommand) HandleComponent(s *discordgo.Session, i *discordgo.In
 	}
 }
 
-func (c *BlackjackCommand) handleHit(s *discordgo.Session, game *BlackjackGame) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if game.State != BJStatePlayerTurn {
-		return
-	}
-
-	// Disable special moves after hitting. This applies to the current hand.
-	game.CanDoubleDown = false
-	game.CanSplit = false
-	game.CanSurrender = false
-
-	// Determine which hand to hit
-	hand := &game.PlayerHand
-	if game.CurrentHand == 2 {
-		hand = &game.PlayerHand2
+// peek は、ディーラーがブラックジャックかを確認します。ブラックジャックでゲームが終わった場合は true を返します。
+// 呼び出し側で c.mu を保持してください。
+func (c *BlackjackCommand) peek(s *discordgo.Session, game *BlackjackGame) bool {
+	if game.Peeked {
+		return false
 	}
-
-	// Deal a new card
-	dealCard(game, hand)
-
-	playerValue, _ := CalculateHandValue(*hand)
-
-	// Check for bust
-	if playerValue > 21 {
-		// If it was the first hand of a split, move to the next hand
-		if game.CurrentHand == 1 && len(game.PlayerHand2) > 0 {
-			game.CurrentHand = 2
-			// Update message and return
-			embed := c.buildGameEmbed(game, "あなたのターン (2つ目の手)")
-			components := c.buildGameComponents(game)
-			_, err := s.InteractionResponseEdit(game.Interaction, &discordgo.WebhookEdit{
-				Embeds:     &[]*discordgo.MessageEmbed{embed},
-				Components: &components,
-			})
-			if err != nil {
-				c.Log.Error("Failed to edit blackjack message on split bust", "error", err)
-			}
-			return
-		} else {
-			// If not a split or it was the second hand, end the game
-			time.AfterFunc(1*time.Second, func() {
-				c.determineWinner(s, game)
-			})
-			return // Return to prevent updating the message twice
-		}
+	game.Peeked = true
+	if _, dealerBlackjack := CalculateHandValue(game.DealerHand); !dealerBlackjack {
+		return false
 	}
+	game.State = BJStateDealerTurn
+	time.AfterFunc(1*time.Second, func() {
+		c.determineWinner(s, game)
+	})
+	return true
+}
 
-	// Update message
-	title := "あなたのターン"
-	if len(game.PlayerHand2) > 0 {
-		title = fmt.Sprintf("あなたのターン (%dつ目の手)", game.CurrentHand)
+// checkNatural は、プレイヤーが最初の2枚でブラックジャックならゲームを終わらせます。
+// 呼び出し側で c.mu を保持してください。
+func (c *BlackjackCommand) checkNatural(s *discordgo.Session, game *BlackjackGame) bool {
+	if _, natural := CalculateHandValue(game.Hands[0].Cards); !natural || len(game.Hands) > 1 {
+		return false
 	}
-	embed := c.buildGameEmbed(game, title)
-	components := c.buildGameComponents(game)
-	_, err := s.InteractionResponseEdit(game.Interaction, &discordgo.WebhookEdit{
-		Embeds:     &[]*discordgo.MessageEmbed{embed},
-		Components: &components,
+	game.State = BJStateDealerTurn
+	time.AfterFunc(1*time.Second, func() {
+		c.determineWinner(s, game)
 	})
-	if err != nil {
-		c.Log.Error("Failed to edit blackjack message on hit", "error", err)
-	}
+	return true
 }
 
-func (c *BlackjackCommand) handleStand(s *discordgo.Session, game *BlackjackGame) {
-	c.mu.Lock()
-
-	if game.State != BJStatePlayerTurn {
-		c.mu.Unlock()
+func (c *BlackjackCommand) handleHit(s *discordgo.Session, game *BlackjackGame) {
+	hand := game.hand()
+	if hand.FromAces {
 		return
 	}
-
-	// If it's the first hand of a split, move to the second hand
-	if game.CurrentHand == 1 && len(game.PlayerHand2) > 0 {
-		game.CurrentHand = 2
-		c.mu.Unlock()
-
-		// Update the UI for the second hand
-		embed := c.buildGameEmbed(game, "あなたのターン (2つ目の手)")
-		components := c.buildGameComponents(game)
-		_, err := s.InteractionResponseEdit(game.Interaction, &discordgo.WebhookEdit{
-			Embeds:     &[]*discordgo.MessageEmbed{embed},
-			Components: &components,
-		})
-		if err != nil {
-			c.Log.Error("Failed to edit blackjack message on split stand", "error", err)
-		}
-		return
+	dealCard(game, &hand.Cards)
+	if value, _ := CalculateHandValue(hand.Cards); value >= 21 {
+		hand.Done = true
 	}
+	c.advance(s, game, "")
+}
 
-	// If not a split or it's the second hand, proceed to the dealer's turn
-	game.State = BJStateDealerTurn
-	c.mu.Unlock()
-
-	// Reveal dealer's hand and start their turn
-	embed := c.buildGameEmbed(game, "ディーラーのターン")
-	components := c.buildGameComponents(game) // Disable buttons
-	_, err := s.InteractionResponseEdit(game.Interaction, &discordgo.WebhookEdit{
-		Embeds:     &[]*discordgo.MessageEmbed{embed},
-		Components: &components,
-	})
-	if err != nil {
-		c.Log.Error("Failed to edit blackjack message on stand", "error", err)
+func (c *BlackjackCommand) handleStand(s *discordgo.Session, game *BlackjackGame) {
+	if c.checkNatural(s, game) {
+		return
 	}
-
-	// Dealer plays
-	go func() {
-		time.Sleep(1 * time.Second)
-		dealerValue, _ := CalculateHandValue(game.DealerHand)
-		for dealerValue < 17 {
-			time.Sleep(1 * time.Second)
-			c.mu.Lock()
-			if game.State == BJStateFinished { // Check if game ended while sleeping
-				c.mu.Unlock()
-				return
-			}
-			dealCard(game, &game.DealerHand)
-			dealerValue, _ = CalculateHandValue(game.DealerHand)
-			embed := c.buildGameEmbed(game, "ディーラーのターン")
-			_, err := s.InteractionResponseEdit(game.Interaction, &discordgo.WebhookEdit{
-				Embeds: &[]*discordgo.MessageEmbed{embed},
-			})
-			if err != nil {
-				c.Log.Error("Failed to edit blackjack message on dealer hit", "error", err)
-			}
-			c.mu.Unlock()
-		}
-
-		time.Sleep(1 * time.Second)
-		c.determineWinner(s, game)
-	}()
+	game.hand().Done = true
+	c.advance(s, game, "")
 }
 
 func (c *BlackjackCommand) handleDoubleDown(s *discordgo.Session, game *BlackjackGame) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if game.State != BJStatePlayerTurn || !game.CanDoubleDown {
+	if !game.canDoubleDown() {
 		return
 	}
-
-	// Determine which hand and bet to double
-	hand := &game.PlayerHand
-	betAmount := game.BetAmount
-	if game.CurrentHand == 2 {
-		hand = &game.PlayerHand2
-		betAmount = game.BetAmount2
-	}
+	hand := game.hand()
 
 	// Double the bet
+	if c.exceedsGamblingLimits(game, hand.Bet) {
+		return
+	}
 	casinoData, err := c.Store.GetCasinoData(game.Interaction.GuildID, game.PlayerID)
-	if err != nil || casinoData.Chips < betAmount {
+	if err != nil || casinoData.Chips < hand.Bet {
 		// Not enough chips, can't double down. Silently ignore.
 		return
 	}
-	casinoData.Chips -= betAmount
+	casinoData.Chips -= hand.Bet
 	c.Store.UpdateCasinoData(casinoData)
-
-	if game.CurrentHand == 1 {
-		game.BetAmount *= 2
-	} else {
-		game.BetAmount2 *= 2
-	}
-
-	// Deal one more card
-	dealCard(game, hand)
-
-	// After doubling, the turn for this hand ends. Move to the next, or to the dealer.
-	game.CanDoubleDown = false
-
-	// Check for bust immediately after doubling
-	playerValue, _ := CalculateHandValue(*hand)
-	if playerValue > 21 {
-		// If it was the first hand of a split, just move to the next hand
-		if game.CurrentHand == 1 && len(game.PlayerHand2) > 0 {
-			game.CurrentHand = 2
-			embed := c.buildGameEmbed(game, "手札1はバスト！あなたのターン (2つ目の手)")
-			components := c.buildGameComponents(game)
-			_, err = s.InteractionResponseEdit(game.Interaction, &discordgo.WebhookEdit{
-				Embeds:     &[]*discordgo.MessageEmbed{embed},
-				Components: &components,
-			})
-			if err != nil {
-				c.Log.Error("Failed to edit message on double down bust", "error", err)
-			}
-			return // End the function here
-		} else {
-			// If it wasn't a split, or was the second hand, the game ends
-			go func() {
-				time.Sleep(1 * time.Second)
-				c.determineWinner(s, game)
-			}()
-			return
-		}
-	}
-
-	if game.CurrentHand == 1 && len(game.PlayerHand2) > 0 {
-		game.CurrentHand = 2
-		// Update UI for the second hand
-		embed := c.buildGameEmbed(game, "ダブルダウン！あなたのターン (2つ目の手)")
-		components := c.buildGameComponents(game)
-		_, err = s.InteractionResponseEdit(game.Interaction, &discordgo.WebhookEdit{
-			Embeds:     &[]*discordgo.MessageEmbed{embed},
-			Components: &components,
-		})
-		if err != nil {
-			c.Log.Error("Failed to edit message on double down split", "error", err)
-		}
-		return
-	}
-
-	// End player's turn
-	game.State = BJStateDealerTurn
-
-	// Update UI and start dealer's turn after a delay
-	embed := c.buildGameEmbed(game, "ダブルダウン！ディーラーのターン")
-	components := c.buildGameComponents(game)
-	_, err = s.InteractionResponseEdit(game.Interaction, &discordgo.WebhookEdit{
-		Embeds:     &[]*discordgo.MessageEmbed{embed},
-		Components: &components,
-	})
-	if err != nil {
-		c.Log.Error("Failed to edit message on double down", "error", err)
-	}
-
-	go func() {
-		time.Sleep(2 * time.Second)
-		c.determineWinner(s, game)
-	}()
+	pendingBets.add(game.Interaction.GuildID, game.PlayerID, storage.GameBlackjack, hand.Bet)
+	hand.Bet *= 2
+	hand.Doubled = true
+
+	// After doubling, the turn for this hand ends with exactly one more card.
+	dealCard(game, &hand.Cards)
+	hand.Done = true
+	c.advance(s, game, "ダブルダウン！")
 }
 
 func (c *BlackjackCommand) handleSplit(s *discordgo.Session, game *BlackjackGame) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if game.State != BJStatePlayerTurn || !game.CanSplit {
+	if !game.canSplit() {
 		return
 	}
+	hand := game.hand()
 
+	if c.exceedsGamblingLimits(game, hand.Bet) {
+		return
+	}
 	// Check if user has enough chips to split
 	casinoData, err := c.Store.GetCasinoData(game.Interaction.GuildID, game.PlayerID)
-	if err != nil || casinoData.Chips < game.BetAmount {
+	if err != nil || casinoData.Chips < hand.Bet {
 		// Not enough chips, can't split. Silently ignore.
 		return
 	}
-	casinoData.Chips -= game.BetAmount
+	casinoData.Chips -= hand.Bet
 	c.Store.UpdateCasinoData(casinoData)
+	pendingBets.add(game.Interaction.GuildID, game.PlayerID, storage.GameBlackjack, hand.Bet)
 
-	// Split the hand
-	game.PlayerHand2 = []Card{game.PlayerHand[1]}
-	game.PlayerHand = []Card{game.PlayerHand[0]}
-	game.BetAmount2 = game.BetAmount
-
-	// Deal a new card to each hand
-	dealCard(game, &game.PlayerHand)
-	dealCard(game, &game.PlayerHand2)
-
-	// Re-evaluate double down possibility for each new hand
-	playerValue1, _ := CalculateHandValue(game.PlayerHand)
-	game.CanDoubleDown = (playerValue1 == 9 || playerValue1 == 10 || playerValue1 == 11) && casinoData.Chips >= game.BetAmount
-	// Note: A more complex implementation would allow doubling down on the second hand later.
-	// For simplicity, we only check the first hand's double down possibility initially.
+	// Split the hand and deal a new card to each
+	aces := hand.Cards[0].Rank == "A"
+	newHand := &BlackjackHand{Cards: []Card{hand.Cards[1]}, Bet: hand.Bet, FromAces: aces}
+	hand.Cards = []Card{hand.Cards[0]}
+	hand.FromAces = aces
+	dealCard(game, &hand.Cards)
+	dealCard(game, &newHand.Cards)
 
-	// Disable further splitting
-	game.CanSplit = false
+	game.Hands = append(game.Hands, nil)
+	copy(game.Hands[game.CurrentHand+2:], game.Hands[game.CurrentHand+1:])
+	game.Hands[game.CurrentHand+1] = newHand
 
-	// Update UI
-	embed := c.buildGameEmbed(game, "スプリット！あなたのターン (1つ目の手)")
-	components := c.buildGameComponents(game)
-	_, err = s.InteractionResponseEdit(game.Interaction, &discordgo.WebhookEdit{
-		Embeds:     &[]*discordgo.MessageEmbed{embed},
-		Components: &components,
-	})
-	if err != nil {
-		c.Log.Error("Failed to edit message on split", "error", err)
-	}
+	c.advance(s, game, "スプリット！")
 }
 
 func (c *BlackjackCommand) handleInsurance(s *discordgo.Session, game *BlackjackGame) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if game.State != BJStatePlayerTurn || game.DealerHand[0].Rank != "A" || game.InsuranceBet > 0 {
+	if !game.canInsure() {
 		return
 	}
 
-	insuranceAmount := game.BetAmount / 2
+	insuranceAmount := game.Hands[0].Bet / 2
+	if c.exceedsGamblingLimits(game, insuranceAmount) {
+		return
+	}
 	casinoData, err := c.Store.GetCasinoData(game.Interaction.GuildID, game.PlayerID)
 	if err != nil || casinoData.Chips < insuranceAmount {
 		// Not enough chips for insurance. Silently ignore.
@@ -538,40 +460,26 @@ func (c *BlackjackCommand) handleInsurance(s *discordgo.Session, game *Blackjack
 
 	casinoData.Chips -= insuranceAmount
 	c.Store.UpdateCasinoData(casinoData)
+	pendingBets.add(game.Interaction.GuildID, game.PlayerID, storage.GameBlackjack, insuranceAmount)
 	game.InsuranceBet = insuranceAmount
 
-	// Update UI to show insurance was taken
-	embed := c.buildGameEmbed(game, "インシュランスを受け付けました。あなたのターン")
-	components := c.buildGameComponents(game)
-	_, err = s.InteractionResponseEdit(game.Interaction, &discordgo.WebhookEdit{
-		Embeds:     &[]*discordgo.MessageEmbed{embed},
-		Components: &components,
-	})
-	if err != nil {
-		c.Log.Error("Failed to edit message on insurance", "error", err)
-	}
-
 	// Check if dealer has blackjack immediately
-	_, dealerBlackjack := CalculateHandValue(game.DealerHand)
-	if dealerBlackjack {
-		time.AfterFunc(1*time.Second, func() {
-			c.determineWinner(s, game)
-		})
+	if c.peek(s, game) || c.checkNatural(s, game) {
+		return
 	}
+	c.advance(s, game, "インシュランスを受け付けました。")
 }
 
 func (c *BlackjackCommand) handleSurrender(s *discordgo.Session, game *BlackjackGame) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if game.State != BJStatePlayerTurn || !game.CanSurrender {
+	if !game.canSurrender() {
 		return
 	}
 
 	game.State = BJStateFinished
 
 	// Refund half of the bet
-	refund := game.BetAmount / 2
+	hand := game.Hands[0]
+	refund := hand.Bet / 2
 	casinoData, err := c.Store.GetCasinoData(game.Interaction.GuildID, game.PlayerID)
 	if err == nil {
 		casinoData.Chips += refund
@@ -594,7 +502,137 @@ func (c *BlackjackCommand) handleSurrender(s *discordgo.Session, game *Blackjack
 		c.Log.Error("Failed to edit message on surrender", "error", err)
 	}
 
+	game.timer.Stop()
 	delete(c.games, game.PlayerID)
+	game.Round.Close()
+	recordGameRound(c.Store, c.Log, game.Interaction.GuildID, game.PlayerID, storage.GameBlackjack,
+		hand.Bet+game.InsuranceBet+game.SideBet, refund+game.SideBetPayout)
+}
+
+// handleHint は、現在の手札に対する基本戦略のおすすめを本人にだけ表示します。
+func (c *BlackjackCommand) handleHint(s *discordgo.Session, i *discordgo.InteractionCreate, game *BlackjackGame) {
+	c.mu.Lock()
+	if game.State != BJStatePlayerTurn {
+		c.mu.Unlock()
+		sendBlackjackErrorResponse(s, i, "今はヒントを表示できません。")
+		return
+	}
+	action, reason := basicStrategy(game)
+	c.mu.Unlock()
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("💡 基本戦略のおすすめ: **%s**\n%s", action, reason),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// timeout は、しばらく操作がなかったゲームの残りの手札をすべてスタンドし、ディーラーのターンに進めて精算します。
+// インシュランスの判断を待っている場合は、断ったものとして扱います。
+func (c *BlackjackCommand) timeout(s *discordgo.Session, game *BlackjackGame) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if game.State != BJStatePlayerTurn {
+		return
+	}
+	if c.peek(s, game) || c.checkNatural(s, game) {
+		return
+	}
+	for _, hand := range game.Hands {
+		hand.Done = true
+	}
+	c.advance(s, game, "時間切れ！")
+}
+
+// advance は、終わった手札を飛ばして次の手札に進みます。すべての手札が終わればディーラーのターンに移ります。
+// 呼び出し側で c.mu を保持してください。
+func (c *BlackjackCommand) advance(s *discordgo.Session, game *BlackjackGame, prefix string) {
+	for game.CurrentHand < len(game.Hands) {
+		hand := game.hand()
+		value, _ := CalculateHandValue(hand.Cards)
+		// エースのスプリットでできた手札は、さらにスプリットできなければ1枚で終わる
+		if value >= 21 || (hand.FromAces && !game.canSplit()) {
+			hand.Done = true
+		}
+		if !hand.Done {
+			break
+		}
+		game.CurrentHand++
+	}
+
+	if game.CurrentHand < len(game.Hands) {
+		title := prefix + "あなたのターン"
+		if len(game.Hands) > 1 {
+			title = fmt.Sprintf("%sあなたのターン (%dつ目の手)", prefix, game.CurrentHand+1)
+		}
+		embed := c.buildGameEmbed(game, title)
+		components := c.buildGameComponents(game)
+		_, err := s.InteractionResponseEdit(game.Interaction, &discordgo.WebhookEdit{
+			Embeds:     &[]*discordgo.MessageEmbed{embed},
+			Components: &components,
+		})
+		if err != nil {
+			c.Log.Error("Failed to edit blackjack message", "error", err)
+		}
+		return
+	}
+	game.CurrentHand = len(game.Hands) - 1
+
+	// すべての手札がバストしていればディーラーは引かない
+	allBusted := true
+	for _, hand := range game.Hands {
+		if value, _ := CalculateHandValue(hand.Cards); value <= 21 {
+			allBusted = false
+		}
+	}
+	game.State = BJStateDealerTurn
+	if allBusted {
+		time.AfterFunc(1*time.Second, func() {
+			c.determineWinner(s, game)
+		})
+		return
+	}
+
+	// Reveal dealer's hand and start their turn
+	embed := c.buildGameEmbed(game, prefix+"ディーラーのターン")
+	components := c.buildGameComponents(game) // Disable buttons
+	_, err := s.InteractionResponseEdit(game.Interaction, &discordgo.WebhookEdit{
+		Embeds:     &[]*discordgo.MessageEmbed{embed},
+		Components: &components,
+	})
+	if err != nil {
+		c.Log.Error("Failed to edit blackjack message on stand", "error", err)
+	}
+
+	// Dealer plays
+	go func() {
+		time.Sleep(1 * time.Second)
+		for {
+			c.mu.Lock()
+			if game.State == BJStateFinished { // Check if game ended while sleeping
+				c.mu.Unlock()
+				return
+			}
+			if !dealerShouldHit(game.DealerHand, game.Rules) {
+				c.mu.Unlock()
+				break
+			}
+			dealCard(game, &game.DealerHand)
+			embed := c.buildGameEmbed(game, "ディーラーのターン")
+			_, err := s.InteractionResponseEdit(game.Interaction, &discordgo.WebhookEdit{
+				Embeds: &[]*discordgo.MessageEmbed{embed},
+			})
+			if err != nil {
+				c.Log.Error("Failed to edit blackjack message on dealer hit", "error", err)
+			}
+			c.mu.Unlock()
+			time.Sleep(1 * time.Second)
+		}
+		c.determineWinner(s, game)
+	}()
 }
 
 func (c *BlackjackCommand) HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate) { /* No modal for now */ }
@@ -604,7 +642,7 @@ func (c *BlackjackCommand) GetCategory() string {
 }
 
 func (c *BlackjackCommand) GetComponentIDs() []string {
-	return []string{BlackjackHitButton, BlackjackStandButton, BlackjackDoubleDownButton, BlackjackSplitButton, BlackjackInsuranceButton, BlackjackSurrenderButton}
+	return []string{BlackjackHitButton, BlackjackStandButton, BlackjackDoubleDownButton, BlackjackSplitButton, BlackjackInsuranceButton, BlackjackSurrenderButton, BlackjackHintButton}
 }
 
 // --- Game Logic ---
@@ -612,9 +650,10 @@ func (c *BlackjackCommand) GetComponentIDs() []string {
 var suits = []string{"♠️", "♥️", "♦️", "♣️"}
 var ranks = []string{"A", "2", "3", "4", "5", "6", "7", "8", "9", "10", "J", "Q", "K"}
 
-func NewDeck() []Card {
-	deck := make([]Card, 0, 52*6) // Use 6 decks
-	for i := 0; i < 6; i++ {
+// NewDeck は、decks 組のトランプを重ねたシューを返します。
+func NewDeck(decks int) []Card {
+	deck := make([]Card, 0, 52*decks)
+	for i := 0; i < decks; i++ {
 		for _, suit := range suits {
 			for _, rank := range ranks {
 				deck = append(deck, Card{Suit: suit, Rank: rank})
@@ -636,6 +675,56 @@ func dealCard(game *BlackjackGame, hand *[]Card) {
 	game.Deck = game.Deck[1:]
 }
 
+// hand は、行動中の手札を返します。
+func (g *BlackjackGame) hand() *BlackjackHand {
+	return g.Hands[g.CurrentHand]
+}
+
+// upCard は、ディーラーの表向きのカードを返します。
+func (g *BlackjackGame) upCard() Card {
+	return g.DealerHand[1]
+}
+
+// canDoubleDown は、行動中の手札でダブルダウンできるかを返します。
+func (g *BlackjackGame) canDoubleDown() bool {
+	if g.State != BJStatePlayerTurn {
+		return false
+	}
+	hand := g.hand()
+	return len(hand.Cards) == 2 && !hand.FromAces && canDoubleOn(hand.Cards, g.Rules)
+}
+
+// canDoubleOn は、最初の2枚でルール上ダブルダウンできるかを返します。
+func canDoubleOn(cards []Card, rules *storage.BlackjackRules) bool {
+	if rules.DoubleAnyTwo {
+		return true
+	}
+	value, _ := CalculateHandValue(cards)
+	return value >= 9 && value <= 11
+}
+
+// canSplit は、行動中の手札をスプリットできるかを返します。
+func (g *BlackjackGame) canSplit() bool {
+	if g.State != BJStatePlayerTurn || len(g.Hands) >= g.Rules.MaxHands {
+		return false
+	}
+	hand := g.hand()
+	if len(hand.Cards) != 2 || hand.Cards[0].Rank != hand.Cards[1].Rank {
+		return false
+	}
+	return !hand.FromAces || g.Rules.ResplitAces
+}
+
+// canInsure は、インシュランスをかけられるかを返します。
+func (g *BlackjackGame) canInsure() bool {
+	return g.State == BJStatePlayerTurn && !g.Peeked && g.upCard().Rank == "A" && g.InsuranceBet == 0
+}
+
+// canSurrender は、最初の2枚のままでレイトサレンダーできるかを返します。
+func (g *BlackjackGame) canSurrender() bool {
+	return g.State == BJStatePlayerTurn && !g.Rules.NoSurrender && len(g.Hands) == 1 && len(g.Hands[0].Cards) == 2
+}
+
 func (c *Card) String() string {
 	return c.Suit + " " + c.Rank
 }
@@ -657,22 +746,27 @@ func HandToString(hand []Card, hideFirst bool) string {
 	return strings.Join(parts, " | ")
 }
 
-func CalculateHandValue(hand []Card) (int, bool) {
+// cardValue は、カード1枚の点数です。エースは11として数えます。
+func cardValue(card Card) int {
+	switch card.Rank {
+	case "A":
+		return 11
+	case "K", "Q", "J":
+		return 10
+	}
+	value, _ := strconv.Atoi(card.Rank)
+	return value
+}
+
+// handValue は、手札の合計と、エースを11として数えているか (ソフトハンド) を返します。
+func handValue(hand []Card) (int, bool) {
 	value := 0
 	aces := 0
 	for _, card := range hand {
-		switch card.Rank {
-		case "A":
+		if card.Rank == "A" {
 			aces++
-			value += 11
-		case "K", "Q", "J":
-			value += 10
-		default:
-			rankValue, err := strconv.Atoi(card.Rank)
-			if err == nil {
-				value += rankValue
-			}
 		}
+		value += cardValue(card)
 	}
 
 	for value > 21 && aces > 0 {
@@ -680,33 +774,71 @@ func CalculateHandValue(hand []Card) (int, bool) {
 		aces--
 	}
 
+	return value, aces > 0
+}
+
+func CalculateHandValue(hand []Card) (int, bool) {
+	value, _ := handValue(hand)
 	return value, len(hand) == 2 && value == 21
 }
 
+// dealerShouldHit は、ルールに従ってディーラーがもう1枚引くかを返します。
+func dealerShouldHit(hand []Card, rules *storage.BlackjackRules) bool {
+	value, soft := handValue(hand)
+	return value < 17 || (value == 17 && soft && rules.HitSoft17)
+}
+
 // --- Helper Functions ---
 
-func (c *BlackjackCommand) buildGameEmbed(game *BlackjackGame, title string) *discordgo.MessageEmbed {
-	playerValue, _ := CalculateHandValue(game.PlayerHand)
-	playerValue2, _ := CalculateHandValue(game.PlayerHand2)
+// rulesSummary は、ルールを1行で表します。
+func rulesSummary(rules *storage.BlackjackRules) string {
+	parts := []string{fmt.Sprintf("%dデッキ", rules.Decks)}
+	if rules.HitSoft17 {
+		parts = append(parts, "H17")
+	} else {
+		parts = append(parts, "S17")
+	}
+	parts = append(parts, "BJ "+rules.BlackjackPays)
+	if rules.DoubleAnyTwo {
+		parts = append(parts, "ダブル: 任意の2枚")
+	} else {
+		parts = append(parts, "ダブル: 9〜11")
+	}
+	parts = append(parts, fmt.Sprintf("手札は最大%d", rules.MaxHands))
+	if rules.ResplitAces {
+		parts = append(parts, "エースの再スプリット可")
+	}
+	if !rules.NoSurrender {
+		parts = append(parts, "レイトサレンダー")
+	}
+	return strings.Join(parts, " / ")
+}
 
+func (c *BlackjackCommand) buildGameEmbed(game *BlackjackGame, title string) *discordgo.MessageEmbed {
 	var dealerHandStr string
 	var dealerValue int
 
 	if game.State == BJStatePlayerTurn {
 		dealerHandStr = HandToString(game.DealerHand, true)
-		if len(game.DealerHand) > 1 {
-			// Show only the value of the up-card
-			dealerValue, _ = CalculateHandValue([]Card{game.DealerHand[1]})
-		}
+		// Show only the value of the up-card
+		dealerValue, _ = CalculateHandValue([]Card{game.upCard()})
 	} else {
 		dealerHandStr = HandToString(game.DealerHand, false)
 		dealerValue, _ = CalculateHandValue(game.DealerHand)
 	}
 
-	description := fmt.Sprintf("ベット額: **%d** チップ", game.BetAmount)
+	var totalBet int64
+	for _, hand := range game.Hands {
+		totalBet += hand.Bet
+	}
+	description := fmt.Sprintf("ベット額: **%d** チップ", totalBet)
 	if game.InsuranceBet > 0 {
 		description += fmt.Sprintf(" | インシュランス: **%d** チップ", game.InsuranceBet)
 	}
+	if game.SideBet > 0 {
+		description += fmt.Sprintf(" | サイドベット: **%d** チップ", game.SideBet)
+	}
+	description += "\n" + rulesSummary(game.Rules)
 
 	embed := &discordgo.MessageEmbed{
 		Title:       "♠️♥️ ブラックジャック ♦️♣️",
@@ -719,41 +851,42 @@ func (c *BlackjackCommand) buildGameEmbed(game *BlackjackGame, title string) *di
 			},
 		},
 		Color:  0x000000,
-		Footer: &discordgo.MessageEmbedFooter{Text: title},
+		Footer: &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("%s | %s", title, game.Round.Footer())},
 	}
 
 	// Add player hand fields
-	playerHandName := "あなたの手札"
-	if len(game.PlayerHand2) > 0 {
-		playerHandName = "あなたの手札 (1)"
-		if game.CurrentHand == 1 {
-			playerHandName += " ◀️"
+	for n, hand := range game.Hands {
+		value, _ := CalculateHandValue(hand.Cards)
+		name := "あなたの手札"
+		if len(game.Hands) > 1 {
+			name = fmt.Sprintf("あなたの手札 (%d)", n+1)
 		}
-	}
-	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-		Name:   fmt.Sprintf("%s (%d)", playerHandName, playerValue),
-		Value:  HandToString(game.PlayerHand, false),
-		Inline: false,
-	})
-
-	if len(game.PlayerHand2) > 0 {
-		playerHand2Name := "あなたの手札 (2)"
-		if game.CurrentHand == 2 {
-			playerHand2Name += " ◀️"
+		name = fmt.Sprintf("%s (%d)", name, value)
+		if hand.Doubled {
+			name += " ダブルダウン"
+		}
+		if len(game.Hands) > 1 && n == game.CurrentHand && game.State == BJStatePlayerTurn {
+			name += " ◀️"
 		}
 		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-			Name:   fmt.Sprintf("%s (%d)", playerHand2Name, playerValue2),
-			Value:  HandToString(game.PlayerHand2, false),
+			Name:   name,
+			Value:  HandToString(hand.Cards, false),
 			Inline: false,
 		})
 	}
 
+	if game.SideBetResult != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "サイドベット",
+			Value: game.SideBetResult,
+		})
+	}
+
 	return embed
 }
 
 func (c *BlackjackCommand) buildGameComponents(game *BlackjackGame) []discordgo.MessageComponent {
 	disabled := game.State != BJStatePlayerTurn
-	showInsurance := game.DealerHand[0].Rank == "A" && game.InsuranceBet == 0
 
 	// First row of buttons: core actions
 	actionsRow1 := discordgo.ActionsRow{
@@ -762,7 +895,7 @@ func (c *BlackjackCommand) buildGameComponents(game *BlackjackGame) []discordgo.
 				Label:    "ヒット",
 				Style:    discordgo.SuccessButton,
 				CustomID: BlackjackHitButton,
-				Disabled: disabled,
+				Disabled: disabled || game.hand().FromAces,
 			},
 			discordgo.Button{
 				Label:    "スタンド",
@@ -770,49 +903,52 @@ func (c *BlackjackCommand) buildGameComponents(game *BlackjackGame) []discordgo.
 				CustomID: BlackjackStandButton,
 				Disabled: disabled,
 			},
+			discordgo.Button{
+				Label:    "ヒント",
+				Style:    discordgo.SecondaryButton,
+				CustomID: BlackjackHintButton,
+				Emoji:    &discordgo.ComponentEmoji{Name: "💡"},
+				Disabled: disabled,
+			},
 		},
 	}
 
 	// Second row of buttons: special actions (Double Down, Split)
 	var actionsRow2 *discordgo.ActionsRow
-	if game.CanDoubleDown || game.CanSplit {
-		var specialButtons []discordgo.MessageComponent
-		if game.CanDoubleDown {
-			specialButtons = append(specialButtons, discordgo.Button{
-				Label:    "ダブルダウン",
-				Style:    discordgo.PrimaryButton,
-				CustomID: BlackjackDoubleDownButton,
-				Disabled: disabled,
-			})
-		}
-		if game.CanSplit {
-			specialButtons = append(specialButtons, discordgo.Button{
-				Label:    "スプリット",
-				Style:    discordgo.PrimaryButton,
-				CustomID: BlackjackSplitButton,
-				Disabled: disabled,
-			})
-		}
+	var specialButtons []discordgo.MessageComponent
+	if game.canDoubleDown() {
+		specialButtons = append(specialButtons, discordgo.Button{
+			Label:    "ダブルダウン",
+			Style:    discordgo.PrimaryButton,
+			CustomID: BlackjackDoubleDownButton,
+		})
+	}
+	if game.canSplit() {
+		specialButtons = append(specialButtons, discordgo.Button{
+			Label:    "スプリット",
+			Style:    discordgo.PrimaryButton,
+			CustomID: BlackjackSplitButton,
+		})
+	}
+	if len(specialButtons) > 0 {
 		actionsRow2 = &discordgo.ActionsRow{Components: specialButtons}
 	}
 
 	// Third row for Insurance and Surrender
 	var actionsRow3 *discordgo.ActionsRow
 	var specialButtons2 []discordgo.MessageComponent
-	if showInsurance {
+	if game.canInsure() {
 		specialButtons2 = append(specialButtons2, discordgo.Button{
 			Label:    "インシュランス",
 			Style:    discordgo.SecondaryButton,
 			CustomID: BlackjackInsuranceButton,
-			Disabled: disabled,
 		})
 	}
-	if game.CanSurrender {
+	if game.canSurrender() {
 		specialButtons2 = append(specialButtons2, discordgo.Button{
 			Label:    "サレンダー",
 			Style:    discordgo.SecondaryButton,
 			CustomID: BlackjackSurrenderButton,
-			Disabled: disabled,
 		})
 	}
 	if len(specialButtons2) > 0 {
@@ -851,37 +987,38 @@ func (c *BlackjackCommand) determineWinner(s *discordgo.Session, game *Blackjack
 			totalPayout += insurancePayout
 			finalResultText.WriteString(fmt.Sprintf("✅ **インシュランス成功！** ディーラーはブラックジャックでした。配当 **%d** チップを獲得しました。\n", insurancePayout))
 		} else {
-			finalResultText.WriteString(fmt.Sprintf("❌ **インシュランス失敗。** ディーラーはブラックジャックではありませんでした。\n"))
+			finalResultText.WriteString("❌ **インシュランス失敗。** ディーラーはブラックジャックではありませんでした。\n")
 		}
 	}
 
-	// Determine result for the first hand (and the only hand if not split)
-	payout1, resultText1 := c.calculateHandResult(game.PlayerHand, game.DealerHand, game.BetAmount)
-	totalPayout += payout1
-	finalResultText.WriteString(fmt.Sprintf("**手札1:** %s\n", resultText1))
-
-	// Determine result for the second hand if it exists
-	if len(game.PlayerHand2) > 0 {
-		payout2, resultText2 := c.calculateHandResult(game.PlayerHand2, game.DealerHand, game.BetAmount2)
-		totalPayout += payout2
-		finalResultText.WriteString(fmt.Sprintf("**手札2:** %s\n", resultText2))
+	// Determine result for each hand
+	split := len(game.Hands) > 1
+	var totalBet int64
+	progress := map[string]int64{}
+	for n, hand := range game.Hands {
+		payout, resultText := calculateHandResult(hand.Cards, game.DealerHand, hand.Bet, game.Rules, split)
+		totalPayout += payout
+		totalBet += hand.Bet
+		finalResultText.WriteString(fmt.Sprintf("**手札%d:** %s\n", n+1, resultText))
+		if payout > hand.Bet {
+			progress[counterBlackjackWins]++
+			if _, natural := CalculateHandValue(hand.Cards); natural && !split {
+				progress[counterBlackjackNatural]++
+			}
+		}
 	}
 
 	// Update user's balance
-	if totalPayout > 0 {
-		casinoData, err := c.Store.GetCasinoData(game.Interaction.GuildID, game.PlayerID)
-		if err == nil {
+	casinoData, err := c.Store.GetCasinoData(game.Interaction.GuildID, game.PlayerID)
+	if err == nil {
+		if totalPayout > 0 {
 			casinoData.Chips += totalPayout
 			c.Store.UpdateCasinoData(casinoData)
-			finalResultText.WriteString(fmt.Sprintf("\n**合計収支:** `+%d` チップ | **現在の所持チップ:** `%d`", totalPayout-(game.BetAmount+game.BetAmount2), casinoData.Chips))
-		} else {
-			c.Log.Error("Failed to get casino data for payout", "error", err)
 		}
+		net := totalPayout + game.SideBetPayout - totalBet - game.InsuranceBet - game.SideBet
+		finalResultText.WriteString(fmt.Sprintf("\n**合計収支:** `%+d` チップ | **現在の所持チップ:** `%d`", net, casinoData.Chips))
 	} else {
-		casinoData, err := c.Store.GetCasinoData(game.Interaction.GuildID, game.PlayerID)
-		if err == nil {
-			finalResultText.WriteString(fmt.Sprintf("\n**合計収支:** `-%d` チップ | **現在の所持チップ:** `%d`", game.BetAmount+game.BetAmount2, casinoData.Chips))
-		}
+		c.Log.Error("Failed to get casino data for payout", "error", err)
 	}
 
 	embed := c.buildGameEmbed(game, "ゲーム終了")
@@ -893,7 +1030,7 @@ func (c *BlackjackCommand) determineWinner(s *discordgo.Session, game *Blackjack
 
 	components := c.buildGameComponents(game) // This will disable all buttons
 
-	_, err := s.InteractionResponseEdit(game.Interaction, &discordgo.WebhookEdit{
+	_, err = s.InteractionResponseEdit(game.Interaction, &discordgo.WebhookEdit{
 		Embeds:     &[]*discordgo.MessageEmbed{embed},
 		Components: &components,
 	})
@@ -901,20 +1038,39 @@ func (c *BlackjackCommand) determineWinner(s *discordgo.Session, game *Blackjack
 		c.Log.Error("Failed to edit blackjack final message", "error", err)
 	}
 
+	game.timer.Stop()
 	delete(c.games, game.PlayerID)
+	game.Round.Close()
+
+	recordGameRound(c.Store, c.Log, game.Interaction.GuildID, game.PlayerID, storage.GameBlackjack,
+		totalBet+game.InsuranceBet+game.SideBet, totalPayout+game.SideBetPayout)
+	progress[counterChipsWon] = totalPayout + game.SideBetPayout
+	recordAchievements(s, c.Store, c.Log, game.Interaction.ChannelID, game.Interaction.GuildID, game.PlayerID, progress)
+}
+
+// exceedsGamblingLimits は、ゲーム中の追加のベットが賭けの制限に触れるかを返します。
+// 進行中のゲームの賭け金は記録待ちとして checkGamblingBet が含めるため、追加の分だけを渡します。
+func (c *BlackjackCommand) exceedsGamblingLimits(game *BlackjackGame, additional int64) bool {
+	_, _, err := checkGamblingBet(c.Store, game.Interaction.GuildID, game.PlayerID, additional)
+	if err != nil {
+		c.Log.Warn("Blackjack bet rejected by gambling limits", "error", err, "userID", game.PlayerID)
+	}
+	return err != nil
 }
 
 // calculateHandResult calculates the payout and result text for a single hand.
-func (c *BlackjackCommand) calculateHandResult(playerHand, dealerHand []Card, betAmount int64) (int64, string) {
+// スプリットした手札の21はブラックジャックとして扱いません。
+func calculateHandResult(playerHand, dealerHand []Card, betAmount int64, rules *storage.BlackjackRules, split bool) (int64, string) {
 	playerValue, playerBlackjack := CalculateHandValue(playerHand)
 	dealerValue, dealerBlackjack := CalculateHandValue(dealerHand)
+	playerBlackjack = playerBlackjack && !split
 
 	if playerBlackjack && !dealerBlackjack {
-		payout := int64(float64(betAmount) * 2.2)
+		payout := betAmount + int64(float64(betAmount)*rules.BlackjackPayout())
 		return payout, fmt.Sprintf("ブラックジャック！あなたの勝ちです！🎉 (配当: %d)", payout)
 	} else if playerValue > 21 {
 		return 0, "バスト！あなたの負けです...😢"
-	} else if dealerBlackjack {
+	} else if dealerBlackjack && !playerBlackjack {
 		return 0, "ディーラーのブラックジャック！あなたの負けです...😭"
 	} else if dealerValue > 21 {
 		payout := betAmount * 2
@@ -937,4 +1093,4 @@ func sendBlackjackErrorResponse(s *discordgo.Session, i *discordgo.InteractionCr
 			Flags:   discordgo.MessageFlagsEphemeral,
 		},
 	})
-}
\ No newline at end of file
+}
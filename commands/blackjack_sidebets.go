@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// パーフェクトペアの配当 (賭け金に対する利益の倍率)
+const (
+	perfectPairPays = 25 // 同じスートのペア
+	coloredPairPays = 12 // 同じ色で違うスートのペア
+	mixedPairPays   = 6  // 色の違うペア
+)
+
+// 21+3 の配当 (賭け金に対する利益の倍率)
+const (
+	suitedTripsPays   = 100
+	straightFlushPays = 40
+	threeOfAKindPays  = 30
+	straightPays      = 10
+	flushPays         = 5
+)
+
+// isRed は、カードが赤いスートかを返します。
+func isRed(card Card) bool {
+	return card.Suit == "♥️" || card.Suit == "♦️"
+}
+
+// perfectPairs は、最初の2枚のパーフェクトペアの役と配当倍率を返します。役がなければ倍率は0です。
+func perfectPairs(first, second Card) (string, int64) {
+	switch {
+	case first.Rank != second.Rank:
+		return "", 0
+	case first.Suit == second.Suit:
+		return "パーフェクトペア", perfectPairPays
+	case isRed(first) == isRed(second):
+		return "カラードペア", coloredPairPays
+	default:
+		return "ミックスペア", mixedPairPays
+	}
+}
+
+// rankIndex は、ランクの並び順 (A=0, K=12) を返します。
+func rankIndex(rank string) int {
+	for n, r := range ranks {
+		if r == rank {
+			return n
+		}
+	}
+	return -1
+}
+
+// twentyOnePlusThree は、最初の2枚とディーラーの表向きのカードでできる役と配当倍率を返します。
+// 役がなければ倍率は0です。
+func twentyOnePlusThree(cards ...Card) (string, int64) {
+	indexes := make([]int, len(cards))
+	for n, card := range cards {
+		indexes[n] = rankIndex(card.Rank)
+	}
+	sort.Ints(indexes)
+
+	flush := cards[0].Suit == cards[1].Suit && cards[1].Suit == cards[2].Suit
+	trips := indexes[0] == indexes[2]
+	// エースは Q-K-A の上にも A-2-3 の下にも使える
+	straight := (indexes[1] == indexes[0]+1 && indexes[2] == indexes[1]+1) || (indexes[0] == 0 && indexes[1] == 11 && indexes[2] == 12)
+
+	switch {
+	case trips && flush:
+		return "スーテッド・スリーカード", suitedTripsPays
+	case straight && flush:
+		return "ストレートフラッシュ", straightFlushPays
+	case trips:
+		return "スリーカード", threeOfAKindPays
+	case straight:
+		return "ストレート", straightPays
+	case flush:
+		return "フラッシュ", flushPays
+	}
+	return "", 0
+}
+
+// settleSideBets は、サイドベットの払い戻し (賭け金を含む) と結果の説明を返します。
+func settleSideBets(playerCards []Card, upCard Card, perfectPairsBet, twentyOnePlusThreeBet int64) (int64, string) {
+	var payout int64
+	var sb strings.Builder
+	if perfectPairsBet > 0 {
+		if name, pays := perfectPairs(playerCards[0], playerCards[1]); pays > 0 {
+			win := perfectPairsBet * (pays + 1)
+			payout += win
+			fmt.Fprintf(&sb, "✅ パーフェクトペア: **%s** (%d:1) → %d チップ\n", name, pays, win)
+		} else {
+			sb.WriteString("❌ パーフェクトペア: 役なし\n")
+		}
+	}
+	if twentyOnePlusThreeBet > 0 {
+		if name, pays := twentyOnePlusThree(playerCards[0], playerCards[1], upCard); pays > 0 {
+			win := twentyOnePlusThreeBet * (pays + 1)
+			payout += win
+			fmt.Fprintf(&sb, "✅ 21+3: **%s** (%d:1) → %d チップ\n", name, pays, win)
+		} else {
+			sb.WriteString("❌ 21+3: 役なし\n")
+		}
+	}
+	return payout, sb.String()
+}
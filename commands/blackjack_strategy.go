@@ -0,0 +1,160 @@
+package commands
+
+import (
+	"fmt"
+	"luna/storage"
+)
+
+// 基本戦略で選ぶ行動
+const (
+	strategyHit       = "ヒット"
+	strategyStand     = "スタンド"
+	strategyDouble    = "ダブルダウン"
+	strategySplit     = "スプリット"
+	strategySurrender = "サレンダー"
+)
+
+// strategyOptions は、今の手札で選べる行動です。
+type strategyOptions struct {
+	double, split, surrender bool
+}
+
+// basicStrategy は、行動中の手札に対する基本戦略の行動と、その理由を返します。
+// 呼び出し側で c.mu を保持してください。
+func basicStrategy(game *BlackjackGame) (string, string) {
+	hand := game.hand()
+	options := strategyOptions{
+		double:    game.canDoubleDown(),
+		split:     game.canSplit(),
+		surrender: game.canSurrender(),
+	}
+	up := game.upCard()
+	if hand.FromAces {
+		// エースのスプリットでできた手札は引けないため、再スプリットかスタンドしかない
+		if options.split {
+			return strategySplit, "エースのペアは常にスプリットします。"
+		}
+		return strategyStand, "エースのスプリット後はカードを引けません。"
+	}
+	return strategyFor(hand.Cards, up, game.Rules, options)
+}
+
+// strategyFor は、マルチデッキの基本戦略 (スプリット後のダブルダウンあり) に従って行動を選びます。
+// 選んだ行動ができない場合は、その次に良い行動を返します。
+func strategyFor(cards []Card, up Card, rules *storage.BlackjackRules, options strategyOptions) (string, string) {
+	dealer := cardValue(up)
+	value, soft := handValue(cards)
+	pair := len(cards) == 2 && cards[0].Rank == cards[1].Rank
+	pairValue := cardValue(cards[0])
+	situation := fmt.Sprintf("ディーラーの表向きのカードが %s のとき", up.Rank)
+
+	// サレンダーはほかのどの行動よりも先に判断する
+	if options.surrender && !soft {
+		surrender := false
+		switch {
+		case pair && pairValue == 8:
+			surrender = rules.HitSoft17 && dealer == 11
+		case value == 16:
+			surrender = dealer >= 9
+		case value == 15:
+			surrender = dealer == 10 || (rules.HitSoft17 && dealer == 11)
+		case value == 17:
+			surrender = rules.HitSoft17 && dealer == 11
+		}
+		if surrender {
+			return strategySurrender, fmt.Sprintf("ハード %d は%s、半分を取り戻して降りるのが最善です。", value, situation)
+		}
+	}
+
+	if pair && options.split {
+		split := false
+		switch pairValue {
+		case 11, 8:
+			split = true
+		case 9:
+			split = dealer <= 9 && dealer != 7
+		case 7:
+			split = dealer <= 7
+		case 6:
+			split = dealer <= 6
+		case 4:
+			split = dealer == 5 || dealer == 6
+		case 2, 3:
+			split = dealer <= 7
+		}
+		if split {
+			return strategySplit, fmt.Sprintf("%s のペアは%sスプリットします。", cards[0].Rank, situation)
+		}
+	}
+
+	double := func(reason string) (string, string) {
+		if options.double {
+			return strategyDouble, reason
+		}
+		return strategyHit, reason + " (ダブルダウンできないためヒット)"
+	}
+
+	if soft {
+		reason := fmt.Sprintf("ソフト %d は%s", value, situation)
+		switch {
+		case value >= 20:
+			return strategyStand, reason + "スタンドします。"
+		case value == 19:
+			if rules.HitSoft17 && dealer == 6 && options.double {
+				return strategyDouble, reason + "ダブルダウンします。"
+			}
+			return strategyStand, reason + "スタンドします。"
+		case value == 18:
+			if (dealer >= 3 && dealer <= 6) || (rules.HitSoft17 && dealer == 2) {
+				if options.double {
+					return strategyDouble, reason + "ダブルダウンします。"
+				}
+				return strategyStand, reason + "ダブルダウンします。(ダブルダウンできないためスタンド)"
+			}
+			if dealer <= 8 {
+				return strategyStand, reason + "スタンドします。"
+			}
+			return strategyHit, reason + "ヒットします。"
+		case value == 17:
+			if dealer >= 3 && dealer <= 6 {
+				return double(reason + "ダブルダウンします。")
+			}
+		case value >= 15:
+			if dealer >= 4 && dealer <= 6 {
+				return double(reason + "ダブルダウンします。")
+			}
+		default:
+			if dealer == 5 || dealer == 6 {
+				return double(reason + "ダブルダウンします。")
+			}
+		}
+		return strategyHit, reason + "ヒットします。"
+	}
+
+	reason := fmt.Sprintf("ハード %d は%s", value, situation)
+	switch {
+	case value >= 17:
+		return strategyStand, reason + "スタンドします。"
+	case value >= 13:
+		if dealer <= 6 {
+			return strategyStand, reason + "ディーラーのバストを待ってスタンドします。"
+		}
+	case value == 12:
+		if dealer >= 4 && dealer <= 6 {
+			return strategyStand, reason + "ディーラーのバストを待ってスタンドします。"
+		}
+	case value == 11:
+		if dealer <= 10 || rules.HitSoft17 {
+			return double(reason + "ダブルダウンします。")
+		}
+	case value == 10:
+		if dealer <= 9 {
+			return double(reason + "ダブルダウンします。")
+		}
+	case value == 9:
+		if dealer >= 3 && dealer <= 6 {
+			return double(reason + "ダブルダウンします。")
+		}
+	}
+	return strategyHit, reason + "ヒットします。"
+}
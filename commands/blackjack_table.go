@@ -0,0 +1,780 @@
+package commands
+
+import (
+	"fmt"
+	"luna/fair"
+	"luna/interfaces"
+	"luna/storage"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// テーブルのボタンとモーダルのID
+const (
+	TableBetButton    = "bjt_bet"
+	TableLeaveButton  = "bjt_leave"
+	TableHitButton    = "bjt_hit"
+	TableStandButton  = "bjt_stand"
+	TableDoubleButton = "bjt_double"
+	TableBetModalID   = "bjt_bet_modal"
+)
+
+// テーブルの設定
+const (
+	DefaultTableSeats    = 5
+	MaxTableSeats        = 7
+	TableBettingWindow   = 30 * time.Second // 各ラウンドの前にベットを受け付ける時間
+	TableTurnTimeout     = 30 * time.Second // 操作がないと自動でスタンドするまでの時間
+	TableShoePenetration = 0.75             // カットカードまでに配るシューの割合
+)
+
+// TableState は、テーブルの進行状況を表します。
+type TableState int
+
+const (
+	TableStateBetting TableState = iota
+	TableStatePlaying
+)
+
+// TableSeat は、テーブルの1つの席です。
+type TableSeat struct {
+	UserID  string
+	Bet     int64 // 0 の場合はまだこのラウンドにベットしていない
+	Hand    []Card
+	Doubled bool
+	Done    bool // 行動が終わったか
+}
+
+// BlackjackTable は、チャンネルに1つ置かれるブラックジャックのテーブルです。
+// 全員で1つのシューを使い、ディーラーの手札も共有します。
+type BlackjackTable struct {
+	GuildID   string
+	ChannelID string
+	MessageID string
+	OwnerID   string
+	MaxSeats  int
+	State     TableState
+	Seats     []*TableSeat
+	Shoe      []Card
+	CutCard   int // シューの残りがこの枚数以下になったら、次のラウンドの前に切り直す
+	Dealer    []Card
+	Turn      int                     // 行動中の席の番号
+	Deadline  time.Time               // ベットの締め切り、または行動中の席の制限時間
+	Closing   bool                    // ラウンドが終わったら閉じる
+	Rules     *storage.BlackjackRules // ラウンドの開始時にサーバーの設定から読み込み直す
+	Round     *fair.Round
+	Shuffled  bool   // 直前のラウンドの前にシューを切り直したか
+	Results   string // 直前のラウンドの結果
+	timer     *time.Timer
+}
+
+// BlackjackTableCommand は、/bjtable コマンドを処理します。
+type BlackjackTableCommand struct {
+	Store  interfaces.DataStore
+	Log    interfaces.Logger
+	Fair   *fair.Service
+	tables map[string]*BlackjackTable // channelID -> table
+	mu     sync.Mutex
+}
+
+func NewBlackjackTableCommand(store interfaces.DataStore, log interfaces.Logger, fairRNG *fair.Service) *BlackjackTableCommand {
+	return &BlackjackTableCommand{
+		Store:  store,
+		Log:    log,
+		Fair:   fairRNG,
+		tables: make(map[string]*BlackjackTable),
+	}
+}
+
+func (c *BlackjackTableCommand) GetCommandDef() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "bjtable",
+		Description: "チャンネルのみんなで遊べるブラックジャックのテーブルを管理します。",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "open",
+				Description: "このチャンネルにテーブルを開きます。",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "seats",
+						Description: fmt.Sprintf("席の数 (デフォルト: %d)", DefaultTableSeats),
+						MinValue:    &[]float64{1}[0],
+						MaxValue:    MaxTableSeats,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "close",
+				Description: "このチャンネルのテーブルを閉じます。",
+			},
+		},
+	}
+}
+
+func (c *BlackjackTableCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	switch i.ApplicationCommandData().Options[0].Name {
+	case "open":
+		c.handleOpen(s, i)
+	case "close":
+		c.handleClose(s, i)
+	}
+}
+
+func (c *BlackjackTableCommand) handleOpen(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.tables[i.ChannelID]; exists {
+		sendErrorResponse(s, i, "このチャンネルには既にテーブルがあります。")
+		return
+	}
+
+	seats := DefaultTableSeats
+	for _, opt := range i.ApplicationCommandData().Options[0].Options {
+		if opt.Name == "seats" {
+			seats = int(opt.IntValue())
+		}
+	}
+
+	table := &BlackjackTable{
+		GuildID:   i.GuildID,
+		ChannelID: i.ChannelID,
+		OwnerID:   i.Member.User.ID,
+		MaxSeats:  seats,
+		State:     TableStateBetting,
+		Deadline:  time.Now().Add(TableBettingWindow),
+	}
+	rules, err := loadBlackjackRules(c.Store, i.GuildID)
+	if err != nil {
+		c.Log.Error("Failed to get blackjack rules", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+	table.Rules = rules
+	if err := c.shuffleShoe(table); err != nil {
+		c.Log.Error("Failed to start fair round for blackjack table", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+	table.Shuffled = false
+
+	components := c.buildTableComponents(table)
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{c.buildTableEmbed(table)},
+			Components: components,
+		},
+	})
+	if err != nil {
+		c.Log.Error("Failed to send blackjack table message", "error", err)
+		table.Round.Close()
+		return
+	}
+	msg, err := s.InteractionResponse(i.Interaction)
+	if err != nil {
+		c.Log.Error("Failed to get interaction response message", "error", err)
+		table.Round.Close()
+		return
+	}
+	table.MessageID = msg.ID
+	c.tables[i.ChannelID] = table
+	c.schedule(table, TableBettingWindow, func() { c.startRound(s, table) })
+}
+
+func (c *BlackjackTableCommand) handleClose(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	table, exists := c.tables[i.ChannelID]
+	if !exists {
+		sendErrorResponse(s, i, "このチャンネルにはテーブルがありません。")
+		return
+	}
+	if i.Member.User.ID != table.OwnerID && !hasManageGuild(i) {
+		sendErrorResponse(s, i, "テーブルを閉じられるのは、テーブルを開いた本人かサーバーの管理者だけです。")
+		return
+	}
+	if table.State == TableStateBetting {
+		c.closeTable(s, table, "テーブルは閉じられました。ベットは返却されました。")
+		sendSuccessResponse(s, i, "テーブルを閉じました。")
+		return
+	}
+	table.Closing = true
+	sendSuccessResponse(s, i, "このラウンドが終わったらテーブルを閉じます。")
+}
+
+func (c *BlackjackTableCommand) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	table, exists := c.tables[i.ChannelID]
+	if !exists || table.MessageID != i.Message.ID {
+		sendErrorResponse(s, i, "このテーブルは既に閉じられています。")
+		return
+	}
+
+	switch i.MessageComponentData().CustomID {
+	case TableBetButton:
+		c.handleBetButton(s, i, table)
+	case TableLeaveButton:
+		c.handleLeave(s, i, table)
+	case TableHitButton, TableStandButton, TableDoubleButton:
+		c.handleAction(s, i, table)
+	}
+}
+
+func (c *BlackjackTableCommand) HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	table, exists := c.tables[i.ChannelID]
+	if !exists {
+		sendErrorResponse(s, i, "このテーブルは既に閉じられています。")
+		return
+	}
+	if i.ModalSubmitData().CustomID == TableBetModalID {
+		c.handleBetModalSubmit(s, i, table)
+	}
+}
+
+func (c *BlackjackTableCommand) GetComponentIDs() []string {
+	return []string{TableBetButton, TableLeaveButton, TableHitButton, TableStandButton, TableDoubleButton, TableBetModalID}
+}
+
+func (c *BlackjackTableCommand) GetCategory() string {
+	return "カジノ"
+}
+
+// --- Betting ---
+
+// seatOf は、ユーザーの席を返します。座っていなければ nil を返します。
+func (t *BlackjackTable) seatOf(userID string) *TableSeat {
+	for _, seat := range t.Seats {
+		if seat.UserID == userID {
+			return seat
+		}
+	}
+	return nil
+}
+
+func (c *BlackjackTableCommand) handleBetButton(s *discordgo.Session, i *discordgo.InteractionCreate, table *BlackjackTable) {
+	if table.State != TableStateBetting {
+		sendErrorResponse(s, i, "ベット受付は終了しました。次のラウンドをお待ちください。")
+		return
+	}
+	seat := table.seatOf(i.Member.User.ID)
+	if seat == nil && len(table.Seats) >= table.MaxSeats {
+		sendErrorResponse(s, i, "満席です。")
+		return
+	}
+	if seat != nil && seat.Bet > 0 {
+		sendErrorResponse(s, i, "このラウンドには既にベットしています。")
+		return
+	}
+
+	modal := discordgo.InteractionResponseData{
+		CustomID: TableBetModalID,
+		Title:    "ブラックジャックテーブルにベット",
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "bet_amount",
+						Label:       "ベットするチップの額",
+						Style:       discordgo.TextInputShort,
+						Placeholder: "100",
+						Required:    true,
+					},
+				},
+			},
+		},
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseModal, Data: &modal})
+}
+
+func (c *BlackjackTableCommand) handleBetModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate, table *BlackjackTable) {
+	if table.State != TableStateBetting {
+		sendErrorResponse(s, i, "ベット受付は終了しました。次のラウンドをお待ちください。")
+		return
+	}
+	userID := i.Member.User.ID
+	seat := table.seatOf(userID)
+	if seat == nil && len(table.Seats) >= table.MaxSeats {
+		sendErrorResponse(s, i, "満席です。")
+		return
+	}
+	if seat != nil && seat.Bet > 0 {
+		sendErrorResponse(s, i, "このラウンドには既にベットしています。")
+		return
+	}
+
+	betAmountStr := i.ModalSubmitData().Components[0].(*discordgo.ActionsRow).Components[0].(*discordgo.TextInput).Value
+	betAmount, err := strconv.ParseInt(strings.TrimSpace(betAmountStr), 10, 64)
+	if err != nil || betAmount <= 0 {
+		sendErrorResponse(s, i, "有効なベット額を入力してください。")
+		return
+	}
+	if rejectGamblingBet(s, i, c.Store, c.Log, betAmount) {
+		return
+	}
+
+	casinoData, err := c.Store.GetCasinoData(i.GuildID, userID)
+	if err != nil {
+		c.Log.Error("Failed to get casino data for blackjack table", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+	if casinoData.Chips < betAmount {
+		sendErrorResponse(s, i, fmt.Sprintf("チップが足りません！現在の所持チップ: %d", casinoData.Chips))
+		return
+	}
+	casinoData.Chips -= betAmount
+	if err := c.Store.UpdateCasinoData(casinoData); err != nil {
+		c.Log.Error("Failed to update casino data on blackjack table bet", "error", err)
+		sendErrorResponse(s, i, "ベット処理中にエラーが発生しました。")
+		return
+	}
+	pendingBets.add(i.GuildID, userID, storage.GameBlackjack, betAmount)
+
+	if seat == nil {
+		seat = &TableSeat{UserID: userID}
+		table.Seats = append(table.Seats, seat)
+	}
+	seat.Bet = betAmount
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("✅ **%d** チップをベットしました。", betAmount),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	c.refresh(s, table)
+}
+
+func (c *BlackjackTableCommand) handleLeave(s *discordgo.Session, i *discordgo.InteractionCreate, table *BlackjackTable) {
+	userID := i.Member.User.ID
+	seat := table.seatOf(userID)
+	if seat == nil {
+		sendErrorResponse(s, i, "このテーブルに座っていません。")
+		return
+	}
+	if table.State != TableStateBetting {
+		sendErrorResponse(s, i, "ラウンド中は退席できません。")
+		return
+	}
+
+	if seat.Bet > 0 {
+		c.refund(table, seat)
+	}
+	for n, other := range table.Seats {
+		if other == seat {
+			table.Seats = append(table.Seats[:n], table.Seats[n+1:]...)
+			break
+		}
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: "✅ 退席しました。",
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	c.refresh(s, table)
+}
+
+// refund は、席のベットを返却します。
+func (c *BlackjackTableCommand) refund(table *BlackjackTable, seat *TableSeat) {
+	casinoData, err := c.Store.GetCasinoData(table.GuildID, seat.UserID)
+	if err != nil {
+		c.Log.Error("Failed to get casino data for blackjack table refund", "error", err)
+		return
+	}
+	casinoData.Chips += seat.Bet
+	if err := c.Store.UpdateCasinoData(casinoData); err != nil {
+		c.Log.Error("Failed to refund blackjack table bet", "error", err)
+		return
+	}
+	pendingBets.release(table.GuildID, seat.UserID, storage.GameBlackjack, seat.Bet)
+	seat.Bet = 0
+}
+
+// --- Round ---
+
+// shuffleShoe は、新しい検証可能な乱数でシューを切り直し、カットカードを差し込みます。
+func (c *BlackjackTableCommand) shuffleShoe(table *BlackjackTable) error {
+	round, err := c.Fair.NewRound(table.GuildID, table.OwnerID)
+	if err != nil {
+		return err
+	}
+	// 前のシューの乱数はもう使わないため、ラウンドを閉じる
+	table.Round.Close()
+	table.Round = round
+	table.Shoe = NewDeck(table.Rules.Decks)
+	ShuffleDeck(table.Shoe, round.Rand)
+	table.CutCard = len(table.Shoe) - int(float64(len(table.Shoe))*TableShoePenetration)
+	table.Shuffled = true
+	return nil
+}
+
+// draw は、シューの先頭から1枚配ります。
+func (c *BlackjackTableCommand) draw(table *BlackjackTable, hand *[]Card) {
+	if len(table.Shoe) == 0 {
+		// カットカードの後ろまで配り切った場合は、同じ乱数で新しいシューを作る
+		table.Shoe = NewDeck(table.Rules.Decks)
+		ShuffleDeck(table.Shoe, table.Round.Rand)
+	}
+	*hand = append(*hand, table.Shoe[0])
+	table.Shoe = table.Shoe[1:]
+}
+
+// schedule は、d の後に fn を呼ぶタイマーを設定します。前のタイマーは取り消されます。
+// fn は c.mu を取得した状態で呼ばれます。
+func (c *BlackjackTableCommand) schedule(table *BlackjackTable, d time.Duration, fn func()) {
+	if table.timer != nil {
+		table.timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		// 止める前に発火したタイマーは無視する
+		if table.timer != timer {
+			return
+		}
+		table.timer = nil
+		fn()
+	})
+	table.timer = timer
+}
+
+// startRound は、ベットの受付を締め切って最初の2枚を配ります。
+func (c *BlackjackTableCommand) startRound(s *discordgo.Session, table *BlackjackTable) {
+	// ベットしなかったプレイヤーは退席させる
+	seats := table.Seats[:0]
+	for _, seat := range table.Seats {
+		if seat.Bet > 0 {
+			seats = append(seats, seat)
+		}
+	}
+	table.Seats = seats
+	if len(table.Seats) == 0 {
+		c.closeTable(s, table, "ベットがなかったため、テーブルを閉じました。")
+		return
+	}
+
+	table.Shuffled = false
+	// デッキの数が変わった場合も、新しいシューに切り直す
+	rules, err := loadBlackjackRules(c.Store, table.GuildID)
+	if err != nil {
+		c.Log.Error("Failed to get blackjack rules", "error", err)
+	} else {
+		if rules.Decks != table.Rules.Decks {
+			table.Shoe = nil
+		}
+		table.Rules = rules
+	}
+	if len(table.Shoe) <= table.CutCard {
+		if err := c.shuffleShoe(table); err != nil {
+			c.Log.Error("Failed to reshuffle blackjack table shoe", "error", err)
+		}
+	}
+
+	table.State = TableStatePlaying
+	table.Results = ""
+	table.Dealer = make([]Card, 0, 5)
+	for _, seat := range table.Seats {
+		seat.Hand = make([]Card, 0, 5)
+		seat.Doubled = false
+		seat.Done = false
+	}
+	for n := 0; n < 2; n++ {
+		for _, seat := range table.Seats {
+			c.draw(table, &seat.Hand)
+		}
+		c.draw(table, &table.Dealer)
+	}
+
+	if _, dealerBlackjack := CalculateHandValue(table.Dealer); dealerBlackjack {
+		c.finishRound(s, table)
+		return
+	}
+	for _, seat := range table.Seats {
+		if _, natural := CalculateHandValue(seat.Hand); natural {
+			seat.Done = true
+		}
+	}
+	table.Turn = -1
+	c.nextTurn(s, table)
+}
+
+// nextTurn は、まだ行動していない次の席に手番を回します。全員が終わっていればディーラーが引きます。
+func (c *BlackjackTableCommand) nextTurn(s *discordgo.Session, table *BlackjackTable) {
+	for n := table.Turn + 1; n < len(table.Seats); n++ {
+		if !table.Seats[n].Done {
+			table.Turn = n
+			c.startTurnTimer(s, table)
+			c.refresh(s, table)
+			return
+		}
+	}
+	c.finishRound(s, table)
+}
+
+// startTurnTimer は、行動中の席の制限時間を設定し直します。時間切れになると自動でスタンドします。
+func (c *BlackjackTableCommand) startTurnTimer(s *discordgo.Session, table *BlackjackTable) {
+	table.Deadline = time.Now().Add(TableTurnTimeout)
+	seat := table.Seats[table.Turn]
+	c.schedule(table, TableTurnTimeout, func() {
+		seat.Done = true
+		c.nextTurn(s, table)
+	})
+}
+
+func (c *BlackjackTableCommand) handleAction(s *discordgo.Session, i *discordgo.InteractionCreate, table *BlackjackTable) {
+	if table.State != TableStatePlaying {
+		sendErrorResponse(s, i, "今は操作できません。")
+		return
+	}
+	seat := table.Seats[table.Turn]
+	if seat.UserID != i.Member.User.ID {
+		sendErrorResponse(s, i, "あなたの番ではありません。")
+		return
+	}
+
+	switch i.MessageComponentData().CustomID {
+	case TableHitButton:
+		c.draw(table, &seat.Hand)
+		if value, _ := CalculateHandValue(seat.Hand); value >= 21 {
+			seat.Done = true
+		}
+	case TableStandButton:
+		seat.Done = true
+	case TableDoubleButton:
+		if len(seat.Hand) != 2 || !canDoubleOn(seat.Hand, table.Rules) {
+			sendErrorResponse(s, i, "この手札ではダブルダウンできません。")
+			return
+		}
+		if _, _, err := checkGamblingBet(c.Store, table.GuildID, seat.UserID, seat.Bet); err != nil {
+			c.Log.Warn("Blackjack table double down rejected by gambling limits", "error", err, "userID", seat.UserID)
+			sendErrorResponse(s, i, "賭けの制限により、ダブルダウンできません。")
+			return
+		}
+		casinoData, err := c.Store.GetCasinoData(table.GuildID, seat.UserID)
+		if err != nil {
+			c.Log.Error("Failed to get casino data for blackjack table double down", "error", err)
+			sendErrorResponse(s, i, "エラーが発生しました。")
+			return
+		}
+		if casinoData.Chips < seat.Bet {
+			sendErrorResponse(s, i, "ダブルダウンするためのチップが足りません。")
+			return
+		}
+		casinoData.Chips -= seat.Bet
+		if err := c.Store.UpdateCasinoData(casinoData); err != nil {
+			c.Log.Error("Failed to update casino data on blackjack table double down", "error", err)
+			sendErrorResponse(s, i, "ベット処理中にエラーが発生しました。")
+			return
+		}
+		pendingBets.add(table.GuildID, seat.UserID, storage.GameBlackjack, seat.Bet)
+		seat.Bet *= 2
+		seat.Doubled = true
+		c.draw(table, &seat.Hand)
+		seat.Done = true
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate})
+	if seat.Done {
+		c.nextTurn(s, table)
+		return
+	}
+	c.startTurnTimer(s, table)
+	c.refresh(s, table)
+}
+
+// finishRound は、ディーラーの手札を引き切って全員の勝敗を精算し、次のラウンドのベット受付を始めます。
+func (c *BlackjackTableCommand) finishRound(s *discordgo.Session, table *BlackjackTable) {
+	// 全員がバストしていればディーラーは引かない
+	for _, seat := range table.Seats {
+		if value, _ := CalculateHandValue(seat.Hand); value <= 21 {
+			for dealerShouldHit(table.Dealer, table.Rules) {
+				c.draw(table, &table.Dealer)
+			}
+			break
+		}
+	}
+
+	dealerValue, _ := CalculateHandValue(table.Dealer)
+	var results strings.Builder
+	fmt.Fprintf(&results, "ディーラー (%d): %s\n", dealerValue, HandToString(table.Dealer, false))
+	for _, seat := range table.Seats {
+		payout, resultText := calculateHandResult(seat.Hand, table.Dealer, seat.Bet, table.Rules, false)
+		if payout > 0 {
+			casinoData, err := c.Store.GetCasinoData(table.GuildID, seat.UserID)
+			if err == nil {
+				casinoData.Chips += payout
+				err = c.Store.UpdateCasinoData(casinoData)
+			}
+			if err != nil {
+				c.Log.Error("Failed to pay out blackjack table winnings", "error", err, "userID", seat.UserID)
+			}
+		}
+		value, natural := CalculateHandValue(seat.Hand)
+		fmt.Fprintf(&results, "<@%s> (%d): %s `%+d`\n", seat.UserID, value, resultText, payout-seat.Bet)
+
+		recordGameRound(c.Store, c.Log, table.GuildID, seat.UserID, storage.GameBlackjack, seat.Bet, payout)
+		progress := map[string]int64{counterChipsWon: payout}
+		if payout > seat.Bet {
+			progress[counterBlackjackWins] = 1
+			if natural {
+				progress[counterBlackjackNatural] = 1
+			}
+		}
+		recordAchievements(s, c.Store, c.Log, table.ChannelID, table.GuildID, seat.UserID, progress)
+		seat.Bet = 0
+	}
+	table.Results = results.String()
+
+	if table.Closing {
+		c.closeTable(s, table, "テーブルは閉じられました。")
+		return
+	}
+	table.State = TableStateBetting
+	table.Deadline = time.Now().Add(TableBettingWindow)
+	c.schedule(table, TableBettingWindow, func() { c.startRound(s, table) })
+	c.refresh(s, table)
+}
+
+// closeTable は、受付中のベットを返却してテーブルを片付けます。
+func (c *BlackjackTableCommand) closeTable(s *discordgo.Session, table *BlackjackTable, reason string) {
+	if table.timer != nil {
+		table.timer.Stop()
+		table.timer = nil
+	}
+	table.State = TableStateBetting
+	for _, seat := range table.Seats {
+		if seat.Bet > 0 {
+			c.refund(table, seat)
+		}
+	}
+	delete(c.tables, table.ChannelID)
+	table.Round.Close()
+
+	embed := c.buildTableEmbed(table)
+	embed.Description = reason
+	embed.Color = 0x95a5a6 // Gray
+	var emptyComponents []discordgo.MessageComponent
+	if _, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:         table.MessageID,
+		Channel:    table.ChannelID,
+		Embeds:     &[]*discordgo.MessageEmbed{embed},
+		Components: &emptyComponents,
+	}); err != nil {
+		c.Log.Error("Failed to edit closed blackjack table message", "error", err)
+	}
+}
+
+// --- Rendering ---
+
+// refresh は、テーブルのメッセージを現在の状態に更新します。
+// インタラクションのトークンは15分で切れるため、チャンネルのメッセージとして編集します。
+func (c *BlackjackTableCommand) refresh(s *discordgo.Session, table *BlackjackTable) {
+	components := c.buildTableComponents(table)
+	if _, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:         table.MessageID,
+		Channel:    table.ChannelID,
+		Embeds:     &[]*discordgo.MessageEmbed{c.buildTableEmbed(table)},
+		Components: &components,
+	}); err != nil {
+		c.Log.Error("Failed to edit blackjack table message", "error", err)
+	}
+}
+
+func (c *BlackjackTableCommand) buildTableEmbed(table *BlackjackTable) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title: "🃏 ブラックジャックテーブル",
+		Color: 0x000000,
+	}
+
+	switch table.State {
+	case TableStateBetting:
+		embed.Description = fmt.Sprintf("ベット受付中！ <t:%d:R> に締め切ります。\n「ベットして着席」から参加してください。", table.Deadline.Unix())
+		embed.Color = 0x3498db // Blue
+		if table.Results != "" {
+			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "前回の結果", Value: table.Results})
+		}
+	case TableStatePlaying:
+		seat := table.Seats[table.Turn]
+		embed.Description = fmt.Sprintf("<@%s> の番です。<t:%d:R> までに操作しないと自動でスタンドします。", seat.UserID, table.Deadline.Unix())
+		if table.Shuffled {
+			embed.Description += "\n🔀 カットカードが出たため、シューを切り直しました。"
+		}
+		upCard, _ := CalculateHandValue(table.Dealer[1:])
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  fmt.Sprintf("ディーラーの手札 (%d)", upCard),
+			Value: HandToString(table.Dealer, true),
+		})
+	}
+
+	var seats strings.Builder
+	for n, seat := range table.Seats {
+		fmt.Fprintf(&seats, "**%d.** <@%s>", n+1, seat.UserID)
+		switch {
+		case table.State == TableStateBetting && seat.Bet == 0:
+			seats.WriteString(" — ベット待ち")
+		case table.State == TableStateBetting:
+			fmt.Fprintf(&seats, " — ベット: `%d`", seat.Bet)
+		default:
+			value, _ := CalculateHandValue(seat.Hand)
+			fmt.Fprintf(&seats, " — ベット: `%d`\n%s (%d)", seat.Bet, HandToString(seat.Hand, false), value)
+			if seat.Doubled {
+				seats.WriteString(" ダブルダウン")
+			}
+			if n == table.Turn {
+				seats.WriteString(" ◀️")
+			}
+		}
+		seats.WriteString("\n")
+	}
+	if seats.Len() == 0 {
+		seats.WriteString("まだ誰も座っていません。")
+	}
+	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+		Name:  fmt.Sprintf("席 (%d/%d)", len(table.Seats), table.MaxSeats),
+		Value: seats.String(),
+	})
+	embed.Footer = &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("シューの残り: %d 枚 | %s", len(table.Shoe), table.Round.Footer())}
+	return embed
+}
+
+func (c *BlackjackTableCommand) buildTableComponents(table *BlackjackTable) []discordgo.MessageComponent {
+	if table.State == TableStateBetting {
+		return []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{Label: "ベットして着席", Style: discordgo.SuccessButton, CustomID: TableBetButton},
+					discordgo.Button{Label: "退席", Style: discordgo.SecondaryButton, CustomID: TableLeaveButton},
+				},
+			},
+		}
+	}
+	hand := table.Seats[table.Turn].Hand
+	canDouble := len(hand) == 2 && canDoubleOn(hand, table.Rules)
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{Label: "ヒット", Style: discordgo.SuccessButton, CustomID: TableHitButton},
+				discordgo.Button{Label: "スタンド", Style: discordgo.DangerButton, CustomID: TableStandButton},
+				discordgo.Button{Label: "ダブルダウン", Style: discordgo.PrimaryButton, CustomID: TableDoubleButton, Disabled: !canDouble},
+			},
+		},
+	}
+}
@@ -0,0 +1,366 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"luna/interfaces"
+	"luna/storage"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// casinoExcludeConfirmPrefix は、自己排除の確認ボタンのカスタムIDの接頭辞です。後ろに日数が続きます。
+const casinoExcludeConfirmPrefix = "casino_exclude_confirm:"
+
+// CasinoCommand handles the /casino command.
+type CasinoCommand struct {
+	Store interfaces.DataStore
+	Log   interfaces.Logger
+}
+
+func (c *CasinoCommand) GetCommandDef() *discordgo.ApplicationCommand {
+	limitOption := func(name, description string) *discordgo.ApplicationCommandOption {
+		return &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        name,
+			Description: description + " (0で無制限)",
+			MinValue:    &[]float64{0}[0],
+		}
+	}
+	return &discordgo.ApplicationCommand{
+		Name:        "casino",
+		Description: "ギャンブルとの付き合い方を管理します。",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "limits",
+				Description: "賭けの上限とクールダウンを設定します。何も指定しなければ現在の設定を表示します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					limitOption("daily_loss", "24時間で負けてもよい上限"),
+					limitOption("weekly_loss", "7日間で負けてもよい上限"),
+					limitOption("daily_wager", "24時間で賭けられる上限"),
+					limitOption("weekly_wager", "7日間で賭けられる上限"),
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "cooldown_minutes",
+						Description: "1回遊ぶごとに休む時間 (分, 0でなし)",
+						MinValue:    &[]float64{0}[0],
+						MaxValue:    24 * 60,
+					},
+				},
+			},
+			{
+				Name:        "exclude",
+				Description: "一定期間、自分をカジノゲームから締め出します。期間は短縮できません。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "duration",
+						Description: "自己排除の期間",
+						Required:    true,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "1日", Value: 1},
+							{Name: "1週間", Value: 7},
+							{Name: "1か月", Value: 30},
+							{Name: "3か月", Value: 90},
+							{Name: "6か月", Value: 180},
+							{Name: "1年", Value: 365},
+						},
+					},
+				},
+			},
+			{
+				Name:        "exclusions",
+				Description: "[管理者] 自己排除中のメンバーを表示します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+		},
+	}
+}
+
+func (c *CasinoCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	subcommand := i.ApplicationCommandData().Options[0]
+	switch subcommand.Name {
+	case "limits":
+		c.handleLimits(s, i, subcommand.Options)
+	case "exclude":
+		c.handleExclude(s, i, subcommand.Options[0].IntValue())
+	case "exclusions":
+		c.handleExclusions(s, i)
+	}
+}
+
+// formatLimit は、上限の表示用の文字列を返します。
+func formatLimit(used, limit int64) string {
+	if limit <= 0 {
+		return fmt.Sprintf("%d / 無制限", used)
+	}
+	return fmt.Sprintf("%d / %d", used, limit)
+}
+
+func (c *CasinoCommand) handleLimits(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	userID := i.Member.User.ID
+	limits, err := c.Store.GetGamblingLimits(i.GuildID, userID)
+	if err != nil {
+		c.Log.Error("Failed to get gambling limits", "error", err)
+		sendErrorResponse(s, i, "設定の取得に失敗しました。")
+		return
+	}
+
+	for _, opt := range options {
+		switch opt.Name {
+		case "daily_loss":
+			limits.DailyLossLimit = opt.IntValue()
+		case "weekly_loss":
+			limits.WeeklyLossLimit = opt.IntValue()
+		case "daily_wager":
+			limits.DailyWagerLimit = opt.IntValue()
+		case "weekly_wager":
+			limits.WeeklyWagerLimit = opt.IntValue()
+		case "cooldown_minutes":
+			limits.Cooldown = time.Duration(opt.IntValue()) * time.Minute
+		}
+	}
+	if len(options) > 0 {
+		if err := c.Store.SetGamblingLimits(limits); err != nil {
+			c.Log.Error("Failed to save gambling limits", "error", err)
+			sendErrorResponse(s, i, "設定の保存に失敗しました。")
+			return
+		}
+	}
+
+	now := time.Now()
+	activity, err := c.Store.GetGamblingActivity(i.GuildID, userID, now)
+	if err != nil {
+		c.Log.Error("Failed to get gambling activity", "error", err)
+		sendErrorResponse(s, i, "設定の取得に失敗しました。")
+		return
+	}
+
+	title := "🛡️ 賭けの制限"
+	if len(options) > 0 {
+		title = "🛡️ 賭けの制限を更新しました"
+	}
+	cooldown := "なし"
+	if limits.Cooldown > 0 {
+		cooldown = formatDuration(limits.Cooldown)
+	}
+	embed := &discordgo.MessageEmbed{
+		Title:       title,
+		Description: "損失は負けた額から勝った額を引いた値です。1日・1週間は直近24時間・直近7日間で数えます。",
+		Color:       0x1abc9c, // Teal
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "24時間の損失", Value: formatLimit(max(activity.DailyLost, 0), limits.DailyLossLimit), Inline: true},
+			{Name: "7日間の損失", Value: formatLimit(max(activity.WeeklyLost, 0), limits.WeeklyLossLimit), Inline: true},
+			{Name: "24時間の賭け金", Value: formatLimit(activity.DailyWagered, limits.DailyWagerLimit), Inline: true},
+			{Name: "7日間の賭け金", Value: formatLimit(activity.WeeklyWagered, limits.WeeklyWagerLimit), Inline: true},
+			{Name: "クールダウン", Value: cooldown, Inline: true},
+		},
+	}
+	if limits.Excluded(now) {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "🚫 自己排除中",
+			Value: fmt.Sprintf("<t:%d:f> まで", limits.ExcludedUntil.Time.Unix()),
+		})
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+func (c *CasinoCommand) handleExclude(s *discordgo.Session, i *discordgo.InteractionCreate, days int64) {
+	until := time.Now().AddDate(0, 0, int(days))
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("⚠️ **<t:%d:f>** まで、このサーバーのカジノゲームで一切賭けられなくなります。\n"+
+				"自己排除は管理者にも解除・短縮できません。本当によろしいですか？", until.Unix()),
+			Flags: discordgo.MessageFlagsEphemeral,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{
+							Label:    "自己排除する",
+							Style:    discordgo.DangerButton,
+							CustomID: casinoExcludeConfirmPrefix + strconv.FormatInt(days, 10),
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+func (c *CasinoCommand) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	customID := i.MessageComponentData().CustomID
+	if !strings.HasPrefix(customID, casinoExcludeConfirmPrefix) {
+		return
+	}
+	days, err := strconv.Atoi(strings.TrimPrefix(customID, casinoExcludeConfirmPrefix))
+	if err != nil || days <= 0 {
+		return
+	}
+
+	until := time.Now().AddDate(0, 0, days)
+	content := fmt.Sprintf("🚫 <t:%d:f> まで自己排除しました。ゆっくり休んでくださいね。", until.Unix())
+	if err := c.Store.SelfExclude(i.GuildID, i.Member.User.ID, until); err != nil {
+		if errors.Is(err, storage.ErrExclusionTooShort) {
+			content = "❌ すでにそれより長い自己排除が設定されています。自己排除は短縮できません。"
+		} else {
+			c.Log.Error("Failed to self-exclude", "error", err)
+			content = "❌ 自己排除の設定中にエラーが発生しました。"
+		}
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+}
+
+func (c *CasinoCommand) handleExclusions(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !hasManageGuild(i) {
+		sendErrorResponse(s, i, "このコマンドを実行するにはサーバー管理権限が必要です。")
+		return
+	}
+	exclusions, err := c.Store.GetActiveExclusions(i.GuildID, time.Now())
+	if err != nil {
+		c.Log.Error("Failed to get active exclusions", "error", err)
+		sendErrorResponse(s, i, "自己排除の取得に失敗しました。")
+		return
+	}
+
+	description := "自己排除中のメンバーはいません。"
+	if len(exclusions) > 0 {
+		var sb strings.Builder
+		for _, e := range exclusions {
+			fmt.Fprintf(&sb, "<@%s> — <t:%d:f> まで (<t:%d:R>)\n", e.UserID, e.ExcludedUntil.Time.Unix(), e.ExcludedUntil.Time.Unix())
+		}
+		description = sb.String()
+	}
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("🚫 自己排除中のメンバー (%d人)", len(exclusions)),
+		Description: description,
+		Color:       0x95a5a6, // Gray
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// pendingWagerKey は、記録待ちの賭け金を数える単位です。
+type pendingWagerKey struct {
+	guildID, userID, game string
+}
+
+// pendingWagers は、チップを引き落としたものの、まだゲームの結果として記録していない賭け金です。
+// 競馬やブラックジャックのように賭け金をメモリで持つゲームは、引き落としたときに add し、
+// 記録 (recordGameRound) や返金のときに release します。預かりを使うゲームは保存先で数えるため使いません。
+type pendingWagers struct {
+	mu      sync.Mutex
+	amounts map[pendingWagerKey]int64
+}
+
+// pendingBets は、すべてのゲームで共有する記録待ちの賭け金です。
+var pendingBets = &pendingWagers{amounts: make(map[pendingWagerKey]int64)}
+
+// add は、game で引き落とした amount を記録待ちに加えます。
+func (p *pendingWagers) add(guildID, userID, game string, amount int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.amounts[pendingWagerKey{guildID, userID, game}] += amount
+}
+
+// release は、記録または返金した amount を記録待ちから外します。
+func (p *pendingWagers) release(guildID, userID, game string, amount int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := pendingWagerKey{guildID, userID, game}
+	if p.amounts[key] <= amount {
+		delete(p.amounts, key)
+		return
+	}
+	p.amounts[key] -= amount
+}
+
+// total は、ユーザーの記録待ちの賭け金をすべてのゲームで合計します。
+func (p *pendingWagers) total(guildID, userID string) int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var total int64
+	for key, amount := range p.amounts {
+		if key.guildID == guildID && key.userID == userID {
+			total += amount
+		}
+	}
+	return total
+}
+
+// checkGamblingBet は、ユーザーが bet チップを賭けられるかを確認し、触れた制限のエラーを返します。
+// 進行中のゲームの記録待ちの賭け金は、負ける可能性があるものとして賭け金と損失の両方に含めます。
+func checkGamblingBet(store interfaces.DataStore, guildID, userID string, bet int64) (*storage.GamblingLimits, *storage.GamblingActivity, error) {
+	now := time.Now()
+	limits, err := store.GetGamblingLimits(guildID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	activity, err := store.GetGamblingActivity(guildID, userID, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	pending := pendingBets.total(guildID, userID)
+	activity.DailyWagered += pending
+	activity.DailyLost += pending
+	activity.WeeklyWagered += pending
+	activity.WeeklyLost += pending
+	return limits, activity, limits.Check(activity, bet, now)
+}
+
+// rejectGamblingBet は、賭け金 bet が自己排除や賭けの制限に触れる場合にエラーを返信して true を返します。
+// カジノゲームはチップを引き落とす前にこれを呼び出します。
+func rejectGamblingBet(s *discordgo.Session, i *discordgo.InteractionCreate, store interfaces.DataStore, log interfaces.Logger, bet int64) bool {
+	limits, activity, err := checkGamblingBet(store, i.GuildID, i.Member.User.ID, bet)
+	var message string
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, storage.ErrSelfExcluded):
+		message = fmt.Sprintf("自己排除中のため、<t:%d:f> まで賭けることはできません。", limits.ExcludedUntil.Time.Unix())
+	case errors.Is(err, storage.ErrCasinoCooldown):
+		message = fmt.Sprintf("クールダウン中です。<t:%d:R> から再び賭けられます。", activity.LastRoundAt.Time.Add(limits.Cooldown).Unix())
+	case errors.Is(err, storage.ErrDailyWagerLimit):
+		message = fmt.Sprintf("24時間の賭け金の上限に達します。残り: %d チップ", max(limits.DailyWagerLimit-activity.DailyWagered, 0))
+	case errors.Is(err, storage.ErrWeeklyWagerLimit):
+		message = fmt.Sprintf("7日間の賭け金の上限に達します。残り: %d チップ", max(limits.WeeklyWagerLimit-activity.WeeklyWagered, 0))
+	case errors.Is(err, storage.ErrDailyLossLimit):
+		message = fmt.Sprintf("24時間の損失の上限に達する可能性があります。賭けられるのはあと %d チップまでです。", max(limits.DailyLossLimit-activity.DailyLost, 0))
+	case errors.Is(err, storage.ErrWeeklyLossLimit):
+		message = fmt.Sprintf("7日間の損失の上限に達する可能性があります。賭けられるのはあと %d チップまでです。", max(limits.WeeklyLossLimit-activity.WeeklyLost, 0))
+	default:
+		log.Error("Failed to check gambling limits", "error", err)
+		message = "エラーが発生しました。"
+	}
+	sendErrorResponse(s, i, message+"\n`/casino limits` で設定を確認できます。")
+	return true
+}
+
+func (c *CasinoCommand) HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate) {}
+func (c *CasinoCommand) GetComponentIDs() []string                                        { return []string{casinoExcludeConfirmPrefix} }
+func (c *CasinoCommand) GetCategory() string                                              { return "カジノ" }
@@ -0,0 +1,595 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"luna/fair"
+	"luna/interfaces"
+	"luna/storage"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ルーレットのコンポーネントのID
+const (
+	RouletteBetSelectID    = "rl_bet_select"
+	RouletteSpinButtonID   = "rl_spin"
+	RouletteBetModalPrefix = "rl_bet_modal:"
+)
+
+// ルーレットの設定
+const (
+	RouletteBettingWindow  = 45 * time.Second // ベットを受け付ける時間
+	RouletteHistorySize    = 20               // 出目の履歴に残す数
+	MaxRouletteBetsPerUser = 10               // 1回のスピンに1人が置けるベットの数
+)
+
+// rouletteRedNumbers は、ヨーロピアンルーレットの赤の数字です。
+var rouletteRedNumbers = map[int]bool{
+	1: true, 3: true, 5: true, 7: true, 9: true, 12: true, 14: true, 16: true, 18: true,
+	19: true, 21: true, 23: true, 25: true, 27: true, 30: true, 32: true, 34: true, 36: true,
+}
+
+// rouletteBetKind は、ルーレットの賭け方です。
+// 内側のベットは Numbers 個の数字を指定し、外側のベットは Covers で当たりを判定します。
+type rouletteBetKind struct {
+	ID      string
+	Label   string
+	Pays    int64 // 賭け金に対する利益の倍率
+	Numbers int
+	Example string
+	Covers  func(n int) bool
+}
+
+var rouletteBetKinds = []rouletteBetKind{
+	{ID: "straight", Label: "ストレートアップ (1つの数字)", Pays: 35, Numbers: 1, Example: "17"},
+	{ID: "split", Label: "スプリット (隣り合う2つ)", Pays: 17, Numbers: 2, Example: "17 20"},
+	{ID: "street", Label: "ストリート (横一列の3つ)", Pays: 11, Numbers: 3, Example: "13 14 15"},
+	{ID: "corner", Label: "コーナー (四角の4つ)", Pays: 8, Numbers: 4, Example: "17 18 20 21"},
+	{ID: "sixline", Label: "シックスライン (横二列の6つ)", Pays: 5, Numbers: 6, Example: "13 14 15 16 17 18"},
+	{ID: "dozen1", Label: "第1ダズン (1〜12)", Pays: 2, Covers: func(n int) bool { return n >= 1 && n <= 12 }},
+	{ID: "dozen2", Label: "第2ダズン (13〜24)", Pays: 2, Covers: func(n int) bool { return n >= 13 && n <= 24 }},
+	{ID: "dozen3", Label: "第3ダズン (25〜36)", Pays: 2, Covers: func(n int) bool { return n >= 25 && n <= 36 }},
+	{ID: "column1", Label: "第1コラム (1, 4, 7…34)", Pays: 2, Covers: func(n int) bool { return n > 0 && n%3 == 1 }},
+	{ID: "column2", Label: "第2コラム (2, 5, 8…35)", Pays: 2, Covers: func(n int) bool { return n > 0 && n%3 == 2 }},
+	{ID: "column3", Label: "第3コラム (3, 6, 9…36)", Pays: 2, Covers: func(n int) bool { return n > 0 && n%3 == 0 }},
+	{ID: "red", Label: "赤", Pays: 1, Covers: func(n int) bool { return rouletteRedNumbers[n] }},
+	{ID: "black", Label: "黒", Pays: 1, Covers: func(n int) bool { return n > 0 && !rouletteRedNumbers[n] }},
+	{ID: "odd", Label: "奇数", Pays: 1, Covers: func(n int) bool { return n%2 == 1 }},
+	{ID: "even", Label: "偶数", Pays: 1, Covers: func(n int) bool { return n > 0 && n%2 == 0 }},
+	{ID: "low", Label: "ロー (1〜18)", Pays: 1, Covers: func(n int) bool { return n >= 1 && n <= 18 }},
+	{ID: "high", Label: "ハイ (19〜36)", Pays: 1, Covers: func(n int) bool { return n >= 19 && n <= 36 }},
+}
+
+// findRouletteBetKind は、ID の賭け方を返します。見つからなければ nil を返します。
+func findRouletteBetKind(id string) *rouletteBetKind {
+	for n := range rouletteBetKinds {
+		if rouletteBetKinds[n].ID == id {
+			return &rouletteBetKinds[n]
+		}
+	}
+	return nil
+}
+
+// rouletteInsideBets は、内側のベットで選べる数字の組み合わせを、数字の数ごとにまとめたものです。
+var rouletteInsideBets = buildRouletteInsideBets()
+
+// buildRouletteInsideBets は、3列12段のレイアウトと0から、内側のベットで選べる組み合わせを作ります。
+func buildRouletteInsideBets() map[int]map[string]bool {
+	bets := map[int][][]int{
+		2: {{0, 1}, {0, 2}, {0, 3}},
+		3: {{0, 1, 2}, {0, 2, 3}},
+		4: {{0, 1, 2, 3}},
+	}
+	for n := 0; n <= 36; n++ {
+		bets[1] = append(bets[1], []int{n})
+	}
+	for n := 1; n <= 36; n++ {
+		if n%3 != 0 {
+			bets[2] = append(bets[2], []int{n, n + 1})
+		}
+		if n <= 33 {
+			bets[2] = append(bets[2], []int{n, n + 3})
+		}
+		if n%3 != 0 && n <= 32 {
+			bets[4] = append(bets[4], []int{n, n + 1, n + 3, n + 4})
+		}
+		if n%3 == 1 {
+			bets[3] = append(bets[3], []int{n, n + 1, n + 2})
+			if n <= 31 {
+				bets[6] = append(bets[6], []int{n, n + 1, n + 2, n + 3, n + 4, n + 5})
+			}
+		}
+	}
+
+	sets := make(map[int]map[string]bool, len(bets))
+	for count, combinations := range bets {
+		sets[count] = make(map[string]bool, len(combinations))
+		for _, numbers := range combinations {
+			sets[count][fmt.Sprint(numbers)] = true
+		}
+	}
+	return sets
+}
+
+// parseRouletteNumbers は、入力された数字が賭け方に合う組み合わせかを確認し、小さい順に並べて返します。
+func parseRouletteNumbers(kind *rouletteBetKind, input string) ([]int, bool) {
+	fields := strings.FieldsFunc(input, func(r rune) bool { return r == ' ' || r == ',' || r == '、' || r == '-' })
+	numbers := make([]int, 0, len(fields))
+	for _, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, false
+		}
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	return numbers, len(numbers) == kind.Numbers && rouletteInsideBets[kind.Numbers][fmt.Sprint(numbers)]
+}
+
+// rouletteNumber は、出目を色付きで表示します。
+func rouletteNumber(n int) string {
+	switch {
+	case n == 0:
+		return "🟢 0"
+	case rouletteRedNumbers[n]:
+		return fmt.Sprintf("🔴 %d", n)
+	default:
+		return fmt.Sprintf("⚫ %d", n)
+	}
+}
+
+// RouletteBet は、1つのベットです。
+type RouletteBet struct {
+	UserID  string
+	Kind    *rouletteBetKind
+	Numbers []int // 内側のベットで選んだ数字
+	Amount  int64
+}
+
+// Wins は、出目 n でベットが当たるかを返します。
+func (b RouletteBet) Wins(n int) bool {
+	if b.Kind.Covers != nil {
+		return b.Kind.Covers(n)
+	}
+	for _, number := range b.Numbers {
+		if number == n {
+			return true
+		}
+	}
+	return false
+}
+
+// String は、ベットの内容を表示用に返します。
+func (b RouletteBet) String() string {
+	if len(b.Numbers) == 0 {
+		return b.Kind.Label
+	}
+	numbers := make([]string, len(b.Numbers))
+	for n, number := range b.Numbers {
+		numbers[n] = strconv.Itoa(number)
+	}
+	label, _, _ := strings.Cut(b.Kind.Label, " (")
+	return fmt.Sprintf("%s %s", label, strings.Join(numbers, "-"))
+}
+
+// RouletteGame は、チャンネルで1回分のスピンのベットを受け付けるゲームです。
+type RouletteGame struct {
+	GuildID     string
+	ChannelID   string
+	CreatorID   string
+	Interaction *discordgo.Interaction
+	Bets        []RouletteBet
+	Deadline    time.Time
+	Spinning    bool
+	Round       *fair.Round // 開始したユーザーのシードで決まる出目の乱数
+	timer       *time.Timer
+}
+
+// CasinoRouletteCommand は、/casino-roulette コマンドを処理します。
+type CasinoRouletteCommand struct {
+	Store   interfaces.DataStore
+	Log     interfaces.Logger
+	Fair    *fair.Service
+	games   map[string]*RouletteGame // channelID -> game
+	history map[string][]int         // channelID -> 最近の出目 (新しい順)
+	mu      sync.Mutex
+}
+
+func NewCasinoRouletteCommand(store interfaces.DataStore, log interfaces.Logger, fairRNG *fair.Service) *CasinoRouletteCommand {
+	return &CasinoRouletteCommand{
+		Store:   store,
+		Log:     log,
+		Fair:    fairRNG,
+		games:   make(map[string]*RouletteGame),
+		history: make(map[string][]int),
+	}
+}
+
+func (c *CasinoRouletteCommand) GetCommandDef() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "casino-roulette",
+		Description: "ヨーロピアンルーレットのベット受付を開始します！",
+	}
+}
+
+func (c *CasinoRouletteCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.games[i.ChannelID]; exists {
+		sendErrorResponse(s, i, "このチャンネルでは既にルーレットが進行中です。")
+		return
+	}
+
+	round, err := c.Fair.NewRound(i.GuildID, i.Member.User.ID)
+	if err != nil {
+		c.Log.Error("Failed to start fair round for roulette", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+
+	game := &RouletteGame{
+		GuildID:     i.GuildID,
+		ChannelID:   i.ChannelID,
+		CreatorID:   i.Member.User.ID,
+		Interaction: i.Interaction,
+		Deadline:    time.Now().Add(RouletteBettingWindow),
+		Round:       round,
+	}
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{c.buildBettingEmbed(game)},
+			Components: c.buildBettingComponents(),
+		},
+	})
+	if err != nil {
+		c.Log.Error("Failed to send roulette message", "error", err)
+		round.Close()
+		return
+	}
+	c.games[i.ChannelID] = game
+	game.timer = time.AfterFunc(RouletteBettingWindow, func() { c.spin(s, game) })
+}
+
+func (c *CasinoRouletteCommand) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	game, exists := c.games[i.ChannelID]
+	if !exists || game.Spinning {
+		sendErrorResponse(s, i, "ベット受付は終了しました。")
+		return
+	}
+
+	switch i.MessageComponentData().CustomID {
+	case RouletteBetSelectID:
+		c.handleBetSelect(s, i)
+	case RouletteSpinButtonID:
+		if i.Member.User.ID != game.CreatorID {
+			sendErrorResponse(s, i, "ホイールを回せるのは、ルーレットを開始した本人だけです。")
+			return
+		}
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate})
+		game.timer.Stop()
+		go c.spin(s, game)
+	}
+}
+
+func (c *CasinoRouletteCommand) HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	game, exists := c.games[i.ChannelID]
+	if !exists || game.Spinning {
+		sendErrorResponse(s, i, "ベット受付は終了しました。")
+		return
+	}
+	c.handleBetModalSubmit(s, i, game)
+}
+
+func (c *CasinoRouletteCommand) GetComponentIDs() []string {
+	return []string{RouletteBetSelectID, RouletteSpinButtonID, RouletteBetModalPrefix}
+}
+
+func (c *CasinoRouletteCommand) GetCategory() string {
+	return "カジノ"
+}
+
+// --- Betting ---
+
+func (c *CasinoRouletteCommand) handleBetSelect(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	values := i.MessageComponentData().Values
+	if len(values) == 0 {
+		return
+	}
+	kind := findRouletteBetKind(values[0])
+	if kind == nil {
+		sendErrorResponse(s, i, "不明な賭け方です。")
+		return
+	}
+
+	var rows []discordgo.MessageComponent
+	if kind.Numbers > 0 {
+		rows = append(rows, discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.TextInput{
+					CustomID:    "numbers",
+					Label:       fmt.Sprintf("賭ける数字 (%d個、スペース区切り)", kind.Numbers),
+					Style:       discordgo.TextInputShort,
+					Placeholder: kind.Example,
+					Required:    true,
+				},
+			},
+		})
+	}
+	rows = append(rows, discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.TextInput{
+				CustomID:    "bet_amount",
+				Label:       "ベットするチップの額",
+				Style:       discordgo.TextInputShort,
+				Placeholder: "100",
+				Required:    true,
+			},
+		},
+	})
+
+	title, _, _ := strings.Cut(kind.Label, " (")
+	modal := discordgo.InteractionResponseData{
+		CustomID:   RouletteBetModalPrefix + kind.ID,
+		Title:      fmt.Sprintf("%s にベット (%d:1)", title, kind.Pays),
+		Components: rows,
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseModal, Data: &modal})
+}
+
+func (c *CasinoRouletteCommand) handleBetModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate, game *RouletteGame) {
+	data := i.ModalSubmitData()
+	kind := findRouletteBetKind(strings.TrimPrefix(data.CustomID, RouletteBetModalPrefix))
+	if kind == nil {
+		sendErrorResponse(s, i, "不明な賭け方です。")
+		return
+	}
+
+	bet := RouletteBet{UserID: i.Member.User.ID, Kind: kind}
+	inputs := make(map[string]string)
+	for _, row := range data.Components {
+		for _, component := range row.(*discordgo.ActionsRow).Components {
+			input := component.(*discordgo.TextInput)
+			inputs[input.CustomID] = input.Value
+		}
+	}
+	if kind.Numbers > 0 {
+		numbers, ok := parseRouletteNumbers(kind, inputs["numbers"])
+		if !ok {
+			sendErrorResponse(s, i, fmt.Sprintf("盤面で%sになる %d 個の数字を入力してください。(例: %s)", kind.Label, kind.Numbers, kind.Example))
+			return
+		}
+		bet.Numbers = numbers
+	}
+	betAmount, err := strconv.ParseInt(strings.TrimSpace(inputs["bet_amount"]), 10, 64)
+	if err != nil || betAmount <= 0 {
+		sendErrorResponse(s, i, "有効なベット額を入力してください。")
+		return
+	}
+	bet.Amount = betAmount
+
+	placed := 0
+	for _, other := range game.Bets {
+		if other.UserID == bet.UserID {
+			placed++
+		}
+	}
+	if placed >= MaxRouletteBetsPerUser {
+		sendErrorResponse(s, i, fmt.Sprintf("1回のスピンに置けるベットは %d 個までです。", MaxRouletteBetsPerUser))
+		return
+	}
+	if rejectGamblingBet(s, i, c.Store, c.Log, betAmount) {
+		return
+	}
+
+	// 賭け金はスピンの精算まで預かりに移し、再起動で失われないようにする
+	_, err = c.Store.HoldEscrow(i.GuildID, bet.UserID, storage.EscrowRoulette, game.ChannelID, betAmount)
+	switch {
+	case errors.Is(err, storage.ErrInsufficientFunds):
+		sendErrorResponse(s, i, "チップが足りません！")
+		return
+	case errors.Is(err, storage.ErrAccountFrozen):
+		sendErrorResponse(s, i, "あなたの口座は管理者によって凍結されています。")
+		return
+	case err != nil:
+		c.Log.Error("Failed to hold roulette bet", "error", err)
+		sendErrorResponse(s, i, "ベット処理中にエラーが発生しました。")
+		return
+	}
+	game.Bets = append(game.Bets, bet)
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("✅ **%s** に **%d** チップをベットしました。", bet, betAmount),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	embed := c.buildBettingEmbed(game)
+	if _, err := s.InteractionResponseEdit(game.Interaction, &discordgo.WebhookEdit{Embeds: &[]*discordgo.MessageEmbed{embed}}); err != nil {
+		c.Log.Error("Failed to edit roulette betting embed", "error", err)
+	}
+}
+
+// --- Spin ---
+
+// spin は、ベットを締め切ってホイールを回し、全員の配当を精算します。
+func (c *CasinoRouletteCommand) spin(s *discordgo.Session, game *RouletteGame) {
+	c.mu.Lock()
+	if game.Spinning {
+		c.mu.Unlock()
+		return
+	}
+	game.Spinning = true
+	c.mu.Unlock()
+
+	embed := &discordgo.MessageEmbed{
+		Title:       "🎡 ルーレット - ホイールが回っています…",
+		Description: "ノー・モア・ベット！",
+		Color:       0xf1c40f, // Yellow
+	}
+	var emptyComponents []discordgo.MessageComponent
+	if _, err := s.InteractionResponseEdit(game.Interaction, &discordgo.WebhookEdit{Embeds: &[]*discordgo.MessageEmbed{embed}, Components: &emptyComponents}); err != nil {
+		c.Log.Error("Failed to edit message for roulette spin", "error", err)
+	}
+	time.Sleep(3 * time.Second)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	number := game.Round.Rand.Intn(37)
+	history := append([]int{number}, c.history[game.ChannelID]...)
+	c.history[game.ChannelID] = history[:min(len(history), RouletteHistorySize)]
+
+	type userResult struct {
+		wagered, returned int64
+	}
+	results := make(map[string]*userResult)
+	var users []string
+	var lines strings.Builder
+	for _, bet := range game.Bets {
+		result, ok := results[bet.UserID]
+		if !ok {
+			result = &userResult{}
+			results[bet.UserID] = result
+			users = append(users, bet.UserID)
+		}
+		result.wagered += bet.Amount
+		if bet.Wins(number) {
+			payout := bet.Amount * (bet.Kind.Pays + 1)
+			result.returned += payout
+			fmt.Fprintf(&lines, "✅ <@%s> %s `%d` → **%d** チップ\n", bet.UserID, bet, bet.Amount, payout)
+		} else {
+			fmt.Fprintf(&lines, "❌ <@%s> %s `%d`\n", bet.UserID, bet, bet.Amount)
+		}
+	}
+
+	// 負けたユーザーの預かりも0に書き換えるため、ベットした全員の払い戻し額を渡す
+	payouts := make(map[string]int64, len(users))
+	for _, userID := range users {
+		payouts[userID] = results[userID].returned
+	}
+	if err := c.Store.SettleEscrows(game.GuildID, storage.EscrowRoulette, game.ChannelID, payouts); err != nil {
+		c.Log.Error("Failed to settle roulette bets", "error", err, "channelID", game.ChannelID)
+	}
+	for _, userID := range users {
+		result := results[userID]
+		recordGameRound(c.Store, c.Log, game.GuildID, userID, storage.GameRoulette, result.wagered, result.returned)
+		recordAchievements(s, c.Store, c.Log, game.ChannelID, game.GuildID, userID, map[string]int64{counterChipsWon: result.returned})
+	}
+
+	resultEmbed := &discordgo.MessageEmbed{
+		Title:  fmt.Sprintf("🎡 結果は %s！", rouletteNumber(number)),
+		Color:  0x2ecc71, // Green
+		Footer: &discordgo.MessageEmbedFooter{Text: game.Round.Footer() + " (開始者)"},
+	}
+	if lines.Len() == 0 {
+		lines.WriteString("誰もベットしていませんでした。")
+	}
+	resultEmbed.Fields = []*discordgo.MessageEmbedField{
+		{Name: "ベット結果", Value: truncateField(lines.String())},
+		{Name: "最近の出目", Value: c.historyBoard(game.ChannelID)},
+	}
+	if _, err := s.InteractionResponseEdit(game.Interaction, &discordgo.WebhookEdit{Embeds: &[]*discordgo.MessageEmbed{resultEmbed}}); err != nil {
+		c.Log.Error("Failed to edit final roulette result", "error", err)
+	}
+	delete(c.games, game.ChannelID)
+	game.Round.Close()
+}
+
+// --- Rendering ---
+
+// truncateField は、Embed のフィールドの上限 (1024文字) に収まるように行単位で切り詰めます。
+func truncateField(value string) string {
+	if len([]rune(value)) <= 1024 {
+		return value
+	}
+	var sb strings.Builder
+	for _, line := range strings.SplitAfter(value, "\n") {
+		if len([]rune(sb.String()+line)) > 1000 {
+			break
+		}
+		sb.WriteString(line)
+	}
+	sb.WriteString("…")
+	return sb.String()
+}
+
+// historyBoard は、チャンネルの最近の出目と、その中の赤・黒・0の数を表示します。
+func (c *CasinoRouletteCommand) historyBoard(channelID string) string {
+	history := c.history[channelID]
+	if len(history) == 0 {
+		return "まだ記録がありません。"
+	}
+	numbers := make([]string, len(history))
+	var red, black, zero int
+	for n, number := range history {
+		numbers[n] = rouletteNumber(number)
+		switch {
+		case number == 0:
+			zero++
+		case rouletteRedNumbers[number]:
+			red++
+		default:
+			black++
+		}
+	}
+	return fmt.Sprintf("%s\n赤 %d / 黒 %d / 0 %d (直近%d回、左が最新)", strings.Join(numbers, " "), red, black, zero, len(history))
+}
+
+func (c *CasinoRouletteCommand) buildBettingEmbed(game *RouletteGame) *discordgo.MessageEmbed {
+	var bets strings.Builder
+	for _, bet := range game.Bets {
+		fmt.Fprintf(&bets, "<@%s> %s `%d`\n", bet.UserID, bet, bet.Amount)
+	}
+	if bets.Len() == 0 {
+		bets.WriteString("まだベットはありません。")
+	}
+	return &discordgo.MessageEmbed{
+		Title:       "🎡 ルーレット - ベット受付中",
+		Description: fmt.Sprintf("<t:%d:R> にホイールを回します。下のメニューから賭け方を選んでください。\n0 が出ると外側のベット (赤/黒、ダズンなど) はすべて負けになります。", game.Deadline.Unix()),
+		Color:       0x3498db, // Blue
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "ベット", Value: truncateField(bets.String())},
+			{Name: "最近の出目", Value: c.historyBoard(game.ChannelID)},
+		},
+		Footer: &discordgo.MessageEmbedFooter{Text: game.Round.Footer() + " (開始者)"},
+	}
+}
+
+func (c *CasinoRouletteCommand) buildBettingComponents() []discordgo.MessageComponent {
+	options := make([]discordgo.SelectMenuOption, len(rouletteBetKinds))
+	for n, kind := range rouletteBetKinds {
+		options[n] = discordgo.SelectMenuOption{
+			Label:       kind.Label,
+			Value:       kind.ID,
+			Description: fmt.Sprintf("配当 %d:1", kind.Pays),
+		}
+	}
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{CustomID: RouletteBetSelectID, Placeholder: "賭け方を選択...", Options: options},
+		}},
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "ホイールを回す",
+				Style:    discordgo.SuccessButton,
+				CustomID: RouletteSpinButtonID,
+				Emoji:    &discordgo.ComponentEmoji{Name: "🎡"},
+			},
+		}},
+	}
+}
@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"errors"
+	"luna/storage"
+	"testing"
+)
+
+// メモリで持つ賭け金は、引き落としてから記録するまで制限の判定に含まれる
+func TestCheckGamblingBetCountsPendingWagers(t *testing.T) {
+	store := newTestStore(t)
+	if err := store.SetGamblingLimits(&storage.GamblingLimits{GuildID: "guild", UserID: "alice", DailyWagerLimit: 1000}); err != nil {
+		t.Fatalf("SetGamblingLimits: %v", err)
+	}
+
+	pendingBets.add("guild", "alice", storage.GameHorseRace, 600)
+	pendingBets.add("guild", "alice", storage.GameBlackjack, 300)
+	t.Cleanup(func() {
+		pendingBets.release("guild", "alice", storage.GameHorseRace, 600)
+		pendingBets.release("guild", "alice", storage.GameBlackjack, 300)
+	})
+
+	if _, _, err := checkGamblingBet(store, "guild", "alice", 101); !errors.Is(err, storage.ErrDailyWagerLimit) {
+		t.Errorf("checkGamblingBet(101) = %v, want ErrDailyWagerLimit", err)
+	}
+	if _, _, err := checkGamblingBet(store, "guild", "alice", 100); err != nil {
+		t.Errorf("checkGamblingBet(100) = %v, want nil", err)
+	}
+	if _, _, err := checkGamblingBet(store, "guild", "bob", 1000); err != nil {
+		t.Errorf("checkGamblingBet for another user = %v, want nil", err)
+	}
+
+	// 記録した賭け金は記録待ちから外れ、保存された履歴として数えられる
+	recordGameRound(store, testLogger{t}, "guild", "alice", storage.GameHorseRace, 600, 0)
+	if got := pendingBets.total("guild", "alice"); got != 300 {
+		t.Errorf("pending after record = %d, want 300", got)
+	}
+	if _, _, err := checkGamblingBet(store, "guild", "alice", 101); !errors.Is(err, storage.ErrDailyWagerLimit) {
+		t.Errorf("checkGamblingBet(101) after record = %v, want ErrDailyWagerLimit", err)
+	}
+}
@@ -2,7 +2,9 @@ package commands
 
 import (
 	"fmt"
+	"luna/fair"
 	"luna/interfaces"
+	"luna/storage"
 	"math/rand"
 	"time"
 
@@ -13,6 +15,7 @@ import (
 type CoinflipCommand struct {
 	Store interfaces.DataStore
 	Log   interfaces.Logger
+	Fair  *fair.Service
 }
 
 func (c *CoinflipCommand) GetCommandDef() *discordgo.ApplicationCommand {
@@ -46,6 +49,9 @@ func (c *CoinflipCommand) Handle(s *discordgo.Session, i *discordgo.InteractionC
 	choice := i.ApplicationCommandData().Options[1].StringValue()
 	userID := i.Member.User.ID
 	guildID := i.GuildID
+	if rejectGamblingBet(s, i, c.Store, c.Log, bet) {
+		return
+	}
 
 	casinoData, err := c.Store.GetCasinoData(guildID, userID)
 	if err != nil {
@@ -59,6 +65,14 @@ func (c *CoinflipCommand) Handle(s *discordgo.Session, i *discordgo.InteractionC
 		return
 	}
 
+	round, err := c.Fair.NewRound(guildID, userID)
+	if err != nil {
+		c.Log.Error("Failed to start fair round for coinflip", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+	defer round.Close()
+
 	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 	}); err != nil {
@@ -80,11 +94,7 @@ func (c *CoinflipCommand) Handle(s *discordgo.Session, i *discordgo.InteractionC
 	}
 	time.Sleep(3 * time.Second)
 
-	rand.Seed(time.Now().UnixNano())
-	result := "tails"
-	if rand.Intn(2) == 0 {
-		result = "heads"
-	}
+	result := flipCoin(round.Rand)
 
 	// First, subtract the bet amount
 	casinoData.Chips -= bet
@@ -102,7 +112,7 @@ func (c *CoinflipCommand) Handle(s *discordgo.Session, i *discordgo.InteractionC
 		return
 	}
 
-	resultEmbed := &discordgo.MessageEmbed{}
+	resultEmbed := &discordgo.MessageEmbed{Footer: &discordgo.MessageEmbedFooter{Text: round.Footer()}}
 	if won {
 		profit := bet
 		resultEmbed.Title = "🎉 勝利！"
@@ -128,6 +138,22 @@ func (c *CoinflipCommand) Handle(s *discordgo.Session, i *discordgo.InteractionC
 	if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Embeds: &[]*discordgo.MessageEmbed{resultEmbed}}); err != nil {
 		c.Log.Error("Failed to edit final coinflip response", "error", err)
 	}
+	returned := int64(0)
+	if won {
+		returned = bet * 2
+	}
+	recordGameRound(c.Store, c.Log, i.GuildID, i.Member.User.ID, storage.GameCoinflip, bet, returned)
+	if won {
+		recordAchievements(s, c.Store, c.Log, i.ChannelID, i.GuildID, i.Member.User.ID, map[string]int64{counterCoinflipWins: 1, counterChipsWon: bet * 2})
+	}
+}
+
+// flipCoin は、r からコインの表裏を決めます。
+func flipCoin(r *rand.Rand) string {
+	if r.Intn(2) == 0 {
+		return "heads"
+	}
+	return "tails"
 }
 
 func translateChoice(choice string) string {
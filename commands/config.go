@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"luna/interfaces"
 	"luna/storage"
+	"strings"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -51,6 +52,108 @@ func (c *ConfigCommand) GetCommandDef() *discordgo.ApplicationCommand {
 					{Type: discordgo.ApplicationCommandOptionRole, Name: "role", Description: "リマインド時にメンションするロール", Required: true},
 				},
 			},
+			{
+				Name:        "economy",
+				Description: "サーバーの経済バランスを設定します (指定しない項目は現在の値を維持)",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					economyIntOption("starting_chips", "新規ユーザーの初期チップ", storage.StartingChipsRange),
+					economyIntOption("daily_amount", "デイリーボーナスで貰えるPPC", storage.DailyAmountRange),
+					economyIntOption("daily_cooldown_hours", "デイリーボーナスの間隔 (時間)", storage.DailyCooldownHoursRange),
+					economyIntOption("ppc_to_chips_rate", "1 PPCあたりのチップ数 (変動レートの基準値)", storage.PpcToChipsRateRange),
+					economyIntOption("fishing_cost", "釣り1回の基本料金 (チップ)", storage.FishingCostRange),
+					{
+						Type:        discordgo.ApplicationCommandOptionNumber,
+						Name:        "jackpot_percent",
+						Description: "スロットの賭け金からジャックポットに積み立てる割合 (%)",
+						MinValue:    &[]float64{storage.JackpotContributionRange.Min * 100}[0],
+						MaxValue:    storage.JackpotContributionRange.Max * 100,
+					},
+					economyIntOption("weekly_amount", "ウィークリーボーナスで貰えるPPC", storage.WeeklyAmountRange),
+					economyIntOption("monthly_amount", "マンスリーボーナスで貰えるPPC", storage.MonthlyAmountRange),
+					economyIntOption("streak_bonus", "デイリーの連続日数1日ごとに増えるPPC", storage.StreakBonusRange),
+					economyIntOption("streak_max_days", "連続ボーナスが増え続ける最大日数", storage.StreakMaxDaysRange),
+					economyIntOption("streak_grace_hours", "連続記録が途切れるまでの猶予 (時間)", storage.StreakGraceHoursRange),
+					{
+						Type:        discordgo.ApplicationCommandOptionNumber,
+						Name:        "bank_interest_percent",
+						Description: "預金に1日ごとに付く利率 (%)",
+						MinValue:    &[]float64{storage.BankInterestRateRange.Min * 100}[0],
+						MaxValue:    storage.BankInterestRateRange.Max * 100,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionNumber,
+						Name:        "loan_interest_percent",
+						Description: "ローン1件あたりの利率 (%)",
+						MinValue:    &[]float64{storage.LoanInterestRateRange.Min * 100}[0],
+						MaxValue:    storage.LoanInterestRateRange.Max * 100,
+					},
+					economyIntOption("loan_term_days", "ローンの返済期限 (日)", storage.LoanTermDaysRange),
+					economyIntOption("loan_base_credit", "借入履歴のないユーザーの与信枠 (チップ)", storage.LoanBaseCreditRange),
+					{
+						Type:        discordgo.ApplicationCommandOptionNumber,
+						Name:        "exchange_spread_percent",
+						Description: "両替の買値と売値の差 (%)",
+						MinValue:    &[]float64{storage.ExchangeSpreadRange.Min * 100}[0],
+						MaxValue:    storage.ExchangeSpreadRange.Max * 100,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionNumber,
+						Name:        "slots_rtp_ceiling_percent",
+						Description: "スロットマシンに許される還元率 (RTP) の上限 (%)",
+						MinValue:    &[]float64{storage.SlotsRTPCeilingRange.Min * 100}[0],
+						MaxValue:    storage.SlotsRTPCeilingRange.Max * 100,
+					},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "timezone", Description: "報酬を0時にリセットするタイムゾーン (例: Asia/Tokyo, rolling で経過時間方式)"},
+					{Type: discordgo.ApplicationCommandOptionBoolean, Name: "reset", Description: "すべての項目を既定値に戻す"},
+				},
+			},
+			{
+				Name:        "blackjack",
+				Description: "ブラックジャックのルールを設定します (指定しない項目は現在の値を維持)",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					economyIntOption("decks", "シューに使うデッキの数", storage.BlackjackDecksRange),
+					{Type: discordgo.ApplicationCommandOptionBoolean, Name: "hit_soft_17", Description: "ディーラーがソフト17でヒットする"},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "blackjack_pays",
+						Description: "ブラックジャックの配当",
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "3:2", Value: storage.BlackjackPays3To2},
+							{Name: "6:5", Value: storage.BlackjackPays6To5},
+						},
+					},
+					{Type: discordgo.ApplicationCommandOptionBoolean, Name: "double_any_two", Description: "最初の2枚ならいつでもダブルダウンできる (無効の場合は合計9〜11のみ)"},
+					economyIntOption("max_hands", "スプリットで作れる手札の最大数", storage.BlackjackMaxHandsRange),
+					{Type: discordgo.ApplicationCommandOptionBoolean, Name: "resplit_aces", Description: "エースの再スプリットを認める"},
+					{Type: discordgo.ApplicationCommandOptionBoolean, Name: "late_surrender", Description: "レイトサレンダーを認める"},
+					{Type: discordgo.ApplicationCommandOptionBoolean, Name: "side_bets", Description: "サイドベット (パーフェクトペア, 21+3) を受け付ける"},
+					{Type: discordgo.ApplicationCommandOptionBoolean, Name: "reset", Description: "すべての項目を既定値に戻す"},
+				},
+			},
+			{
+				Name:        "instant-games",
+				Description: "クラッシュとマインズのハウスエッジを設定します (指定しない項目は現在の値を維持)",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionNumber,
+						Name:        "crash_house_edge_percent",
+						Description: "クラッシュのハウスエッジ (%)",
+						MinValue:    &[]float64{storage.HouseEdgeRange.Min * 100}[0],
+						MaxValue:    storage.HouseEdgeRange.Max * 100,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionNumber,
+						Name:        "mines_house_edge_percent",
+						Description: "マインズのハウスエッジ (%)",
+						MinValue:    &[]float64{storage.HouseEdgeRange.Min * 100}[0],
+						MaxValue:    storage.HouseEdgeRange.Max * 100,
+					},
+					{Type: discordgo.ApplicationCommandOptionBoolean, Name: "reset", Description: "すべての項目を既定値に戻す"},
+				},
+			},
 		},
 	}
 }
@@ -65,6 +168,12 @@ func (c *ConfigCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCre
 		c.handleTempVCConfig(s, i, options)
 	case "bump-reminder":
 		c.handleBumpConfig(s, i, options)
+	case "economy":
+		c.handleEconomyConfig(s, i, options)
+	case "blackjack":
+		c.handleBlackjackConfig(s, i, options)
+	case "instant-games":
+		c.handleInstantGamesConfig(s, i, options)
 	}
 }
 
@@ -112,6 +221,204 @@ func (c *ConfigCommand) handleBumpConfig(s *discordgo.Session, i *discordgo.Inte
 	}
 }
 
+// economyIntOption は、許容範囲つきの経済設定オプションを作成します。
+func economyIntOption(name, description string, rng storage.EconomyRange) *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionInteger,
+		Name:        name,
+		Description: fmt.Sprintf("%s (%g〜%g)", description, rng.Min, rng.Max),
+		MinValue:    &[]float64{rng.Min}[0],
+		MaxValue:    rng.Max,
+	}
+}
+
+func (c *ConfigCommand) handleEconomyConfig(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	config, err := c.Store.GetEconomyConfig(i.GuildID)
+	if err != nil {
+		c.Log.Error("経済設定の取得に失敗", "error", err, "guildID", i.GuildID)
+		sendErrorResponse(s, i, "設定の取得に失敗しました。")
+		return
+	}
+
+	for _, opt := range options {
+		switch opt.Name {
+		case "starting_chips":
+			config.StartingChips = opt.IntValue()
+		case "daily_amount":
+			config.DailyAmount = opt.IntValue()
+		case "daily_cooldown_hours":
+			config.DailyCooldownHours = opt.IntValue()
+		case "ppc_to_chips_rate":
+			config.PpcToChipsRate = opt.IntValue()
+		case "fishing_cost":
+			config.FishingCost = opt.IntValue()
+		case "jackpot_percent":
+			config.JackpotContribution = opt.FloatValue() / 100
+		case "weekly_amount":
+			config.WeeklyAmount = opt.IntValue()
+		case "monthly_amount":
+			config.MonthlyAmount = opt.IntValue()
+		case "streak_bonus":
+			config.StreakBonus = opt.IntValue()
+		case "streak_max_days":
+			config.StreakMaxDays = opt.IntValue()
+		case "streak_grace_hours":
+			config.StreakGraceHours = opt.IntValue()
+		case "bank_interest_percent":
+			config.BankInterestRate = opt.FloatValue() / 100
+		case "loan_interest_percent":
+			config.LoanInterestRate = opt.FloatValue() / 100
+		case "loan_term_days":
+			config.LoanTermDays = opt.IntValue()
+		case "loan_base_credit":
+			config.LoanBaseCredit = opt.IntValue()
+		case "exchange_spread_percent":
+			config.ExchangeSpread = opt.FloatValue() / 100
+		case "slots_rtp_ceiling_percent":
+			config.SlotsRTPCeiling = opt.FloatValue() / 100
+		case "timezone":
+			config.ResetTimezone = strings.TrimSpace(opt.StringValue())
+			if strings.EqualFold(config.ResetTimezone, "rolling") {
+				config.ResetTimezone = ""
+			}
+		case "reset":
+			if opt.BoolValue() {
+				config = &storage.EconomyConfig{}
+			}
+		}
+	}
+
+	// reset 時は空の設定を保存し、読み込み時に既定値が使われるようにする
+	if *config != (storage.EconomyConfig{}) {
+		if err := config.Validate(); err != nil {
+			sendErrorResponse(s, i, fmt.Sprintf("設定値が範囲外です: %v", err))
+			return
+		}
+	}
+	if err := c.Store.SaveConfig(i.GuildID, "economy_config", config); err != nil {
+		c.Log.Error("経済設定の保存に失敗", "error", err, "guildID", i.GuildID)
+		sendErrorResponse(s, i, "設定の保存に失敗しました。")
+		return
+	}
+
+	saved, err := c.Store.GetEconomyConfig(i.GuildID)
+	if err != nil {
+		c.Log.Error("経済設定の取得に失敗", "error", err, "guildID", i.GuildID)
+		sendErrorResponse(s, i, "設定の取得に失敗しました。")
+		return
+	}
+	reset := fmt.Sprintf("`%d` 時間ごと", saved.DailyCooldownHours)
+	if saved.ResetTimezone != "" {
+		reset = fmt.Sprintf("毎日0時 (`%s`)", saved.ResetTimezone)
+	}
+	content := fmt.Sprintf("✅ 経済設定を更新しました。\n- 初期チップ: `%d`\n- デイリーボーナス: `%d` PPC / %s\n- 連続ボーナス: 1日ごとに +`%d` PPC (最大 `%d` 日, 猶予 `%d` 時間)\n- ウィークリー / マンスリー: `%d` / `%d` PPC\n- 両替の基準レート: 1 PPC = `%d` チップ (スプレッド `%.1f%%`)\n- 釣り料金: `%d` チップ\n- ジャックポット積立: `%.1f%%`\n- スロットの還元率上限: `%.1f%%`\n- 預金金利: 日利 `%.2f%%`\n- ローン: 利率 `%.0f%%` / 期限 `%d` 日 / 基本与信枠 `%d` チップ",
+		saved.StartingChips, saved.DailyAmount, reset, saved.StreakBonus, saved.StreakMaxDays, saved.StreakGraceHours,
+		saved.WeeklyAmount, saved.MonthlyAmount, saved.PpcToChipsRate, saved.ExchangeSpread*100, saved.FishingCost, saved.JackpotContribution*100, saved.SlotsRTPCeiling*100,
+		saved.BankInterestRate*100, saved.LoanInterestRate*100, saved.LoanTermDays, saved.LoanBaseCredit)
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseChannelMessageWithSource, Data: &discordgo.InteractionResponseData{Content: content, Flags: discordgo.MessageFlagsEphemeral}}); err != nil {
+		c.Log.Error("Failed to respond to interaction", "error", err)
+	}
+}
+
+func (c *ConfigCommand) handleBlackjackConfig(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	rules, err := loadBlackjackRules(c.Store, i.GuildID)
+	if err != nil {
+		c.Log.Error("ブラックジャックのルールの取得に失敗", "error", err, "guildID", i.GuildID)
+		sendErrorResponse(s, i, "設定の取得に失敗しました。")
+		return
+	}
+
+	for _, opt := range options {
+		switch opt.Name {
+		case "decks":
+			rules.Decks = int(opt.IntValue())
+		case "hit_soft_17":
+			rules.HitSoft17 = opt.BoolValue()
+		case "blackjack_pays":
+			rules.BlackjackPays = opt.StringValue()
+		case "double_any_two":
+			rules.DoubleAnyTwo = opt.BoolValue()
+		case "max_hands":
+			rules.MaxHands = int(opt.IntValue())
+		case "resplit_aces":
+			rules.ResplitAces = opt.BoolValue()
+		case "late_surrender":
+			rules.NoSurrender = !opt.BoolValue()
+		case "side_bets":
+			rules.NoSideBets = !opt.BoolValue()
+		case "reset":
+			if opt.BoolValue() {
+				rules = &storage.BlackjackRules{}
+			}
+		}
+	}
+
+	// reset 時は空の設定を保存し、読み込み時に既定値が使われるようにする
+	if *rules != (storage.BlackjackRules{}) {
+		if err := rules.Validate(); err != nil {
+			sendErrorResponse(s, i, fmt.Sprintf("設定値が範囲外です: %v", err))
+			return
+		}
+	}
+	if err := c.Store.SaveConfig(i.GuildID, "blackjack_rules", rules); err != nil {
+		c.Log.Error("ブラックジャックのルールの保存に失敗", "error", err, "guildID", i.GuildID)
+		sendErrorResponse(s, i, "設定の保存に失敗しました。")
+		return
+	}
+
+	rules.ApplyDefaults()
+	sideBets := "受け付ける"
+	if rules.NoSideBets {
+		sideBets = "受け付けない"
+	}
+	content := fmt.Sprintf("✅ ブラックジャックのルールを更新しました。\n- ルール: %s\n- サイドベット: %s", rulesSummary(rules), sideBets)
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseChannelMessageWithSource, Data: &discordgo.InteractionResponseData{Content: content, Flags: discordgo.MessageFlagsEphemeral}}); err != nil {
+		c.Log.Error("Failed to respond to interaction", "error", err)
+	}
+}
+
+func (c *ConfigCommand) handleInstantGamesConfig(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	config, err := loadInstantGamesConfig(c.Store, i.GuildID)
+	if err != nil {
+		c.Log.Error("インスタントゲームの設定の取得に失敗", "error", err, "guildID", i.GuildID)
+		sendErrorResponse(s, i, "設定の取得に失敗しました。")
+		return
+	}
+
+	for _, opt := range options {
+		switch opt.Name {
+		case "crash_house_edge_percent":
+			config.CrashHouseEdge = opt.FloatValue() / 100
+		case "mines_house_edge_percent":
+			config.MinesHouseEdge = opt.FloatValue() / 100
+		case "reset":
+			if opt.BoolValue() {
+				config = &storage.InstantGamesConfig{}
+			}
+		}
+	}
+
+	// reset 時は空の設定を保存し、読み込み時に既定値が使われるようにする
+	if *config != (storage.InstantGamesConfig{}) {
+		if err := config.Validate(); err != nil {
+			sendErrorResponse(s, i, fmt.Sprintf("設定値が範囲外です: %v", err))
+			return
+		}
+	}
+	if err := c.Store.SaveConfig(i.GuildID, "instant_games_config", config); err != nil {
+		c.Log.Error("インスタントゲームの設定の保存に失敗", "error", err, "guildID", i.GuildID)
+		sendErrorResponse(s, i, "設定の保存に失敗しました。")
+		return
+	}
+
+	config.ApplyDefaults()
+	content := fmt.Sprintf("✅ インスタントゲームの設定を更新しました。\n- クラッシュのハウスエッジ: %.1f%% (還元率 %.1f%%)\n- マインズのハウスエッジ: %.1f%% (還元率 %.1f%%)",
+		config.CrashHouseEdge*100, (1-config.CrashHouseEdge)*100, config.MinesHouseEdge*100, (1-config.MinesHouseEdge)*100)
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseChannelMessageWithSource, Data: &discordgo.InteractionResponseData{Content: content, Flags: discordgo.MessageFlagsEphemeral}}); err != nil {
+		c.Log.Error("Failed to respond to interaction", "error", err)
+	}
+}
+
 func (c *ConfigCommand) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {}
 func (c *ConfigCommand) HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate)     {}
 func (c *ConfigCommand) GetComponentIDs() []string                                            { return []string{} }
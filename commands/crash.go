@@ -0,0 +1,387 @@
+package commands
+
+import (
+	"fmt"
+	"luna/fair"
+	"luna/interfaces"
+	"luna/storage"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	CrashCashoutID       = "crash_cashout"
+	CrashBettingWindow   = 15 * time.Second
+	CrashTickInterval    = 1500 * time.Millisecond // メッセージの編集間隔
+	CrashGrowthRate      = 0.08                    // 倍率は毎秒 e^0.08 倍 (約9秒で2倍) に上がる
+	CrashMaxMultiplier   = 1000.0
+	CrashMaxAutoCashout  = 1000.0
+	CrashMinAutoCashout  = 1.01
+	crashMultiplierScale = 100 // 倍率は 0.01 刻み
+)
+
+// CrashState はクラッシュのラウンドの状態を表します。
+type CrashState int
+
+const (
+	CrashStateBetting CrashState = iota
+	CrashStateRunning
+	CrashStateCrashed
+)
+
+// CrashBet は、1人のプレイヤーのクラッシュのベットです。
+type CrashBet struct {
+	UserID      string
+	Amount      int64
+	AutoCashout float64 // 0 なら自動キャッシュアウトしない
+	CashedOut   float64 // キャッシュアウトした倍率。0 ならまだ
+	Payout      int64
+	Settled     bool
+}
+
+// CrashRound は、チャンネルで共有するクラッシュの1ラウンドです。
+type CrashRound struct {
+	ID         string // 開始したインタラクションのID。預かりの ref にも使う
+	GuildID    string
+	ChannelID  string
+	MessageID  string
+	CreatorID  string
+	State      CrashState
+	Round      *fair.Round // 開始したユーザーのシードで決まるクラッシュ倍率
+	CrashPoint float64
+	HouseEdge  float64
+	Bets       []*CrashBet
+	LaunchAt   time.Time
+	Multiplier float64 // 最後に表示した倍率
+}
+
+// bet は、userID のベットを返します。ベットしていなければ nil を返します。
+func (r *CrashRound) bet(userID string) *CrashBet {
+	for _, b := range r.Bets {
+		if b.UserID == userID {
+			return b
+		}
+	}
+	return nil
+}
+
+// CrashCommand は /crash コマンドを処理します。
+type CrashCommand struct {
+	Store  interfaces.DataStore
+	Log    interfaces.Logger
+	Fair   *fair.Service
+	rounds map[string]*CrashRound // channelID -> round
+	mu     sync.Mutex
+}
+
+func NewCrashCommand(store interfaces.DataStore, log interfaces.Logger, fairRNG *fair.Service) *CrashCommand {
+	return &CrashCommand{
+		Store:  store,
+		Log:    log,
+		Fair:   fairRNG,
+		rounds: make(map[string]*CrashRound),
+	}
+}
+
+func (c *CrashCommand) GetCommandDef() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "crash",
+		Description: "上がり続ける倍率がクラッシュする前にキャッシュアウトしよう！",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionInteger, Name: "bet", Description: "ベットするチップの額", Required: true, MinValue: &[]float64{1}[0]},
+			{Type: discordgo.ApplicationCommandOptionNumber, Name: "auto_cashout", Description: "この倍率に達したら自動でキャッシュアウトする (例: 2.0)", MinValue: &[]float64{CrashMinAutoCashout}[0], MaxValue: CrashMaxAutoCashout},
+		},
+	}
+}
+
+// crashPoint は、r からクラッシュする倍率を決めます。
+// 倍率 m 以上まで上がる確率が (1 - ハウスエッジ) / m になるため、どの倍率でキャッシュアウトしても還元率は 1 - ハウスエッジです。
+func crashPoint(r *rand.Rand, houseEdge float64) float64 {
+	point := math.Floor((1-houseEdge)/(1-r.Float64())*crashMultiplierScale) / crashMultiplierScale
+	return math.Min(math.Max(point, 1), CrashMaxMultiplier)
+}
+
+// crashMultiplier は、発射から elapsed 経過したときの倍率です。
+func crashMultiplier(elapsed time.Duration) float64 {
+	return math.Floor(math.Exp(CrashGrowthRate*elapsed.Seconds())*crashMultiplierScale) / crashMultiplierScale
+}
+
+func (c *CrashCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var bet int64
+	var autoCashout float64
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "bet":
+			bet = opt.IntValue()
+		case "auto_cashout":
+			autoCashout = math.Floor(opt.FloatValue()*crashMultiplierScale) / crashMultiplierScale
+		}
+	}
+	userID := i.Member.User.ID
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	round, exists := c.rounds[i.ChannelID]
+	if exists && round.State != CrashStateBetting {
+		sendErrorResponse(s, i, "ラウンドが進行中です。クラッシュした後に次のラウンドでベットしてください。")
+		return
+	}
+	if exists && round.bet(userID) != nil {
+		sendErrorResponse(s, i, "このラウンドには既にベットしています。")
+		return
+	}
+
+	if exists {
+		if !holdInstantBet(s, i, c.Store, c.Log, storage.EscrowCrash, round.ID, bet) {
+			return
+		}
+		round.Bets = append(round.Bets, &CrashBet{UserID: userID, Amount: bet, AutoCashout: autoCashout})
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("✅ **%d** チップをベットしました。", bet),
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		c.refresh(s, round, nil)
+		return
+	}
+
+	config, err := loadInstantGamesConfig(c.Store, i.GuildID)
+	if err != nil {
+		c.Log.Error("Failed to get instant games config", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+	round = &CrashRound{
+		ID:        i.ID,
+		GuildID:   i.GuildID,
+		ChannelID: i.ChannelID,
+		CreatorID: userID,
+		State:     CrashStateBetting,
+		HouseEdge: config.CrashHouseEdge,
+		LaunchAt:  time.Now().Add(CrashBettingWindow),
+	}
+	if !holdInstantBet(s, i, c.Store, c.Log, storage.EscrowCrash, round.ID, bet) {
+		return
+	}
+	round.Round, err = c.Fair.NewRound(i.GuildID, userID)
+	if err != nil {
+		c.Log.Error("Failed to start fair round for crash", "error", err)
+		c.refundAll(round, []*CrashBet{{UserID: userID}})
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+	// クラッシュ倍率はベットを受け付ける前に決まっている
+	round.CrashPoint = crashPoint(round.Round.Rand, round.HouseEdge)
+	round.Bets = append(round.Bets, &CrashBet{UserID: userID, Amount: bet, AutoCashout: autoCashout})
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{c.buildEmbed(round)},
+		},
+	})
+	if err != nil {
+		c.Log.Error("Failed to send crash round", "error", err)
+		c.refundAll(round, round.Bets)
+		return
+	}
+	msg, err := s.InteractionResponse(i.Interaction)
+	if err != nil {
+		c.Log.Error("Failed to get crash round message", "error", err)
+		c.refundAll(round, round.Bets)
+		return
+	}
+	round.MessageID = msg.ID
+	c.rounds[i.ChannelID] = round
+	time.AfterFunc(CrashBettingWindow, func() { c.launch(s, round) })
+}
+
+// refundAll は、ラウンドを始められなかったときに bets の賭け金を返却します。
+func (c *CrashCommand) refundAll(round *CrashRound, bets []*CrashBet) {
+	round.Round.Close()
+	for _, b := range bets {
+		if _, err := c.Store.ReleaseEscrow(round.GuildID, b.UserID, storage.EscrowCrash, round.ID); err != nil {
+			c.Log.Error("Failed to refund crash bet", "error", err, "userID", b.UserID)
+		}
+	}
+}
+
+func (c *CrashCommand) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	round, exists := c.rounds[i.ChannelID]
+	if !exists || round.State != CrashStateRunning {
+		sendErrorResponse(s, i, "キャッシュアウトできるラウンドはありません。")
+		return
+	}
+	b := round.bet(i.Member.User.ID)
+	if b == nil {
+		sendErrorResponse(s, i, "このラウンドにはベットしていません。")
+		return
+	}
+	if b.Settled {
+		sendErrorResponse(s, i, "既に精算済みです。")
+		return
+	}
+	// 表示はコマ送りだが、キャッシュアウトの倍率は押した時点の経過時間で決める
+	multiplier := crashMultiplier(time.Since(round.LaunchAt))
+	if multiplier >= round.CrashPoint {
+		sendErrorResponse(s, i, "間に合いませんでした… 既にクラッシュしています。")
+		return
+	}
+	if err := c.cashOut(s, round, b, multiplier); err != nil {
+		sendErrorResponse(s, i, "精算中にエラーが発生しました。")
+		return
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("💰 **%s** でキャッシュアウトしました！ **%d** チップを獲得しました。", formatMultiplier(multiplier), b.Payout),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+func (c *CrashCommand) HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate) {}
+
+func (c *CrashCommand) GetComponentIDs() []string {
+	return []string{CrashCashoutID}
+}
+
+func (c *CrashCommand) GetCategory() string {
+	return "カジノ"
+}
+
+// --- Round Logic ---
+
+// cashOut は、ベットを multiplier 倍で精算します。
+func (c *CrashCommand) cashOut(s *discordgo.Session, round *CrashRound, b *CrashBet, multiplier float64) error {
+	payout := int64(float64(b.Amount) * multiplier)
+	if err := settleInstantBet(s, c.Store, c.Log, round.ChannelID, round.GuildID, b.UserID, storage.EscrowCrash, round.ID, storage.GameCrash, b.Amount, payout); err != nil {
+		return err
+	}
+	b.CashedOut, b.Payout, b.Settled = multiplier, payout, true
+	return nil
+}
+
+// launch は、ベットを締め切って倍率を上げ始め、クラッシュするまでメッセージを更新します。
+func (c *CrashCommand) launch(s *discordgo.Session, round *CrashRound) {
+	c.mu.Lock()
+	round.State = CrashStateRunning
+	round.LaunchAt = time.Now()
+	round.Multiplier = 1
+	components := c.buildComponents()
+	c.refresh(s, round, &components)
+	c.mu.Unlock()
+
+	ticker := time.NewTicker(CrashTickInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if c.tick(s, round) {
+			return
+		}
+	}
+}
+
+// tick は、倍率を進めて自動キャッシュアウトを処理します。クラッシュしたら true を返します。
+func (c *CrashCommand) tick(s *discordgo.Session, round *CrashRound) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	multiplier := math.Min(crashMultiplier(time.Since(round.LaunchAt)), round.CrashPoint)
+	for _, b := range round.Bets {
+		// 自動キャッシュアウトは、クラッシュ倍率が目標以上なら目標の倍率で成立する
+		if !b.Settled && b.AutoCashout > 0 && b.AutoCashout <= multiplier && b.AutoCashout <= round.CrashPoint {
+			if err := c.cashOut(s, round, b, b.AutoCashout); err != nil {
+				c.Log.Error("Failed to auto cash out crash bet", "error", err, "userID", b.UserID)
+			}
+		}
+	}
+	round.Multiplier = multiplier
+
+	if multiplier < round.CrashPoint {
+		c.refresh(s, round, nil)
+		return false
+	}
+
+	round.State = CrashStateCrashed
+	for _, b := range round.Bets {
+		if b.Settled {
+			continue
+		}
+		if err := settleInstantBet(s, c.Store, c.Log, round.ChannelID, round.GuildID, b.UserID, storage.EscrowCrash, round.ID, storage.GameCrash, b.Amount, 0); err != nil {
+			continue
+		}
+		b.Settled = true
+	}
+	delete(c.rounds, round.ChannelID)
+	round.Round.Close()
+	var emptyComponents []discordgo.MessageComponent
+	c.refresh(s, round, &emptyComponents)
+	return true
+}
+
+// --- Rendering ---
+
+// refresh は、ラウンドのメッセージを現在の状態に更新します。components が nil ならボタンはそのままです。
+func (c *CrashCommand) refresh(s *discordgo.Session, round *CrashRound, components *[]discordgo.MessageComponent) {
+	if _, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:         round.MessageID,
+		Channel:    round.ChannelID,
+		Embeds:     &[]*discordgo.MessageEmbed{c.buildEmbed(round)},
+		Components: components,
+	}); err != nil {
+		c.Log.Error("Failed to edit crash round message", "error", err)
+	}
+}
+
+func (c *CrashCommand) buildEmbed(round *CrashRound) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:  "🚀 クラッシュ",
+		Footer: &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("%s (開始者) / 還元率 %.1f%%", round.Round.Footer(), (1-round.HouseEdge)*100)},
+	}
+	switch round.State {
+	case CrashStateBetting:
+		embed.Description = fmt.Sprintf("ベット受付中！ `/crash` でこのラウンドにベットできます。\n🚀 発射: <t:%d:R>", round.LaunchAt.Unix())
+		embed.Color = 0x3498db // Blue
+	case CrashStateRunning:
+		embed.Description = fmt.Sprintf("# 🚀 %s\nクラッシュする前にキャッシュアウト！", formatMultiplier(round.Multiplier))
+		embed.Color = 0xf1c40f // Yellow
+	case CrashStateCrashed:
+		embed.Description = fmt.Sprintf("# 💥 %s でクラッシュ！", formatMultiplier(round.CrashPoint))
+		embed.Color = 0xe74c3c // Red
+	}
+
+	var players strings.Builder
+	for _, b := range round.Bets {
+		status := "⏳"
+		switch {
+		case b.CashedOut > 0:
+			status = fmt.Sprintf("✅ %s → **%d**", formatMultiplier(b.CashedOut), b.Payout)
+		case round.State == CrashStateCrashed:
+			status = "💥"
+		case b.AutoCashout > 0:
+			status = fmt.Sprintf("⏳ (自動 %s)", formatMultiplier(b.AutoCashout))
+		}
+		fmt.Fprintf(&players, "<@%s> `%d` %s\n", b.UserID, b.Amount, status)
+	}
+	embed.Fields = []*discordgo.MessageEmbedField{{Name: fmt.Sprintf("プレイヤー (%d人)", len(round.Bets)), Value: truncateField(players.String())}}
+	return embed
+}
+
+func (c *CrashCommand) buildComponents() []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{Label: "キャッシュアウト", Style: discordgo.SuccessButton, CustomID: CrashCashoutID, Emoji: &discordgo.ComponentEmoji{Name: "💰"}},
+		}},
+	}
+}
@@ -1,9 +1,10 @@
 package commands
 
 import (
-	"database/sql"
+	"errors"
 	"fmt"
 	"luna/interfaces"
+	"luna/storage"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -11,61 +12,196 @@ import (
 
 // DailyCommand handles the /daily command.
 type DailyCommand struct {
-	Store interfaces.DataStore
-	Log   interfaces.Logger
+	Store   interfaces.DataStore
+	Log     interfaces.Logger
+	Session *discordgo.Session // リマインダーの送信に使用
 }
 
 func (c *DailyCommand) GetCommandDef() *discordgo.ApplicationCommand {
 	return &discordgo.ApplicationCommand{
 		Name:        "daily",
-		Description: "1日1回、2000チップを受け取ります。",
+		Description: "1日1回、デイリーボーナスのPepeCoinを受け取ります。",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "reminder",
+				Description: "受け取れるようになったときの通知方法を設定します (指定時はボーナスを受け取りません)",
+				Required:    false,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "DMで通知", Value: storage.ReminderDM},
+					{Name: "このチャンネルで通知", Value: storage.ReminderChannel},
+					{Name: "通知しない", Value: "off"},
+				},
+			},
+		},
 	}
 }
 
 func (c *DailyCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if options := i.ApplicationCommandData().Options; len(options) > 0 {
+		c.handleReminder(s, i, options[0].StringValue())
+		return
+	}
+
 	userID := i.Member.User.ID
 	guildID := i.GuildID
 
-	casinoData, err := c.Store.GetCasinoData(guildID, userID)
+	economy, err := c.Store.GetEconomyConfig(guildID)
 	if err != nil {
-		c.Log.Error("Failed to get casino data for daily command", "error", err)
+		c.Log.Error("Failed to get economy config for daily command", "error", err)
 		sendErrorResponse(s, i, "エラーが発生しました。後でもう一度お試しください。")
 		return
 	}
 
-	// Check if the user is eligible for the daily reward
-	if casinoData.LastDaily.Valid && time.Since(casinoData.LastDaily.Time) < 24*time.Hour {
-		remaining := (24 * time.Hour) - time.Since(casinoData.LastDaily.Time)
-		embed := &discordgo.MessageEmbed{
-			Title:       "⏰ また後で！",
-			Description: fmt.Sprintf("次のデイリーチップが受け取れるまで、あと **%s** です。", formatDuration(remaining)),
-			Color:       0xf1c40f, // Yellow
-		}
-		sendEmbedResponse(s, i, embed)
+	status, err := c.Store.GetRewardStatus(guildID, userID)
+	if err != nil {
+		c.Log.Error("Failed to get reward status for daily command", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。後でもう一度お試しください。")
 		return
 	}
 
-	// Grant the daily chips
-		now := time.Now()
-	dailyAmount := int64(100) // Grant 100 PepeCoins
+	// Check if the user is eligible for the daily reward
+	now := time.Now()
+	periodStart, next := rewardPeriod(storage.RewardDaily, economy, status.LastDaily, now)
+	if !rewardClaimable(status.LastDaily, periodStart) {
+		c.sendWaitEmbed(s, i, next, status.DailyStreak)
+		return
+	}
 
-	casinoData.PepeCoinBalance += dailyAmount
-	casinoData.LastDaily = sql.NullTime{Time: now, Valid: true}
+	// Grant the daily PPC
+	streak := nextDailyStreak(status, economy, now)
+	dailyAmount := dailyRewardAmount(economy, streak)
 
-	if err := c.Store.UpdateCasinoData(casinoData); err != nil {
-		c.Log.Error("Failed to update casino data for daily command", "error", err)
+	if err := c.Store.ClaimReward(guildID, userID, storage.RewardDaily, dailyAmount, periodStart, streak); err != nil {
+		if errors.Is(err, storage.ErrAlreadyClaimed) {
+			c.sendWaitEmbed(s, i, next, status.DailyStreak)
+			return
+		}
+		c.Log.Error("Failed to claim daily reward", "error", err)
 		sendErrorResponse(s, i, "デイリーボーナスの受け取り中にエラーが発生しました。")
 		return
 	}
 
+	casinoData, err := c.Store.GetCasinoData(guildID, userID)
+	if err != nil {
+		c.Log.Error("Failed to get casino data for daily command", "error", err)
+	}
+
+	description := fmt.Sprintf("**%d PepeCoin (PPC)** を獲得しました！", dailyAmount)
+	if casinoData != nil {
+		description += fmt.Sprintf("\n現在のあなたのPPC: **%d**", casinoData.PepeCoinBalance)
+	}
+	if streak > 1 {
+		description += fmt.Sprintf("\n🔥 連続ボーナス: +%d PPC", dailyAmount-economy.DailyAmount)
+	} else if status.DailyStreak > 1 {
+		description += fmt.Sprintf("\n💔 連続記録 (%d日) が途切れてしまいました…", status.DailyStreak)
+	}
+
 	embed := &discordgo.MessageEmbed{
 		Title:       "🎉 デイリーボーナス！",
-		Description: fmt.Sprintf("**%d PepeCoin (PPC)** を獲得しました！\n現在のあなたのチップ: **%%d**", dailyAmount, casinoData.PepeCoinBalance),
+		Description: description,
 		Color:       0xffd700, // Gold
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "連続日数", Value: fmt.Sprintf("🔥 **%d** 日", streak), Inline: true},
+			{Name: "最高記録", Value: fmt.Sprintf("%d 日", max(streak, status.BestStreak)), Inline: true},
+		},
 	}
 	sendEmbedResponse(s, i, embed)
 }
 
+func (c *DailyCommand) sendWaitEmbed(s *discordgo.Session, i *discordgo.InteractionCreate, next time.Time, streak int64) {
+	embed := &discordgo.MessageEmbed{
+		Title:       "⏰ また後で！",
+		Description: fmt.Sprintf("次のデイリーボーナスが受け取れるまで、あと **%s** です。", formatDuration(time.Until(next))),
+		Color:       0xf1c40f, // Yellow
+		Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("現在の連続日数: %d日", streak)},
+	}
+	sendEmbedResponse(s, i, embed)
+}
+
+func (c *DailyCommand) handleReminder(s *discordgo.Session, i *discordgo.InteractionCreate, mode string) {
+	var message string
+	channelID := ""
+	switch mode {
+	case storage.ReminderDM:
+		message = "デイリーボーナスが受け取れるようになったらDMでお知らせします。"
+	case storage.ReminderChannel:
+		channelID = i.ChannelID
+		message = fmt.Sprintf("デイリーボーナスが受け取れるようになったら <#%s> でお知らせします。", channelID)
+	default:
+		mode = storage.ReminderOff
+		message = "デイリーボーナスのリマインダーを停止しました。"
+	}
+
+	if err := c.Store.SetDailyReminder(i.GuildID, i.Member.User.ID, mode, channelID); err != nil {
+		c.Log.Error("Failed to set daily reminder", "error", err)
+		sendErrorResponse(s, i, "リマインダーの設定に失敗しました。")
+		return
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: "🔔 " + message,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// SendReminders は、デイリーボーナスが受け取れるようになったユーザーに通知します。スケジューラから定期的に呼び出されます。
+func (c *DailyCommand) SendReminders() {
+	if c.Session == nil {
+		return
+	}
+	pending, err := c.Store.GetPendingDailyReminders()
+	if err != nil {
+		c.Log.Error("Failed to get pending daily reminders", "error", err)
+		return
+	}
+
+	now := time.Now()
+	configs := make(map[string]*storage.EconomyConfig)
+	for _, status := range pending {
+		economy, ok := configs[status.GuildID]
+		if !ok {
+			economy, err = c.Store.GetEconomyConfig(status.GuildID)
+			if err != nil {
+				c.Log.Error("Failed to get economy config for daily reminder", "error", err, "guildID", status.GuildID)
+				continue
+			}
+			configs[status.GuildID] = economy
+		}
+
+		periodStart, _ := rewardPeriod(storage.RewardDaily, economy, status.LastDaily, now)
+		if !rewardClaimable(status.LastDaily, periodStart) {
+			continue
+		}
+
+		message := "🎁 デイリーボーナスが受け取れるようになりました！`/daily` で受け取りましょう。"
+		if status.DailyStreak > 0 {
+			message += fmt.Sprintf(" (現在 🔥%d日連続)", status.DailyStreak)
+		}
+		switch status.Reminder {
+		case storage.ReminderDM:
+			channel, err := c.Session.UserChannelCreate(status.UserID)
+			if err == nil {
+				_, err = c.Session.ChannelMessageSend(channel.ID, message)
+			}
+			if err != nil {
+				c.Log.Warn("Failed to send daily reminder DM", "error", err, "userID", status.UserID)
+			}
+		case storage.ReminderChannel:
+			if _, err := c.Session.ChannelMessageSend(status.ReminderChannelID, fmt.Sprintf("<@%s> %s", status.UserID, message)); err != nil {
+				c.Log.Warn("Failed to send daily reminder", "error", err, "channelID", status.ReminderChannelID)
+			}
+		}
+
+		// 送信に失敗しても同じ期間に何度も通知しないよう、通知済みにする
+		if err := c.Store.MarkDailyReminded(status.GuildID, status.UserID); err != nil {
+			c.Log.Error("Failed to mark daily reminder as sent", "error", err)
+		}
+	}
+}
+
 // Helper function to format duration nicely
 func formatDuration(d time.Duration) string {
 	d = d.Round(time.Second)
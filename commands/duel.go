@@ -0,0 +1,603 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"luna/fair"
+	"luna/interfaces"
+	"luna/storage"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	DuelAcceptPrefix     = "duel_accept:"
+	DuelDeclinePrefix    = "duel_decline:"
+	DuelRPSPrefix        = "duel_rps:" // duel_rps:<デュエルID>:<手>
+	DuelChallengeTimeout = 60 * time.Second
+	DuelChoiceTimeout    = 30 * time.Second
+)
+
+// duelGame は、デュエルで遊べるゲームです。
+type duelGame struct {
+	ID    string
+	Name  string
+	Emoji string
+}
+
+var duelGames = []duelGame{
+	{ID: "coinflip", Name: "コイントス", Emoji: "🪙"},
+	{ID: "dice", Name: "サイコロ", Emoji: "🎲"},
+	{ID: "hilow", Name: "ハイ＆ロー", Emoji: "🔼"},
+	{ID: "rps", Name: "じゃんけん", Emoji: "✊"},
+}
+
+// findDuelGame は、ID のゲームを返します。見つからなければ nil を返します。
+func findDuelGame(id string) *duelGame {
+	for n := range duelGames {
+		if duelGames[n].ID == id {
+			return &duelGames[n]
+		}
+	}
+	return nil
+}
+
+// じゃんけんの手
+var rpsHands = []struct {
+	ID    string
+	Name  string
+	Emoji string
+	Beats string
+}{
+	{ID: "rock", Name: "グー", Emoji: "✊", Beats: "scissors"},
+	{ID: "scissors", Name: "チョキ", Emoji: "✌️", Beats: "paper"},
+	{ID: "paper", Name: "パー", Emoji: "🖐️", Beats: "rock"},
+}
+
+// rpsLabel は、じゃんけんの手を表示用に返します。
+func rpsLabel(hand string) string {
+	for _, h := range rpsHands {
+		if h.ID == hand {
+			return h.Emoji + " " + h.Name
+		}
+	}
+	return "—"
+}
+
+// DuelState はデュエルの状態を表します。
+type DuelState int
+
+const (
+	DuelStatePending  DuelState = iota // 相手の返事を待っている
+	DuelStateChoosing                  // じゃんけんの手を待っている
+	DuelStateFinished
+)
+
+// Duel は、2人のユーザーの1回のデュエルです。賭け金は挑戦した時点と受けた時点でそれぞれ預かりに移します。
+type Duel struct {
+	ID           string // 挑戦したインタラクションのID。預かりの ref にも使う
+	GuildID      string
+	ChannelID    string
+	MessageID    string
+	Game         *duelGame
+	ChallengerID string
+	OpponentID   string
+	Bet          int64
+	State        DuelState
+	Choices      map[string]string // userID -> じゃんけんの手
+	ExpiresAt    time.Time
+	Round        *fair.Round // 挑戦者のシードで勝負を決める乱数。じゃんけんでは nil
+	timer        *time.Timer
+}
+
+// DuelCommand は /duel コマンドを処理します。
+type DuelCommand struct {
+	Store interfaces.DataStore
+	Log   interfaces.Logger
+	Fair  *fair.Service
+	duels map[string]*Duel // duelID -> duel
+	mu    sync.Mutex
+}
+
+func NewDuelCommand(store interfaces.DataStore, log interfaces.Logger, fairRNG *fair.Service) *DuelCommand {
+	return &DuelCommand{
+		Store: store,
+		Log:   log,
+		Fair:  fairRNG,
+		duels: make(map[string]*Duel),
+	}
+}
+
+func (c *DuelCommand) GetCommandDef() *discordgo.ApplicationCommand {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, len(duelGames))
+	for n, game := range duelGames {
+		choices[n] = &discordgo.ApplicationCommandOptionChoice{Name: game.Emoji + " " + game.Name, Value: game.ID}
+	}
+	return &discordgo.ApplicationCommand{
+		Name:        "duel",
+		Description: "他のユーザーにチップを賭けた勝負を挑みます。",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionUser, Name: "user", Description: "勝負を挑む相手", Required: true},
+			{Type: discordgo.ApplicationCommandOptionString, Name: "game", Description: "勝負するゲーム", Required: true, Choices: choices},
+			{Type: discordgo.ApplicationCommandOptionInteger, Name: "bet", Description: "お互いに賭けるチップの額", Required: true, MinValue: &[]float64{1}[0]},
+		},
+	}
+}
+
+func (c *DuelCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	options := i.ApplicationCommandData().Options
+	opponent := options[0].UserValue(s)
+	game := findDuelGame(options[1].StringValue())
+	bet := options[2].IntValue()
+	challengerID := i.Member.User.ID
+
+	if opponent == nil || game == nil {
+		sendErrorResponse(s, i, "相手またはゲームの指定が正しくありません。")
+		return
+	}
+	if opponent.ID == challengerID {
+		sendErrorResponse(s, i, "自分自身に勝負を挑むことはできません。")
+		return
+	}
+	if opponent.Bot {
+		sendErrorResponse(s, i, "ボットに勝負を挑むことはできません。")
+		return
+	}
+	if rejectGamblingBet(s, i, c.Store, c.Log, bet) {
+		return
+	}
+
+	duel := &Duel{
+		ID:           i.ID,
+		GuildID:      i.GuildID,
+		ChannelID:    i.ChannelID,
+		Game:         game,
+		ChallengerID: challengerID,
+		OpponentID:   opponent.ID,
+		Bet:          bet,
+		Choices:      make(map[string]string),
+		ExpiresAt:    time.Now().Add(DuelChallengeTimeout),
+	}
+	// 返事を待つ間も賭け金を使えないように、挑戦した時点で預かりに移す
+	if !c.holdStake(s, i, duel, challengerID) {
+		return
+	}
+
+	embed := c.buildChallengeEmbed(duel)
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("<@%s>", opponent.ID),
+			Embeds:  []*discordgo.MessageEmbed{embed},
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.Button{Label: "受ける", Style: discordgo.SuccessButton, CustomID: DuelAcceptPrefix + duel.ID, Emoji: &discordgo.ComponentEmoji{Name: "⚔️"}},
+					discordgo.Button{Label: "断る", Style: discordgo.DangerButton, CustomID: DuelDeclinePrefix + duel.ID},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		c.Log.Error("Failed to send duel challenge", "error", err)
+		c.refund(duel)
+		return
+	}
+	msg, err := s.InteractionResponse(i.Interaction)
+	if err != nil {
+		c.Log.Error("Failed to get duel challenge message", "error", err)
+		c.refund(duel)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	duel.MessageID = msg.ID
+	duel.timer = time.AfterFunc(DuelChallengeTimeout, func() { c.expire(s, duel) })
+	c.duels[duel.ID] = duel
+}
+
+func (c *DuelCommand) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	customID := i.MessageComponentData().CustomID
+	var action, rest string
+	for _, prefix := range []string{DuelAcceptPrefix, DuelDeclinePrefix, DuelRPSPrefix} {
+		if strings.HasPrefix(customID, prefix) {
+			action, rest = prefix, strings.TrimPrefix(customID, prefix)
+		}
+	}
+	duelID, hand, _ := strings.Cut(rest, ":")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	duel, exists := c.duels[duelID]
+	if !exists {
+		sendErrorResponse(s, i, "このデュエルは既に終了しています。")
+		return
+	}
+
+	switch action {
+	case DuelAcceptPrefix:
+		c.handleAccept(s, i, duel)
+	case DuelDeclinePrefix:
+		c.handleDecline(s, i, duel)
+	case DuelRPSPrefix:
+		c.handleHand(s, i, duel, hand)
+	}
+}
+
+func (c *DuelCommand) HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate) {}
+
+func (c *DuelCommand) GetComponentIDs() []string {
+	return []string{DuelAcceptPrefix, DuelDeclinePrefix, DuelRPSPrefix}
+}
+
+func (c *DuelCommand) GetCategory() string {
+	return "カジノ"
+}
+
+// --- Handler Logic ---
+
+// holdStake は、userID の賭け金を預かりに移します。移せなかった場合はエラーを返信して false を返します。
+func (c *DuelCommand) holdStake(s *discordgo.Session, i *discordgo.InteractionCreate, duel *Duel, userID string) bool {
+	_, err := c.Store.HoldEscrow(duel.GuildID, userID, storage.EscrowDuel, duel.ID, duel.Bet)
+	switch {
+	case errors.Is(err, storage.ErrInsufficientFunds):
+		sendErrorResponse(s, i, fmt.Sprintf("チップが足りません！このデュエルには %d チップ必要です。", duel.Bet))
+		return false
+	case errors.Is(err, storage.ErrAccountFrozen):
+		sendErrorResponse(s, i, "あなたの口座は管理者によって凍結されています。")
+		return false
+	case err != nil:
+		c.Log.Error("Failed to hold duel stake", "error", err)
+		sendErrorResponse(s, i, "賭け金の処理中にエラーが発生しました。")
+		return false
+	}
+	return true
+}
+
+// refund は、デュエルの預かりをすべて返却します。
+func (c *DuelCommand) refund(duel *Duel) {
+	if err := c.Store.SettleEscrows(duel.GuildID, storage.EscrowDuel, duel.ID, nil); err != nil {
+		c.Log.Error("Failed to refund duel stakes", "error", err, "duelID", duel.ID)
+	}
+}
+
+func (c *DuelCommand) handleAccept(s *discordgo.Session, i *discordgo.InteractionCreate, duel *Duel) {
+	if i.Member.User.ID != duel.OpponentID {
+		sendErrorResponse(s, i, "このデュエルを受けられるのは、挑まれた本人だけです。")
+		return
+	}
+	if duel.State != DuelStatePending {
+		sendErrorResponse(s, i, "このデュエルは既に始まっています。")
+		return
+	}
+	if rejectGamblingBet(s, i, c.Store, c.Log, duel.Bet) {
+		return
+	}
+	// じゃんけん以外は、挑戦者の検証可能な乱数で勝負する
+	if duel.Game.ID != "rps" {
+		round, err := c.Fair.NewRound(duel.GuildID, duel.ChallengerID)
+		if err != nil {
+			c.Log.Error("Failed to start fair round for duel", "error", err)
+			sendErrorResponse(s, i, "エラーが発生しました。")
+			return
+		}
+		duel.Round = round
+	}
+	if !c.holdStake(s, i, duel, duel.OpponentID) {
+		duel.Round.Close()
+		duel.Round = nil
+		return
+	}
+	duel.timer.Stop()
+
+	if duel.Game.ID == "rps" {
+		duel.State = DuelStateChoosing
+		duel.ExpiresAt = time.Now().Add(DuelChoiceTimeout)
+		duel.timer = time.AfterFunc(DuelChoiceTimeout, func() { c.forfeit(s, duel) })
+		buttons := make([]discordgo.MessageComponent, len(rpsHands))
+		for n, hand := range rpsHands {
+			buttons[n] = discordgo.Button{Label: hand.Name, Style: discordgo.PrimaryButton, CustomID: DuelRPSPrefix + duel.ID + ":" + hand.ID, Emoji: &discordgo.ComponentEmoji{Name: hand.Emoji}}
+		}
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Embeds:     []*discordgo.MessageEmbed{c.buildChoosingEmbed(duel)},
+				Components: []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}},
+			},
+		})
+		return
+	}
+
+	embed := c.finish(s, duel)
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+}
+
+func (c *DuelCommand) handleDecline(s *discordgo.Session, i *discordgo.InteractionCreate, duel *Duel) {
+	userID := i.Member.User.ID
+	if userID != duel.OpponentID && userID != duel.ChallengerID {
+		sendErrorResponse(s, i, "このデュエルの参加者ではありません。")
+		return
+	}
+	if duel.State != DuelStatePending {
+		sendErrorResponse(s, i, "このデュエルは既に始まっています。")
+		return
+	}
+	duel.timer.Stop()
+	duel.State = DuelStateFinished
+	delete(c.duels, duel.ID)
+	c.refund(duel)
+
+	reason := fmt.Sprintf("<@%s> がデュエルを断りました。", duel.OpponentID)
+	if userID == duel.ChallengerID {
+		reason = fmt.Sprintf("<@%s> がデュエルを取り下げました。", duel.ChallengerID)
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{c.buildClosedEmbed(duel, reason+"賭け金は返却されました。")},
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+}
+
+func (c *DuelCommand) handleHand(s *discordgo.Session, i *discordgo.InteractionCreate, duel *Duel, hand string) {
+	userID := i.Member.User.ID
+	if userID != duel.OpponentID && userID != duel.ChallengerID {
+		sendErrorResponse(s, i, "このデュエルの参加者ではありません。")
+		return
+	}
+	if duel.State != DuelStateChoosing {
+		sendErrorResponse(s, i, "まだ手を選ぶことはできません。")
+		return
+	}
+	if _, chosen := duel.Choices[userID]; chosen {
+		sendErrorResponse(s, i, "既に手を選んでいます。")
+		return
+	}
+	duel.Choices[userID] = hand
+
+	if len(duel.Choices) < 2 {
+		// 相手に手が見えないよう、選んだ手は本人にだけ伝える
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("%s を選びました。相手の手を待っています…", rpsLabel(hand)),
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		c.editMessage(s, duel, c.buildChoosingEmbed(duel), nil)
+		return
+	}
+
+	duel.timer.Stop()
+	embed := c.finish(s, duel)
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+}
+
+// expire は、期限までに返事がなかった挑戦を取り消し、挑戦者の賭け金を返却します。
+func (c *DuelCommand) expire(s *discordgo.Session, duel *Duel) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if duel.State != DuelStatePending {
+		return
+	}
+	duel.State = DuelStateFinished
+	delete(c.duels, duel.ID)
+	c.refund(duel)
+
+	var emptyComponents []discordgo.MessageComponent
+	c.editMessage(s, duel, c.buildClosedEmbed(duel, fmt.Sprintf("<@%s> から返事がなかったため、デュエルは期限切れになりました。賭け金は返却されました。", duel.OpponentID)), &emptyComponents)
+}
+
+// forfeit は、じゃんけんの手を期限までに選ばなかったプレイヤーを負けにします。
+// どちらも選ばなかった場合は、引き分けとして賭け金を返却します。
+func (c *DuelCommand) forfeit(s *discordgo.Session, duel *Duel) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if duel.State != DuelStateChoosing {
+		return
+	}
+	var emptyComponents []discordgo.MessageComponent
+	c.editMessage(s, duel, c.finish(s, duel), &emptyComponents)
+}
+
+// --- Game Logic ---
+
+// playDuel は、デュエルの勝負をして勝者 (0: 挑戦者, 1: 相手, -1: 引き分け) と勝負の内容を返します。
+// じゃんけんは choices の手で、それ以外のゲームは r で勝負します。
+func playDuel(game string, r *rand.Rand, choices [2]string) (int, string) {
+	switch game {
+	case "coinflip":
+		// 挑戦者が表、相手が裏
+		side := flipCoin(r)
+		if side == "heads" {
+			return 0, "コインは **表** でした (表: 挑戦者 / 裏: 相手)。"
+		}
+		return 1, "コインは **裏** でした (表: 挑戦者 / 裏: 相手)。"
+	case "dice":
+		var totals [2]int
+		var rolls [2]string
+		for n := range totals {
+			a, b := r.Intn(6)+1, r.Intn(6)+1
+			totals[n] = a + b
+			rolls[n] = fmt.Sprintf("🎲 %d + %d = **%d**", a, b, a+b)
+		}
+		return compareDuel(totals[0], totals[1]), rolls[0] + " / " + rolls[1]
+	case "hilow":
+		first, second := drawHiLowCards(r)
+		return compareDuel(first, second), fmt.Sprintf("🃏 **%d** / 🃏 **%d**", first, second)
+	case "rps":
+		detail := rpsLabel(choices[0]) + " / " + rpsLabel(choices[1])
+		switch {
+		case choices[0] == "" && choices[1] == "":
+			return -1, "どちらも手を選びませんでした。"
+		case choices[1] == "":
+			return 0, detail + " (時間切れ)"
+		case choices[0] == "":
+			return 1, detail + " (時間切れ)"
+		}
+		for _, hand := range rpsHands {
+			if hand.ID == choices[0] && hand.Beats == choices[1] {
+				return 0, detail
+			}
+			if hand.ID == choices[1] && hand.Beats == choices[0] {
+				return 1, detail
+			}
+		}
+		return -1, detail
+	}
+	return -1, ""
+}
+
+// compareDuel は、大きい方を勝者として返します。同じなら引き分けです。
+func compareDuel(a, b int) int {
+	switch {
+	case a > b:
+		return 0
+	case b > a:
+		return 1
+	}
+	return -1
+}
+
+// finish は、デュエルの勝負をして賭け金を精算し、結果の Embed を返します。
+func (c *DuelCommand) finish(s *discordgo.Session, duel *Duel) *discordgo.MessageEmbed {
+	duel.State = DuelStateFinished
+	delete(c.duels, duel.ID)
+
+	var r *rand.Rand
+	if duel.Round != nil {
+		r = duel.Round.Rand
+	}
+	players := [2]string{duel.ChallengerID, duel.OpponentID}
+	winner, detail := playDuel(duel.Game.ID, r, [2]string{duel.Choices[players[0]], duel.Choices[players[1]]})
+	duel.Round.Close()
+
+	returned := [2]int64{duel.Bet, duel.Bet}
+	var amounts map[string]int64
+	if winner >= 0 {
+		returned[winner], returned[1-winner] = duel.Bet*2, 0
+		amounts = map[string]int64{players[winner]: duel.Bet * 2, players[1-winner]: 0}
+	}
+	if err := c.Store.SettleEscrows(duel.GuildID, storage.EscrowDuel, duel.ID, amounts); err != nil {
+		c.Log.Error("Failed to settle duel", "error", err, "duelID", duel.ID)
+		return c.buildClosedEmbed(duel, "精算中にエラーが発生しました。賭け金はボットの再起動時に返却されます。")
+	}
+
+	if winner >= 0 {
+		err := c.Store.RecordDuel(duel.GuildID, players[winner], players[1-winner], false)
+		if err != nil {
+			c.Log.Error("Failed to record duel", "error", err)
+		}
+		recordAchievements(s, c.Store, c.Log, duel.ChannelID, duel.GuildID, players[winner], map[string]int64{counterChipsWon: duel.Bet * 2})
+	} else if err := c.Store.RecordDuel(duel.GuildID, players[0], players[1], true); err != nil {
+		c.Log.Error("Failed to record duel", "error", err)
+	}
+	for n, userID := range players {
+		recordGameRound(c.Store, c.Log, duel.GuildID, userID, storage.GameDuel, duel.Bet, returned[n])
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:  fmt.Sprintf("⚔️ デュエル結果 - %s %s", duel.Game.Emoji, duel.Game.Name),
+		Color:  0x2ecc71, // Green
+		Fields: []*discordgo.MessageEmbedField{{Name: "勝負", Value: fmt.Sprintf("<@%s> vs <@%s>\n%s", players[0], players[1], detail)}},
+	}
+	if winner >= 0 {
+		embed.Description = fmt.Sprintf("🏆 <@%s> の勝ち！ **%d** チップを獲得しました。", players[winner], duel.Bet*2)
+	} else {
+		embed.Description = "🤝 引き分けです。賭け金は返却されました。"
+		embed.Color = 0x95a5a6 // Gray
+	}
+	embed.Fields = append(embed.Fields, c.recordField(duel))
+	if duel.Round != nil {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: duel.Round.Footer()}
+	}
+	return embed
+}
+
+// --- Rendering ---
+
+// editMessage は、デュエルのメッセージを更新します。components が nil ならボタンはそのままです。
+func (c *DuelCommand) editMessage(s *discordgo.Session, duel *Duel, embed *discordgo.MessageEmbed, components *[]discordgo.MessageComponent) {
+	if _, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:         duel.MessageID,
+		Channel:    duel.ChannelID,
+		Embeds:     &[]*discordgo.MessageEmbed{embed},
+		Components: components,
+	}); err != nil {
+		c.Log.Error("Failed to edit duel message", "error", err)
+	}
+}
+
+// recordField は、2人の対戦成績を挑戦者から見た形で返します。
+func (c *DuelCommand) recordField(duel *Duel) *discordgo.MessageEmbedField {
+	value := "まだ対戦したことがありません。"
+	record, err := c.Store.GetDuelRecord(duel.GuildID, duel.ChallengerID, duel.OpponentID)
+	if err != nil {
+		c.Log.Error("Failed to get duel record", "error", err)
+		value = "取得できませんでした。"
+	} else if wins, losses, draws := record.For(duel.ChallengerID); wins+losses+draws > 0 {
+		value = fmt.Sprintf("<@%s> **%d** 勝 - **%d** 勝 <@%s> (引き分け %d)", duel.ChallengerID, wins, losses, duel.OpponentID, draws)
+	}
+	return &discordgo.MessageEmbedField{Name: "対戦成績", Value: value}
+}
+
+func (c *DuelCommand) buildChallengeEmbed(duel *Duel) *discordgo.MessageEmbed {
+	return &discordgo.MessageEmbed{
+		Title:       "⚔️ デュエルの挑戦",
+		Description: fmt.Sprintf("<@%s> が <@%s> に勝負を挑みました！\n期限: <t:%d:R>", duel.ChallengerID, duel.OpponentID, duel.ExpiresAt.Unix()),
+		Color:       0xe67e22, // Orange
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "ゲーム", Value: duel.Game.Emoji + " " + duel.Game.Name, Inline: true},
+			{Name: "賭け金", Value: fmt.Sprintf("%d チップずつ", duel.Bet), Inline: true},
+			c.recordField(duel),
+		},
+	}
+}
+
+func (c *DuelCommand) buildChoosingEmbed(duel *Duel) *discordgo.MessageEmbed {
+	var status strings.Builder
+	for _, userID := range []string{duel.ChallengerID, duel.OpponentID} {
+		mark := "⏳ 考え中"
+		if _, chosen := duel.Choices[userID]; chosen {
+			mark = "✅ 決定"
+		}
+		fmt.Fprintf(&status, "<@%s>: %s\n", userID, mark)
+	}
+	return &discordgo.MessageEmbed{
+		Title:       "✊ じゃんけん",
+		Description: fmt.Sprintf("手を選んでください！ 期限: <t:%d:R>\n期限までに選ばなかった方の負けになります。", duel.ExpiresAt.Unix()),
+		Color:       0xe67e22, // Orange
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "賭け金", Value: fmt.Sprintf("%d チップずつ", duel.Bet), Inline: true},
+			{Name: "プレイヤー", Value: status.String(), Inline: true},
+		},
+	}
+}
+
+func (c *DuelCommand) buildClosedEmbed(duel *Duel, reason string) *discordgo.MessageEmbed {
+	return &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("⚔️ デュエル - %s %s", duel.Game.Emoji, duel.Game.Name),
+		Description: reason,
+		Color:       0x95a5a6, // Gray
+	}
+}
@@ -0,0 +1,365 @@
+package commands
+
+import (
+	"fmt"
+	"luna/handlers/events"
+	"luna/interfaces"
+	"luna/storage"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	ecoWipeModalID   = "eco_wipe_confirm"
+	ecoWipeConfirmed = "WIPE"
+)
+
+// EcoCommand handles the /eco command.
+type EcoCommand struct {
+	Store interfaces.DataStore
+	Log   interfaces.Logger
+	Stock *StockCommand // ワイプ後に板を読み直すために使用
+}
+
+// NewEcoCommand creates a new EcoCommand.
+func NewEcoCommand(store interfaces.DataStore, log interfaces.Logger, stock *StockCommand) *EcoCommand {
+	return &EcoCommand{Store: store, Log: log, Stock: stock}
+}
+
+func (c *EcoCommand) GetCommandDef() *discordgo.ApplicationCommand {
+	targetOption := &discordgo.ApplicationCommandOption{Type: discordgo.ApplicationCommandOptionMentionable, Name: "target", Description: "対象のユーザーまたはロール", Required: true}
+	currencyOption := &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "currency",
+		Description: "対象の通貨",
+		Required:    true,
+		Choices: []*discordgo.ApplicationCommandOptionChoice{
+			{Name: "チップ", Value: storage.CurrencyChips},
+			{Name: "PepeCoin (PPC)", Value: storage.CurrencyPPC},
+		},
+	}
+	amountOption := &discordgo.ApplicationCommandOption{Type: discordgo.ApplicationCommandOptionInteger, Name: "amount", Description: "量", Required: true, MinValue: &[]float64{0}[0]}
+	reasonOption := &discordgo.ApplicationCommandOption{Type: discordgo.ApplicationCommandOptionString, Name: "reason", Description: "操作の理由 (ログに記録されます)"}
+	adjustOptions := []*discordgo.ApplicationCommandOption{targetOption, currencyOption, amountOption, reasonOption}
+
+	return &discordgo.ApplicationCommand{
+		Name:                     "eco",
+		Description:              "[管理者] サーバーの経済を管理します。",
+		DefaultMemberPermissions: int64Ptr(discordgo.PermissionManageGuild),
+		Options: []*discordgo.ApplicationCommandOption{
+			{Name: "give", Description: "ユーザーまたはロールの全員に残高を付与します。", Type: discordgo.ApplicationCommandOptionSubCommand, Options: adjustOptions},
+			{Name: "take", Description: "ユーザーまたはロールの全員から残高を差し引きます。", Type: discordgo.ApplicationCommandOptionSubCommand, Options: adjustOptions},
+			{Name: "set", Description: "ユーザーまたはロールの全員の残高を指定した値にします。", Type: discordgo.ApplicationCommandOptionSubCommand, Options: adjustOptions},
+			{Name: "reset", Description: "ユーザーまたはロールの全員の残高を初期状態に戻します。", Type: discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{targetOption, currencyOption, reasonOption},
+			},
+			{Name: "freeze", Description: "ユーザーの口座を凍結または凍結解除します。", Type: discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionUser, Name: "user", Description: "対象のユーザー", Required: true},
+					{Type: discordgo.ApplicationCommandOptionBoolean, Name: "frozen", Description: "凍結する (false で凍結解除, デフォルト: true)"},
+					reasonOption,
+				},
+			},
+			{Name: "wipe", Description: "サーバーの残高・資産・ゲームの記録をリセットします (ショップ・設定・賭けの制限・実績は残ります)。", Type: discordgo.ApplicationCommandOptionSubCommand},
+			{Name: "log", Description: "経済の管理操作の履歴を表示します。", Type: discordgo.ApplicationCommandOptionSubCommand},
+		},
+	}
+}
+
+func (c *EcoCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !hasManageGuild(i) {
+		sendErrorResponse(s, i, "このコマンドを実行するにはサーバー管理権限が必要です。")
+		return
+	}
+	subcommand := i.ApplicationCommandData().Options[0]
+	switch subcommand.Name {
+	case "give", "take", "set", "reset":
+		c.handleAdjust(s, i, subcommand)
+	case "freeze":
+		c.handleFreeze(s, i, subcommand.Options)
+	case "wipe":
+		c.showWipeModal(s, i)
+	case "log":
+		c.handleLog(s, i)
+	}
+}
+
+func (c *EcoCommand) handleAdjust(s *discordgo.Session, i *discordgo.InteractionCreate, subcommand *discordgo.ApplicationCommandInteractionDataOption) {
+	entry := &storage.EconomyAuditEntry{
+		GuildID:     i.GuildID,
+		ModeratorID: i.Member.User.ID,
+		Action:      subcommand.Name,
+	}
+	for _, opt := range subcommand.Options {
+		switch opt.Name {
+		case "target":
+			entry.TargetID = opt.Value.(string)
+			_, entry.TargetRole = i.ApplicationCommandData().Resolved.Roles[entry.TargetID]
+		case "currency":
+			entry.Currency = opt.StringValue()
+		case "amount":
+			entry.Amount = opt.IntValue()
+		case "reason":
+			entry.Reason = opt.StringValue()
+		}
+	}
+
+	// ロールのメンバー一覧の取得に時間がかかることがあるため、先に応答を保留する
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	}); err != nil {
+		c.Log.Error("Failed to send initial eco response", "error", err)
+		return
+	}
+
+	userIDs := []string{entry.TargetID}
+	if entry.TargetRole {
+		var err error
+		userIDs, err = roleMemberIDs(s, i.GuildID, entry.TargetID)
+		if err != nil {
+			c.Log.Error("Failed to list role members", "error", err, "roleID", entry.TargetID)
+			c.editResponse(s, i, "❌ ロールのメンバーを取得できませんでした。")
+			return
+		}
+		if len(userIDs) == 0 {
+			c.editResponse(s, i, "❌ このロールを持つメンバーがいません。")
+			return
+		}
+	}
+
+	if err := c.Store.AdjustEconomy(entry, userIDs); err != nil {
+		c.Log.Error("Failed to adjust economy", "error", err, "action", entry.Action)
+		c.editResponse(s, i, "❌ 操作中にエラーが発生しました。")
+		return
+	}
+
+	summary := describeEcoAction(entry)
+	c.editResponse(s, i, "✅ "+summary)
+	c.sendAuditLog(s, entry, summary)
+}
+
+func (c *EcoCommand) handleFreeze(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	entry := &storage.EconomyAuditEntry{
+		GuildID:     i.GuildID,
+		ModeratorID: i.Member.User.ID,
+		Action:      storage.EcoActionFreeze,
+	}
+	for _, opt := range options {
+		switch opt.Name {
+		case "user":
+			entry.TargetID = opt.UserValue(nil).ID
+		case "frozen":
+			if !opt.BoolValue() {
+				entry.Action = storage.EcoActionUnfreeze
+			}
+		case "reason":
+			entry.Reason = opt.StringValue()
+		}
+	}
+
+	if err := c.Store.SetAccountFrozen(entry); err != nil {
+		c.Log.Error("Failed to set account frozen", "error", err)
+		sendErrorResponse(s, i, "操作中にエラーが発生しました。")
+		return
+	}
+	summary := describeEcoAction(entry)
+	sendSuccessResponse(s, i, summary)
+	c.sendAuditLog(s, entry, summary)
+}
+
+func (c *EcoCommand) showWipeModal(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: ecoWipeModalID, Title: "経済リセットの確認",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{CustomID: "confirm", Label: fmt.Sprintf("元に戻せません。実行するには %s と入力", ecoWipeConfirmed), Style: discordgo.TextInputShort, Placeholder: ecoWipeConfirmed, Required: true},
+				}},
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{CustomID: "reason", Label: "理由", Style: discordgo.TextInputParagraph, Required: true},
+				}},
+			},
+		},
+	})
+}
+
+func (c *EcoCommand) HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.ModalSubmitData().CustomID != ecoWipeModalID {
+		return
+	}
+	if !hasManageGuild(i) {
+		sendErrorResponse(s, i, "このコマンドを実行するにはサーバー管理権限が必要です。")
+		return
+	}
+	components := i.ModalSubmitData().Components
+	confirm := components[0].(*discordgo.ActionsRow).Components[0].(*discordgo.TextInput).Value
+	reason := components[1].(*discordgo.ActionsRow).Components[0].(*discordgo.TextInput).Value
+	if strings.TrimSpace(confirm) != ecoWipeConfirmed {
+		sendErrorResponse(s, i, "確認の文字列が一致しないため、リセットを中止しました。")
+		return
+	}
+
+	entry := &storage.EconomyAuditEntry{
+		GuildID:     i.GuildID,
+		ModeratorID: i.Member.User.ID,
+		Action:      storage.EcoActionWipe,
+		Reason:      reason,
+	}
+	if err := c.Store.WipeGuildEconomy(entry); err != nil {
+		c.Log.Error("Failed to wipe guild economy", "error", err, "guildID", i.GuildID)
+		sendErrorResponse(s, i, "リセット中にエラーが発生しました。")
+		return
+	}
+	if c.Stock != nil {
+		c.Stock.ReloadOrderBooks()
+	}
+
+	summary := describeEcoAction(entry)
+	sendSuccessResponse(s, i, summary)
+	c.sendAuditLog(s, entry, summary)
+}
+
+func (c *EcoCommand) handleLog(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	entries, err := c.Store.GetEconomyAuditLog(i.GuildID, 15)
+	if err != nil {
+		c.Log.Error("Failed to get economy audit log", "error", err)
+		sendErrorResponse(s, i, "履歴の取得に失敗しました。")
+		return
+	}
+	var sb strings.Builder
+	for _, e := range entries {
+		fmt.Fprintf(&sb, "`#%d` <t:%d:d> <@%s>: %s", e.ID, e.CreatedAt.Unix(), e.ModeratorID, describeEcoAction(&e))
+		if e.Reason != "" {
+			fmt.Fprintf(&sb, " (%s)", e.Reason)
+		}
+		sb.WriteString("\n")
+	}
+	description := sb.String()
+	if description == "" {
+		description = "管理操作の履歴はまだありません。"
+	}
+	embed := &discordgo.MessageEmbed{
+		Title:       "📜 経済の管理ログ",
+		Description: description,
+		Color:       0x95a5a6, // Gray
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+func (c *EcoCommand) editResponse(s *discordgo.Session, i *discordgo.InteractionCreate, content string) {
+	if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+		c.Log.Error("Failed to edit eco response", "error", err)
+	}
+}
+
+// sendAuditLog は、管理操作をサーバーのログチャンネルに送信します。
+func (c *EcoCommand) sendAuditLog(s *discordgo.Session, entry *storage.EconomyAuditEntry, summary string) {
+	reason := entry.Reason
+	if reason == "" {
+		reason = "なし"
+	}
+	embed := &discordgo.MessageEmbed{
+		Title:       "🏦 経済の管理操作",
+		Description: summary,
+		Color:       0xe67e22, // Orange
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "実行者", Value: fmt.Sprintf("<@%s>", entry.ModeratorID), Inline: true},
+			{Name: "対象人数", Value: fmt.Sprintf("%d 人", entry.Affected), Inline: true},
+			{Name: "理由", Value: reason, Inline: false},
+		},
+	}
+	events.SendLog(s, entry.GuildID, c.Store, c.Log, embed)
+}
+
+// describeEcoAction は、管理操作の内容を1文で表します。
+func describeEcoAction(entry *storage.EconomyAuditEntry) string {
+	target := fmt.Sprintf("<@%s>", entry.TargetID)
+	if entry.TargetRole {
+		target = fmt.Sprintf("<@&%s> のメンバー", entry.TargetID)
+	}
+	unit := currencyLabel(entry.Currency)
+	// 保有株はサーバーをまたいで共通のため今は操作できないが、以前の監査ログには残っている
+	if entry.Currency == storage.CurrencyShares {
+		unit = "株"
+		if entry.CompanyCode != "" {
+			unit = fmt.Sprintf("株 (%s)", entry.CompanyCode)
+		}
+	}
+
+	switch entry.Action {
+	case storage.EcoActionGive:
+		return fmt.Sprintf("%s に `%d` %s を付与しました。", target, entry.Amount, unit)
+	case storage.EcoActionTake:
+		return fmt.Sprintf("%s から `%d` %s を差し引きました。", target, entry.Amount, unit)
+	case storage.EcoActionSet:
+		return fmt.Sprintf("%s の残高を `%d` %s に設定しました。", target, entry.Amount, unit)
+	case storage.EcoActionReset:
+		return fmt.Sprintf("%s の%sを初期状態に戻しました。", target, unit)
+	case storage.EcoActionFreeze:
+		return fmt.Sprintf("%s の口座を凍結しました。", target)
+	case storage.EcoActionUnfreeze:
+		return fmt.Sprintf("%s の口座の凍結を解除しました。", target)
+	case storage.EcoActionWipe:
+		return "サーバーの経済をすべてリセットしました。"
+	}
+	return entry.Action
+}
+
+// roleMemberIDs は、ロールを持つメンバー (Botを除く) のIDを返します。@everyone の場合は全メンバーです。
+func roleMemberIDs(s *discordgo.Session, guildID, roleID string) ([]string, error) {
+	var ids []string
+	after := ""
+	for {
+		members, err := s.GuildMembers(guildID, after, 1000)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range members {
+			if m.User.Bot {
+				continue
+			}
+			if roleID == guildID || hasRole(m, roleID) {
+				ids = append(ids, m.User.ID)
+			}
+		}
+		if len(members) < 1000 {
+			return ids, nil
+		}
+		after = members[len(members)-1].User.ID
+	}
+}
+
+func hasRole(member *discordgo.Member, roleID string) bool {
+	for _, id := range member.Roles {
+		if id == roleID {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectFrozenAccount は、実行したユーザーの口座が凍結されていればエラーを返信して true を返します。
+func rejectFrozenAccount(s *discordgo.Session, i *discordgo.InteractionCreate, store interfaces.DataStore) bool {
+	if i.Member == nil {
+		return false
+	}
+	frozen, err := store.IsAccountFrozen(i.GuildID, i.Member.User.ID)
+	if err != nil || !frozen {
+		return false
+	}
+	sendErrorResponse(s, i, "あなたの口座は管理者によって凍結されています。")
+	return true
+}
+
+func (c *EcoCommand) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {}
+func (c *EcoCommand) GetComponentIDs() []string                                            { return []string{ecoWipeModalID} }
+func (c *EcoCommand) GetCategory() string                                                  { return "管理" }
@@ -1,14 +1,29 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"luna/interfaces"
+	"luna/storage"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
 
+// 為替レートの変動に関するパラメータ
 const (
-	PpcToChipsRate = 10
+	exchangeFlowImpact     = 0.05   // 需給による1回あたりの最大変動率
+	exchangeFlowDamping    = 200    // 売買量がこれより少ないうちは需給の影響を弱める
+	exchangeActivityImpact = 0.01   // カジノの活動による1回あたりの最大上昇率
+	exchangeVolumeScale    = 100000 // 活動の影響が半分になるチップの量
+	exchangeReversion      = 0.02   // 基準レートに戻る強さ
+	exchangeNoise          = 0.01   // ランダムな揺らぎの幅
+	exchangeRateFloor      = 0.5    // 基準レートに対する下限
+	exchangeRateCeiling    = 2.0    // 基準レートに対する上限
+	exchangeChartWidth     = 32
 )
 
 // ExchangeCommand handles the /exchange command.
@@ -54,10 +69,15 @@ func (c *ExchangeCommand) GetCommandDef() *discordgo.ApplicationCommand {
 						Name:        "amount",
 						Description: "両替するチップの額",
 						Required:    true,
-						MinValue:    &[]float64{10}[0], // Must be at least 10 to get 1 PPC
+						MinValue:    &[]float64{1}[0],
 					},
 				},
 			},
+			{
+				Name:        "rate",
+				Description: "現在の為替レートとチャートを表示します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
 		},
 	}
 }
@@ -69,74 +89,167 @@ func (c *ExchangeCommand) Handle(s *discordgo.Session, i *discordgo.InteractionC
 		c.handlePpcToChips(s, i)
 	case "chips_to_ppc":
 		c.handleChipsToPpc(s, i)
+	case "rate":
+		c.handleRate(s, i)
 	}
 }
 
 func (c *ExchangeCommand) handlePpcToChips(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	amount := i.ApplicationCommandData().Options[0].Options[0].IntValue()
-	userID := i.Member.User.ID
-	guildID := i.GuildID
 
-	casinoData, err := c.Store.GetCasinoData(guildID, userID)
+	result, err := c.Store.ExchangeCurrency(i.GuildID, i.Member.User.ID, storage.ExchangeSellPPC, amount)
 	if err != nil {
-		c.Log.Error("Failed to get casino data for exchange", "error", err)
-		sendErrorResponse(s, i, "エラーが発生しました。")
+		c.sendExchangeError(s, i, err, "PepeCoin")
 		return
 	}
 
-	if casinoData.PepeCoinBalance < amount {
-		sendErrorResponse(s, i, fmt.Sprintf("PepeCoinが足りません！\n現在のPPC: `%d`", casinoData.PepeCoinBalance))
-		return
-	}
+	response := fmt.Sprintf("🐸 **%d PPC** を 💰 **%d チップ** に両替しました。(売値: 1 PPC = %.2f チップ)", result.Paid, result.Received, result.Quote.Sell)
+	sendSuccessResponse(s, i, response)
+}
 
-	casinoData.PepeCoinBalance -= amount
-	chipsToReceive := amount * PpcToChipsRate
-	casinoData.Chips += chipsToReceive
+func (c *ExchangeCommand) handleChipsToPpc(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	amount := i.ApplicationCommandData().Options[0].Options[0].IntValue()
 
-	if err := c.Store.UpdateCasinoData(casinoData); err != nil {
-		c.Log.Error("Failed to update casino data for exchange", "error", err)
-		sendErrorResponse(s, i, "両替処理中にエラーが発生しました。")
+	result, err := c.Store.ExchangeCurrency(i.GuildID, i.Member.User.ID, storage.ExchangeBuyPPC, amount)
+	if err != nil {
+		c.sendExchangeError(s, i, err, "チップ")
 		return
 	}
 
-	response := fmt.Sprintf("🐸 **%d PPC** を 💰 **%d チップ** に両替しました。", amount, chipsToReceive)
+	response := fmt.Sprintf("💰 **%d チップ** を 🐸 **%d PPC** に両替しました。(買値: 1 PPC = %.2f チップ)", result.Paid, result.Received, result.Quote.Buy)
 	sendSuccessResponse(s, i, response)
 }
 
-func (c *ExchangeCommand) handleChipsToPpc(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	amount := i.ApplicationCommandData().Options[0].Options[0].IntValue()
-	userID := i.Member.User.ID
-	guildID := i.GuildID
+func (c *ExchangeCommand) sendExchangeError(s *discordgo.Session, i *discordgo.InteractionCreate, err error, currency string) {
+	switch {
+	case errors.Is(err, storage.ErrInsufficientFunds):
+		sendErrorResponse(s, i, fmt.Sprintf("%sが足りません！", currency))
+	case errors.Is(err, storage.ErrExchangeTooSmall):
+		sendErrorResponse(s, i, "両替額が少なすぎます。`/exchange rate` で現在のレートを確認してください。")
+	case errors.Is(err, storage.ErrAccountFrozen):
+		sendErrorResponse(s, i, "あなたの口座は管理者によって凍結されています。")
+	default:
+		c.Log.Error("Failed to exchange currency", "error", err)
+		sendErrorResponse(s, i, "両替処理中にエラーが発生しました。")
+	}
+}
 
-	if amount%PpcToChipsRate != 0 {
-		sendErrorResponse(s, i, fmt.Sprintf("チップは%dの倍数で入力してください。", PpcToChipsRate))
+func (c *ExchangeCommand) handleRate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	economy, err := c.Store.GetEconomyConfig(i.GuildID)
+	if err != nil {
+		c.Log.Error("Failed to get economy config for exchange rate", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
 		return
 	}
-
-	casinoData, err := c.Store.GetCasinoData(guildID, userID)
+	market, err := c.Store.GetExchangeMarket(i.GuildID)
 	if err != nil {
-		c.Log.Error("Failed to get casino data for exchange", "error", err)
+		c.Log.Error("Failed to get exchange market", "error", err)
 		sendErrorResponse(s, i, "エラーが発生しました。")
 		return
 	}
-
-	if casinoData.Chips < amount {
-		sendErrorResponse(s, i, fmt.Sprintf("チップが足りません！\n現在のチップ: `%d`", casinoData.Chips))
+	history, err := c.Store.GetExchangeRateHistory(i.GuildID, time.Now().Add(-48*time.Hour))
+	if err != nil {
+		c.Log.Error("Failed to get exchange rate history", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
 		return
 	}
 
-	casinoData.Chips -= amount
-	ppcToReceive := amount / PpcToChipsRate
-	casinoData.PepeCoinBalance += ppcToReceive
+	quote := storage.QuoteFor(market.Rate, economy.ExchangeSpread)
+	embed := &discordgo.MessageEmbed{
+		Title: "💱 PepeCoin 為替レート",
+		Color: 0x1abc9c, // Teal
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "仲値", Value: fmt.Sprintf("1 PPC = **%.2f** チップ", quote.Mid), Inline: true},
+			{Name: "買値 (チップ→PPC)", Value: fmt.Sprintf("%.2f チップ", quote.Buy), Inline: true},
+			{Name: "売値 (PPC→チップ)", Value: fmt.Sprintf("%.2f チップ", quote.Sell), Inline: true},
+		},
+		Footer: &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("基準レート: %d チップ / スプレッド: %.1f%%", economy.PpcToChipsRate, economy.ExchangeSpread*100)},
+	}
 
-	if err := c.Store.UpdateCasinoData(casinoData); err != nil {
-		c.Log.Error("Failed to update casino data for exchange", "error", err)
-		sendErrorResponse(s, i, "両替処理中にエラーが発生しました。")
-		return
+	if len(history) >= 2 {
+		low, high := history[0].Rate, history[0].Rate
+		for _, p := range history {
+			low = min(low, p.Rate)
+			high = max(high, p.Rate)
+		}
+		first := history[0].Rate
+		change := (quote.Mid - first) / first * 100
+		arrow := "📈"
+		if change < 0 {
+			arrow = "📉"
+		}
+		embed.Fields = append(embed.Fields,
+			&discordgo.MessageEmbedField{Name: "48時間の変動", Value: fmt.Sprintf("%s %+.2f%% (安値 %.2f / 高値 %.2f)", arrow, change, low, high)},
+			&discordgo.MessageEmbedField{Name: "チャート", Value: "```\n" + rateChart(history, exchangeChartWidth) + "\n```"},
+		)
+	} else {
+		embed.Description = "レートの履歴がまだありません。レートは15分ごとに更新されます。"
 	}
+	sendEmbedResponse(s, i, embed)
+}
 
-	response := fmt.Sprintf("💰 **%d チップ** を 🐸 **%d PPC** に両替しました。", amount, ppcToReceive)
-	sendSuccessResponse(s, i, response)
+// rateChart は、レート履歴を width 本のブロック文字で表した簡易チャートを返します。
+func rateChart(history []storage.ExchangeRatePoint, width int) string {
+	blocks := []rune("▁▂▃▄▅▆▇█")
+	// 点が多すぎる場合は間引く
+	step := max(1, (len(history)+width-1)/width)
+	var rates []float64
+	for idx := 0; idx < len(history); idx += step {
+		rates = append(rates, history[idx].Rate)
+	}
+	low, high := rates[0], rates[0]
+	for _, r := range rates {
+		low = min(low, r)
+		high = max(high, r)
+	}
+
+	var sb strings.Builder
+	for _, r := range rates {
+		level := len(blocks) / 2
+		if high > low {
+			level = int((r - low) / (high - low) * float64(len(blocks)-1))
+		}
+		sb.WriteRune(blocks[level])
+	}
+	return sb.String()
+}
+
+// nextExchangeRate は、需給とカジノの活動量から次の仲値を計算します。
+// PPCが買われるほど、またゲームでチップが多く動くほどPPCは高くなり、基準レートに向かって少しずつ戻ります。
+func nextExchangeRate(market storage.ExchangeMarket, baseRate float64) float64 {
+	rate := market.Rate
+	// 需給: 買い越しなら上昇、売り越しなら下落 (売買が少ないうちは影響を小さくする)
+	pressure := float64(market.NetFlow) / (float64(market.Turnover) + exchangeFlowDamping)
+	// カジノの活動: チップが多く動くほどチップの価値が下がる
+	activity := float64(market.CasinoVolume) / (float64(market.CasinoVolume) + exchangeVolumeScale)
+	// 基準レートへの回帰
+	reversion := (baseRate - rate) / rate
+
+	drift := exchangeFlowImpact*pressure + exchangeActivityImpact*activity + exchangeReversion*reversion
+	noise := (rand.Float64() - 0.5) * exchangeNoise
+	rate *= 1 + drift + noise
+
+	return math.Max(baseRate*exchangeRateFloor, math.Min(baseRate*exchangeRateCeiling, rate))
+}
+
+// UpdateRates は、すべてのサーバーの為替レートを更新します。スケジューラから定期的に呼び出されます。
+func (c *ExchangeCommand) UpdateRates() {
+	markets, err := c.Store.GetExchangeMarkets()
+	if err != nil {
+		c.Log.Error("Failed to get exchange markets", "error", err)
+		return
+	}
+	for _, market := range markets {
+		economy, err := c.Store.GetEconomyConfig(market.GuildID)
+		if err != nil {
+			c.Log.Error("Failed to get economy config for exchange rate update", "error", err, "guildID", market.GuildID)
+			continue
+		}
+		rate := nextExchangeRate(market, float64(economy.PpcToChipsRate))
+		if err := c.Store.UpdateExchangeRate(&market, rate); err != nil {
+			c.Log.Error("Failed to update exchange rate", "error", err, "guildID", market.GuildID)
+		}
+	}
 }
 
 func (c *ExchangeCommand) GetCategory() string {
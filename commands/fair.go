@@ -0,0 +1,296 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"luna/fair"
+	"luna/interfaces"
+	"luna/slots"
+	"luna/storage"
+	"math/rand"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// FairCommand handles the /fair command.
+type FairCommand struct {
+	Fair *fair.Service
+	Log  interfaces.Logger
+}
+
+// fairGameChoices は、結果を再計算できるゲームの選択肢です。
+var fairGameChoices = []*discordgo.ApplicationCommandOptionChoice{
+	{Name: "スロット", Value: storage.GameSlots},
+	{Name: "ビデオスロット", Value: storage.GameVideoSlot},
+	{Name: "コインフリップ", Value: storage.GameCoinflip},
+	{Name: "ハイ＆ロー", Value: storage.GameHiLow},
+	{Name: "ブラックジャック", Value: storage.GameBlackjack},
+	{Name: "ポーカー", Value: storage.GamePoker},
+	{Name: "ルーレット", Value: storage.GameRoulette},
+	{Name: "競馬", Value: storage.GameHorseRace},
+	{Name: "クラッシュ", Value: storage.GameCrash},
+	{Name: "マインズ", Value: storage.GameMines},
+}
+
+func (c *FairCommand) GetCommandDef() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "fair",
+		Description: "カジノゲームの公平性を検証します。",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "seeds",
+				Description: "現在のサーバーシードのハッシュ、クライアントシード、ナンスを表示します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Name:        "verify",
+				Description: "現在のサーバーシードを公開して新しいシードに切り替えます。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "game", Description: "結果を再計算するゲーム", Choices: fairGameChoices},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "nonce", Description: "再計算するナンス (デフォルト: 最後に遊んだゲーム)", MinValue: &[]float64{0}[0]},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "client_seed", Description: "次のシードで使うクライアントシード (デフォルト: 引き継ぎ)", MaxLength: 64},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "machine", Description: "スロットの場合、遊んだスロットマシン"},
+				},
+			},
+			{
+				Name:        "check",
+				Description: "公開されたシードからゲームの結果を再計算します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "game", Description: "結果を再計算するゲーム", Required: true, Choices: fairGameChoices},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "server_seed", Description: "公開されたサーバーシード", Required: true},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "client_seed", Description: "クライアントシード", Required: true},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "nonce", Description: "ナンス", Required: true, MinValue: &[]float64{0}[0]},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "machine", Description: "スロットの場合、遊んだスロットマシン"},
+				},
+			},
+		},
+	}
+}
+
+func (c *FairCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	subcommand := i.ApplicationCommandData().Options[0]
+	options := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, opt := range subcommand.Options {
+		options[opt.Name] = opt
+	}
+
+	switch subcommand.Name {
+	case "seeds":
+		c.handleSeeds(s, i)
+	case "verify":
+		c.handleVerify(s, i, options)
+	case "check":
+		c.handleCheck(s, i, options)
+	}
+}
+
+func (c *FairCommand) handleSeeds(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	seeds, err := c.Fair.Seeds(i.GuildID, i.Member.User.ID)
+	if err != nil {
+		c.Log.Error("Failed to get fair seeds", "error", err)
+		sendErrorResponse(s, i, "シードの取得中にエラーが発生しました。")
+		return
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       "🔐 あなたのシード",
+		Description: "ゲームの結果はサーバーシードとクライアントシードとナンスだけで決まります。\n`/fair verify` でサーバーシードを公開すると、過去の結果を誰でも再計算できます。",
+		Color:       0x3498db, // Blue
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "サーバーシードのハッシュ (SHA-256)", Value: "`" + seeds.ServerSeedHash + "`"},
+			{Name: "クライアントシード", Value: "`" + seeds.ClientSeed + "`", Inline: true},
+			{Name: "次のナンス", Value: fmt.Sprintf("%d", seeds.Nonce), Inline: true},
+		},
+	}
+	if seeds.PrevServerSeed != "" {
+		embed.Fields = append(embed.Fields, previousSeedFields(seeds)...)
+	}
+	sendEphemeralEmbed(s, i, embed)
+}
+
+func (c *FairCommand) handleVerify(s *discordgo.Session, i *discordgo.InteractionCreate, options map[string]*discordgo.ApplicationCommandInteractionDataOption) {
+	clientSeed := ""
+	if opt, ok := options["client_seed"]; ok {
+		clientSeed = strings.TrimSpace(opt.StringValue())
+	}
+
+	seeds, err := c.Fair.Rotate(i.GuildID, i.Member.User.ID, clientSeed)
+	if errors.Is(err, fair.ErrRoundInProgress) {
+		sendErrorResponse(s, i, "結果が決まっていないゲームがあるため、シードを公開できません。ゲームが終わってからもう一度お試しください。")
+		return
+	}
+	if err != nil {
+		c.Log.Error("Failed to rotate fair seeds", "error", err)
+		sendErrorResponse(s, i, "シードの切り替え中にエラーが発生しました。")
+		return
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       "🔓 サーバーシードを公開しました",
+		Description: "公開したシードのハッシュが、遊ぶ前に表示されていたハッシュと一致することを確認してください。",
+		Color:       0x2ecc71, // Green
+		Fields:      previousSeedFields(seeds),
+	}
+	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+		Name:  "新しいサーバーシードのハッシュ",
+		Value: fmt.Sprintf("`%s`\nクライアントシード: `%s`", seeds.ServerSeedHash, seeds.ClientSeed),
+	})
+
+	if opt, ok := options["game"]; ok {
+		// シードはすでに切り替わっているため、再計算できない場合もエラーにせず公開した内容は表示する
+		nonce := seeds.PrevNonce - 1
+		if n, ok := options["nonce"]; ok {
+			nonce = n.IntValue()
+		}
+		field := &discordgo.MessageEmbedField{Name: fmt.Sprintf("%s の結果 (ナンス %d)", gameLabel(opt.StringValue()), nonce)}
+		switch {
+		case seeds.PrevNonce == 0:
+			field.Value = "公開したシードではまだ一度も遊んでいないため、再計算できる結果がありません。"
+		case nonce >= seeds.PrevNonce:
+			field.Value = fmt.Sprintf("ナンスは 0 から %d の範囲で指定してください。", seeds.PrevNonce-1)
+		default:
+			field.Value = c.outcome(i.GuildID, opt.StringValue(), options, fair.NewRand(seeds.PrevServerSeed, seeds.PrevClientSeed, nonce))
+		}
+		embed.Fields = append(embed.Fields, field)
+	}
+	sendEphemeralEmbed(s, i, embed)
+}
+
+func (c *FairCommand) handleCheck(s *discordgo.Session, i *discordgo.InteractionCreate, options map[string]*discordgo.ApplicationCommandInteractionDataOption) {
+	game := options["game"].StringValue()
+	serverSeed := options["server_seed"].StringValue()
+	clientSeed := options["client_seed"].StringValue()
+	nonce := options["nonce"].IntValue()
+
+	embed := &discordgo.MessageEmbed{
+		Title: "🧮 結果の再計算",
+		Color: 0x95a5a6, // Gray
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "サーバーシードのハッシュ", Value: "`" + fair.HashSeed(serverSeed) + "`"},
+			{Name: "クライアントシード", Value: "`" + clientSeed + "`", Inline: true},
+			{Name: "ナンス", Value: fmt.Sprintf("%d", nonce), Inline: true},
+			{Name: gameLabel(game) + " の結果", Value: c.outcome(i.GuildID, game, options, fair.NewRand(serverSeed, clientSeed, nonce))},
+		},
+	}
+	sendEphemeralEmbed(s, i, embed)
+}
+
+// previousSeedFields は、公開済みのシードを表示するフィールドを返します。
+func previousSeedFields(seeds *storage.FairSeeds) []*discordgo.MessageEmbedField {
+	return []*discordgo.MessageEmbedField{
+		{Name: "公開したサーバーシード", Value: "`" + seeds.PrevServerSeed + "`"},
+		{Name: "そのハッシュ", Value: "`" + seeds.PrevServerSeedHash + "`"},
+		{Name: "クライアントシード", Value: "`" + seeds.PrevClientSeed + "`", Inline: true},
+		{Name: "遊んだ回数", Value: fmt.Sprintf("%d 回 (ナンス 0〜%d)", seeds.PrevNonce, seeds.PrevNonce-1), Inline: true},
+	}
+}
+
+// outcome は、r からゲームの結果を各ゲームと同じ手順で再計算して表示用の文字列にします。
+func (c *FairCommand) outcome(guildID, game string, options map[string]*discordgo.ApplicationCommandInteractionDataOption, r *rand.Rand) string {
+	switch game {
+	case storage.GameSlots:
+		// 出目はリールの並びで決まるため、遊んだマシンの定義で再計算する
+		name := ""
+		if opt, ok := options["machine"]; ok {
+			name = opt.StringValue()
+		}
+		machine, err := loadSlotMachine(c.Fair.Store, guildID, name)
+		if err != nil {
+			c.Log.Error("Failed to get slot machine", "error", err)
+			return "スロットマシンの取得中にエラーが発生しました。"
+		}
+		if machine == nil {
+			return fmt.Sprintf("スロットマシン `%s` は見つかりません。", name)
+		}
+		return strings.Join(slots.Spin(machine, r), " | ")
+	case storage.GameVideoSlot:
+		// 盤面はテーマのリールで決まるため、サーバーで選ばれているテーマで再計算する
+		theme, err := loadVideoTheme(c.Fair.Store, guildID)
+		if err != nil {
+			c.Log.Error("Failed to get video slots theme", "error", err)
+			return "テーマの取得中にエラーが発生しました。"
+		}
+		result := theme.Play(r, len(slots.Paylines))
+		return fmt.Sprintf("%s %s\n%sフリースピン: %d 回", theme.Emoji, theme.Name, renderVideoGrid(result.Base.Grid), len(result.Free))
+	case storage.GameCoinflip:
+		return translateChoice(flipCoin(r))
+	case storage.GameHiLow:
+		first, second := drawHiLowCards(r)
+		return fmt.Sprintf("1枚目: **%d** / 2枚目: **%d**", first, second)
+	case storage.GameBlackjack:
+		// シューの枚数はデッキの数で決まるため、サーバーのルールで再計算する
+		rules, err := loadBlackjackRules(c.Fair.Store, guildID)
+		if err != nil {
+			c.Log.Error("Failed to get blackjack rules", "error", err)
+			return "ルールの取得中にエラーが発生しました。"
+		}
+		deck := NewDeck(rules.Decks)
+		ShuffleDeck(deck, r)
+		cards := make([]string, 0, 10)
+		for _, card := range deck[:10] {
+			cards = append(cards, card.String())
+		}
+		return "山札の先頭10枚: " + strings.Join(cards, ", ")
+	case storage.GamePoker:
+		// ポーカーのテーブルは1ハンドごとに1デッキを切り、ボタンの左から配る
+		deck := NewDeck(1)
+		ShuffleDeck(deck, r)
+		cards := make([]string, 0, 10)
+		for _, card := range deck[:10] {
+			cards = append(cards, card.String())
+		}
+		return "デッキの先頭10枚: " + strings.Join(cards, ", ")
+	case storage.GameRoulette:
+		return "出目: " + rouletteNumber(r.Intn(37))
+	case storage.GameHorseRace:
+		// 出走馬は厩舎から選ぶため、今の厩舎で再計算する。レース後に年を取ったり引退したりした馬がいると結果は変わる
+		stable, err := c.Fair.Store.GetStable(guildID)
+		if err != nil {
+			c.Log.Error("Failed to get horse stable", "error", err)
+			return "厩舎の取得中にエラーが発生しました。"
+		}
+		if len(stable) == 0 {
+			return "厩舎に馬がいません。"
+		}
+		horses := pickField(stable, RaceFieldSize, r)
+		_, order := simulateRace(horses, r)
+		winner := order[0]
+		return fmt.Sprintf("優勝: %d. %s %s", winner+1, horses[winner].Emoji, horses[winner].Name)
+	case storage.GameCrash:
+		// クラッシュ倍率はハウスエッジで決まるため、今のサーバーの設定で再計算する。ラウンド後に設定が変わっていると結果は変わる
+		config, err := loadInstantGamesConfig(c.Fair.Store, guildID)
+		if err != nil {
+			c.Log.Error("Failed to get instant games config", "error", err)
+			return "設定の取得中にエラーが発生しました。"
+		}
+		return "クラッシュ倍率: " + formatMultiplier(crashPoint(r, config.CrashHouseEdge))
+	case storage.GameMines:
+		// マスの並びをシャッフルした先頭から地雷の数だけが地雷になる
+		order := r.Perm(MinesTiles)
+		tiles := make([]string, 0, MinesTiles)
+		for _, tile := range order {
+			tiles = append(tiles, fmt.Sprintf("%d", tile+1))
+		}
+		return "マスの並び (左上から1〜25、先頭から地雷の数だけが地雷): " + strings.Join(tiles, ", ")
+	}
+	return "このゲームは再計算できません。"
+}
+
+// sendEphemeralEmbed は、本人にだけ見えるEmbedで応答します。
+func sendEphemeralEmbed(s *discordgo.Session, i *discordgo.InteractionCreate, embed *discordgo.MessageEmbed) {
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+func (c *FairCommand) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {}
+func (c *FairCommand) HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate)     {}
+func (c *FairCommand) GetComponentIDs() []string                                            { return []string{} }
+func (c *FairCommand) GetCategory() string                                                  { return "カジノ" }
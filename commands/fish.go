@@ -1,38 +1,18 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"luna/interfaces"
+	"luna/storage"
+	"math"
 	"math/rand"
+	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
 
-const (
-	FishingCost int64 = 10 // Cost to fish once
-)
-
-// Fish represents an item that can be caught.
-type Fish struct {
-	Name   string
-	Payout int64
-	Rarity string
-	Weight int
-	Emoji  string
-}
-
-// fishTable holds all possible items that can be caught.
-var fishTable = []Fish{
-	{Name: "藻", Payout: 0, Rarity: "コモン", Weight: 30, Emoji: "🌿"},
-	{Name: "長靴", Payout: 1, Rarity: "コモン", Weight: 20, Emoji: "👢"},
-	{Name: "小アジ", Payout: 5, Rarity: "コモン", Weight: 25, Emoji: "🐟"},
-	{Name: "普通のバス", Payout: 15, Rarity: "アンコモン", Weight: 15, Emoji: "🐠"},
-	{Name: "大きなタイ", Payout: 50, Rarity: "レア", Weight: 8, Emoji: "🐡"},
-	{Name: "巨大なマグロ", Payout: 100, Rarity: "エピック", Weight: 2, Emoji: "🦑"},
-	{Name: "宝箱", Payout: 500, Rarity: "レジェンダリー", Weight: 1, Emoji: "💎"},
-}
-
 // FishCommand handles the /fish command.
 type FishCommand struct {
 	Store interfaces.DataStore
@@ -48,86 +28,522 @@ func NewFishCommand(store interfaces.DataStore, log interfaces.Logger) *FishComm
 }
 
 func (c *FishCommand) GetCommandDef() *discordgo.ApplicationCommand {
+	locationChoices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(fishingLocations))
+	for _, loc := range fishingLocations {
+		locationChoices = append(locationChoices, &discordgo.ApplicationCommandOptionChoice{Name: loc.Emoji + " " + loc.Name, Value: loc.ID})
+	}
+	gearChoices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(fishingRods)+len(fishingBaits))
+	rodChoices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(fishingRods))
+	for _, rod := range fishingRods {
+		rodChoices = append(rodChoices, &discordgo.ApplicationCommandOptionChoice{Name: rod.Emoji + " " + rod.Name, Value: rod.ID})
+		if rod.Price > 0 {
+			gearChoices = append(gearChoices, &discordgo.ApplicationCommandOptionChoice{Name: fmt.Sprintf("%s %s (竿)", rod.Emoji, rod.Name), Value: "rod:" + rod.ID})
+		}
+	}
+	baitChoices := []*discordgo.ApplicationCommandOptionChoice{{Name: "エサなし", Value: "none"}}
+	for _, bait := range fishingBaits {
+		baitChoices = append(baitChoices, &discordgo.ApplicationCommandOptionChoice{Name: bait.Emoji + " " + bait.Name, Value: bait.ID})
+		gearChoices = append(gearChoices, &discordgo.ApplicationCommandOptionChoice{Name: fmt.Sprintf("%s %s (エサ)", bait.Emoji, bait.Name), Value: "bait:" + bait.ID})
+	}
+
 	return &discordgo.ApplicationCommand{
 		Name:        "fish",
 		Description: "チップを払って釣りをします。何が釣れるかな？",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "cast",
+				Description: "釣りをします。釣った魚はクーラーボックスに入ります。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "location", Description: "釣り場 (デフォルト: 港の桟橋)", Required: false, Choices: locationChoices},
+				},
+			},
+			{
+				Name:        "bag",
+				Description: "クーラーボックスの中身（未売却の釣果）を表示します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Name:        "sell",
+				Description: "釣った魚を売ってチップに換えます。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "fish", Description: "売る魚の名前 (省略するとすべて売却)", Required: false},
+				},
+			},
+			{
+				Name:        "dex",
+				Description: "釣った魚の図鑑を表示します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionUser, Name: "user", Description: "表示するユーザー (デフォルト: 自分)", Required: false},
+				},
+			},
+			{
+				Name:        "shop",
+				Description: "釣具店の品揃えと現在の装備を表示します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Name:        "buy",
+				Description: "竿やエサを購入します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "item", Description: "購入する竿またはエサ", Required: true, Choices: gearChoices},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "amount", Description: "エサの個数 (デフォルト: 10)", Required: false, MinValue: &[]float64{1}[0]},
+				},
+			},
+			{
+				Name:        "equip",
+				Description: "所持している竿やエサを装備します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "rod", Description: "装備する竿", Required: false, Choices: rodChoices},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "bait", Description: "装備するエサ", Required: false, Choices: baitChoices},
+				},
+			},
+		},
 	}
 }
 
 func (c *FishCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	subcommand := i.ApplicationCommandData().Options[0]
+	switch subcommand.Name {
+	case "cast":
+		c.handleCast(s, i, subcommand.Options)
+	case "bag":
+		c.handleBag(s, i)
+	case "sell":
+		c.handleSell(s, i, subcommand.Options)
+	case "dex":
+		c.handleDex(s, i, subcommand.Options)
+	case "shop":
+		c.handleShop(s, i)
+	case "buy":
+		c.handleBuy(s, i, subcommand.Options)
+	case "equip":
+		c.handleEquip(s, i, subcommand.Options)
+	}
+}
+
+// rollFish は、時間帯に合う魚の中から重み付き抽選を行います。
+// luck はレアリティの段階に比例して重みを増やすため、レアな魚ほど効果が大きくなります。
+func rollFish(loc FishingLocation, now FishTime, luck float64) Fish {
+	var candidates []Fish
+	var weights []float64
+	var totalWeight float64
+	for _, fish := range loc.Fish {
+		if fish.Time != FishTimeAny && fish.Time != now {
+			continue
+		}
+		w := float64(fish.Weight) * (1 + luck*float64(rarityTiers[fish.Rarity]))
+		candidates = append(candidates, fish)
+		weights = append(weights, w)
+		totalWeight += w
+	}
+
+	randomNum := rand.Float64() * totalWeight
+	for idx, w := range weights {
+		if randomNum < w {
+			return candidates[idx]
+		}
+		randomNum -= w
+	}
+	return candidates[len(candidates)-1]
+}
+
+// rollSize は、魚のサイズと、サイズに応じた売値（平均の0.5〜1.5倍）を決めます。
+func rollSize(fish Fish) (float64, int64) {
+	ratio := rand.Float64()
+	size := math.Round((fish.MinSize+(fish.MaxSize-fish.MinSize)*ratio)*10) / 10
+	value := int64(math.Round(float64(fish.Payout) * (0.5 + ratio)))
+	return size, value
+}
+
+func (c *FishCommand) handleCast(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
 	userID := i.Member.User.ID
 	guildID := i.GuildID
 
-	casinoData, err := c.Store.GetCasinoData(guildID, userID)
+	locationID := fishingLocations[0].ID
+	if len(options) > 0 {
+		locationID = options[0].StringValue()
+	}
+	loc, ok := findFishingLocation(locationID)
+	if !ok {
+		sendErrorResponse(s, i, "その釣り場は存在しません。")
+		return
+	}
+
+	profile, err := c.Store.GetFishingProfile(guildID, userID)
 	if err != nil {
-		c.Log.Error("Failed to get casino data for fish", "error", err)
+		c.Log.Error("Failed to get fishing profile", "error", err)
 		sendErrorResponse(s, i, "エラーが発生しました。")
 		return
 	}
-
-	if casinoData.Chips < FishingCost {
-		sendErrorResponse(s, i, fmt.Sprintf("チップが足りません！釣るには %d チップ必要です。", FishingCost))
+	rod, _ := findFishingRod(profile.Rod)
+	if loc.RequiredRod != "" && rodRank(profile.Rod) < rodRank(loc.RequiredRod) {
+		required, _ := findFishingRod(loc.RequiredRod)
+		sendErrorResponse(s, i, fmt.Sprintf("%sで釣りをするには **%s** 以上の竿が必要です。", loc.Name, required.Name))
 		return
 	}
 
-	// Deduct cost first
-	casinoData.Chips -= FishingCost
-
-	// Perform weighted random selection
-	rand.Seed(time.Now().UnixNano())
-	totalWeight := 0
-	for _, fish := range fishTable {
-		totalWeight += fish.Weight
+	luck := rod.Luck
+	var bait FishingBait
+	if profile.Bait != "" && profile.BaitCounts[profile.Bait] > 0 {
+		bait, _ = findFishingBait(profile.Bait)
+		luck += bait.Luck
 	}
-	randomNum := rand.Intn(totalWeight)
 
-	var caughtFish Fish
-	currentWeight := 0
-	for _, fish := range fishTable {
-		currentWeight += fish.Weight
-		if randomNum < currentWeight {
-			caughtFish = fish
-			break
-		}
+	economy, err := c.Store.GetEconomyConfig(guildID)
+	if err != nil {
+		c.Log.Error("Failed to get economy config for fish", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
 	}
 
-	// Add payout
-	casinoData.Chips += caughtFish.Payout
+	cost := economy.FishingCost * loc.CostMultiplier
+	if rejectGamblingBet(s, i, c.Store, c.Log, cost) {
+		return
+	}
+	timeOfDay := currentFishTime(time.Now())
+	caughtFish := rollFish(loc, timeOfDay, luck)
+	size, value := rollSize(caughtFish)
 
-	// Update database
-	if err := c.Store.UpdateCasinoData(casinoData); err != nil {
-		c.Log.Error("Failed to update casino data after fishing", "error", err)
-		sendErrorResponse(s, i, "結果の保存中にエラーが発生しました。")
+	catch := &storage.FishCatch{GuildID: guildID, UserID: userID, FishID: caughtFish.ID, Location: loc.ID, Size: size, Value: value}
+	firstCatch, newRecord, err := c.Store.RecordFishCatch(catch, cost, bait.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrInsufficientFunds):
+			sendErrorResponse(s, i, fmt.Sprintf("チップが足りません！%sで釣るには %d チップ必要です。", loc.Name, cost))
+		case errors.Is(err, storage.ErrItemNotOwned):
+			sendErrorResponse(s, i, "エサが切れてしまいました。もう一度お試しください。")
+		default:
+			c.Log.Error("Failed to record fish catch", "error", err)
+			sendErrorResponse(s, i, "結果の保存中にエラーが発生しました。")
+		}
 		return
 	}
 
-	profit := caughtFish.Payout - FishingCost
+	description := fmt.Sprintf("**%s %s** (%.1fcm) を釣り上げた！", caughtFish.Emoji, caughtFish.Name, size)
+	if firstCatch {
+		description += "\n🆕 **初めて釣った魚です！図鑑に登録されました。**"
+	} else if newRecord {
+		description += "\n📏 **自己ベストのサイズを更新しました！**"
+	}
+
+	gear := fmt.Sprintf("%s %s", rod.Emoji, rod.Name)
+	if bait.ID != "" {
+		gear += fmt.Sprintf("\n%s %s (残り %d)", bait.Emoji, bait.Name, profile.BaitCounts[bait.ID]-1)
+	}
 
 	embed := &discordgo.MessageEmbed{
-		Title:       fmt.Sprintf("🎣 %sは釣りをした！", i.Member.User.Username),
-		Description: fmt.Sprintf("**%s %s** を釣り上げた！", caughtFish.Emoji, caughtFish.Name),
+		Title:       fmt.Sprintf("🎣 %sは%sで釣りをした！", i.Member.User.Username, loc.Name),
+		Description: description,
 		Fields: []*discordgo.MessageEmbedField{
 			{
 				Name:   "結果",
-				Value:  fmt.Sprintf("獲得: `%d` チップ\n収支: `%+d` チップ", caughtFish.Payout, profit),
+				Value:  fmt.Sprintf("売値: `%d` チップ\n釣り代: `%d` チップ", value, cost),
 				Inline: true,
 			},
 			{
-				Name:   "所持チップ",
-				Value:  fmt.Sprintf("**%d** チップ", casinoData.Chips),
+				Name:   "装備",
+				Value:  gear,
 				Inline: true,
 			},
 		},
 		Color:  0x45b3e0, // Water blue
-		Footer: &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("レアリティ: %s", caughtFish.Rarity)},
+		Footer: &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("レアリティ: %s | 時間帯: %s | /fish sell で売却できます", caughtFish.Rarity, timeOfDay)},
+	}
+	sendEmbedResponse(s, i, embed)
+
+	// 釣りは釣り代を賭け金、釣った魚の売値を戻りとして記録する
+	recordGameRound(c.Store, c.Log, guildID, userID, storage.GameFish, cost, value)
+	progress := map[string]int64{counterFishCaught: 1}
+	if caughtFish.Rarity == "レジェンダリー" {
+		progress[counterFishLegendary] = 1
+	}
+	recordAchievements(s, c.Store, c.Log, i.ChannelID, guildID, userID, progress)
+}
+
+func (c *FishCommand) handleBag(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	catches, err := c.Store.GetFishCatches(i.GuildID, i.Member.User.ID)
+	if err != nil {
+		c.Log.Error("Failed to get fish catches", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+	if len(catches) == 0 {
+		sendErrorResponse(s, i, "クーラーボックスは空です。`/fish cast` で釣りをしましょう！")
+		return
+	}
+
+	// 魚の種類ごとにまとめて表示する
+	type summary struct {
+		fish  Fish
+		count int
+		value int64
+	}
+	var order []string
+	summaries := make(map[string]*summary)
+	var total int64
+	for _, catch := range catches {
+		sum, ok := summaries[catch.FishID]
+		if !ok {
+			fish, _, _ := findFish(catch.FishID)
+			sum = &summary{fish: fish}
+			summaries[catch.FishID] = sum
+			order = append(order, catch.FishID)
+		}
+		sum.count++
+		sum.value += catch.Value
+		total += catch.Value
+	}
+
+	var sb strings.Builder
+	for _, id := range order {
+		sum := summaries[id]
+		sb.WriteString(fmt.Sprintf("%s **%s** × %d — `%d` チップ\n", sum.fish.Emoji, sum.fish.Name, sum.count, sum.value))
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("🧊 %s のクーラーボックス", i.Member.User.Username),
+		Description: sb.String(),
+		Color:       0x45b3e0, // Water blue
+		Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("合計 %d 匹 / 売値合計 %d チップ", len(catches), total)},
+	}
+	sendEmbedResponse(s, i, embed)
+}
+
+func (c *FishCommand) handleSell(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	var fishID, fishName string
+	if len(options) > 0 {
+		fishName = strings.TrimSpace(options[0].StringValue())
+		for _, loc := range fishingLocations {
+			for _, fish := range loc.Fish {
+				if fish.Name == fishName {
+					fishID = fish.ID
+				}
+			}
+		}
+		if fishID == "" {
+			sendErrorResponse(s, i, fmt.Sprintf("「%s」という魚はいません。", fishName))
+			return
+		}
+	}
+
+	count, total, err := c.Store.SellFishCatches(i.GuildID, i.Member.User.ID, fishID)
+	if err != nil {
+		c.Log.Error("Failed to sell fish catches", "error", err)
+		sendErrorResponse(s, i, "売却中にエラーが発生しました。")
+		return
+	}
+	if count == 0 {
+		sendErrorResponse(s, i, "売却できる魚がありません。")
+		return
+	}
+	sendSuccessResponse(s, i, fmt.Sprintf("魚を %d 匹売却し、**%d** チップを獲得しました！", count, total))
+}
+
+func (c *FishCommand) handleDex(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	targetUser := i.Member.User
+	if len(options) > 0 {
+		targetUser = options[0].UserValue(s)
+	}
+
+	collection, err := c.Store.GetFishCollection(i.GuildID, targetUser.ID)
+	if err != nil {
+		c.Log.Error("Failed to get fish collection", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("📖 %s の釣り図鑑", targetUser.Username),
+		Color: 0x45b3e0, // Water blue
+	}
+	var totalFish int
+	for _, loc := range fishingLocations {
+		var sb strings.Builder
+		for _, fish := range loc.Fish {
+			totalFish++
+			entry, caught := collection[fish.ID]
+			if !caught {
+				sb.WriteString(fmt.Sprintf("❔ ??? (%s", fish.Rarity))
+				if fish.Time != FishTimeAny {
+					sb.WriteString(" / " + fish.Time.String())
+				}
+				sb.WriteString(")\n")
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("%s **%s** × %d | 最大 `%.1f`cm | 初 <t:%d:d> (`%.1f`cm)\n",
+				fish.Emoji, fish.Name, entry.Count, entry.BestSize, entry.FirstCaughtAt.Unix(), entry.FirstSize))
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: loc.Emoji + " " + loc.Name, Value: sb.String()})
+	}
+	embed.Description = fmt.Sprintf("発見済み: **%d / %d** 種", len(collection), totalFish)
+	sendEmbedResponse(s, i, embed)
+}
+
+func (c *FishCommand) handleShop(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	profile, err := c.Store.GetFishingProfile(i.GuildID, i.Member.User.ID)
+	if err != nil {
+		c.Log.Error("Failed to get fishing profile", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+	economy, err := c.Store.GetEconomyConfig(i.GuildID)
+	if err != nil {
+		c.Log.Error("Failed to get economy config for fish", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+	owned := map[string]bool{fishingRods[0].ID: true}
+	for _, id := range profile.OwnedRods {
+		owned[id] = true
+	}
+
+	var rods strings.Builder
+	for _, rod := range fishingRods {
+		status := fmt.Sprintf("`%d` チップ", rod.Price)
+		if owned[rod.ID] {
+			status = "所持済み"
+		}
+		equipped := ""
+		if rodRank(profile.Rod) == rodRank(rod.ID) {
+			equipped = " ⬅️ 装備中"
+		}
+		rods.WriteString(fmt.Sprintf("%s **%s** (運 +%.0f%%) — %s%s\n", rod.Emoji, rod.Name, rod.Luck*100, status, equipped))
+	}
+
+	var baits strings.Builder
+	for _, bait := range fishingBaits {
+		equipped := ""
+		if profile.Bait == bait.ID {
+			equipped = " ⬅️ 装備中"
+		}
+		baits.WriteString(fmt.Sprintf("%s **%s** (運 +%.0f%%) — `%d` チップ/個 | 所持: %d%s\n",
+			bait.Emoji, bait.Name, bait.Luck*100, bait.Price, profile.BaitCounts[bait.ID], equipped))
+	}
+
+	var locations strings.Builder
+	for _, loc := range fishingLocations {
+		locations.WriteString(fmt.Sprintf("%s **%s** — `%d` チップ/回", loc.Emoji, loc.Name, economy.FishingCost*loc.CostMultiplier))
+		if loc.RequiredRod != "" {
+			required, _ := findFishingRod(loc.RequiredRod)
+			locations.WriteString(fmt.Sprintf(" (要: %s)", required.Name))
+		}
+		locations.WriteString("\n")
 	}
 
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Embeds: []*discordgo.MessageEmbed{embed},
+	embed := &discordgo.MessageEmbed{
+		Title:       "🏪 釣具店",
+		Description: "`/fish buy` で購入、`/fish equip` で装備を変更できます。\n運が高いほどレアな魚が釣れやすくなります。",
+		Color:       0x45b3e0, // Water blue
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "竿", Value: rods.String()},
+			{Name: "エサ (1回の釣りで1個消費)", Value: baits.String()},
+			{Name: "釣り場", Value: locations.String()},
 		},
-	})
+		Footer: &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("現在の時間帯: %s", currentFishTime(time.Now()))},
+	}
+	sendEmbedResponse(s, i, embed)
+}
+
+func (c *FishCommand) handleBuy(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	var itemValue string
+	quantity := int64(10)
+	for _, opt := range options {
+		switch opt.Name {
+		case "item":
+			itemValue = opt.StringValue()
+		case "amount":
+			quantity = opt.IntValue()
+		}
+	}
+	kind, id, _ := strings.Cut(itemValue, ":")
+
+	var err error
+	var message string
+	switch kind {
+	case "rod":
+		rod, ok := findFishingRod(id)
+		if !ok {
+			sendErrorResponse(s, i, "その竿は存在しません。")
+			return
+		}
+		err = c.Store.BuyFishingRod(i.GuildID, i.Member.User.ID, rod.ID, rod.Price)
+		message = fmt.Sprintf("%s **%s** を `%d` チップで購入し、装備しました！", rod.Emoji, rod.Name, rod.Price)
+	case "bait":
+		bait, ok := findFishingBait(id)
+		if !ok {
+			sendErrorResponse(s, i, "そのエサは存在しません。")
+			return
+		}
+		err = c.Store.BuyFishingBait(i.GuildID, i.Member.User.ID, bait.ID, quantity, bait.Price)
+		message = fmt.Sprintf("%s **%s** を %d 個 (`%d` チップ) 購入し、装備しました！", bait.Emoji, bait.Name, quantity, bait.Price*quantity)
+	default:
+		sendErrorResponse(s, i, "購入するアイテムを選択してください。")
+		return
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrInsufficientFunds):
+			sendErrorResponse(s, i, "チップが足りません！")
+		case errors.Is(err, storage.ErrAlreadyOwned):
+			sendErrorResponse(s, i, "その竿は既に持っています。`/fish equip` で装備できます。")
+		default:
+			c.Log.Error("Failed to buy fishing gear", "error", err)
+			sendErrorResponse(s, i, "購入中にエラーが発生しました。")
+		}
+		return
+	}
+	sendSuccessResponse(s, i, message)
+}
+
+func (c *FishCommand) handleEquip(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	profile, err := c.Store.GetFishingProfile(i.GuildID, i.Member.User.ID)
+	if err != nil {
+		c.Log.Error("Failed to get fishing profile", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+
+	rodID, baitID := profile.Rod, profile.Bait
+	for _, opt := range options {
+		switch opt.Name {
+		case "rod":
+			rodID = opt.StringValue()
+			if rodID == fishingRods[0].ID {
+				rodID = ""
+			}
+		case "bait":
+			baitID = opt.StringValue()
+			if baitID == "none" {
+				baitID = ""
+			} else if profile.BaitCounts[baitID] == 0 {
+				sendErrorResponse(s, i, "そのエサを持っていません。`/fish buy` で購入できます。")
+				return
+			}
+		}
+	}
+
+	if err := c.Store.EquipFishingGear(i.GuildID, i.Member.User.ID, rodID, baitID); err != nil {
+		if errors.Is(err, storage.ErrItemNotOwned) {
+			sendErrorResponse(s, i, "その竿を持っていません。`/fish buy` で購入できます。")
+			return
+		}
+		c.Log.Error("Failed to equip fishing gear", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+
+	rod, _ := findFishingRod(rodID)
+	baitName := "エサなし"
+	if bait, ok := findFishingBait(baitID); ok {
+		baitName = bait.Emoji + " " + bait.Name
+	}
+	sendSuccessResponse(s, i, fmt.Sprintf("装備を変更しました: %s %s / %s", rod.Emoji, rod.Name, baitName))
 }
 
 func (c *FishCommand) GetCategory() string {
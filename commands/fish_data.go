@@ -0,0 +1,193 @@
+package commands
+
+import "time"
+
+// FishTime は、魚が釣れる時間帯です。
+type FishTime int
+
+const (
+	FishTimeAny   FishTime = iota // いつでも
+	FishTimeDay                   // 昼 (6:00〜17:59)
+	FishTimeNight                 // 夜 (18:00〜5:59)
+)
+
+// jst は、釣りの時間帯の判定に使うタイムゾーンです。
+var jst = time.FixedZone("JST", 9*60*60)
+
+// currentFishTime は、現在の時間帯を返します。
+func currentFishTime(now time.Time) FishTime {
+	hour := now.In(jst).Hour()
+	if hour >= 6 && hour < 18 {
+		return FishTimeDay
+	}
+	return FishTimeNight
+}
+
+func (t FishTime) String() string {
+	switch t {
+	case FishTimeDay:
+		return "☀️ 昼"
+	case FishTimeNight:
+		return "🌙 夜"
+	}
+	return "いつでも"
+}
+
+// Fish represents an item that can be caught.
+type Fish struct {
+	ID      string
+	Name    string
+	Payout  int64 // 平均的なサイズのときの売値
+	Rarity  string
+	Weight  int
+	Emoji   string
+	MinSize float64 // cm
+	MaxSize float64 // cm
+	Time    FishTime
+}
+
+// rarityTiers は、レアリティごとの段階です。竿やエサの効果は段階が高いほど大きくかかります。
+var rarityTiers = map[string]int{
+	"コモン":     0,
+	"アンコモン":   1,
+	"レア":      2,
+	"エピック":    3,
+	"レジェンダリー": 4,
+}
+
+// FishingLocation は、釣り場とそこで釣れる魚のテーブルです。
+type FishingLocation struct {
+	ID             string
+	Name           string
+	Emoji          string
+	CostMultiplier int64  // 1回あたりの料金 = サーバーの釣り料金 × CostMultiplier
+	RequiredRod    string // 釣りに必要な竿 (空なら不要)
+	Fish           []Fish
+}
+
+// fishingLocations holds all fishing spots and their tables.
+var fishingLocations = []FishingLocation{
+	{
+		ID: "pier", Name: "港の桟橋", Emoji: "⚓", CostMultiplier: 1,
+		Fish: []Fish{
+			{ID: "seaweed", Name: "藻", Payout: 0, Rarity: "コモン", Weight: 30, Emoji: "🌿", MinSize: 5, MaxSize: 40},
+			{ID: "boot", Name: "長靴", Payout: 1, Rarity: "コモン", Weight: 20, Emoji: "👢", MinSize: 20, MaxSize: 30},
+			{ID: "horse_mackerel", Name: "小アジ", Payout: 5, Rarity: "コモン", Weight: 25, Emoji: "🐟", MinSize: 8, MaxSize: 20},
+			{ID: "bass", Name: "普通のバス", Payout: 15, Rarity: "アンコモン", Weight: 15, Emoji: "🐠", MinSize: 25, MaxSize: 55},
+			{ID: "cutlassfish", Name: "タチウオ", Payout: 30, Rarity: "アンコモン", Weight: 10, Emoji: "🗡️", MinSize: 60, MaxSize: 130, Time: FishTimeNight},
+			{ID: "sea_bream", Name: "大きなタイ", Payout: 50, Rarity: "レア", Weight: 8, Emoji: "🐡", MinSize: 30, MaxSize: 80},
+			{ID: "tuna", Name: "巨大なマグロ", Payout: 100, Rarity: "エピック", Weight: 2, Emoji: "🦑", MinSize: 100, MaxSize: 300},
+			{ID: "treasure", Name: "宝箱", Payout: 500, Rarity: "レジェンダリー", Weight: 1, Emoji: "💎", MinSize: 30, MaxSize: 60},
+		},
+	},
+	{
+		ID: "river", Name: "山奥の清流", Emoji: "🏞️", CostMultiplier: 1,
+		Fish: []Fish{
+			{ID: "can", Name: "空き缶", Payout: 1, Rarity: "コモン", Weight: 25, Emoji: "🥫", MinSize: 10, MaxSize: 15},
+			{ID: "pale_chub", Name: "オイカワ", Payout: 4, Rarity: "コモン", Weight: 35, Emoji: "🐟", MinSize: 8, MaxSize: 15},
+			{ID: "masu", Name: "ヤマメ", Payout: 20, Rarity: "アンコモン", Weight: 18, Emoji: "🐠", MinSize: 15, MaxSize: 35, Time: FishTimeDay},
+			{ID: "eel", Name: "ウナギ", Payout: 60, Rarity: "レア", Weight: 8, Emoji: "🐍", MinSize: 40, MaxSize: 100, Time: FishTimeNight},
+			{ID: "taimen", Name: "イトウ", Payout: 150, Rarity: "エピック", Weight: 2, Emoji: "🐉", MinSize: 70, MaxSize: 150},
+			{ID: "gold_dust", Name: "砂金の袋", Payout: 400, Rarity: "レジェンダリー", Weight: 1, Emoji: "💰", MinSize: 5, MaxSize: 15},
+		},
+	},
+	{
+		ID: "offshore", Name: "沖合", Emoji: "🚤", CostMultiplier: 3, RequiredRod: "carbon",
+		Fish: []Fish{
+			{ID: "mackerel", Name: "サバ", Payout: 10, Rarity: "コモン", Weight: 35, Emoji: "🐟", MinSize: 25, MaxSize: 50},
+			{ID: "skipjack", Name: "カツオ", Payout: 30, Rarity: "アンコモン", Weight: 25, Emoji: "🐠", MinSize: 40, MaxSize: 100},
+			{ID: "yellowtail", Name: "ブリ", Payout: 80, Rarity: "レア", Weight: 12, Emoji: "🐡", MinSize: 60, MaxSize: 120},
+			{ID: "marlin", Name: "カジキ", Payout: 250, Rarity: "エピック", Weight: 3, Emoji: "🗡️", MinSize: 150, MaxSize: 450, Time: FishTimeDay},
+			{ID: "giant_squid", Name: "ダイオウイカ", Payout: 300, Rarity: "エピック", Weight: 3, Emoji: "🦑", MinSize: 300, MaxSize: 1200, Time: FishTimeNight},
+			{ID: "shipwreck_chest", Name: "沈没船の宝箱", Payout: 1200, Rarity: "レジェンダリー", Weight: 1, Emoji: "👑", MinSize: 50, MaxSize: 90},
+		},
+	},
+}
+
+// FishingRod は、購入できる竿です。Luck が高いほどレアな魚が釣れやすくなります。
+type FishingRod struct {
+	ID    string
+	Name  string
+	Emoji string
+	Price int64
+	Luck  float64
+}
+
+// fishingRods holds all rods. The first entry is the default rod every user owns.
+var fishingRods = []FishingRod{
+	{ID: "bamboo", Name: "竹の竿", Emoji: "🎋", Price: 0, Luck: 0},
+	{ID: "carbon", Name: "カーボンロッド", Emoji: "🎣", Price: 500, Luck: 0.3},
+	{ID: "master", Name: "名匠の竿", Emoji: "✨", Price: 3000, Luck: 0.8},
+}
+
+// FishingBait は、1回の釣りごとに1つ消費されるエサです。
+type FishingBait struct {
+	ID    string
+	Name  string
+	Emoji string
+	Price int64 // 1個あたりの価格
+	Luck  float64
+}
+
+// fishingBaits holds all bait types.
+var fishingBaits = []FishingBait{
+	{ID: "worm", Name: "ミミズ", Emoji: "🪱", Price: 3, Luck: 0.15},
+	{ID: "paste", Name: "練り餌", Emoji: "🍡", Price: 8, Luck: 0.35},
+	{ID: "lure", Name: "特製ルアー", Emoji: "🪝", Price: 25, Luck: 0.7},
+}
+
+func findFishingLocation(id string) (FishingLocation, bool) {
+	for _, loc := range fishingLocations {
+		if loc.ID == id {
+			return loc, true
+		}
+	}
+	return FishingLocation{}, false
+}
+
+// findFishingRod は、竿を返します。空のIDはデフォルトの竿として扱います。
+func findFishingRod(id string) (FishingRod, bool) {
+	if id == "" {
+		return fishingRods[0], true
+	}
+	for _, rod := range fishingRods {
+		if rod.ID == id {
+			return rod, true
+		}
+	}
+	return FishingRod{}, false
+}
+
+func findFishingBait(id string) (FishingBait, bool) {
+	for _, bait := range fishingBaits {
+		if bait.ID == id {
+			return bait, true
+		}
+	}
+	return FishingBait{}, false
+}
+
+// findFish は、すべての釣り場から魚を検索し、その釣り場とあわせて返します。
+func findFish(id string) (Fish, FishingLocation, bool) {
+	for _, loc := range fishingLocations {
+		for _, fish := range loc.Fish {
+			if fish.ID == id {
+				return fish, loc, true
+			}
+		}
+	}
+	return Fish{}, FishingLocation{}, false
+}
+
+// rodRank は、竿の序列を返します（fishingRods 内の位置）。
+func rodRank(id string) int {
+	if id == "" {
+		return 0
+	}
+	for idx, rod := range fishingRods {
+		if rod.ID == id {
+			return idx
+		}
+	}
+	return 0
+}
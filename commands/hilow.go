@@ -2,7 +2,9 @@ package commands
 
 import (
 	"fmt"
+	"luna/fair"
 	"luna/interfaces"
+	"luna/storage"
 	"math/rand"
 	"sync"
 	"time"
@@ -11,8 +13,9 @@ import (
 )
 
 const (
-	HiLowButtonHigh = "hilow_high"
-	HiLowButtonLow  = "hilow_low"
+	HiLowButtonHigh  = "hilow_high"
+	HiLowButtonLow   = "hilow_low"
+	HiLowIdleTimeout = 5 * time.Minute
 )
 
 // HiLowGame holds the state of a single game.
@@ -21,21 +24,26 @@ type HiLowGame struct {
 	Interaction *discordgo.Interaction
 	BetAmount   int64
 	FirstCard   int
+	SecondCard  int // ゲーム開始時に決まっている次のカード
+	Round       *fair.Round
+	timer       *time.Timer // しばらく選ばなければ賭け金を返却して終わる
 }
 
 // HiLowCommand handles the /hilow command.
 type HiLowCommand struct {
 	Store interfaces.DataStore
 	Log   interfaces.Logger
+	Fair  *fair.Service
 	games map[string]*HiLowGame // userID -> game
 	mu    sync.Mutex
 }
 
 // NewHiLowCommand creates a new HiLowCommand.
-func NewHiLowCommand(store interfaces.DataStore, log interfaces.Logger) *HiLowCommand {
+func NewHiLowCommand(store interfaces.DataStore, log interfaces.Logger, fairRNG *fair.Service) *HiLowCommand {
 	return &HiLowCommand{
 		Store: store,
 		Log:   log,
+		Fair:  fairRNG,
 		games: make(map[string]*HiLowGame),
 	}
 }
@@ -68,6 +76,9 @@ func (c *HiLowCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCrea
 	c.mu.Unlock()
 
 	betAmount := i.ApplicationCommandData().Options[0].IntValue()
+	if rejectGamblingBet(s, i, c.Store, c.Log, betAmount) {
+		return
+	}
 
 	casinoData, err := c.Store.GetCasinoData(i.GuildID, userID)
 	if err != nil {
@@ -80,24 +91,35 @@ func (c *HiLowCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCrea
 		return
 	}
 
+	round, err := c.Fair.NewRound(i.GuildID, userID)
+	if err != nil {
+		c.Log.Error("Failed to start fair round for hilow", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+
 	casinoData.Chips -= betAmount
 	if err := c.Store.UpdateCasinoData(casinoData); err != nil {
 		c.Log.Error("Failed to update casino data on bet", "error", err)
+		round.Close()
 		sendErrorResponse(s, i, "ベット処理中にエラーが発生しました。")
 		return
 	}
+	pendingBets.add(i.GuildID, userID, storage.GameHiLow, betAmount)
 
-	rand.Seed(time.Now().UnixNano())
-	firstCard := rand.Intn(13) + 1
+	firstCard, secondCard := drawHiLowCards(round.Rand)
 
 	game := &HiLowGame{
 		PlayerID:    userID,
 		Interaction: i.Interaction,
 		BetAmount:   betAmount,
 		FirstCard:   firstCard,
+		SecondCard:  secondCard,
+		Round:       round,
 	}
 
 	c.mu.Lock()
+	game.timer = time.AfterFunc(HiLowIdleTimeout, func() { c.timeout(s, game) })
 	c.games[userID] = game
 	c.mu.Unlock()
 
@@ -105,7 +127,7 @@ func (c *HiLowCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCrea
 		Title:       "🃏 ハイ＆ロー",
 		Description: fmt.Sprintf("最初のカードは **%d** です。\n次のカードはこれより高い(High)か低い(Low)か？", firstCard),
 		Color:       0x3498db, // Blue
-		Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("ベット額: %d チップ", betAmount)},
+		Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("ベット額: %d チップ | %s", betAmount, round.Footer())},
 	}
 
 	components := []discordgo.MessageComponent{
@@ -134,8 +156,70 @@ func (c *HiLowCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCrea
 	})
 	if err != nil {
 		c.Log.Error("Failed to send hilow initial message", "error", err)
-		casinoData.Chips += betAmount
-		c.Store.UpdateCasinoData(casinoData)
+		if c.claim(game) {
+			c.refund(i.GuildID, game)
+		}
+	}
+}
+
+// claim は、進行中のゲームを一覧から外します。ボタンとタイムアウトのどちらか先に来た方だけが true を受け取り、ゲームを終わらせます。
+func (c *HiLowCommand) claim(game *HiLowGame) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.games[game.PlayerID] != game {
+		return false
+	}
+	game.timer.Stop()
+	delete(c.games, game.PlayerID)
+	return true
+}
+
+// refund は、結果を出さずに終わったゲームの賭け金を返却します。
+func (c *HiLowCommand) refund(guildID string, game *HiLowGame) {
+	game.Round.Close()
+	casinoData, err := c.Store.GetCasinoData(guildID, game.PlayerID)
+	if err == nil {
+		casinoData.Chips += game.BetAmount
+		err = c.Store.UpdateCasinoData(casinoData)
+	}
+	if err != nil {
+		c.Log.Error("Failed to refund hilow bet", "error", err, "userID", game.PlayerID)
+		return
+	}
+	pendingBets.release(guildID, game.PlayerID, storage.GameHiLow, game.BetAmount)
+}
+
+// timeout は、しばらく選ばれなかったゲームの賭け金を返却して終わらせます。
+func (c *HiLowCommand) timeout(s *discordgo.Session, game *HiLowGame) {
+	if !c.claim(game) {
+		return
+	}
+	c.refund(game.Interaction.GuildID, game)
+
+	embed := &discordgo.MessageEmbed{
+		Title:       "🃏 ハイ＆ロー - 時間切れ",
+		Description: fmt.Sprintf("最初のカード: **%d**\n次のカード: **%d**\n\n⏰ 時間内に選ばれなかったため、ベット額 (**%d** チップ) を返却しました。", game.FirstCard, game.SecondCard, game.BetAmount),
+		Color:       0x95a5a6, // Gray
+		Footer:      &discordgo.MessageEmbedFooter{Text: game.Round.Footer()},
+	}
+	components := hiLowDisabledButtons()
+	if _, err := s.InteractionResponseEdit(game.Interaction, &discordgo.WebhookEdit{
+		Embeds:     &[]*discordgo.MessageEmbed{embed},
+		Components: &components,
+	}); err != nil {
+		c.Log.Error("Failed to edit hilow message on timeout", "error", err)
+	}
+}
+
+// hiLowDisabledButtons は、ゲームが終わった後の押せないボタンを返します。
+func hiLowDisabledButtons() []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{Label: "ハイ (High)", Style: discordgo.SuccessButton, CustomID: HiLowButtonHigh, Disabled: true},
+				discordgo.Button{Label: "ロー (Low)", Style: discordgo.DangerButton, CustomID: HiLowButtonLow, Disabled: true},
+			},
+		},
 	}
 }
 
@@ -143,17 +227,15 @@ func (c *HiLowCommand) HandleComponent(s *discordgo.Session, i *discordgo.Intera
 	userID := i.Member.User.ID
 	c.mu.Lock()
 	game, exists := c.games[userID]
-	if !exists {
-		c.mu.Unlock()
+	c.mu.Unlock()
+	if !exists || !c.claim(game) {
 		sendErrorResponse(s, i, "これはあなたのゲームではありません。")
 		return
 	}
-	c.mu.Unlock()
 
 	playerChoiceIsHigh := i.MessageComponentData().CustomID == HiLowButtonHigh
 
-	rand.Seed(time.Now().UnixNano())
-	secondCard := rand.Intn(13) + 1
+	secondCard := game.SecondCard
 
 	var resultText string
 	payout := int64(0)
@@ -185,33 +267,31 @@ func (c *HiLowCommand) HandleComponent(s *discordgo.Session, i *discordgo.Intera
 		Title:       "🃏 ハイ＆ロー - 結果",
 		Description: fmt.Sprintf("最初のカード: **%d**\n次のカード: **%d**\n\n%s", game.FirstCard, secondCard, resultText),
 		Color:       0x2ecc71, // Green for win/push, should be dynamic
+		Footer:      &discordgo.MessageEmbedFooter{Text: game.Round.Footer()},
 	}
 
 	if !won && payout == 0 {
 		embed.Color = 0xe74c3c // Red for loss
 	}
 
-	// Disable buttons
-	disabledComponents := []discordgo.MessageComponent{
-		discordgo.ActionsRow{
-			Components: []discordgo.MessageComponent{
-				discordgo.Button{Label: "ハイ (High)", Style: discordgo.SuccessButton, CustomID: HiLowButtonHigh, Disabled: true},
-				discordgo.Button{Label: "ロー (Low)", Style: discordgo.DangerButton, CustomID: HiLowButtonLow, Disabled: true},
-			},
-		},
-	}
-
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseUpdateMessage,
 		Data: &discordgo.InteractionResponseData{
 			Embeds:     []*discordgo.MessageEmbed{embed},
-			Components: disabledComponents,
+			Components: hiLowDisabledButtons(),
 		},
 	})
 
-	c.mu.Lock()
-	delete(c.games, userID)
-	c.mu.Unlock()
+	game.Round.Close()
+
+	recordGameRound(c.Store, c.Log, i.GuildID, userID, storage.GameHiLow, game.BetAmount, payout)
+}
+
+// drawHiLowCards は、r から最初のカードと次のカードを決めます。
+func drawHiLowCards(r *rand.Rand) (int, int) {
+	first := r.Intn(13) + 1
+	second := r.Intn(13) + 1
+	return first, second
 }
 
 func (c *HiLowCommand) HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate) {}
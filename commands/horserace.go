@@ -2,8 +2,11 @@ package commands
 
 import (
 	"fmt"
+	"luna/fair"
 	"luna/interfaces"
+	"luna/storage"
 	"math/rand"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -13,10 +16,14 @@ import (
 )
 
 const (
-	BetButtonPrefix   = "hr_bet_"
-	StartRaceButtonID = "hr_start_race"
-	BetModalCustomID  = "hr_bet_modal"
-	RaceTrackLength   = 20
+	HorseBetSelectID    = "hr_bet_select"
+	StartRaceButtonID   = "hr_start_race"
+	HorseBetModalPrefix = "hr_bet_modal:"
+	RaceTrackLength     = 20
+	RaceFieldSize       = 6  // 1レースの出走頭数
+	HorseStableSize     = 12 // 厩舎に揃えておく馬の数
+	HorseFormRaces      = 5  // 近走として表示するレースの数
+	HorseHistoryRaces   = 10 // /horserace horse で表示するレースの数
 )
 
 // RaceState はレースの状態を表します。
@@ -28,13 +35,13 @@ const (
 	HRStateFinished
 )
 
-// Horse は馬の情報を表します。
+// Horse は、レースに出走する馬です。厩舎の馬に、その日の状態と年齢を反映した能力を加えたものです。
 type Horse struct {
-	Name      string
-	Odds      float64
-	Emoji     string
+	storage.Horse
 	Condition string
-	Modifier  float64
+	Ability   float64 // 能力値・年齢・状態から求めた走る速さの倍率 (おおむね 0.8〜1.2)
+	Form      string  // 近走の着順
+	Odds      float64 // 能力から見積もった単勝の予想オッズ
 }
 
 // Condition は馬の状態を表します。
@@ -45,47 +52,51 @@ type Condition struct {
 }
 
 var conditions = []Condition{
-	{Name: "絶好調", Modifier: 1.15, Weight: 1},
-	{Name: "好調", Modifier: 1.05, Weight: 3},
+	{Name: "絶好調", Modifier: 1.06, Weight: 1},
+	{Name: "好調", Modifier: 1.02, Weight: 3},
 	{Name: "普通", Modifier: 1.0, Weight: 5},
-	{Name: "不調", Modifier: 0.9, Weight: 2},
+	{Name: "不調", Modifier: 0.95, Weight: 2},
 }
 
-// Bet はベット情報を表します。
-type Bet struct {
-	UserID     string
-	HorseIndex int
-	Amount     int64
-}
+// horseAgeModifiers は、年齢ごとの能力の倍率です。4〜5歳が全盛期です。
+var horseAgeModifiers = map[int]float64{2: 0.94, 3: 0.98, 4: 1.0, 5: 1.0, 6: 0.98, 7: 0.95, 8: 0.91}
 
 // HorseRaceGame はレースゲーム全体の管理を行います。
 type HorseRaceGame struct {
-	State       RaceState
-	Horses      []Horse
-	Bets        []Bet
-	MessageID   string
-	ChannelID   string
-	Interaction *discordgo.Interaction
-	CreatorID   string
+	State     RaceState
+	GuildID   string
+	Horses    []Horse
+	Bets      []Bet
+	MessageID string
+	ChannelID string
+	CreatorID string      // 定時レースでは空
+	Round     *fair.Round // 開始したユーザー (定時レースではボット) のシードで決まるレースの乱数
+	CloseAt   time.Time   // 定時レースのベット締め切り時刻
+	timer     *time.Timer // 定時レースを締め切りに走らせるタイマー
 }
 
 // HorseRaceCommand は /horserace コマンドを処理します。
 type HorseRaceCommand struct {
-	Store interfaces.DataStore
-	Log   interfaces.Logger
-	races map[string]*HorseRaceGame // channelID -> game
-	mu    sync.Mutex
-	rand  *rand.Rand
+	Store   interfaces.DataStore
+	Log     interfaces.Logger
+	Fair    *fair.Service
+	Session *discordgo.Session        // 定時レースの開催に使用
+	races   map[string]*HorseRaceGame // channelID -> game
+	mu      sync.Mutex
+
+	lastScheduleCheck time.Time // 定時レースの開催時刻を最後に確認した時刻
 }
 
 // --- Command/Component/Modal Handlers ---
 
-func NewHorseRaceCommand(store interfaces.DataStore, log interfaces.Logger) *HorseRaceCommand {
+func NewHorseRaceCommand(store interfaces.DataStore, log interfaces.Logger, fairRNG *fair.Service, session *discordgo.Session) *HorseRaceCommand {
 	return &HorseRaceCommand{
-		Store: store,
-		Log:   log,
-		races: make(map[string]*HorseRaceGame),
-		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+		Store:             store,
+		Log:               log,
+		Fair:              fairRNG,
+		Session:           session,
+		races:             make(map[string]*HorseRaceGame),
+		lastScheduleCheck: time.Now(),
 	}
 }
 
@@ -93,10 +104,55 @@ func (c *HorseRaceCommand) GetCommandDef() *discordgo.ApplicationCommand {
 	return &discordgo.ApplicationCommand{
 		Name:        "horserace",
 		Description: "競馬を開始します！",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "start",
+				Description: "このチャンネルでレースのベット受付を開始します。",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "stable",
+				Description: "サーバーの厩舎にいる馬の一覧を表示します。",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "horse",
+				Description: "馬の能力と戦績を表示します。",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "name", Description: "馬の名前", Required: true},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "schedule",
+				Description: "定時レースの時刻表を表示します。オプションを指定すると設定を変更します [管理者]。",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionChannel, Name: "channel", Description: "レースを開催するチャンネル", ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText}},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "spec", Description: "開催時刻 (cron 形式: 分 時 日 月 曜日, 例: 0 12,21 * * *)"},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "betting_minutes", Description: "ベット受付の時間 (分)", MinValue: &[]float64{1}[0], MaxValue: 30},
+					{Type: discordgo.ApplicationCommandOptionBoolean, Name: "enabled", Description: "定時レースを開催する"},
+				},
+			},
+		},
 	}
 }
 
 func (c *HorseRaceCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	subcommand := i.ApplicationCommandData().Options[0]
+	switch subcommand.Name {
+	case "start":
+		c.handleStart(s, i)
+	case "stable":
+		c.handleStable(s, i)
+	case "horse":
+		c.handleHorse(s, i, subcommand.Options[0].StringValue())
+	case "schedule":
+		c.handleSchedule(s, i, subcommand.Options)
+	}
+}
+
+func (c *HorseRaceCommand) handleStart(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -105,18 +161,16 @@ func (c *HorseRaceCommand) Handle(s *discordgo.Session, i *discordgo.Interaction
 		return
 	}
 
-	game := &HorseRaceGame{
-		State:       HRStateBetting,
-		ChannelID:   i.ChannelID,
-		Interaction: i.Interaction,
-		CreatorID:   i.Member.User.ID,
+	game, err := c.newRace(i.GuildID, i.ChannelID, i.Member.User.ID)
+	if err != nil {
+		c.Log.Error("Failed to open horse race", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
 	}
-
-	game.Horses = generateHorses(5, c.rand)
 	embed := c.buildBettingEmbed(game)
 	components := c.buildBettingComponents(game)
 
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
 			Embeds:     []*discordgo.MessageEmbed{embed},
@@ -125,18 +179,50 @@ func (c *HorseRaceCommand) Handle(s *discordgo.Session, i *discordgo.Interaction
 	})
 	if err != nil {
 		c.Log.Error("Failed to send initial race message", "error", err)
+		game.Round.Close()
 		return
 	}
 
 	msg, err := s.InteractionResponse(i.Interaction)
 	if err != nil {
 		c.Log.Error("Failed to get interaction response message", "error", err)
+		game.Round.Close()
 		return
 	}
 	game.MessageID = msg.ID
 	c.races[i.ChannelID] = game
 }
 
+// newRace は、厩舎から出走馬を選んでベット受付中のレースを作ります。
+// creatorID が空なら定時レースとして、ボットのシードで乱数を決めます。
+func (c *HorseRaceCommand) newRace(guildID, channelID, creatorID string) (*HorseRaceGame, error) {
+	stable, err := c.loadStable(guildID)
+	if err != nil {
+		return nil, err
+	}
+	seedOwner := creatorID
+	if seedOwner == "" {
+		seedOwner = c.Session.State.User.ID
+	}
+	round, err := c.Fair.NewRound(guildID, seedOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	game := &HorseRaceGame{
+		State:     HRStateBetting,
+		GuildID:   guildID,
+		ChannelID: channelID,
+		CreatorID: creatorID,
+		Round:     round,
+	}
+	game.Horses = pickField(stable, RaceFieldSize, round.Rand)
+	for n := range game.Horses {
+		game.Horses[n].Form = c.horseForm(guildID, game.Horses[n].ID)
+	}
+	return game, nil
+}
+
 func (c *HorseRaceCommand) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	c.mu.Lock()
 	game, exists := c.races[i.ChannelID]
@@ -146,11 +232,10 @@ func (c *HorseRaceCommand) HandleComponent(s *discordgo.Session, i *discordgo.In
 		return
 	}
 
-	customID := i.MessageComponentData().CustomID
-
-	if strings.HasPrefix(customID, BetButtonPrefix) {
-		c.handleBetButton(s, i, game)
-	} else if customID == StartRaceButtonID {
+	switch i.MessageComponentData().CustomID {
+	case HorseBetSelectID:
+		c.handleBetSelect(s, i, game)
+	case StartRaceButtonID:
 		c.handleStartRaceButton(s, i, game)
 	}
 }
@@ -161,17 +246,18 @@ func (c *HorseRaceCommand) HandleModal(s *discordgo.Session, i *discordgo.Intera
 	c.mu.Unlock()
 
 	if !exists {
+		sendErrorResponse(s, i, "ベット受付は終了しました。")
 		return
 	}
 
 	customID := i.ModalSubmitData().CustomID
-	if strings.HasPrefix(customID, BetModalCustomID) {
+	if strings.HasPrefix(customID, HorseBetModalPrefix) {
 		c.handleBetModalSubmit(s, i, game)
 	}
 }
 
 func (c *HorseRaceCommand) GetComponentIDs() []string {
-	return []string{BetButtonPrefix, StartRaceButtonID, BetModalCustomID}
+	return []string{HorseBetSelectID, StartRaceButtonID, HorseBetModalPrefix}
 }
 
 func (c *HorseRaceCommand) GetCategory() string {
@@ -180,7 +266,7 @@ func (c *HorseRaceCommand) GetCategory() string {
 
 // --- Handler Logic ---
 
-func (c *HorseRaceCommand) handleBetButton(s *discordgo.Session, i *discordgo.InteractionCreate, game *HorseRaceGame) {
+func (c *HorseRaceCommand) handleBetSelect(s *discordgo.Session, i *discordgo.InteractionCreate, game *HorseRaceGame) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -188,14 +274,36 @@ func (c *HorseRaceCommand) handleBetButton(s *discordgo.Session, i *discordgo.In
 		sendErrorResponse(s, i, "ベット受付は終了しました。")
 		return
 	}
+	values := i.MessageComponentData().Values
+	if len(values) == 0 {
+		return
+	}
+	betType := findHorseBetType(values[0])
+	if betType == nil {
+		sendErrorResponse(s, i, "不明な賭け方です。")
+		return
+	}
 
-	horseIndexStr := strings.TrimPrefix(i.MessageComponentData().CustomID, BetButtonPrefix)
-	horseIndex, _ := strconv.Atoi(horseIndexStr)
-
+	label, placeholder := "馬番", "1"
+	if betType.Picks > 1 {
+		label = fmt.Sprintf("馬番 (1着から順に%d頭、スペース区切り)", betType.Picks)
+		placeholder = strings.Join([]string{"3", "1", "5"}[:betType.Picks], " ")
+	}
 	modal := discordgo.InteractionResponseData{
-		CustomID: BetModalCustomID + "_" + horseIndexStr,
-		Title:    fmt.Sprintf("%s にベット", game.Horses[horseIndex].Name),
+		CustomID: HorseBetModalPrefix + betType.ID,
+		Title:    fmt.Sprintf("%s にベット", betType.Label),
 		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "horses",
+						Label:       label,
+						Style:       discordgo.TextInputShort,
+						Placeholder: placeholder,
+						Required:    true,
+					},
+				},
+			},
 			discordgo.ActionsRow{
 				Components: []discordgo.MessageComponent{
 					discordgo.TextInput{
@@ -225,16 +333,32 @@ func (c *HorseRaceCommand) handleBetModalSubmit(s *discordgo.Session, i *discord
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	customID := i.ModalSubmitData().CustomID
-	horseIndexStr := strings.TrimPrefix(customID, BetModalCustomID+"_")
-	horseIndex, _ := strconv.Atoi(horseIndexStr)
-	betAmountStr := i.ModalSubmitData().Components[0].(*discordgo.ActionsRow).Components[0].(*discordgo.TextInput).Value
-	betAmount, err := strconv.ParseInt(betAmountStr, 10, 64)
+	if game.State != HRStateBetting {
+		sendErrorResponse(s, i, "ベット受付は終了しました。")
+		return
+	}
+	data := i.ModalSubmitData()
+	betType := findHorseBetType(strings.TrimPrefix(data.CustomID, HorseBetModalPrefix))
+	if betType == nil {
+		sendErrorResponse(s, i, "不明な賭け方です。")
+		return
+	}
+	horsesStr := data.Components[0].(*discordgo.ActionsRow).Components[0].(*discordgo.TextInput).Value
+	picks, err := parseHorsePicks(betType, horsesStr, len(game.Horses))
+	if err != nil {
+		sendErrorResponse(s, i, err.Error())
+		return
+	}
+	betAmountStr := data.Components[1].(*discordgo.ActionsRow).Components[0].(*discordgo.TextInput).Value
+	betAmount, err := strconv.ParseInt(strings.TrimSpace(betAmountStr), 10, 64)
 
 	if err != nil || betAmount <= 0 {
 		sendErrorResponse(s, i, "有効なベット額を入力してください。")
 		return
 	}
+	if rejectGamblingBet(s, i, c.Store, c.Log, betAmount) {
+		return
+	}
 
 	userID := i.Member.User.ID
 	casinoData, err := c.Store.GetCasinoData(i.GuildID, userID)
@@ -256,10 +380,12 @@ func (c *HorseRaceCommand) handleBetModalSubmit(s *discordgo.Session, i *discord
 		sendErrorResponse(s, i, "ベット処理中にエラーが発生しました。")
 		return
 	}
+	pendingBets.add(i.GuildID, userID, storage.GameHorseRace, betAmount)
 
-	game.Bets = append(game.Bets, Bet{UserID: userID, HorseIndex: horseIndex, Amount: betAmount})
+	bet := Bet{UserID: userID, Type: betType, Horses: picks, Amount: betAmount}
+	game.Bets = append(game.Bets, bet)
 
-	content := fmt.Sprintf("✅ <@%s> が **%s** に **%d** チップをベットしました。", userID, game.Horses[horseIndex].Name, betAmount)
+	content := fmt.Sprintf("✅ **%s %s** に **%d** チップをベットしました。", betType.Label, bet.Picks(), betAmount)
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
@@ -267,6 +393,125 @@ func (c *HorseRaceCommand) handleBetModalSubmit(s *discordgo.Session, i *discord
 			Flags:   discordgo.MessageFlagsEphemeral,
 		},
 	})
+
+	// オッズはプールから計算するため、ベットのたびに表示を更新する
+	if err := c.editRace(s, game, c.buildBettingEmbed(game), nil); err != nil {
+		c.Log.Error("Failed to edit horse race betting embed", "error", err)
+	}
+}
+
+// --- Stable ---
+
+// loadStable は、サーバーの厩舎の馬を返します。足りない分は新しい馬を入厩させます。
+func (c *HorseRaceCommand) loadStable(guildID string) ([]storage.Horse, error) {
+	stable, err := c.Store.GetStable(guildID)
+	if err != nil {
+		return nil, err
+	}
+	if len(stable) >= HorseStableSize {
+		return stable, nil
+	}
+
+	taken := make(map[string]bool, len(stable))
+	for _, h := range stable {
+		taken[h.Name] = true
+	}
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for len(stable) < HorseStableSize {
+		h := newHorse(guildID, taken, r)
+		if err := c.Store.AddHorse(&h); err != nil {
+			return nil, err
+		}
+		taken[h.Name] = true
+		stable = append(stable, h)
+	}
+	return stable, nil
+}
+
+// horseForm は、馬の近走の着順を "1-3-2" のように新しい順で返します。
+func (c *HorseRaceCommand) horseForm(guildID string, horseID int64) string {
+	results, err := c.Store.GetHorseResults(guildID, horseID, HorseFormRaces)
+	if err != nil {
+		c.Log.Error("Failed to get horse results", "error", err, "horseID", horseID)
+		return "?"
+	}
+	if len(results) == 0 {
+		return "新馬"
+	}
+	positions := make([]string, len(results))
+	for n, result := range results {
+		positions[n] = strconv.Itoa(result.Position)
+	}
+	return strings.Join(positions, "-")
+}
+
+func (c *HorseRaceCommand) handleStable(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	stable, err := c.loadStable(i.GuildID)
+	if err != nil {
+		c.Log.Error("Failed to load horse stable", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+
+	var sb strings.Builder
+	for _, h := range stable {
+		fmt.Fprintf(&sb, "%s **%s** (%d歳) %s\n　%d戦%d勝 / 近走: %s\n", h.Emoji, h.Name, h.Age(), horseStats(h), h.Starts, h.Wins, c.horseForm(i.GuildID, h.ID))
+	}
+	embed := &discordgo.MessageEmbed{
+		Title:       "🐴 厩舎",
+		Description: sb.String(),
+		Color:       0x8e5a2b, // Brown
+		Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("馬は %d レースごとに1歳年を取り、%d歳で引退します。", storage.HorseRacesPerYear, storage.HorseRetireAge)},
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Embeds: []*discordgo.MessageEmbed{embed}},
+	})
+}
+
+func (c *HorseRaceCommand) handleHorse(s *discordgo.Session, i *discordgo.InteractionCreate, name string) {
+	h, err := c.Store.GetHorseByName(i.GuildID, name)
+	if err != nil {
+		c.Log.Error("Failed to get horse", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+	if h == nil {
+		sendErrorResponse(s, i, fmt.Sprintf("「%s」という馬は厩舎にいません。", name))
+		return
+	}
+	results, err := c.Store.GetHorseResults(i.GuildID, h.ID, HorseHistoryRaces)
+	if err != nil {
+		c.Log.Error("Failed to get horse results", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+
+	status := fmt.Sprintf("%d歳", h.Age())
+	if h.Retired {
+		status += " (引退)"
+	}
+	var history strings.Builder
+	for _, result := range results {
+		fmt.Fprintf(&history, "<t:%d:d> %d着 / %d頭\n", result.RacedAt.Unix(), result.Position, result.FieldSize)
+	}
+	if history.Len() == 0 {
+		history.WriteString("まだレースに出ていません。")
+	}
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("%s %s", h.Emoji, h.Name),
+		Color: 0x8e5a2b, // Brown
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "年齢", Value: status, Inline: true},
+			{Name: "能力", Value: horseStats(*h), Inline: true},
+			{Name: "通算成績", Value: fmt.Sprintf("%d戦%d勝 (3着以内 %d回)", h.Starts, h.Wins, h.Top3), Inline: true},
+			{Name: fmt.Sprintf("直近%dレース", HorseHistoryRaces), Value: history.String()},
+		},
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Embeds: []*discordgo.MessageEmbed{embed}},
+	})
 }
 
 // --- Race Logic ---
@@ -278,6 +523,9 @@ func (c *HorseRaceCommand) startRace(s *discordgo.Session, game *HorseRaceGame)
 		return
 	}
 	game.State = HRStateRacing
+	if game.timer != nil {
+		game.timer.Stop()
+	}
 	c.mu.Unlock()
 
 	embed := &discordgo.MessageEmbed{
@@ -286,17 +534,18 @@ func (c *HorseRaceCommand) startRace(s *discordgo.Session, game *HorseRaceGame)
 		Color:       0xf1c40f, // Yellow
 	}
 	var emptyComponents []discordgo.MessageComponent
-	_, err := s.InteractionResponseEdit(game.Interaction, &discordgo.WebhookEdit{Embeds: &[]*discordgo.MessageEmbed{embed}, Components: &emptyComponents})
-	if err != nil {
+	if err := c.editRace(s, game, embed, &emptyComponents); err != nil {
 		c.Log.Error("Failed to edit message for race start", "error", err)
 		return
 	}
 
 	time.Sleep(2 * time.Second)
 
+	// レースの展開は乱数から先にすべて決めておき、1コマずつ表示する
+	frames, order := simulateRace(game.Horses, game.Round.Rand)
 	horsePositions := make([]int, len(game.Horses))
 
-	for {
+	for frame, positions := range frames {
 		c.mu.Lock()
 		if game.State == HRStateFinished {
 			c.mu.Unlock()
@@ -304,40 +553,16 @@ func (c *HorseRaceCommand) startRace(s *discordgo.Session, game *HorseRaceGame)
 		}
 		c.mu.Unlock()
 
-		winner := -1
-		for i := range horsePositions {
-			// All horses move, but the distance depends on their condition
-			baseMove := c.rand.Intn(3) + 1 // Base move of 1-3
-			modifier := 0
-			switch game.Horses[i].Condition {
-			case "絶好調":
-				modifier = 1
-			case "不調":
-				modifier = -1
-			}
-			move := baseMove + modifier
-			if move < 1 {
-				move = 1 // Ensure horse moves at least 1 space
-			}
-			horsePositions[i] += move
-
-			if horsePositions[i] >= RaceTrackLength {
-				horsePositions[i] = RaceTrackLength
-				winner = i
-				break
-			}
-		}
-
+		horsePositions = positions
 		trackEmbed := c.buildRaceTrackEmbed(game, horsePositions)
-		_, err := s.InteractionResponseEdit(game.Interaction, &discordgo.WebhookEdit{Embeds: &[]*discordgo.MessageEmbed{trackEmbed}})
-		if err != nil {
+		if err := c.editRace(s, game, trackEmbed, nil); err != nil {
 			c.Log.Error("Failed to edit race track embed", "error", err)
-			c.finishRace(s, game, -1, horsePositions)
+			c.finishRace(s, game, nil, horsePositions)
 			return
 		}
 
-		if winner != -1 {
-			c.finishRace(s, game, winner, horsePositions)
+		if frame == len(frames)-1 {
+			c.finishRace(s, game, order, horsePositions)
 			return
 		}
 
@@ -345,7 +570,47 @@ func (c *HorseRaceCommand) startRace(s *discordgo.Session, game *HorseRaceGame)
 	}
 }
 
-func (c *HorseRaceCommand) finishRace(s *discordgo.Session, game *HorseRaceGame, winnerIndex int, positions []int) {
+// simulateRace は、r からレースの展開を決め、各コマの馬の位置と着順 (馬の番号を1着から並べたもの) を返します。
+// 全馬がゴールするまで走らせ、同じコマでゴールした馬はゴール線をより大きく越えた方を先着とします。
+func simulateRace(horses []Horse, r *rand.Rand) ([][]int, []int) {
+	distances := make([]float64, len(horses))
+	finished := make([]bool, len(horses))
+	var frames [][]int
+	var order []int
+	for len(order) < len(horses) {
+		var crossed []int
+		for i, horse := range horses {
+			if finished[i] {
+				continue
+			}
+			// 安定した馬ほど1コマごとの走りのムラが小さい
+			spread := 1.8 - float64(horse.Consistency)/100
+			move := horse.Ability * (2 + (r.Float64()-0.5)*2*spread)
+			// 後半はスタミナのある馬ほど伸びる
+			if distances[i] > RaceTrackLength/2 {
+				move *= 0.85 + 0.3*float64(horse.Stamina)/100
+			}
+			distances[i] += max(move, 0.5)
+			if distances[i] >= RaceTrackLength {
+				crossed = append(crossed, i)
+			}
+		}
+		sort.SliceStable(crossed, func(x, y int) bool { return distances[crossed[x]] > distances[crossed[y]] })
+		for _, i := range crossed {
+			finished[i] = true
+			order = append(order, i)
+		}
+
+		positions := make([]int, len(horses))
+		for i, distance := range distances {
+			positions[i] = min(int(distance), RaceTrackLength)
+		}
+		frames = append(frames, positions)
+	}
+	return frames, order
+}
+
+func (c *HorseRaceCommand) finishRace(s *discordgo.Session, game *HorseRaceGame, order []int, positions []int) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -356,7 +621,7 @@ func (c *HorseRaceCommand) finishRace(s *discordgo.Session, game *HorseRaceGame,
 
 	var resultEmbed *discordgo.MessageEmbed
 
-	if winnerIndex == -1 {
+	if order == nil {
 		resultEmbed = &discordgo.MessageEmbed{
 			Title:       "レース中止",
 			Description: "レース中にエラーが発生したため、中止されました。ベットは返金されます。",
@@ -364,7 +629,8 @@ func (c *HorseRaceCommand) finishRace(s *discordgo.Session, game *HorseRaceGame,
 		}
 		// Refund all bets
 		for _, bet := range game.Bets {
-			casinoData, err := c.Store.GetCasinoData(game.Interaction.GuildID, bet.UserID)
+			pendingBets.release(game.GuildID, bet.UserID, storage.GameHorseRace, bet.Amount)
+			casinoData, err := c.Store.GetCasinoData(game.GuildID, bet.UserID)
 			if err != nil {
 				c.Log.Error("Failed to get user data for refund", "error", err, "userID", bet.UserID)
 				continue
@@ -375,92 +641,173 @@ func (c *HorseRaceCommand) finishRace(s *discordgo.Session, game *HorseRaceGame,
 			}
 		}
 	} else {
-		winnerHorse := game.Horses[winnerIndex]
-		var totalPot int64 = 0
-		var totalWinnerBets int64 = 0
-		var winners []Bet
-
-		for _, bet := range game.Bets {
-			totalPot += bet.Amount
-			if bet.HorseIndex == winnerIndex {
-				winner := bet
-				winners = append(winners, winner)
-				totalWinnerBets += bet.Amount
-			}
-		}
-
+		winnerHorse := game.Horses[order[0]]
 		resultEmbed = &discordgo.MessageEmbed{
 			Title:       fmt.Sprintf("🏁 レース終了！ 優勝は %s %s！", winnerHorse.Emoji, winnerHorse.Name),
 			Description: c.buildRaceTrack(game, positions),
 			Color:       0x2ecc71, // Green
+			Footer:      &discordgo.MessageEmbedFooter{Text: c.raceFooter(game)},
 		}
 
-		var resultDescription strings.Builder
-		if len(winners) > 0 {
-			resultDescription.WriteString("**🎉 勝者の配当**\n")
-			for _, winner := range winners {
-				// Parimutuel betting: payout is proportional to the bet amount relative to the winners' pool
-				payout := int64(float64(winner.Amount) / float64(totalWinnerBets) * float64(totalPot))
-				casinoData, err := c.Store.GetCasinoData(game.Interaction.GuildID, winner.UserID)
-				if err != nil {
-					c.Log.Error("Failed to get winner data for payout", "error", err, "userID", winner.UserID)
-					continue
-				}
-				casinoData.Chips += payout
-				if err := c.Store.UpdateCasinoData(casinoData); err != nil {
-					c.Log.Error("Failed to update winner data after race", "error", err, "userID", winner.UserID)
-				}
-				profit := payout - winner.Amount
-				resultDescription.WriteString(fmt.Sprintf("👑 <@%s> は **%d** チップをベットして **%d** チップの配当を獲得！ (収支: **+%d**)\n", winner.UserID, winner.Amount, payout, profit))
-			}
-		} else {
-			resultDescription.WriteString("**💔 勝者なし**\n優勝した馬には誰もベットしていませんでした。\n")
-			if len(game.Bets) > 0 {
-				resultDescription.WriteString("\n**参加者一覧:**\n")
-				for _, bet := range game.Bets {
-					resultDescription.WriteString(fmt.Sprintf(" <@%s> は **%d** チップを失いました...\n", bet.UserID, bet.Amount))
-				}
-			}
+		var finish strings.Builder
+		for place, n := range order[:min(3, len(order))] {
+			fmt.Fprintf(&finish, "%d着: **%d. %s %s**\n", place+1, n+1, game.Horses[n].Emoji, game.Horses[n].Name)
 		}
+		resultEmbed.Fields = append(resultEmbed.Fields, &discordgo.MessageEmbedField{Name: "着順", Value: finish.String()})
 
-		if len(game.Bets) > 0 {
-			resultEmbed.Fields = []*discordgo.MessageEmbedField{{
-				Name:  "ベット結果",
-				Value: resultDescription.String(),
-			}}
-		} else {
-			resultEmbed.Fields = []*discordgo.MessageEmbedField{{
-				Name:  "ベット結果",
-				Value: "誰もベットしていませんでした。",
-			}}
+		resultEmbed.Fields = append(resultEmbed.Fields, &discordgo.MessageEmbedField{Name: "ベット結果", Value: c.settleBets(s, game, order)})
+		if retired := c.recordResults(game, order); retired != "" {
+			resultEmbed.Fields = append(resultEmbed.Fields, &discordgo.MessageEmbedField{Name: "🎓 引退", Value: retired})
 		}
 	}
 
-	_, err := s.InteractionResponseEdit(game.Interaction, &discordgo.WebhookEdit{Embeds: &[]*discordgo.MessageEmbed{resultEmbed}})
-	if err != nil {
+	if err := c.editRace(s, game, resultEmbed, nil); err != nil {
 		c.Log.Error("Failed to edit final race result", "error", err)
 	}
 
 	delete(c.races, game.ChannelID)
+	game.Round.Close()
+}
+
+// settleBets は、着順に従ってベットを精算し、結果の説明を返します。
+func (c *HorseRaceCommand) settleBets(s *discordgo.Session, game *HorseRaceGame, order []int) string {
+	if len(game.Bets) == 0 {
+		return "誰もベットしていませんでした。"
+	}
+
+	payouts := settleHorseBets(game.Bets, order)
+	type userResult struct {
+		wagered, returned int64
+		hitWinner         bool
+	}
+	results := make(map[string]*userResult)
+	var users []string
+	var sb strings.Builder
+	for n, bet := range game.Bets {
+		result, ok := results[bet.UserID]
+		if !ok {
+			result = &userResult{}
+			results[bet.UserID] = result
+			users = append(users, bet.UserID)
+		}
+		result.wagered += bet.Amount
+		result.returned += payouts[n]
+		if payouts[n] > 0 {
+			// 勝ち馬を1着に選んだベットだけを実績の的中として数える
+			if bet.Type.ID == "win" || bet.Type.Ordered {
+				result.hitWinner = true
+			}
+			fmt.Fprintf(&sb, "👑 <@%s> %s %s `%d` → **%d** チップ (%.2f倍)\n", bet.UserID, bet.Type.Label, bet.Picks(), bet.Amount, payouts[n], float64(payouts[n])/float64(bet.Amount))
+		} else {
+			fmt.Fprintf(&sb, "💔 <@%s> %s %s `%d`\n", bet.UserID, bet.Type.Label, bet.Picks(), bet.Amount)
+		}
+	}
+
+	for _, userID := range users {
+		result := results[userID]
+		if result.returned > 0 {
+			casinoData, err := c.Store.GetCasinoData(game.GuildID, userID)
+			if err == nil {
+				casinoData.Chips += result.returned
+				err = c.Store.UpdateCasinoData(casinoData)
+			}
+			if err != nil {
+				c.Log.Error("Failed to update winner data after race", "error", err, "userID", userID)
+			}
+		}
+		recordGameRound(c.Store, c.Log, game.GuildID, userID, storage.GameHorseRace, result.wagered, result.returned)
+		progress := map[string]int64{counterChipsWon: result.returned}
+		if result.hitWinner {
+			progress[counterHorseRaceWins] = 1
+		}
+		recordAchievements(s, c.Store, c.Log, game.ChannelID, game.GuildID, userID, progress)
+	}
+	return truncateField(sb.String())
+}
+
+// recordResults は、出走した全馬の着順を戦績に残し、引退の年齢に達した馬を引退させます。
+// 引退した馬の説明を返します。
+func (c *HorseRaceCommand) recordResults(game *HorseRaceGame, order []int) string {
+	now := time.Now().UTC()
+	results := make([]storage.HorseResult, len(order))
+	for place, n := range order {
+		results[place] = storage.HorseResult{HorseID: game.Horses[n].ID, Position: place + 1, FieldSize: len(order), RacedAt: now}
+	}
+	if err := c.Store.RecordHorseRace(game.GuildID, results); err != nil {
+		c.Log.Error("Failed to record horse race results", "error", err)
+		return ""
+	}
+
+	var retired strings.Builder
+	for _, horse := range game.Horses {
+		horse.Starts++
+		if horse.Age() < storage.HorseRetireAge {
+			continue
+		}
+		if err := c.Store.RetireHorse(game.GuildID, horse.ID); err != nil {
+			c.Log.Error("Failed to retire horse", "error", err, "horseID", horse.ID)
+			continue
+		}
+		fmt.Fprintf(&retired, "%s %s が %d戦%d勝で引退しました。お疲れさまでした！\n", horse.Emoji, horse.Name, horse.Starts, horse.Wins)
+	}
+	return retired.String()
 }
 
 // --- Helper Functions ---
 
-var horseNames = []string{"スミレバカノフ", "シンボリルドルフ", "ディープインパクト", "オルフェーヴル", "キタサンブラック", "ハルウララ", "サイレンススズカ", "ウオッカ", "ダイワスカーレット", "ゴールドシップ", "メジロマックイーン"}
+var horseNames = []string{
+	"スミレバカノフ", "シンボリルドルフ", "ディープインパクト", "オルフェーヴル", "キタサンブラック", "ハルウララ",
+	"サイレンススズカ", "ウオッカ", "ダイワスカーレット", "ゴールドシップ", "メジロマックイーン", "トウカイテイオー",
+	"オグリキャップ", "ナリタブライアン", "エルコンドルパサー", "グラスワンダー", "スペシャルウィーク", "マルゼンスキー",
+	"ライスシャワー", "ミホノブルボン", "タマモクロス", "アグネスタキオン", "マンハッタンカフェ", "テイエムオペラオー",
+}
 var horseEmojis = []string{"🏇", "🐎", "🐴", "🦄", "🦓"}
 
-func generateHorses(count int, r *rand.Rand) []Horse {
-	names := make([]string, len(horseNames))
-	copy(names, horseNames)
-	r.Shuffle(len(names), func(i, j int) { names[i], names[j] = names[j], names[i] })
+// newHorse は、能力値をランダムに決めた新しい馬を作ります。名前は厩舎の馬と重ならないように選びます。
+func newHorse(guildID string, taken map[string]bool, r *rand.Rand) storage.Horse {
+	var names []string
+	for _, name := range horseNames {
+		if !taken[name] {
+			names = append(names, name)
+		}
+	}
+	name := ""
+	if len(names) > 0 {
+		name = names[r.Intn(len(names))]
+	} else {
+		// 名前を使い切ったら、2代目・3代目として同じ名前を使う
+		for generation := 2; name == ""; generation++ {
+			candidate := fmt.Sprintf("%s%d世", horseNames[r.Intn(len(horseNames))], generation)
+			if !taken[candidate] {
+				name = candidate
+			}
+		}
+	}
+	return storage.Horse{
+		GuildID:     guildID,
+		Name:        name,
+		Emoji:       horseEmojis[r.Intn(len(horseEmojis))],
+		Speed:       40 + r.Intn(56),
+		Stamina:     40 + r.Intn(56),
+		Consistency: 40 + r.Intn(56),
+	}
+}
 
-	horses := make([]Horse, count)
+// horseStats は、馬の能力値を表示用に返します。
+func horseStats(h storage.Horse) string {
+	return fmt.Sprintf("速 %d / 持 %d / 安 %d", h.Speed, h.Stamina, h.Consistency)
+}
+
+// pickField は、r で厩舎から出走馬を選び、それぞれのその日の状態を決めます。
+func pickField(stable []storage.Horse, count int, r *rand.Rand) []Horse {
+	count = min(count, len(stable))
 	totalWeight := 0
 	for _, cond := range conditions {
 		totalWeight += cond.Weight
 	}
 
-	for i := 0; i < count; i++ {
+	horses := make([]Horse, count)
+	for i, n := range r.Perm(len(stable))[:count] {
 		randomNum := r.Intn(totalWeight)
 		var selectedCond Condition
 		currentWeight := 0
@@ -472,70 +819,116 @@ func generateHorses(count int, r *rand.Rand) []Horse {
 			}
 		}
 
+		h := stable[n]
+		ageModifier, ok := horseAgeModifiers[h.Age()]
+		if !ok {
+			ageModifier = horseAgeModifiers[storage.HorseRetireAge-1]
+		}
+		base := 0.85 + 0.3*(0.6*float64(h.Speed)+0.2*float64(h.Stamina)+0.2*float64(h.Consistency))/100
 		horses[i] = Horse{
-			Name:      names[i],
-			Emoji:     horseEmojis[i%len(horseEmojis)], // Use modulo to prevent out of bounds
-			Odds:      1.5 + r.Float64()*15,
+			Horse:     h,
 			Condition: selectedCond.Name,
-			Modifier:  selectedCond.Modifier,
+			Ability:   base * ageModifier * selectedCond.Modifier,
 		}
 	}
+
+	// 予想オッズは、固定の乱数で何度もレースを走らせた勝率から見積もる
+	chances := make([]int, count)
+	sim := rand.New(rand.NewSource(1))
+	const trials = 500
+	for t := 0; t < trials; t++ {
+		_, order := simulateRace(horses, sim)
+		chances[order[0]]++
+	}
+	for i := range horses {
+		horses[i].Odds = trials / float64(max(chances[i], 1))
+	}
 	return horses
 }
 
 func (c *HorseRaceCommand) buildBettingEmbed(game *HorseRaceGame) *discordgo.MessageEmbed {
-	fields := make([]*discordgo.MessageEmbedField, len(game.Horses))
+	odds := winOdds(game.Bets, len(game.Horses))
+	fields := make([]*discordgo.MessageEmbedField, 0, len(game.Horses)+1)
 	for i, horse := range game.Horses {
-		fields[i] = &discordgo.MessageEmbedField{
+		current := "—"
+		if odds[i] > 0 {
+			current = fmt.Sprintf("%.2f倍", odds[i])
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{
 			Name:   fmt.Sprintf("%d. %s %s", i+1, horse.Emoji, horse.Name),
-			Value:  fmt.Sprintf("状態: **%s**\n単勝: %.2f倍", horse.Condition, horse.Odds),
+			Value:  fmt.Sprintf("%d歳 / 状態: **%s**\n%s\n近走: %s\n予想: %.1f倍 / 単勝: **%s**", horse.Age(), horse.Condition, horseStats(horse.Horse), horse.Form, horse.Odds, current),
 			Inline: true,
-		}
+		})
 	}
 
+	var pools strings.Builder
+	for n := range horseBetTypes {
+		betType := &horseBetTypes[n]
+		fmt.Fprintf(&pools, "%s: `%d` ", betType.Label, horsePool(game.Bets, betType))
+	}
+	fields = append(fields, &discordgo.MessageEmbedField{Name: "プール (チップ)", Value: pools.String()})
+
+	description := "メニューから賭け方を選んでください！\n配当は賭け方ごとのプールを当たったベットで分け合うため、オッズはベットのたびに変わります。"
+	if !game.CloseAt.IsZero() {
+		description += fmt.Sprintf("\n\n⏰ ベット締め切り・発走: <t:%d:R>", game.CloseAt.Unix())
+	}
 	return &discordgo.MessageEmbed{
 		Title:       "🏇 競馬 - ベット受付中",
-		Description: "ベットしたい馬のボタンを押してください！",
+		Description: description,
 		Color:       0x3498db, // Blue
 		Fields:      fields,
+		Footer:      &discordgo.MessageEmbedFooter{Text: c.raceFooter(game)},
 	}
 }
 
 func (c *HorseRaceCommand) buildBettingComponents(game *HorseRaceGame) []discordgo.MessageComponent {
-	var actionRows []discordgo.ActionsRow
-	var buttons []discordgo.MessageComponent
-
-	for i, horse := range game.Horses {
-		buttons = append(buttons, discordgo.Button{
-			Label:    fmt.Sprintf("%d. %s", i+1, horse.Name),
-			Style:    discordgo.SecondaryButton,
-			CustomID: BetButtonPrefix + strconv.Itoa(i),
-			Emoji:    &discordgo.ComponentEmoji{Name: horse.Emoji},
-		})
-		if (i+1)%5 == 0 || i == len(game.Horses)-1 {
-			actionRows = append(actionRows, discordgo.ActionsRow{Components: buttons})
-			buttons = []discordgo.MessageComponent{}
+	options := make([]discordgo.SelectMenuOption, len(horseBetTypes))
+	for n, betType := range horseBetTypes {
+		options[n] = discordgo.SelectMenuOption{
+			Label:       betType.Label,
+			Value:       betType.ID,
+			Description: betType.Description,
 		}
 	}
-
-	actionRows = append(actionRows, discordgo.ActionsRow{
-		Components: []discordgo.MessageComponent{
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{CustomID: HorseBetSelectID, Placeholder: "賭け方を選択...", Options: options},
+		}},
+	}
+	// 定時レースは締め切り時刻に自動で始まるため、開始ボタンを出さない
+	if game.CreatorID != "" {
+		components = append(components, discordgo.ActionsRow{Components: []discordgo.MessageComponent{
 			discordgo.Button{
 				Label:    "レース開始",
 				Style:    discordgo.SuccessButton,
 				CustomID: StartRaceButtonID,
 				Emoji:    &discordgo.ComponentEmoji{Name: "🏁"},
 			},
-		},
-	})
-
-	components := make([]discordgo.MessageComponent, len(actionRows))
-	for i, row := range actionRows {
-		components[i] = row
+		}})
 	}
 	return components
 }
 
+// raceFooter は、レースの乱数の検証用の情報を、誰のシードで決まるかを添えて返します。
+func (c *HorseRaceCommand) raceFooter(game *HorseRaceGame) string {
+	if game.CreatorID == "" {
+		return game.Round.Footer() + " (定時レース)"
+	}
+	return game.Round.Footer() + " (開始者)"
+}
+
+// editRace は、レースのメッセージを更新します。components が nil ならボタンはそのままです。
+// 定時レースにはインタラクションがないため、チャンネルのメッセージとして編集します。
+func (c *HorseRaceCommand) editRace(s *discordgo.Session, game *HorseRaceGame, embed *discordgo.MessageEmbed, components *[]discordgo.MessageComponent) error {
+	_, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:         game.MessageID,
+		Channel:    game.ChannelID,
+		Embeds:     &[]*discordgo.MessageEmbed{embed},
+		Components: components,
+	})
+	return err
+}
+
 func (c *HorseRaceCommand) buildRaceTrackEmbed(game *HorseRaceGame, positions []int) *discordgo.MessageEmbed {
 	return &discordgo.MessageEmbed{
 		Title:       "🏇 レース中！",
@@ -0,0 +1,166 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// horseBetType は、競馬の賭け方です。賭け方ごとに別のプールを作り、パリミュチュエル方式で分配します。
+type horseBetType struct {
+	ID          string
+	Label       string
+	Description string
+	Picks       int  // 選ぶ馬の数
+	Places      int  // 選んだ馬が何着までに入れば当たりか (着順を当てる賭け方では使わない)
+	Ordered     bool // 選んだ馬が選んだ順に1着から入れば当たり
+}
+
+var horseBetTypes = []horseBetType{
+	{ID: "win", Label: "単勝", Description: "1着になる馬を当てる", Picks: 1, Places: 1},
+	{ID: "place", Label: "プレース", Description: "2着以内に入る馬を当てる", Picks: 1, Places: 2},
+	{ID: "show", Label: "ショー", Description: "3着以内に入る馬を当てる", Picks: 1, Places: 3},
+	{ID: "exacta", Label: "馬単", Description: "1着と2着を順番通りに当てる", Picks: 2, Ordered: true},
+	{ID: "trifecta", Label: "3連単", Description: "1着から3着までを順番通りに当てる", Picks: 3, Ordered: true},
+}
+
+// findHorseBetType は、ID の賭け方を返します。見つからなければ nil を返します。
+func findHorseBetType(id string) *horseBetType {
+	for n := range horseBetTypes {
+		if horseBetTypes[n].ID == id {
+			return &horseBetTypes[n]
+		}
+	}
+	return nil
+}
+
+// Bet はベット情報を表します。
+type Bet struct {
+	UserID string
+	Type   *horseBetType
+	Horses []int // 選んだ馬の番号 (0始まり)。着順を当てる賭け方では着順通り
+	Amount int64
+}
+
+// Hits は、着順 order (馬の番号を1着から並べたもの) でベットが当たるかを返します。
+func (b Bet) Hits(order []int) bool {
+	if b.Type.Ordered {
+		for n, horse := range b.Horses {
+			if order[n] != horse {
+				return false
+			}
+		}
+		return true
+	}
+	for _, horse := range order[:min(b.Type.Places, len(order))] {
+		if horse == b.Horses[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// Picks は、選んだ馬番を表示用に返します。
+func (b Bet) Picks() string {
+	numbers := make([]string, len(b.Horses))
+	for n, horse := range b.Horses {
+		numbers[n] = strconv.Itoa(horse + 1)
+	}
+	return strings.Join(numbers, "→")
+}
+
+// parseHorsePicks は、入力された馬番を賭け方に合わせて確認し、0始まりの番号で返します。
+func parseHorsePicks(betType *horseBetType, input string, fieldSize int) ([]int, error) {
+	fields := strings.FieldsFunc(input, func(r rune) bool { return r == ' ' || r == ',' || r == '、' || r == '-' || r == '>' || r == '→' })
+	if len(fields) != betType.Picks {
+		return nil, fmt.Errorf("馬番を %d つ入力してください。", betType.Picks)
+	}
+	picks := make([]int, 0, len(fields))
+	seen := make(map[int]bool)
+	for _, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 1 || n > fieldSize {
+			return nil, fmt.Errorf("馬番は 1〜%d で入力してください。", fieldSize)
+		}
+		if seen[n] {
+			return nil, fmt.Errorf("同じ馬番を2回選ぶことはできません。")
+		}
+		seen[n] = true
+		picks = append(picks, n-1)
+	}
+	return picks, nil
+}
+
+// horsePool は、賭け方ごとのベットの合計です。
+func horsePool(bets []Bet, betType *horseBetType) int64 {
+	var pool int64
+	for _, bet := range bets {
+		if bet.Type == betType {
+			pool += bet.Amount
+		}
+	}
+	return pool
+}
+
+// winOdds は、単勝のプールから計算した、各馬の現在の単勝オッズ (払い戻しの倍率) を返します。
+// まだ誰も賭けていない馬は 0 です。
+func winOdds(bets []Bet, fieldSize int) []float64 {
+	win := findHorseBetType("win")
+	stakes := make([]int64, fieldSize)
+	for _, bet := range bets {
+		if bet.Type == win {
+			stakes[bet.Horses[0]] += bet.Amount
+		}
+	}
+	pool := horsePool(bets, win)
+	odds := make([]float64, fieldSize)
+	for n, stake := range stakes {
+		if stake > 0 {
+			odds[n] = float64(pool) / float64(stake)
+		}
+	}
+	return odds
+}
+
+// settleHorseBets は、賭け方ごとのプールを着順に従って当たったベットに分配し、ベットごとの払い戻し (賭け金を含む) を返します。
+// 当たりが複数の馬に分かれる賭け方 (プレース・ショー) では、外れたベットの分を当たった馬ごとに等分してから賭け金に応じて分けます。
+// 当たったベットがない賭け方のプールは払い戻されません。
+func settleHorseBets(bets []Bet, order []int) []int64 {
+	payouts := make([]int64, len(bets))
+	for n := range horseBetTypes {
+		betType := &horseBetTypes[n]
+		pool := horsePool(bets, betType)
+
+		// 当たったベットを、当たりの馬 (着順を当てる賭け方では1組) ごとにまとめる
+		groups := make(map[int]int64)
+		var winningStake int64
+		for _, bet := range bets {
+			if bet.Type != betType || !bet.Hits(order) {
+				continue
+			}
+			key := 0
+			if !betType.Ordered {
+				key = bet.Horses[0]
+			}
+			groups[key] += bet.Amount
+			winningStake += bet.Amount
+		}
+		if winningStake == 0 {
+			continue
+		}
+
+		profit := pool - winningStake
+		for k, bet := range bets {
+			if bet.Type != betType || !bet.Hits(order) {
+				continue
+			}
+			key := 0
+			if !betType.Ordered {
+				key = bet.Horses[0]
+			}
+			share := profit / int64(len(groups))
+			payouts[k] = bet.Amount + share*bet.Amount/groups[key]
+		}
+	}
+	return payouts
+}
@@ -0,0 +1,209 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"luna/storage"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/robfig/cron/v3"
+)
+
+// HorseRaceTimetableSize は、/horserace schedule で表示する今後のレースの数です。
+const HorseRaceTimetableSize = 5
+
+// errHorseRaceSpecNeverFires は、開催時刻が一度も来ない cron 形式 (例: 2月30日) を表します。
+var errHorseRaceSpecNeverFires = errors.New("horse race schedule never fires")
+
+// loadSchedule は、既定値を補った定時レースの設定を返します。
+func (c *HorseRaceCommand) loadSchedule(guildID string) (*storage.HorseRaceSchedule, error) {
+	var schedule storage.HorseRaceSchedule
+	if err := c.Store.GetConfig(guildID, "horserace_schedule", &schedule); err != nil {
+		return nil, err
+	}
+	schedule.ApplyDefaults()
+	return &schedule, nil
+}
+
+// parseHorseRaceSpec は、cron 形式の開催時刻を読み取ります。時刻は loc のタイムゾーンで解釈します。
+// 開催時刻は1分ごとの確認で判定するため、時刻に依らない @every は使えません。
+// 今後一度も開催時刻が来ない場合は errHorseRaceSpecNeverFires を返します。
+func parseHorseRaceSpec(spec string, loc *time.Location) (cron.Schedule, error) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := schedule.(*cron.SpecSchedule)
+	if !ok {
+		return nil, fmt.Errorf("unsupported horse race schedule: %s", spec)
+	}
+	// CRON_TZ= で指定されていなければ、サーバーのタイムゾーンを使う
+	if s.Location == time.Local {
+		s.Location = loc
+	}
+	// 次の開催時刻が見つからないとゼロ値が返り、毎分の確認で開催時刻を過ぎたと判定されてしまう
+	if s.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("%w: %s", errHorseRaceSpecNeverFires, spec)
+	}
+	return s, nil
+}
+
+func (c *HorseRaceCommand) handleSchedule(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	if len(options) > 0 && !hasManageGuild(i) {
+		sendErrorResponse(s, i, "定時レースの設定を変更するにはサーバー管理権限が必要です。")
+		return
+	}
+	schedule, err := c.loadSchedule(i.GuildID)
+	if err != nil {
+		c.Log.Error("Failed to get horse race schedule", "error", err)
+		sendErrorResponse(s, i, "設定の取得に失敗しました。")
+		return
+	}
+
+	if len(options) > 0 {
+		schedule.Enabled = true
+		for _, opt := range options {
+			switch opt.Name {
+			case "channel":
+				schedule.ChannelID = opt.ChannelValue(nil).ID
+			case "spec":
+				schedule.Spec = strings.TrimSpace(opt.StringValue())
+			case "betting_minutes":
+				schedule.BettingMinutes = int(opt.IntValue())
+			case "enabled":
+				schedule.Enabled = opt.BoolValue()
+			}
+		}
+	}
+	if schedule.Enabled && schedule.ChannelID == "" {
+		sendErrorResponse(s, i, "定時レースを開催するチャンネルを `channel` で指定してください。")
+		return
+	}
+	loc := guildLocation(c.Store, i.GuildID)
+	spec, err := parseHorseRaceSpec(schedule.Spec, loc)
+	if errors.Is(err, errHorseRaceSpecNeverFires) {
+		sendErrorResponse(s, i, "この開催時刻では一度もレースが開催されません。日付と月の組み合わせを確認してください。")
+		return
+	}
+	if err != nil {
+		sendErrorResponse(s, i, "開催時刻は cron 形式 (分 時 日 月 曜日) で指定してください (例: `0 12,21 * * *`)。")
+		return
+	}
+
+	if len(options) > 0 {
+		if err := c.Store.SaveConfig(i.GuildID, "horserace_schedule", schedule); err != nil {
+			c.Log.Error("Failed to save horse race schedule", "error", err)
+			sendErrorResponse(s, i, "設定の保存に失敗しました。")
+			return
+		}
+	}
+
+	status := "開催中"
+	if !schedule.Enabled {
+		status = "停止中"
+	}
+	channel := "未設定"
+	if schedule.ChannelID != "" {
+		channel = fmt.Sprintf("<#%s>", schedule.ChannelID)
+	}
+	var timetable strings.Builder
+	if schedule.Enabled {
+		next := time.Now()
+		for n := 0; n < HorseRaceTimetableSize; n++ {
+			next = spec.Next(next)
+			fmt.Fprintf(&timetable, "<t:%d:f> (<t:%d:R>)\n", next.Unix(), next.Unix())
+		}
+	} else {
+		timetable.WriteString("定時レースは停止中です。")
+	}
+
+	title := "🏇 定時レースの時刻表"
+	if len(options) > 0 {
+		title = "✅ 定時レースの設定を更新しました"
+	}
+	embed := &discordgo.MessageEmbed{
+		Title: title,
+		Color: 0x8e5a2b, // Brown
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "状態", Value: status, Inline: true},
+			{Name: "チャンネル", Value: channel, Inline: true},
+			{Name: "ベット受付", Value: fmt.Sprintf("開催時刻から %d 分間", schedule.BettingMinutes), Inline: true},
+			{Name: "開催時刻", Value: fmt.Sprintf("`%s` (%s)", schedule.Spec, loc.String())},
+			{Name: "今後のレース", Value: timetable.String()},
+		},
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Embeds: []*discordgo.MessageEmbed{embed}},
+	})
+}
+
+// RunScheduledRaces は、開催時刻を迎えたサーバーの定時レースのベット受付を開始します。スケジューラから1分ごとに呼び出されます。
+// 開催時刻はベット受付の開始時刻で、締め切りの時刻になるとレースが自動で始まります。
+func (c *HorseRaceCommand) RunScheduledRaces() {
+	if c.Session == nil || c.Session.State.User == nil {
+		return
+	}
+	schedules, err := c.Store.GetHorseRaceSchedules()
+	if err != nil {
+		c.Log.Error("Failed to get horse race schedules", "error", err)
+		return
+	}
+
+	c.mu.Lock()
+	since := c.lastScheduleCheck
+	now := time.Now()
+	c.lastScheduleCheck = now
+	c.mu.Unlock()
+
+	for guildID, schedule := range schedules {
+		if !schedule.Enabled || schedule.ChannelID == "" {
+			continue
+		}
+		spec, err := parseHorseRaceSpec(schedule.Spec, guildLocation(c.Store, guildID))
+		if err != nil {
+			c.Log.Warn("Invalid horse race schedule", "error", err, "guildID", guildID)
+			continue
+		}
+		// 前回の確認から今回までの間に開催時刻があれば開催する (ボットが止まっていた間の分は開催しない)
+		if next := spec.Next(since); next.IsZero() || next.After(now) {
+			continue
+		}
+		c.openScheduledRace(guildID, schedule)
+	}
+}
+
+// openScheduledRace は、定時レースのベット受付を開始し、締め切りにレースを走らせるタイマーを設定します。
+func (c *HorseRaceCommand) openScheduledRace(guildID string, schedule storage.HorseRaceSchedule) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 前のレースが終わっていなければ、今回の開催は見送る
+	if _, exists := c.races[schedule.ChannelID]; exists {
+		c.Log.Info("Skipping scheduled horse race because a race is in progress", "guildID", guildID, "channelID", schedule.ChannelID)
+		return
+	}
+
+	game, err := c.newRace(guildID, schedule.ChannelID, "")
+	if err != nil {
+		c.Log.Error("Failed to open scheduled horse race", "error", err, "guildID", guildID)
+		return
+	}
+	game.CloseAt = time.Now().Add(time.Duration(schedule.BettingMinutes) * time.Minute)
+
+	msg, err := c.Session.ChannelMessageSendComplex(schedule.ChannelID, &discordgo.MessageSend{
+		Content:    "📣 定時レースのベット受付を開始しました！",
+		Embeds:     []*discordgo.MessageEmbed{c.buildBettingEmbed(game)},
+		Components: c.buildBettingComponents(game),
+	})
+	if err != nil {
+		c.Log.Warn("Failed to send scheduled horse race message", "error", err, "channelID", schedule.ChannelID)
+		game.Round.Close()
+		return
+	}
+	game.MessageID = msg.ID
+	game.timer = time.AfterFunc(time.Until(game.CloseAt), func() { c.startRace(c.Session, game) })
+	c.races[schedule.ChannelID] = game
+}
@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseHorseRaceSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr error // nil なら成功
+		invalid bool  // cron 形式として読めないことを期待する
+	}{
+		{"twice a day", "0 12,21 * * *", nil, false},
+		{"leap day", "0 0 29 2 *", nil, false},
+		{"february 30th never fires", "0 0 30 2 *", errHorseRaceSpecNeverFires, false},
+		{"april 31st never fires", "30 9 31 4 *", errHorseRaceSpecNeverFires, false},
+		{"every is not supported", "@every 1h", nil, true},
+		{"malformed", "0 25 * * *", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := parseHorseRaceSpec(tt.spec, time.UTC)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("parseHorseRaceSpec(%q) = %v, want %v", tt.spec, err, tt.wantErr)
+				}
+			case tt.invalid:
+				if err == nil || errors.Is(err, errHorseRaceSpecNeverFires) {
+					t.Errorf("parseHorseRaceSpec(%q) = %v, want a parse error", tt.spec, err)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("parseHorseRaceSpec(%q) = %v", tt.spec, err)
+				}
+				if spec.Next(time.Now()).IsZero() {
+					t.Errorf("parseHorseRaceSpec(%q) never fires", tt.spec)
+				}
+			}
+		})
+	}
+}
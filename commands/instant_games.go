@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"luna/interfaces"
+	"luna/storage"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// loadInstantGamesConfig は、既定値を補ったクラッシュとマインズの設定を返します。
+func loadInstantGamesConfig(store interfaces.DataStore, guildID string) (*storage.InstantGamesConfig, error) {
+	var config storage.InstantGamesConfig
+	if err := store.GetConfig(guildID, "instant_games_config", &config); err != nil {
+		return nil, err
+	}
+	config.ApplyDefaults()
+	return &config, nil
+}
+
+// holdInstantBet は、賭けの制限を確認してから賭け金を預かりに移します。
+// 精算は預かりの書き換えと返却を1つのトランザクションで行うため、途中で失敗しても賭け金は失われません。
+// 賭けられなかった場合はエラーを返信して false を返します。
+func holdInstantBet(s *discordgo.Session, i *discordgo.InteractionCreate, store interfaces.DataStore, log interfaces.Logger, kind, ref string, bet int64) bool {
+	if rejectGamblingBet(s, i, store, log, bet) {
+		return false
+	}
+	_, err := store.HoldEscrow(i.GuildID, i.Member.User.ID, kind, ref, bet)
+	switch {
+	case errors.Is(err, storage.ErrInsufficientFunds):
+		sendErrorResponse(s, i, "チップが足りません！")
+		return false
+	case errors.Is(err, storage.ErrAccountFrozen):
+		sendErrorResponse(s, i, "あなたの口座は管理者によって凍結されています。")
+		return false
+	case err != nil:
+		log.Error("Failed to hold instant game bet", "error", err, "kind", kind)
+		sendErrorResponse(s, i, "ベット処理中にエラーが発生しました。")
+		return false
+	}
+	return true
+}
+
+// settleInstantBet は、預かった賭け金を払い戻し額に書き換えて残高に戻し、ゲームの統計と実績に記録します。
+func settleInstantBet(s *discordgo.Session, store interfaces.DataStore, log interfaces.Logger, channelID, guildID, userID, kind, ref, game string, bet, payout int64) error {
+	if err := store.SettleEscrow(guildID, userID, kind, ref, payout); err != nil {
+		log.Error("Failed to settle instant game bet", "error", err, "kind", kind, "userID", userID)
+		return err
+	}
+	recordGameRound(store, log, guildID, userID, game, bet, payout)
+	if payout > 0 {
+		recordAchievements(s, store, log, channelID, guildID, userID, map[string]int64{counterChipsWon: payout})
+	}
+	return nil
+}
+
+// formatMultiplier は、倍率を表示用に返します。
+func formatMultiplier(m float64) string {
+	return fmt.Sprintf("%.2fx", m)
+}
@@ -0,0 +1,229 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"luna/interfaces"
+	"luna/storage"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// InventoryCommand handles the /inventory command.
+type InventoryCommand struct {
+	Store interfaces.DataStore
+	Log   interfaces.Logger
+}
+
+func (c *InventoryCommand) GetCommandDef() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "inventory",
+		Description: "所持しているアイテムを表示します。",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user",
+				Description: "表示するユーザー (デフォルト: 自分)",
+				Required:    false,
+			},
+		},
+	}
+}
+
+func (c *InventoryCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	targetUser := i.Member.User
+	if len(i.ApplicationCommandData().Options) > 0 {
+		targetUser = i.ApplicationCommandData().Options[0].UserValue(s)
+	}
+
+	inventory, err := c.Store.GetInventory(i.GuildID, targetUser.ID)
+	if err != nil {
+		c.Log.Error("Failed to get inventory", "error", err)
+		sendErrorResponse(s, i, "インベントリの取得に失敗しました。")
+		return
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("🎒 %s のインベントリ", targetUser.Username),
+		Color: 0x9b59b6, // Purple
+		Thumbnail: &discordgo.MessageEmbedThumbnail{
+			URL: targetUser.AvatarURL(""),
+		},
+	}
+	if len(inventory) == 0 {
+		embed.Description = "アイテムを持っていません。`/shop browse` で購入できます。"
+	} else {
+		var sb strings.Builder
+		for _, entry := range inventory {
+			sb.WriteString(fmt.Sprintf("**%s** × `%d`", entry.Item.Name, entry.Quantity))
+			if entry.Item.Consumable {
+				sb.WriteString(" 🧪")
+			}
+			sb.WriteString("\n")
+		}
+		embed.Description = sb.String()
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: "🧪 = /use で使用できるアイテム"}
+	}
+	sendEmbedResponse(s, i, embed)
+}
+
+func (c *InventoryCommand) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {}
+func (c *InventoryCommand) HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate)     {}
+func (c *InventoryCommand) GetComponentIDs() []string                                            { return []string{} }
+func (c *InventoryCommand) GetCategory() string                                                  { return "カジノ" }
+
+// UseCommand handles the /use command.
+type UseCommand struct {
+	Store interfaces.DataStore
+	Log   interfaces.Logger
+}
+
+func (c *UseCommand) GetCommandDef() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "use",
+		Description: "所持している消費アイテムを使用します。",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "item",
+				Description: "使用するアイテム名",
+				Required:    true,
+			},
+		},
+	}
+}
+
+func (c *UseCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	name := i.ApplicationCommandData().Options[0].StringValue()
+
+	item, err := c.Store.GetShopItemByName(i.GuildID, name)
+	if err != nil {
+		if errors.Is(err, storage.ErrItemNotFound) {
+			sendErrorResponse(s, i, fmt.Sprintf("アイテム「%s」は存在しません。", name))
+			return
+		}
+		c.Log.Error("Failed to find item for use", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+	if !item.Consumable {
+		sendErrorResponse(s, i, fmt.Sprintf("**%s** は使用できるアイテムではありません。", item.Name))
+		return
+	}
+
+	if _, err := c.Store.UseInventoryItem(i.GuildID, i.Member.User.ID, item.ID); err != nil {
+		if errors.Is(err, storage.ErrItemNotOwned) {
+			sendErrorResponse(s, i, fmt.Sprintf("**%s** を持っていません。", item.Name))
+			return
+		}
+		c.Log.Error("Failed to use inventory item", "error", err)
+		sendErrorResponse(s, i, "アイテムの使用に失敗しました。")
+		return
+	}
+
+	message := fmt.Sprintf("**%s** を使用しました。", item.Name)
+	switch item.Effect {
+	case storage.ItemEffectChips:
+		message += fmt.Sprintf(" **%d** チップを獲得しました！", item.EffectValue)
+	case storage.ItemEffectPPC:
+		message += fmt.Sprintf(" **%d** PPCを獲得しました！", item.EffectValue)
+	}
+	sendSuccessResponse(s, i, message)
+}
+
+func (c *UseCommand) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {}
+func (c *UseCommand) HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate)     {}
+func (c *UseCommand) GetComponentIDs() []string                                            { return []string{} }
+func (c *UseCommand) GetCategory() string                                                  { return "カジノ" }
+
+// GiveItemCommand handles the /give-item command.
+type GiveItemCommand struct {
+	Store interfaces.DataStore
+	Log   interfaces.Logger
+}
+
+func (c *GiveItemCommand) GetCommandDef() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "give-item",
+		Description: "所持しているアイテムを他のユーザーに渡します。",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user",
+				Description: "渡す相手",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "item",
+				Description: "渡すアイテム名",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "amount",
+				Description: "渡す個数 (デフォルト: 1)",
+				Required:    false,
+				MinValue:    &[]float64{1}[0],
+			},
+		},
+	}
+}
+
+func (c *GiveItemCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var recipient *discordgo.User
+	var name string
+	quantity := int64(1)
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "user":
+			recipient = opt.UserValue(s)
+		case "item":
+			name = opt.StringValue()
+		case "amount":
+			quantity = opt.IntValue()
+		}
+	}
+
+	if recipient.ID == i.Member.User.ID {
+		sendErrorResponse(s, i, "自分自身にアイテムを渡すことはできません。")
+		return
+	}
+	if recipient.Bot {
+		sendErrorResponse(s, i, "Botにアイテムを渡すことはできません。")
+		return
+	}
+
+	item, err := c.Store.GetShopItemByName(i.GuildID, name)
+	if err != nil {
+		if errors.Is(err, storage.ErrItemNotFound) {
+			sendErrorResponse(s, i, fmt.Sprintf("アイテム「%s」は存在しません。", name))
+			return
+		}
+		c.Log.Error("Failed to find item for give-item", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+
+	if err := c.Store.TransferInventoryItem(i.GuildID, i.Member.User.ID, recipient.ID, item.ID, quantity); err != nil {
+		if errors.Is(err, storage.ErrItemNotOwned) {
+			sendErrorResponse(s, i, fmt.Sprintf("**%s** を %d 個持っていません。", item.Name, quantity))
+			return
+		}
+		c.Log.Error("Failed to transfer inventory item", "error", err)
+		sendErrorResponse(s, i, "アイテムの受け渡しに失敗しました。")
+		return
+	}
+
+	sendEmbedResponse(s, i, &discordgo.MessageEmbed{
+		Title:       "🎁 アイテムを渡しました",
+		Description: fmt.Sprintf("**%s** に **%s** を %d 個渡しました。", recipient.Username, item.Name, quantity),
+		Color:       0x2ecc71, // Green
+	})
+}
+
+func (c *GiveItemCommand) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {}
+func (c *GiveItemCommand) HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate)     {}
+func (c *GiveItemCommand) GetComponentIDs() []string                                            { return []string{} }
+func (c *GiveItemCommand) GetCategory() string                                                  { return "カジノ" }
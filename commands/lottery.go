@@ -0,0 +1,420 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"luna/interfaces"
+	"luna/storage"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// LotteryCommand handles the /lottery command.
+type LotteryCommand struct {
+	Store   interfaces.DataStore
+	Log     interfaces.Logger
+	Session *discordgo.Session // 抽選結果の発表に使用
+}
+
+// NewLotteryCommand creates a new LotteryCommand.
+func NewLotteryCommand(store interfaces.DataStore, log interfaces.Logger, session *discordgo.Session) *LotteryCommand {
+	return &LotteryCommand{Store: store, Log: log, Session: session}
+}
+
+func (c *LotteryCommand) GetCommandDef() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "lottery",
+		Description: "サーバーの宝くじに参加します。",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "buy",
+				Description: "宝くじのチケットを購入します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "numbers", Description: "購入する番号 (スペース区切り, 例: 7 13 42)"},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "quick_pick", Description: "ランダムな番号で購入する枚数", MinValue: &[]float64{1}[0]},
+				},
+			},
+			{Name: "info", Description: "現在の賞金と次の抽選時刻を表示します。", Type: discordgo.ApplicationCommandOptionSubCommand},
+			{Name: "tickets", Description: "今回のラウンドで購入したチケットを表示します。", Type: discordgo.ApplicationCommandOptionSubCommand},
+			{Name: "history", Description: "過去の抽選結果を表示します。", Type: discordgo.ApplicationCommandOptionSubCommand},
+			{
+				Name:        "setup",
+				Description: "[管理者] 宝くじを設定します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionChannel, Name: "channel", Description: "抽選結果を発表するチャンネル", Required: true, ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText}},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "draw_time", Description: "毎日の抽選時刻 (HH:MM, デフォルト: 21:00)"},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "ticket_price", Description: "チケット1枚の価格 (チップ)", MinValue: &[]float64{1}[0]},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "max_number", Description: "選べる番号の上限", MinValue: &[]float64{2}[0], MaxValue: 10000},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "max_tickets", Description: "1人が1ラウンドで買えるチケットの上限", MinValue: &[]float64{1}[0], MaxValue: 100},
+					{Type: discordgo.ApplicationCommandOptionBoolean, Name: "enabled", Description: "宝くじを開催する (デフォルト: true)"},
+				},
+			},
+		},
+	}
+}
+
+func (c *LotteryCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	subcommand := i.ApplicationCommandData().Options[0]
+	switch subcommand.Name {
+	case "buy":
+		c.handleBuy(s, i, subcommand.Options)
+	case "info":
+		c.handleInfo(s, i)
+	case "tickets":
+		c.handleTickets(s, i)
+	case "history":
+		c.handleHistory(s, i)
+	case "setup":
+		c.handleSetup(s, i, subcommand.Options)
+	}
+}
+
+// loadConfig は、既定値を補った宝くじの設定を返します。
+func (c *LotteryCommand) loadConfig(guildID string) (*storage.LotteryConfig, error) {
+	var config storage.LotteryConfig
+	if err := c.Store.GetConfig(guildID, "lottery_config", &config); err != nil {
+		return nil, err
+	}
+	config.ApplyDefaults()
+	return &config, nil
+}
+
+// guildLocation は、抽選や定時レースの時刻の基準にするタイムゾーンです。経済設定のタイムゾーンがなければ日本時間を使います。
+func guildLocation(store interfaces.DataStore, guildID string) *time.Location {
+	if economy, err := store.GetEconomyConfig(guildID); err == nil {
+		if loc := economy.Location(); loc != nil {
+			return loc
+		}
+	}
+	return jst
+}
+
+// lotteryDrawTimes は、now の直前と直後の抽選時刻を返します。
+func lotteryDrawTimes(drawTime string, loc *time.Location, now time.Time) (last, next time.Time, err error) {
+	clock, err := time.Parse("15:04", drawTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+	if today.After(now) {
+		return today.AddDate(0, 0, -1), today, nil
+	}
+	return today, today.AddDate(0, 0, 1), nil
+}
+
+// parseLotteryNumbers は、スペースまたはカンマ区切りの番号を読み取ります。
+func parseLotteryNumbers(input string, maxNumber int64) ([]int64, error) {
+	fields := strings.FieldsFunc(input, func(r rune) bool { return r == ' ' || r == ',' || r == '、' })
+	numbers := make([]int64, 0, len(fields))
+	for _, f := range fields {
+		n, err := strconv.ParseInt(f, 10, 64)
+		if err != nil || n < 1 || n > maxNumber {
+			return nil, fmt.Errorf("番号は 1〜%d の整数で指定してください: `%s`", maxNumber, f)
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, nil
+}
+
+func (c *LotteryCommand) handleBuy(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	config, err := c.loadConfig(i.GuildID)
+	if err != nil {
+		c.Log.Error("Failed to get lottery config", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。後でもう一度お試しください。")
+		return
+	}
+	if !config.Enabled {
+		sendErrorResponse(s, i, "このサーバーでは宝くじが開催されていません。")
+		return
+	}
+
+	var numbers []int64
+	var quickPick int64
+	for _, opt := range options {
+		switch opt.Name {
+		case "numbers":
+			if numbers, err = parseLotteryNumbers(opt.StringValue(), config.MaxNumber); err != nil {
+				sendErrorResponse(s, i, err.Error())
+				return
+			}
+		case "quick_pick":
+			quickPick = opt.IntValue()
+		}
+	}
+	if len(numbers) == 0 && quickPick == 0 {
+		quickPick = 1
+	}
+	if int64(len(numbers))+quickPick > config.MaxTickets {
+		sendErrorResponse(s, i, fmt.Sprintf("1ラウンドで購入できるチケットは %d 枚までです。", config.MaxTickets))
+		return
+	}
+	for n := int64(0); n < quickPick; n++ {
+		numbers = append(numbers, rand.Int63n(config.MaxNumber)+1)
+	}
+
+	if rejectGamblingBet(s, i, c.Store, c.Log, config.TicketPrice*int64(len(numbers))) {
+		return
+	}
+
+	round, err := c.Store.BuyLotteryTickets(i.GuildID, i.Member.User.ID, numbers, config.TicketPrice, config.MaxTickets)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrInsufficientFunds):
+			sendErrorResponse(s, i, fmt.Sprintf("チップが足りません！チケットは1枚 %d チップです。", config.TicketPrice))
+		case errors.Is(err, storage.ErrTicketLimit):
+			sendErrorResponse(s, i, fmt.Sprintf("1ラウンドで購入できるチケットは %d 枚までです。", config.MaxTickets))
+		default:
+			c.Log.Error("Failed to buy lottery tickets", "error", err)
+			sendErrorResponse(s, i, "チケットの購入中にエラーが発生しました。")
+		}
+		return
+	}
+
+	labels := make([]string, len(numbers))
+	for idx, n := range numbers {
+		labels[idx] = fmt.Sprintf("`%d`", n)
+	}
+	embed := &discordgo.MessageEmbed{
+		Title:       "🎟️ 宝くじを購入しました",
+		Description: fmt.Sprintf("番号: %s\n支払い: **%d** チップ", strings.Join(labels, " "), config.TicketPrice*int64(len(numbers))),
+		Color:       0x9b59b6, // Purple
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "現在の賞金", Value: fmt.Sprintf("💰 %d チップ", round.Pot), Inline: true},
+		},
+	}
+	if _, next, err := lotteryDrawTimes(config.DrawTime, guildLocation(c.Store, i.GuildID), time.Now()); err == nil {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "次の抽選", Value: fmt.Sprintf("<t:%d:R>", next.Unix()), Inline: true})
+	}
+	sendEmbedResponse(s, i, embed)
+}
+
+func (c *LotteryCommand) handleInfo(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	config, err := c.loadConfig(i.GuildID)
+	if err != nil {
+		c.Log.Error("Failed to get lottery config", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。後でもう一度お試しください。")
+		return
+	}
+	if !config.Enabled {
+		sendErrorResponse(s, i, "このサーバーでは宝くじが開催されていません。")
+		return
+	}
+	round, err := c.Store.GetOpenLotteryRound(i.GuildID)
+	if err != nil {
+		c.Log.Error("Failed to get lottery round", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。後でもう一度お試しください。")
+		return
+	}
+
+	var pot, tickets int64
+	if round != nil {
+		pot, tickets = round.Pot, round.TicketCount
+	}
+	embed := &discordgo.MessageEmbed{
+		Title: "🎰 サーバー宝くじ",
+		Description: fmt.Sprintf("1〜%d の番号から選んでチケットを購入しましょう！\n当選者がいない場合、賞金は次回に繰り越されます。",
+			config.MaxNumber),
+		Color: 0x9b59b6, // Purple
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "現在の賞金", Value: fmt.Sprintf("💰 **%d** チップ", pot), Inline: true},
+			{Name: "販売済みチケット", Value: fmt.Sprintf("%d 枚", tickets), Inline: true},
+			{Name: "チケット価格", Value: fmt.Sprintf("%d チップ (1人 %d 枚まで)", config.TicketPrice, config.MaxTickets), Inline: true},
+		},
+	}
+	if _, next, err := lotteryDrawTimes(config.DrawTime, guildLocation(c.Store, i.GuildID), time.Now()); err == nil {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "次の抽選", Value: fmt.Sprintf("<t:%d:f> (<t:%d:R>)", next.Unix(), next.Unix())})
+	}
+	sendEmbedResponse(s, i, embed)
+}
+
+func (c *LotteryCommand) handleTickets(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	round, err := c.Store.GetOpenLotteryRound(i.GuildID)
+	if err != nil {
+		c.Log.Error("Failed to get lottery round", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。後でもう一度お試しください。")
+		return
+	}
+	var tickets []storage.LotteryTicket
+	if round != nil {
+		if tickets, err = c.Store.GetLotteryTickets(round.ID, i.Member.User.ID); err != nil {
+			c.Log.Error("Failed to get lottery tickets", "error", err)
+			sendErrorResponse(s, i, "エラーが発生しました。後でもう一度お試しください。")
+			return
+		}
+	}
+	if len(tickets) == 0 {
+		sendErrorResponse(s, i, "今回のラウンドのチケットはまだ購入していません。`/lottery buy` で購入できます。")
+		return
+	}
+
+	labels := make([]string, len(tickets))
+	for idx, t := range tickets {
+		labels[idx] = fmt.Sprintf("`%d`", t.Number)
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("🎟️ あなたのチケット (%d 枚): %s", len(tickets), strings.Join(labels, " ")),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+func (c *LotteryCommand) handleHistory(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	rounds, err := c.Store.GetLotteryHistory(i.GuildID, 10)
+	if err != nil {
+		c.Log.Error("Failed to get lottery history", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。後でもう一度お試しください。")
+		return
+	}
+	if len(rounds) == 0 {
+		sendErrorResponse(s, i, "まだ抽選は行われていません。")
+		return
+	}
+
+	var sb strings.Builder
+	for _, r := range rounds {
+		fmt.Fprintf(&sb, "**第%d回** <t:%d:d> 当選番号 `%d` / 賞金 %d チップ", r.ID, r.DrawnAt.Time.Unix(), r.WinningNumber.Int64, r.Pot)
+		if r.WinnerCount > 0 {
+			fmt.Fprintf(&sb, " → %d 枚が当選 (1枚 %d チップ)\n", r.WinnerCount, r.PayoutPerWin)
+		} else {
+			sb.WriteString(" → 当選者なし (繰り越し)\n")
+		}
+	}
+	embed := &discordgo.MessageEmbed{
+		Title:       "📜 宝くじの抽選結果",
+		Description: sb.String(),
+		Color:       0x9b59b6, // Purple
+	}
+	sendEmbedResponse(s, i, embed)
+}
+
+func (c *LotteryCommand) handleSetup(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	if !hasManageGuild(i) {
+		sendErrorResponse(s, i, "このコマンドを実行するにはサーバー管理権限が必要です。")
+		return
+	}
+	config, err := c.loadConfig(i.GuildID)
+	if err != nil {
+		c.Log.Error("Failed to get lottery config", "error", err)
+		sendErrorResponse(s, i, "設定の取得に失敗しました。")
+		return
+	}
+
+	config.Enabled = true
+	for _, opt := range options {
+		switch opt.Name {
+		case "channel":
+			config.ChannelID = opt.ChannelValue(nil).ID
+		case "draw_time":
+			config.DrawTime = strings.TrimSpace(opt.StringValue())
+		case "ticket_price":
+			config.TicketPrice = opt.IntValue()
+		case "max_number":
+			config.MaxNumber = opt.IntValue()
+		case "max_tickets":
+			config.MaxTickets = opt.IntValue()
+		case "enabled":
+			config.Enabled = opt.BoolValue()
+		}
+	}
+	loc := guildLocation(c.Store, i.GuildID)
+	_, next, err := lotteryDrawTimes(config.DrawTime, loc, time.Now())
+	if err != nil {
+		sendErrorResponse(s, i, "抽選時刻は `HH:MM` の形式で指定してください (例: 21:00)。")
+		return
+	}
+
+	if err := c.Store.SaveConfig(i.GuildID, "lottery_config", config); err != nil {
+		c.Log.Error("Failed to save lottery config", "error", err)
+		sendErrorResponse(s, i, "設定の保存に失敗しました。")
+		return
+	}
+
+	status := "開催中"
+	if !config.Enabled {
+		status = "停止中"
+	}
+	sendSuccessResponse(s, i, fmt.Sprintf("宝くじの設定を更新しました (%s)。\n- 発表チャンネル: <#%s>\n- 抽選時刻: 毎日 %s (%s) / 次回 <t:%d:f>\n- チケット: %d チップ / 番号 1〜%d / 1人 %d 枚まで",
+		status, config.ChannelID, config.DrawTime, loc.String(), next.Unix(), config.TicketPrice, config.MaxNumber, config.MaxTickets))
+}
+
+// RunDraws は、抽選時刻を過ぎたサーバーの宝くじを抽選します。スケジューラから1分ごとに呼び出されます。
+func (c *LotteryCommand) RunDraws() {
+	configs, err := c.Store.GetLotteryConfigs()
+	if err != nil {
+		c.Log.Error("Failed to get lottery configs", "error", err)
+		return
+	}
+
+	now := time.Now()
+	for guildID, config := range configs {
+		if !config.Enabled {
+			continue
+		}
+		round, err := c.Store.GetOpenLotteryRound(guildID)
+		if err != nil {
+			c.Log.Error("Failed to get lottery round", "error", err, "guildID", guildID)
+			continue
+		}
+		// チケットが1枚も売れていないラウンドは抽選せずに次の抽選時刻まで持ち越す
+		if round == nil || round.TicketCount == 0 {
+			continue
+		}
+		last, _, err := lotteryDrawTimes(config.DrawTime, guildLocation(c.Store, guildID), now)
+		if err != nil || !round.OpenedAt.Before(last) {
+			continue
+		}
+
+		draw, err := c.Store.DrawLottery(guildID, rand.Int63n(config.MaxNumber)+1)
+		if err != nil {
+			c.Log.Error("Failed to draw lottery", "error", err, "guildID", guildID)
+			continue
+		}
+		if draw != nil {
+			c.announce(config.ChannelID, draw)
+		}
+	}
+}
+
+// announce は、抽選結果を発表チャンネルに送信します。
+func (c *LotteryCommand) announce(channelID string, draw *storage.LotteryDraw) {
+	if c.Session == nil || channelID == "" {
+		return
+	}
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("🎰 第%d回 宝くじ 抽選結果", draw.Round.ID),
+		Description: fmt.Sprintf("当選番号は… **%d** ！", draw.Round.WinningNumber.Int64),
+		Color:       0xffd700, // Gold
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "賞金総額", Value: fmt.Sprintf("%d チップ", draw.Round.Pot), Inline: true},
+			{Name: "販売チケット", Value: fmt.Sprintf("%d 枚", draw.Round.TicketCount), Inline: true},
+		},
+	}
+	if len(draw.Winners) > 0 {
+		var sb strings.Builder
+		for _, w := range draw.Winners {
+			fmt.Fprintf(&sb, "🎉 <@%s> — %d 枚的中 / **%d** チップ\n", w.UserID, w.Tickets, w.Payout)
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "当選者", Value: sb.String()})
+	} else {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "当選者", Value: "なし… 賞金は次回に繰り越されます！"})
+	}
+	embed.Footer = &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("次回の繰り越し賞金: %d チップ", draw.NextRound.Pot)}
+
+	if _, err := c.Session.ChannelMessageSendEmbed(channelID, embed); err != nil {
+		c.Log.Warn("Failed to announce lottery result", "error", err, "channelID", channelID)
+	}
+}
+
+func (c *LotteryCommand) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {}
+func (c *LotteryCommand) HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate)     {}
+func (c *LotteryCommand) GetComponentIDs() []string                                            { return []string{} }
+func (c *LotteryCommand) GetCategory() string                                                  { return "カジノ" }
@@ -0,0 +1,389 @@
+package commands
+
+import (
+	"fmt"
+	"luna/fair"
+	"luna/interfaces"
+	"luna/storage"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	MinesTilePrefix    = "mines_tile:"    // mines_tile:<userID>:<マスの番号>
+	MinesCashoutPrefix = "mines_cashout:" // mines_cashout:<userID>
+	MinesGridSize      = 5
+	MinesTiles         = MinesGridSize * MinesGridSize
+	MinesDefaultCount  = 3
+	MinesIdleTimeout   = 5 * time.Minute
+)
+
+// MinesGame は、1人のプレイヤーのマインズの1ゲームです。
+type MinesGame struct {
+	ID           string // 開始したインタラクションのID。預かりの ref にも使う
+	GuildID      string
+	ChannelID    string
+	UserID       string
+	Bet          int64
+	Mines        int
+	HouseEdge    float64
+	Round        *fair.Round
+	MineAt       [MinesTiles]bool
+	Revealed     [MinesTiles]bool
+	Safe         int // 開いた安全なマスの数
+	Exploded     int // 踏んだ地雷のマス。踏んでいなければ -1
+	Finished     bool
+	Payout       int64
+	GridMsgID    string
+	ControlMsgID string // キャッシュアウトボタンのメッセージ。盤面だけで行の上限を使い切るため別のメッセージにする
+	timer        *time.Timer
+}
+
+// minesMultiplier は、mines 個の地雷があるときに safe マスを開けた時点のキャッシュアウトの倍率です。
+// 開けたマスがすべて安全である確率の逆数に (1 - ハウスエッジ) を掛けたものです。まだ開けていなければ賭け金をそのまま返します。
+func minesMultiplier(mines, safe int, houseEdge float64) float64 {
+	if safe == 0 {
+		return 1
+	}
+	m := 1 - houseEdge
+	for j := 0; j < safe; j++ {
+		m *= float64(MinesTiles-j) / float64(MinesTiles-mines-j)
+	}
+	return math.Floor(m*100) / 100
+}
+
+// MinesCommand は /mines コマンドを処理します。
+type MinesCommand struct {
+	Store interfaces.DataStore
+	Log   interfaces.Logger
+	Fair  *fair.Service
+	games map[string]*MinesGame // userID -> game
+	mu    sync.Mutex
+}
+
+func NewMinesCommand(store interfaces.DataStore, log interfaces.Logger, fairRNG *fair.Service) *MinesCommand {
+	return &MinesCommand{
+		Store: store,
+		Log:   log,
+		Fair:  fairRNG,
+		games: make(map[string]*MinesGame),
+	}
+}
+
+func (c *MinesCommand) GetCommandDef() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "mines",
+		Description: "地雷を避けてマスを開け、倍率を上げよう！",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionInteger, Name: "bet", Description: "ベットするチップの額", Required: true, MinValue: &[]float64{1}[0]},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "mines",
+				Description: fmt.Sprintf("地雷の数 (%g〜%g, デフォルト: %d)", storage.MinesCountRange.Min, storage.MinesCountRange.Max, MinesDefaultCount),
+				MinValue:    &[]float64{storage.MinesCountRange.Min}[0],
+				MaxValue:    storage.MinesCountRange.Max,
+			},
+		},
+	}
+}
+
+func (c *MinesCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var bet int64
+	mines := MinesDefaultCount
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "bet":
+			bet = opt.IntValue()
+		case "mines":
+			mines = int(opt.IntValue())
+		}
+	}
+	userID := i.Member.User.ID
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.games[userID]; exists {
+		sendErrorResponse(s, i, "既にマインズをプレイ中です。")
+		return
+	}
+	config, err := loadInstantGamesConfig(c.Store, i.GuildID)
+	if err != nil {
+		c.Log.Error("Failed to get instant games config", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+
+	game := &MinesGame{
+		ID:        i.ID,
+		GuildID:   i.GuildID,
+		ChannelID: i.ChannelID,
+		UserID:    userID,
+		Bet:       bet,
+		Mines:     mines,
+		HouseEdge: config.MinesHouseEdge,
+		Exploded:  -1,
+	}
+	if !holdInstantBet(s, i, c.Store, c.Log, storage.EscrowMines, game.ID, bet) {
+		return
+	}
+	game.Round, err = c.Fair.NewRound(i.GuildID, userID)
+	if err != nil {
+		c.Log.Error("Failed to start fair round for mines", "error", err)
+		c.refund(game)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+	// 地雷の位置は最初に決まっている。マスの並びをシャッフルした先頭の mines 個が地雷
+	for _, tile := range game.Round.Rand.Perm(MinesTiles)[:mines] {
+		game.MineAt[tile] = true
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:    fmt.Sprintf("💣 <@%s> のマインズ", userID),
+			Components: c.buildGrid(game),
+		},
+	})
+	if err != nil {
+		c.Log.Error("Failed to send mines grid", "error", err)
+		c.refund(game)
+		return
+	}
+	gridMsg, err := s.InteractionResponse(i.Interaction)
+	if err != nil {
+		c.Log.Error("Failed to get mines grid message", "error", err)
+		c.refund(game)
+		return
+	}
+	game.GridMsgID = gridMsg.ID
+	controlMsg, err := s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Embeds:     []*discordgo.MessageEmbed{c.buildEmbed(game)},
+		Components: c.buildControls(game),
+	})
+	if err != nil {
+		c.Log.Error("Failed to send mines controls", "error", err)
+		c.refund(game)
+		var emptyComponents []discordgo.MessageComponent
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &[]string{"エラーが発生したため、ゲームを中止しました。賭け金は返却されました。"}[0], Components: &emptyComponents})
+		return
+	}
+	game.ControlMsgID = controlMsg.ID
+	game.timer = time.AfterFunc(MinesIdleTimeout, func() { c.timeout(s, game) })
+	c.games[userID] = game
+}
+
+// refund は、ゲームを始められなかったときに賭け金を返却します。
+func (c *MinesCommand) refund(game *MinesGame) {
+	game.Round.Close()
+	if _, err := c.Store.ReleaseEscrow(game.GuildID, game.UserID, storage.EscrowMines, game.ID); err != nil {
+		c.Log.Error("Failed to refund mines bet", "error", err, "userID", game.UserID)
+	}
+}
+
+func (c *MinesCommand) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	customID := i.MessageComponentData().CustomID
+	isCashout := strings.HasPrefix(customID, MinesCashoutPrefix)
+	parts := strings.Split(strings.TrimPrefix(strings.TrimPrefix(customID, MinesTilePrefix), MinesCashoutPrefix), ":")
+	ownerID := parts[0]
+
+	if i.Member.User.ID != ownerID {
+		sendErrorResponse(s, i, "これはあなたのゲームではありません。")
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	game, exists := c.games[ownerID]
+	if !exists {
+		sendErrorResponse(s, i, "このゲームは既に終了しています。")
+		return
+	}
+	game.timer.Reset(MinesIdleTimeout)
+
+	if isCashout {
+		if err := c.finish(s, game); err != nil {
+			sendErrorResponse(s, i, "精算中にエラーが発生しました。")
+			return
+		}
+		c.editGrid(s, game)
+		c.respondUpdate(s, i, &discordgo.InteractionResponseData{Embeds: []*discordgo.MessageEmbed{c.buildEmbed(game)}, Components: []discordgo.MessageComponent{}})
+		return
+	}
+
+	tile, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil || tile < 0 || tile >= MinesTiles || game.Revealed[tile] {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate})
+		return
+	}
+	game.Revealed[tile] = true
+	if game.MineAt[tile] {
+		game.Exploded = tile
+	} else {
+		game.Safe++
+	}
+
+	// 地雷を踏むか、安全なマスをすべて開けたらゲーム終了
+	if game.Exploded >= 0 || game.Safe == MinesTiles-game.Mines {
+		if err := c.finish(s, game); err != nil {
+			sendErrorResponse(s, i, "精算中にエラーが発生しました。")
+			return
+		}
+	}
+	c.respondUpdate(s, i, &discordgo.InteractionResponseData{Components: c.buildGrid(game)})
+	c.editControls(s, game)
+}
+
+func (c *MinesCommand) HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate) {}
+
+func (c *MinesCommand) GetComponentIDs() []string {
+	return []string{MinesTilePrefix, MinesCashoutPrefix}
+}
+
+func (c *MinesCommand) GetCategory() string {
+	return "カジノ"
+}
+
+// --- Game Logic ---
+
+// finish は、ゲームを終えて賭け金を精算します。地雷を踏んでいれば没収、そうでなければ現在の倍率で払い戻します。
+func (c *MinesCommand) finish(s *discordgo.Session, game *MinesGame) error {
+	payout := int64(0)
+	if game.Exploded < 0 {
+		payout = int64(float64(game.Bet) * minesMultiplier(game.Mines, game.Safe, game.HouseEdge))
+	}
+	if err := settleInstantBet(s, c.Store, c.Log, game.ChannelID, game.GuildID, game.UserID, storage.EscrowMines, game.ID, storage.GameMines, game.Bet, payout); err != nil {
+		return err
+	}
+	game.Payout = payout
+	game.Finished = true
+	game.timer.Stop()
+	delete(c.games, game.UserID)
+	game.Round.Close()
+	return nil
+}
+
+// timeout は、しばらく操作がなかったゲームを現在の倍率でキャッシュアウトします。
+func (c *MinesCommand) timeout(s *discordgo.Session, game *MinesGame) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if game.Finished {
+		return
+	}
+	if err := c.finish(s, game); err != nil {
+		return
+	}
+	c.editGrid(s, game)
+	c.editControls(s, game)
+}
+
+// --- Rendering ---
+
+func (c *MinesCommand) respondUpdate(s *discordgo.Session, i *discordgo.InteractionCreate, data *discordgo.InteractionResponseData) {
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseUpdateMessage, Data: data}); err != nil {
+		c.Log.Error("Failed to update mines message", "error", err)
+	}
+}
+
+// editGrid と editControls は、操作されなかった方のメッセージを更新します。
+// インタラクションのトークンは15分で切れるため、チャンネルのメッセージとして編集します。
+func (c *MinesCommand) editGrid(s *discordgo.Session, game *MinesGame) {
+	components := c.buildGrid(game)
+	if _, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{ID: game.GridMsgID, Channel: game.ChannelID, Components: &components}); err != nil {
+		c.Log.Error("Failed to edit mines grid", "error", err)
+	}
+}
+
+func (c *MinesCommand) editControls(s *discordgo.Session, game *MinesGame) {
+	components := c.buildControls(game)
+	if _, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:         game.ControlMsgID,
+		Channel:    game.ChannelID,
+		Embeds:     &[]*discordgo.MessageEmbed{c.buildEmbed(game)},
+		Components: &components,
+	}); err != nil {
+		c.Log.Error("Failed to edit mines controls", "error", err)
+	}
+}
+
+func (c *MinesCommand) buildGrid(game *MinesGame) []discordgo.MessageComponent {
+	rows := make([]discordgo.MessageComponent, 0, MinesGridSize)
+	for row := 0; row < MinesGridSize; row++ {
+		buttons := make([]discordgo.MessageComponent, 0, MinesGridSize)
+		for col := 0; col < MinesGridSize; col++ {
+			tile := row*MinesGridSize + col
+			button := discordgo.Button{
+				Style:    discordgo.SecondaryButton,
+				CustomID: fmt.Sprintf("%s%s:%d", MinesTilePrefix, game.UserID, tile),
+				Emoji:    &discordgo.ComponentEmoji{Name: "❔"},
+				Disabled: game.Finished,
+			}
+			switch {
+			case tile == game.Exploded:
+				button.Style, button.Emoji.Name = discordgo.DangerButton, "💥"
+			case game.Revealed[tile]:
+				button.Style, button.Emoji.Name, button.Disabled = discordgo.SuccessButton, "💎", true
+			case game.Finished && game.MineAt[tile]:
+				button.Emoji.Name = "💣"
+			case game.Finished:
+				button.Emoji.Name = "💎"
+			}
+			buttons = append(buttons, button)
+		}
+		rows = append(rows, discordgo.ActionsRow{Components: buttons})
+	}
+	return rows
+}
+
+func (c *MinesCommand) buildControls(game *MinesGame) []discordgo.MessageComponent {
+	if game.Finished {
+		return []discordgo.MessageComponent{}
+	}
+	current := minesMultiplier(game.Mines, game.Safe, game.HouseEdge)
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    fmt.Sprintf("キャッシュアウト (%d チップ)", int64(float64(game.Bet)*current)),
+				Style:    discordgo.SuccessButton,
+				CustomID: MinesCashoutPrefix + game.UserID,
+				Emoji:    &discordgo.ComponentEmoji{Name: "💰"},
+			},
+		}},
+	}
+}
+
+func (c *MinesCommand) buildEmbed(game *MinesGame) *discordgo.MessageEmbed {
+	current := minesMultiplier(game.Mines, game.Safe, game.HouseEdge)
+	embed := &discordgo.MessageEmbed{
+		Title: "💣 マインズ",
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "ベット", Value: fmt.Sprintf("%d チップ", game.Bet), Inline: true},
+			{Name: "地雷", Value: fmt.Sprintf("%d 個", game.Mines), Inline: true},
+			{Name: "開けたマス", Value: fmt.Sprintf("%d / %d", game.Safe, MinesTiles-game.Mines), Inline: true},
+		},
+		Footer: &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("%s / 還元率 %.1f%%", game.Round.Footer(), (1-game.HouseEdge)*100)},
+	}
+	switch {
+	case !game.Finished:
+		embed.Description = fmt.Sprintf("<@%s> マスを開けて倍率を上げよう！\n現在の倍率: **%s**", game.UserID, formatMultiplier(current))
+		if game.Safe < MinesTiles-game.Mines {
+			embed.Description += fmt.Sprintf(" / 次のマスが安全なら: **%s**", formatMultiplier(minesMultiplier(game.Mines, game.Safe+1, game.HouseEdge)))
+		}
+		embed.Color = 0x3498db // Blue
+	case game.Exploded >= 0:
+		embed.Description = fmt.Sprintf("💥 <@%s> は地雷を踏んでしまいました… **%d** チップを失いました。", game.UserID, game.Bet)
+		embed.Color = 0xe74c3c // Red
+	default:
+		embed.Description = fmt.Sprintf("💰 <@%s> は **%s** でキャッシュアウトし、**%d** チップを獲得しました！", game.UserID, formatMultiplier(current), game.Payout)
+		embed.Color = 0x2ecc71 // Green
+	}
+	return embed
+}
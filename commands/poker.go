@@ -0,0 +1,1205 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"luna/fair"
+	"luna/interfaces"
+	"luna/storage"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ポーカーのボタンとモーダルのID
+const (
+	PokerJoinButton   = "pk_join"
+	PokerLeaveButton  = "pk_leave"
+	PokerStartButton  = "pk_start"
+	PokerCardsButton  = "pk_cards"
+	PokerFoldButton   = "pk_fold"
+	PokerCallButton   = "pk_call"
+	PokerRaiseButton  = "pk_raise"
+	PokerAllInButton  = "pk_allin"
+	PokerJoinModalID  = "pk_join_modal"
+	PokerRaiseModalID = "pk_raise_modal"
+)
+
+// ポーカーのテーブルの設定
+const (
+	DefaultPokerSeats      = 6
+	MaxPokerSeats          = 9
+	DefaultPokerSmallBlind = 10
+	DefaultPokerMinBuyIn   = 20               // ビッグブラインドの何倍か
+	DefaultPokerMaxBuyIn   = 100              // ビッグブラインドの何倍か
+	MaxPokerRake           = 10               // レーキの上限 (%)
+	PokerTurnTimeout       = 45 * time.Second // 操作がないと自動でチェックまたはフォールドするまでの時間
+	PokerHandInterval      = 15 * time.Second // ハンドが終わってから次のハンドを配るまでの時間
+)
+
+// ベットラウンド
+const (
+	PokerPreflop = iota
+	PokerFlop
+	PokerTurn
+	PokerRiver
+)
+
+var pokerStreetNames = []string{"プリフロップ", "フロップ", "ターン", "リバー"}
+
+// PokerState は、ポーカーのテーブルの進行状況を表します。
+type PokerState int
+
+const (
+	PokerStateWaiting PokerState = iota
+	PokerStatePlaying
+)
+
+// PokerPlayer は、ポーカーのテーブルの1つの席です。
+// Stack は預かり (エスクロー) に入っているチップのうち、テーブルに出していない分です。
+type PokerPlayer struct {
+	UserID      string
+	Stack       int64
+	Hole        []Card
+	Bet         int64 // このベットラウンドに出した額
+	Contributed int64 // このハンドに出した額の合計
+	InHand      bool  // このハンドに参加しているか (ハンドの途中で座った場合は false)
+	Folded      bool
+	AllIn       bool
+	Acted       bool // このベットラウンドで行動したか
+	Leaving     bool // ハンドが終わったら退席する
+}
+
+// PokerTable は、チャンネルに1つ置かれるノーリミット・テキサスホールデムのテーブルです。
+type PokerTable struct {
+	GuildID     string
+	ChannelID   string
+	MessageID   string
+	OwnerID     string
+	SmallBlind  int64
+	BigBlind    int64
+	MinBuyIn    int64
+	MaxBuyIn    int64
+	MaxSeats    int
+	RakePercent int64 // フロップまで進んだポットから取り、サーバーのジャックポットに入れる
+	State       PokerState
+	Players     []*PokerPlayer
+	Deck        []Card
+	Board       []Card
+	Street      int
+	Button      int // ディーラーボタンの席の番号
+	Turn        int // 行動中の席の番号
+	CurrentBet  int64
+	MinRaise    int64     // 次のレイズで上乗せしなければならない最低額
+	Deadline    time.Time // 行動中の席の制限時間、または次のハンドの開始時刻
+	NextHand    bool      // 次のハンドを自動で配る予定か
+	Closing     bool      // ハンドが終わったら閉じる
+	Round       *fair.Round
+	LastAction  string
+	Results     string // 直前のハンドの結果
+	timer       *time.Timer
+}
+
+// PokerCommand は、/poker コマンドを処理します。
+type PokerCommand struct {
+	Store  interfaces.DataStore
+	Log    interfaces.Logger
+	Fair   *fair.Service
+	tables map[string]*PokerTable // channelID -> table
+	mu     sync.Mutex
+}
+
+func NewPokerCommand(store interfaces.DataStore, log interfaces.Logger, fairRNG *fair.Service) *PokerCommand {
+	return &PokerCommand{
+		Store:  store,
+		Log:    log,
+		Fair:   fairRNG,
+		tables: make(map[string]*PokerTable),
+	}
+}
+
+func (c *PokerCommand) GetCommandDef() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "poker",
+		Description: "チャンネルのみんなで遊べるテキサスホールデムのテーブルを管理します。",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "open",
+				Description: "このチャンネルにノーリミット・ホールデムのテーブルを開きます。",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "small_blind",
+						Description: fmt.Sprintf("スモールブラインド (デフォルト: %d)", DefaultPokerSmallBlind),
+						MinValue:    &[]float64{1}[0],
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "big_blind",
+						Description: "ビッグブラインド (デフォルト: スモールブラインドの2倍)",
+						MinValue:    &[]float64{1}[0],
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "min_buyin",
+						Description: fmt.Sprintf("最低バイイン (デフォルト: ビッグブラインドの%d倍)", DefaultPokerMinBuyIn),
+						MinValue:    &[]float64{1}[0],
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "max_buyin",
+						Description: fmt.Sprintf("最高バイイン (デフォルト: ビッグブラインドの%d倍)", DefaultPokerMaxBuyIn),
+						MinValue:    &[]float64{1}[0],
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "seats",
+						Description: fmt.Sprintf("席の数 (デフォルト: %d)", DefaultPokerSeats),
+						MinValue:    &[]float64{2}[0],
+						MaxValue:    MaxPokerSeats,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "rake",
+						Description: "ポットから取ってジャックポットに入れる割合 (%、管理者のみ、デフォルト: 0)",
+						MinValue:    &[]float64{0}[0],
+						MaxValue:    MaxPokerRake,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "close",
+				Description: "このチャンネルのテーブルを閉じて、バイインを返却します。",
+			},
+		},
+	}
+}
+
+func (c *PokerCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	switch i.ApplicationCommandData().Options[0].Name {
+	case "open":
+		c.handleOpen(s, i)
+	case "close":
+		c.handleClose(s, i)
+	}
+}
+
+func (c *PokerCommand) handleOpen(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.tables[i.ChannelID]; exists {
+		sendErrorResponse(s, i, "このチャンネルには既にテーブルがあります。")
+		return
+	}
+
+	table := &PokerTable{
+		GuildID:    i.GuildID,
+		ChannelID:  i.ChannelID,
+		OwnerID:    i.Member.User.ID,
+		SmallBlind: DefaultPokerSmallBlind,
+		MaxSeats:   DefaultPokerSeats,
+		State:      PokerStateWaiting,
+		Button:     -1,
+		Turn:       -1,
+	}
+	for _, opt := range i.ApplicationCommandData().Options[0].Options {
+		switch opt.Name {
+		case "small_blind":
+			table.SmallBlind = opt.IntValue()
+		case "big_blind":
+			table.BigBlind = opt.IntValue()
+		case "min_buyin":
+			table.MinBuyIn = opt.IntValue()
+		case "max_buyin":
+			table.MaxBuyIn = opt.IntValue()
+		case "seats":
+			table.MaxSeats = int(opt.IntValue())
+		case "rake":
+			table.RakePercent = opt.IntValue()
+		}
+	}
+	if table.BigBlind == 0 {
+		table.BigBlind = table.SmallBlind * 2
+	}
+	if table.MinBuyIn == 0 {
+		table.MinBuyIn = table.BigBlind * DefaultPokerMinBuyIn
+	}
+	if table.MaxBuyIn == 0 {
+		table.MaxBuyIn = max(table.BigBlind*DefaultPokerMaxBuyIn, table.MinBuyIn)
+	}
+	switch {
+	case table.BigBlind < table.SmallBlind:
+		sendErrorResponse(s, i, "ビッグブラインドはスモールブラインド以上にしてください。")
+		return
+	case table.MinBuyIn < table.BigBlind:
+		sendErrorResponse(s, i, "最低バイインはビッグブラインド以上にしてください。")
+		return
+	case table.MaxBuyIn < table.MinBuyIn:
+		sendErrorResponse(s, i, "最高バイインは最低バイイン以上にしてください。")
+		return
+	case table.RakePercent > 0 && !hasManageGuild(i):
+		sendErrorResponse(s, i, "レーキを設定できるのはサーバーの管理者だけです。")
+		return
+	}
+
+	components := c.buildTableComponents(table)
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{c.buildTableEmbed(table)},
+			Components: components,
+		},
+	})
+	if err != nil {
+		c.Log.Error("Failed to send poker table message", "error", err)
+		return
+	}
+	msg, err := s.InteractionResponse(i.Interaction)
+	if err != nil {
+		c.Log.Error("Failed to get interaction response message", "error", err)
+		return
+	}
+	table.MessageID = msg.ID
+	c.tables[i.ChannelID] = table
+}
+
+func (c *PokerCommand) handleClose(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	table, exists := c.tables[i.ChannelID]
+	if !exists {
+		sendErrorResponse(s, i, "このチャンネルにはテーブルがありません。")
+		return
+	}
+	if i.Member.User.ID != table.OwnerID && !hasManageGuild(i) {
+		sendErrorResponse(s, i, "テーブルを閉じられるのは、テーブルを開いた本人かサーバーの管理者だけです。")
+		return
+	}
+	if table.State == PokerStateWaiting {
+		c.closeTable(s, table, "テーブルは閉じられました。バイインは返却されました。")
+		sendSuccessResponse(s, i, "テーブルを閉じました。")
+		return
+	}
+	table.Closing = true
+	sendSuccessResponse(s, i, "このハンドが終わったらテーブルを閉じます。")
+}
+
+func (c *PokerCommand) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	table, exists := c.tables[i.ChannelID]
+	if !exists || table.MessageID != i.Message.ID {
+		sendErrorResponse(s, i, "このテーブルは既に閉じられています。")
+		return
+	}
+
+	switch i.MessageComponentData().CustomID {
+	case PokerJoinButton:
+		c.handleJoinButton(s, i, table)
+	case PokerLeaveButton:
+		c.handleLeave(s, i, table)
+	case PokerStartButton:
+		c.handleStart(s, i, table)
+	case PokerCardsButton:
+		c.handleCards(s, i, table)
+	case PokerRaiseButton:
+		c.handleRaiseButton(s, i, table)
+	case PokerFoldButton, PokerCallButton, PokerAllInButton:
+		c.handleAction(s, i, table)
+	}
+}
+
+func (c *PokerCommand) HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	table, exists := c.tables[i.ChannelID]
+	if !exists {
+		sendErrorResponse(s, i, "このテーブルは既に閉じられています。")
+		return
+	}
+	switch i.ModalSubmitData().CustomID {
+	case PokerJoinModalID:
+		c.handleJoinModalSubmit(s, i, table)
+	case PokerRaiseModalID:
+		c.handleRaiseModalSubmit(s, i, table)
+	}
+}
+
+func (c *PokerCommand) GetComponentIDs() []string {
+	return []string{
+		PokerJoinButton, PokerLeaveButton, PokerStartButton, PokerCardsButton,
+		PokerFoldButton, PokerCallButton, PokerRaiseButton, PokerAllInButton,
+		PokerJoinModalID, PokerRaiseModalID,
+	}
+}
+
+func (c *PokerCommand) GetCategory() string {
+	return "カジノ"
+}
+
+// --- Seats ---
+
+// playerOf は、ユーザーの席を返します。座っていなければ nil を返します。
+func (t *PokerTable) playerOf(userID string) *PokerPlayer {
+	for _, p := range t.Players {
+		if p.UserID == userID {
+			return p
+		}
+	}
+	return nil
+}
+
+// modalValue は、モーダルの最初の入力欄の値を数値として返します。
+func modalValue(i *discordgo.InteractionCreate) (int64, error) {
+	value := i.ModalSubmitData().Components[0].(*discordgo.ActionsRow).Components[0].(*discordgo.TextInput).Value
+	return strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+}
+
+func (c *PokerCommand) handleJoinButton(s *discordgo.Session, i *discordgo.InteractionCreate, table *PokerTable) {
+	p := table.playerOf(i.Member.User.ID)
+	if p == nil && len(table.Players) >= table.MaxSeats {
+		sendErrorResponse(s, i, "満席です。")
+		return
+	}
+	if p != nil && table.State == PokerStatePlaying && p.InHand {
+		sendErrorResponse(s, i, "ハンドの途中はチップを買い足せません。")
+		return
+	}
+	if p != nil && p.Stack >= table.MaxBuyIn {
+		sendErrorResponse(s, i, "スタックが最高バイインに達しているため、買い足せません。")
+		return
+	}
+
+	title, placeholder := "ポーカーテーブルに着席", strconv.FormatInt(table.MinBuyIn, 10)
+	label := fmt.Sprintf("バイイン額 (%d〜%d)", table.MinBuyIn, table.MaxBuyIn)
+	if p != nil {
+		title, placeholder = "チップを買い足す", strconv.FormatInt(table.MaxBuyIn-p.Stack, 10)
+		label = fmt.Sprintf("買い足す額 (最大 %d)", table.MaxBuyIn-p.Stack)
+	}
+	modal := discordgo.InteractionResponseData{
+		CustomID: PokerJoinModalID,
+		Title:    title,
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "buyin_amount",
+						Label:       label,
+						Style:       discordgo.TextInputShort,
+						Placeholder: placeholder,
+						Required:    true,
+					},
+				},
+			},
+		},
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseModal, Data: &modal})
+}
+
+func (c *PokerCommand) handleJoinModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate, table *PokerTable) {
+	userID := i.Member.User.ID
+	p := table.playerOf(userID)
+	if p == nil && len(table.Players) >= table.MaxSeats {
+		sendErrorResponse(s, i, "満席です。")
+		return
+	}
+	if p != nil && table.State == PokerStatePlaying && p.InHand {
+		sendErrorResponse(s, i, "ハンドの途中はチップを買い足せません。")
+		return
+	}
+
+	amount, err := modalValue(i)
+	if err != nil || amount <= 0 {
+		sendErrorResponse(s, i, "有効な額を入力してください。")
+		return
+	}
+	var stack int64
+	if p != nil {
+		stack = p.Stack
+	}
+	if stack+amount < table.MinBuyIn || stack+amount > table.MaxBuyIn {
+		sendErrorResponse(s, i, fmt.Sprintf("スタックが %d〜%d チップになるようにしてください。", table.MinBuyIn, table.MaxBuyIn))
+		return
+	}
+	if rejectGamblingBet(s, i, c.Store, c.Log, amount) {
+		return
+	}
+
+	// テーブルにいる間のチップは預かりに移し、再起動しても失われないようにする
+	total, err := c.Store.HoldEscrow(table.GuildID, userID, storage.EscrowPoker, table.ChannelID, amount)
+	switch {
+	case errors.Is(err, storage.ErrInsufficientFunds):
+		sendErrorResponse(s, i, "チップが足りません！")
+		return
+	case errors.Is(err, storage.ErrAccountFrozen):
+		sendErrorResponse(s, i, "あなたの口座は管理者によって凍結されています。")
+		return
+	case err != nil:
+		c.Log.Error("Failed to hold poker buy-in", "error", err)
+		sendErrorResponse(s, i, "バイインの処理中にエラーが発生しました。")
+		return
+	}
+
+	if p == nil {
+		p = &PokerPlayer{UserID: userID}
+		table.Players = append(table.Players, p)
+	}
+	p.Stack = total
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("✅ **%d** チップでテーブルに着きました。", p.Stack),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if table.State == PokerStateWaiting && !table.NextHand && table.dealable() >= 2 {
+		// 前のハンドの後に人数が足りず止まっていた場合は、自動で再開する
+		c.scheduleNextHand(s, table)
+	}
+	c.refresh(s, table)
+}
+
+func (c *PokerCommand) handleLeave(s *discordgo.Session, i *discordgo.InteractionCreate, table *PokerTable) {
+	p := table.playerOf(i.Member.User.ID)
+	if p == nil {
+		sendErrorResponse(s, i, "このテーブルに座っていません。")
+		return
+	}
+
+	message := "✅ 退席しました。チップは返却されました。"
+	if table.State == PokerStatePlaying {
+		// 席の番号がずれないよう、ハンドの途中は退席を予約するだけにする
+		p.Leaving = true
+		message = "✅ このハンドが終わったら退席します。"
+	} else {
+		c.removePlayer(table, p)
+		if table.dealable() < 2 {
+			table.NextHand = false
+			table.stopTimer()
+		}
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: message,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	c.refresh(s, table)
+}
+
+// removePlayer は、プレイヤーの預かりを返却して席を空けます。ハンドの途中には呼ばないでください。
+func (c *PokerCommand) removePlayer(table *PokerTable, p *PokerPlayer) {
+	if _, err := c.Store.ReleaseEscrow(table.GuildID, p.UserID, storage.EscrowPoker, table.ChannelID); err != nil {
+		c.Log.Error("Failed to release poker escrow", "error", err, "userID", p.UserID)
+	}
+	for n, other := range table.Players {
+		if other == p {
+			table.Players = append(table.Players[:n], table.Players[n+1:]...)
+			// ボタンが次に回る席を変えないよう、前の席が空いたらボタンも詰める
+			if n <= table.Button {
+				table.Button--
+			}
+			break
+		}
+	}
+}
+
+// dealable は、次のハンドに参加できるプレイヤーの数を返します。
+func (t *PokerTable) dealable() int {
+	count := 0
+	for _, p := range t.Players {
+		if p.Stack > 0 && !p.Leaving {
+			count++
+		}
+	}
+	return count
+}
+
+func (c *PokerCommand) handleStart(s *discordgo.Session, i *discordgo.InteractionCreate, table *PokerTable) {
+	if table.State != PokerStateWaiting {
+		sendErrorResponse(s, i, "既にハンドが進行中です。")
+		return
+	}
+	if table.playerOf(i.Member.User.ID) == nil {
+		sendErrorResponse(s, i, "ハンドを始められるのは、テーブルに座っているプレイヤーだけです。")
+		return
+	}
+	if table.dealable() < 2 {
+		sendErrorResponse(s, i, "ハンドを始めるには、チップを持ったプレイヤーが2人以上必要です。")
+		return
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate})
+	c.startHand(s, table)
+}
+
+func (c *PokerCommand) handleCards(s *discordgo.Session, i *discordgo.InteractionCreate, table *PokerTable) {
+	p := table.playerOf(i.Member.User.ID)
+	if table.State != PokerStatePlaying || p == nil || !p.InHand {
+		sendErrorResponse(s, i, "このハンドには参加していません。")
+		return
+	}
+	embed := &discordgo.MessageEmbed{
+		Title:       "🂠 あなたの手札",
+		Description: HandToString(p.Hole, false),
+		Color:       0x2ecc71, // Green
+	}
+	if len(table.Board) > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "ボード", Value: HandToString(table.Board, false)})
+		best := EvaluatePokerHand(append(append([]Card{}, p.Hole...), table.Board...))
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "今の役", Value: fmt.Sprintf("**%s** (%s)", best.Name(), HandToString(best.Cards, false))})
+	}
+	if p.Folded {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: "このハンドはフォールドしました。"}
+	}
+	sendEphemeralEmbed(s, i, embed)
+}
+
+// --- Hand ---
+
+// next は、from の次の席から順に、条件に合う最初の席の番号を返します。見つからなければ -1 を返します。
+func (t *PokerTable) next(from int, ok func(p *PokerPlayer) bool) int {
+	for k := 1; k <= len(t.Players); k++ {
+		n := ((from+k)%len(t.Players) + len(t.Players)) % len(t.Players)
+		if ok(t.Players[n]) {
+			return n
+		}
+	}
+	return -1
+}
+
+// next に渡す席の条件
+func inHand(p *PokerPlayer) bool { return p.InHand }
+func canAct(p *PokerPlayer) bool { return p.InHand && !p.Folded && !p.AllIn }
+func isLive(p *PokerPlayer) bool { return p.InHand && !p.Folded }
+
+// needsAction は、プレイヤーがこのベットラウンドでまだ行動しなければならないかを返します。
+func (t *PokerTable) needsAction(p *PokerPlayer) bool {
+	return canAct(p) && (!p.Acted || p.Bet < t.CurrentBet)
+}
+
+// count は、条件に合うプレイヤーの数を返します。
+func (t *PokerTable) count(ok func(p *PokerPlayer) bool) int {
+	n := 0
+	for _, p := range t.Players {
+		if ok(p) {
+			n++
+		}
+	}
+	return n
+}
+
+// pay は、プレイヤーのスタックから最大 amount をテーブルに出します。
+func (t *PokerTable) pay(p *PokerPlayer, amount int64) int64 {
+	amount = min(amount, p.Stack)
+	p.Stack -= amount
+	p.Bet += amount
+	p.Contributed += amount
+	if p.Stack == 0 {
+		p.AllIn = true
+	}
+	return amount
+}
+
+// pot は、このハンドでテーブルに出たチップの合計を返します。
+func (t *PokerTable) pot() int64 {
+	var total int64
+	for _, p := range t.Players {
+		total += p.Contributed
+	}
+	return total
+}
+
+// schedule は、d の後に fn を呼ぶタイマーを設定します。前のタイマーは取り消されます。
+// fn は c.mu を取得した状態で呼ばれます。
+func (c *PokerCommand) schedule(table *PokerTable, d time.Duration, fn func()) {
+	if table.timer != nil {
+		table.timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		// 止める前に発火したタイマーは無視する
+		if table.timer != timer {
+			return
+		}
+		table.timer = nil
+		fn()
+	})
+	table.timer = timer
+}
+
+// stopTimer は、予約しているタイマーを取り消します。
+func (t *PokerTable) stopTimer() {
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+}
+
+// scheduleNextHand は、少し待ってから次のハンドを配るように予約します。
+func (c *PokerCommand) scheduleNextHand(s *discordgo.Session, table *PokerTable) {
+	table.NextHand = true
+	table.Deadline = time.Now().Add(PokerHandInterval)
+	c.schedule(table, PokerHandInterval, func() { c.startHand(s, table) })
+}
+
+// startHand は、ボタンを回してブラインドを集め、手札を2枚ずつ配ります。
+func (c *PokerCommand) startHand(s *discordgo.Session, table *PokerTable) {
+	table.stopTimer()
+	table.NextHand = false
+	if table.dealable() < 2 {
+		c.refresh(s, table)
+		return
+	}
+	round, err := c.Fair.NewRound(table.GuildID, table.OwnerID)
+	if err != nil {
+		c.Log.Error("Failed to start fair round for poker hand", "error", err)
+		return
+	}
+	table.Round = round
+	table.Deck = NewDeck(1)
+	ShuffleDeck(table.Deck, round.Rand)
+
+	for _, p := range table.Players {
+		*p = PokerPlayer{UserID: p.UserID, Stack: p.Stack, Leaving: p.Leaving}
+		p.InHand = p.Stack > 0 && !p.Leaving
+	}
+	table.State = PokerStatePlaying
+	table.Board = nil
+	table.Street = PokerPreflop
+	table.LastAction = ""
+	table.Results = ""
+
+	table.Button = table.next(table.Button, inHand)
+	// ヘッズアップではボタンがスモールブラインドを払う
+	smallBlind := table.Button
+	if table.count(inHand) > 2 {
+		smallBlind = table.next(table.Button, inHand)
+	}
+	bigBlind := table.next(smallBlind, inHand)
+	table.pay(table.Players[smallBlind], table.SmallBlind)
+	table.pay(table.Players[bigBlind], table.BigBlind)
+	table.CurrentBet = table.BigBlind
+	table.MinRaise = table.BigBlind
+
+	for n := 0; n < 2; n++ {
+		for k, seat := 0, table.Button; k < table.count(inHand); k++ {
+			seat = table.next(seat, inHand)
+			table.Players[seat].Hole = append(table.Players[seat].Hole, table.draw())
+		}
+	}
+	c.continueHand(s, table, bigBlind)
+}
+
+// draw は、デッキの先頭から1枚配ります。
+func (t *PokerTable) draw() Card {
+	card := t.Deck[0]
+	t.Deck = t.Deck[1:]
+	return card
+}
+
+// dealStreet は、ベットラウンドを締めて次のボードのカードを配ります。
+func (t *PokerTable) dealStreet() {
+	for _, p := range t.Players {
+		p.Bet = 0
+		p.Acted = false
+	}
+	t.CurrentBet = 0
+	t.MinRaise = t.BigBlind
+	t.Street++
+	t.draw() // バーンカード
+	cards := 1
+	if t.Street == PokerFlop {
+		cards = 3
+	}
+	for n := 0; n < cards; n++ {
+		t.Board = append(t.Board, t.draw())
+	}
+}
+
+// continueHand は、from の次の席から行動が必要なプレイヤーに手番を回します。
+// ベットラウンドが終わっていれば次のボードを配り、ハンドが終わっていれば精算します。
+func (c *PokerCommand) continueHand(s *discordgo.Session, table *PokerTable, from int) {
+	if table.count(isLive) == 1 {
+		c.finishHand(s, table)
+		return
+	}
+	if next := table.next(from, table.needsAction); next >= 0 {
+		table.Turn = next
+		if table.Players[next].Leaving {
+			// 退席を予約したプレイヤーは、手番が来たらフォールドする
+			table.Players[next].Folded = true
+			table.LastAction = fmt.Sprintf("<@%s> がフォールドしました (退席)。", table.Players[next].UserID)
+			c.continueHand(s, table, next)
+			return
+		}
+		c.startTurnTimer(s, table)
+		c.refresh(s, table)
+		return
+	}
+
+	// ベットラウンドが終わった。行動できるのが1人以下なら、残りのボードを配り切る
+	if table.Street == PokerRiver || table.count(canAct) <= 1 {
+		for table.Street < PokerRiver {
+			table.dealStreet()
+		}
+		c.finishHand(s, table)
+		return
+	}
+	table.dealStreet()
+	c.continueHand(s, table, table.Button)
+}
+
+// startTurnTimer は、行動中の席の制限時間を設定し直します。
+// 時間切れになると、チェックできればチェックし、できなければフォールドします。
+func (c *PokerCommand) startTurnTimer(s *discordgo.Session, table *PokerTable) {
+	table.Deadline = time.Now().Add(PokerTurnTimeout)
+	turn := table.Turn
+	p := table.Players[turn]
+	c.schedule(table, PokerTurnTimeout, func() {
+		if p.Bet >= table.CurrentBet {
+			table.LastAction = fmt.Sprintf("<@%s> がチェックしました (時間切れ)。", p.UserID)
+		} else {
+			p.Folded = true
+			table.LastAction = fmt.Sprintf("<@%s> がフォールドしました (時間切れ)。", p.UserID)
+		}
+		p.Acted = true
+		c.continueHand(s, table, turn)
+	})
+}
+
+// currentPlayer は、手番のプレイヤーがボタンを押したユーザーであれば返します。
+func (c *PokerCommand) currentPlayer(s *discordgo.Session, i *discordgo.InteractionCreate, table *PokerTable) *PokerPlayer {
+	if table.State != PokerStatePlaying {
+		sendErrorResponse(s, i, "今は操作できません。")
+		return nil
+	}
+	p := table.Players[table.Turn]
+	if p.UserID != i.Member.User.ID {
+		sendErrorResponse(s, i, "あなたの番ではありません。")
+		return nil
+	}
+	return p
+}
+
+func (c *PokerCommand) handleAction(s *discordgo.Session, i *discordgo.InteractionCreate, table *PokerTable) {
+	p := c.currentPlayer(s, i, table)
+	if p == nil {
+		return
+	}
+
+	switch i.MessageComponentData().CustomID {
+	case PokerFoldButton:
+		p.Folded = true
+		table.LastAction = fmt.Sprintf("<@%s> がフォールドしました。", p.UserID)
+	case PokerCallButton:
+		if paid := table.pay(p, table.CurrentBet-p.Bet); paid > 0 {
+			table.LastAction = fmt.Sprintf("<@%s> が %d チップでコールしました。", p.UserID, paid)
+		} else {
+			table.LastAction = fmt.Sprintf("<@%s> がチェックしました。", p.UserID)
+		}
+	case PokerAllInButton:
+		if to := p.Bet + p.Stack; to > table.CurrentBet {
+			table.raiseTo(p, to)
+		} else {
+			table.pay(p, p.Stack)
+		}
+		table.LastAction = fmt.Sprintf("<@%s> がオールイン (%d) しました。", p.UserID, p.Bet)
+	}
+	p.Acted = true
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate})
+	c.continueHand(s, table, table.Turn)
+}
+
+// raiseTo は、プレイヤーのこのベットラウンドの額を to まで引き上げます。
+func (t *PokerTable) raiseTo(p *PokerPlayer, to int64) {
+	raise := to - t.CurrentBet
+	t.pay(p, to-p.Bet)
+	if raise >= t.MinRaise {
+		t.MinRaise = raise
+		// 最低額を満たすレイズは、既に行動したプレイヤーにも行動の機会を戻す
+		for _, other := range t.Players {
+			if other != p {
+				other.Acted = false
+			}
+		}
+	}
+	t.CurrentBet = to
+}
+
+// raiseRange は、プレイヤーがレイズできる額 (このベットラウンドの合計) の範囲を返します。
+// 最低額に届かない場合でも、オールインはできます。
+func (t *PokerTable) raiseRange(p *PokerPlayer) (int64, int64) {
+	most := p.Bet + p.Stack
+	return min(t.CurrentBet+t.MinRaise, most), most
+}
+
+// canRaise は、プレイヤーがコールより多く出せるか、また出す意味があるかを返します。
+func (t *PokerTable) canRaise(p *PokerPlayer) bool {
+	others := t.count(canAct)
+	if canAct(p) {
+		others--
+	}
+	return p.Bet+p.Stack > t.CurrentBet && others > 0
+}
+
+func (c *PokerCommand) handleRaiseButton(s *discordgo.Session, i *discordgo.InteractionCreate, table *PokerTable) {
+	p := c.currentPlayer(s, i, table)
+	if p == nil {
+		return
+	}
+	if !table.canRaise(p) {
+		sendErrorResponse(s, i, "今はレイズできません。")
+		return
+	}
+
+	least, most := table.raiseRange(p)
+	title := "レイズ"
+	if table.CurrentBet == 0 {
+		title = "ベット"
+	}
+	modal := discordgo.InteractionResponseData{
+		CustomID: PokerRaiseModalID,
+		Title:    title,
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "raise_to",
+						Label:       fmt.Sprintf("このラウンドの合計額 (%d〜%d)", least, most),
+						Style:       discordgo.TextInputShort,
+						Placeholder: strconv.FormatInt(least, 10),
+						Required:    true,
+					},
+				},
+			},
+		},
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseModal, Data: &modal})
+}
+
+func (c *PokerCommand) handleRaiseModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate, table *PokerTable) {
+	p := c.currentPlayer(s, i, table)
+	if p == nil {
+		return
+	}
+	if !table.canRaise(p) {
+		sendErrorResponse(s, i, "今はレイズできません。")
+		return
+	}
+	to, err := modalValue(i)
+	least, most := table.raiseRange(p)
+	if err != nil || to < least || to > most {
+		sendErrorResponse(s, i, fmt.Sprintf("%d〜%d の額を入力してください。", least, most))
+		return
+	}
+
+	verb := "レイズ"
+	if table.CurrentBet == 0 {
+		verb = "ベット"
+	}
+	table.raiseTo(p, to)
+	p.Acted = true
+	table.LastAction = fmt.Sprintf("<@%s> が %d に%sしました。", p.UserID, to, verb)
+	if p.AllIn {
+		table.LastAction = fmt.Sprintf("<@%s> がオールイン (%d) しました。", p.UserID, to)
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate})
+	c.continueHand(s, table, table.Turn)
+}
+
+// finishHand は、ポットを勝者に分配して預かりを更新し、次のハンドを予約します。
+func (c *PokerCommand) finishHand(s *discordgo.Session, table *PokerTable) {
+	table.stopTimer()
+	contributed := make([]int64, len(table.Players))
+	folded := make([]bool, len(table.Players))
+	for n, p := range table.Players {
+		contributed[n] = p.Contributed
+		folded[n] = !isLive(p)
+	}
+	pots := buildPots(contributed, folded)
+	showdown := table.count(isLive) > 1
+
+	hands := make(map[int]PokerHand)
+	var results strings.Builder
+	if showdown {
+		fmt.Fprintf(&results, "ボード: %s\n", HandToString(table.Board, false))
+		for n, p := range table.Players {
+			if isLive(p) {
+				hands[n] = EvaluatePokerHand(append(append([]Card{}, p.Hole...), table.Board...))
+				fmt.Fprintf(&results, "<@%s>: %s — %s\n", p.UserID, HandToString(p.Hole, false), hands[n].Name())
+			}
+		}
+	}
+
+	won := make([]int64, len(table.Players))
+	var rake int64
+	for k, pot := range pots {
+		if pot.Uncalled {
+			winner := table.Players[pot.Eligible[0]]
+			won[pot.Eligible[0]] += pot.Amount
+			fmt.Fprintf(&results, "コールされなかった %d チップを <@%s> に返却しました。\n", pot.Amount, winner.UserID)
+			continue
+		}
+
+		amount := pot.Amount
+		// フロップが開かなかったポットからはレーキを取らない
+		if table.RakePercent > 0 && len(table.Board) > 0 {
+			taken := amount * table.RakePercent / 100
+			amount -= taken
+			rake += taken
+		}
+
+		winners := pot.Eligible
+		if showdown && len(winners) > 1 {
+			best := -1
+			for _, n := range pot.Eligible {
+				best = max(best, hands[n].Score)
+			}
+			winners = nil
+			for _, n := range pot.Eligible {
+				if hands[n].Score == best {
+					winners = append(winners, n)
+				}
+			}
+		}
+		share, odd := amount/int64(len(winners)), amount%int64(len(winners))
+		for _, n := range winners {
+			won[n] += share
+		}
+		// 割り切れない端数は、ボタンの左から順に1枚ずつ配る
+		for seat := table.Button; odd > 0; odd-- {
+			seat = table.next(seat, func(p *PokerPlayer) bool {
+				for _, n := range winners {
+					if table.Players[n] == p {
+						return true
+					}
+				}
+				return false
+			})
+			won[seat]++
+		}
+
+		name := "メインポット"
+		if k > 0 {
+			name = fmt.Sprintf("サイドポット%d", k)
+		}
+		mentions := make([]string, len(winners))
+		for n, winner := range winners {
+			mentions[n] = fmt.Sprintf("<@%s>", table.Players[winner].UserID)
+		}
+		fmt.Fprintf(&results, "**%s** (%d): %s", name, amount, strings.Join(mentions, ", "))
+		if showdown {
+			fmt.Fprintf(&results, " — %s", hands[winners[0]].Name())
+		}
+		results.WriteString("\n")
+	}
+	if rake > 0 {
+		if _, err := c.Store.AddToJackpot(table.GuildID, rake); err != nil {
+			c.Log.Error("Failed to add poker rake to jackpot", "error", err)
+		}
+		fmt.Fprintf(&results, "💰 レーキ %d チップをジャックポットに加えました。\n", rake)
+	}
+
+	stacks := make(map[string]int64, len(table.Players))
+	for n, p := range table.Players {
+		p.Stack += won[n]
+		stacks[p.UserID] = p.Stack
+		if !p.InHand {
+			continue
+		}
+		recordGameRound(c.Store, c.Log, table.GuildID, p.UserID, storage.GamePoker, p.Contributed, won[n])
+		recordAchievements(s, c.Store, c.Log, table.ChannelID, table.GuildID, p.UserID, map[string]int64{counterChipsWon: won[n]})
+	}
+	if err := c.Store.SetEscrows(table.GuildID, storage.EscrowPoker, table.ChannelID, stacks); err != nil {
+		c.Log.Error("Failed to update poker escrows", "error", err)
+	}
+
+	table.State = PokerStateWaiting
+	table.Turn = -1
+	table.Results = results.String()
+	table.Round.Close()
+	for _, p := range append([]*PokerPlayer{}, table.Players...) {
+		if p.Leaving {
+			c.removePlayer(table, p)
+		}
+	}
+	if table.Closing {
+		c.closeTable(s, table, "テーブルは閉じられました。チップは返却されました。")
+		return
+	}
+	if table.dealable() >= 2 {
+		c.scheduleNextHand(s, table)
+	}
+	c.refresh(s, table)
+}
+
+// closeTable は、全員の預かりを返却してテーブルを片付けます。ハンドの途中には呼ばないでください。
+func (c *PokerCommand) closeTable(s *discordgo.Session, table *PokerTable, reason string) {
+	table.stopTimer()
+	table.NextHand = false
+	for _, p := range append([]*PokerPlayer{}, table.Players...) {
+		c.removePlayer(table, p)
+	}
+	delete(c.tables, table.ChannelID)
+	table.Round.Close()
+
+	embed := c.buildTableEmbed(table)
+	embed.Description = reason
+	embed.Color = 0x95a5a6 // Gray
+	var emptyComponents []discordgo.MessageComponent
+	if _, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:         table.MessageID,
+		Channel:    table.ChannelID,
+		Embeds:     &[]*discordgo.MessageEmbed{embed},
+		Components: &emptyComponents,
+	}); err != nil {
+		c.Log.Error("Failed to edit closed poker table message", "error", err)
+	}
+}
+
+// --- Rendering ---
+
+// refresh は、テーブルのメッセージを現在の状態に更新します。
+// インタラクションのトークンは15分で切れるため、チャンネルのメッセージとして編集します。
+func (c *PokerCommand) refresh(s *discordgo.Session, table *PokerTable) {
+	components := c.buildTableComponents(table)
+	if _, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:         table.MessageID,
+		Channel:    table.ChannelID,
+		Embeds:     &[]*discordgo.MessageEmbed{c.buildTableEmbed(table)},
+		Components: &components,
+	}); err != nil {
+		c.Log.Error("Failed to edit poker table message", "error", err)
+	}
+}
+
+func (c *PokerCommand) buildTableEmbed(table *PokerTable) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title: "♠️ テキサスホールデム",
+		Color: 0x1f8b4c, // Dark green
+	}
+	stakes := fmt.Sprintf("ブラインド: `%d/%d` | バイイン: `%d〜%d`", table.SmallBlind, table.BigBlind, table.MinBuyIn, table.MaxBuyIn)
+	if table.RakePercent > 0 {
+		stakes += fmt.Sprintf(" | レーキ: `%d%%`", table.RakePercent)
+	}
+
+	switch table.State {
+	case PokerStateWaiting:
+		if table.NextHand {
+			embed.Description = fmt.Sprintf("%s\n次のハンドは <t:%d:R> に配ります。", stakes, table.Deadline.Unix())
+		} else {
+			embed.Description = fmt.Sprintf("%s\n「着席」から参加してください。2人以上座ったら「ハンド開始」で始められます。", stakes)
+		}
+		if table.Results != "" {
+			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "前回のハンド", Value: table.Results})
+		}
+	case PokerStatePlaying:
+		p := table.Players[table.Turn]
+		embed.Description = fmt.Sprintf("%s\n**%s** — <@%s> の番です。<t:%d:R> までに操作しないと自動でチェックまたはフォールドします。",
+			stakes, pokerStreetNames[table.Street], p.UserID, table.Deadline.Unix())
+		if table.LastAction != "" {
+			embed.Description += "\n" + table.LastAction
+		}
+		board := "まだ開いていません。"
+		if len(table.Board) > 0 {
+			board = HandToString(table.Board, false)
+		}
+		embed.Fields = append(embed.Fields,
+			&discordgo.MessageEmbedField{Name: "ボード", Value: board},
+			&discordgo.MessageEmbedField{Name: "ポット", Value: fmt.Sprintf("`%d` (現在のベット: `%d`)", table.pot(), table.CurrentBet)},
+		)
+	}
+
+	var seats strings.Builder
+	for n, p := range table.Players {
+		fmt.Fprintf(&seats, "**%d.** <@%s>", n+1, p.UserID)
+		if n == table.Button {
+			seats.WriteString(" 🔘")
+		}
+		fmt.Fprintf(&seats, " — スタック: `%d`", p.Stack)
+		switch {
+		case table.State == PokerStatePlaying && !p.InHand:
+			seats.WriteString(" (次のハンドから)")
+		case table.State == PokerStatePlaying && p.Folded:
+			seats.WriteString(" フォールド")
+		case table.State == PokerStatePlaying:
+			if p.Bet > 0 {
+				fmt.Fprintf(&seats, " ベット: `%d`", p.Bet)
+			}
+			if p.AllIn {
+				seats.WriteString(" オールイン")
+			}
+			if n == table.Turn {
+				seats.WriteString(" ◀️")
+			}
+		}
+		if p.Leaving {
+			seats.WriteString(" (退席予定)")
+		}
+		seats.WriteString("\n")
+	}
+	if seats.Len() == 0 {
+		seats.WriteString("まだ誰も座っていません。")
+	}
+	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+		Name:  fmt.Sprintf("席 (%d/%d)", len(table.Players), table.MaxSeats),
+		Value: seats.String(),
+	})
+	if table.Round != nil {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: table.Round.Footer()}
+	}
+	return embed
+}
+
+func (c *PokerCommand) buildTableComponents(table *PokerTable) []discordgo.MessageComponent {
+	seatRow := discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.Button{Label: "着席・買い足し", Style: discordgo.SuccessButton, CustomID: PokerJoinButton},
+			discordgo.Button{Label: "退席", Style: discordgo.SecondaryButton, CustomID: PokerLeaveButton},
+		},
+	}
+	if table.State == PokerStateWaiting {
+		seatRow.Components = append(seatRow.Components,
+			discordgo.Button{Label: "ハンド開始", Style: discordgo.PrimaryButton, CustomID: PokerStartButton, Disabled: table.dealable() < 2})
+		return []discordgo.MessageComponent{seatRow}
+	}
+
+	p := table.Players[table.Turn]
+	callLabel := "チェック"
+	if toCall := min(table.CurrentBet-p.Bet, p.Stack); toCall > 0 {
+		callLabel = fmt.Sprintf("コール (%d)", toCall)
+	}
+	raiseLabel := "レイズ"
+	if table.CurrentBet == 0 {
+		raiseLabel = "ベット"
+	}
+	seatRow.Components = append([]discordgo.MessageComponent{
+		discordgo.Button{Label: "手札を見る", Style: discordgo.PrimaryButton, CustomID: PokerCardsButton},
+	}, seatRow.Components...)
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{Label: "フォールド", Style: discordgo.DangerButton, CustomID: PokerFoldButton},
+				discordgo.Button{Label: callLabel, Style: discordgo.SuccessButton, CustomID: PokerCallButton},
+				discordgo.Button{Label: raiseLabel, Style: discordgo.PrimaryButton, CustomID: PokerRaiseButton, Disabled: !table.canRaise(p)},
+				discordgo.Button{Label: "オールイン", Style: discordgo.SecondaryButton, CustomID: PokerAllInButton},
+			},
+		},
+		seatRow,
+	}
+}
@@ -0,0 +1,197 @@
+package commands
+
+import (
+	"sort"
+)
+
+// ポーカーの役 (弱い順)
+const (
+	pokerHighCard = iota
+	pokerOnePair
+	pokerTwoPair
+	pokerThreeOfAKind
+	pokerStraight
+	pokerFlush
+	pokerFullHouse
+	pokerFourOfAKind
+	pokerStraightFlush
+)
+
+var pokerHandNames = []string{
+	"ハイカード",
+	"ワンペア",
+	"ツーペア",
+	"スリーカード",
+	"ストレート",
+	"フラッシュ",
+	"フルハウス",
+	"フォーカード",
+	"ストレートフラッシュ",
+}
+
+// PokerHand は、5枚のカードで作った役です。Score が大きいほど強く、同じ Score は引き分けです。
+type PokerHand struct {
+	Category int
+	Score    int
+	Cards    []Card // 役を作る5枚
+}
+
+// Name は、役の名前を返します。
+func (h PokerHand) Name() string {
+	if h.Category == pokerStraightFlush && h.Score&0xf0000 == 14<<16 {
+		return "ロイヤルフラッシュ"
+	}
+	return pokerHandNames[h.Category]
+}
+
+// pokerRank は、ポーカーでのカードの強さ (2〜14、エースは14) を返します。
+func pokerRank(card Card) int {
+	if card.Rank == "A" {
+		return 14
+	}
+	return rankIndex(card.Rank) + 1
+}
+
+// EvaluatePokerHand は、5〜7枚のカードから作れる最も強い役を返します。
+func EvaluatePokerHand(cards []Card) PokerHand {
+	var best PokerHand
+	best.Score = -1
+	n := len(cards)
+	// 7枚から5枚を選ぶ組み合わせは21通りしかないため、すべて試す
+	for a := 0; a < n; a++ {
+		for b := a + 1; b < n; b++ {
+			for c := b + 1; c < n; c++ {
+				for d := c + 1; d < n; d++ {
+					for e := d + 1; e < n; e++ {
+						hand := evaluateFive([]Card{cards[a], cards[b], cards[c], cards[d], cards[e]})
+						if hand.Score > best.Score {
+							best = hand
+						}
+					}
+				}
+			}
+		}
+	}
+	return best
+}
+
+// evaluateFive は、ちょうど5枚のカードの役を評価します。
+// Score は役の種類を上位に、比べる順に並べたランクを4ビットずつ下位に詰めた値です。
+func evaluateFive(cards []Card) PokerHand {
+	counts := make(map[int]int, 5)
+	flush := true
+	for n, card := range cards {
+		counts[pokerRank(card)]++
+		if n > 0 && card.Suit != cards[0].Suit {
+			flush = false
+		}
+	}
+
+	// 枚数の多い順、同じ枚数なら強い順に並べる
+	groups := make([]int, 0, len(counts))
+	for rank := range counts {
+		groups = append(groups, rank)
+	}
+	sort.Slice(groups, func(x, y int) bool {
+		if counts[groups[x]] != counts[groups[y]] {
+			return counts[groups[x]] > counts[groups[y]]
+		}
+		return groups[x] > groups[y]
+	})
+
+	straightHigh := 0
+	if len(groups) == 5 {
+		switch {
+		case groups[0]-groups[4] == 4:
+			straightHigh = groups[0]
+		case groups[0] == 14 && groups[1] == 5:
+			// A-2-3-4-5 (ホイール) ではエースを1として扱う
+			straightHigh = 5
+		}
+	}
+
+	category := pokerHighCard
+	switch {
+	case straightHigh > 0 && flush:
+		category = pokerStraightFlush
+	case counts[groups[0]] == 4:
+		category = pokerFourOfAKind
+	case counts[groups[0]] == 3 && counts[groups[1]] == 2:
+		category = pokerFullHouse
+	case flush:
+		category = pokerFlush
+	case straightHigh > 0:
+		category = pokerStraight
+	case counts[groups[0]] == 3:
+		category = pokerThreeOfAKind
+	case counts[groups[0]] == 2 && counts[groups[1]] == 2:
+		category = pokerTwoPair
+	case counts[groups[0]] == 2:
+		category = pokerOnePair
+	}
+
+	if straightHigh > 0 {
+		groups = []int{straightHigh}
+	}
+	score := category
+	for n := 0; n < 5; n++ {
+		score <<= 4
+		if n < len(groups) {
+			score |= groups[n]
+		}
+	}
+	return PokerHand{Category: category, Score: score, Cards: cards}
+}
+
+// pokerPot は、メインポットまたはサイドポットです。
+type pokerPot struct {
+	Amount   int64
+	Eligible []int // ポットを獲得できる (フォールドしていない) プレイヤーの番号
+	Uncalled bool  // 1人しか出していない額 (誰もコールしなかったベット) だけのポット
+}
+
+// buildPots は、各プレイヤーがハンドで出したチップの合計からメインポットとサイドポットを作ります。
+// オールインしたプレイヤーは、自分が出した額までしか他のプレイヤーから受け取れません。
+func buildPots(contributed []int64, folded []bool) []pokerPot {
+	var levels []int64
+	for n, amount := range contributed {
+		if !folded[n] && amount > 0 {
+			levels = append(levels, amount)
+		}
+	}
+	sort.Slice(levels, func(x, y int) bool { return levels[x] < levels[y] })
+
+	var pots []pokerPot
+	var previous int64
+	for _, level := range levels {
+		if level == previous {
+			continue
+		}
+		pot := pokerPot{}
+		contributors := 0
+		for n, amount := range contributed {
+			share := min(amount, level) - min(amount, previous)
+			if share > 0 {
+				pot.Amount += share
+				contributors++
+			}
+			if !folded[n] && amount >= level {
+				pot.Eligible = append(pot.Eligible, n)
+			}
+		}
+		pot.Uncalled = contributors == 1
+		pots = append(pots, pot)
+		previous = level
+	}
+
+	// フォールドしたプレイヤーが残ったプレイヤーより多く出していた分は、最後のポットに入れる
+	var excess int64
+	for _, amount := range contributed {
+		excess += max(amount-previous, 0)
+	}
+	if excess > 0 && len(pots) > 0 {
+		pots[len(pots)-1].Amount += excess
+		pots[len(pots)-1].Uncalled = false
+	}
+	return pots
+}
@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parseCards は、"A♠ 10♥" のような表記をカードに変換します。
+func parseCards(t *testing.T, s string) []Card {
+	t.Helper()
+	suitOf := map[string]string{"♠": "♠️", "♥": "♥️", "♦": "♦️", "♣": "♣️"}
+	var cards []Card
+	for _, field := range strings.Fields(s) {
+		runes := []rune(field)
+		rank, suit := string(runes[:len(runes)-1]), suitOf[string(runes[len(runes)-1])]
+		if suit == "" || rankIndex(rank) < 0 {
+			t.Fatalf("invalid card %q", field)
+		}
+		cards = append(cards, Card{Suit: suit, Rank: rank})
+	}
+	return cards
+}
+
+func TestEvaluatePokerHandCategories(t *testing.T) {
+	tests := []struct {
+		cards string
+		want  string
+	}{
+		{"A♠ K♠ Q♠ J♠ 10♠ 2♥ 3♦", "ロイヤルフラッシュ"},
+		{"9♥ 8♥ 7♥ 6♥ 5♥ A♠ A♦", "ストレートフラッシュ"},
+		{"A♣ 2♣ 3♣ 4♣ 5♣ K♦ Q♦", "ストレートフラッシュ"},
+		{"7♠ 7♥ 7♦ 7♣ K♠ K♥ K♦", "フォーカード"},
+		{"Q♠ Q♥ Q♦ 4♣ 4♠ 4♥ 2♦", "フルハウス"},
+		{"2♦ 9♦ J♦ 4♦ 6♦ 5♠ 3♣", "フラッシュ"},
+		{"A♠ 2♥ 3♦ 4♣ 5♠ 9♥ J♦", "ストレート"},
+		{"10♠ J♥ Q♦ K♣ A♥ 2♥ 2♦", "ストレート"},
+		{"8♠ 8♥ 8♦ K♣ 2♠ 5♥ J♦", "スリーカード"},
+		{"8♠ 8♥ K♦ K♣ 2♠ 2♥ J♦", "ツーペア"},
+		{"8♠ 8♥ 4♦ K♣ 2♠ 5♥ J♦", "ワンペア"},
+		{"A♠ 9♥ 4♦ K♣ 2♠ 5♥ J♦", "ハイカード"},
+		{"Q♠ K♠ A♠ 2♠ 3♥", "ハイカード"}, // Q-K-A-2-3 はストレートではない
+	}
+	for _, tt := range tests {
+		if got := EvaluatePokerHand(parseCards(t, tt.cards)).Name(); got != tt.want {
+			t.Errorf("EvaluatePokerHand(%s) = %s, want %s", tt.cards, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluatePokerHandOrdering(t *testing.T) {
+	// 各組は、左の手札が右の手札より強い
+	tests := []struct {
+		stronger, weaker string
+	}{
+		{"2♠ 3♥ 4♦ 5♣ 6♠", "A♠ 2♥ 3♦ 4♣ 5♠"},       // ホイールは最も弱いストレート
+		{"A♠ A♥ K♦ 7♣ 2♠", "A♦ A♣ Q♠ J♥ 10♦"},      // キッカーで比べる
+		{"K♠ K♥ 2♦ 2♣ 3♠", "Q♠ Q♥ J♦ J♣ A♠"},       // 上のペアで比べる
+		{"9♠ 9♥ 9♦ 2♣ 2♠", "8♠ 8♥ 8♦ A♣ A♠"},       // フルハウスはスリーカードで比べる
+		{"2♥ 3♥ 4♥ 5♥ 7♥", "A♠ K♠ Q♠ J♠ 9♥"},       // フラッシュはストレートより強い
+		{"A♦ Q♦ 9♦ 5♦ 3♦", "A♣ Q♣ 9♣ 5♣ 2♣"},       // フラッシュは5枚目まで比べる
+		{"3♠ 3♥ 3♦ 3♣ 2♠", "A♠ A♥ A♦ K♣ K♠"},       // フォーカードはフルハウスより強い
+		{"5♠ 5♥ 4♦ 4♣ A♠", "5♦ 5♣ 4♠ 4♥ K♠"},       // ツーペアのキッカー
+		{"A♠ K♥ Q♦ J♣ 9♠", "A♦ K♣ Q♠ J♥ 8♦"},       // ハイカードは最後の1枚まで比べる
+		{"6♣ 2♣ 3♣ 4♣ 5♣", "A♥ 2♥ 3♥ 4♥ 5♥"},       // ストレートフラッシュでもホイールは最弱
+		{"A♠ A♥ 7♦ 7♣ 2♠ 2♥ K♦", "A♦ A♣ 7♠ 7♥ Q♠"}, // 7枚から最善の5枚を選ぶ
+	}
+	for _, tt := range tests {
+		stronger := EvaluatePokerHand(parseCards(t, tt.stronger))
+		weaker := EvaluatePokerHand(parseCards(t, tt.weaker))
+		if stronger.Score <= weaker.Score {
+			t.Errorf("%s (%s) should beat %s (%s)", tt.stronger, stronger.Name(), tt.weaker, weaker.Name())
+		}
+	}
+}
+
+func TestEvaluatePokerHandTie(t *testing.T) {
+	// ボードの5枚が最善の場合、手札に関係なく引き分け
+	board := "A♠ K♠ Q♥ J♦ 10♣"
+	first := EvaluatePokerHand(parseCards(t, board+" 2♥ 3♦"))
+	second := EvaluatePokerHand(parseCards(t, board+" 4♥ 5♦"))
+	if first.Score != second.Score {
+		t.Errorf("scores differ: %x vs %x", first.Score, second.Score)
+	}
+	if len(first.Cards) != 5 {
+		t.Errorf("best hand has %d cards, want 5", len(first.Cards))
+	}
+}
+
+func TestBuildPots(t *testing.T) {
+	tests := []struct {
+		name        string
+		contributed []int64
+		folded      []bool
+		want        []pokerPot
+	}{
+		{
+			name:        "single pot",
+			contributed: []int64{100, 100, 100},
+			folded:      []bool{false, false, false},
+			want:        []pokerPot{{Amount: 300, Eligible: []int{0, 1, 2}}},
+		},
+		{
+			name:        "short all-in creates side pot",
+			contributed: []int64{50, 200, 200},
+			folded:      []bool{false, false, false},
+			want: []pokerPot{
+				{Amount: 150, Eligible: []int{0, 1, 2}},
+				{Amount: 300, Eligible: []int{1, 2}},
+			},
+		},
+		{
+			name:        "uncalled bet",
+			contributed: []int64{100, 300},
+			folded:      []bool{false, false},
+			want: []pokerPot{
+				{Amount: 200, Eligible: []int{0, 1}},
+				{Amount: 200, Eligible: []int{1}, Uncalled: true},
+			},
+		},
+		{
+			name:        "folded chips stay in the pot",
+			contributed: []int64{40, 100, 60, 100},
+			folded:      []bool{true, false, false, true},
+			want: []pokerPot{
+				{Amount: 220, Eligible: []int{1, 2}},
+				{Amount: 80, Eligible: []int{1}},
+			},
+		},
+		{
+			name:        "multiple all-ins",
+			contributed: []int64{25, 75, 150, 150},
+			folded:      []bool{false, false, false, false},
+			want: []pokerPot{
+				{Amount: 100, Eligible: []int{0, 1, 2, 3}},
+				{Amount: 150, Eligible: []int{1, 2, 3}},
+				{Amount: 150, Eligible: []int{2, 3}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildPots(tt.contributed, tt.folded)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildPots() = %+v, want %+v", got, tt.want)
+			}
+			var total, potTotal int64
+			for _, amount := range tt.contributed {
+				total += amount
+			}
+			for _, pot := range got {
+				potTotal += pot.Amount
+			}
+			if total != potTotal {
+				t.Errorf("pots hold %d chips, want %d", potTotal, total)
+			}
+		})
+	}
+}
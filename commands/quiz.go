@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"luna/ai"
 	"luna/interfaces"
+	"luna/storage"
 	"strconv"
 	"strings"
 	"sync"
@@ -240,6 +241,9 @@ func (c *QuizCommand) handleBetModalSubmit(s *discordgo.Session, i *discordgo.In
 		sendErrorResponse(s, i, "有効なベット額を入力してください。")
 		return
 	}
+	if rejectGamblingBet(s, i, c.Store, c.Log, betAmount) {
+		return
+	}
 
 	userID := i.Member.User.ID
 	casinoData, err := c.Store.GetCasinoData(i.GuildID, userID)
@@ -260,6 +264,7 @@ func (c *QuizCommand) handleBetModalSubmit(s *discordgo.Session, i *discordgo.In
 		sendErrorResponse(s, i, "ベット処理中にエラーが発生しました。")
 		return
 	}
+	pendingBets.add(i.GuildID, userID, storage.GameQuiz, betAmount)
 
 	game.Bets = append(game.Bets, Quiz{UserID: userID, ChoiceIndex: choiceIndex, Amount: betAmount})
 
@@ -425,6 +430,7 @@ func (c *QuizCommand) endBetting(s *discordgo.Session, game *QuizGame) {
 			c.Store.UpdateCasinoData(casinoData)
 			profit := payout - winner.Amount
 			resultDescription.WriteString(fmt.Sprintf("<@%s> が **%d** チップをベットして **%d** チップを獲得！ (収支: **+%d**)\n", winner.UserID, winner.Amount, payout, profit))
+			recordGameRound(c.Store, c.Log, game.Interaction.GuildID, winner.UserID, storage.GameQuiz, winner.Amount, payout)
 		}
 	} else {
 		resultDescription.WriteString("**😥 勝者なし**\n誰も正解できなかったため、ベットしたチップは返金されます。\n")
@@ -433,6 +439,7 @@ func (c *QuizCommand) endBetting(s *discordgo.Session, game *QuizGame) {
 			casinoData, _ := c.Store.GetCasinoData(game.Interaction.GuildID, bet.UserID)
 			casinoData.Chips += bet.Amount
 			c.Store.UpdateCasinoData(casinoData)
+			recordGameRound(c.Store, c.Log, game.Interaction.GuildID, bet.UserID, storage.GameQuiz, bet.Amount, bet.Amount)
 		}
 	}
 
@@ -440,6 +447,9 @@ func (c *QuizCommand) endBetting(s *discordgo.Session, game *QuizGame) {
 		resultDescription.WriteString("\n**💔 敗者**\n")
 		for _, loser := range losers {
 			resultDescription.WriteString(fmt.Sprintf("<@%s>\n", loser.UserID))
+			if len(winners) > 0 {
+				recordGameRound(c.Store, c.Log, game.Interaction.GuildID, loser.UserID, storage.GameQuiz, loser.Amount, 0)
+			}
 		}
 	}
 
@@ -2,7 +2,10 @@ package commands
 
 import (
 	"luna/ai"
+	"luna/fair"
 	"luna/interfaces"
+	"luna/slots"
+	"luna/storage"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -31,7 +34,49 @@ func RegisterCommands(log interfaces.Logger, db interfaces.DataStore, scheduler
 		StartTime: startTime,
 	}
 
-	stockCmd := NewStockCommand(appCtx.Store, appCtx.Log)
+	stockCmd := NewStockCommand(appCtx.Store, appCtx.Log, session)
+	dailyCmd := &DailyCommand{Store: appCtx.Store, Log: appCtx.Log, Session: session}
+	// デイリーボーナスのリマインダーを5分ごとに確認
+	if _, err := appCtx.Scheduler.AddFunc("@every 5m", dailyCmd.SendReminders); err != nil {
+		log.Error("Failed to schedule daily reminders", "error", err)
+	}
+	exchangeCmd := NewExchangeCommand(appCtx.Store, appCtx.Log)
+	// 為替レートを15分ごとに更新
+	if _, err := appCtx.Scheduler.AddFunc("@every 15m", exchangeCmd.UpdateRates); err != nil {
+		log.Error("Failed to schedule exchange rate updates", "error", err)
+	}
+	bankCmd := NewBankCommand(appCtx.Store, appCtx.Log)
+	// 預金の利息を1日1回支払う
+	if _, err := appCtx.Scheduler.AddFunc("@daily", bankCmd.PayInterest); err != nil {
+		log.Error("Failed to schedule bank interest", "error", err)
+	}
+	lotteryCmd := NewLotteryCommand(appCtx.Store, appCtx.Log, session)
+	// 宝くじの抽選時刻を1分ごとに確認
+	if _, err := appCtx.Scheduler.AddFunc("@every 1m", lotteryCmd.RunDraws); err != nil {
+		log.Error("Failed to schedule lottery draws", "error", err)
+	}
+
+	// カジノゲームで共有する検証可能な乱数
+	fairRNG := &fair.Service{Store: appCtx.Store}
+	// ポーカーのテーブルやデュエル、クラッシュとマインズ、ルーレットはメモリにしかないため、前回の起動で預かったままのチップを返却する
+	for _, kind := range []string{storage.EscrowPoker, storage.EscrowDuel, storage.EscrowCrash, storage.EscrowMines, storage.EscrowRoulette} {
+		if released, err := appCtx.Store.ReleaseAllEscrows(kind); err != nil {
+			log.Error("Failed to release escrows", "error", err, "kind", kind)
+		} else if released > 0 {
+			log.Info("Released escrows left from the previous run", "kind", kind, "count", released)
+		}
+	}
+	horseRaceCmd := NewHorseRaceCommand(appCtx.Store, appCtx.Log, fairRNG, session)
+	// 定時レースの開催時刻を1分ごとに確認
+	if _, err := appCtx.Scheduler.AddFunc("@every 1m", horseRaceCmd.RunScheduledRaces); err != nil {
+		log.Error("Failed to schedule horse races", "error", err)
+	}
+	// ビデオスロットの還元率は最初の計算に時間がかかるため、起動時に計算しておく
+	go func() {
+		for _, theme := range slots.Themes {
+			theme.BaseRTP()
+		}
+	}()
 
 	// To add a new command, simply add it to this list.
 	commands := []interfaces.CommandHandler{
@@ -60,20 +105,40 @@ func RegisterCommands(log interfaces.Logger, db interfaces.DataStore, scheduler
 		&WTBRCommand{Log: appCtx.Log},
 		&AutoRoleCommand{Store: appCtx.Store, Log: appCtx.Log},
 		// Casino Commands
-		&DailyCommand{Store: appCtx.Store, Log: appCtx.Log},
+		dailyCmd,
+		&PeriodicRewardCommand{Store: appCtx.Store, Log: appCtx.Log, Kind: storage.RewardWeekly},
+		&PeriodicRewardCommand{Store: appCtx.Store, Log: appCtx.Log, Kind: storage.RewardMonthly},
 		&BalanceCommand{Store: appCtx.Store, Log: appCtx.Log},
-		&SlotsCommand{Store: appCtx.Store, Log: appCtx.Log},
+		&AchievementsCommand{Store: appCtx.Store, Log: appCtx.Log},
+		&StatsCommand{Store: appCtx.Store, Log: appCtx.Log},
+		&CasinoCommand{Store: appCtx.Store, Log: appCtx.Log},
+		&FairCommand{Fair: fairRNG, Log: appCtx.Log},
+		bankCmd,
+		NewEcoCommand(appCtx.Store, appCtx.Log, stockCmd),
+		&SlotsCommand{Store: appCtx.Store, Log: appCtx.Log, Fair: fairRNG},
+		&SlotMachineCommand{Store: appCtx.Store, Log: appCtx.Log},
+		&VideoSlotsCommand{Store: appCtx.Store, Log: appCtx.Log, Fair: fairRNG},
 		&LeaderboardCommand{Store: appCtx.Store, Log: appCtx.Log},
-		&CoinflipCommand{Store: appCtx.Store, Log: appCtx.Log},
+		&CoinflipCommand{Store: appCtx.Store, Log: appCtx.Log, Fair: fairRNG},
 		&PayCommand{Store: appCtx.Store, Log: appCtx.Log},
-		NewHorseRaceCommand(appCtx.Store, appCtx.Log),
+		horseRaceCmd,
 		NewQuizCommand(appCtx.Store, appCtx.Log, appCtx.AI),
-		NewBlackjackCommand(appCtx.Store, appCtx.Log),
-		NewHiLowCommand(appCtx.Store, appCtx.Log),
+		NewBlackjackCommand(appCtx.Store, appCtx.Log, fairRNG),
+		NewBlackjackTableCommand(appCtx.Store, appCtx.Log, fairRNG),
+		NewPokerCommand(appCtx.Store, appCtx.Log, fairRNG),
+		NewCasinoRouletteCommand(appCtx.Store, appCtx.Log, fairRNG),
+		NewDuelCommand(appCtx.Store, appCtx.Log, fairRNG),
+		NewCrashCommand(appCtx.Store, appCtx.Log, fairRNG),
+		NewMinesCommand(appCtx.Store, appCtx.Log, fairRNG),
+		NewHiLowCommand(appCtx.Store, appCtx.Log, fairRNG),
 		NewFishCommand(appCtx.Store, appCtx.Log),
-		NewExchangeCommand(appCtx.Store, appCtx.Log),
+		exchangeCmd,
+		lotteryCmd,
 		stockCmd,
-		// NewShopCommand(appCtx.Store, appCtx.Log),
+		NewShopCommand(appCtx.Store, appCtx.Log),
+		&InventoryCommand{Store: appCtx.Store, Log: appCtx.Log},
+		&UseCommand{Store: appCtx.Store, Log: appCtx.Log},
+		&GiveItemCommand{Store: appCtx.Store, Log: appCtx.Log},
 	}
 
 	for _, cmd := range commands {
@@ -101,6 +166,9 @@ func RegisterCommands(log interfaces.Logger, db interfaces.DataStore, scheduler
 	return commandHandlers, componentHandlers, registeredCommands, stockCmd
 }
 
+// frozenExemptCommands は、口座が凍結されていても使える経済系のコマンドです。
+var frozenExemptCommands = map[string]bool{"balance": true, "leaderboard": true, "achievements": true, "stats": true, "casino": true, "fair": true, "slotmachine": true}
+
 // CommandUsageWrapper は、コマンドの実行をラップして使用状況を記録します。
 type CommandUsageWrapper struct {
 	interfaces.CommandHandler
@@ -113,5 +181,10 @@ func (w *CommandUsageWrapper) Handle(s *discordgo.Session, i *discordgo.Interact
 	if category != "" && category != "管理" { // 管理コマンドは経済に影響を与えない
 		w.Store.IncrementCommandUsage(category)
 	}
+	if (category == "カジノ" || category == "経済") && !frozenExemptCommands[i.ApplicationCommandData().Name] {
+		if rejectFrozenAccount(s, i, w.Store) {
+			return
+		}
+	}
 	w.CommandHandler.Handle(s, i)
 }
\ No newline at end of file
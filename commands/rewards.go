@@ -0,0 +1,191 @@
+package commands
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"luna/interfaces"
+	"luna/storage"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// rewardPeriod は、報酬の現在の期間の開始時刻と、前回の受け取りから次に受け取れるようになる時刻を返します。
+// サーバーにリセット用のタイムゾーンが設定されていればその暦に合わせ、なければ前回の受け取りからの経過時間で判定します。
+func rewardPeriod(kind string, economy *storage.EconomyConfig, last sql.NullTime, now time.Time) (periodStart, next time.Time) {
+	if loc := economy.Location(); loc != nil {
+		local := now.In(loc)
+		today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+		switch kind {
+		case storage.RewardWeekly:
+			// 月曜日の0時にリセット
+			offset := (int(today.Weekday()) + 6) % 7
+			periodStart = today.AddDate(0, 0, -offset)
+			return periodStart, periodStart.AddDate(0, 0, 7)
+		case storage.RewardMonthly:
+			periodStart = time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, loc)
+			return periodStart, periodStart.AddDate(0, 1, 0)
+		default:
+			return today, today.AddDate(0, 0, 1)
+		}
+	}
+
+	interval := rewardInterval(kind, economy)
+	periodStart = now.Add(-interval)
+	next = now
+	if last.Valid {
+		next = last.Time.Add(interval)
+	}
+	return periodStart, next
+}
+
+// rewardInterval は、経過時間で判定する場合の報酬の間隔です。
+func rewardInterval(kind string, economy *storage.EconomyConfig) time.Duration {
+	switch kind {
+	case storage.RewardWeekly:
+		return 7 * 24 * time.Hour
+	case storage.RewardMonthly:
+		return 30 * 24 * time.Hour
+	}
+	return time.Duration(economy.DailyCooldownHours) * time.Hour
+}
+
+// rewardClaimable は、前回の受け取りが現在の期間より前であれば true を返します。
+func rewardClaimable(last sql.NullTime, periodStart time.Time) bool {
+	return !last.Valid || last.Time.Before(periodStart)
+}
+
+// nextDailyStreak は、今回 now に受け取った場合の連続日数を返します。
+// 連続が途切れるのは、前回の受け取りの次に受け取れるようになってから猶予時間を過ぎたときです。
+// 経過時間で判定する場合は前回の受け取りから (間隔 + 猶予) まで、タイムゾーンの暦で判定する場合は
+// 受け取り損ねた日の0時から猶予時間まで (ただしその日のうちなら常に連続) を連続とみなします。
+func nextDailyStreak(status *storage.RewardStatus, economy *storage.EconomyConfig, now time.Time) int64 {
+	if !status.LastDaily.Valid {
+		return 1
+	}
+	grace := time.Duration(economy.StreakGraceHours) * time.Hour
+	var deadline time.Time
+	if loc := economy.Location(); loc != nil {
+		last := status.LastDaily.Time.In(loc)
+		missedStart := time.Date(last.Year(), last.Month(), last.Day()+1, 0, 0, 0, 0, loc)
+		deadline = missedStart.AddDate(0, 0, 1)
+		if graceEnd := missedStart.Add(grace); graceEnd.After(deadline) {
+			deadline = graceEnd
+		}
+		if !now.Before(deadline) {
+			return 1
+		}
+	} else {
+		deadline = status.LastDaily.Time.Add(rewardInterval(storage.RewardDaily, economy) + grace)
+		if now.After(deadline) {
+			return 1
+		}
+	}
+	return status.DailyStreak + 1
+}
+
+// dailyRewardAmount は、連続日数に応じたデイリーボーナスの額を返します。
+func dailyRewardAmount(economy *storage.EconomyConfig, streak int64) int64 {
+	bonusDays := streak - 1
+	if bonusDays > economy.StreakMaxDays {
+		bonusDays = economy.StreakMaxDays
+	}
+	return economy.DailyAmount + economy.StreakBonus*bonusDays
+}
+
+// PeriodicRewardCommand handles the /weekly and /monthly commands.
+type PeriodicRewardCommand struct {
+	Store interfaces.DataStore
+	Log   interfaces.Logger
+	Kind  string // storage.RewardWeekly or storage.RewardMonthly
+}
+
+func (c *PeriodicRewardCommand) label() string {
+	if c.Kind == storage.RewardMonthly {
+		return "マンスリー"
+	}
+	return "ウィークリー"
+}
+
+func (c *PeriodicRewardCommand) GetCommandDef() *discordgo.ApplicationCommand {
+	description := "週に1回、ウィークリーボーナスのPepeCoinを受け取ります。"
+	if c.Kind == storage.RewardMonthly {
+		description = "月に1回、マンスリーボーナスのPepeCoinを受け取ります。"
+	}
+	return &discordgo.ApplicationCommand{
+		Name:        c.Kind,
+		Description: description,
+	}
+}
+
+func (c *PeriodicRewardCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	userID := i.Member.User.ID
+	guildID := i.GuildID
+
+	economy, err := c.Store.GetEconomyConfig(guildID)
+	if err != nil {
+		c.Log.Error("Failed to get economy config for periodic reward", "error", err, "kind", c.Kind)
+		sendErrorResponse(s, i, "エラーが発生しました。後でもう一度お試しください。")
+		return
+	}
+	status, err := c.Store.GetRewardStatus(guildID, userID)
+	if err != nil {
+		c.Log.Error("Failed to get reward status", "error", err, "kind", c.Kind)
+		sendErrorResponse(s, i, "エラーが発生しました。後でもう一度お試しください。")
+		return
+	}
+
+	last := status.LastWeekly
+	amount := economy.WeeklyAmount
+	if c.Kind == storage.RewardMonthly {
+		last = status.LastMonthly
+		amount = economy.MonthlyAmount
+	}
+
+	now := time.Now()
+	periodStart, next := rewardPeriod(c.Kind, economy, last, now)
+	if !rewardClaimable(last, periodStart) {
+		c.sendWaitEmbed(s, i, next)
+		return
+	}
+
+	if err := c.Store.ClaimReward(guildID, userID, c.Kind, amount, periodStart, 0); err != nil {
+		if errors.Is(err, storage.ErrAlreadyClaimed) {
+			c.sendWaitEmbed(s, i, next)
+			return
+		}
+		c.Log.Error("Failed to claim periodic reward", "error", err, "kind", c.Kind)
+		sendErrorResponse(s, i, fmt.Sprintf("%sボーナスの受け取り中にエラーが発生しました。", c.label()))
+		return
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("🎁 %sボーナス！", c.label()),
+		Description: fmt.Sprintf("**%d PepeCoin (PPC)** を獲得しました！", amount),
+		Color:       0xffd700, // Gold
+	}
+	sendEmbedResponse(s, i, embed)
+}
+
+func (c *PeriodicRewardCommand) sendWaitEmbed(s *discordgo.Session, i *discordgo.InteractionCreate, next time.Time) {
+	embed := &discordgo.MessageEmbed{
+		Title:       "⏰ また後で！",
+		Description: fmt.Sprintf("次の%sボーナスは <t:%d:R> に受け取れます。", c.label(), next.Unix()),
+		Color:       0xf1c40f, // Yellow
+	}
+	sendEmbedResponse(s, i, embed)
+}
+
+func (c *PeriodicRewardCommand) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+}
+
+func (c *PeriodicRewardCommand) HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate) {}
+
+func (c *PeriodicRewardCommand) GetComponentIDs() []string {
+	return []string{}
+}
+
+func (c *PeriodicRewardCommand) GetCategory() string {
+	return "カジノ"
+}
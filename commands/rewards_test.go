@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"database/sql"
+	"luna/storage"
+	"testing"
+	"time"
+)
+
+func TestNextDailyStreak(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+	last := time.Date(2025, 1, 10, 21, 0, 0, 0, tokyo) // 1月10日 21時に受け取った
+
+	tests := []struct {
+		name     string
+		timezone string
+		grace    int64
+		now      time.Time
+		want     int64
+	}{
+		// 経過時間で判定する場合 (24時間ごと、猶予12時間): 前回から36時間が期限
+		{"rolling within grace", "", 12, last.Add(36 * time.Hour), 6},
+		{"rolling just past grace", "", 12, last.Add(36*time.Hour + time.Second), 1},
+		{"rolling skipped a whole day", "", 12, last.Add(48 * time.Hour), 1},
+		// 暦で判定する場合 (猶予12時間): 翌日 (11日) 中はいつでも連続、12日になったら途切れる
+		{"calendar next day late", "Asia/Tokyo", 12, time.Date(2025, 1, 11, 23, 59, 59, 0, tokyo), 6},
+		{"calendar missed day", "Asia/Tokyo", 12, time.Date(2025, 1, 12, 0, 0, 0, 0, tokyo), 1},
+		// 猶予は受け取り損ねた日 (11日) の0時から数えるため、前回の受け取りの時刻には左右されない
+		{"calendar long grace within", "Asia/Tokyo", 30, time.Date(2025, 1, 12, 5, 59, 59, 0, tokyo), 6},
+		{"calendar long grace boundary", "Asia/Tokyo", 30, time.Date(2025, 1, 12, 6, 0, 0, 0, tokyo), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			economy := &storage.EconomyConfig{DailyCooldownHours: 24, ResetTimezone: tt.timezone, StreakGraceHours: tt.grace}
+			status := &storage.RewardStatus{LastDaily: sql.NullTime{Time: last, Valid: true}, DailyStreak: 5}
+			if got := nextDailyStreak(status, economy, tt.now); got != tt.want {
+				t.Errorf("nextDailyStreak() at %s = %d, want %d", tt.now, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("first claim", func(t *testing.T) {
+		economy := &storage.EconomyConfig{DailyCooldownHours: 24, StreakGraceHours: 12}
+		if got := nextDailyStreak(&storage.RewardStatus{}, economy, last); got != 1 {
+			t.Errorf("nextDailyStreak() = %d, want 1", got)
+		}
+	})
+}
@@ -0,0 +1,352 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"luna/interfaces"
+	"luna/storage"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const shopBuySelectID = "shop_buy_select"
+
+// ShopCommand handles the /shop command.
+type ShopCommand struct {
+	Store interfaces.DataStore
+	Log   interfaces.Logger
+	// 処理中の購入 (guildID:userID)。ボタンの連打で同じ購入が二重に走らないようにする
+	inFlight sync.Map
+}
+
+// NewShopCommand creates a new ShopCommand.
+func NewShopCommand(store interfaces.DataStore, log interfaces.Logger) *ShopCommand {
+	return &ShopCommand{Store: store, Log: log}
+}
+
+func (c *ShopCommand) GetCommandDef() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "shop",
+		Description: "サーバーのショップでアイテムを購入します。",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "browse",
+				Description: "ショップの商品一覧を表示します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Name:        "buy",
+				Description: "アイテムを購入します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "item", Description: "アイテム名", Required: true},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "amount", Description: "購入する個数 (デフォルト: 1)", Required: false, MinValue: &[]float64{1}[0], MaxValue: storage.MaxPurchaseQuantity},
+				},
+			},
+			{
+				Name:        "add",
+				Description: "[管理者] ショップにアイテムを追加します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "name", Description: "アイテム名", Required: true},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "price", Description: "価格", Required: true, MinValue: &[]float64{1}[0]},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "currency",
+						Description: "支払いに使う通貨",
+						Required:    true,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "チップ", Value: storage.CurrencyChips},
+							{Name: "PepeCoin (PPC)", Value: storage.CurrencyPPC},
+						},
+					},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "description", Description: "アイテムの説明", Required: false},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "stock", Description: "在庫数 (省略すると無制限)", Required: false, MinValue: &[]float64{1}[0]},
+					{Type: discordgo.ApplicationCommandOptionRole, Name: "role", Description: "購入時に付与するロール", Required: false},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "effect",
+						Description: "/use で使用したときの効果 (指定すると消費アイテムになります)",
+						Required:    false,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "チップを獲得", Value: storage.ItemEffectChips},
+							{Name: "PPCを獲得", Value: storage.ItemEffectPPC},
+						},
+					},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "effect_value", Description: "効果で獲得する量", Required: false, MinValue: &[]float64{1}[0]},
+				},
+			},
+			{
+				Name:        "remove",
+				Description: "[管理者] ショップからアイテムを取り下げます。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "item", Description: "アイテム名", Required: true},
+				},
+			},
+		},
+	}
+}
+
+func (c *ShopCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	subcommand := i.ApplicationCommandData().Options[0]
+	switch subcommand.Name {
+	case "browse":
+		c.handleBrowse(s, i)
+	case "buy":
+		c.handleBuy(s, i, subcommand.Options)
+	case "add":
+		c.handleAdd(s, i, subcommand.Options)
+	case "remove":
+		c.handleRemove(s, i, subcommand.Options)
+	}
+}
+
+// currencyLabel は、通貨の表示名を返します。
+func currencyLabel(currency string) string {
+	if currency == storage.CurrencyPPC {
+		return "PPC"
+	}
+	return "チップ"
+}
+
+// describeShopItem は、アイテムの価格・在庫・特典を1行にまとめます。
+func describeShopItem(item storage.ShopItem) string {
+	parts := []string{fmt.Sprintf("💰 `%d` %s", item.Price, currencyLabel(item.Currency))}
+	if item.Stock == storage.UnlimitedStock {
+		parts = append(parts, "在庫: 無制限")
+	} else {
+		parts = append(parts, fmt.Sprintf("在庫: `%d`", item.Stock))
+	}
+	if item.RoleID != "" {
+		parts = append(parts, fmt.Sprintf("🎖️ <@&%s>", item.RoleID))
+	}
+	switch item.Effect {
+	case storage.ItemEffectChips:
+		parts = append(parts, fmt.Sprintf("🧪 使用で `%d` チップ", item.EffectValue))
+	case storage.ItemEffectPPC:
+		parts = append(parts, fmt.Sprintf("🧪 使用で `%d` PPC", item.EffectValue))
+	}
+	return strings.Join(parts, " | ")
+}
+
+func (c *ShopCommand) handleBrowse(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	items, err := c.Store.GetShopItems(i.GuildID)
+	if err != nil {
+		c.Log.Error("Failed to get shop items", "error", err)
+		sendErrorResponse(s, i, "ショップの読み込みに失敗しました。")
+		return
+	}
+	if len(items) == 0 {
+		sendErrorResponse(s, i, "このサーバーのショップにはまだ商品がありません。")
+		return
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       "🛒 ショップ",
+		Description: "下のメニューから購入するアイテムを選んでください。",
+		Color:       0xf1c40f, // Gold
+	}
+	var options []discordgo.SelectMenuOption
+	for _, item := range items {
+		value := describeShopItem(item)
+		if item.Description != "" {
+			value = item.Description + "\n" + value
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: item.Name, Value: value})
+
+		// セレクトメニューの選択肢は25個まで
+		if len(options) < 25 && item.Stock != 0 {
+			options = append(options, discordgo.SelectMenuOption{
+				Label:       item.Name,
+				Value:       strconv.FormatInt(item.ID, 10),
+				Description: fmt.Sprintf("%d %s", item.Price, currencyLabel(item.Currency)),
+			})
+		}
+	}
+
+	response := &discordgo.InteractionResponseData{Embeds: []*discordgo.MessageEmbed{embed}}
+	if len(options) > 0 {
+		response.Components = []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.SelectMenu{CustomID: shopBuySelectID, Placeholder: "購入するアイテムを選択...", Options: options},
+			}},
+		}
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: response,
+	})
+}
+
+func (c *ShopCommand) handleBuy(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	var name string
+	quantity := int64(1)
+	for _, opt := range options {
+		switch opt.Name {
+		case "item":
+			name = opt.StringValue()
+		case "amount":
+			quantity = opt.IntValue()
+		}
+	}
+
+	item, err := c.Store.GetShopItemByName(i.GuildID, name)
+	if err != nil {
+		if errors.Is(err, storage.ErrItemNotFound) {
+			sendErrorResponse(s, i, fmt.Sprintf("アイテム「%s」はショップにありません。", name))
+			return
+		}
+		c.Log.Error("Failed to find shop item", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+	c.purchase(s, i, item.ID, quantity)
+}
+
+// purchase は、アイテムを購入して結果を返信します。
+// 引き落としはストア側で条件付きに行われるため、同時に押されても残高や在庫を超えて購入されることはありません。
+func (c *ShopCommand) purchase(s *discordgo.Session, i *discordgo.InteractionCreate, itemID, quantity int64) {
+	userID := i.Member.User.ID
+	key := i.GuildID + ":" + userID
+	if _, busy := c.inFlight.LoadOrStore(key, struct{}{}); busy {
+		sendErrorResponse(s, i, "前の購入を処理中です。しばらくお待ちください。")
+		return
+	}
+	defer c.inFlight.Delete(key)
+
+	item, err := c.Store.PurchaseShopItem(i.GuildID, userID, itemID, quantity)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrInsufficientFunds):
+			sendErrorResponse(s, i, "残高が足りません！")
+		case errors.Is(err, storage.ErrOutOfStock):
+			sendErrorResponse(s, i, "在庫が足りません。")
+		case errors.Is(err, storage.ErrItemNotFound):
+			sendErrorResponse(s, i, "このアイテムは既に販売終了しています。")
+		case errors.Is(err, storage.ErrPurchaseTooLarge):
+			sendErrorResponse(s, i, "購入する個数が多すぎます。")
+		default:
+			c.Log.Error("Failed to purchase shop item", "error", err)
+			sendErrorResponse(s, i, "購入処理中にエラーが発生しました。")
+		}
+		return
+	}
+
+	description := fmt.Sprintf("**%s** を %d 個購入しました。(`%d` %s)", item.Name, quantity, item.Price*quantity, currencyLabel(item.Currency))
+	if item.RoleID != "" {
+		if err := s.GuildMemberRoleAdd(i.GuildID, userID, item.RoleID); err != nil {
+			c.Log.Error("Failed to grant shop item role", "error", err, "roleID", item.RoleID)
+			description += "\n⚠️ ロールの付与に失敗しました。管理者に連絡してください。"
+		} else {
+			description += fmt.Sprintf("\nロール <@&%s> が付与されました。", item.RoleID)
+		}
+	}
+	if item.Consumable {
+		description += "\n`/use` で使用できます。"
+	}
+
+	sendEmbedResponse(s, i, &discordgo.MessageEmbed{
+		Title:       "🛍️ 購入完了",
+		Description: description,
+		Color:       0x2ecc71, // Green
+	})
+}
+
+// hasManageGuild は、コマンドの実行者がサーバー管理権限を持っているかを返します。
+func hasManageGuild(i *discordgo.InteractionCreate) bool {
+	return i.Member != nil && i.Member.Permissions&discordgo.PermissionManageGuild != 0
+}
+
+func (c *ShopCommand) handleAdd(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	if !hasManageGuild(i) {
+		sendErrorResponse(s, i, "このコマンドを実行するにはサーバー管理権限が必要です。")
+		return
+	}
+
+	item := &storage.ShopItem{GuildID: i.GuildID, Stock: storage.UnlimitedStock}
+	for _, opt := range options {
+		switch opt.Name {
+		case "name":
+			item.Name = strings.TrimSpace(opt.StringValue())
+		case "price":
+			item.Price = opt.IntValue()
+		case "currency":
+			item.Currency = opt.StringValue()
+		case "description":
+			item.Description = opt.StringValue()
+		case "stock":
+			item.Stock = opt.IntValue()
+		case "role":
+			item.RoleID = opt.RoleValue(s, i.GuildID).ID
+		case "effect":
+			item.Effect = opt.StringValue()
+		case "effect_value":
+			item.EffectValue = opt.IntValue()
+		}
+	}
+	if item.Name == "" || len(item.Name) > 100 {
+		sendErrorResponse(s, i, "アイテム名は1〜100文字で指定してください。")
+		return
+	}
+	if item.Effect != storage.ItemEffectNone {
+		if item.EffectValue <= 0 {
+			sendErrorResponse(s, i, "効果を指定する場合は `effect_value` も指定してください。")
+			return
+		}
+		item.Consumable = true
+	}
+
+	if err := c.Store.CreateShopItem(item); err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			sendErrorResponse(s, i, fmt.Sprintf("アイテム「%s」は既に存在します。", item.Name))
+			return
+		}
+		c.Log.Error("Failed to create shop item", "error", err)
+		sendErrorResponse(s, i, "アイテムの追加に失敗しました。")
+		return
+	}
+	sendSuccessResponse(s, i, fmt.Sprintf("ショップに **%s** を追加しました。\n%s", item.Name, describeShopItem(*item)))
+}
+
+func (c *ShopCommand) handleRemove(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	if !hasManageGuild(i) {
+		sendErrorResponse(s, i, "このコマンドを実行するにはサーバー管理権限が必要です。")
+		return
+	}
+
+	name := options[0].StringValue()
+	item, err := c.Store.GetShopItemByName(i.GuildID, name)
+	if err == nil {
+		err = c.Store.DeleteShopItem(i.GuildID, item.ID)
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrItemNotFound) {
+			sendErrorResponse(s, i, fmt.Sprintf("アイテム「%s」はショップにありません。", name))
+			return
+		}
+		c.Log.Error("Failed to remove shop item", "error", err)
+		sendErrorResponse(s, i, "アイテムの削除に失敗しました。")
+		return
+	}
+	sendSuccessResponse(s, i, fmt.Sprintf("ショップから **%s** を取り下げました。", item.Name))
+}
+
+func (c *ShopCommand) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.MessageComponentData()
+	if data.CustomID != shopBuySelectID || len(data.Values) == 0 {
+		return
+	}
+	itemID, err := strconv.ParseInt(data.Values[0], 10, 64)
+	if err != nil {
+		return
+	}
+	c.purchase(s, i, itemID, 1)
+}
+
+func (c *ShopCommand) HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate) {}
+func (c *ShopCommand) GetComponentIDs() []string                                        { return []string{shopBuySelectID} }
+func (c *ShopCommand) GetCategory() string                                              { return "カジノ" }
@@ -0,0 +1,428 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"luna/interfaces"
+	"luna/slots"
+	"luna/storage"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// slotMachineModalPrefix は、スロットマシン編集モーダルのカスタムIDの接頭辞です。後ろにマシンの名前が続きます。
+const slotMachineModalPrefix = "slotmachine_modal:"
+
+// SlotMachineCommand handles the /slotmachine command.
+type SlotMachineCommand struct {
+	Store interfaces.DataStore
+	Log   interfaces.Logger
+}
+
+func (c *SlotMachineCommand) GetCommandDef() *discordgo.ApplicationCommand {
+	nameOption := func(description string) *discordgo.ApplicationCommandOption {
+		return &discordgo.ApplicationCommandOption{Type: discordgo.ApplicationCommandOptionString, Name: "name", Description: description, Required: true, MaxLength: 32}
+	}
+	return &discordgo.ApplicationCommand{
+		Name:        "slotmachine",
+		Description: "サーバーのスロットマシンを管理します。",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "list",
+				Description: "スロットマシンの一覧と還元率を表示します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Name:        "show",
+				Description: "スロットマシンのリールと配当表を表示します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options:     []*discordgo.ApplicationCommandOption{nameOption("表示するマシンの名前")},
+			},
+			{
+				Name:        "edit",
+				Description: "[管理者] スロットマシンを作成・編集します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options:     []*discordgo.ApplicationCommandOption{nameOption("マシンの名前 (default にすると /slots の既定のマシンを置き換えます)")},
+			},
+			{
+				Name:        "theme",
+				Description: "[管理者] /videoslots のテーマを選びます。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "theme", Description: "テーマ", Required: true, Choices: videoThemeChoices()},
+				},
+			},
+			{
+				Name:        "delete",
+				Description: "[管理者] スロットマシンを削除します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options:     []*discordgo.ApplicationCommandOption{nameOption("削除するマシンの名前")},
+			},
+		},
+	}
+}
+
+func (c *SlotMachineCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	subcommand := i.ApplicationCommandData().Options[0]
+	name := ""
+	if len(subcommand.Options) > 0 {
+		name = strings.ToLower(strings.TrimSpace(subcommand.Options[0].StringValue()))
+	}
+
+	switch subcommand.Name {
+	case "theme":
+		c.handleTheme(s, i, name)
+	case "list":
+		c.handleList(s, i)
+	case "show":
+		c.handleShow(s, i, name)
+	case "edit":
+		c.handleEdit(s, i, name)
+	case "delete":
+		c.handleDelete(s, i, name)
+	}
+}
+
+func (c *SlotMachineCommand) handleList(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	saved, err := c.Store.GetSlotMachines(i.GuildID)
+	if err != nil {
+		c.Log.Error("Failed to get slot machines", "error", err)
+		sendErrorResponse(s, i, "スロットマシンの取得中にエラーが発生しました。")
+		return
+	}
+	economy, err := c.Store.GetEconomyConfig(i.GuildID)
+	if err != nil {
+		c.Log.Error("Failed to get economy config", "error", err)
+		sendErrorResponse(s, i, "スロットマシンの取得中にエラーが発生しました。")
+		return
+	}
+
+	machines := saved
+	hasDefault := false
+	for _, m := range saved {
+		if m.Name == slots.DefaultName {
+			hasDefault = true
+		}
+	}
+	if !hasDefault {
+		machines = append([]storage.SlotMachine{*slots.DefaultMachine()}, saved...)
+	}
+
+	var sb strings.Builder
+	for _, m := range machines {
+		report := slots.Analyze(&m, slots.Contribution(&m, economy.JackpotContribution))
+		fmt.Fprintf(&sb, "**%s** — 還元率 `%.2f%%` / 当選頻度 `%.1f%%`\n", m.Name, report.RTP*100, report.HitFrequency*100)
+	}
+
+	current, err := loadVideoTheme(c.Store, i.GuildID)
+	if err != nil {
+		c.Log.Error("Failed to get video slots theme", "error", err)
+		sendErrorResponse(s, i, "スロットマシンの取得中にエラーが発生しました。")
+		return
+	}
+	var themes strings.Builder
+	for _, theme := range slots.Themes {
+		marker := ""
+		if theme == current {
+			marker = " ✅"
+		}
+		fmt.Fprintf(&themes, "%s **%s** (`%s`) — 還元率 `%.1f%%`%s\n", theme.Emoji, theme.Name, theme.ID, (theme.BaseRTP()+economy.JackpotContribution)*100, marker)
+	}
+
+	sendEmbedResponse(s, i, &discordgo.MessageEmbed{
+		Title:       "🎰 スロットマシン一覧",
+		Description: sb.String(),
+		Color:       0x3498db, // Blue
+		Fields:      []*discordgo.MessageEmbedField{{Name: "/videoslots のテーマ", Value: themes.String()}},
+		Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("/slots machine:<名前> で遊べます | 還元率の上限: %.1f%%", economy.SlotsRTPCeiling*100)},
+	})
+}
+
+// videoThemeChoices は、ビデオスロットのテーマの選択肢を返します。
+func videoThemeChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, len(slots.Themes))
+	for n, theme := range slots.Themes {
+		choices[n] = &discordgo.ApplicationCommandOptionChoice{Name: theme.Emoji + " " + theme.Name, Value: theme.ID}
+	}
+	return choices
+}
+
+func (c *SlotMachineCommand) handleTheme(s *discordgo.Session, i *discordgo.InteractionCreate, id string) {
+	if !hasManageGuild(i) {
+		sendErrorResponse(s, i, "このコマンドを実行するにはサーバー管理権限が必要です。")
+		return
+	}
+	theme := slots.FindTheme(id)
+	if theme == nil {
+		sendErrorResponse(s, i, fmt.Sprintf("テーマ `%s` は見つかりません。", id))
+		return
+	}
+	economy, err := c.Store.GetEconomyConfig(i.GuildID)
+	if err != nil {
+		c.Log.Error("Failed to get economy config", "error", err)
+		sendErrorResponse(s, i, "テーマの保存中にエラーが発生しました。")
+		return
+	}
+	if rtp := theme.BaseRTP() + economy.JackpotContribution; rtp > economy.SlotsRTPCeiling {
+		sendErrorResponse(s, i, fmt.Sprintf("このテーマの還元率 (%.2f%%) が上限 (%.2f%%) を超えているため選べません。", rtp*100, economy.SlotsRTPCeiling*100))
+		return
+	}
+	if err := c.Store.SaveConfig(i.GuildID, "video_slots_config", storage.VideoSlotsConfig{Theme: theme.ID}); err != nil {
+		c.Log.Error("Failed to save video slots config", "error", err)
+		sendErrorResponse(s, i, "テーマの保存中にエラーが発生しました。")
+		return
+	}
+	sendSuccessResponse(s, i, fmt.Sprintf("/videoslots のテーマを %s **%s** にしました。", theme.Emoji, theme.Name))
+}
+
+func (c *SlotMachineCommand) handleShow(s *discordgo.Session, i *discordgo.InteractionCreate, name string) {
+	machine, err := loadSlotMachine(c.Store, i.GuildID, name)
+	if err != nil {
+		c.Log.Error("Failed to get slot machine", "error", err)
+		sendErrorResponse(s, i, "スロットマシンの取得中にエラーが発生しました。")
+		return
+	}
+	if machine == nil {
+		sendErrorResponse(s, i, fmt.Sprintf("スロットマシン `%s` は見つかりません。", name))
+		return
+	}
+	economy, err := c.Store.GetEconomyConfig(i.GuildID)
+	if err != nil {
+		c.Log.Error("Failed to get economy config", "error", err)
+		sendErrorResponse(s, i, "スロットマシンの取得中にエラーが発生しました。")
+		return
+	}
+	sendEmbedResponse(s, i, c.buildMachineEmbed(machine, economy))
+}
+
+// buildMachineEmbed は、マシンの定義と還元率を表示するEmbedを作ります。
+func (c *SlotMachineCommand) buildMachineEmbed(machine *storage.SlotMachine, economy *storage.EconomyConfig) *discordgo.MessageEmbed {
+	contribution := slots.Contribution(machine, economy.JackpotContribution)
+	report := slots.Analyze(machine, contribution)
+
+	var reels strings.Builder
+	for n, reel := range machine.Reels {
+		fmt.Fprintf(&reels, "%d: %s\n", n+1, strings.Join(reel, " "))
+	}
+	jackpot := "なし"
+	if machine.JackpotSymbol != "" {
+		jackpot = fmt.Sprintf("%s 3つ (積立 %.1f%%)", machine.JackpotSymbol, contribution*100)
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("🎰 %s", machine.Name),
+		Color: 0x3498db, // Blue
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "リール", Value: reels.String()},
+			{Name: "3つ揃い", Value: orNone(formatPaytable(machine.ThreeOfAKind, " ")), Inline: true},
+			{Name: "2つ揃い", Value: orNone(formatPaytable(machine.TwoOfAKind, " ")), Inline: true},
+			{Name: "ジャックポット", Value: jackpot, Inline: true},
+			{Name: "還元率", Value: fmt.Sprintf("**%.2f%%** (ジャックポットを除くと %.2f%%)", report.RTP*100, report.BaseRTP*100), Inline: true},
+			{Name: "当選頻度", Value: fmt.Sprintf("%.1f%%", report.HitFrequency*100), Inline: true},
+			{Name: "標準偏差", Value: fmt.Sprintf("%.2f倍", report.StdDev()), Inline: true},
+		},
+	}
+	if !machine.UpdatedAt.IsZero() {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: "最終更新"}
+		embed.Timestamp = machine.UpdatedAt.Format(time.RFC3339)
+	}
+	return embed
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "なし"
+	}
+	return s
+}
+
+func (c *SlotMachineCommand) handleEdit(s *discordgo.Session, i *discordgo.InteractionCreate, name string) {
+	if !hasManageGuild(i) {
+		sendErrorResponse(s, i, "このコマンドを実行するにはサーバー管理権限が必要です。")
+		return
+	}
+	if name == "" || strings.ContainsAny(name, " :") {
+		sendErrorResponse(s, i, "マシンの名前にスペースや `:` は使えません。")
+		return
+	}
+
+	machine, err := loadSlotMachine(c.Store, i.GuildID, name)
+	if err != nil {
+		c.Log.Error("Failed to get slot machine", "error", err)
+		sendErrorResponse(s, i, "スロットマシンの取得中にエラーが発生しました。")
+		return
+	}
+	if machine == nil {
+		// 新しいマシンは組み込みのマシンを雛形にする
+		machine = slots.DefaultMachine()
+	}
+
+	var reels []string
+	for _, reel := range machine.Reels {
+		reels = append(reels, strings.Join(reel, " "))
+	}
+	contribution := ""
+	if machine.JackpotContribution > 0 {
+		contribution = strconv.FormatFloat(machine.JackpotContribution*100, 'f', -1, 64)
+	}
+
+	textInput := func(id, label, value, placeholder string, style discordgo.TextInputStyle, required bool) discordgo.ActionsRow {
+		return discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.TextInput{CustomID: id, Label: label, Style: style, Value: value, Placeholder: placeholder, Required: required},
+		}}
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: slotMachineModalPrefix + name,
+			Title:    fmt.Sprintf("スロットマシン %s の編集", name),
+			Components: []discordgo.MessageComponent{
+				textInput("reels", "リール (1行に1リール、絵柄はスペース区切り)", strings.Join(reels, "\n"), "🍒 🍒 🍋 🍊 💎", discordgo.TextInputParagraph, true),
+				textInput("three", "3つ揃いの配当 (絵柄=倍率)", formatPaytable(machine.ThreeOfAKind, "\n"), "🍇=20", discordgo.TextInputParagraph, false),
+				textInput("two", "2つ揃いの配当 (絵柄=倍率)", formatPaytable(machine.TwoOfAKind, "\n"), "🍒=2", discordgo.TextInputParagraph, false),
+				textInput("jackpot", "ジャックポットの絵柄 (空欄でなし)", machine.JackpotSymbol, "💎", discordgo.TextInputShort, false),
+				textInput("contribution", "ジャックポット積立率 % (空欄で経済設定の値)", contribution, "1", discordgo.TextInputShort, false),
+			},
+		},
+	})
+}
+
+func (c *SlotMachineCommand) HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ModalSubmitData()
+	if !strings.HasPrefix(data.CustomID, slotMachineModalPrefix) {
+		return
+	}
+	if !hasManageGuild(i) {
+		sendErrorResponse(s, i, "このコマンドを実行するにはサーバー管理権限が必要です。")
+		return
+	}
+
+	values := make(map[string]string)
+	for _, row := range data.Components {
+		for _, component := range row.(*discordgo.ActionsRow).Components {
+			input := component.(*discordgo.TextInput)
+			values[input.CustomID] = strings.TrimSpace(input.Value)
+		}
+	}
+
+	machine := &storage.SlotMachine{
+		GuildID:       i.GuildID,
+		Name:          strings.TrimPrefix(data.CustomID, slotMachineModalPrefix),
+		JackpotSymbol: values["jackpot"],
+		UpdatedBy:     i.Member.User.ID,
+	}
+	for _, line := range strings.Split(values["reels"], "\n") {
+		if reel := strings.Fields(line); len(reel) > 0 {
+			machine.Reels = append(machine.Reels, reel)
+		}
+	}
+	var err error
+	if machine.ThreeOfAKind, err = parsePaytable(values["three"]); err != nil {
+		sendErrorResponse(s, i, fmt.Sprintf("3つ揃いの配当を読み取れません: %v", err))
+		return
+	}
+	if machine.TwoOfAKind, err = parsePaytable(values["two"]); err != nil {
+		sendErrorResponse(s, i, fmt.Sprintf("2つ揃いの配当を読み取れません: %v", err))
+		return
+	}
+	if values["contribution"] != "" {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(values["contribution"], "%"), 64)
+		rng := storage.JackpotContributionRange
+		if err != nil || percent/100 < rng.Min || percent/100 > rng.Max {
+			sendErrorResponse(s, i, fmt.Sprintf("ジャックポット積立率は %g%% から %g%% の範囲で指定してください。", rng.Min*100, rng.Max*100))
+			return
+		}
+		machine.JackpotContribution = percent / 100
+	}
+
+	economy, err := c.Store.GetEconomyConfig(i.GuildID)
+	if err != nil {
+		c.Log.Error("Failed to get economy config", "error", err)
+		sendErrorResponse(s, i, "スロットマシンの保存中にエラーが発生しました。")
+		return
+	}
+	if err := slots.Validate(machine, economy.SlotsRTPCeiling, slots.Contribution(machine, economy.JackpotContribution)); err != nil {
+		if errors.Is(err, slots.ErrRTPTooHigh) {
+			sendErrorResponse(s, i, fmt.Sprintf("還元率が上限を超えているため保存できません: %v\n配当を下げるか、`/config economy` で上限を変更してください。", err))
+			return
+		}
+		sendErrorResponse(s, i, fmt.Sprintf("スロットマシンの設定が正しくありません: %v", err))
+		return
+	}
+
+	if err := c.Store.SaveSlotMachine(machine); err != nil {
+		c.Log.Error("Failed to save slot machine", "error", err)
+		sendErrorResponse(s, i, "スロットマシンの保存中にエラーが発生しました。")
+		return
+	}
+	embed := c.buildMachineEmbed(machine, economy)
+	embed.Title = fmt.Sprintf("✅ スロットマシン %s を保存しました", machine.Name)
+	embed.Color = 0x2ecc71 // Green
+	sendEmbedResponse(s, i, embed)
+}
+
+func (c *SlotMachineCommand) handleDelete(s *discordgo.Session, i *discordgo.InteractionCreate, name string) {
+	if !hasManageGuild(i) {
+		sendErrorResponse(s, i, "このコマンドを実行するにはサーバー管理権限が必要です。")
+		return
+	}
+	deleted, err := c.Store.DeleteSlotMachine(i.GuildID, name)
+	if err != nil {
+		c.Log.Error("Failed to delete slot machine", "error", err)
+		sendErrorResponse(s, i, "スロットマシンの削除中にエラーが発生しました。")
+		return
+	}
+	if !deleted {
+		sendErrorResponse(s, i, fmt.Sprintf("スロットマシン `%s` は見つかりません。", name))
+		return
+	}
+	message := fmt.Sprintf("スロットマシン `%s` を削除しました。", name)
+	if name == slots.DefaultName {
+		message += "\n`/slots` は組み込みのマシンに戻ります。"
+	}
+	sendSuccessResponse(s, i, message)
+}
+
+// formatPaytable は、配当表を倍率の高い順に「絵柄=倍率」の形式で並べます。
+func formatPaytable(paytable map[string]float64, separator string) string {
+	symbols := make([]string, 0, len(paytable))
+	for symbol := range paytable {
+		symbols = append(symbols, symbol)
+	}
+	sort.Slice(symbols, func(a, b int) bool {
+		if paytable[symbols[a]] != paytable[symbols[b]] {
+			return paytable[symbols[a]] > paytable[symbols[b]]
+		}
+		return symbols[a] < symbols[b]
+	})
+	entries := make([]string, len(symbols))
+	for n, symbol := range symbols {
+		entries[n] = symbol + "=" + strconv.FormatFloat(paytable[symbol], 'f', -1, 64)
+	}
+	return strings.Join(entries, separator)
+}
+
+// parsePaytable は、「絵柄=倍率」をスペースか改行で区切った配当表を読み取ります。
+func parsePaytable(text string) (map[string]float64, error) {
+	paytable := make(map[string]float64)
+	for _, entry := range strings.Fields(text) {
+		symbol, value, ok := strings.Cut(entry, "=")
+		if !ok || symbol == "" {
+			return nil, fmt.Errorf("%q は 絵柄=倍率 の形式ではありません", entry)
+		}
+		multiplier, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q の倍率が数値ではありません", entry)
+		}
+		paytable[symbol] = multiplier
+	}
+	return paytable, nil
+}
+
+func (c *SlotMachineCommand) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {}
+func (c *SlotMachineCommand) GetComponentIDs() []string                                            { return []string{slotMachineModalPrefix} }
+func (c *SlotMachineCommand) GetCategory() string                                                  { return "カジノ" }
@@ -2,7 +2,10 @@ package commands
 
 import (
 	"fmt"
+	"luna/fair"
 	"luna/interfaces"
+	"luna/slots"
+	"luna/storage"
 	"math/rand"
 	"strings"
 	"time"
@@ -14,35 +17,9 @@ import (
 type SlotsCommand struct {
 	Store interfaces.DataStore
 	Log   interfaces.Logger
+	Fair  *fair.Service
 }
 
-var (
-	// Reels are now weighted. More common symbols appear more frequently.
-	reels = [][]string{
-		{"🍒", "🍒", "🍒", "🍋", "🍋", "🍊", "🍊", "🍉", "🍇", "🍓", "💎"}, // Reel 1
-		{"🍒", "🍒", "🍋", "🍋", "🍋", "🍊", "🍊", "🍉", "🍉", "🍇", "🍓", "💎"}, // Reel 2
-		{"🍒", "🍒", "🍒", "🍋", "🍋", "🍊", "🍉", "🍉", "🍇", "🍇", "🍓", "💎"}, // Reel 3
-	}
-	payouts = map[string]int{
-		"💎💎💎": 50, // This is now the jackpot trigger, so the direct payout is less important.
-		"🍇🍇🍇": 20,
-		"🍓🍓🍓": 15,
-		"🍉🍉🍉": 10,
-		"🍊🍊🍊": 8,
-		"🍋🍋🍋": 5,
-		"🍒🍒🍒": 3,
-	}
-	payoutsTwoOfAKind = map[string]float64{
-		"💎": 1.5,
-		"🍇": 1.0,
-		"🍓": 1.0,
-		"🍉": 0.5,
-		"🍊": 0.5,
-		"🍋": 0.3,
-		"🍒": 2.0,
-	}
-)
-
 func (c *SlotsCommand) GetCommandDef() *discordgo.ApplicationCommand {
 	return &discordgo.ApplicationCommand{
 		Name:        "slots",
@@ -55,14 +32,31 @@ func (c *SlotsCommand) GetCommandDef() *discordgo.ApplicationCommand {
 				Required:    true,
 				MinValue:    &[]float64{1}[0],
 			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "machine",
+				Description: "遊ぶスロットマシン (一覧は /slotmachine list)",
+			},
 		},
 	}
 }
 
 func (c *SlotsCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	bet := i.ApplicationCommandData().Options[0].IntValue()
+	var bet int64
+	machineName := ""
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "bet":
+			bet = opt.IntValue()
+		case "machine":
+			machineName = opt.StringValue()
+		}
+	}
 	userID := i.Member.User.ID
 	guildID := i.GuildID
+	if rejectGamblingBet(s, i, c.Store, c.Log, bet) {
+		return
+	}
 
 	casinoData, err := c.Store.GetCasinoData(guildID, userID)
 	if err != nil {
@@ -76,12 +70,42 @@ func (c *SlotsCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCrea
 		return
 	}
 
+	economy, err := c.Store.GetEconomyConfig(guildID)
+	if err != nil {
+		c.Log.Error("Failed to get economy config for slots", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+
+	machine, err := loadSlotMachine(c.Store, guildID, machineName)
+	if err != nil {
+		c.Log.Error("Failed to get slot machine", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+	if machine == nil {
+		sendErrorResponse(s, i, fmt.Sprintf("スロットマシン `%s` は見つかりません。`/slotmachine list` で確認してください。", machineName))
+		return
+	}
+	contribution := slots.Contribution(machine, economy.JackpotContribution)
+	// 上限が後から下げられた場合に備え、遊ぶたびに還元率を確認する
+	if err := slots.Validate(machine, economy.SlotsRTPCeiling, contribution); err != nil {
+		sendErrorResponse(s, i, fmt.Sprintf("このスロットマシンは設定が正しくないため遊べません。管理者に連絡してください。(%v)", err))
+		return
+	}
+
+	round, err := c.Fair.NewRound(guildID, userID)
+	if err != nil {
+		c.Log.Error("Failed to start fair round for slots", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+	defer round.Close()
+
 	// --- Debit user's bet and contribute to jackpot BEFORE animation ---
 	casinoData.Chips -= bet
-	jackpotContribution := int64(float64(bet) * 0.01)
-	if jackpotContribution < 1 {
-		jackpotContribution = 1
-	}
+	// 端数は切り捨てる。少額のベットでも1チップ積み立てると、還元率が検証した上限を超えてしまう
+	jackpotContribution := int64(float64(bet) * contribution)
 
 	// Update the user's balance first to prevent race conditions
 	if err := c.Store.UpdateCasinoData(casinoData); err != nil {
@@ -109,25 +133,23 @@ func (c *SlotsCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCrea
 		return
 	}
 
-	rand.Seed(time.Now().UnixNano())
-
 	// --- Animation --- 
-	finalResult := []string{
-		reels[0][rand.Intn(len(reels[0]))],
-		reels[1][rand.Intn(len(reels[1]))],
-		reels[2][rand.Intn(len(reels[2]))],
-	}
+	finalResult := slots.Spin(machine, round.Rand)
+	// 回転中の表示は結果に関係しないため、公平性のための乱数とは別の乱数を使う
+	animationRand := rand.New(rand.NewSource(time.Now().UnixNano()))
 
 	// 1. Fast spinning animation
+	title := "🎰 スロット"
+	if machine.Name != slots.DefaultName {
+		title = fmt.Sprintf("🎰 %s", machine.Name)
+	}
 	animationEmbed := &discordgo.MessageEmbed{
-		Title: "🎰 スロット回転中...",
+		Title: title + " 回転中...",
 		Color: 0x3498db, // Blue
 	}
 	for j := 0; j < 5; j++ { // Spin for a short duration
-		r1 := reels[0][rand.Intn(len(reels[0]))]
-		r2 := reels[1][rand.Intn(len(reels[1]))]
-		r3 := reels[2][rand.Intn(len(reels[2]))]
-		animationEmbed.Description = fmt.Sprintf("**[ %s | %s | %s ]**", r1, r2, r3)
+		spinning := slots.Spin(machine, animationRand)
+		animationEmbed.Description = fmt.Sprintf("**[ %s | %s | %s ]**", spinning[0], spinning[1], spinning[2])
 		if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Embeds: &[]*discordgo.MessageEmbed{animationEmbed}}); err != nil {
 			c.Log.Error("Failed to edit animation embed", "error", err)
 		}
@@ -152,42 +174,26 @@ func (c *SlotsCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCrea
 	jackpotWon := false
 	winDescription := ""
 
-	resultStr := strings.Join(finalResult, "")
-
-	// 1. Check for 3-of-a-kind (including jackpot)
-	if p, ok := payouts[resultStr]; ok {
+	outcome := slots.Evaluate(machine, finalResult)
+	switch {
+	case outcome.Jackpot:
 		won = true
-		if resultStr == "💎💎💎" {
-			jackpotWon = true
-			winDescription = "👑 JACKPOT! 👑"
-			winnings = int64(currentJackpot)
-			if err := c.Store.UpdateJackpot(guildID, 0); err != nil {
-				c.Log.Error("Failed to reset jackpot", "error", err)
-			}
-		} else {
-			winDescription = fmt.Sprintf("%s 揃い！", resultStr)
-			winnings = bet * int64(p)
-		}
-		casinoData.Chips += winnings
-	} else {
-		// 2. If no 3-of-a-kind, check for 2-of-a-kind
-		counts := make(map[string]int)
-		for _, s := range finalResult {
-			counts[s]++
-		}
-
-		for symbol, count := range counts {
-			if count == 2 {
-				if multiplier, ok := payoutsTwoOfAKind[symbol]; ok {
-					won = true
-					winDescription = fmt.Sprintf("%s 2つ！", symbol)
-					winnings = int64(float64(bet) * multiplier)
-					casinoData.Chips += winnings
-					break // Found a 2-of-a-kind, no need to check others
-				}
-			}
+		jackpotWon = true
+		winDescription = "👑 JACKPOT! 👑"
+		winnings = int64(currentJackpot)
+		if err := c.Store.UpdateJackpot(guildID, 0); err != nil {
+			c.Log.Error("Failed to reset jackpot", "error", err)
 		}
+	case outcome.Count == len(finalResult) && outcome.Multiplier > 0:
+		won = true
+		winDescription = fmt.Sprintf("%s 揃い！", strings.Join(finalResult, ""))
+		winnings = int64(float64(bet) * outcome.Multiplier)
+	case outcome.Multiplier > 0:
+		won = true
+		winDescription = fmt.Sprintf("%s 2つ！", outcome.Symbol)
+		winnings = int64(float64(bet) * outcome.Multiplier)
 	}
+	casinoData.Chips += winnings
 
 	// If there were winnings, update the database again
 	if won {
@@ -198,9 +204,9 @@ func (c *SlotsCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCrea
 
 	// Final result embed
 	resultEmbed := &discordgo.MessageEmbed{
-		Title:       "🎰 スロット結果！",
+		Title:       title + " 結果！",
 		Description: fmt.Sprintf("**[ %s | %s | %s ]**", finalResult[0], finalResult[1], finalResult[2]),
-		Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("現在のジャックポット: %d チップ", currentJackpot)},
+		Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("現在のジャックポット: %d チップ | %s", currentJackpot, round.Footer())},
 	}
 
 	if jackpotWon {
@@ -233,6 +239,30 @@ func (c *SlotsCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCrea
 	if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Embeds: &[]*discordgo.MessageEmbed{resultEmbed}}); err != nil {
 		c.Log.Error("Failed to edit final slots response", "error", err)
 	}
+
+	recordGameRound(c.Store, c.Log, guildID, userID, storage.GameSlots, bet, winnings)
+	progress := map[string]int64{counterSlotsSpins: 1, counterChipsWon: winnings}
+	if jackpotWon {
+		progress[counterSlotsJackpots] = 1
+	}
+	recordAchievements(s, c.Store, c.Log, i.ChannelID, guildID, userID, progress)
+}
+
+// loadSlotMachine は、サーバーのスロットマシンを返します。name が空なら既定のマシンを使います。
+// 既定のマシンをサーバーで定義していなければ組み込みのマシンを返し、それ以外で見つからなければ nil を返します。
+func loadSlotMachine(store interfaces.DataStore, guildID, name string) (*storage.SlotMachine, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = slots.DefaultName
+	}
+	machine, err := store.GetSlotMachine(guildID, name)
+	if err != nil || machine != nil {
+		return machine, err
+	}
+	if name == slots.DefaultName {
+		return slots.DefaultMachine(), nil
+	}
+	return nil, nil
 }
 
 func (c *SlotsCommand) GetCategory() string {
@@ -0,0 +1,178 @@
+package commands
+
+import (
+	"fmt"
+	"luna/interfaces"
+	"luna/storage"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// StatsCommand handles the /stats command.
+type StatsCommand struct {
+	Store interfaces.DataStore
+	Log   interfaces.Logger
+}
+
+func (c *StatsCommand) GetCommandDef() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "stats",
+		Description: "カジノゲームの成績を表示します。",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "player",
+				Description: "ゲームごとの勝敗と収支を表示します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionUser, Name: "user", Description: "成績を表示するユーザー (デフォルト: 自分)"},
+				},
+			},
+			{
+				Name:        "house",
+				Description: "[管理者] サーバー全体の賭け金と胴元の取り分を表示します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+		},
+	}
+}
+
+func (c *StatsCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	subcommand := i.ApplicationCommandData().Options[0]
+	switch subcommand.Name {
+	case "player":
+		c.handlePlayer(s, i, subcommand.Options)
+	case "house":
+		c.handleHouse(s, i)
+	}
+}
+
+// gameLabel は、ゲームの表示名を返します。
+func gameLabel(game string) string {
+	switch game {
+	case storage.GameSlots:
+		return "🎰 スロット"
+	case storage.GameVideoSlot:
+		return "🎰 ビデオスロット"
+	case storage.GameBlackjack:
+		return "🃏 ブラックジャック"
+	case storage.GameCoinflip:
+		return "🪙 コインフリップ"
+	case storage.GameHiLow:
+		return "🔼 ハイ＆ロー"
+	case storage.GameHorseRace:
+		return "🏇 競馬"
+	case storage.GameQuiz:
+		return "❓ クイズ"
+	case storage.GameFish:
+		return "🎣 釣り"
+	case storage.GamePoker:
+		return "♠️ ポーカー"
+	case storage.GameRoulette:
+		return "🎡 ルーレット"
+	case storage.GameDuel:
+		return "⚔️ デュエル"
+	case storage.GameCrash:
+		return "🚀 クラッシュ"
+	case storage.GameMines:
+		return "💣 マインズ"
+	}
+	return game
+}
+
+func (c *StatsCommand) handlePlayer(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	user := i.Member.User
+	if len(options) > 0 {
+		user = options[0].UserValue(s)
+	}
+
+	stats, err := c.Store.GetGameStats(i.GuildID, user.ID)
+	if err != nil {
+		c.Log.Error("Failed to get game stats", "error", err)
+		sendErrorResponse(s, i, "成績の取得中にエラーが発生しました。")
+		return
+	}
+	if len(stats) == 0 {
+		sendErrorResponse(s, i, fmt.Sprintf("%s はまだカジノゲームで遊んでいません。", user.Username))
+		return
+	}
+
+	var total storage.GameStats
+	embed := &discordgo.MessageEmbed{
+		Title:     fmt.Sprintf("📊 %s の成績", user.Username),
+		Color:     0x3498db, // Blue
+		Thumbnail: &discordgo.MessageEmbedThumbnail{URL: user.AvatarURL("")},
+	}
+	for _, g := range stats {
+		total.Rounds += g.Rounds
+		total.Wagered += g.Wagered
+		total.Returned += g.Returned
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name: gameLabel(g.Game),
+			Value: fmt.Sprintf("プレイ: %d 回\n賭け: %d / 戻り: %d\n収支: **%+d**\n最大勝ち: %d\n連勝: %d (最長 %d)",
+				g.Rounds, g.Wagered, g.Returned, g.Net(), g.BiggestWin, g.CurrentStreak, g.LongestStreak),
+			Inline: true,
+		})
+	}
+	embed.Description = fmt.Sprintf("合計 %d 回プレイ / 賭け %d チップ / 戻り %d チップ\n**通算収支: %+d チップ**",
+		total.Rounds, total.Wagered, total.Returned, total.Net())
+	sendEmbedResponse(s, i, embed)
+}
+
+func (c *StatsCommand) handleHouse(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !hasManageGuild(i) {
+		sendErrorResponse(s, i, "このコマンドを実行するにはサーバー管理権限が必要です。")
+		return
+	}
+
+	stats, err := c.Store.GetGuildGameStats(i.GuildID)
+	if err != nil {
+		c.Log.Error("Failed to get guild game stats", "error", err)
+		sendErrorResponse(s, i, "統計の取得中にエラーが発生しました。")
+		return
+	}
+	if len(stats) == 0 {
+		sendErrorResponse(s, i, "まだこのサーバーでカジノゲームは遊ばれていません。")
+		return
+	}
+
+	var total storage.GameStats
+	embed := &discordgo.MessageEmbed{
+		Title: "🏛️ カジノの収支",
+		Color: 0x95a5a6, // Gray
+	}
+	for _, g := range stats {
+		total.Rounds += g.Rounds
+		total.Wagered += g.Wagered
+		total.Returned += g.Returned
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name: gameLabel(g.Game),
+			Value: fmt.Sprintf("プレイヤー: %d 人 / %d 回\n賭け: %d / 払い戻し: %d\n胴元の収支: **%+d**\nハウスエッジ: **%.2f%%**\n最大勝ち: %d",
+				g.Players, g.Rounds, g.Wagered, g.Returned, -g.Net(), g.HouseEdge()*100, g.BiggestWin),
+			Inline: true,
+		})
+	}
+	embed.Description = fmt.Sprintf("合計 %d 回 / 賭け %d チップ / 払い戻し %d チップ\n胴元の収支: **%+d チップ** (ハウスエッジ %.2f%%)",
+		total.Rounds, total.Wagered, total.Returned, -total.Net(), total.HouseEdge()*100)
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// recordGameRound は、ゲーム1回分の賭け金と戻りを統計に記録し、記録待ちの賭け金から外します。
+// 統計の記録に失敗してもゲームの結果には影響させないため、エラーはログに残すだけにします。
+func recordGameRound(store interfaces.DataStore, log interfaces.Logger, guildID, userID, game string, wagered, returned int64) {
+	pendingBets.release(guildID, userID, game, wagered)
+	if err := store.RecordGameRound(guildID, userID, game, wagered, returned); err != nil {
+		log.Error("Failed to record game round", "error", err, "game", game, "userID", userID)
+	}
+}
+
+func (c *StatsCommand) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {}
+func (c *StatsCommand) HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate)     {}
+func (c *StatsCommand) GetComponentIDs() []string                                            { return []string{} }
+func (c *StatsCommand) GetCategory() string                                                  { return "カジノ" }
@@ -49,20 +49,57 @@ var (
 type StockCommand struct {
 	Store     interfaces.DataStore
 	Log       interfaces.Logger
-	Companies []storage.Company // Now uses the struct from storage
+	Session   *discordgo.Session // 追証などの通知に使用
+	Companies []storage.Company  // Now uses the struct from storage
 	mu        sync.RWMutex
+	books     map[string]*OrderBook // companyCode -> order book
+	bookMu    sync.Mutex
 }
 
 // NewStockCommand creates a new StockCommand.
-func NewStockCommand(store interfaces.DataStore, log interfaces.Logger) *StockCommand {
+func NewStockCommand(store interfaces.DataStore, log interfaces.Logger, session *discordgo.Session) *StockCommand {
 	sc := &StockCommand{
-		Store: store,
-		Log:   log,
+		Store:   store,
+		Log:     log,
+		Session: session,
+		books:   make(map[string]*OrderBook),
 	}
 	go sc.loadInitialCompanies()
+	go sc.ReloadOrderBooks()
 	return sc
 }
 
+// ReloadOrderBooks は、DBに残っている未約定の注文から板を復元します。
+func (c *StockCommand) ReloadOrderBooks() {
+	c.bookMu.Lock()
+	defer c.bookMu.Unlock()
+	c.restoreBooksLocked()
+}
+
+// restoreBooksLocked rebuilds every order book from the DB. Caller must hold bookMu.
+func (c *StockCommand) restoreBooksLocked() {
+	orders, err := c.Store.GetOpenStockOrders()
+	if err != nil {
+		c.Log.Error("Failed to load open stock orders from DB", "error", err)
+		return
+	}
+	c.books = make(map[string]*OrderBook)
+	for idx := range orders {
+		c.getBook(orders[idx].CompanyCode).insert(&orders[idx])
+	}
+	c.Log.Info("Successfully restored order books", "orders", len(orders))
+}
+
+// getBook returns the order book of a company, creating it if needed. Caller must hold bookMu.
+func (c *StockCommand) getBook(code string) *OrderBook {
+	book, ok := c.books[code]
+	if !ok {
+		book = NewOrderBook(code)
+		c.books[code] = book
+	}
+	return book
+}
+
 func (c *StockCommand) loadInitialCompanies() {
 	companies, err := c.Store.GetAllCompanies()
 	if err != nil {
@@ -124,6 +161,74 @@ func (c *StockCommand) GetCommandDef() *discordgo.ApplicationCommand {
 				Description: "株式資産を含めたサーバー内の資産家ランキングを表示します。",
 				Type:        discordgo.ApplicationCommandOptionSubCommand,
 			},
+			{
+				Name:        "bid",
+				Description: "他のプレイヤーから株を買う指値注文を出します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "code", Description: "銘柄コード (例: CSN)", Required: true},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "price", Description: "1株あたりの買値 (PPC)", Required: true, MinValue: &[]float64{1}[0], MaxValue: storage.MaxOrderPrice},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "amount", Description: "購入したい株数", Required: true, MinValue: &[]float64{1}[0], MaxValue: storage.MaxOrderQuantity},
+				},
+			},
+			{
+				Name:        "ask",
+				Description: "保有株を他のプレイヤーに売る指値注文を出します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "code", Description: "銘柄コード (例: CSN)", Required: true},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "price", Description: "1株あたりの売値 (PPC)", Required: true, MinValue: &[]float64{1}[0], MaxValue: storage.MaxOrderPrice},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "amount", Description: "売却したい株数", Required: true, MinValue: &[]float64{1}[0], MaxValue: storage.MaxOrderQuantity},
+				},
+			},
+			{
+				Name:        "cancel",
+				Description: "未約定の注文を取り消します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "order_id", Description: "取り消す注文の番号", Required: true},
+				},
+			},
+			{
+				Name:        "orders",
+				Description: "あなたの未約定の注文一覧を表示します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Name:        "short",
+				Description: "株を借りて空売りします。証拠金としてPPCを預けます。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "code", Description: "銘柄コード (例: CSN)", Required: true},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "amount", Description: "空売りする株数", Required: true, MinValue: &[]float64{1}[0]},
+				},
+			},
+			{
+				Name:        "cover",
+				Description: "空売りした株を買い戻します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "code", Description: "銘柄コード (例: CSN)", Required: true},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "amount", Description: "買い戻す株数", Required: true, MinValue: &[]float64{1}[0]},
+				},
+			},
+			{
+				Name:        "collateral",
+				Description: "空売りポジションに証拠金を追加します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "code", Description: "銘柄コード (例: CSN)", Required: true},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "amount", Description: "追加するPPC", Required: true, MinValue: &[]float64{1}[0]},
+				},
+			},
+			{
+				Name:        "book",
+				Description: "銘柄の板（注文状況）と直近の約定を表示します。",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "code", Description: "銘柄コード (例: CSN)", Required: true},
+				},
+			},
 		},
 	}
 }
@@ -142,6 +247,22 @@ func (c *StockCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCrea
 		c.handleInfo(s, i)
 	case "leaderboard":
 		c.handleLeaderboard(s, i)
+	case "bid":
+		c.handlePlaceOrder(s, i, storage.OrderSideBid)
+	case "ask":
+		c.handlePlaceOrder(s, i, storage.OrderSideAsk)
+	case "cancel":
+		c.handleCancelOrder(s, i)
+	case "orders":
+		c.handleOrders(s, i)
+	case "book":
+		c.handleBook(s, i)
+	case "short":
+		c.handleShort(s, i)
+	case "cover":
+		c.handleCover(s, i)
+	case "collateral":
+		c.handleCollateral(s, i)
 	}
 }
 
@@ -157,7 +278,7 @@ func (c *StockCommand) handleList(s *discordgo.Session, i *discordgo.Interaction
 	for _, company := range c.Companies {
 		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
 			Name:   fmt.Sprintf("**%s (%s)**", company.Name, company.Code),
-			Value:  fmt.Sprintf("```\n現在価格: %.2f PPC\n```\n*事業内容: %s*", company.Price, company.Description),
+			Value:  fmt.Sprintf("```\n現在価格: %.2f PPC\n最終約定: %s\n```\n*事業内容: %s*", company.Price, formatLastTrade(company), company.Description),
 			Inline: false,
 		})
 	}
@@ -205,7 +326,7 @@ func (c *StockCommand) handleBuy(s *discordgo.Session, i *discordgo.InteractionC
 		return
 	}
 
-	if err := c.Store.UpdateUserPortfolio(userID, code, amount); err != nil {
+	if err := c.Store.UpdateUserPortfolio(guildID, userID, code, amount); err != nil {
 		c.Log.Error("Failed to update portfolio for stock buy", "error", err)
 		// TODO: Add logic to refund PepeCoin if this fails
 		sendErrorResponse(s, i, "ポートフォリオの更新中にエラーが発生しました。")
@@ -258,7 +379,7 @@ func (c *StockCommand) handleSell(s *discordgo.Session, i *discordgo.Interaction
 		return
 	}
 
-	if err := c.Store.UpdateUserPortfolio(userID, code, -amountToSell); err != nil {
+	if err := c.Store.UpdateUserPortfolio(guildID, userID, code, -amountToSell); err != nil {
 		c.Log.Error("Failed to update portfolio for stock sell", "error", err)
 		// Attempt to revert the balance change
 		casinoData.PepeCoinBalance -= totalProceeds
@@ -281,7 +402,35 @@ func (c *StockCommand) findCompanyByCode(code string) (*storage.Company, bool) {
 	return nil, false
 }
 
-// UpdateStockPrices は、コマンド利用状況に基づいて株価を更新します。
+// 株価モデルのパラメータ
+const (
+	revenuePerUse      = 5.0   // コマンド1回の利用で企業にもたらされる売上 (PPC)
+	revenueSmoothing   = 0.1   // 売上の指数移動平均の平滑化係数
+	updatesPerDay      = 288.0 // 5分ごとの株価更新が1日に行われる回数
+	earningsMultiple   = 100.0 // 1日あたりの1株利益に掛ける倍率
+	meanReversionSpeed = 0.05  // 理論株価へ回帰する速さ (1回の更新あたり)
+	priceNoise         = 0.02  // ランダムな価格変動の幅 (±1%)
+)
+
+// fairValue は、企業の純資産と直近の売上から理論株価を算出します。
+func fairValue(company storage.Company) float64 {
+	if company.SharesOutstanding <= 0 {
+		return company.BookValue
+	}
+	dailyEarningsPerShare := company.Revenue * updatesPerDay / float64(company.SharesOutstanding)
+	return company.BookValue + dailyEarningsPerShare*earningsMultiple
+}
+
+// expectedDividendPerShare は、現在までに積み上がった売上から次回の1株あたり配当を見積もります。
+func expectedDividendPerShare(company storage.Company) float64 {
+	if company.SharesOutstanding <= 0 {
+		return 0
+	}
+	return company.AccruedRevenue * company.PayoutRatio / float64(company.SharesOutstanding)
+}
+
+// UpdateStockPrices は、コマンド利用状況から各企業の売上を計算し、
+// 株価を理論株価に向かって平均回帰させます。
 func (c *StockCommand) UpdateStockPrices() {
 	usage, err := c.Store.GetAndResetCommandUsage()
 	if err != nil {
@@ -289,44 +438,74 @@ func (c *StockCommand) UpdateStockPrices() {
 		return
 	}
 
+	// 価格の更新が終わりロックを解放した後に、空売りの証拠金維持率を確認する
+	defer c.checkMargins()
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	newPrices := make(map[string]float64)
-	for i, company := range c.Companies {
-		activityFactor := 0.0
+	for i := range c.Companies {
+		company := &c.Companies[i]
+		activity := 0.0
 		for _, category := range company.RelatedCategories {
-			activityFactor += float64(usage[category])
+			activity += float64(usage[category])
 		}
 
-		// アクティビティに基づいて価格変動率を計算
-		// 基本変動率 + アクティビティによる変動
-		baseChange := (rand.Float64() - 0.5) * 0.02 // -1% to +1%
-		activityChange := activityFactor * 0.001    // 1 usage = +0.1% change
-		decay := -0.005                             // 何も使われないと少しずつ下がる
+		// 売上を計上し、平滑化された売上を更新
+		revenue := activity * revenuePerUse
+		company.AccruedRevenue += revenue
+		company.Revenue += (revenue - company.Revenue) * revenueSmoothing
 
-		changePercent := baseChange + activityChange + decay
-		newPrice := company.Price * (1 + changePercent)
+		// 理論株価との乖離に応じた回帰 + ランダムな変動
+		target := fairValue(*company)
+		reversion := meanReversionSpeed * (target - company.Price) / company.Price
+		noise := (rand.Float64() - 0.5) * priceNoise
+		newPrice := company.Price * (1 + reversion + noise)
 
 		// 価格が極端になりすぎないように制限
 		if newPrice < 1.0 {
 			newPrice = 1.0
 		}
-
-		c.Companies[i].Price = newPrice
-		newPrices[company.Code] = newPrice
+		company.Price = newPrice
 	}
 
-	if err := c.Store.UpdateCompanyPrices(newPrices); err != nil {
-		c.Log.Error("Failed to update company prices in DB", "error", err)
+	if err := c.Store.UpdateCompanyFundamentals(c.Companies); err != nil {
+		c.Log.Error("Failed to update company fundamentals in DB", "error", err)
 		return
 	}
 
 	c.Log.Info("Stock prices updated based on command usage", "usage_data", usage)
 }
 
+// PayDividends は、各企業の積み上がった売上の一部を保有者にPepeCoinで配当します。
+func (c *StockCommand) PayDividends() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.Companies {
+		company := &c.Companies[i]
+		perShare := expectedDividendPerShare(*company)
+		if perShare <= 0 {
+			continue
+		}
+
+		payments, err := c.Store.PayDividend(company.Code, perShare)
+		if err != nil {
+			c.Log.Error("Failed to pay dividend", "error", err, "company", company.Code)
+			continue
+		}
+		company.AccruedRevenue = 0
+
+		var total int64
+		for _, p := range payments {
+			total += p.Amount
+		}
+		c.Log.Info("Dividend paid", "company", company.Code, "per_share", perShare, "holders", len(payments), "total", total)
+	}
+}
+
 // TriggerRandomEvent は、ランダムな市場イベントを発生させ、特定の企業の株価を大きく変動させます。
 func (c *StockCommand) TriggerRandomEvent(s *discordgo.Session, guildID string) {
+	defer c.checkMargins()
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -403,10 +582,26 @@ func (c *StockCommand) handlePortfolio(s *discordgo.Session, i *discordgo.Intera
 		},
 	}
 
+	shorts, err := c.Store.GetUserShortPositions(targetUser.ID)
+	if err != nil {
+		c.Log.Error("Failed to get short positions for portfolio", "error", err)
+	}
+
+	// 注文で預けている株とPPCも資産として数える
+	orders, err := c.Store.GetUserOpenStockOrders(targetUser.ID)
+	if err != nil {
+		c.Log.Error("Failed to get open orders for portfolio", "error", err)
+	}
+	lockedShares, lockedPPC := openOrderEscrow(orders, i.GuildID)
+	for code, shares := range lockedShares {
+		portfolio[code] += shares
+	}
+
 	var totalStockValue float64
+	var totalShortEquity float64
 	var stockDetails strings.Builder
 
-	if len(portfolio) == 0 {
+	if len(portfolio) == 0 && len(shorts) == 0 {
 		stockDetails.WriteString("現在、株式を保有していません。")
 	} else {
 		for code, shares := range portfolio {
@@ -417,11 +612,39 @@ func (c *StockCommand) handlePortfolio(s *discordgo.Session, i *discordgo.Intera
 			currentValue := company.Price * float64(shares)
 			totalStockValue += currentValue
 			stockDetails.WriteString(fmt.Sprintf("**%s (%s)**\n", company.Name, company.Code))
-			stockDetails.WriteString(fmt.Sprintf("保有数: `%d`株\n評価額: `%.2f` PPC\n\n", shares, currentValue))
+			stockDetails.WriteString(fmt.Sprintf("保有数: `%d`株", shares))
+			if locked := lockedShares[code]; locked > 0 {
+				stockDetails.WriteString(fmt.Sprintf(" (うち売り注文中 `%d`株)", locked))
+			}
+			stockDetails.WriteString(fmt.Sprintf("\n評価額: `%.2f` PPC\n\n", currentValue))
+		}
+		// 空売りはマイナスの保有数として表示する
+		for _, position := range shorts {
+			company, exists := c.findCompanyByCode(position.CompanyCode)
+			if !exists {
+				continue
+			}
+			totalShortEquity += shortEquity(position, company.Price)
+			stockDetails.WriteString(fmt.Sprintf("**%s (%s)** 🔻空売り\n", company.Name, company.Code))
+			stockDetails.WriteString(fmt.Sprintf("保有数: `%d`株\n評価額: `%.2f` PPC\n証拠金: `%d` PPC (維持率 `%.0f%%`)\n\n",
+				-position.Shares, -company.Price*float64(position.Shares), position.Collateral, shortMarginRatio(position, company.Price)*100))
 		}
 	}
 
-	totalAssets := totalStockValue + float64(casinoData.PepeCoinBalance)
+	totalAssets := totalStockValue + totalShortEquity + float64(casinoData.PepeCoinBalance+lockedPPC)
+
+	var dividendDetails strings.Builder
+	dividends, err := c.Store.GetDividendHistory(targetUser.ID, 5)
+	if err != nil {
+		c.Log.Error("Failed to get dividend history", "error", err)
+	}
+	if len(dividends) == 0 {
+		dividendDetails.WriteString("まだ配当を受け取っていません。")
+	} else {
+		for _, d := range dividends {
+			dividendDetails.WriteString(fmt.Sprintf("<t:%d:d> **%s** `%d`株 × `%.2f` = **`%d`** PPC\n", d.PaidAt.Unix(), d.CompanyCode, d.Shares, d.PerShare, d.Amount))
+		}
+	}
 
 	embed.Fields = []*discordgo.MessageEmbedField{
 		{
@@ -433,15 +656,24 @@ func (c *StockCommand) handlePortfolio(s *discordgo.Session, i *discordgo.Intera
 			Value:  fmt.Sprintf("評価額合計: `%.2f` PPC", totalStockValue),
 			Inline: true,
 		},
+		{
+			Name:   "空売り (純資産)",
+			Value:  fmt.Sprintf("`%.2f` PPC", totalShortEquity),
+			Inline: true,
+		},
 		{
 			Name:   "現金",
-			Value:  fmt.Sprintf("`%d` PPC", casinoData.PepeCoinBalance),
+			Value:  fmt.Sprintf("`%d` PPC (買い注文中 `%d` PPC)", casinoData.PepeCoinBalance, lockedPPC),
 			Inline: true,
 		},
 		{
 			Name:  "保有銘柄一覧",
 			Value: stockDetails.String(),
 		},
+		{
+			Name:  "📜 最近の配当",
+			Value: dividendDetails.String(),
+		},
 	}
 
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
@@ -471,11 +703,26 @@ func (c *StockCommand) handleInfo(s *discordgo.Session, i *discordgo.Interaction
 				Value:  fmt.Sprintf("**`%.2f` PPC**", company.Price),
 				Inline: true,
 			},
+			{
+				Name:   "理論株価",
+				Value:  fmt.Sprintf("`%.2f` PPC", fairValue(*company)),
+				Inline: true,
+			},
 			{
 				Name:   "関連カテゴリ",
 				Value:  strings.Join(company.RelatedCategories, ", "),
 				Inline: true,
 			},
+			{
+				Name:   "1日あたりの売上 (推定)",
+				Value:  fmt.Sprintf("`%.2f` PPC", company.Revenue*updatesPerDay),
+				Inline: true,
+			},
+			{
+				Name:   "次回配当 (1株あたり・推定)",
+				Value:  fmt.Sprintf("`%.2f` PPC (配当性向 %.0f%%)", expectedDividendPerShare(*company), company.PayoutRatio*100),
+				Inline: true,
+			},
 		},
 	}
 
@@ -515,6 +762,14 @@ func (c *StockCommand) handleLeaderboard(s *discordgo.Session, i *discordgo.Inte
 		if err != nil {
 			continue // Skip user on error
 		}
+		orders, err := c.Store.GetUserOpenStockOrders(userID)
+		if err != nil {
+			continue // Skip user on error
+		}
+		lockedShares, lockedPPC := openOrderEscrow(orders, i.GuildID)
+		for code, shares := range lockedShares {
+			portfolio[code] += shares
+		}
 
 		var totalStockValue float64
 		for code, shares := range portfolio {
@@ -525,7 +780,20 @@ func (c *StockCommand) handleLeaderboard(s *discordgo.Session, i *discordgo.Inte
 			totalStockValue += company.Price * float64(shares)
 		}
 
-		totalAssets := totalStockValue + float64(casinoData.PepeCoinBalance)
+		// 空売りは、証拠金から買戻し必要額を差し引いた純資産として計上する
+		shorts, err := c.Store.GetUserShortPositions(userID)
+		if err != nil {
+			continue // Skip user on error
+		}
+		for _, position := range shorts {
+			company, exists := c.findCompanyByCode(position.CompanyCode)
+			if !exists {
+				continue
+			}
+			totalStockValue += shortEquity(position, company.Price)
+		}
+
+		totalAssets := totalStockValue + float64(casinoData.PepeCoinBalance+lockedPPC)
 		assets = append(assets, UserAsset{UserID: userID, TotalAssets: totalAssets})
 	}
 
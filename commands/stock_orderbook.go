@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"luna/storage"
+	"sort"
+)
+
+// OrderBook は、1銘柄分の板情報（買い注文と売り注文）を保持するマッチングエンジンです。
+// 約定は価格優先・時間優先で行われ、約定価格は板に先に並んでいた注文（メイカー）の指値になります。
+// 時間の前後は注文IDで判定するため、同じ入力からは常に同じ結果が得られます。
+type OrderBook struct {
+	Code string
+	Bids []*storage.StockOrder // 価格の高い順、同価格なら古い順
+	Asks []*storage.StockOrder // 価格の安い順、同価格なら古い順
+}
+
+// BookLevel は、板の1つの価格帯に並んでいる注文の合計です。
+type BookLevel struct {
+	Price    int64
+	Quantity int64
+	Orders   int
+}
+
+// NewOrderBook creates an empty order book for a company.
+func NewOrderBook(code string) *OrderBook {
+	return &OrderBook{Code: code}
+}
+
+// Submit は注文を板に出し、成立した約定を返します。
+// 約定しきれなかった残りは板に並びます。注文の Remaining は約定に合わせて更新されます。
+func (b *OrderBook) Submit(order *storage.StockOrder) []storage.StockTrade {
+	var trades []storage.StockTrade
+
+	if order.Side == storage.OrderSideBid {
+		b.Asks, trades = b.match(order, b.Asks, func(resting int64) bool { return resting <= order.Price })
+	} else {
+		b.Bids, trades = b.match(order, b.Bids, func(resting int64) bool { return resting >= order.Price })
+	}
+
+	if order.Remaining > 0 {
+		b.insert(order)
+	}
+	return trades
+}
+
+// match は、incoming を反対側の板 opposite と突き合わせ、更新後の板と約定を返します。
+func (b *OrderBook) match(incoming *storage.StockOrder, opposite []*storage.StockOrder, crosses func(int64) bool) ([]*storage.StockOrder, []storage.StockTrade) {
+	var trades []storage.StockTrade
+	kept := opposite[:0:0]
+
+	for idx, resting := range opposite {
+		if incoming.Remaining == 0 || !crosses(resting.Price) {
+			kept = append(kept, opposite[idx:]...)
+			break
+		}
+		// 自己約定は行わず、自分の注文は板に残したままにする
+		if resting.UserID == incoming.UserID {
+			kept = append(kept, resting)
+			continue
+		}
+
+		quantity := min(incoming.Remaining, resting.Remaining)
+		incoming.Remaining -= quantity
+		resting.Remaining -= quantity
+		trades = append(trades, newTrade(b.Code, incoming, resting, quantity))
+
+		if resting.Remaining > 0 {
+			kept = append(kept, resting)
+		}
+	}
+	return kept, trades
+}
+
+func newTrade(code string, incoming, resting *storage.StockOrder, quantity int64) storage.StockTrade {
+	buy, sell := incoming, resting
+	if incoming.Side == storage.OrderSideAsk {
+		buy, sell = resting, incoming
+	}
+	return storage.StockTrade{
+		CompanyCode:   code,
+		BuyOrderID:    buy.ID,
+		SellOrderID:   sell.ID,
+		BuyerID:       buy.UserID,
+		BuyerGuildID:  buy.GuildID,
+		SellerID:      sell.UserID,
+		SellerGuildID: sell.GuildID,
+		BidPrice:      buy.Price,
+		Price:         resting.Price,
+		Quantity:      quantity,
+	}
+}
+
+// insert は、価格優先・時間優先の順序を保って注文を板に加えます。
+func (b *OrderBook) insert(order *storage.StockOrder) {
+	if order.Side == storage.OrderSideBid {
+		idx := sort.Search(len(b.Bids), func(i int) bool {
+			return b.Bids[i].Price < order.Price || (b.Bids[i].Price == order.Price && b.Bids[i].ID > order.ID)
+		})
+		b.Bids = append(b.Bids, nil)
+		copy(b.Bids[idx+1:], b.Bids[idx:])
+		b.Bids[idx] = order
+		return
+	}
+	idx := sort.Search(len(b.Asks), func(i int) bool {
+		return b.Asks[i].Price > order.Price || (b.Asks[i].Price == order.Price && b.Asks[i].ID > order.ID)
+	})
+	b.Asks = append(b.Asks, nil)
+	copy(b.Asks[idx+1:], b.Asks[idx:])
+	b.Asks[idx] = order
+}
+
+// Cancel は板から注文を取り除きます。見つからなければ false を返します。
+func (b *OrderBook) Cancel(orderID int64) bool {
+	for idx, o := range b.Bids {
+		if o.ID == orderID {
+			b.Bids = append(b.Bids[:idx], b.Bids[idx+1:]...)
+			return true
+		}
+	}
+	for idx, o := range b.Asks {
+		if o.ID == orderID {
+			b.Asks = append(b.Asks[:idx], b.Asks[idx+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Depth は、買い・売りそれぞれ最良気配から levels 段分の板を価格帯ごとに集計して返します。
+func (b *OrderBook) Depth(levels int) (bids, asks []BookLevel) {
+	return aggregateLevels(b.Bids, levels), aggregateLevels(b.Asks, levels)
+}
+
+func aggregateLevels(orders []*storage.StockOrder, levels int) []BookLevel {
+	var result []BookLevel
+	for _, o := range orders {
+		if n := len(result); n > 0 && result[n-1].Price == o.Price {
+			result[n-1].Quantity += o.Remaining
+			result[n-1].Orders++
+			continue
+		}
+		if len(result) == levels {
+			break
+		}
+		result = append(result, BookLevel{Price: o.Price, Quantity: o.Remaining, Orders: 1})
+	}
+	return result
+}
@@ -0,0 +1,233 @@
+package commands
+
+import (
+	"luna/storage"
+	"reflect"
+	"testing"
+)
+
+// bidOrder と askOrder は、テスト用の注文を作ります。ID が小さいほど古い注文です。
+func bidOrder(id int64, user string, price, quantity int64) *storage.StockOrder {
+	return &storage.StockOrder{ID: id, UserID: user, GuildID: "guild", Side: storage.OrderSideBid, Price: price, Quantity: quantity, Remaining: quantity}
+}
+
+func askOrder(id int64, user string, price, quantity int64) *storage.StockOrder {
+	return &storage.StockOrder{ID: id, UserID: user, GuildID: "guild", Side: storage.OrderSideAsk, Price: price, Quantity: quantity, Remaining: quantity}
+}
+
+// fill は、約定のうちテストで確かめる項目です。
+type fill struct {
+	Buy, Sell       int64 // 買い注文と売り注文のID
+	Price, Quantity int64
+}
+
+// resting は、板に残っている注文のIDと残数です。
+type resting struct {
+	ID, Remaining int64
+}
+
+func restingOrders(orders []*storage.StockOrder) []resting {
+	result := []resting{}
+	for _, o := range orders {
+		result = append(result, resting{o.ID, o.Remaining})
+	}
+	return result
+}
+
+func TestOrderBookSubmit(t *testing.T) {
+	tests := []struct {
+		name   string
+		orders []*storage.StockOrder
+		fills  []fill
+		bids   []resting
+		asks   []resting
+	}{
+		{
+			name:   "no cross rests on both sides",
+			orders: []*storage.StockOrder{bidOrder(1, "a", 99, 10), askOrder(2, "b", 101, 10)},
+			bids:   []resting{{1, 10}},
+			asks:   []resting{{2, 10}},
+		},
+		{
+			name:   "better price matches first",
+			orders: []*storage.StockOrder{askOrder(1, "a", 102, 5), askOrder(2, "b", 100, 5), bidOrder(3, "c", 105, 5)},
+			fills:  []fill{{Buy: 3, Sell: 2, Price: 100, Quantity: 5}},
+			bids:   []resting{},
+			asks:   []resting{{1, 5}},
+		},
+		{
+			name:   "same price matches older order first",
+			orders: []*storage.StockOrder{bidOrder(1, "a", 100, 5), bidOrder(2, "b", 100, 5), askOrder(3, "c", 100, 5)},
+			fills:  []fill{{Buy: 1, Sell: 3, Price: 100, Quantity: 5}},
+			bids:   []resting{{2, 5}},
+			asks:   []resting{},
+		},
+		{
+			name:   "bids are ordered by price then time",
+			orders: []*storage.StockOrder{bidOrder(1, "a", 100, 1), bidOrder(2, "b", 101, 1), bidOrder(3, "c", 100, 1), bidOrder(4, "d", 99, 1)},
+			bids:   []resting{{2, 1}, {1, 1}, {3, 1}, {4, 1}},
+			asks:   []resting{},
+		},
+		{
+			name:   "partial fill leaves maker remainder on the book",
+			orders: []*storage.StockOrder{askOrder(1, "a", 100, 10), bidOrder(2, "b", 100, 4)},
+			fills:  []fill{{Buy: 2, Sell: 1, Price: 100, Quantity: 4}},
+			bids:   []resting{},
+			asks:   []resting{{1, 6}},
+		},
+		{
+			name:   "partial fill leaves taker remainder on the book",
+			orders: []*storage.StockOrder{askOrder(1, "a", 100, 3), bidOrder(2, "b", 101, 10)},
+			fills:  []fill{{Buy: 2, Sell: 1, Price: 100, Quantity: 3}},
+			bids:   []resting{{2, 7}},
+			asks:   []resting{},
+		},
+		{
+			name:   "taker sweeps several levels at each maker's price",
+			orders: []*storage.StockOrder{askOrder(1, "a", 100, 2), askOrder(2, "b", 101, 2), askOrder(3, "c", 103, 2), bidOrder(4, "d", 102, 5)},
+			fills: []fill{
+				{Buy: 4, Sell: 1, Price: 100, Quantity: 2},
+				{Buy: 4, Sell: 2, Price: 101, Quantity: 2},
+			},
+			bids: []resting{{4, 1}},
+			asks: []resting{{3, 2}},
+		},
+		{
+			name:   "incoming ask fills at the resting bid's price",
+			orders: []*storage.StockOrder{bidOrder(1, "a", 110, 5), askOrder(2, "b", 90, 5)},
+			fills:  []fill{{Buy: 1, Sell: 2, Price: 110, Quantity: 5}},
+			bids:   []resting{},
+			asks:   []resting{},
+		},
+		{
+			name:   "own orders are skipped and stay on the book",
+			orders: []*storage.StockOrder{askOrder(1, "a", 100, 5), askOrder(2, "b", 101, 5), bidOrder(3, "a", 101, 5)},
+			fills:  []fill{{Buy: 3, Sell: 2, Price: 101, Quantity: 5}},
+			bids:   []resting{},
+			asks:   []resting{{1, 5}},
+		},
+		{
+			name:   "only own orders cross so the order rests",
+			orders: []*storage.StockOrder{bidOrder(1, "a", 100, 5), askOrder(2, "a", 100, 5)},
+			bids:   []resting{{1, 5}},
+			asks:   []resting{{2, 5}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := NewOrderBook("LUNA")
+			var fills []fill
+			for _, o := range tt.orders {
+				for _, trade := range book.Submit(o) {
+					if trade.CompanyCode != "LUNA" {
+						t.Errorf("trade company = %q, want LUNA", trade.CompanyCode)
+					}
+					fills = append(fills, fill{trade.BuyOrderID, trade.SellOrderID, trade.Price, trade.Quantity})
+				}
+			}
+			if !reflect.DeepEqual(fills, tt.fills) {
+				t.Errorf("fills = %+v, want %+v", fills, tt.fills)
+			}
+			if got := restingOrders(book.Bids); !reflect.DeepEqual(got, tt.bids) {
+				t.Errorf("bids = %+v, want %+v", got, tt.bids)
+			}
+			if got := restingOrders(book.Asks); !reflect.DeepEqual(got, tt.asks) {
+				t.Errorf("asks = %+v, want %+v", got, tt.asks)
+			}
+		})
+	}
+}
+
+func TestOrderBookTradeParties(t *testing.T) {
+	book := NewOrderBook("LUNA")
+	book.Submit(&storage.StockOrder{ID: 1, UserID: "seller", GuildID: "g1", Side: storage.OrderSideAsk, Price: 100, Quantity: 1, Remaining: 1})
+	trades := book.Submit(&storage.StockOrder{ID: 2, UserID: "buyer", GuildID: "g2", Side: storage.OrderSideBid, Price: 120, Quantity: 1, Remaining: 1})
+	want := []storage.StockTrade{{
+		CompanyCode:   "LUNA",
+		BuyOrderID:    2,
+		SellOrderID:   1,
+		BuyerID:       "buyer",
+		BuyerGuildID:  "g2",
+		SellerID:      "seller",
+		SellerGuildID: "g1",
+		BidPrice:      120, // 差額を返すため、買い手の指値も残す
+		Price:         100,
+		Quantity:      1,
+	}}
+	if !reflect.DeepEqual(trades, want) {
+		t.Errorf("trades = %+v, want %+v", trades, want)
+	}
+}
+
+func TestOrderBookCancel(t *testing.T) {
+	book := NewOrderBook("LUNA")
+	for _, o := range []*storage.StockOrder{bidOrder(1, "a", 100, 1), bidOrder(2, "b", 99, 1), askOrder(3, "c", 101, 1), askOrder(4, "d", 102, 1)} {
+		book.Submit(o)
+	}
+
+	tests := []struct {
+		id   int64
+		want bool
+		bids []resting
+		asks []resting
+	}{
+		{id: 1, want: true, bids: []resting{{2, 1}}, asks: []resting{{3, 1}, {4, 1}}},
+		{id: 4, want: true, bids: []resting{{2, 1}}, asks: []resting{{3, 1}}},
+		{id: 1, want: false, bids: []resting{{2, 1}}, asks: []resting{{3, 1}}},
+		{id: 99, want: false, bids: []resting{{2, 1}}, asks: []resting{{3, 1}}},
+	}
+	for _, tt := range tests {
+		if got := book.Cancel(tt.id); got != tt.want {
+			t.Errorf("Cancel(%d) = %v, want %v", tt.id, got, tt.want)
+		}
+		if got := restingOrders(book.Bids); !reflect.DeepEqual(got, tt.bids) {
+			t.Errorf("after Cancel(%d) bids = %+v, want %+v", tt.id, got, tt.bids)
+		}
+		if got := restingOrders(book.Asks); !reflect.DeepEqual(got, tt.asks) {
+			t.Errorf("after Cancel(%d) asks = %+v, want %+v", tt.id, got, tt.asks)
+		}
+	}
+
+	// 取り消した注文とは約定しない
+	if trades := book.Submit(bidOrder(5, "e", 200, 5)); len(trades) != 1 || trades[0].SellOrderID != 3 {
+		t.Errorf("trades after cancel = %+v, want a single fill against order 3", trades)
+	}
+}
+
+func TestOrderBookDepth(t *testing.T) {
+	book := NewOrderBook("LUNA")
+	for _, o := range []*storage.StockOrder{
+		bidOrder(1, "a", 100, 3), bidOrder(2, "b", 100, 2), bidOrder(3, "c", 98, 1), bidOrder(4, "d", 97, 4),
+		askOrder(5, "e", 101, 5), askOrder(6, "f", 103, 1), askOrder(7, "g", 103, 2), askOrder(8, "h", 101, 1),
+	} {
+		book.Submit(o)
+	}
+	// 一部約定した注文は残数で数える
+	book.Submit(askOrder(9, "i", 100, 1))
+
+	tests := []struct {
+		levels     int
+		bids, asks []BookLevel
+	}{
+		{
+			levels: 2,
+			bids:   []BookLevel{{Price: 100, Quantity: 4, Orders: 2}, {Price: 98, Quantity: 1, Orders: 1}},
+			asks:   []BookLevel{{Price: 101, Quantity: 6, Orders: 2}, {Price: 103, Quantity: 3, Orders: 2}},
+		},
+		{
+			levels: 5,
+			bids:   []BookLevel{{Price: 100, Quantity: 4, Orders: 2}, {Price: 98, Quantity: 1, Orders: 1}, {Price: 97, Quantity: 4, Orders: 1}},
+			asks:   []BookLevel{{Price: 101, Quantity: 6, Orders: 2}, {Price: 103, Quantity: 3, Orders: 2}},
+		},
+		{levels: 0},
+	}
+	for _, tt := range tests {
+		bids, asks := book.Depth(tt.levels)
+		if !reflect.DeepEqual(bids, tt.bids) {
+			t.Errorf("Depth(%d) bids = %+v, want %+v", tt.levels, bids, tt.bids)
+		}
+		if !reflect.DeepEqual(asks, tt.asks) {
+			t.Errorf("Depth(%d) asks = %+v, want %+v", tt.levels, asks, tt.asks)
+		}
+	}
+}
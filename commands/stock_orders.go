@@ -0,0 +1,252 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"luna/storage"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	tradePriceWeight = 0.3 // 約定価格を表示株価に反映する割合
+	bookDepthLevels  = 5   // /stock book で表示する板の段数
+)
+
+// handlePlaceOrder は、/stock bid と /stock ask を処理します。
+// 注文時に代金または株を預かり、板で約定した分はその場で受け渡しを行います。
+func (c *StockCommand) handlePlaceOrder(s *discordgo.Session, i *discordgo.InteractionCreate, side storage.OrderSide) {
+	options := i.ApplicationCommandData().Options[0].Options
+	code := strings.ToUpper(options[0].StringValue())
+	price := options[1].IntValue()
+	amount := options[2].IntValue()
+
+	company, exists := c.findCompanyByCode(code)
+	if !exists {
+		sendErrorResponse(s, i, "指定された銘柄コードの企業は存在しません。")
+		return
+	}
+
+	order := &storage.StockOrder{
+		CompanyCode: code,
+		UserID:      i.Member.User.ID,
+		GuildID:     i.GuildID,
+		Side:        side,
+		Price:       price,
+		Quantity:    amount,
+	}
+
+	c.bookMu.Lock()
+	defer c.bookMu.Unlock()
+
+	if err := c.Store.CreateStockOrder(order); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrInsufficientFunds):
+			sendErrorResponse(s, i, fmt.Sprintf("PepeCoinが足りません！\n注文に必要なPPC: `%d`", price*amount))
+		case errors.Is(err, storage.ErrInsufficientShares):
+			sendErrorResponse(s, i, fmt.Sprintf("保有株数が足りません。\n銘柄: %s\n注文数: %d", code, amount))
+		case errors.Is(err, storage.ErrAccountFrozen):
+			sendErrorResponse(s, i, "あなたの口座は管理者によって凍結されています。")
+		case errors.Is(err, storage.ErrOrderTooLarge):
+			sendErrorResponse(s, i, "注文の金額が大きすぎます。指値か株数を減らしてください。")
+		default:
+			c.Log.Error("Failed to create stock order", "error", err)
+			sendErrorResponse(s, i, "注文の登録中にエラーが発生しました。")
+		}
+		return
+	}
+
+	trades := c.getBook(code).Submit(order)
+	if err := c.Store.SettleStockTrades(trades); err != nil {
+		c.Log.Error("Failed to settle stock trades", "error", err, "order_id", order.ID)
+		// 板とDBの内容がずれないよう、DBから板を作り直す
+		c.restoreBooksLocked()
+		sendErrorResponse(s, i, "約定処理中にエラーが発生しました。注文は板に残っています。")
+		return
+	}
+	if len(trades) > 0 {
+		c.applyTradePrice(code, trades[len(trades)-1].Price)
+	}
+
+	sideLabel := "買い"
+	if side == storage.OrderSideAsk {
+		sideLabel = "売り"
+	}
+
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("**%s (%s)** に **%d** PPC × **%d** 株の%s注文 (#%d) を出しました。\n", company.Name, code, price, amount, sideLabel, order.ID))
+	if len(trades) > 0 {
+		var filled int64
+		for _, t := range trades {
+			filled += t.Quantity
+			counterparty := t.SellerID
+			if side == storage.OrderSideAsk {
+				counterparty = t.BuyerID
+			}
+			msg.WriteString(fmt.Sprintf("🤝 <@%s> と **%d** 株を **%d** PPC で約定\n", counterparty, t.Quantity, t.Price))
+		}
+		msg.WriteString(fmt.Sprintf("約定: **%d** 株", filled))
+	}
+	if order.Remaining > 0 {
+		msg.WriteString(fmt.Sprintf("\n残り **%d** 株は板に並んでいます。", order.Remaining))
+	}
+	sendSuccessResponse(s, i, msg.String())
+}
+
+func (c *StockCommand) handleCancelOrder(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	orderID := i.ApplicationCommandData().Options[0].Options[0].IntValue()
+
+	c.bookMu.Lock()
+	defer c.bookMu.Unlock()
+
+	order, err := c.Store.CancelStockOrder(orderID, i.Member.User.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrOrderNotFound) {
+			sendErrorResponse(s, i, "取り消せる注文が見つかりません。")
+			return
+		}
+		c.Log.Error("Failed to cancel stock order", "error", err)
+		sendErrorResponse(s, i, "注文の取り消し中にエラーが発生しました。")
+		return
+	}
+	c.getBook(order.CompanyCode).Cancel(order.ID)
+
+	refund := fmt.Sprintf("`%d` 株", order.Remaining)
+	if order.Side == storage.OrderSideBid {
+		refund = fmt.Sprintf("`%d` PPC", order.Price*order.Remaining)
+	}
+	sendSuccessResponse(s, i, fmt.Sprintf("注文 #%d を取り消しました。%s を返却しました。", order.ID, refund))
+}
+
+func (c *StockCommand) handleOrders(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	orders, err := c.Store.GetUserOpenStockOrders(i.Member.User.ID)
+	if err != nil {
+		c.Log.Error("Failed to get user stock orders", "error", err)
+		sendErrorResponse(s, i, "注文一覧の取得に失敗しました。")
+		return
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title: "📋 未約定の注文",
+		Color: 0x3498db, // Blue
+	}
+	if len(orders) == 0 {
+		embed.Description = "未約定の注文はありません。"
+	} else {
+		var desc strings.Builder
+		for _, o := range orders {
+			sideLabel := "🟢 買い"
+			if o.Side == storage.OrderSideAsk {
+				sideLabel = "🔴 売り"
+			}
+			desc.WriteString(fmt.Sprintf("`#%d` %s **%s** `%d` PPC × `%d`/`%d` 株\n", o.ID, sideLabel, o.CompanyCode, o.Price, o.Remaining, o.Quantity))
+		}
+		embed.Description = desc.String()
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+func (c *StockCommand) handleBook(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	code := strings.ToUpper(i.ApplicationCommandData().Options[0].Options[0].StringValue())
+
+	company, exists := c.findCompanyByCode(code)
+	if !exists {
+		sendErrorResponse(s, i, "指定された銘柄コードの企業は存在しません。")
+		return
+	}
+
+	c.bookMu.Lock()
+	bids, asks := c.getBook(code).Depth(bookDepthLevels)
+	c.bookMu.Unlock()
+
+	var book strings.Builder
+	book.WriteString("```\n   売り数量 |   価格 | 買い数量\n")
+	for idx := len(asks) - 1; idx >= 0; idx-- {
+		book.WriteString(fmt.Sprintf("%11d | %6d |\n", asks[idx].Quantity, asks[idx].Price))
+	}
+	book.WriteString("------------+--------+---------\n")
+	for _, level := range bids {
+		book.WriteString(fmt.Sprintf("%11s | %6d | %d\n", "", level.Price, level.Quantity))
+	}
+	book.WriteString("```")
+	if len(bids) == 0 && len(asks) == 0 {
+		book.Reset()
+		book.WriteString("現在、注文はありません。")
+	}
+
+	var tradeList strings.Builder
+	trades, err := c.Store.GetRecentStockTrades(code, 5)
+	if err != nil {
+		c.Log.Error("Failed to get recent stock trades", "error", err)
+	}
+	for _, t := range trades {
+		tradeList.WriteString(fmt.Sprintf("<t:%d:R> `%d` PPC × `%d` 株\n", t.ExecutedAt.Unix(), t.Price, t.Quantity))
+	}
+	if tradeList.Len() == 0 {
+		tradeList.WriteString("まだ約定はありません。")
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("📊 %s (%s) の板", company.Name, company.Code),
+		Description: book.String(),
+		Color:       0x1abc9c, // Turquoise
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "現在価格", Value: fmt.Sprintf("`%.2f` PPC", company.Price), Inline: true},
+			{Name: "最終約定", Value: formatLastTrade(*company), Inline: true},
+			{Name: "直近の約定", Value: tradeList.String()},
+		},
+	}
+	sendEmbedResponse(s, i, embed)
+}
+
+// applyTradePrice は、プレイヤー間の約定価格を表示株価に反映します。
+// シミュレーションによる株価と約定価格を加重平均し、以後の価格更新はこの値から続きます。
+func (c *StockCommand) applyTradePrice(code string, tradePrice int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for idx := range c.Companies {
+		company := &c.Companies[idx]
+		if company.Code != code {
+			continue
+		}
+		company.LastTradePrice = float64(tradePrice)
+		company.Price = company.Price*(1-tradePriceWeight) + float64(tradePrice)*tradePriceWeight
+		if err := c.Store.UpdateCompanyPrices(map[string]float64{code: company.Price}); err != nil {
+			c.Log.Error("Failed to update price after trade", "error", err, "company", code)
+		}
+		return
+	}
+}
+
+func formatLastTrade(company storage.Company) string {
+	if company.LastTradePrice <= 0 {
+		return "なし"
+	}
+	return fmt.Sprintf("%.0f PPC", company.LastTradePrice)
+}
+
+// openOrderEscrow は、未約定の注文が預かっている株とPPCを返します。
+// 保有株はサーバーをまたいで共通のため売り注文はすべて数え、PPCは guildID のサーバーで出した買い注文の分だけを数えます。
+func openOrderEscrow(orders []storage.StockOrder, guildID string) (lockedShares map[string]int64, lockedPPC int64) {
+	lockedShares = make(map[string]int64)
+	for _, o := range orders {
+		switch o.Side {
+		case storage.OrderSideAsk:
+			lockedShares[o.CompanyCode] += o.Remaining
+		case storage.OrderSideBid:
+			if o.GuildID == guildID {
+				lockedPPC += o.Price * o.Remaining
+			}
+		}
+	}
+	return lockedShares, lockedPPC
+}
@@ -0,0 +1,215 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"luna/storage"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 空売り・信用取引のパラメータ
+const (
+	shortInitialMargin     = 0.5    // 新規空売り時に売却代金に加えて預ける証拠金の割合
+	shortMarginCallLevel   = 0.35   // 証拠金維持率がこれを下回ると追証を通知する
+	shortMaintenanceMargin = 0.25   // 証拠金維持率がこれを下回ると強制決済する
+	shortBorrowFeeRate     = 0.0002 // 1時間あたりの貸株料 (時価に対する割合)
+)
+
+// shortMarginRatio は、空売りポジションの証拠金維持率（純資産 ÷ 買戻し必要額）を返します。
+func shortMarginRatio(position storage.ShortPosition, price float64) float64 {
+	liability := price * float64(position.Shares)
+	if liability <= 0 {
+		return 0
+	}
+	return (float64(position.Collateral) - liability) / liability
+}
+
+// shortEquity は、空売りポジションを今決済した場合に手元に残るPPCです。
+func shortEquity(position storage.ShortPosition, price float64) float64 {
+	return float64(position.Collateral) - price*float64(position.Shares)
+}
+
+func (c *StockCommand) handleShort(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	options := i.ApplicationCommandData().Options[0].Options
+	code := strings.ToUpper(options[0].StringValue())
+	amount := options[1].IntValue()
+
+	company, exists := c.findCompanyByCode(code)
+	if !exists {
+		sendErrorResponse(s, i, "指定された銘柄コードの企業は存在しません。")
+		return
+	}
+
+	position, err := c.Store.OpenShortPosition(i.GuildID, i.Member.User.ID, code, amount, company.Price, shortInitialMargin)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrInsufficientFunds):
+			required := company.Price * float64(amount) * shortInitialMargin
+			sendErrorResponse(s, i, fmt.Sprintf("証拠金が足りません！\n必要な証拠金: `%.0f` PPC", required))
+		case errors.Is(err, storage.ErrAccountFrozen):
+			sendErrorResponse(s, i, "あなたの口座は管理者によって凍結されています。")
+		case errors.Is(err, storage.ErrShortInOtherGuild):
+			sendErrorResponse(s, i, "この銘柄は別のサーバーで空売りしています。追加の空売りはそのサーバーで行ってください。")
+		default:
+			c.Log.Error("Failed to open short position", "error", err)
+			sendErrorResponse(s, i, "空売り処理中にエラーが発生しました。")
+		}
+		return
+	}
+
+	sendSuccessResponse(s, i, fmt.Sprintf("**%s (%s)** を **%d** 株、**%.2f** PPC で空売りしました。\n建玉: `%d` 株 (平均 `%.2f` PPC) | 証拠金: `%d` PPC | 維持率: `%.0f%%`",
+		company.Name, company.Code, amount, company.Price, position.Shares, position.EntryPrice, position.Collateral, shortMarginRatio(*position, company.Price)*100))
+}
+
+func (c *StockCommand) handleCover(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	options := i.ApplicationCommandData().Options[0].Options
+	code := strings.ToUpper(options[0].StringValue())
+	amount := options[1].IntValue()
+
+	company, exists := c.findCompanyByCode(code)
+	if !exists {
+		sendErrorResponse(s, i, "指定された銘柄コードの企業は存在しません。")
+		return
+	}
+
+	settlement, err := c.Store.CoverShortPosition(i.Member.User.ID, code, amount, company.Price, false)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrInsufficientShares):
+			sendErrorResponse(s, i, fmt.Sprintf("空売りしている株数が足りません。\n銘柄: %s", code))
+		case errors.Is(err, storage.ErrInsufficientFunds):
+			sendErrorResponse(s, i, "買い戻しに必要なPepeCoinが足りません。")
+		case errors.Is(err, storage.ErrAccountFrozen):
+			sendErrorResponse(s, i, "あなたの口座は管理者によって凍結されています。")
+		default:
+			c.Log.Error("Failed to cover short position", "error", err)
+			sendErrorResponse(s, i, "買い戻し処理中にエラーが発生しました。")
+		}
+		return
+	}
+
+	sendSuccessResponse(s, i, fmt.Sprintf("**%s (%s)** を **%d** 株、**%.2f** PPC で買い戻しました。\n証拠金の精算: **`%+d`** PPC",
+		company.Name, company.Code, amount, company.Price, settlement))
+}
+
+func (c *StockCommand) handleCollateral(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	options := i.ApplicationCommandData().Options[0].Options
+	code := strings.ToUpper(options[0].StringValue())
+	amount := options[1].IntValue()
+
+	company, exists := c.findCompanyByCode(code)
+	if !exists {
+		sendErrorResponse(s, i, "指定された銘柄コードの企業は存在しません。")
+		return
+	}
+
+	position, err := c.Store.AddShortCollateral(i.Member.User.ID, code, amount)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrInsufficientShares):
+			sendErrorResponse(s, i, "この銘柄の空売りポジションがありません。")
+		case errors.Is(err, storage.ErrInsufficientFunds):
+			sendErrorResponse(s, i, "PepeCoinが足りません！")
+		case errors.Is(err, storage.ErrAccountFrozen):
+			sendErrorResponse(s, i, "あなたの口座は管理者によって凍結されています。")
+		default:
+			c.Log.Error("Failed to add short collateral", "error", err)
+			sendErrorResponse(s, i, "証拠金の追加中にエラーが発生しました。")
+		}
+		return
+	}
+
+	ratio := shortMarginRatio(*position, company.Price)
+	if position.MarginCall && ratio >= shortMarginCallLevel {
+		if err := c.Store.SetShortMarginCall(position.UserID, code, false); err != nil {
+			c.Log.Error("Failed to clear margin call", "error", err)
+		}
+	}
+	sendSuccessResponse(s, i, fmt.Sprintf("**%s** の空売りポジションに **%d** PPC の証拠金を追加しました。\n証拠金: `%d` PPC | 維持率: `%.0f%%`",
+		code, amount, position.Collateral, ratio*100))
+}
+
+// ChargeBorrowFees は、空売りポジションから貸株料を徴収し、その後に証拠金維持率を確認します。
+func (c *StockCommand) ChargeBorrowFees() {
+	if err := c.Store.ChargeShortBorrowFees(c.currentPrices(), shortBorrowFeeRate); err != nil {
+		c.Log.Error("Failed to charge short borrow fees", "error", err)
+		return
+	}
+	c.checkMargins()
+}
+
+// currentPrices は、全銘柄の現在価格のスナップショットを返します。
+func (c *StockCommand) currentPrices() map[string]float64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	prices := make(map[string]float64, len(c.Companies))
+	for _, company := range c.Companies {
+		prices[company.Code] = company.Price
+	}
+	return prices
+}
+
+// checkMargins は、全空売りポジションの証拠金維持率を確認し、
+// 追証ラインを割ったポジションには通知を、維持率を割ったポジションは強制決済します。
+func (c *StockCommand) checkMargins() {
+	positions, err := c.Store.GetAllShortPositions()
+	if err != nil {
+		c.Log.Error("Failed to get short positions for margin check", "error", err)
+		return
+	}
+	prices := c.currentPrices()
+
+	for _, position := range positions {
+		price, ok := prices[position.CompanyCode]
+		if !ok {
+			continue
+		}
+		ratio := shortMarginRatio(position, price)
+
+		switch {
+		case ratio < shortMaintenanceMargin:
+			settlement, err := c.Store.CoverShortPosition(position.UserID, position.CompanyCode, position.Shares, price, true)
+			if err != nil {
+				c.Log.Error("Failed to liquidate short position", "error", err, "userID", position.UserID, "company", position.CompanyCode)
+				continue
+			}
+			c.Log.Info("Short position liquidated", "userID", position.UserID, "company", position.CompanyCode, "settlement", settlement)
+			c.notifyUser(position.UserID, &discordgo.MessageEmbed{
+				Title:       "⚠️ 強制決済のお知らせ",
+				Description: fmt.Sprintf("**%s** の空売りポジション (`%d` 株) は証拠金維持率が `%.0f%%` を下回ったため、`%.2f` PPC で強制決済されました。\n精算額: **`%+d`** PPC", position.CompanyCode, position.Shares, shortMaintenanceMargin*100, price, settlement),
+				Color:       0xe74c3c, // Red
+			})
+		case ratio < shortMarginCallLevel && !position.MarginCall:
+			if err := c.Store.SetShortMarginCall(position.UserID, position.CompanyCode, true); err != nil {
+				c.Log.Error("Failed to record margin call", "error", err)
+				continue
+			}
+			c.notifyUser(position.UserID, &discordgo.MessageEmbed{
+				Title:       "📣 追証のお知らせ",
+				Description: fmt.Sprintf("**%s** の空売りポジションの証拠金維持率が `%.0f%%` に低下しています。\n`%.0f%%` を下回ると強制決済されます。`/stock collateral` で証拠金を追加するか、`/stock cover` で買い戻してください。", position.CompanyCode, ratio*100, shortMaintenanceMargin*100),
+				Color:       0xf1c40f, // Yellow
+			})
+		case ratio >= shortMarginCallLevel && position.MarginCall:
+			if err := c.Store.SetShortMarginCall(position.UserID, position.CompanyCode, false); err != nil {
+				c.Log.Error("Failed to clear margin call", "error", err)
+			}
+		}
+	}
+}
+
+// notifyUser sends a direct message to a user. Errors are logged and otherwise ignored.
+func (c *StockCommand) notifyUser(userID string, embed *discordgo.MessageEmbed) {
+	if c.Session == nil {
+		return
+	}
+	channel, err := c.Session.UserChannelCreate(userID)
+	if err != nil {
+		c.Log.Warn("Failed to open DM channel", "error", err, "userID", userID)
+		return
+	}
+	if _, err := c.Session.ChannelMessageSendEmbed(channel.ID, embed); err != nil {
+		c.Log.Warn("Failed to send DM", "error", err, "userID", userID)
+	}
+}
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"luna/storage"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// testLogger は、ログをテストの出力に流す interfaces.Logger の実装です。
+type testLogger struct{ t *testing.T }
+
+func (l testLogger) Info(msg string, args ...any)  { l.t.Log(append([]any{"INFO", msg}, args...)...) }
+func (l testLogger) Warn(msg string, args ...any)  { l.t.Log(append([]any{"WARN", msg}, args...)...) }
+func (l testLogger) Error(msg string, args ...any) { l.t.Log(append([]any{"ERROR", msg}, args...)...) }
+func (l testLogger) Fatal(msg string, args ...any) {
+	l.t.Fatal(append([]any{"FATAL", msg}, args...)...)
+}
+
+func newTestStore(t *testing.T) *storage.DBStore {
+	t.Helper()
+	store, err := storage.NewDBStore(filepath.Join(t.TempDir(), "luna.db"))
+	if err != nil {
+		t.Fatalf("NewDBStore: %v", err)
+	}
+	t.Cleanup(store.Close)
+	return store
+}
+
+func TestShortMarginRatio(t *testing.T) {
+	tests := []struct {
+		name       string
+		collateral int64
+		shares     int64
+		price      float64
+		want       float64
+	}{
+		{"at entry with 50% initial margin", 1500, 10, 100, 0.5},
+		{"price rises into margin call", 1500, 10, 112, 380.0 / 1120},
+		{"price rises below maintenance", 1500, 10, 125, 0.2},
+		{"collateral wiped out", 1500, 10, 200, -0.25},
+		{"price falls", 1500, 10, 50, 2},
+		{"no liability", 1500, 10, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			position := storage.ShortPosition{Shares: tt.shares, Collateral: tt.collateral}
+			if got := shortMarginRatio(position, tt.price); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("shortMarginRatio() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckMargins(t *testing.T) {
+	// 各ポジションは 100 PPC で10株を売り建て、証拠金は 1500 PPC
+	tests := []struct {
+		name          string
+		price         float64
+		marginCalled  bool
+		wantOpen      bool
+		wantCall      bool
+		wantPPCChange int64
+	}{
+		{"healthy position is left alone", 100, false, true, false, 0},
+		{"margin call is recorded once", 112, false, true, true, 0},
+		{"margin call stays while still low", 112, true, true, true, 0},
+		{"recovered position clears the margin call", 100, true, true, false, 0},
+		{"below maintenance is liquidated", 125, false, false, false, 1500 - 1250},
+		{"liquidation collects the shortfall from the balance", 200, true, false, false, 1500 - 2000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			if err := store.AdjustEconomy(&storage.EconomyAuditEntry{GuildID: "guild", Action: storage.EcoActionSet, Currency: storage.CurrencyPPC, Amount: 10000}, []string{"alice"}); err != nil {
+				t.Fatalf("AdjustEconomy: %v", err)
+			}
+			if _, err := store.OpenShortPosition("guild", "alice", "LUNA", 10, 100, shortInitialMargin); err != nil {
+				t.Fatalf("OpenShortPosition: %v", err)
+			}
+			if err := store.SetShortMarginCall("alice", "LUNA", tt.marginCalled); err != nil {
+				t.Fatalf("SetShortMarginCall: %v", err)
+			}
+			before, _ := store.GetCasinoData("guild", "alice")
+
+			c := &StockCommand{Store: store, Log: testLogger{t}, Companies: []storage.Company{{Code: "LUNA", Price: tt.price}}}
+			c.checkMargins()
+
+			positions, err := store.GetUserShortPositions("alice")
+			if err != nil {
+				t.Fatalf("GetUserShortPositions: %v", err)
+			}
+			if open := len(positions) == 1; open != tt.wantOpen {
+				t.Fatalf("position open = %v, want %v", open, tt.wantOpen)
+			}
+			if tt.wantOpen && positions[0].MarginCall != tt.wantCall {
+				t.Errorf("margin call = %v, want %v", positions[0].MarginCall, tt.wantCall)
+			}
+			after, _ := store.GetCasinoData("guild", "alice")
+			if got := after.PepeCoinBalance - before.PepeCoinBalance; got != tt.wantPPCChange {
+				t.Errorf("PPC change = %d, want %d", got, tt.wantPPCChange)
+			}
+		})
+	}
+}
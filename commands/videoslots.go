@@ -0,0 +1,264 @@
+package commands
+
+import (
+	"fmt"
+	"luna/fair"
+	"luna/interfaces"
+	"luna/slots"
+	"luna/storage"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// VideoSlotsCommand handles the /videoslots command.
+type VideoSlotsCommand struct {
+	Store interfaces.DataStore
+	Log   interfaces.Logger
+	Fair  *fair.Service
+}
+
+func (c *VideoSlotsCommand) GetCommandDef() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "videoslots",
+		Description: "3x5のビデオスロットを回します。ワイルドとスキャッター、フリースピンもあります！",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "bet",
+				Description: "1ラインあたりのベット額",
+				Required:    true,
+				MinValue:    &[]float64{1}[0],
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "lines",
+				Description: fmt.Sprintf("有効にする配当ラインの数 (デフォルト: %d)", len(slots.Paylines)),
+				MinValue:    &[]float64{1}[0],
+				MaxValue:    float64(len(slots.Paylines)),
+			},
+		},
+	}
+}
+
+// loadVideoTheme は、サーバーで選ばれているビデオスロットのテーマを返します。
+func loadVideoTheme(store interfaces.DataStore, guildID string) (*slots.Theme, error) {
+	var config storage.VideoSlotsConfig
+	if err := store.GetConfig(guildID, "video_slots_config", &config); err != nil {
+		return nil, err
+	}
+	if theme := slots.FindTheme(config.Theme); theme != nil {
+		return theme, nil
+	}
+	return slots.FindTheme(slots.DefaultTheme), nil
+}
+
+func (c *VideoSlotsCommand) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var lineBet int64
+	lines := len(slots.Paylines)
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "bet":
+			lineBet = opt.IntValue()
+		case "lines":
+			lines = int(opt.IntValue())
+		}
+	}
+	totalBet := lineBet * int64(lines)
+	userID := i.Member.User.ID
+	guildID := i.GuildID
+	if rejectGamblingBet(s, i, c.Store, c.Log, totalBet) {
+		return
+	}
+
+	casinoData, err := c.Store.GetCasinoData(guildID, userID)
+	if err != nil {
+		c.Log.Error("Failed to get casino data for video slots", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+	if casinoData.Chips < totalBet {
+		sendErrorResponse(s, i, fmt.Sprintf("チップが足りません！ %d ライン x %d チップ = %d チップが必要です (所持チップ: %d)", lines, lineBet, totalBet, casinoData.Chips))
+		return
+	}
+
+	economy, err := c.Store.GetEconomyConfig(guildID)
+	if err != nil {
+		c.Log.Error("Failed to get economy config for video slots", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+	theme, err := loadVideoTheme(c.Store, guildID)
+	if err != nil {
+		c.Log.Error("Failed to get video slots theme", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+	// 上限が後から下げられた場合に備え、遊ぶたびに還元率を確認する
+	if theme.BaseRTP()+economy.JackpotContribution > economy.SlotsRTPCeiling {
+		sendErrorResponse(s, i, "このテーマは還元率の上限を超えているため遊べません。管理者に連絡してください。")
+		return
+	}
+
+	round, err := c.Fair.NewRound(guildID, userID)
+	if err != nil {
+		c.Log.Error("Failed to start fair round for video slots", "error", err)
+		sendErrorResponse(s, i, "エラーが発生しました。")
+		return
+	}
+	defer round.Close()
+
+	casinoData.Chips -= totalBet
+	if err := c.Store.UpdateCasinoData(casinoData); err != nil {
+		c.Log.Error("Failed to update casino data on video slots bet", "error", err)
+		sendErrorResponse(s, i, "ベット処理中にエラーが発生しました。")
+		return
+	}
+	// 端数は切り捨てる。少額のベットでも1チップ積み立てると、還元率が検証した上限を超えてしまう
+	jackpotContribution := int64(float64(totalBet) * economy.JackpotContribution)
+	currentJackpot, err := c.Store.AddToJackpot(guildID, jackpotContribution)
+	if err != nil {
+		c.Log.Error("Failed to add to jackpot", "error", err)
+		currentJackpot, _ = c.Store.GetJackpot(guildID)
+	}
+
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	}); err != nil {
+		c.Log.Error("Failed to send initial video slots response", "error", err)
+		casinoData.Chips += totalBet
+		c.Store.UpdateCasinoData(casinoData)
+		return
+	}
+
+	result := theme.Play(round.Rand, lines)
+
+	// 回転中の表示は結果に関係しないため、公平性のための乱数とは別の乱数を使う
+	animationRand := rand.New(rand.NewSource(time.Now().UnixNano()))
+	title := fmt.Sprintf("%s %s", theme.Emoji, theme.Name)
+	animationEmbed := &discordgo.MessageEmbed{Title: title + " 回転中...", Color: 0x3498db} // Blue
+	for j := 0; j < 3; j++ {
+		animationEmbed.Description = renderVideoGrid(theme.SpinGrid(animationRand))
+		if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Embeds: &[]*discordgo.MessageEmbed{animationEmbed}}); err != nil {
+			c.Log.Error("Failed to edit video slots animation", "error", err)
+		}
+		time.Sleep(300 * time.Millisecond)
+	}
+
+	winnings := int64(float64(lineBet) * result.Multiplier)
+	if result.Base.Jackpot {
+		winnings += currentJackpot
+		if err := c.Store.UpdateJackpot(guildID, 0); err != nil {
+			c.Log.Error("Failed to reset jackpot", "error", err)
+		}
+		currentJackpot = 0
+	}
+	if winnings > 0 {
+		casinoData.Chips += winnings
+		if err := c.Store.UpdateCasinoData(casinoData); err != nil {
+			c.Log.Error("Failed to update casino data after video slots win", "error", err)
+		}
+	}
+
+	resultEmbed := &discordgo.MessageEmbed{
+		Title:       title + " 結果！",
+		Description: renderVideoGrid(result.Base.Grid),
+		Color:       0xe74c3c, // Red
+		Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("現在のジャックポット: %d チップ | %s", currentJackpot, round.Footer())},
+	}
+	if wins := describeVideoWins(theme, result.Base, lineBet, lines); wins != "" {
+		resultEmbed.Fields = append(resultEmbed.Fields, &discordgo.MessageEmbedField{Name: "当たり", Value: wins})
+	}
+	if len(result.Free) > 0 {
+		var freeTotal float64
+		best := result.Free[0]
+		for _, spin := range result.Free {
+			freeTotal += spin.Multiplier
+			if spin.Multiplier > best.Multiplier {
+				best = spin
+			}
+		}
+		resultEmbed.Fields = append(resultEmbed.Fields, &discordgo.MessageEmbedField{
+			Name: fmt.Sprintf("🎁 フリースピン %d 回 (配当 x%g)", len(result.Free), theme.FreeSpinMultiplier),
+			Value: fmt.Sprintf("合計 **%d** チップ獲得！\n最高のスピン (%d チップ):\n%s",
+				int64(float64(lineBet)*freeTotal), int64(float64(lineBet)*best.Multiplier), renderVideoGrid(best.Grid)),
+		})
+	}
+
+	switch {
+	case result.Base.Jackpot:
+		resultEmbed.Title = "👑 JACKPOT! 👑"
+		resultEmbed.Color = 0xFFD700 // Gold
+	case winnings > totalBet:
+		resultEmbed.Color = 0x2ecc71 // Green
+	case winnings > 0:
+		resultEmbed.Color = 0xf1c40f // Yellow
+	}
+	resultEmbed.Fields = append(resultEmbed.Fields,
+		&discordgo.MessageEmbedField{Name: "ベット", Value: fmt.Sprintf("`%d` x %d ライン = `%d` チップ", lineBet, lines, totalBet), Inline: true},
+		&discordgo.MessageEmbedField{Name: "配当", Value: fmt.Sprintf("`%d` チップ", winnings), Inline: true},
+		&discordgo.MessageEmbedField{Name: "収支", Value: fmt.Sprintf("**`%+d`** チップ", winnings-totalBet), Inline: true},
+		&discordgo.MessageEmbedField{Name: "💰 所持チップ", Value: fmt.Sprintf("**%d**", casinoData.Chips)},
+	)
+	if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Embeds: &[]*discordgo.MessageEmbed{resultEmbed}}); err != nil {
+		c.Log.Error("Failed to edit final video slots response", "error", err)
+	}
+
+	recordGameRound(c.Store, c.Log, guildID, userID, storage.GameVideoSlot, totalBet, winnings)
+	progress := map[string]int64{counterSlotsSpins: 1, counterChipsWon: winnings}
+	if result.Base.Jackpot {
+		progress[counterSlotsJackpots] = 1
+	}
+	recordAchievements(s, c.Store, c.Log, i.ChannelID, guildID, userID, progress)
+}
+
+// renderVideoGrid は、盤面を3行の絵文字で表します。
+func renderVideoGrid(grid slots.Grid) string {
+	var sb strings.Builder
+	for row := 0; row < slots.GridRows; row++ {
+		symbols := make([]string, slots.GridReels)
+		for reel := 0; reel < slots.GridReels; reel++ {
+			symbols[reel] = grid[reel][row]
+		}
+		sb.WriteString("**[ " + strings.Join(symbols, " | ") + " ]**\n")
+	}
+	return sb.String()
+}
+
+// describeVideoWins は、通常のスピンの当たりを配当の高い順に最大5件まで並べます。
+func describeVideoWins(theme *slots.Theme, spin slots.SpinResult, lineBet int64, lines int) string {
+	var sb strings.Builder
+	for n, win := range spin.SortedLineWins() {
+		if n == 5 {
+			fmt.Fprintf(&sb, "…ほか %d ライン\n", len(spin.LineWins)-n)
+			break
+		}
+		fmt.Fprintf(&sb, "ライン %d: %s x%d → %d チップ", win.Line+1, win.Symbol, win.Count, int64(float64(lineBet)*win.Multiplier))
+		if win.Jackpot {
+			sb.WriteString(" + 👑 ジャックポット")
+		}
+		sb.WriteString("\n")
+	}
+	if spin.Scatters >= 3 {
+		fmt.Fprintf(&sb, "%s x%d (スキャッター) → %d チップ", theme.Scatter.Emoji, spin.Scatters, int64(float64(lineBet)*spin.ScatterPay*float64(lines)))
+		if spin.FreeSpins > 0 {
+			fmt.Fprintf(&sb, " + フリースピン %d 回", spin.FreeSpins)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func (c *VideoSlotsCommand) GetCategory() string {
+	return "カジノ"
+}
+
+func (c *VideoSlotsCommand) GetComponentIDs() []string {
+	return nil
+}
+
+func (c *VideoSlotsCommand) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {}
+
+func (c *VideoSlotsCommand) HandleModal(s *discordgo.Session, i *discordgo.InteractionCreate) {}
@@ -0,0 +1,170 @@
+// Package fair は、検証可能な公平性 (Provably Fair) を持つカジノゲームの乱数を提供します。
+//
+// ユーザーごとに秘密のサーバーシードを用意し、そのSHA-256ハッシュを遊ぶ前に公開します。
+// 各ゲームの乱数は HMAC-SHA256(サーバーシード, "クライアントシード:ナンス") の先頭8バイトを
+// math/rand のシードにして生成するため、サーバーシードが公開された後は誰でも結果を再計算できます。
+package fair
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"luna/interfaces"
+	"luna/storage"
+	mathrand "math/rand"
+	"sync"
+)
+
+// ErrRoundInProgress は、結果が決まっていないラウンドが残っているためにシードを切り替えられないことを表します。
+var ErrRoundInProgress = errors.New("fair: round in progress")
+
+// NewServerSeed は、新しいサーバーシードを生成します。
+func NewServerSeed() string {
+	return randomHex(32)
+}
+
+// NewClientSeed は、ユーザーが指定しなかった場合のクライアントシードを生成します。
+func NewClientSeed() string {
+	return randomHex(8)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// HashSeed は、遊ぶ前に公開するサーバーシードのハッシュを返します。
+func HashSeed(serverSeed string) string {
+	sum := sha256.Sum256([]byte(serverSeed))
+	return hex.EncodeToString(sum[:])
+}
+
+// Seed は、シードの組とナンスから1回のゲームに使う math/rand のシードを計算します。
+func Seed(serverSeed, clientSeed string, nonce int64) int64 {
+	mac := hmac.New(sha256.New, []byte(serverSeed))
+	fmt.Fprintf(mac, "%s:%d", clientSeed, nonce)
+	return int64(binary.BigEndian.Uint64(mac.Sum(nil)[:8]) >> 1)
+}
+
+// NewRand は、シードの組とナンスから決定的な乱数生成器を作ります。
+func NewRand(serverSeed, clientSeed string, nonce int64) *mathrand.Rand {
+	return mathrand.New(mathrand.NewSource(Seed(serverSeed, clientSeed, nonce)))
+}
+
+// Round は、1回のゲームに割り当てられた乱数と、その検証に必要な公開情報です。
+type Round struct {
+	ServerSeedHash string
+	ClientSeed     string
+	Nonce          int64
+	Rand           *mathrand.Rand
+	closeOnce      sync.Once
+	release        func()
+}
+
+// Close は、ラウンドの精算が終わったことを知らせます。開いているラウンドがある間はシードを切り替えられません。
+// 何度呼んでもよく、nil のラウンドでは何もしません。
+func (r *Round) Close() {
+	if r == nil || r.release == nil {
+		return
+	}
+	r.closeOnce.Do(r.release)
+}
+
+// Footer は、ゲームの結果に添える検証用の情報を返します。
+func (r *Round) Footer() string {
+	return fmt.Sprintf("🔐 シード %s… / ナンス %d", r.ServerSeedHash[:12], r.Nonce)
+}
+
+// Service は、ユーザーごとのシードからゲームの乱数を払い出す共有のサービスです。
+type Service struct {
+	Store interfaces.DataStore
+
+	mu   sync.Mutex
+	open map[string]int // guildID:userID -> 精算が終わっていないラウンドの数
+}
+
+func roundKey(guildID, userID string) string {
+	return guildID + ":" + userID
+}
+
+// acquire は、ユーザーのラウンドを1つ開いたものとして数えます。
+func (f *Service) acquire(guildID, userID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.open == nil {
+		f.open = make(map[string]int)
+	}
+	f.open[roundKey(guildID, userID)]++
+}
+
+// release は、acquire で数えたラウンドを1つ閉じます。
+func (f *Service) release(guildID, userID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := roundKey(guildID, userID)
+	if f.open[key]--; f.open[key] <= 0 {
+		delete(f.open, key)
+	}
+}
+
+// Seeds は、ユーザーの現在のシードを返します。まだなければ新しく作ります。
+func (f *Service) Seeds(guildID, userID string) (*storage.FairSeeds, error) {
+	seeds, err := f.Store.GetFairSeeds(guildID, userID)
+	if err != nil || seeds != nil {
+		return seeds, err
+	}
+	serverSeed := NewServerSeed()
+	return f.Store.CreateFairSeeds(guildID, userID, serverSeed, HashSeed(serverSeed), NewClientSeed())
+}
+
+// NewRound は、ユーザーの次のナンスを消費して1回のゲームの乱数を返します。
+// 返したラウンドは、ゲームの精算が終わったら (中止した場合も) Close してください。
+func (f *Service) NewRound(guildID, userID string) (*Round, error) {
+	// ナンスを使う前に数えておき、その間にシードが公開されないようにする
+	f.acquire(guildID, userID)
+	release := func() { f.release(guildID, userID) }
+	if _, err := f.Seeds(guildID, userID); err != nil {
+		release()
+		return nil, err
+	}
+	seeds, err := f.Store.UseFairNonce(guildID, userID)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &Round{
+		ServerSeedHash: seeds.ServerSeedHash,
+		ClientSeed:     seeds.ClientSeed,
+		Nonce:          seeds.Nonce,
+		Rand:           NewRand(seeds.ServerSeed, seeds.ClientSeed, seeds.Nonce),
+		release:        release,
+	}, nil
+}
+
+// Rotate は、現在のサーバーシードを公開して新しいシードに切り替えます。
+// clientSeed が空なら、クライアントシードは引き継がれます。
+// 精算が終わっていないラウンドがあると結果が先に分かってしまうため、その間は ErrRoundInProgress を返します。
+func (f *Service) Rotate(guildID, userID, clientSeed string) (*storage.FairSeeds, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.open[roundKey(guildID, userID)] > 0 {
+		return nil, ErrRoundInProgress
+	}
+
+	current, err := f.Seeds(guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if clientSeed == "" {
+		clientSeed = current.ClientSeed
+	}
+	serverSeed := NewServerSeed()
+	return f.Store.RotateFairSeeds(guildID, userID, serverSeed, HashSeed(serverSeed), clientSeed)
+}
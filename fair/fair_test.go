@@ -0,0 +1,67 @@
+package fair
+
+import (
+	"errors"
+	"luna/storage"
+	"path/filepath"
+	"testing"
+)
+
+// newTestService は、一時ディレクトリのデータベースを使う Service を作ります。
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	store, err := storage.NewDBStore(filepath.Join(t.TempDir(), "fair.db"))
+	if err != nil {
+		t.Fatalf("NewDBStore: %v", err)
+	}
+	t.Cleanup(store.Close)
+	return &Service{Store: store}
+}
+
+func TestRotateRefusedWhileRoundOpen(t *testing.T) {
+	f := newTestService(t)
+
+	first, err := f.NewRound("guild", "user")
+	if err != nil {
+		t.Fatalf("NewRound: %v", err)
+	}
+	second, err := f.NewRound("guild", "user")
+	if err != nil {
+		t.Fatalf("NewRound: %v", err)
+	}
+
+	if _, err := f.Rotate("guild", "user", ""); !errors.Is(err, ErrRoundInProgress) {
+		t.Fatalf("Rotate with open rounds: err = %v, want ErrRoundInProgress", err)
+	}
+	// 他のユーザーや他のサーバーのシードは切り替えられる
+	if _, err := f.Rotate("guild", "other", ""); err != nil {
+		t.Errorf("Rotate for another user: %v", err)
+	}
+	if _, err := f.Rotate("other-guild", "user", ""); err != nil {
+		t.Errorf("Rotate in another guild: %v", err)
+	}
+
+	// Close は何度呼んでも1回としか数えない
+	first.Close()
+	first.Close()
+	if _, err := f.Rotate("guild", "user", ""); !errors.Is(err, ErrRoundInProgress) {
+		t.Fatalf("Rotate with one round still open: err = %v, want ErrRoundInProgress", err)
+	}
+
+	second.Close()
+	seeds, err := f.Rotate("guild", "user", "")
+	if err != nil {
+		t.Fatalf("Rotate after all rounds closed: %v", err)
+	}
+	if seeds.PrevNonce != 2 {
+		t.Errorf("PrevNonce = %d, want 2", seeds.PrevNonce)
+	}
+	if seeds.PrevServerSeedHash != first.ServerSeedHash {
+		t.Errorf("revealed seed hash = %s, want %s", seeds.PrevServerSeedHash, first.ServerSeedHash)
+	}
+}
+
+func TestCloseNilRound(t *testing.T) {
+	var r *Round
+	r.Close()
+}
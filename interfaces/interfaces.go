@@ -3,6 +3,7 @@ package interfaces
 import (
 	"context"
 	"luna/storage"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/robfig/cron/v3"
@@ -43,12 +44,120 @@ type DataStore interface {
 	GetJackpot(guildID string) (int64, error)
 	UpdateJackpot(guildID string, newJackpot int64) error
 	AddToJackpot(guildID string, amount int64) (int64, error)
+	GetEconomyConfig(guildID string) (*storage.EconomyConfig, error)
+	GetRewardStatus(guildID, userID string) (*storage.RewardStatus, error)
+	ClaimReward(guildID, userID, kind string, amount int64, periodStart time.Time, streak int64) error
+	SetDailyReminder(guildID, userID, mode, channelID string) error
+	GetPendingDailyReminders() ([]storage.RewardStatus, error)
+	MarkDailyReminded(guildID, userID string) error
 	// Stocks
 	GetUserPortfolio(userID string) (map[string]int64, error)
-	UpdateUserPortfolio(userID, companyCode string, shares int64) error
+	UpdateUserPortfolio(guildID, userID, companyCode string, shares int64) error
 	GetAllCompanies() ([]storage.Company, error)
 	GetCompanyByCode(code string) (*storage.Company, error)
 	UpdateCompanyPrices(prices map[string]float64) error
+	UpdateCompanyFundamentals(companies []storage.Company) error
+	PayDividend(companyCode string, perShare float64) ([]storage.DividendPayment, error)
+	GetDividendHistory(userID string, limit int) ([]storage.DividendPayment, error)
+	CreateStockOrder(order *storage.StockOrder) error
+	CancelStockOrder(orderID int64, userID string) (*storage.StockOrder, error)
+	SettleStockTrades(trades []storage.StockTrade) error
+	GetOpenStockOrders() ([]storage.StockOrder, error)
+	GetUserOpenStockOrders(userID string) ([]storage.StockOrder, error)
+	GetRecentStockTrades(companyCode string, limit int) ([]storage.StockTrade, error)
+	OpenShortPosition(guildID, userID, companyCode string, shares int64, price, initialMargin float64) (*storage.ShortPosition, error)
+	CoverShortPosition(userID, companyCode string, shares int64, price float64, forced bool) (int64, error)
+	AddShortCollateral(userID, companyCode string, amount int64) (*storage.ShortPosition, error)
+	ChargeShortBorrowFees(prices map[string]float64, rate float64) error
+	SetShortMarginCall(userID, companyCode string, called bool) error
+	GetUserShortPositions(userID string) ([]storage.ShortPosition, error)
+	GetAllShortPositions() ([]storage.ShortPosition, error)
+	// Shop & Inventory
+	CreateShopItem(item *storage.ShopItem) error
+	DeleteShopItem(guildID string, itemID int64) error
+	GetShopItems(guildID string) ([]storage.ShopItem, error)
+	GetShopItemByName(guildID, name string) (*storage.ShopItem, error)
+	PurchaseShopItem(guildID, userID string, itemID, quantity int64) (*storage.ShopItem, error)
+	GetInventory(guildID, userID string) ([]storage.InventoryItem, error)
+	UseInventoryItem(guildID, userID string, itemID int64) (*storage.ShopItem, error)
+	TransferInventoryItem(guildID, fromUserID, toUserID string, itemID, quantity int64) error
+	// Fishing
+	GetFishingProfile(guildID, userID string) (*storage.FishingProfile, error)
+	BuyFishingRod(guildID, userID, rodID string, price int64) error
+	BuyFishingBait(guildID, userID, baitID string, quantity, unitPrice int64) error
+	EquipFishingGear(guildID, userID, rodID, baitID string) error
+	RecordFishCatch(catch *storage.FishCatch, cost int64, baitID string) (firstCatch, newRecord bool, err error)
+	GetFishCatches(guildID, userID string) ([]storage.FishCatch, error)
+	SellFishCatches(guildID, userID, fishID string) (count, total int64, err error)
+	GetFishCollection(guildID, userID string) (map[string]storage.FishCollectionEntry, error)
+	// Bank & Loans
+	GetBankAccount(guildID, userID string) (*storage.BankAccount, error)
+	BankDeposit(guildID, userID string, amount int64) error
+	BankWithdraw(guildID, userID string, amount int64) error
+	PayBankInterest() error
+	TakeLoan(guildID, userID string, amount int64) (*storage.Loan, error)
+	RepayLoan(guildID, userID string, amount int64) (*storage.Loan, error)
+	GetTransactions(guildID, userID string, limit int) ([]storage.Transaction, error)
+	// Economy administration
+	IsAccountFrozen(guildID, userID string) (bool, error)
+	AdjustEconomy(entry *storage.EconomyAuditEntry, userIDs []string) error
+	SetAccountFrozen(entry *storage.EconomyAuditEntry) error
+	WipeGuildEconomy(entry *storage.EconomyAuditEntry) error
+	GetEconomyAuditLog(guildID string, limit int) ([]storage.EconomyAuditEntry, error)
+	// Currency exchange
+	GetExchangeMarket(guildID string) (*storage.ExchangeMarket, error)
+	GetExchangeMarkets() ([]storage.ExchangeMarket, error)
+	ExchangeCurrency(guildID, userID, direction string, amount int64) (*storage.ExchangeResult, error)
+	UpdateExchangeRate(market *storage.ExchangeMarket, rate float64) error
+	GetExchangeRateHistory(guildID string, since time.Time) ([]storage.ExchangeRatePoint, error)
+	// Lottery
+	GetLotteryConfigs() (map[string]storage.LotteryConfig, error)
+	GetOpenLotteryRound(guildID string) (*storage.LotteryRound, error)
+	BuyLotteryTickets(guildID, userID string, numbers []int64, price, maxTickets int64) (*storage.LotteryRound, error)
+	GetLotteryTickets(roundID int64, userID string) ([]storage.LotteryTicket, error)
+	DrawLottery(guildID string, winningNumber int64) (*storage.LotteryDraw, error)
+	GetLotteryHistory(guildID string, limit int) ([]storage.LotteryRound, error)
+	// Achievements
+	RecordAchievementProgress(guildID, userID string, progress map[string]int64, achievements []storage.Achievement) ([]storage.Achievement, error)
+	GetUserAchievements(guildID, userID string, achievements []storage.Achievement) ([]storage.UserAchievement, error)
+	// Gambling statistics
+	RecordGameRound(guildID, userID, game string, wagered, returned int64) error
+	GetGameStats(guildID, userID string) ([]storage.GameStats, error)
+	GetGuildGameStats(guildID string) ([]storage.GameStats, error)
+	// Responsible gambling
+	GetGamblingLimits(guildID, userID string) (*storage.GamblingLimits, error)
+	SetGamblingLimits(limits *storage.GamblingLimits) error
+	SelfExclude(guildID, userID string, until time.Time) error
+	GetActiveExclusions(guildID string, now time.Time) ([]storage.GamblingLimits, error)
+	GetGamblingActivity(guildID, userID string, now time.Time) (*storage.GamblingActivity, error)
+	// Provably fair seeds
+	GetFairSeeds(guildID, userID string) (*storage.FairSeeds, error)
+	CreateFairSeeds(guildID, userID, serverSeed, serverSeedHash, clientSeed string) (*storage.FairSeeds, error)
+	UseFairNonce(guildID, userID string) (*storage.FairSeeds, error)
+	RotateFairSeeds(guildID, userID, serverSeed, serverSeedHash, clientSeed string) (*storage.FairSeeds, error)
+	// Slot machines
+	GetSlotMachine(guildID, name string) (*storage.SlotMachine, error)
+	GetSlotMachines(guildID string) ([]storage.SlotMachine, error)
+	SaveSlotMachine(m *storage.SlotMachine) error
+	DeleteSlotMachine(guildID, name string) (bool, error)
+	// Escrow
+	HoldEscrow(guildID, userID, kind, ref string, amount int64) (int64, error)
+	SetEscrows(guildID, kind, ref string, amounts map[string]int64) error
+	ReleaseEscrow(guildID, userID, kind, ref string) (int64, error)
+	SettleEscrow(guildID, userID, kind, ref string, amount int64) error
+	SettleEscrows(guildID, kind, ref string, amounts map[string]int64) error
+	ReleaseAllEscrows(kind string) (int, error)
+	// Horse racing
+	GetStable(guildID string) ([]storage.Horse, error)
+	GetHorseByName(guildID, name string) (*storage.Horse, error)
+	AddHorse(h *storage.Horse) error
+	RetireHorse(guildID string, horseID int64) error
+	RecordHorseRace(guildID string, results []storage.HorseResult) error
+	GetHorseResults(guildID string, horseID int64, limit int) ([]storage.HorseResult, error)
+	GetHorseRaceSchedules() (map[string]storage.HorseRaceSchedule, error)
+	// Duels
+	GetDuelRecord(guildID, user1, user2 string) (*storage.DuelRecord, error)
+	RecordDuel(guildID, winnerID, loserID string, draw bool) error
 	IncrementCommandUsage(category string) error
 	GetAndResetCommandUsage() (map[string]int, error)
 	GetRecentMessagesByUser(guildID, userID string, limit int) ([]string, error)
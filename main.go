@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"luna/ai"
 	"luna/bot"
 	"luna/commands"
@@ -16,6 +17,15 @@ import (
 )
 
 func main() {
+	// `luna simulate ...` はBotを起動せずにゲームのシミュレーションだけを行う
+	if len(os.Args) > 1 && os.Args[1] == "simulate" {
+		if err := runSimulate(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	log := logger.New()
 
 	if err := config.LoadConfig(log); err != nil {
@@ -63,6 +73,10 @@ func main() {
 
 	// 5分ごとに株価を更新
 	scheduler.AddFunc("@every 5m", stockCmd.UpdateStockPrices)
+	// 毎日、企業の売上から配当を支払う
+	scheduler.AddFunc("@daily", stockCmd.PayDividends)
+	// 1時間ごとに空売りの貸株料を徴収
+	scheduler.AddFunc("@hourly", stockCmd.ChargeBorrowFees)
 	scheduler.AddFunc("@hourly", func() {
 		if rand.Float32() < 0.25 { // 25% chance to trigger an event every hour
 			// Need a guild ID to announce the event. This is a limitation.
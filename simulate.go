@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"luna/slots"
+	"luna/storage"
+	"math/rand"
+	"time"
+)
+
+// runSimulate は、`luna simulate <game>` のサブコマンドを実行します。
+func runSimulate(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: luna simulate slots|videoslots [--spins N]")
+	}
+	switch args[0] {
+	case "slots":
+		return simulateSlots(args[1:])
+	case "videoslots":
+		return simulateVideoSlots(args[1:])
+	}
+	return fmt.Errorf("unknown game %q (available: slots, videoslots)", args[0])
+}
+
+// simulateSlots は、スロットマシンを指定回数まわして還元率・当選頻度・分散を表示します。
+// --db と --guild を指定すると、そのサーバーに保存されたマシンと経済設定を使います。
+func simulateSlots(args []string) error {
+	fs := flag.NewFlagSet("simulate slots", flag.ContinueOnError)
+	spins := fs.Int64("spins", 1000000, "number of spins to simulate")
+	seed := fs.Int64("seed", time.Now().UnixNano(), "random seed")
+	dbPath := fs.String("db", "", "database to load a guild's slot machine from (e.g. ./luna.db)")
+	guildID := fs.String("guild", "", "guild ID whose slot machine to simulate")
+	name := fs.String("machine", slots.DefaultName, "slot machine name")
+	contribution := fs.Float64("contribution", storage.DefaultJackpotContribution, "jackpot contribution used when the machine does not set one")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *spins <= 0 {
+		return errors.New("--spins must be positive")
+	}
+
+	machine := slots.DefaultMachine()
+	ceiling := storage.DefaultSlotsRTPCeiling
+	if *dbPath != "" && *guildID != "" {
+		db, err := storage.NewDBStore(*dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		economy, err := db.GetEconomyConfig(*guildID)
+		if err != nil {
+			return err
+		}
+		*contribution, ceiling = economy.JackpotContribution, economy.SlotsRTPCeiling
+		saved, err := db.GetSlotMachine(*guildID, *name)
+		if err != nil {
+			return err
+		}
+		if saved != nil {
+			machine = saved
+		} else if *name != slots.DefaultName {
+			return fmt.Errorf("slot machine %q not found in guild %s", *name, *guildID)
+		}
+	}
+	rate := slots.Contribution(machine, *contribution)
+
+	exact := slots.Analyze(machine, rate)
+	start := time.Now()
+	simulated := slots.Simulate(machine, rand.New(rand.NewSource(*seed)), *spins, rate)
+
+	fmt.Printf("machine: %s (jackpot contribution %.2f%%, RTP ceiling %.2f%%)\n", machine.Name, rate*100, ceiling*100)
+	fmt.Printf("%-10s %10s %10s %10s %10s %10s\n", "", "RTP", "base RTP", "hit freq", "jackpot", "std dev")
+	for _, row := range []struct {
+		label  string
+		report slots.Report
+	}{{"exact", exact}, {"simulated", simulated}} {
+		fmt.Printf("%-10s %9.3f%% %9.3f%% %9.3f%% %9.5f%% %10.3f\n", row.label,
+			row.report.RTP*100, row.report.BaseRTP*100, row.report.HitFrequency*100, row.report.JackpotFrequency*100, row.report.StdDev())
+	}
+	fmt.Printf("%d spins (seed %d) in %s\n", simulated.Spins, *seed, time.Since(start).Round(time.Millisecond))
+	if exact.RTP > ceiling {
+		fmt.Println("warning: RTP exceeds the ceiling; this machine would be rejected")
+	}
+	return nil
+}
+
+// simulateVideoSlots は、ビデオスロットのテーマを指定回数遊んで還元率・当選頻度・分散を表示します。
+func simulateVideoSlots(args []string) error {
+	fs := flag.NewFlagSet("simulate videoslots", flag.ContinueOnError)
+	spins := fs.Int64("spins", 100000, "number of paid spins to simulate (free spins are played on top)")
+	seed := fs.Int64("seed", time.Now().UnixNano(), "random seed")
+	themeID := fs.String("theme", "", "theme to simulate (default: all themes)")
+	lines := fs.Int("lines", len(slots.Paylines), "number of active paylines")
+	contribution := fs.Float64("contribution", storage.DefaultJackpotContribution, "jackpot contribution")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *spins <= 0 {
+		return errors.New("--spins must be positive")
+	}
+	if *lines < 1 || *lines > len(slots.Paylines) {
+		return fmt.Errorf("--lines must be between 1 and %d", len(slots.Paylines))
+	}
+
+	themes := slots.Themes
+	if *themeID != "" {
+		theme := slots.FindTheme(*themeID)
+		if theme == nil {
+			return fmt.Errorf("unknown theme %q", *themeID)
+		}
+		themes = []*slots.Theme{theme}
+	}
+
+	start := time.Now()
+	fmt.Printf("%d lines, jackpot contribution %.2f%%\n", *lines, *contribution*100)
+	fmt.Printf("%-10s %10s %10s %10s %10s %10s\n", "theme", "RTP", "base RTP", "hit freq", "jackpot", "std dev")
+	for _, theme := range themes {
+		report := slots.SimulateTheme(theme, rand.New(rand.NewSource(*seed)), *spins, *lines, *contribution)
+		fmt.Printf("%-10s %9.3f%% %9.3f%% %9.3f%% %9.5f%% %10.3f\n", theme.ID,
+			report.RTP*100, report.BaseRTP*100, report.HitFrequency*100, report.JackpotFrequency*100, report.StdDev())
+	}
+	fmt.Printf("%d spins per theme (seed %d) in %s\n", *spins, *seed, time.Since(start).Round(time.Millisecond))
+	return nil
+}
@@ -0,0 +1,238 @@
+// Package slots は、スロットマシンの出目と配当の判定、還元率 (RTP) の計算を提供します。
+//
+// 還元率は賭け金1あたりの平均払い戻しです。ジャックポットは積み立てた分が長期的にすべて
+// 払い戻されるものとして、積立率をそのまま還元率に加えます。
+package slots
+
+import (
+	"errors"
+	"fmt"
+	"luna/storage"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+// マシンの定義に許される値の範囲
+const (
+	ReelCount      = 3
+	MaxReelSymbols = 30
+	MaxMultiplier  = 1000
+)
+
+// ErrRTPTooHigh は、マシンの還元率が上限を超えていることを表します。
+var ErrRTPTooHigh = errors.New("slot machine RTP exceeds the ceiling")
+
+// DefaultName は、/slots でマシンを指定しなかったときに使うマシンの名前です。
+// この名前でサーバーのマシンを保存すると、組み込みのマシンの代わりに使われます。
+const DefaultName = "default"
+
+// DefaultMachine は、組み込みのスロットマシンを返します。
+func DefaultMachine() *storage.SlotMachine {
+	return &storage.SlotMachine{
+		Name: DefaultName,
+		// Reels are weighted. More common symbols appear more frequently.
+		Reels: [][]string{
+			{"🍒", "🍒", "🍒", "🍋", "🍋", "🍊", "🍊", "🍉", "🍇", "🍓", "💎"},
+			{"🍒", "🍒", "🍋", "🍋", "🍋", "🍊", "🍊", "🍉", "🍉", "🍇", "🍓", "💎"},
+			{"🍒", "🍒", "🍒", "🍋", "🍋", "🍊", "🍉", "🍉", "🍇", "🍇", "🍓", "💎"},
+		},
+		ThreeOfAKind: map[string]float64{
+			"🍇": 20,
+			"🍓": 15,
+			"🍉": 10,
+			"🍊": 8,
+			"🍋": 5,
+			"🍒": 3,
+		},
+		TwoOfAKind: map[string]float64{
+			"💎": 1.5,
+			"🍇": 1.0,
+			"🍓": 1.0,
+			"🍉": 0.5,
+			"🍊": 0.5,
+			"🍋": 0.3,
+			"🍒": 2.0,
+		},
+		JackpotSymbol: "💎",
+	}
+}
+
+// Contribution は、マシンのジャックポット積立率を返します。マシンで指定がなければ fallback を返します。
+func Contribution(m *storage.SlotMachine, fallback float64) float64 {
+	if m.JackpotContribution > 0 {
+		return m.JackpotContribution
+	}
+	return fallback
+}
+
+// Spin は、r から各リールの出目を決めます。
+func Spin(m *storage.SlotMachine, r *rand.Rand) []string {
+	symbols := make([]string, len(m.Reels))
+	for i, reel := range m.Reels {
+		symbols[i] = reel[r.Intn(len(reel))]
+	}
+	return symbols
+}
+
+// Outcome は、出目に対する配当の判定結果です。
+type Outcome struct {
+	Symbol     string  // 揃った絵柄
+	Count      int     // 揃った数 (揃っていなければ0)
+	Multiplier float64 // 賭け金に対する配当倍率
+	Jackpot    bool    // ジャックポットの絵柄が揃ったか
+}
+
+// Won は、配当かジャックポットがあるかを返します。
+func (o Outcome) Won() bool {
+	return o.Jackpot || o.Multiplier > 0
+}
+
+// Evaluate は、出目の配当を判定します。ジャックポットの絵柄が揃った場合、Multiplier は0です。
+func Evaluate(m *storage.SlotMachine, symbols []string) Outcome {
+	counts := make(map[string]int)
+	for _, s := range symbols {
+		counts[s]++
+	}
+	var best Outcome
+	for symbol, count := range counts {
+		switch {
+		case count == len(symbols) && symbol == m.JackpotSymbol:
+			return Outcome{Symbol: symbol, Count: count, Jackpot: true}
+		case count == len(symbols):
+			return Outcome{Symbol: symbol, Count: count, Multiplier: m.ThreeOfAKind[symbol]}
+		case count == 2 && m.TwoOfAKind[symbol] > best.Multiplier:
+			best = Outcome{Symbol: symbol, Count: count, Multiplier: m.TwoOfAKind[symbol]}
+		}
+	}
+	return best
+}
+
+// Report は、マシンの還元率と配当の分布です。すべて賭け金1あたりの値です。
+type Report struct {
+	Spins            int64   // シミュレーションの回転数 (厳密計算では0)
+	BaseRTP          float64 // ジャックポットを除いた還元率
+	RTP              float64 // ジャックポットを含めた還元率
+	HitFrequency     float64 // 配当かジャックポットがある割合
+	JackpotFrequency float64 // ジャックポットの割合
+	Variance         float64 // 1回の払い戻しの分散
+}
+
+// StdDev は、1回の払い戻しの標準偏差を返します。
+func (r Report) StdDev() float64 {
+	return math.Sqrt(r.Variance)
+}
+
+// Analyze は、すべての出目の組み合わせを数えて還元率を厳密に計算します。
+// contribution はジャックポットへの積立率で、ジャックポットの払い戻しは1回あたり contribution / 当選確率 とみなします。
+func Analyze(m *storage.SlotMachine, contribution float64) Report {
+	var report Report
+	total := 1.0
+	for _, reel := range m.Reels {
+		total *= float64(len(reel))
+	}
+	if total == 0 {
+		return report
+	}
+
+	var sum, sumSquares, hits, jackpots float64
+	symbols := make([]string, len(m.Reels))
+	var walk func(reel int)
+	walk = func(reel int) {
+		if reel == len(m.Reels) {
+			outcome := Evaluate(m, symbols)
+			switch {
+			case outcome.Jackpot:
+				jackpots++
+				hits++
+			case outcome.Multiplier > 0:
+				sum += outcome.Multiplier
+				sumSquares += outcome.Multiplier * outcome.Multiplier
+				hits++
+			}
+			return
+		}
+		for _, symbol := range m.Reels[reel] {
+			symbols[reel] = symbol
+			walk(reel + 1)
+		}
+	}
+	walk(0)
+
+	report.BaseRTP = sum / total
+	report.HitFrequency = hits / total
+	report.JackpotFrequency = jackpots / total
+	report.RTP = report.BaseRTP
+	secondMoment := sumSquares / total
+	if jackpots > 0 {
+		jackpotPayout := contribution / report.JackpotFrequency
+		report.RTP += contribution
+		secondMoment += report.JackpotFrequency * jackpotPayout * jackpotPayout
+	}
+	report.Variance = secondMoment - report.RTP*report.RTP
+	return report
+}
+
+// Simulate は、r で spins 回まわして還元率を実測します。
+// ジャックポットは0から積み立て、当選するたびに積み立てた額を払い戻します。
+func Simulate(m *storage.SlotMachine, r *rand.Rand, spins int64, contribution float64) Report {
+	report := Report{Spins: spins}
+	if spins <= 0 {
+		return report
+	}
+
+	var pool, base, hits, jackpots, mean, m2 float64
+	for n := int64(1); n <= spins; n++ {
+		pool += contribution
+		outcome := Evaluate(m, Spin(m, r))
+		payout := outcome.Multiplier
+		if outcome.Jackpot {
+			payout = pool
+			pool = 0
+			jackpots++
+		}
+		base += outcome.Multiplier
+		if outcome.Won() {
+			hits++
+		}
+		// Welford の方法で平均と分散を逐次計算する
+		delta := payout - mean
+		mean += delta / float64(n)
+		m2 += delta * (payout - mean)
+	}
+
+	report.BaseRTP = base / float64(spins)
+	report.RTP = mean
+	report.HitFrequency = hits / float64(spins)
+	report.JackpotFrequency = jackpots / float64(spins)
+	report.Variance = m2 / float64(spins)
+	return report
+}
+
+// Validate は、マシンの定義が正しく、還元率が ceiling 以下であるかを確認します。
+func Validate(m *storage.SlotMachine, ceiling, contribution float64) error {
+	if len(m.Reels) != ReelCount {
+		return fmt.Errorf("a slot machine needs exactly %d reels", ReelCount)
+	}
+	for i, reel := range m.Reels {
+		if len(reel) == 0 || len(reel) > MaxReelSymbols {
+			return fmt.Errorf("reel %d must have between 1 and %d symbols", i+1, MaxReelSymbols)
+		}
+		for _, symbol := range reel {
+			if strings.TrimSpace(symbol) == "" {
+				return fmt.Errorf("reel %d has an empty symbol", i+1)
+			}
+		}
+	}
+	for _, paytable := range []map[string]float64{m.ThreeOfAKind, m.TwoOfAKind} {
+		for symbol, multiplier := range paytable {
+			if multiplier < 0 || multiplier > MaxMultiplier {
+				return fmt.Errorf("multiplier for %s must be between 0 and %d", symbol, MaxMultiplier)
+			}
+		}
+	}
+	if rtp := Analyze(m, contribution).RTP; rtp > ceiling {
+		return fmt.Errorf("%w: %.2f%% > %.2f%%", ErrRTPTooHigh, rtp*100, ceiling*100)
+	}
+	return nil
+}
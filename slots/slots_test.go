@@ -0,0 +1,155 @@
+package slots
+
+import (
+	"errors"
+	"luna/storage"
+	"math"
+	"strings"
+	"testing"
+)
+
+// tinyMachine は、2種類の絵柄だけの手計算できるマシンを返します。
+// 8通りの出目は AAA (配当2)、A2枚 (3通り、配当0.5)、B2枚 (3通り、配当0.25)、BBB (ジャックポット) です。
+func tinyMachine() *storage.SlotMachine {
+	return &storage.SlotMachine{
+		Reels:         [][]string{{"A", "B"}, {"A", "B"}, {"A", "B"}},
+		ThreeOfAKind:  map[string]float64{"A": 2},
+		TwoOfAKind:    map[string]float64{"A": 0.5, "B": 0.25},
+		JackpotSymbol: "B",
+	}
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-12
+}
+
+func TestEvaluate(t *testing.T) {
+	m := DefaultMachine()
+	tests := []struct {
+		name    string
+		symbols []string
+		want    Outcome
+	}{
+		{"jackpot", []string{"💎", "💎", "💎"}, Outcome{Symbol: "💎", Count: 3, Jackpot: true}},
+		{"three of a kind", []string{"🍇", "🍇", "🍇"}, Outcome{Symbol: "🍇", Count: 3, Multiplier: 20}},
+		{"two cherries", []string{"🍒", "🍋", "🍒"}, Outcome{Symbol: "🍒", Count: 2, Multiplier: 2}},
+		{"two jackpot symbols pay the pair", []string{"💎", "🍒", "💎"}, Outcome{Symbol: "💎", Count: 2, Multiplier: 1.5}},
+		{"two lemons", []string{"💎", "🍋", "🍋"}, Outcome{Symbol: "🍋", Count: 2, Multiplier: 0.3}},
+		{"no match", []string{"🍇", "🍓", "🍒"}, Outcome{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Evaluate(m, tt.symbols)
+			if got != tt.want {
+				t.Errorf("Evaluate(%v) = %+v, want %+v", tt.symbols, got, tt.want)
+			}
+			if got.Won() != (tt.want != Outcome{}) {
+				t.Errorf("Won() = %v", got.Won())
+			}
+		})
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name         string
+		machine      *storage.SlotMachine
+		contribution float64
+		want         Report
+	}{
+		{
+			name:         "tiny machine",
+			machine:      tinyMachine(),
+			contribution: 0.04,
+			// 配当の合計 2 + 3*0.5 + 3*0.25 = 4.25、2乗の合計 4 + 3*0.25 + 3*0.0625 = 4.9375
+			// ジャックポットは1/8の確率で 0.04 / (1/8) = 0.32 を払い戻す
+			want: Report{
+				BaseRTP:          4.25 / 8,
+				RTP:              4.25/8 + 0.04,
+				HitFrequency:     1,
+				JackpotFrequency: 1.0 / 8,
+				Variance:         4.9375/8 + 0.32*0.32/8 - (4.25/8+0.04)*(4.25/8+0.04),
+			},
+		},
+		{
+			name:         "tiny machine without contribution",
+			machine:      tinyMachine(),
+			contribution: 0,
+			want: Report{
+				BaseRTP:          4.25 / 8,
+				RTP:              4.25 / 8,
+				HitFrequency:     1,
+				JackpotFrequency: 1.0 / 8,
+				Variance:         4.9375/8 - (4.25/8)*(4.25/8),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Analyze(tt.machine, tt.contribution)
+			if !almostEqual(got.BaseRTP, tt.want.BaseRTP) || !almostEqual(got.RTP, tt.want.RTP) ||
+				!almostEqual(got.HitFrequency, tt.want.HitFrequency) || !almostEqual(got.JackpotFrequency, tt.want.JackpotFrequency) ||
+				!almostEqual(got.Variance, tt.want.Variance) || got.Spins != 0 {
+				t.Errorf("Analyze = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 組み込みのマシンは 11*12*12 = 1584 通りの出目のうち、配当の合計が 883、当たりが 662 通り、ジャックポットが1通り
+func TestAnalyzeDefaultMachine(t *testing.T) {
+	got := Analyze(DefaultMachine(), 0.01)
+	if !almostEqual(got.BaseRTP, 883.0/1584) {
+		t.Errorf("BaseRTP = %v, want %v", got.BaseRTP, 883.0/1584)
+	}
+	if !almostEqual(got.RTP, 883.0/1584+0.01) {
+		t.Errorf("RTP = %v, want %v", got.RTP, 883.0/1584+0.01)
+	}
+	if !almostEqual(got.HitFrequency, 662.0/1584) {
+		t.Errorf("HitFrequency = %v, want %v", got.HitFrequency, 662.0/1584)
+	}
+	if !almostEqual(got.JackpotFrequency, 1.0/1584) {
+		t.Errorf("JackpotFrequency = %v, want %v", got.JackpotFrequency, 1.0/1584)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(m *storage.SlotMachine)
+		ceiling float64
+		wantErr string // 空なら成功
+		wantRTP bool   // ErrRTPTooHigh を期待する
+	}{
+		{"valid", func(m *storage.SlotMachine) {}, 0.6, "", false},
+		{"RTP at the ceiling", func(m *storage.SlotMachine) {}, 4.25/8 + 0.04, "", false},
+		{"RTP above the ceiling", func(m *storage.SlotMachine) {}, 0.5, "", true},
+		{"two reels", func(m *storage.SlotMachine) { m.Reels = m.Reels[:2] }, 1, "a slot machine needs exactly 3 reels", false},
+		{"empty reel", func(m *storage.SlotMachine) { m.Reels[1] = nil }, 1, "reel 2 must have between 1 and 30 symbols", false},
+		{"too many symbols", func(m *storage.SlotMachine) {
+			m.Reels[2] = strings.Split(strings.Repeat("A,", MaxReelSymbols)+"B", ",")
+		}, 1, "reel 3 must have between 1 and 30 symbols", false},
+		{"blank symbol", func(m *storage.SlotMachine) { m.Reels[0] = []string{"A", " "} }, 1, "reel 1 has an empty symbol", false},
+		{"negative multiplier", func(m *storage.SlotMachine) { m.TwoOfAKind["B"] = -1 }, 1, "multiplier for B must be between 0 and 1000", false},
+		{"multiplier too large", func(m *storage.SlotMachine) { m.ThreeOfAKind["A"] = MaxMultiplier + 1 }, 1, "multiplier for A must be between 0 and 1000", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := tinyMachine()
+			tt.modify(m)
+			err := Validate(m, tt.ceiling, 0.04)
+			switch {
+			case tt.wantRTP:
+				if !errors.Is(err, ErrRTPTooHigh) {
+					t.Errorf("Validate = %v, want ErrRTPTooHigh", err)
+				}
+			case tt.wantErr == "":
+				if err != nil {
+					t.Errorf("Validate = %v, want nil", err)
+				}
+			case err == nil || err.Error() != tt.wantErr:
+				t.Errorf("Validate = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package slots
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// ビデオスロットの盤面の大きさと上限
+const (
+	GridRows      = 3
+	GridReels     = 5
+	MaxFreeSpins  = 100 // 1回のボーナスで遊べるフリースピンの上限 (再獲得を含む)
+	rtpSampleSize = 200000
+)
+
+// Paylines は、ビデオスロットの配当ラインです。各要素はリールごとの行 (0が上段) です。
+// プレイヤーが選んだライン数だけ、先頭から順に有効になります。
+var Paylines = [][GridReels]int{
+	{1, 1, 1, 1, 1},
+	{0, 0, 0, 0, 0},
+	{2, 2, 2, 2, 2},
+	{0, 1, 2, 1, 0},
+	{2, 1, 0, 1, 2},
+	{0, 0, 1, 2, 2},
+	{2, 2, 1, 0, 0},
+	{1, 0, 0, 0, 1},
+	{1, 2, 2, 2, 1},
+	{1, 0, 1, 2, 1},
+	{1, 2, 1, 0, 1},
+	{0, 1, 0, 1, 0},
+	{2, 1, 2, 1, 2},
+	{0, 1, 1, 1, 0},
+	{2, 1, 1, 1, 2},
+	{1, 1, 0, 1, 1},
+	{1, 1, 2, 1, 1},
+	{0, 0, 2, 0, 0},
+	{2, 2, 0, 2, 2},
+	{0, 2, 0, 2, 0},
+}
+
+// VideoSymbol は、テーマの絵柄です。Pays はライン上で左から3・4・5個揃ったときのライン賭け金に対する倍率です。
+type VideoSymbol struct {
+	Emoji  string
+	Weight int // 各リールに並ぶ数
+	Pays   [3]float64
+}
+
+// Theme は、ビデオスロットのテーマです。
+type Theme struct {
+	ID     string
+	Name   string
+	Emoji  string
+	Symbol []VideoSymbol
+	// Wild は、スキャッター以外のすべての絵柄の代わりになります。
+	Wild VideoSymbol
+	// Scatter は、盤面のどこにあっても数えられ、総賭け金に対する ScatterPays と FreeSpins を与えます。
+	Scatter     VideoSymbol
+	ScatterPays map[int]float64
+	FreeSpins   map[int]int
+	// FreeSpinMultiplier は、フリースピン中の配当の倍率です。
+	FreeSpinMultiplier float64
+	// JackpotSymbol が通常のスピンで有効なライン上に5個揃うと、ジャックポットを獲得します。
+	JackpotSymbol string
+
+	reelsOnce sync.Once
+	reels     [][]string
+	rtpOnce   sync.Once
+	rtp       float64
+}
+
+// Themes は、選べるビデオスロットのテーマです。
+var Themes = []*Theme{
+	{
+		ID: "fruits", Name: "フルーツパラダイス", Emoji: "🍒",
+		Symbol: []VideoSymbol{
+			{Emoji: "💎", Weight: 1, Pays: [3]float64{40, 180, 900}},
+			{Emoji: "🔔", Weight: 2, Pays: [3]float64{17, 70, 340}},
+			{Emoji: "🍉", Weight: 3, Pays: [3]float64{10, 35, 135}},
+			{Emoji: "🍇", Weight: 3, Pays: [3]float64{7, 20, 70}},
+			{Emoji: "🍊", Weight: 4, Pays: [3]float64{3.5, 14, 40}},
+			{Emoji: "🍋", Weight: 4, Pays: [3]float64{1.5, 7, 27}},
+			{Emoji: "🍒", Weight: 5, Pays: [3]float64{1.5, 5, 18}},
+		},
+		Wild:               VideoSymbol{Emoji: "🃏", Weight: 2, Pays: [3]float64{40, 180, 900}},
+		Scatter:            VideoSymbol{Emoji: "⭐", Weight: 1},
+		ScatterPays:        map[int]float64{3: 2, 4: 10, 5: 50},
+		FreeSpins:          map[int]int{3: 8, 4: 12, 5: 20},
+		FreeSpinMultiplier: 2,
+		JackpotSymbol:      "💎",
+	},
+	{
+		ID: "ocean", Name: "オーシャントレジャー", Emoji: "🌊",
+		Symbol: []VideoSymbol{
+			{Emoji: "👑", Weight: 1, Pays: [3]float64{20, 95, 470}},
+			{Emoji: "🐬", Weight: 2, Pays: [3]float64{8, 30, 155}},
+			{Emoji: "🐙", Weight: 3, Pays: [3]float64{5, 15, 60}},
+			{Emoji: "🦀", Weight: 4, Pays: [3]float64{3, 10, 30}},
+			{Emoji: "🐠", Weight: 4, Pays: [3]float64{1.5, 6, 20}},
+			{Emoji: "🐚", Weight: 5, Pays: [3]float64{1, 2.5, 8}},
+		},
+		Wild:               VideoSymbol{Emoji: "🌊", Weight: 2, Pays: [3]float64{20, 95, 470}},
+		Scatter:            VideoSymbol{Emoji: "🔱", Weight: 1},
+		ScatterPays:        map[int]float64{3: 2, 4: 10, 5: 50},
+		FreeSpins:          map[int]int{3: 10, 4: 15, 5: 25},
+		FreeSpinMultiplier: 2,
+		JackpotSymbol:      "👑",
+	},
+	{
+		ID: "space", Name: "ギャラクシーラッシュ", Emoji: "🚀",
+		Symbol: []VideoSymbol{
+			{Emoji: "🪐", Weight: 1, Pays: [3]float64{9, 45, 225}},
+			{Emoji: "👽", Weight: 2, Pays: [3]float64{4, 14, 70}},
+			{Emoji: "🚀", Weight: 3, Pays: [3]float64{2, 7, 25}},
+			{Emoji: "🌙", Weight: 4, Pays: [3]float64{1, 3.5, 9}},
+			{Emoji: "⭐", Weight: 5, Pays: [3]float64{0.4, 1, 3.5}},
+		},
+		Wild:               VideoSymbol{Emoji: "🌌", Weight: 2, Pays: [3]float64{9, 45, 225}},
+		Scatter:            VideoSymbol{Emoji: "☄️", Weight: 1},
+		ScatterPays:        map[int]float64{3: 2, 4: 10, 5: 50},
+		FreeSpins:          map[int]int{3: 5, 4: 8, 5: 12},
+		FreeSpinMultiplier: 3,
+		JackpotSymbol:      "🪐",
+	},
+}
+
+// DefaultTheme は、サーバーでテーマを選んでいないときのテーマです。
+const DefaultTheme = "fruits"
+
+// FindTheme は、ID のテーマを返します。見つからなければ nil を返します。
+func FindTheme(id string) *Theme {
+	for _, t := range Themes {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// Reels は、テーマのリールの並びを返します。
+// 絵柄を重みの数だけ並べ、リールごとに固定のシードで混ぜるため、同じテーマなら常に同じ並びになります。
+func (t *Theme) Reels() [][]string {
+	t.reelsOnce.Do(func() {
+		var strip []string
+		for _, s := range append(append([]VideoSymbol{}, t.Symbol...), t.Wild, t.Scatter) {
+			for n := 0; n < s.Weight; n++ {
+				strip = append(strip, s.Emoji)
+			}
+		}
+		t.reels = make([][]string, GridReels)
+		for reel := range t.reels {
+			t.reels[reel] = append([]string(nil), strip...)
+			shuffler := rand.New(rand.NewSource(int64(reel + 1)))
+			shuffler.Shuffle(len(strip), func(a, b int) {
+				t.reels[reel][a], t.reels[reel][b] = t.reels[reel][b], t.reels[reel][a]
+			})
+		}
+	})
+	return t.reels
+}
+
+// pays は、絵柄が count 個揃ったときのライン賭け金に対する倍率を返します。
+func (t *Theme) pays(symbol string, count int) float64 {
+	if count < 3 {
+		return 0
+	}
+	if symbol == t.Wild.Emoji {
+		return t.Wild.Pays[count-3]
+	}
+	for _, s := range t.Symbol {
+		if s.Emoji == symbol {
+			return s.Pays[count-3]
+		}
+	}
+	return 0
+}
+
+// Grid は、ビデオスロットの盤面です。Grid[reel][row] の形で参照します。
+type Grid [GridReels][GridRows]string
+
+// SpinGrid は、r から各リールの停止位置を決めて盤面を作ります。
+func (t *Theme) SpinGrid(r *rand.Rand) Grid {
+	var grid Grid
+	for reel, strip := range t.Reels() {
+		stop := r.Intn(len(strip))
+		for row := 0; row < GridRows; row++ {
+			grid[reel][row] = strip[(stop+row)%len(strip)]
+		}
+	}
+	return grid
+}
+
+// LineWin は、1本の配当ラインの当たりです。
+type LineWin struct {
+	Line       int // Paylines の添字
+	Symbol     string
+	Count      int
+	Multiplier float64 // ライン賭け金に対する倍率
+	Jackpot    bool
+}
+
+// SpinResult は、1回のスピンの結果です。倍率はすべてライン賭け金に対する値です。
+type SpinResult struct {
+	Grid       Grid
+	LineWins   []LineWin
+	Scatters   int
+	ScatterPay float64 // 総賭け金に対する倍率
+	FreeSpins  int     // このスピンで獲得したフリースピンの回数
+	Multiplier float64 // ラインとスキャッターを合わせた倍率 (フリースピンの倍率を含む)
+	Jackpot    bool
+}
+
+// evaluateLine は、配当ラインの左端から続く同じ絵柄 (ワイルドを含む) を数えます。
+func (t *Theme) evaluateLine(grid Grid, line [GridReels]int) (string, int) {
+	symbols := make([]string, GridReels)
+	for reel, row := range line {
+		symbols[reel] = grid[reel][row]
+	}
+
+	// ワイルドだけの並びと、ワイルドを最初の通常の絵柄として数えた並びのうち、配当の高い方を使う
+	wildCount := 0
+	for wildCount < GridReels && symbols[wildCount] == t.Wild.Emoji {
+		wildCount++
+	}
+	target := ""
+	if wildCount < GridReels {
+		target = symbols[wildCount]
+	}
+	if target == "" || target == t.Scatter.Emoji {
+		return t.Wild.Emoji, wildCount
+	}
+	count := wildCount
+	for count < GridReels && (symbols[count] == target || symbols[count] == t.Wild.Emoji) {
+		count++
+	}
+	if t.pays(t.Wild.Emoji, wildCount) > t.pays(target, count) {
+		return t.Wild.Emoji, wildCount
+	}
+	return target, count
+}
+
+// Evaluate は、lines 本のラインで盤面の配当を判定します。
+// freeSpin が true の場合はフリースピンの倍率をかけ、ジャックポットは判定しません。
+func (t *Theme) Evaluate(grid Grid, lines int, freeSpin bool) SpinResult {
+	result := SpinResult{Grid: grid}
+	multiplier := 1.0
+	if freeSpin {
+		multiplier = t.FreeSpinMultiplier
+	}
+
+	for n := 0; n < lines && n < len(Paylines); n++ {
+		symbol, count := t.evaluateLine(grid, Paylines[n])
+		win := LineWin{Line: n, Symbol: symbol, Count: count, Multiplier: t.pays(symbol, count) * multiplier}
+		if !freeSpin && count == GridReels && symbol == t.JackpotSymbol {
+			win.Jackpot = true
+			result.Jackpot = true
+		}
+		if win.Multiplier > 0 || win.Jackpot {
+			result.LineWins = append(result.LineWins, win)
+			result.Multiplier += win.Multiplier
+		}
+	}
+
+	for _, reel := range grid {
+		for _, symbol := range reel {
+			if symbol == t.Scatter.Emoji {
+				result.Scatters++
+			}
+		}
+	}
+	result.ScatterPay = t.ScatterPays[result.Scatters] * multiplier
+	result.Multiplier += result.ScatterPay * float64(lines)
+	result.FreeSpins = t.FreeSpins[result.Scatters]
+	return result
+}
+
+// RoundResult は、通常のスピンと、そこから獲得したフリースピンをまとめた1回のゲームの結果です。
+type RoundResult struct {
+	Base       SpinResult
+	Free       []SpinResult
+	Multiplier float64 // ライン賭け金に対する払い戻しの合計 (ジャックポットを除く)
+}
+
+// Play は、r で通常のスピンを1回行い、獲得したフリースピンをすべて消化します。
+func (t *Theme) Play(r *rand.Rand, lines int) RoundResult {
+	round := RoundResult{Base: t.Evaluate(t.SpinGrid(r), lines, false)}
+	round.Multiplier = round.Base.Multiplier
+	remaining := round.Base.FreeSpins
+	awarded := remaining
+	for remaining > 0 {
+		remaining--
+		spin := t.Evaluate(t.SpinGrid(r), lines, true)
+		// フリースピン中の再獲得は上限まで
+		if extra := min(spin.FreeSpins, MaxFreeSpins-awarded); extra > 0 {
+			remaining += extra
+			awarded += extra
+		}
+		round.Free = append(round.Free, spin)
+		round.Multiplier += spin.Multiplier
+	}
+	return round
+}
+
+// SimulateTheme は、r で spins 回遊んで還元率を実測します。ライン数は lines 本、賭け金は総賭け金1あたりで数えます。
+// ジャックポットは Simulate と同じく0から積み立て、当選するたびに積み立てた額を払い戻します。
+func SimulateTheme(t *Theme, r *rand.Rand, spins int64, lines int, contribution float64) Report {
+	report := Report{Spins: spins}
+	if spins <= 0 || lines <= 0 {
+		return report
+	}
+
+	var pool, base, hits, jackpots, mean, m2 float64
+	for n := int64(1); n <= spins; n++ {
+		pool += contribution
+		round := t.Play(r, lines)
+		payout := round.Multiplier / float64(lines)
+		base += payout
+		if round.Base.Jackpot {
+			payout += pool
+			pool = 0
+			jackpots++
+		}
+		if payout > 0 {
+			hits++
+		}
+		delta := payout - mean
+		mean += delta / float64(n)
+		m2 += delta * (payout - mean)
+	}
+
+	report.BaseRTP = base / float64(spins)
+	report.RTP = mean
+	report.HitFrequency = hits / float64(spins)
+	report.JackpotFrequency = jackpots / float64(spins)
+	report.Variance = m2 / float64(spins)
+	return report
+}
+
+// BaseRTP は、ジャックポットを除いたテーマの還元率を返します。
+// 全ラインで遊んだ場合を固定のシードで実測し、結果はテーマごとに一度だけ計算します。
+func (t *Theme) BaseRTP() float64 {
+	t.rtpOnce.Do(func() {
+		t.rtp = SimulateTheme(t, rand.New(rand.NewSource(1)), rtpSampleSize, len(Paylines), 0).BaseRTP
+	})
+	return t.rtp
+}
+
+// SortedLineWins は、配当の高い順に並べたラインの当たりを返します。
+func (s SpinResult) SortedLineWins() []LineWin {
+	wins := append([]LineWin(nil), s.LineWins...)
+	sort.SliceStable(wins, func(a, b int) bool { return wins[a].Multiplier > wins[b].Multiplier })
+	return wins
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+// TxAchievementReward は、実績の解除報酬を表す取引履歴の種類です。
+const TxAchievementReward = "achievement_reward"
+
+// Achievement は、実績の定義です。Counter の進捗が Goal に達すると解除され、Reward チップが1度だけ支払われます。
+type Achievement struct {
+	ID          string
+	Name        string
+	Description string
+	Emoji       string
+	Counter     string // 進捗として数えるカウンター
+	Goal        int64
+	Reward      int64
+}
+
+// UserAchievement は、ユーザーの実績の進捗と解除状況です。
+type UserAchievement struct {
+	Achievement
+	Progress   int64
+	Reward     int64 // 実際に支払われた報酬
+	UnlockedAt sql.NullTime
+}
+
+// Unlocked は、実績が解除済みかを返します。
+func (a UserAchievement) Unlocked() bool {
+	return a.UnlockedAt.Valid
+}
+
+// RecordAchievementProgress は、progress の各カウンターを加算し、新しく Goal に達した実績を解除して報酬を支払います。
+// 解除された実績を返します。
+func (s *DBStore) RecordAchievementProgress(guildID, userID string, progress map[string]int64, achievements []Achievement) ([]Achievement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	values := make(map[string]int64, len(progress))
+	for counter, amount := range progress {
+		if amount <= 0 {
+			continue
+		}
+		var value int64
+		if err := tx.QueryRow(`INSERT INTO achievement_progress (guild_id, user_id, counter, value) VALUES (?, ?, ?, ?)
+			ON CONFLICT(guild_id, user_id, counter) DO UPDATE SET value = value + excluded.value RETURNING value`,
+			guildID, userID, counter, amount).Scan(&value); err != nil {
+			return nil, err
+		}
+		values[counter] = value
+	}
+
+	var unlocked []Achievement
+	now := time.Now().UTC()
+	for _, a := range achievements {
+		value, ok := values[a.Counter]
+		if !ok || value < a.Goal {
+			continue
+		}
+		res, err := tx.Exec("INSERT INTO user_achievements (guild_id, user_id, achievement_id, reward, unlocked_at) VALUES (?, ?, ?, 0, ?) ON CONFLICT DO NOTHING",
+			guildID, userID, a.ID, now)
+		if err != nil {
+			return nil, err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return nil, err
+		} else if n == 0 {
+			continue // 解除済み
+		}
+		unlocked = append(unlocked, a)
+		if a.Reward <= 0 {
+			continue
+		}
+		if err := creditBalance(tx, guildID, userID, CurrencyChips, a.Reward); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec("UPDATE user_achievements SET reward = ? WHERE guild_id = ? AND user_id = ? AND achievement_id = ?", a.Reward, guildID, userID, a.ID); err != nil {
+			return nil, err
+		}
+		if err := logTransaction(tx, guildID, userID, TxAchievementReward, CurrencyChips, a.Reward, a.Name); err != nil {
+			return nil, err
+		}
+	}
+	return unlocked, tx.Commit()
+}
+
+// GetUserAchievements は、achievements の各実績についてユーザーの進捗と解除状況を定義順に返します。
+func (s *DBStore) GetUserAchievements(guildID, userID string, achievements []Achievement) ([]UserAchievement, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counters := make(map[string]int64)
+	rows, err := s.db.Query("SELECT counter, value FROM achievement_progress WHERE guild_id = ? AND user_id = ?", guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var counter string
+		var value int64
+		if err := rows.Scan(&counter, &value); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		counters[counter] = value
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	type unlock struct {
+		reward int64
+		at     time.Time
+	}
+	unlocks := make(map[string]unlock)
+	rows, err = s.db.Query("SELECT achievement_id, reward, unlocked_at FROM user_achievements WHERE guild_id = ? AND user_id = ?", guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var u unlock
+		if err := rows.Scan(&id, &u.reward, &u.at); err != nil {
+			return nil, err
+		}
+		unlocks[id] = u
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]UserAchievement, len(achievements))
+	for idx, a := range achievements {
+		result[idx] = UserAchievement{Achievement: a, Progress: min(counters[a.Counter], a.Goal)}
+		if u, ok := unlocks[a.ID]; ok {
+			result[idx].Reward = u.reward
+			result[idx].UnlockedAt = sql.NullTime{Time: u.at, Valid: true}
+			result[idx].Progress = a.Goal
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,423 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrLoanExists は、返済中のローンがあるため新しく借りられないことを表します。
+var ErrLoanExists = errors.New("loan already exists")
+
+// ErrNoLoan は、返済中のローンがないことを表します。
+var ErrNoLoan = errors.New("no active loan")
+
+// ErrCreditLimit は、借入額が与信枠を超えていることを表します。
+var ErrCreditLimit = errors.New("credit limit exceeded")
+
+// 取引履歴の種類
+const (
+	TxBankDeposit    = "bank_deposit"
+	TxBankWithdraw   = "bank_withdraw"
+	TxBankInterest   = "bank_interest"
+	TxLoanIssued     = "loan_issued"
+	TxLoanRepayment  = "loan_repayment"
+	TxLoanCollection = "loan_collection" // 延滞したローンの入金 (勝ち分や配当など) からの自動回収
+)
+
+// ローンの状態
+const (
+	LoanStatusOpen   = "open"
+	LoanStatusRepaid = "repaid"
+)
+
+// Transaction は、取引履歴の1件です。Amount はユーザーから見た増減 (マイナスは支出) です。
+type Transaction struct {
+	ID        int64
+	GuildID   string
+	UserID    string
+	Kind      string
+	Currency  string
+	Amount    int64
+	Note      string
+	CreatedAt time.Time
+}
+
+// Loan は、ユーザーの借入です。
+type Loan struct {
+	ID        int64
+	GuildID   string
+	UserID    string
+	Principal int64
+	AmountDue int64 // 利息込みの返済総額
+	Repaid    int64
+	Status    string
+	IssuedAt  time.Time
+	DueAt     time.Time
+	RepaidAt  sql.NullTime
+}
+
+// Outstanding は、返済残高を返します。
+func (l *Loan) Outstanding() int64 {
+	return l.AmountDue - l.Repaid
+}
+
+// Overdue は、返済期限を過ぎているかを返します。
+func (l *Loan) Overdue(now time.Time) bool {
+	return l.Status == LoanStatusOpen && now.After(l.DueAt)
+}
+
+// BankAccount は、ユーザーの銀行口座とローンの状況です。
+type BankAccount struct {
+	GuildID     string
+	UserID      string
+	Balance     int64 // 預金残高 (チップ)
+	Loan        *Loan // 返済中のローン (なければ nil)
+	CreditLimit int64
+}
+
+// logTransaction は、取引履歴を1件記録します。
+func logTransaction(tx *sql.Tx, guildID, userID, kind, currency string, amount int64, note string) error {
+	_, err := tx.Exec("INSERT INTO transactions (guild_id, user_id, kind, currency, amount, note, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		guildID, userID, kind, currency, amount, note, time.Now().UTC())
+	return err
+}
+
+// GetTransactions は、ユーザーの取引履歴を新しい順に返します。
+func (s *DBStore) GetTransactions(guildID, userID string, limit int) ([]Transaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query("SELECT id, guild_id, user_id, kind, currency, amount, note, created_at FROM transactions WHERE guild_id = ? AND user_id = ? ORDER BY id DESC LIMIT ?",
+		guildID, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []Transaction
+	for rows.Next() {
+		var t Transaction
+		if err := rows.Scan(&t.ID, &t.GuildID, &t.UserID, &t.Kind, &t.Currency, &t.Amount, &t.Note, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, t)
+	}
+	return history, rows.Err()
+}
+
+const loanColumns = "id, guild_id, user_id, principal, amount_due, repaid, status, issued_at, due_at, repaid_at"
+
+func scanLoan(row rowScanner) (*Loan, error) {
+	var l Loan
+	if err := row.Scan(&l.ID, &l.GuildID, &l.UserID, &l.Principal, &l.AmountDue, &l.Repaid, &l.Status, &l.IssuedAt, &l.DueAt, &l.RepaidAt); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// openLoan は、返済中のローンを返します。なければ nil を返します。
+func openLoan(q queryRower, guildID, userID string) (*Loan, error) {
+	loan, err := scanLoan(q.QueryRow("SELECT "+loanColumns+" FROM loans WHERE guild_id = ? AND user_id = ? AND status = ?", guildID, userID, LoanStatusOpen))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return loan, err
+}
+
+// creditLimit は、借入の履歴から与信枠を計算します。
+// 期限内に完済した元本の半分だけ枠が広がり、延滞したローン1件ごとに基本枠の分だけ狭まります。
+func creditLimit(q queryRower, guildID, userID string, baseCredit int64) (int64, error) {
+	var repaidOnTime, lateCount int64
+	err := q.QueryRow(`
+		SELECT
+			COALESCE(SUM(CASE WHEN status = ? AND repaid_at <= due_at THEN principal ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN (status = ? AND repaid_at > due_at) OR (status = ? AND due_at < ?) THEN 1 ELSE 0 END), 0)
+		FROM loans WHERE guild_id = ? AND user_id = ?`,
+		LoanStatusRepaid, LoanStatusRepaid, LoanStatusOpen, time.Now().UTC(), guildID, userID).Scan(&repaidOnTime, &lateCount)
+	if err != nil {
+		return 0, err
+	}
+	limit := baseCredit + repaidOnTime/2 - baseCredit*lateCount
+	if limit < 0 {
+		limit = 0
+	}
+	return limit, nil
+}
+
+// GetBankAccount は、ユーザーの預金残高・返済中のローン・与信枠を返します。
+func (s *DBStore) GetBankAccount(guildID, userID string) (*BankAccount, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	account := &BankAccount{GuildID: guildID, UserID: userID}
+	err := s.db.QueryRow("SELECT balance FROM bank_accounts WHERE guild_id = ? AND user_id = ?", guildID, userID).Scan(&account.Balance)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if account.Loan, err = openLoan(s.db, guildID, userID); err != nil {
+		return nil, err
+	}
+	config, err := loadEconomyConfig(s.db, guildID)
+	if err != nil {
+		return nil, err
+	}
+	if account.CreditLimit, err = creditLimit(s.db, guildID, userID, config.LoanBaseCredit); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// BankDeposit は、手持ちのチップを銀行に預けます。
+func (s *DBStore) BankDeposit(guildID, userID string, amount int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := debitBalance(tx, guildID, userID, CurrencyChips, amount); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO bank_accounts (guild_id, user_id, balance) VALUES (?, ?, ?)
+		ON CONFLICT(guild_id, user_id) DO UPDATE SET balance = balance + excluded.balance`,
+		guildID, userID, amount); err != nil {
+		return err
+	}
+	if err := logTransaction(tx, guildID, userID, TxBankDeposit, CurrencyChips, -amount, ""); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// BankWithdraw は、預金からチップを引き出します。
+func (s *DBStore) BankWithdraw(guildID, userID string, amount int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.Exec("UPDATE bank_accounts SET balance = balance - ? WHERE guild_id = ? AND user_id = ? AND balance >= ?", amount, guildID, userID, amount)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrInsufficientFunds
+	}
+	if err := creditBalance(tx, guildID, userID, CurrencyChips, amount); err != nil {
+		return err
+	}
+	if err := logTransaction(tx, guildID, userID, TxBankWithdraw, CurrencyChips, amount, ""); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// PayBankInterest は、すべての預金にサーバーごとの利率で1日分の利息を支払います。
+func (s *DBStore) PayBankInterest() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.Query("SELECT guild_id, user_id, balance FROM bank_accounts WHERE balance > 0")
+	if err != nil {
+		return err
+	}
+	type deposit struct {
+		guildID, userID string
+		balance         int64
+	}
+	var deposits []deposit
+	for rows.Next() {
+		var d deposit
+		if err := rows.Scan(&d.guildID, &d.userID, &d.balance); err != nil {
+			rows.Close()
+			return err
+		}
+		deposits = append(deposits, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	configs := make(map[string]*EconomyConfig)
+	for _, d := range deposits {
+		config, ok := configs[d.guildID]
+		if !ok {
+			if config, err = loadEconomyConfig(tx, d.guildID); err != nil {
+				return err
+			}
+			configs[d.guildID] = config
+		}
+		interest := int64(math.Floor(float64(d.balance) * config.BankInterestRate))
+		if interest <= 0 {
+			continue
+		}
+		if _, err := tx.Exec("UPDATE bank_accounts SET balance = balance + ? WHERE guild_id = ? AND user_id = ?", interest, d.guildID, d.userID); err != nil {
+			return err
+		}
+		if err := logTransaction(tx, d.guildID, d.userID, TxBankInterest, CurrencyChips, interest, ""); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// TakeLoan は、与信枠の範囲内でチップを借ります。利息込みの返済額は amount × (1 + 利率) です。
+func (s *DBStore) TakeLoan(guildID, userID string, amount int64) (*Loan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	existing, err := openLoan(tx, guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrLoanExists
+	}
+
+	config, err := loadEconomyConfig(tx, guildID)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := creditLimit(tx, guildID, userID, config.LoanBaseCredit)
+	if err != nil {
+		return nil, err
+	}
+	if amount > limit {
+		return nil, ErrCreditLimit
+	}
+
+	now := time.Now().UTC()
+	loan := &Loan{
+		GuildID:   guildID,
+		UserID:    userID,
+		Principal: amount,
+		AmountDue: int64(math.Ceil(float64(amount) * (1 + config.LoanInterestRate))),
+		Status:    LoanStatusOpen,
+		IssuedAt:  now,
+		DueAt:     now.AddDate(0, 0, int(config.LoanTermDays)),
+	}
+	res, err := tx.Exec("INSERT INTO loans (guild_id, user_id, principal, amount_due, repaid, status, issued_at, due_at) VALUES (?, ?, ?, ?, 0, ?, ?, ?)",
+		loan.GuildID, loan.UserID, loan.Principal, loan.AmountDue, loan.Status, loan.IssuedAt, loan.DueAt)
+	if err != nil {
+		return nil, err
+	}
+	if loan.ID, err = res.LastInsertId(); err != nil {
+		return nil, err
+	}
+	if err := creditBalance(tx, guildID, userID, CurrencyChips, amount); err != nil {
+		return nil, err
+	}
+	if err := logTransaction(tx, guildID, userID, TxLoanIssued, CurrencyChips, amount, ""); err != nil {
+		return nil, err
+	}
+	return loan, tx.Commit()
+}
+
+// applyLoanPayment は、ローンに amount を充当し、完済したら状態を更新します。
+func applyLoanPayment(tx *sql.Tx, loan *Loan, amount int64) error {
+	loan.Repaid += amount
+	if loan.Outstanding() <= 0 {
+		loan.Status = LoanStatusRepaid
+		loan.RepaidAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	}
+	_, err := tx.Exec("UPDATE loans SET repaid = ?, status = ?, repaid_at = ? WHERE id = ?", loan.Repaid, loan.Status, loan.RepaidAt, loan.ID)
+	return err
+}
+
+// RepayLoan は、手持ちのチップでローンを返済します。amount が返済残高を超える場合は残高分だけ返済します。
+func (s *DBStore) RepayLoan(guildID, userID string, amount int64) (*Loan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	loan, err := openLoan(tx, guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if loan == nil {
+		return nil, ErrNoLoan
+	}
+	if amount <= 0 || amount > loan.Outstanding() {
+		amount = loan.Outstanding()
+	}
+
+	if err := debitBalance(tx, guildID, userID, CurrencyChips, amount); err != nil {
+		return nil, err
+	}
+	if err := applyLoanPayment(tx, loan, amount); err != nil {
+		return nil, err
+	}
+	if err := logTransaction(tx, guildID, userID, TxLoanRepayment, CurrencyChips, -amount, ""); err != nil {
+		return nil, err
+	}
+	return loan, tx.Commit()
+}
+
+// collectOverdueLoan は、延滞中のローンがあれば、currency での入金 income から返済に充当します。
+// ローンはチップ建てのため、PPCの入金は両替の売値でチップに換算して回収します。回収した額を currency で返します。
+func collectOverdueLoan(tx *sql.Tx, guildID, userID, currency string, income int64) (int64, error) {
+	if income <= 0 {
+		return 0, nil
+	}
+	loan, err := openLoan(tx, guildID, userID)
+	if err != nil || loan == nil || !loan.Overdue(time.Now()) {
+		return 0, err
+	}
+
+	collected, applied := min(income, loan.Outstanding()), int64(0)
+	if currency == CurrencyPPC {
+		market, err := loadExchangeMarket(tx, guildID)
+		if err != nil {
+			return 0, err
+		}
+		config, err := loadEconomyConfig(tx, guildID)
+		if err != nil {
+			return 0, err
+		}
+		sell := QuoteFor(market.Rate, config.ExchangeSpread).Sell
+		// 返済残高に足りる分だけPPCを回収する。端数の分は切り上げ、充当額は返済残高までにする
+		collected = min(income, int64(math.Ceil(float64(loan.Outstanding())/sell)))
+		applied = min(int64(math.Floor(float64(collected)*sell)), loan.Outstanding())
+	} else {
+		applied = collected
+	}
+	if applied <= 0 {
+		return 0, nil
+	}
+
+	if err := applyLoanPayment(tx, loan, applied); err != nil {
+		return 0, err
+	}
+	if err := logTransaction(tx, guildID, userID, TxLoanCollection, currency, -collected, ""); err != nil {
+		return 0, err
+	}
+	return collected, nil
+}
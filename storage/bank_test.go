@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+// overdueLoan は、userID に amount チップを貸し、返済期限を過ぎた状態にします。
+func overdueLoan(t *testing.T, store *DBStore, userID string, amount int64) *Loan {
+	t.Helper()
+	loan, err := store.TakeLoan("guild", userID, amount)
+	if err != nil {
+		t.Fatalf("TakeLoan: %v", err)
+	}
+	if _, err := store.db.Exec("UPDATE loans SET due_at = ? WHERE id = ?", time.Now().Add(-time.Hour), loan.ID); err != nil {
+		t.Fatalf("expire loan: %v", err)
+	}
+	return loan
+}
+
+func outstanding(t *testing.T, store *DBStore, userID string) int64 {
+	t.Helper()
+	account, err := store.GetBankAccount("guild", userID)
+	if err != nil {
+		t.Fatalf("GetBankAccount: %v", err)
+	}
+	if account.Loan == nil {
+		return 0
+	}
+	return account.Loan.Outstanding()
+}
+
+func TestEscrowSettlementCollectsOverdueLoan(t *testing.T) {
+	store := newTestStore(t)
+	chips(t, store, "alice")
+	chips(t, store, "bob")
+	loan := overdueLoan(t, store, "alice", 100)
+	start := chips(t, store, "alice")
+
+	for _, userID := range []string{"alice", "bob"} {
+		if _, err := store.HoldEscrow("guild", userID, EscrowDuel, "ref", 50); err != nil {
+			t.Fatalf("HoldEscrow(%s): %v", userID, err)
+		}
+	}
+	// 勝ち分 100 チップのうち、返済残高の分が回収される
+	if err := store.SettleEscrows("guild", EscrowDuel, "ref", map[string]int64{"alice": 100, "bob": 0}); err != nil {
+		t.Fatalf("SettleEscrows: %v", err)
+	}
+	collected := min(100, loan.Outstanding())
+	if got, want := chips(t, store, "alice"), start-50+100-collected; got != want {
+		t.Errorf("chips = %d, want %d", got, want)
+	}
+	if got, want := outstanding(t, store, "alice"), loan.Outstanding()-collected; got != want {
+		t.Errorf("outstanding = %d, want %d", got, want)
+	}
+}
+
+func TestDividendCollectsOverdueLoan(t *testing.T) {
+	store := newTestStore(t)
+	loan := overdueLoan(t, store, "alice", 100)
+	if err := store.UpdateUserPortfolio("guild", "alice", "LUNA", 1000); err != nil {
+		t.Fatalf("UpdateUserPortfolio: %v", err)
+	}
+
+	payments, err := store.PayDividend("LUNA", 1)
+	if err != nil || len(payments) != 1 {
+		t.Fatalf("PayDividend = %v, %v", payments, err)
+	}
+	// PPCの配当は両替の売値でチップに換算して、返済残高に足りる分だけ回収される
+	if got := outstanding(t, store, "alice"); got != 0 {
+		t.Errorf("outstanding after dividend = %d, want 0 (loan due %d)", got, loan.Outstanding())
+	}
+	data, err := store.GetCasinoData("guild", "alice")
+	if err != nil {
+		t.Fatalf("GetCasinoData: %v", err)
+	}
+	if data.PepeCoinBalance <= 0 || data.PepeCoinBalance >= 1000 {
+		t.Errorf("PPC after collection = %d, want between 0 and 1000", data.PepeCoinBalance)
+	}
+}
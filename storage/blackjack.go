@@ -0,0 +1,70 @@
+package storage
+
+import "fmt"
+
+// ブラックジャックの配当の種類
+const (
+	BlackjackPays3To2 = "3:2"
+	BlackjackPays6To5 = "6:5"
+)
+
+// ブラックジャックのルールの既定値
+const (
+	DefaultBlackjackDecks    = 6
+	DefaultBlackjackPays     = BlackjackPays6To5
+	DefaultBlackjackMaxHands = 2 // スプリットは1回まで
+)
+
+// ブラックジャックのルールに許される値の範囲
+var (
+	BlackjackDecksRange    = EconomyRange{1, 8}
+	BlackjackMaxHandsRange = EconomyRange{2, 4}
+)
+
+// BlackjackRules は、サーバーごとのブラックジャックのルールです。
+// 未設定の項目 (ゼロ値) には既定値が使われます。
+type BlackjackRules struct {
+	Decks         int    `json:"decks"`
+	HitSoft17     bool   `json:"hit_soft_17"`    // ディーラーがソフト17でヒットするか
+	BlackjackPays string `json:"blackjack_pays"` // ブラックジャックの配当 (3:2 または 6:5)
+	DoubleAnyTwo  bool   `json:"double_any_two"` // false の場合、ダブルダウンは合計9〜11のときだけ
+	MaxHands      int    `json:"max_hands"`      // スプリットで作れる手札の最大数
+	ResplitAces   bool   `json:"resplit_aces"`
+	NoSurrender   bool   `json:"no_surrender"` // true の場合、レイトサレンダーを認めない
+	NoSideBets    bool   `json:"no_side_bets"` // true の場合、サイドベットを受け付けない
+}
+
+// ApplyDefaults は、未設定の項目を既定値で埋めます。
+func (r *BlackjackRules) ApplyDefaults() {
+	if r.Decks == 0 {
+		r.Decks = DefaultBlackjackDecks
+	}
+	if r.BlackjackPays == "" {
+		r.BlackjackPays = DefaultBlackjackPays
+	}
+	if r.MaxHands == 0 {
+		r.MaxHands = DefaultBlackjackMaxHands
+	}
+}
+
+// Validate は、すべての項目が許容範囲内にあるかを確認します。
+func (r *BlackjackRules) Validate() error {
+	if float64(r.Decks) < BlackjackDecksRange.Min || float64(r.Decks) > BlackjackDecksRange.Max {
+		return fmt.Errorf("decks must be between %g and %g", BlackjackDecksRange.Min, BlackjackDecksRange.Max)
+	}
+	if float64(r.MaxHands) < BlackjackMaxHandsRange.Min || float64(r.MaxHands) > BlackjackMaxHandsRange.Max {
+		return fmt.Errorf("max_hands must be between %g and %g", BlackjackMaxHandsRange.Min, BlackjackMaxHandsRange.Max)
+	}
+	if r.BlackjackPays != BlackjackPays3To2 && r.BlackjackPays != BlackjackPays6To5 {
+		return fmt.Errorf("unknown blackjack payout %q", r.BlackjackPays)
+	}
+	return nil
+}
+
+// BlackjackPayout は、ブラックジャックで勝ったときの賭け金に対する利益の倍率です。
+func (r *BlackjackRules) BlackjackPayout() float64 {
+	if r.BlackjackPays == BlackjackPays3To2 {
+		return 1.5
+	}
+	return 1.2
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync"
+	"time"
 
 	_ "modernc.org/sqlite"
 )
@@ -63,20 +64,39 @@ type CasinoData struct {
 }
 
 type Company struct {
-	Name                string  `json:"name"`
-	Code                string  `json:"code"`
-	Description         string  `json:"description"`
-	Price               float64 `json:"price"`
+	Name              string   `json:"name"`
+	Code              string   `json:"code"`
+	Description       string   `json:"description"`
+	Price             float64  `json:"price"`
 	RelatedCategories []string `json:"related_categories"` // JSONとして保存
+	// Fundamentals
+	BookValue         float64 `json:"book_value"`         // 1株あたり純資産
+	Revenue           float64 `json:"revenue"`            // 価格更新1回あたりの平滑化された売上
+	AccruedRevenue    float64 `json:"accrued_revenue"`    // 前回の配当以降に積み上がった売上
+	SharesOutstanding int64   `json:"shares_outstanding"` // 発行済株式数
+	PayoutRatio       float64 `json:"payout_ratio"`       // 売上のうち配当に回す割合
+	LastTradePrice    float64 `json:"last_trade_price"`   // プレイヤー間取引の最終約定価格 (0 は約定なし)
 }
 
 // PortfolioItem represents a single stock holding for a user.
 type PortfolioItem struct {
 	UserID      string
+	GuildID     string // 配当の支払先となるサーバー（最後に取引したサーバー）
 	CompanyCode string
 	Shares      int64
 }
 
+// DividendPayment represents a dividend paid to a single holder.
+type DividendPayment struct {
+	UserID      string
+	GuildID     string
+	CompanyCode string
+	Shares      int64
+	PerShare    float64
+	Amount      int64
+	PaidAt      time.Time
+}
+
 // --- DBStore ---
 
 type DBStore struct {
@@ -109,6 +129,10 @@ func (s *DBStore) initTables() error {
 			bump_config TEXT DEFAULT '{}',
 			welcome_config TEXT DEFAULT '{}',
 			autorole_config TEXT DEFAULT '{}',
+			economy_config TEXT DEFAULT '{}',
+			lottery_config TEXT DEFAULT '{}',
+			horserace_schedule TEXT DEFAULT '{}',
+			instant_games_config TEXT DEFAULT '{}',
 			jackpot INTEGER DEFAULT 0,
 			ticket_counter INTEGER DEFAULT 0
 		);`,
@@ -156,7 +180,13 @@ func (s *DBStore) initTables() error {
 			name TEXT NOT NULL,
 			description TEXT NOT NULL,
 			price REAL NOT NULL,
-			related_categories TEXT NOT NULL DEFAULT '[]'
+			related_categories TEXT NOT NULL DEFAULT '[]',
+			book_value REAL NOT NULL DEFAULT 0,
+			revenue REAL NOT NULL DEFAULT 0,
+			accrued_revenue REAL NOT NULL DEFAULT 0,
+			shares_outstanding INTEGER NOT NULL DEFAULT 10000,
+			payout_ratio REAL NOT NULL DEFAULT 0.4,
+			last_trade_price REAL NOT NULL DEFAULT 0
 		);`,
 		`CREATE TABLE IF NOT EXISTS command_usage (
 			category TEXT PRIMARY KEY,
@@ -166,15 +196,394 @@ func (s *DBStore) initTables() error {
 			user_id TEXT NOT NULL,
 			company_code TEXT NOT NULL,
 			shares INTEGER NOT NULL,
+			guild_id TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (user_id, company_code)
+		);`,
+		`CREATE TABLE IF NOT EXISTS stock_dividends (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT NOT NULL,
+			guild_id TEXT NOT NULL,
+			company_code TEXT NOT NULL,
+			shares INTEGER NOT NULL,
+			per_share REAL NOT NULL,
+			amount INTEGER NOT NULL,
+			paid_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS stock_orders (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			company_code TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			guild_id TEXT NOT NULL,
+			side TEXT NOT NULL,
+			price INTEGER NOT NULL,
+			quantity INTEGER NOT NULL,
+			remaining INTEGER NOT NULL,
+			status TEXT NOT NULL DEFAULT 'open',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS stock_shorts (
+			user_id TEXT NOT NULL,
+			guild_id TEXT NOT NULL,
+			company_code TEXT NOT NULL,
+			shares INTEGER NOT NULL,
+			entry_price REAL NOT NULL,
+			collateral INTEGER NOT NULL,
+			fees_paid INTEGER NOT NULL DEFAULT 0,
+			margin_call BOOLEAN NOT NULL DEFAULT 0,
+			opened_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			PRIMARY KEY (user_id, company_code)
 		);`,
+		`CREATE TABLE IF NOT EXISTS stock_trades (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			company_code TEXT NOT NULL,
+			buy_order_id INTEGER NOT NULL,
+			sell_order_id INTEGER NOT NULL,
+			buyer_id TEXT NOT NULL,
+			seller_id TEXT NOT NULL,
+			price INTEGER NOT NULL,
+			quantity INTEGER NOT NULL,
+			executed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS shop_items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			guild_id TEXT NOT NULL,
+			name TEXT NOT NULL COLLATE NOCASE,
+			description TEXT NOT NULL DEFAULT '',
+			price INTEGER NOT NULL,
+			currency TEXT NOT NULL,
+			stock INTEGER NOT NULL DEFAULT -1,
+			role_id TEXT NOT NULL DEFAULT '',
+			consumable BOOLEAN NOT NULL DEFAULT 0,
+			effect TEXT NOT NULL DEFAULT '',
+			effect_value INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (guild_id, name)
+		);`,
+		`CREATE TABLE IF NOT EXISTS inventories (
+			guild_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			item_id INTEGER NOT NULL,
+			quantity INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (guild_id, user_id, item_id)
+		);`,
+		`CREATE TABLE IF NOT EXISTS fishing_profiles (
+			guild_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			rod TEXT NOT NULL DEFAULT '',
+			bait TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (guild_id, user_id)
+		);`,
+		`CREATE TABLE IF NOT EXISTS fishing_rods (
+			guild_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			rod TEXT NOT NULL,
+			PRIMARY KEY (guild_id, user_id, rod)
+		);`,
+		`CREATE TABLE IF NOT EXISTS fishing_bait (
+			guild_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			bait TEXT NOT NULL,
+			quantity INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (guild_id, user_id, bait)
+		);`,
+		`CREATE TABLE IF NOT EXISTS fish_catches (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			guild_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			fish_id TEXT NOT NULL,
+			location TEXT NOT NULL,
+			size REAL NOT NULL,
+			value INTEGER NOT NULL,
+			caught_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS fish_collection (
+			guild_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			fish_id TEXT NOT NULL,
+			count INTEGER NOT NULL DEFAULT 0,
+			best_size REAL NOT NULL DEFAULT 0,
+			first_size REAL NOT NULL DEFAULT 0,
+			first_caught_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (guild_id, user_id, fish_id)
+		);`,
+		`CREATE TABLE IF NOT EXISTS reward_claims (
+			guild_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			last_weekly DATETIME,
+			last_monthly DATETIME,
+			daily_streak INTEGER NOT NULL DEFAULT 0,
+			best_streak INTEGER NOT NULL DEFAULT 0,
+			reminder TEXT NOT NULL DEFAULT '',
+			reminder_channel_id TEXT NOT NULL DEFAULT '',
+			reminded BOOLEAN NOT NULL DEFAULT 0,
+			PRIMARY KEY (guild_id, user_id)
+		);`,
+		`CREATE TABLE IF NOT EXISTS bank_accounts (
+			guild_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			balance INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (guild_id, user_id)
+		);`,
+		`CREATE TABLE IF NOT EXISTS loans (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			guild_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			principal INTEGER NOT NULL,
+			amount_due INTEGER NOT NULL,
+			repaid INTEGER NOT NULL DEFAULT 0,
+			status TEXT NOT NULL DEFAULT 'open',
+			issued_at DATETIME NOT NULL,
+			due_at DATETIME NOT NULL,
+			repaid_at DATETIME
+		);`,
+		`CREATE TABLE IF NOT EXISTS transactions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			guild_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			currency TEXT NOT NULL,
+			amount INTEGER NOT NULL,
+			note TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS economy_audit_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			guild_id TEXT NOT NULL,
+			moderator_id TEXT NOT NULL,
+			action TEXT NOT NULL,
+			target_id TEXT NOT NULL DEFAULT '',
+			target_role BOOLEAN NOT NULL DEFAULT 0,
+			currency TEXT NOT NULL DEFAULT '',
+			company_code TEXT NOT NULL DEFAULT '',
+			amount INTEGER NOT NULL DEFAULT 0,
+			affected INTEGER NOT NULL DEFAULT 0,
+			reason TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS exchange_markets (
+			guild_id TEXT PRIMARY KEY,
+			rate REAL NOT NULL,
+			net_flow INTEGER NOT NULL DEFAULT 0,
+			turnover INTEGER NOT NULL DEFAULT 0,
+			casino_volume INTEGER NOT NULL DEFAULT 0,
+			updated_at DATETIME NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS lottery_rounds (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			guild_id TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'open',
+			pot INTEGER NOT NULL DEFAULT 0,
+			rollover INTEGER NOT NULL DEFAULT 0,
+			ticket_count INTEGER NOT NULL DEFAULT 0,
+			winning_number INTEGER,
+			payout_per_win INTEGER NOT NULL DEFAULT 0,
+			winner_count INTEGER NOT NULL DEFAULT 0,
+			opened_at DATETIME NOT NULL,
+			drawn_at DATETIME
+		);`,
+		`CREATE TABLE IF NOT EXISTS lottery_tickets (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			round_id INTEGER NOT NULL,
+			guild_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			number INTEGER NOT NULL,
+			price INTEGER NOT NULL,
+			bought_at DATETIME NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS exchange_rate_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			guild_id TEXT NOT NULL,
+			rate REAL NOT NULL,
+			recorded_at DATETIME NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS achievement_progress (
+			guild_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			counter TEXT NOT NULL,
+			value INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (guild_id, user_id, counter)
+		);`,
+		`CREATE TABLE IF NOT EXISTS user_achievements (
+			guild_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			achievement_id TEXT NOT NULL,
+			reward INTEGER NOT NULL DEFAULT 0,
+			unlocked_at DATETIME NOT NULL,
+			PRIMARY KEY (guild_id, user_id, achievement_id)
+		);`,
+		`CREATE TABLE IF NOT EXISTS game_stats (
+			guild_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			game TEXT NOT NULL,
+			rounds INTEGER NOT NULL DEFAULT 0,
+			wagered INTEGER NOT NULL DEFAULT 0,
+			returned INTEGER NOT NULL DEFAULT 0,
+			biggest_win INTEGER NOT NULL DEFAULT 0,
+			current_streak INTEGER NOT NULL DEFAULT 0,
+			longest_streak INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (guild_id, user_id, game)
+		);`,
+		`CREATE TABLE IF NOT EXISTS gambling_limits (
+			guild_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			daily_loss_limit INTEGER NOT NULL DEFAULT 0,
+			weekly_loss_limit INTEGER NOT NULL DEFAULT 0,
+			daily_wager_limit INTEGER NOT NULL DEFAULT 0,
+			weekly_wager_limit INTEGER NOT NULL DEFAULT 0,
+			cooldown_seconds INTEGER NOT NULL DEFAULT 0,
+			excluded_until DATETIME,
+			PRIMARY KEY (guild_id, user_id)
+		);`,
+		`CREATE TABLE IF NOT EXISTS slot_machines (
+			guild_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			definition TEXT NOT NULL,
+			updated_by TEXT NOT NULL DEFAULT '',
+			updated_at DATETIME NOT NULL,
+			PRIMARY KEY (guild_id, name)
+		);`,
+		`CREATE TABLE IF NOT EXISTS fair_seeds (
+			guild_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			server_seed TEXT NOT NULL,
+			server_seed_hash TEXT NOT NULL,
+			client_seed TEXT NOT NULL,
+			nonce INTEGER NOT NULL DEFAULT 0,
+			prev_server_seed TEXT NOT NULL DEFAULT '',
+			prev_server_seed_hash TEXT NOT NULL DEFAULT '',
+			prev_client_seed TEXT NOT NULL DEFAULT '',
+			prev_nonce INTEGER NOT NULL DEFAULT 0,
+			rotated_at DATETIME,
+			PRIMARY KEY (guild_id, user_id)
+		);`,
+		`CREATE TABLE IF NOT EXISTS escrows (
+			guild_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			ref TEXT NOT NULL,
+			amount INTEGER NOT NULL,
+			updated_at DATETIME NOT NULL,
+			PRIMARY KEY (guild_id, user_id, kind, ref)
+		);`,
+		`CREATE TABLE IF NOT EXISTS duel_records (
+			guild_id TEXT NOT NULL,
+			user_a TEXT NOT NULL,
+			user_b TEXT NOT NULL,
+			a_wins INTEGER NOT NULL DEFAULT 0,
+			b_wins INTEGER NOT NULL DEFAULT 0,
+			draws INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (guild_id, user_a, user_b)
+		);`,
+		`CREATE TABLE IF NOT EXISTS horses (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			guild_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			emoji TEXT NOT NULL,
+			speed INTEGER NOT NULL,
+			stamina INTEGER NOT NULL,
+			consistency INTEGER NOT NULL,
+			starts INTEGER NOT NULL DEFAULT 0,
+			wins INTEGER NOT NULL DEFAULT 0,
+			top3 INTEGER NOT NULL DEFAULT 0,
+			retired INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS horse_results (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			guild_id TEXT NOT NULL,
+			horse_id INTEGER NOT NULL,
+			position INTEGER NOT NULL,
+			field_size INTEGER NOT NULL,
+			raced_at DATETIME NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS gambling_activity (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			guild_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			game TEXT NOT NULL,
+			wagered INTEGER NOT NULL,
+			returned INTEGER NOT NULL,
+			created_at DATETIME NOT NULL
+		);`,
 	}
 	for _, table := range tables {
 		if _, err := s.db.Exec(table); err != nil {
 			return err
 		}
 	}
-	return nil
+	return s.migrateColumns()
+}
+
+// migrateColumns は、既存のDBに後から追加されたカラムを補完します。
+func (s *DBStore) migrateColumns() error {
+	columns := []struct {
+		table, column, definition string
+	}{
+		{"companies", "book_value", "REAL NOT NULL DEFAULT 0"},
+		{"companies", "revenue", "REAL NOT NULL DEFAULT 0"},
+		{"companies", "accrued_revenue", "REAL NOT NULL DEFAULT 0"},
+		{"companies", "shares_outstanding", "INTEGER NOT NULL DEFAULT 10000"},
+		{"companies", "payout_ratio", "REAL NOT NULL DEFAULT 0.4"},
+		{"companies", "last_trade_price", "REAL NOT NULL DEFAULT 0"},
+		{"stocks_portfolios", "guild_id", "TEXT NOT NULL DEFAULT ''"},
+		{"guilds", "economy_config", "TEXT DEFAULT '{}'"},
+		{"casino_data", "frozen", "BOOLEAN NOT NULL DEFAULT 0"},
+		{"guilds", "lottery_config", "TEXT DEFAULT '{}'"},
+		{"guilds", "video_slots_config", "TEXT DEFAULT '{}'"},
+		{"guilds", "blackjack_rules", "TEXT DEFAULT '{}'"},
+		{"guilds", "horserace_schedule", "TEXT DEFAULT '{}'"},
+		{"guilds", "instant_games_config", "TEXT DEFAULT '{}'"},
+	}
+	for _, col := range columns {
+		if err := s.ensureColumn(col.table, col.column, col.definition); err != nil {
+			return err
+		}
+	}
+	return s.backfillPortfolioGuilds()
+}
+
+// backfillPortfolioGuilds は、guild_id を追加する前からある保有株に、配当の入金先のサーバーを割り当てます。
+// どのサーバーで買ったかは残っていないため、PPCの残高が最も多いサーバーを使います。
+// カジノの口座がどのサーバーにもないユーザーの保有株は空のまま残り、次に取引するまで配当を受け取りません。
+func (s *DBStore) backfillPortfolioGuilds() error {
+	_, err := s.db.Exec(`UPDATE stocks_portfolios SET guild_id = COALESCE((
+			SELECT c.guild_id FROM casino_data c WHERE c.user_id = stocks_portfolios.user_id
+			ORDER BY c.pepecoin_balance DESC, c.guild_id LIMIT 1), '')
+		WHERE guild_id = ''`)
+	return err
+}
+
+// ensureColumn は、指定したカラムが存在しなければ追加します。
+func (s *DBStore) ensureColumn(table, column, definition string) error {
+	rows, err := s.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
 }
 
 func (s *DBStore) Close() {
@@ -257,7 +666,11 @@ func (s *DBStore) GetCasinoData(guildID, userID string) (*CasinoData, error) {
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			data.Chips = 1000
+			config, configErr := loadEconomyConfig(s.db, guildID)
+			if configErr != nil {
+				return nil, configErr
+			}
+			data.Chips = config.StartingChips
 			data.PepeCoinBalance = 0
 			insertQuery := "INSERT INTO casino_data (guild_id, user_id, chips, pepecoin_balance, last_daily) VALUES (?, ?, ?, ?, NULL)"
 			_, insertErr := s.db.Exec(insertQuery, guildID, userID, data.Chips, data.PepeCoinBalance)
@@ -272,13 +685,43 @@ func (s *DBStore) GetCasinoData(guildID, userID string) (*CasinoData, error) {
 	return data, nil
 }
 
+// UpdateCasinoData は、チップとPPCの残高を保存します。
+// 延滞中のローンがある場合、増えたチップ (勝ち分) は返済に充当され、data.Chips も回収後の値になります。
+// last_daily は ClaimReward だけが更新するため、ここでは書き込みません。
+// 口座が凍結されている場合、残高を減らす更新には ErrAccountFrozen を返します (増やす更新は保存します)。
 func (s *DBStore) UpdateCasinoData(data *CasinoData) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	query := "UPDATE casino_data SET chips = ?, pepecoin_balance = ?, last_daily = ? WHERE guild_id = ? AND user_id = ?"
-	_, err := s.db.Exec(query, data.Chips, data.PepeCoinBalance, data.LastDaily, data.GuildID, data.UserID)
-	return err
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var current, currentPPC int64
+	err = tx.QueryRow("SELECT chips, pepecoin_balance FROM casino_data WHERE guild_id = ? AND user_id = ?", data.GuildID, data.UserID).Scan(&current, &currentPPC)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err == nil {
+		if data.Chips < current || data.PepeCoinBalance < currentPPC {
+			if err := checkNotFrozen(tx, data.GuildID, data.UserID); err != nil {
+				return err
+			}
+		}
+		collected, err := collectOverdueLoan(tx, data.GuildID, data.UserID, CurrencyChips, data.Chips-current)
+		if err != nil {
+			return err
+		}
+		data.Chips -= collected
+	}
+
+	query := "UPDATE casino_data SET chips = ?, pepecoin_balance = ? WHERE guild_id = ? AND user_id = ?"
+	if _, err := tx.Exec(query, data.Chips, data.PepeCoinBalance, data.GuildID, data.UserID); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (s *DBStore) GetChipLeaderboard(guildID string, limit int) ([]CasinoData, error) {
@@ -635,25 +1078,100 @@ func (s *DBStore) GetUserPortfolio(userID string) (map[string]int64, error) {
 	return portfolio, nil
 }
 
-func (s *DBStore) UpdateUserPortfolio(userID, companyCode string, shares int64) error {
+// UpdateUserPortfolio は保有株数を増減させ、取引したサーバーを配当の支払先として記録します。
+func (s *DBStore) UpdateUserPortfolio(guildID, userID, companyCode string, shares int64) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	query := `
-		INSERT INTO stocks_portfolios (user_id, company_code, shares)
-		VALUES (?, ?, ?)
-		ON CONFLICT(user_id, company_code) DO UPDATE SET shares = shares + ?;
+		INSERT INTO stocks_portfolios (user_id, company_code, shares, guild_id)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, company_code) DO UPDATE SET shares = shares + ?, guild_id = excluded.guild_id;
 	`
-	_, err := s.db.Exec(query, userID, companyCode, shares, shares)
+	_, err := s.db.Exec(query, userID, companyCode, shares, guildID, shares)
 	return err
 }
 
+// PayDividend は、企業の全保有者に1株あたり perShare PPC の配当を支払い、
+// 積み上がった売上をリセットします。すべて1つのトランザクション内で行われます。
+// 入金先のサーバーが分からない保有株 (backfillPortfolioGuilds を参照) は対象外です。
+func (s *DBStore) PayDividend(companyCode string, perShare float64) ([]DividendPayment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.Query("SELECT user_id, guild_id, shares FROM stocks_portfolios WHERE company_code = ? AND shares > 0 AND guild_id != ''", companyCode)
+	if err != nil {
+		return nil, err
+	}
+	var payments []DividendPayment
+	for rows.Next() {
+		p := DividendPayment{CompanyCode: companyCode, PerShare: perShare}
+		if err := rows.Scan(&p.UserID, &p.GuildID, &p.Shares); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		p.Amount = int64(float64(p.Shares) * perShare)
+		if p.Amount > 0 {
+			payments = append(payments, p)
+		}
+	}
+	rows.Close()
+
+	now := time.Now()
+	for i := range payments {
+		p := &payments[i]
+		p.PaidAt = now
+		if err := creditBalance(tx, p.GuildID, p.UserID, CurrencyPPC, p.Amount); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec("INSERT INTO stock_dividends (user_id, guild_id, company_code, shares, per_share, amount, paid_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			p.UserID, p.GuildID, p.CompanyCode, p.Shares, p.PerShare, p.Amount, p.PaidAt); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE companies SET accrued_revenue = 0 WHERE code = ?", companyCode); err != nil {
+		return nil, err
+	}
+
+	return payments, tx.Commit()
+}
+
+// GetDividendHistory returns the most recent dividend payments received by a user.
+func (s *DBStore) GetDividendHistory(userID string, limit int) ([]DividendPayment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := "SELECT user_id, guild_id, company_code, shares, per_share, amount, paid_at FROM stock_dividends WHERE user_id = ? ORDER BY paid_at DESC, id DESC LIMIT ?"
+	rows, err := s.db.Query(query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []DividendPayment
+	for rows.Next() {
+		var p DividendPayment
+		if err := rows.Scan(&p.UserID, &p.GuildID, &p.CompanyCode, &p.Shares, &p.PerShare, &p.Amount, &p.PaidAt); err != nil {
+			return nil, err
+		}
+		history = append(history, p)
+	}
+	return history, nil
+}
+
 // GetAllCompanies retrieves all companies from the database.
 func (s *DBStore) GetAllCompanies() ([]Company, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	rows, err := s.db.Query("SELECT code, name, description, price, related_categories FROM companies")
+	rows, err := s.db.Query("SELECT " + companyColumns + " FROM companies")
 	if err != nil {
 		return nil, err
 	}
@@ -661,15 +1179,11 @@ func (s *DBStore) GetAllCompanies() ([]Company, error) {
 
 	var companies []Company
 	for rows.Next() {
-		var c Company
-		var categoriesJSON string
-		if err := rows.Scan(&c.Code, &c.Name, &c.Description, &c.Price, &categoriesJSON); err != nil {
-			return nil, err
-		}
-		if err := json.Unmarshal([]byte(categoriesJSON), &c.RelatedCategories); err != nil {
+		c, err := scanCompany(rows)
+		if err != nil {
 			return nil, err
 		}
-		companies = append(companies, c)
+		companies = append(companies, *c)
 	}
 	return companies, nil
 }
@@ -679,22 +1193,64 @@ func (s *DBStore) GetCompanyByCode(code string) (*Company, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	var c Company
-	var categoriesJSON string
-	query := "SELECT code, name, description, price, related_categories FROM companies WHERE code = ?"
-	err := s.db.QueryRow(query, code).Scan(&c.Code, &c.Name, &c.Description, &c.Price, &categoriesJSON)
+	query := "SELECT " + companyColumns + " FROM companies WHERE code = ?"
+	c, err := scanCompany(s.db.QueryRow(query, code))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Not found
 		}
 		return nil, err
 	}
+	return c, nil
+}
+
+const companyColumns = "code, name, description, price, related_categories, book_value, revenue, accrued_revenue, shares_outstanding, payout_ratio, last_trade_price"
+
+// rowScanner は *sql.Row と *sql.Rows の共通部分です。
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanCompany(row rowScanner) (*Company, error) {
+	var c Company
+	var categoriesJSON string
+	err := row.Scan(&c.Code, &c.Name, &c.Description, &c.Price, &categoriesJSON,
+		&c.BookValue, &c.Revenue, &c.AccruedRevenue, &c.SharesOutstanding, &c.PayoutRatio, &c.LastTradePrice)
+	if err != nil {
+		return nil, err
+	}
 	if err := json.Unmarshal([]byte(categoriesJSON), &c.RelatedCategories); err != nil {
 		return nil, err
 	}
 	return &c, nil
 }
 
+// UpdateCompanyFundamentals は、株価と業績データをまとめて更新します。
+func (s *DBStore) UpdateCompanyFundamentals(companies []Company) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.Prepare("UPDATE companies SET price = ?, revenue = ?, accrued_revenue = ? WHERE code = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, c := range companies {
+		if _, err := stmt.Exec(c.Price, c.Revenue, c.AccruedRevenue, c.Code); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
 // UpdateCompanyPrices updates the prices of multiple companies in a single transaction.
 func (s *DBStore) UpdateCompanyPrices(prices map[string]float64) error {
 	s.mu.Lock()
@@ -774,6 +1330,9 @@ func (s *DBStore) GetAndResetCommandUsage() (map[string]int, error) {
 	return usage, tx.Commit()
 }
 
+// initialBookValueRatio は、初期株価に対する1株あたり純資産の割合です。
+const initialBookValueRatio = 0.6
+
 // SeedInitialCompanies は、データベースに初期の企業データを投入します。
 func (s *DBStore) SeedInitialCompanies() error {
 	s.mu.Lock()
@@ -798,7 +1357,7 @@ func (s *DBStore) SeedInitialCompanies() error {
 		return err
 	}
 
-	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO companies (code, name, description, price, related_categories) VALUES (?, ?, ?, ?, ?)`)
+	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO companies (code, name, description, price, related_categories, book_value) VALUES (?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		tx.Rollback()
 		return err
@@ -807,12 +1366,18 @@ func (s *DBStore) SeedInitialCompanies() error {
 
 	for _, company := range initialCompanies {
 		categoriesJSON, _ := json.Marshal(company.RelatedCategories)
-		_, err := stmt.Exec(company.Code, company.Name, company.Description, company.Price, string(categoriesJSON))
+		_, err := stmt.Exec(company.Code, company.Name, company.Description, company.Price, string(categoriesJSON), company.Price*initialBookValueRatio)
 		if err != nil {
 			tx.Rollback()
 			return err
 		}
 	}
 
+	// 業績データ導入前に登録された企業の純資産を補完する
+	if _, err := tx.Exec("UPDATE companies SET book_value = price * ? WHERE book_value = 0", initialBookValueRatio); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	return tx.Commit()
 }
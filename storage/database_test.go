@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// guild_id を追加する前の保有株にも、再起動時の移行で配当の入金先が割り当てられる
+func TestMigrationBackfillsPortfolioGuild(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "luna.db")
+	store, err := NewDBStore(path)
+	if err != nil {
+		t.Fatalf("NewDBStore: %v", err)
+	}
+	for _, stmt := range []string{
+		"INSERT INTO casino_data (guild_id, user_id, chips, pepecoin_balance) VALUES ('small', 'alice', 0, 10), ('main', 'alice', 0, 500)",
+		"INSERT INTO stocks_portfolios (user_id, company_code, shares, guild_id) VALUES ('alice', 'LUNA', 100, ''), ('ghost', 'LUNA', 100, '')",
+	} {
+		if _, err := store.db.Exec(stmt); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+	store.Close()
+
+	store, err = NewDBStore(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	t.Cleanup(store.Close)
+
+	payments, err := store.PayDividend("LUNA", 1)
+	if err != nil {
+		t.Fatalf("PayDividend: %v", err)
+	}
+	if len(payments) != 1 || payments[0].UserID != "alice" || payments[0].GuildID != "main" {
+		t.Errorf("payments = %+v, want one payment to alice in main", payments)
+	}
+}
@@ -0,0 +1,66 @@
+package storage
+
+import "database/sql"
+
+// DuelRecord は、サーバーでの2人のユーザーのデュエルの対戦成績です。
+// 同じ組み合わせを1行にまとめるため、UserA は常に UserB より小さいIDです。
+type DuelRecord struct {
+	GuildID string
+	UserA   string
+	UserB   string
+	AWins   int
+	BWins   int
+	Draws   int
+}
+
+// For は、userID から見た勝ち・負け・引き分けの数を返します。
+func (r *DuelRecord) For(userID string) (wins, losses, draws int) {
+	if userID == r.UserA {
+		return r.AWins, r.BWins, r.Draws
+	}
+	return r.BWins, r.AWins, r.Draws
+}
+
+// duelPair は、2人のユーザーを DuelRecord の並びに揃えます。
+func duelPair(user1, user2 string) (string, string) {
+	if user1 < user2 {
+		return user1, user2
+	}
+	return user2, user1
+}
+
+// GetDuelRecord は、2人のユーザーの対戦成績を返します。まだ対戦していなければすべて0の成績を返します。
+func (s *DBStore) GetDuelRecord(guildID, user1, user2 string) (*DuelRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r := &DuelRecord{GuildID: guildID}
+	r.UserA, r.UserB = duelPair(user1, user2)
+	err := s.db.QueryRow("SELECT a_wins, b_wins, draws FROM duel_records WHERE guild_id = ? AND user_a = ? AND user_b = ?",
+		guildID, r.UserA, r.UserB).Scan(&r.AWins, &r.BWins, &r.Draws)
+	if err == sql.ErrNoRows {
+		return r, nil
+	}
+	return r, err
+}
+
+// RecordDuel は、デュエルの結果を対戦成績に加えます。draw が true なら winnerID と loserID の引き分けとして記録します。
+func (s *DBStore) RecordDuel(guildID, winnerID, loserID string, draw bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userA, userB := duelPair(winnerID, loserID)
+	aWins, bWins, draws := 0, 0, 0
+	switch {
+	case draw:
+		draws = 1
+	case winnerID == userA:
+		aWins = 1
+	default:
+		bWins = 1
+	}
+	_, err := s.db.Exec(`INSERT INTO duel_records (guild_id, user_a, user_b, a_wins, b_wins, draws) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(guild_id, user_a, user_b) DO UPDATE SET a_wins = a_wins + excluded.a_wins, b_wins = b_wins + excluded.b_wins, draws = draws + excluded.draws`,
+		guildID, userA, userB, aWins, bWins, draws)
+	return err
+}
@@ -0,0 +1,287 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrAccountFrozen は、管理者によって口座が凍結されていることを表します。
+// 凍結された口座は、新しい賭けや取引、支払いのための引き落としができません。
+// 既に預かった賭け金の精算や返却、当選金、配当、約定代金などの入金は凍結中も加算され、解除されるまで使えません。
+var ErrAccountFrozen = errors.New("account is frozen")
+
+// 経済の管理操作の種類
+const (
+	EcoActionGive     = "give"
+	EcoActionTake     = "take"
+	EcoActionSet      = "set"
+	EcoActionReset    = "reset"
+	EcoActionFreeze   = "freeze"
+	EcoActionUnfreeze = "unfreeze"
+	EcoActionWipe     = "wipe"
+)
+
+// CurrencyShares は、以前の監査ログで株式を対象にした操作の通貨の代わりの値です。
+// 保有株はサーバーをまたいで共通のため、サーバーの管理者は株式を操作できません。
+const CurrencyShares = "shares"
+
+// TxAdminAdjust は、管理者による残高の調整を表す取引履歴の種類です。
+const TxAdminAdjust = "admin_adjust"
+
+// EconomyAuditEntry は、経済の管理操作の監査ログ1件です。
+type EconomyAuditEntry struct {
+	ID          int64
+	GuildID     string
+	ModeratorID string // 操作した管理者
+	Action      string
+	TargetID    string // ユーザーIDまたはロールID (サーバー全体の操作では空)
+	TargetRole  bool
+	Currency    string // CurrencyChips / CurrencyPPC / CurrencyShares
+	CompanyCode string // 株式の場合の銘柄 (空ならすべての銘柄)
+	Amount      int64
+	Affected    int64 // 操作の対象になったユーザー数
+	Reason      string
+	CreatedAt   time.Time
+}
+
+// insertAuditEntry は、監査ログを1件記録し、採番したIDを entry に設定します。
+func insertAuditEntry(tx *sql.Tx, entry *EconomyAuditEntry) error {
+	entry.CreatedAt = time.Now().UTC()
+	res, err := tx.Exec(`INSERT INTO economy_audit_log (guild_id, moderator_id, action, target_id, target_role, currency, company_code, amount, affected, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.GuildID, entry.ModeratorID, entry.Action, entry.TargetID, entry.TargetRole, entry.Currency, entry.CompanyCode, entry.Amount, entry.Affected, entry.Reason, entry.CreatedAt)
+	if err != nil {
+		return err
+	}
+	entry.ID, err = res.LastInsertId()
+	return err
+}
+
+// checkNotFrozen は、口座が凍結されていれば ErrAccountFrozen を返します。
+func checkNotFrozen(q queryRower, guildID, userID string) error {
+	var frozen bool
+	err := q.QueryRow("SELECT frozen FROM casino_data WHERE guild_id = ? AND user_id = ?", guildID, userID).Scan(&frozen)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if frozen {
+		return ErrAccountFrozen
+	}
+	return nil
+}
+
+// IsAccountFrozen は、口座が凍結されているかを返します。
+func (s *DBStore) IsAccountFrozen(guildID, userID string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := checkNotFrozen(s.db, guildID, userID); err != nil {
+		if errors.Is(err, ErrAccountFrozen) {
+			return true, nil
+		}
+		return false, err
+	}
+	return false, nil
+}
+
+// AdjustEconomy は、entry の操作 (give / take / set / reset) を userIDs の全員のチップかPPCに適用し、監査ログに記録します。
+// take は残高が0を下回らないように差し引きます。すべて1つのトランザクション内で行われます。
+func (s *DBStore) AdjustEconomy(entry *EconomyAuditEntry, userIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	config, err := loadEconomyConfig(tx, entry.GuildID)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		if err := ensureCasinoAccount(tx, entry.GuildID, userID); err != nil {
+			return err
+		}
+		column, err := balanceColumn(entry.Currency)
+		if err != nil {
+			return err
+		}
+		var before int64
+		if err := tx.QueryRow("SELECT "+column+" FROM casino_data WHERE guild_id = ? AND user_id = ?", entry.GuildID, userID).Scan(&before); err != nil {
+			return err
+		}
+		after := before
+		switch entry.Action {
+		case EcoActionGive:
+			after = before + entry.Amount
+		case EcoActionTake:
+			after = max(before-entry.Amount, 0)
+		case EcoActionSet:
+			after = entry.Amount
+		case EcoActionReset:
+			after = 0
+			if entry.Currency == CurrencyChips {
+				after = config.StartingChips
+			}
+		default:
+			return errors.New("unknown economy action")
+		}
+		if after == before {
+			continue
+		}
+		if _, err := tx.Exec("UPDATE casino_data SET "+column+" = ? WHERE guild_id = ? AND user_id = ?", after, entry.GuildID, userID); err != nil {
+			return err
+		}
+		if err := logTransaction(tx, entry.GuildID, userID, TxAdminAdjust, entry.Currency, after-before, entry.Reason); err != nil {
+			return err
+		}
+	}
+
+	entry.Affected = int64(len(userIDs))
+	if err := insertAuditEntry(tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetAccountFrozen は、ユーザーの口座を凍結または凍結解除し、監査ログに記録します。
+// entry.Action には EcoActionFreeze か EcoActionUnfreeze を指定します。
+func (s *DBStore) SetAccountFrozen(entry *EconomyAuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := ensureCasinoAccount(tx, entry.GuildID, entry.TargetID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE casino_data SET frozen = ? WHERE guild_id = ? AND user_id = ?",
+		entry.Action == EcoActionFreeze, entry.GuildID, entry.TargetID); err != nil {
+		return err
+	}
+	entry.Affected = 1
+	if err := insertAuditEntry(tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// WipeGuildEconomy は、サーバーの経済を初期状態に戻し、監査ログに記録します。
+// 残高・預金・ローン・空売り・注文・ゲームの預かり・インベントリ・釣りの道具と釣果と図鑑を削除し、
+// 開催中の宝くじの賞金とチケット、連続ログイン、ゲームの統計と直近の賭けの履歴、デュエルの戦績、両替レートもリセットします。
+// 預かり中のゲームが後から精算されても、消えた預かりからは何も支払われません。
+// 保有株はサーバーをまたいで共通のため削除せず、売り注文で預かっていた株も保有株に戻します。
+// ショップの商品や設定、ユーザーが決めた賭けの制限、実績、馬、取引履歴と監査ログは残ります。
+func (s *DBStore) WipeGuildEconomy(entry *EconomyAuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := tx.QueryRow("SELECT COUNT(*) FROM casino_data WHERE guild_id = ?", entry.GuildID).Scan(&entry.Affected); err != nil {
+		return err
+	}
+	// 凍結状態は残すため、casino_data は行を消さずに初期状態へ戻す
+	config, err := loadEconomyConfig(tx, entry.GuildID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE casino_data SET chips = ?, pepecoin_balance = 0 WHERE guild_id = ?", config.StartingChips, entry.GuildID); err != nil {
+		return err
+	}
+	for _, table := range []string{
+		"bank_accounts", "loans", "stock_shorts", "escrows", "inventories",
+		"fishing_profiles", "fishing_rods", "fishing_bait", "fish_catches", "fish_collection",
+		"game_stats", "gambling_activity", "duel_records", "exchange_markets",
+	} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE guild_id = ?", entry.GuildID); err != nil {
+			return err
+		}
+	}
+	// 受け取りの時刻は残し、連続ログインの記録だけを消す
+	if _, err := tx.Exec("UPDATE reward_claims SET daily_streak = 0, best_streak = 0 WHERE guild_id = ?", entry.GuildID); err != nil {
+		return err
+	}
+	// 開催中の宝くじは、売れたチケットと積み上がった賞金を消して続ける
+	if _, err := tx.Exec("DELETE FROM lottery_tickets WHERE round_id IN (SELECT id FROM lottery_rounds WHERE guild_id = ? AND status = ?)", entry.GuildID, LotteryRoundOpen); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE lottery_rounds SET pot = 0, rollover = 0, ticket_count = 0 WHERE guild_id = ? AND status = ?", entry.GuildID, LotteryRoundOpen); err != nil {
+		return err
+	}
+	if err := returnAskOrderShares(tx, entry.GuildID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE stock_orders SET status = ? WHERE guild_id = ? AND status = ?", OrderStatusCancelled, entry.GuildID, OrderStatusOpen); err != nil {
+		return err
+	}
+	if err := insertAuditEntry(tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// returnAskOrderShares は、サーバーで出された未約定の売り注文が預かっている株を保有株に戻します。
+// 買い注文が預かったPPCは、残高のリセットでまとめて消えるため戻しません。
+func returnAskOrderShares(tx *sql.Tx, guildID string) error {
+	rows, err := tx.Query("SELECT "+stockOrderColumns+" FROM stock_orders WHERE guild_id = ? AND status = ? AND side = ?", guildID, OrderStatusOpen, OrderSideAsk)
+	if err != nil {
+		return err
+	}
+	var orders []*StockOrder
+	for rows.Next() {
+		o, err := scanStockOrder(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		orders = append(orders, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, o := range orders {
+		if err := refundStockOrder(tx, o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetEconomyAuditLog は、サーバーの経済の監査ログを新しい順に返します。
+func (s *DBStore) GetEconomyAuditLog(guildID string, limit int) ([]EconomyAuditEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`SELECT id, guild_id, moderator_id, action, target_id, target_role, currency, company_code, amount, affected, reason, created_at
+		FROM economy_audit_log WHERE guild_id = ? ORDER BY id DESC LIMIT ?`, guildID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []EconomyAuditEntry
+	for rows.Next() {
+		var e EconomyAuditEntry
+		if err := rows.Scan(&e.ID, &e.GuildID, &e.ModeratorID, &e.Action, &e.TargetID, &e.TargetRole, &e.Currency, &e.CompanyCode,
+			&e.Amount, &e.Affected, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,104 @@
+package storage
+
+import "testing"
+
+// 保有株はサーバーをまたいで共通のため、最後に取引したサーバーをリセットしても消えない
+func TestWipeGuildEconomyKeepsGlobalPortfolios(t *testing.T) {
+	store := newTestStore(t)
+	chips(t, store, "alice")
+	if err := store.UpdateUserPortfolio("guild", "alice", "LUNA", 100); err != nil {
+		t.Fatalf("UpdateUserPortfolio: %v", err)
+	}
+	// 売り注文で預けた株も保有株に戻る
+	if err := store.CreateStockOrder(&StockOrder{CompanyCode: "LUNA", UserID: "alice", GuildID: "guild", Side: OrderSideAsk, Price: 10, Quantity: 30, Remaining: 30}); err != nil {
+		t.Fatalf("CreateStockOrder: %v", err)
+	}
+
+	if err := store.WipeGuildEconomy(&EconomyAuditEntry{GuildID: "guild", Action: EcoActionWipe}); err != nil {
+		t.Fatalf("WipeGuildEconomy: %v", err)
+	}
+	portfolio, err := store.GetUserPortfolio("alice")
+	if err != nil {
+		t.Fatalf("GetUserPortfolio: %v", err)
+	}
+	if got := portfolio["LUNA"]; got != 100 {
+		t.Errorf("shares after wipe = %d, want 100", got)
+	}
+	orders, err := store.GetUserOpenStockOrders("alice")
+	if err != nil || len(orders) != 0 {
+		t.Errorf("open orders after wipe = %v, %v; want none", orders, err)
+	}
+}
+
+// サーバーの管理者は、他のサーバーでも使える保有株を増減できない
+func TestAdjustEconomyRejectsShares(t *testing.T) {
+	store := newTestStore(t)
+	if err := store.UpdateUserPortfolio("other", "alice", "LUNA", 100); err != nil {
+		t.Fatalf("UpdateUserPortfolio: %v", err)
+	}
+	for _, action := range []string{EcoActionGive, EcoActionTake, EcoActionSet, EcoActionReset} {
+		entry := &EconomyAuditEntry{GuildID: "guild", Action: action, Currency: CurrencyShares, CompanyCode: "LUNA", Amount: 1000}
+		if err := store.AdjustEconomy(entry, []string{"alice"}); err == nil {
+			t.Errorf("AdjustEconomy(%s shares) succeeded, want an error", action)
+		}
+	}
+	portfolio, _ := store.GetUserPortfolio("alice")
+	if got := portfolio["LUNA"]; got != 100 {
+		t.Errorf("shares = %d, want 100", got)
+	}
+}
+
+// ワイプ後は、賞金や連続ログイン、賭けの履歴などサーバーの記録も初期状態に戻る
+func TestWipeGuildEconomyResetsGuildRecords(t *testing.T) {
+	store := newTestStore(t)
+	chips(t, store, "alice")
+	chips(t, store, "bob")
+	if _, err := store.HoldEscrow("guild", "alice", EscrowDuel, "ref", 100); err != nil {
+		t.Fatalf("HoldEscrow: %v", err)
+	}
+	if _, err := store.BuyLotteryTickets("guild", "bob", []int64{7}, 100, 10); err != nil {
+		t.Fatalf("BuyLotteryTickets: %v", err)
+	}
+	if err := store.RecordGameRound("guild", "alice", "slots", 500, 0); err != nil {
+		t.Fatalf("RecordGameRound: %v", err)
+	}
+	if _, err := store.db.Exec("INSERT INTO reward_claims (guild_id, user_id, daily_streak, best_streak) VALUES ('guild', 'alice', 9, 12)"); err != nil {
+		t.Fatalf("seed reward claims: %v", err)
+	}
+	limits := &GamblingLimits{GuildID: "guild", UserID: "alice", DailyLossLimit: 1000}
+	if err := store.SetGamblingLimits(limits); err != nil {
+		t.Fatalf("SetGamblingLimits: %v", err)
+	}
+	// 他のサーバーの記録は変わらない
+	if err := store.RecordGameRound("other", "alice", "slots", 500, 0); err != nil {
+		t.Fatalf("RecordGameRound: %v", err)
+	}
+
+	if err := store.WipeGuildEconomy(&EconomyAuditEntry{GuildID: "guild", Action: EcoActionWipe}); err != nil {
+		t.Fatalf("WipeGuildEconomy: %v", err)
+	}
+
+	counts := map[string]int64{
+		"SELECT COUNT(*) FROM escrows WHERE guild_id = 'guild'":                               0,
+		"SELECT COUNT(*) FROM lottery_tickets WHERE guild_id = 'guild'":                       0,
+		"SELECT COALESCE(SUM(pot), 0) FROM lottery_rounds WHERE guild_id = 'guild'":           0,
+		"SELECT COUNT(*) FROM game_stats WHERE guild_id = 'guild'":                            0,
+		"SELECT COUNT(*) FROM gambling_activity WHERE guild_id = 'guild'":                     0,
+		"SELECT COUNT(*) FROM exchange_markets WHERE guild_id = 'guild'":                      0,
+		"SELECT daily_streak + best_streak FROM reward_claims WHERE guild_id = 'guild'":       0,
+		"SELECT daily_loss_limit FROM gambling_limits WHERE guild_id = 'guild'":               1000,
+		"SELECT COUNT(*) FROM game_stats WHERE guild_id = 'other'":                            1,
+		"SELECT COUNT(*) FROM gambling_activity WHERE guild_id = 'other'":                     1,
+		"SELECT COUNT(*) FROM lottery_rounds WHERE guild_id = 'guild' AND status = 'open'":    1,
+		"SELECT COUNT(*) FROM economy_audit_log WHERE guild_id = 'guild' AND action = 'wipe'": 1,
+	}
+	for query, want := range counts {
+		var got int64
+		if err := store.db.QueryRow(query).Scan(&got); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		if got != want {
+			t.Errorf("%s = %d, want %d", query, got, want)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+	_ "time/tzdata" // タイムゾーンデータのない環境でもリセット時刻のタイムゾーンを読み込めるようにする
+)
+
+// 経済設定の既定値
+const (
+	DefaultStartingChips       int64   = 1000
+	DefaultDailyAmount         int64   = 100 // PPC
+	DefaultDailyCooldownHours  int64   = 24
+	DefaultPpcToChipsRate      int64   = 10
+	DefaultFishingCost         int64   = 10
+	DefaultJackpotContribution float64 = 0.01  // スロットの賭け金のうちジャックポットに積み立てる割合
+	DefaultWeeklyAmount        int64   = 500   // PPC
+	DefaultMonthlyAmount       int64   = 2500  // PPC
+	DefaultStreakBonus         int64   = 10    // 連続日数1日あたりの追加PPC
+	DefaultStreakMaxDays       int64   = 7     // 追加PPCが増えなくなる連続日数
+	DefaultStreakGraceHours    int64   = 12    // 受け取り可能になってから連続が途切れるまでの猶予
+	DefaultBankInterestRate    float64 = 0.002 // 預金に1日ごとに付く利率
+	DefaultLoanInterestRate    float64 = 0.1   // ローン1件あたりの利率
+	DefaultLoanTermDays        int64   = 7     // 借入から返済期限までの日数
+	DefaultLoanBaseCredit      int64   = 1000  // 借入履歴のないユーザーの与信枠
+	DefaultExchangeSpread      float64 = 0.04  // 両替の買値と売値の差 (仲値に対する割合)
+	DefaultSlotsRTPCeiling     float64 = 0.97  // スロットマシンに許される還元率の上限
+)
+
+// EconomyRange は、経済設定の各項目に許される値の範囲です。
+type EconomyRange struct {
+	Min, Max float64
+}
+
+// 経済設定の許容範囲
+var (
+	StartingChipsRange       = EconomyRange{1, 1000000}
+	DailyAmountRange         = EconomyRange{1, 100000}
+	DailyCooldownHoursRange  = EconomyRange{1, 168}
+	PpcToChipsRateRange      = EconomyRange{1, 1000}
+	FishingCostRange         = EconomyRange{1, 10000}
+	JackpotContributionRange = EconomyRange{0.001, 0.1}
+	WeeklyAmountRange        = EconomyRange{1, 1000000}
+	MonthlyAmountRange       = EconomyRange{1, 10000000}
+	StreakBonusRange         = EconomyRange{1, 100000}
+	StreakMaxDaysRange       = EconomyRange{1, 365}
+	StreakGraceHoursRange    = EconomyRange{1, 72}
+	BankInterestRateRange    = EconomyRange{0.0001, 0.05}
+	LoanInterestRateRange    = EconomyRange{0.01, 1}
+	LoanTermDaysRange        = EconomyRange{1, 90}
+	LoanBaseCreditRange      = EconomyRange{1, 10000000}
+	ExchangeSpreadRange      = EconomyRange{0.001, 0.5}
+	SlotsRTPCeilingRange     = EconomyRange{0.5, 1}
+)
+
+// EconomyConfig は、サーバーごとの経済バランスの設定です。
+// 未設定の項目 (ゼロ値) には既定値が使われます。
+type EconomyConfig struct {
+	StartingChips       int64   `json:"starting_chips"`
+	DailyAmount         int64   `json:"daily_amount"`
+	DailyCooldownHours  int64   `json:"daily_cooldown_hours"`
+	PpcToChipsRate      int64   `json:"ppc_to_chips_rate"`
+	FishingCost         int64   `json:"fishing_cost"`
+	JackpotContribution float64 `json:"jackpot_contribution"`
+	WeeklyAmount        int64   `json:"weekly_amount"`
+	MonthlyAmount       int64   `json:"monthly_amount"`
+	StreakBonus         int64   `json:"streak_bonus"`
+	StreakMaxDays       int64   `json:"streak_max_days"`
+	StreakGraceHours    int64   `json:"streak_grace_hours"`
+	BankInterestRate    float64 `json:"bank_interest_rate"`
+	LoanInterestRate    float64 `json:"loan_interest_rate"`
+	LoanTermDays        int64   `json:"loan_term_days"`
+	LoanBaseCredit      int64   `json:"loan_base_credit"`
+	ExchangeSpread      float64 `json:"exchange_spread"`
+	SlotsRTPCeiling     float64 `json:"slots_rtp_ceiling"`
+	// ResetTimezone が設定されている場合、定期報酬はそのタイムゾーンの0時にリセットされます。
+	// 空の場合は前回の受け取りからの経過時間で判定します。
+	ResetTimezone string `json:"reset_timezone"`
+}
+
+// applyDefaults は、未設定の項目を既定値で埋めます。
+func (c *EconomyConfig) applyDefaults() {
+	if c.StartingChips == 0 {
+		c.StartingChips = DefaultStartingChips
+	}
+	if c.DailyAmount == 0 {
+		c.DailyAmount = DefaultDailyAmount
+	}
+	if c.DailyCooldownHours == 0 {
+		c.DailyCooldownHours = DefaultDailyCooldownHours
+	}
+	if c.PpcToChipsRate == 0 {
+		c.PpcToChipsRate = DefaultPpcToChipsRate
+	}
+	if c.FishingCost == 0 {
+		c.FishingCost = DefaultFishingCost
+	}
+	if c.JackpotContribution == 0 {
+		c.JackpotContribution = DefaultJackpotContribution
+	}
+	if c.WeeklyAmount == 0 {
+		c.WeeklyAmount = DefaultWeeklyAmount
+	}
+	if c.MonthlyAmount == 0 {
+		c.MonthlyAmount = DefaultMonthlyAmount
+	}
+	if c.StreakBonus == 0 {
+		c.StreakBonus = DefaultStreakBonus
+	}
+	if c.StreakMaxDays == 0 {
+		c.StreakMaxDays = DefaultStreakMaxDays
+	}
+	if c.StreakGraceHours == 0 {
+		c.StreakGraceHours = DefaultStreakGraceHours
+	}
+	if c.BankInterestRate == 0 {
+		c.BankInterestRate = DefaultBankInterestRate
+	}
+	if c.LoanInterestRate == 0 {
+		c.LoanInterestRate = DefaultLoanInterestRate
+	}
+	if c.LoanTermDays == 0 {
+		c.LoanTermDays = DefaultLoanTermDays
+	}
+	if c.LoanBaseCredit == 0 {
+		c.LoanBaseCredit = DefaultLoanBaseCredit
+	}
+	if c.ExchangeSpread == 0 {
+		c.ExchangeSpread = DefaultExchangeSpread
+	}
+	if c.SlotsRTPCeiling == 0 {
+		c.SlotsRTPCeiling = DefaultSlotsRTPCeiling
+	}
+}
+
+// Location は、リセット時刻のタイムゾーンを返します。経過時間で判定する場合は nil を返します。
+func (c *EconomyConfig) Location() *time.Location {
+	if c.ResetTimezone == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(c.ResetTimezone)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+// Validate は、すべての項目が許容範囲内にあるかを確認します。
+func (c *EconomyConfig) Validate() error {
+	checks := []struct {
+		name  string
+		value float64
+		rng   EconomyRange
+	}{
+		{"starting_chips", float64(c.StartingChips), StartingChipsRange},
+		{"daily_amount", float64(c.DailyAmount), DailyAmountRange},
+		{"daily_cooldown_hours", float64(c.DailyCooldownHours), DailyCooldownHoursRange},
+		{"ppc_to_chips_rate", float64(c.PpcToChipsRate), PpcToChipsRateRange},
+		{"fishing_cost", float64(c.FishingCost), FishingCostRange},
+		{"jackpot_contribution", c.JackpotContribution, JackpotContributionRange},
+		{"weekly_amount", float64(c.WeeklyAmount), WeeklyAmountRange},
+		{"monthly_amount", float64(c.MonthlyAmount), MonthlyAmountRange},
+		{"streak_bonus", float64(c.StreakBonus), StreakBonusRange},
+		{"streak_max_days", float64(c.StreakMaxDays), StreakMaxDaysRange},
+		{"streak_grace_hours", float64(c.StreakGraceHours), StreakGraceHoursRange},
+		{"bank_interest_rate", c.BankInterestRate, BankInterestRateRange},
+		{"loan_interest_rate", c.LoanInterestRate, LoanInterestRateRange},
+		{"loan_term_days", float64(c.LoanTermDays), LoanTermDaysRange},
+		{"loan_base_credit", float64(c.LoanBaseCredit), LoanBaseCreditRange},
+		{"exchange_spread", c.ExchangeSpread, ExchangeSpreadRange},
+		{"slots_rtp_ceiling", c.SlotsRTPCeiling, SlotsRTPCeilingRange},
+	}
+	for _, check := range checks {
+		if check.value < check.rng.Min || check.value > check.rng.Max {
+			return fmt.Errorf("%s must be between %g and %g", check.name, check.rng.Min, check.rng.Max)
+		}
+	}
+	if c.ResetTimezone != "" {
+		if _, err := time.LoadLocation(c.ResetTimezone); err != nil {
+			return fmt.Errorf("unknown time zone %q", c.ResetTimezone)
+		}
+	}
+	return nil
+}
+
+// queryRower は、*sql.DB と *sql.Tx の共通部分です。
+type queryRower interface {
+	QueryRow(query string, args ...any) *sql.Row
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// loadEconomyConfig は、ロックを取らずに経済設定を読み込みます。呼び出し側でロックを保持してください。
+func loadEconomyConfig(q queryRower, guildID string) (*EconomyConfig, error) {
+	config := &EconomyConfig{}
+	var configJSON sql.NullString
+	err := q.QueryRow("SELECT economy_config FROM guilds WHERE guild_id = ?", guildID).Scan(&configJSON)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if configJSON.Valid && configJSON.String != "" {
+		if err := json.Unmarshal([]byte(configJSON.String), config); err != nil {
+			return nil, err
+		}
+	}
+	config.applyDefaults()
+	return config, nil
+}
+
+// GetEconomyConfig は、既定値を補ったサーバーの経済設定を返します。
+func (s *DBStore) GetEconomyConfig(guildID string) (*EconomyConfig, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return loadEconomyConfig(s.db, guildID)
+}
+
+// ensureCasinoAccount は、カジノの口座がなければサーバーの初期チップで作成します。
+func ensureCasinoAccount(q queryRower, guildID, userID string) error {
+	config, err := loadEconomyConfig(q, guildID)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec("INSERT OR IGNORE INTO casino_data (guild_id, user_id, chips, pepecoin_balance) VALUES (?, ?, ?, 0)", guildID, userID, config.StartingChips)
+	return err
+}
@@ -0,0 +1,229 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+// 預かりの取引履歴の種類
+const (
+	TxEscrowHold    = "escrow_hold"
+	TxEscrowRelease = "escrow_release"
+)
+
+// 預かりの種類
+const (
+	EscrowPoker    = "poker"
+	EscrowDuel     = "duel"
+	EscrowCrash    = "crash"
+	EscrowMines    = "mines"
+	EscrowRoulette = "roulette"
+)
+
+// Escrow は、ゲームのためにユーザーの残高から預かっているチップです。
+// ゲームの状態はメモリにしかないため、再起動時には預かったチップをそのまま返却します。
+type Escrow struct {
+	GuildID string
+	UserID  string
+	Kind    string // EscrowPoker など
+	Ref     string // 預かり先 (ポーカーとルーレットならチャンネルID、デュエルなら挑戦のID)
+}
+
+// HoldEscrow は、残高から amount を引いて預かりに加えます。預かりの合計を返します。
+func (s *DBStore) HoldEscrow(guildID, userID, kind, ref string, amount int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := debitBalance(tx, guildID, userID, CurrencyChips, amount); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`INSERT INTO escrows (guild_id, user_id, kind, ref, amount, updated_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(guild_id, user_id, kind, ref) DO UPDATE SET amount = amount + excluded.amount, updated_at = excluded.updated_at`,
+		guildID, userID, kind, ref, amount, time.Now().UTC()); err != nil {
+		return 0, err
+	}
+	if err := logTransaction(tx, guildID, userID, TxEscrowHold, CurrencyChips, -amount, kind); err != nil {
+		return 0, err
+	}
+	var total int64
+	if err := tx.QueryRow("SELECT amount FROM escrows WHERE guild_id = ? AND user_id = ? AND kind = ? AND ref = ?", guildID, userID, kind, ref).Scan(&total); err != nil {
+		return 0, err
+	}
+	return total, tx.Commit()
+}
+
+// SetEscrows は、ゲームの結果に合わせて複数のユーザーの預かりをまとめて書き換えます。
+// 預かりの増減はゲーム内のチップの移動なので、残高と取引履歴は変わりません。
+func (s *DBStore) SetEscrows(guildID, kind, ref string, amounts map[string]int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().UTC()
+	for userID, amount := range amounts {
+		if _, err := tx.Exec("UPDATE escrows SET amount = ?, updated_at = ? WHERE guild_id = ? AND user_id = ? AND kind = ? AND ref = ?",
+			amount, now, guildID, userID, kind, ref); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// releaseEscrow は、ロックを取らずに預かりを残高に戻して削除します。返却した額を返します。
+func releaseEscrow(tx *sql.Tx, guildID, userID, kind, ref string) (int64, error) {
+	var amount int64
+	err := tx.QueryRow("SELECT amount FROM escrows WHERE guild_id = ? AND user_id = ? AND kind = ? AND ref = ?", guildID, userID, kind, ref).Scan(&amount)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if amount > 0 {
+		if err := creditBalance(tx, guildID, userID, CurrencyChips, amount); err != nil {
+			return 0, err
+		}
+		if err := logTransaction(tx, guildID, userID, TxEscrowRelease, CurrencyChips, amount, kind); err != nil {
+			return 0, err
+		}
+	}
+	_, err = tx.Exec("DELETE FROM escrows WHERE guild_id = ? AND user_id = ? AND kind = ? AND ref = ?", guildID, userID, kind, ref)
+	return amount, err
+}
+
+// ReleaseEscrow は、ユーザーの預かりをすべて残高に戻します。返却した額を返します。
+func (s *DBStore) ReleaseEscrow(guildID, userID, kind, ref string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	amount, err := releaseEscrow(tx, guildID, userID, kind, ref)
+	if err != nil {
+		return 0, err
+	}
+	return amount, tx.Commit()
+}
+
+// SettleEscrow は、ユーザーの預かりをゲームの結果の amount に書き換えてから残高に戻します。
+// 同じ ref の他のユーザーの預かりはそのまま残ります。
+func (s *DBStore) SettleEscrow(guildID, userID, kind, ref string, amount int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec("UPDATE escrows SET amount = ?, updated_at = ? WHERE guild_id = ? AND user_id = ? AND kind = ? AND ref = ?",
+		amount, time.Now().UTC(), guildID, userID, kind, ref); err != nil {
+		return err
+	}
+	if _, err := releaseEscrow(tx, guildID, userID, kind, ref); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SettleEscrows は、ref の預かりをゲームの結果の amounts に書き換えてから、すべて残高に戻します。
+// 書き換えと返却は1つのトランザクションで行うため、途中で失敗してもチップが失われることはありません。
+func (s *DBStore) SettleEscrows(guildID, kind, ref string, amounts map[string]int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().UTC()
+	for userID, amount := range amounts {
+		if _, err := tx.Exec("UPDATE escrows SET amount = ?, updated_at = ? WHERE guild_id = ? AND user_id = ? AND kind = ? AND ref = ?",
+			amount, now, guildID, userID, kind, ref); err != nil {
+			return err
+		}
+	}
+	rows, err := tx.Query("SELECT user_id FROM escrows WHERE guild_id = ? AND kind = ? AND ref = ?", guildID, kind, ref)
+	if err != nil {
+		return err
+	}
+	var users []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return err
+		}
+		users = append(users, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, userID := range users {
+		if _, err := releaseEscrow(tx, guildID, userID, kind, ref); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ReleaseAllEscrows は、kind の預かりをすべて残高に戻します。起動時に、前回のプロセスで残った預かりを返すために使います。
+// 返却できなかった預かりは残し、返却した件数を返します。
+func (s *DBStore) ReleaseAllEscrows(kind string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.db.Query("SELECT guild_id, user_id, ref FROM escrows WHERE kind = ?", kind)
+	if err != nil {
+		return 0, err
+	}
+	var escrows []Escrow
+	for rows.Next() {
+		e := Escrow{Kind: kind}
+		if err := rows.Scan(&e.GuildID, &e.UserID, &e.Ref); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		escrows = append(escrows, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, e := range escrows {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return released, err
+		}
+		if _, err := releaseEscrow(tx, e.GuildID, e.UserID, e.Kind, e.Ref); err != nil {
+			_ = tx.Rollback()
+			continue
+		}
+		if err := tx.Commit(); err != nil {
+			return released, err
+		}
+		released++
+	}
+	return released, nil
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func newTestStore(t *testing.T) *DBStore {
+	t.Helper()
+	store, err := NewDBStore(filepath.Join(t.TempDir(), "luna.db"))
+	if err != nil {
+		t.Fatalf("NewDBStore: %v", err)
+	}
+	t.Cleanup(store.Close)
+	return store
+}
+
+func chips(t *testing.T, store *DBStore, userID string) int64 {
+	t.Helper()
+	data, err := store.GetCasinoData("guild", userID)
+	if err != nil {
+		t.Fatalf("GetCasinoData: %v", err)
+	}
+	return data.Chips
+}
+
+// 凍結は新しい賭けを止めるが、既に預かった賭け金の精算は凍結された口座にも入金する
+func TestSettleEscrowsCreditsFrozenAccount(t *testing.T) {
+	store := newTestStore(t)
+	start := chips(t, store, "alice")
+	chips(t, store, "bob")
+
+	for _, userID := range []string{"alice", "bob"} {
+		if _, err := store.HoldEscrow("guild", userID, EscrowDuel, "ref", 100); err != nil {
+			t.Fatalf("HoldEscrow(%s): %v", userID, err)
+		}
+	}
+	if err := store.SetAccountFrozen(&EconomyAuditEntry{GuildID: "guild", Action: EcoActionFreeze, TargetID: "alice"}); err != nil {
+		t.Fatalf("SetAccountFrozen: %v", err)
+	}
+
+	if _, err := store.HoldEscrow("guild", "alice", EscrowDuel, "next", 10); !errors.Is(err, ErrAccountFrozen) {
+		t.Errorf("HoldEscrow on frozen account: err = %v, want ErrAccountFrozen", err)
+	}
+	if err := store.SettleEscrows("guild", EscrowDuel, "ref", map[string]int64{"alice": 200, "bob": 0}); err != nil {
+		t.Fatalf("SettleEscrows with a frozen participant: %v", err)
+	}
+	if got, want := chips(t, store, "alice"), start+100; got != want {
+		t.Errorf("frozen winner chips = %d, want %d", got, want)
+	}
+	if got, want := chips(t, store, "bob"), start-100; got != want {
+		t.Errorf("loser chips = %d, want %d", got, want)
+	}
+
+	// 凍結中の口座からは引き出せない
+	data, _ := store.GetCasinoData("guild", "alice")
+	data.Chips -= 50
+	if err := store.UpdateCasinoData(data); !errors.Is(err, ErrAccountFrozen) {
+		t.Errorf("UpdateCasinoData debit on frozen account: err = %v, want ErrAccountFrozen", err)
+	}
+}
+
+func TestReleaseAllEscrowsRefundsFrozenAccount(t *testing.T) {
+	store := newTestStore(t)
+	start := chips(t, store, "alice")
+	if _, err := store.HoldEscrow("guild", "alice", EscrowPoker, "table", 300); err != nil {
+		t.Fatalf("HoldEscrow: %v", err)
+	}
+	if err := store.SetAccountFrozen(&EconomyAuditEntry{GuildID: "guild", Action: EcoActionFreeze, TargetID: "alice"}); err != nil {
+		t.Fatalf("SetAccountFrozen: %v", err)
+	}
+
+	released, err := store.ReleaseAllEscrows(EscrowPoker)
+	if err != nil || released != 1 {
+		t.Fatalf("ReleaseAllEscrows = %d, %v; want 1, nil", released, err)
+	}
+	if got := chips(t, store, "alice"); got != start {
+		t.Errorf("chips after refund = %d, want %d", got, start)
+	}
+}
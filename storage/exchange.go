@@ -0,0 +1,251 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"time"
+)
+
+// 両替の方向
+const (
+	ExchangeBuyPPC  = "buy_ppc"  // チップ → PPC
+	ExchangeSellPPC = "sell_ppc" // PPC → チップ
+)
+
+// TxExchange は、両替を表す取引履歴の種類です。
+const TxExchange = "exchange"
+
+// ErrExchangeTooSmall は、両替額が少なすぎて1単位も受け取れないことを表します。
+var ErrExchangeTooSmall = errors.New("exchange amount too small")
+
+// exchangeHistoryRetention は、レート履歴を保持する期間です。
+const exchangeHistoryRetention = 7 * 24 * time.Hour
+
+// ExchangeMarket は、サーバーのPPCの両替市場の状態です。
+// Rate は 1 PPC あたりのチップ数 (仲値) です。
+type ExchangeMarket struct {
+	GuildID      string
+	Rate         float64
+	NetFlow      int64 // 前回のレート更新以降に買われたPPC - 売られたPPC
+	Turnover     int64 // 前回のレート更新以降に売買されたPPCの合計
+	CasinoVolume int64 // 前回のレート更新以降にゲームに賭けられたチップの合計
+	UpdatedAt    time.Time
+}
+
+// ExchangeQuote は、スプレッドを含めた両替のレートです。
+type ExchangeQuote struct {
+	Mid  float64 // 仲値
+	Buy  float64 // PPCを買うときに1 PPCあたり支払うチップ
+	Sell float64 // PPCを売るときに1 PPCあたり受け取るチップ
+}
+
+// QuoteFor は、仲値とスプレッドから買値と売値を計算します。
+func QuoteFor(rate, spread float64) ExchangeQuote {
+	return ExchangeQuote{Mid: rate, Buy: rate * (1 + spread/2), Sell: rate * (1 - spread/2)}
+}
+
+// ExchangeResult は、両替の結果です。
+type ExchangeResult struct {
+	Paid     int64 // 支払った額 (買いならチップ, 売りならPPC)
+	Received int64 // 受け取った額 (買いならPPC, 売りならチップ)
+	Quote    ExchangeQuote
+}
+
+// ExchangeRatePoint は、レート履歴の1点です。
+type ExchangeRatePoint struct {
+	Rate       float64
+	RecordedAt time.Time
+}
+
+// loadExchangeMarket は、ロックを取らずに両替市場の状態を読み込みます。
+// まだ市場がなければ、サーバーの基準レートで始まる市場を返します。
+func loadExchangeMarket(q queryRower, guildID string) (*ExchangeMarket, error) {
+	market := &ExchangeMarket{GuildID: guildID}
+	err := q.QueryRow("SELECT rate, net_flow, turnover, casino_volume, updated_at FROM exchange_markets WHERE guild_id = ?", guildID).
+		Scan(&market.Rate, &market.NetFlow, &market.Turnover, &market.CasinoVolume, &market.UpdatedAt)
+	if err == sql.ErrNoRows {
+		config, err := loadEconomyConfig(q, guildID)
+		if err != nil {
+			return nil, err
+		}
+		market.Rate = float64(config.PpcToChipsRate)
+		market.UpdatedAt = time.Now().UTC()
+		return market, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return market, nil
+}
+
+// GetExchangeMarket は、サーバーの両替市場の状態を返します。
+func (s *DBStore) GetExchangeMarket(guildID string) (*ExchangeMarket, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return loadExchangeMarket(s.db, guildID)
+}
+
+// GetExchangeMarkets は、両替市場のあるすべてのサーバーの状態を返します。
+func (s *DBStore) GetExchangeMarkets() ([]ExchangeMarket, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query("SELECT guild_id, rate, net_flow, turnover, casino_volume, updated_at FROM exchange_markets")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var markets []ExchangeMarket
+	for rows.Next() {
+		var m ExchangeMarket
+		if err := rows.Scan(&m.GuildID, &m.Rate, &m.NetFlow, &m.Turnover, &m.CasinoVolume, &m.UpdatedAt); err != nil {
+			return nil, err
+		}
+		markets = append(markets, m)
+	}
+	return markets, rows.Err()
+}
+
+// ExchangeCurrency は、現在のレートとスプレッドで両替します。
+// 買い (ExchangeBuyPPC) では amount チップ以内で買えるだけのPPCを買い、売り (ExchangeSellPPC) では amount PPC を売ります。
+// 売買した量は次回のレート更新のために需給として記録されます。
+func (s *DBStore) ExchangeCurrency(guildID, userID, direction string, amount int64) (*ExchangeResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	market, err := loadExchangeMarket(tx, guildID)
+	if err != nil {
+		return nil, err
+	}
+	config, err := loadEconomyConfig(tx, guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ExchangeResult{Quote: QuoteFor(market.Rate, config.ExchangeSpread)}
+	var chips, ppc, flow int64
+	switch direction {
+	case ExchangeBuyPPC:
+		ppc = int64(math.Floor(float64(amount) / result.Quote.Buy))
+		chips = int64(math.Ceil(float64(ppc) * result.Quote.Buy))
+		if chips > amount { // 浮動小数点の誤差で予算を超えないようにする
+			ppc--
+			chips = int64(math.Ceil(float64(ppc) * result.Quote.Buy))
+		}
+		if ppc <= 0 {
+			return nil, ErrExchangeTooSmall
+		}
+		if err := debitBalance(tx, guildID, userID, CurrencyChips, chips); err != nil {
+			return nil, err
+		}
+		if err := creditBalance(tx, guildID, userID, CurrencyPPC, ppc); err != nil {
+			return nil, err
+		}
+		result.Paid, result.Received, flow = chips, ppc, ppc
+		chips = -chips
+	case ExchangeSellPPC:
+		ppc = amount
+		chips = int64(math.Floor(float64(ppc) * result.Quote.Sell))
+		if chips <= 0 {
+			return nil, ErrExchangeTooSmall
+		}
+		if err := debitBalance(tx, guildID, userID, CurrencyPPC, ppc); err != nil {
+			return nil, err
+		}
+		if err := creditBalance(tx, guildID, userID, CurrencyChips, chips); err != nil {
+			return nil, err
+		}
+		result.Paid, result.Received, flow = ppc, chips, -ppc
+		ppc = -ppc
+	default:
+		return nil, errors.New("unknown exchange direction")
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO exchange_markets (guild_id, rate, net_flow, turnover, casino_volume, updated_at) VALUES (?, ?, ?, ?, 0, ?)
+		ON CONFLICT(guild_id) DO UPDATE SET net_flow = net_flow + excluded.net_flow, turnover = turnover + excluded.turnover`,
+		guildID, market.Rate, flow, max(flow, -flow), market.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := logTransaction(tx, guildID, userID, TxExchange, CurrencyChips, chips, ""); err != nil {
+		return nil, err
+	}
+	if err := logTransaction(tx, guildID, userID, TxExchange, CurrencyPPC, ppc, ""); err != nil {
+		return nil, err
+	}
+	return result, tx.Commit()
+}
+
+// recordCasinoVolume は、ゲームに賭けられたチップを両替市場の活動量として記録します。
+func recordCasinoVolume(tx *sql.Tx, guildID string, volume int64) error {
+	if volume <= 0 {
+		return nil
+	}
+	market, err := loadExchangeMarket(tx, guildID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO exchange_markets (guild_id, rate, net_flow, turnover, casino_volume, updated_at) VALUES (?, ?, 0, 0, ?, ?)
+		ON CONFLICT(guild_id) DO UPDATE SET casino_volume = casino_volume + excluded.casino_volume`,
+		guildID, market.Rate, volume, market.UpdatedAt)
+	return err
+}
+
+// UpdateExchangeRate は、レートを更新して履歴に記録し、market の時点までに集計した需給と活動量を差し引きます。
+// 集計を読んでから更新するまでの間に行われた両替は、次回の更新に持ち越されます。
+func (s *DBStore) UpdateExchangeRate(market *ExchangeMarket, rate float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().UTC()
+	if _, err := tx.Exec(`
+		INSERT INTO exchange_markets (guild_id, rate, net_flow, turnover, casino_volume, updated_at) VALUES (?, ?, 0, 0, 0, ?)
+		ON CONFLICT(guild_id) DO UPDATE SET rate = excluded.rate, net_flow = net_flow - ?, turnover = turnover - ?, casino_volume = casino_volume - ?, updated_at = excluded.updated_at`,
+		market.GuildID, rate, now, market.NetFlow, market.Turnover, market.CasinoVolume); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO exchange_rate_history (guild_id, rate, recorded_at) VALUES (?, ?, ?)", market.GuildID, rate, now); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM exchange_rate_history WHERE guild_id = ? AND recorded_at < ?", market.GuildID, now.Add(-exchangeHistoryRetention)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetExchangeRateHistory は、since 以降のレート履歴を古い順に返します。
+func (s *DBStore) GetExchangeRateHistory(guildID string, since time.Time) ([]ExchangeRatePoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query("SELECT rate, recorded_at FROM exchange_rate_history WHERE guild_id = ? AND recorded_at >= ? ORDER BY id", guildID, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []ExchangeRatePoint
+	for rows.Next() {
+		var p ExchangeRatePoint
+		if err := rows.Scan(&p.Rate, &p.RecordedAt); err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
@@ -0,0 +1,30 @@
+package storage
+
+import "testing"
+
+// 両替レートの活動量には、ゲームの賭け金だけが数えられる
+func TestCasinoVolumeCountsOnlyGameRounds(t *testing.T) {
+	store := newTestStore(t)
+	chips(t, store, "alice")
+
+	// 釣果の売却や返金などの残高の更新は活動量に含めない
+	data, _ := store.GetCasinoData("guild", "alice")
+	data.Chips += 5000
+	if err := store.UpdateCasinoData(data); err != nil {
+		t.Fatalf("UpdateCasinoData: %v", err)
+	}
+	if err := store.RecordGameRound("guild", "alice", "slots", 300, 0); err != nil {
+		t.Fatalf("RecordGameRound: %v", err)
+	}
+	if err := store.RecordGameRound("guild", "alice", "slots", 200, 1000); err != nil {
+		t.Fatalf("RecordGameRound: %v", err)
+	}
+
+	market, err := store.GetExchangeMarket("guild")
+	if err != nil {
+		t.Fatalf("GetExchangeMarket: %v", err)
+	}
+	if market.CasinoVolume != 500 {
+		t.Errorf("casino volume = %d, want 500", market.CasinoVolume)
+	}
+}
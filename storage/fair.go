@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+// FairSeeds は、検証可能な公平性のためのユーザーのシードです。
+// ServerSeed は公開されるまで秘密にし、ユーザーには ServerSeedHash だけを見せます。
+// Prev で始まる項目は、直前に公開したシードと、そのシードで遊んだ回数です。
+type FairSeeds struct {
+	GuildID            string
+	UserID             string
+	ServerSeed         string
+	ServerSeedHash     string
+	ClientSeed         string
+	Nonce              int64 // 次のゲームに使うナンス
+	PrevServerSeed     string
+	PrevServerSeedHash string
+	PrevClientSeed     string
+	PrevNonce          int64
+	RotatedAt          sql.NullTime
+}
+
+const fairSeedColumns = "guild_id, user_id, server_seed, server_seed_hash, client_seed, nonce, prev_server_seed, prev_server_seed_hash, prev_client_seed, prev_nonce, rotated_at"
+
+func scanFairSeeds(row rowScanner) (*FairSeeds, error) {
+	f := &FairSeeds{}
+	err := row.Scan(&f.GuildID, &f.UserID, &f.ServerSeed, &f.ServerSeedHash, &f.ClientSeed, &f.Nonce,
+		&f.PrevServerSeed, &f.PrevServerSeedHash, &f.PrevClientSeed, &f.PrevNonce, &f.RotatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// GetFairSeeds は、ユーザーのシードを返します。まだなければ nil を返します。
+func (s *DBStore) GetFairSeeds(guildID, userID string) (*FairSeeds, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seeds, err := scanFairSeeds(s.db.QueryRow("SELECT "+fairSeedColumns+" FROM fair_seeds WHERE guild_id = ? AND user_id = ?", guildID, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return seeds, err
+}
+
+// CreateFairSeeds は、ユーザーの最初のシードを保存して返します。すでにシードがあれば既存のものを返します。
+func (s *DBStore) CreateFairSeeds(guildID, userID, serverSeed, serverSeedHash, clientSeed string) (*FairSeeds, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.db.Exec(`INSERT INTO fair_seeds (guild_id, user_id, server_seed, server_seed_hash, client_seed) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(guild_id, user_id) DO NOTHING`, guildID, userID, serverSeed, serverSeedHash, clientSeed); err != nil {
+		return nil, err
+	}
+	return scanFairSeeds(s.db.QueryRow("SELECT "+fairSeedColumns+" FROM fair_seeds WHERE guild_id = ? AND user_id = ?", guildID, userID))
+}
+
+// UseFairNonce は、ユーザーのナンスを1つ進め、このゲームに使うシードとナンスを返します。
+func (s *DBStore) UseFairNonce(guildID, userID string) (*FairSeeds, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seeds, err := scanFairSeeds(s.db.QueryRow("UPDATE fair_seeds SET nonce = nonce + 1 WHERE guild_id = ? AND user_id = ? RETURNING "+fairSeedColumns,
+		guildID, userID))
+	if err != nil {
+		return nil, err
+	}
+	seeds.Nonce-- // RETURNING は更新後の値を返すため、使ったナンスに戻す
+	return seeds, nil
+}
+
+// RotateFairSeeds は、現在のシードを公開済みとして Prev に移し、新しいシードに切り替えます。ナンスは0に戻ります。
+func (s *DBStore) RotateFairSeeds(guildID, userID, serverSeed, serverSeedHash, clientSeed string) (*FairSeeds, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return scanFairSeeds(s.db.QueryRow(`UPDATE fair_seeds SET
+			prev_server_seed = server_seed, prev_server_seed_hash = server_seed_hash, prev_client_seed = client_seed, prev_nonce = nonce,
+			server_seed = ?, server_seed_hash = ?, client_seed = ?, nonce = 0, rotated_at = ?
+		WHERE guild_id = ? AND user_id = ? RETURNING `+fairSeedColumns,
+		serverSeed, serverSeedHash, clientSeed, time.Now().UTC(), guildID, userID))
+}
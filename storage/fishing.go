@@ -0,0 +1,297 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrAlreadyOwned は、既に所持している装備を購入しようとしたことを表します。
+var ErrAlreadyOwned = errors.New("already owned")
+
+// FishingProfile は、ユーザーの釣り装備です。
+type FishingProfile struct {
+	GuildID    string
+	UserID     string
+	Rod        string           // 装備中の竿 (空ならデフォルトの竿)
+	Bait       string           // 装備中のエサ (空ならエサなし)
+	OwnedRods  []string         // 購入済みの竿
+	BaitCounts map[string]int64 // エサの種類ごとの所持数
+}
+
+// FishCatch は、釣り上げてまだ売っていない魚です。
+type FishCatch struct {
+	ID       int64
+	GuildID  string
+	UserID   string
+	FishID   string
+	Location string
+	Size     float64 // cm
+	Value    int64   // 売値 (チップ)
+	CaughtAt time.Time
+}
+
+// FishCollectionEntry は、図鑑に記録された魚ごとの釣果です。
+type FishCollectionEntry struct {
+	FishID        string
+	Count         int64
+	BestSize      float64
+	FirstSize     float64
+	FirstCaughtAt time.Time
+}
+
+// GetFishingProfile は、ユーザーの釣り装備と所持しているエサを返します。
+func (s *DBStore) GetFishingProfile(guildID, userID string) (*FishingProfile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	profile := &FishingProfile{GuildID: guildID, UserID: userID, BaitCounts: make(map[string]int64)}
+	err := s.db.QueryRow("SELECT rod, bait FROM fishing_profiles WHERE guild_id = ? AND user_id = ?", guildID, userID).Scan(&profile.Rod, &profile.Bait)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	rows, err := s.db.Query("SELECT rod FROM fishing_rods WHERE guild_id = ? AND user_id = ?", guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rod string
+		if err := rows.Scan(&rod); err != nil {
+			return nil, err
+		}
+		profile.OwnedRods = append(profile.OwnedRods, rod)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	baitRows, err := s.db.Query("SELECT bait, quantity FROM fishing_bait WHERE guild_id = ? AND user_id = ? AND quantity > 0", guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer baitRows.Close()
+	for baitRows.Next() {
+		var bait string
+		var quantity int64
+		if err := baitRows.Scan(&bait, &quantity); err != nil {
+			return nil, err
+		}
+		profile.BaitCounts[bait] = quantity
+	}
+	return profile, baitRows.Err()
+}
+
+// BuyFishingRod は、チップを支払って竿を購入し、そのまま装備します。
+func (s *DBStore) BuyFishingRod(guildID, userID, rodID string, price int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.Exec("INSERT OR IGNORE INTO fishing_rods (guild_id, user_id, rod) VALUES (?, ?, ?)", guildID, userID, rodID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrAlreadyOwned
+	}
+	if err := debitBalance(tx, guildID, userID, CurrencyChips, price); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO fishing_profiles (guild_id, user_id, rod) VALUES (?, ?, ?)
+		ON CONFLICT(guild_id, user_id) DO UPDATE SET rod = excluded.rod`, guildID, userID, rodID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// BuyFishingBait は、チップを支払ってエサを quantity 個購入し、そのエサを装備します。
+func (s *DBStore) BuyFishingBait(guildID, userID, baitID string, quantity, unitPrice int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := debitBalance(tx, guildID, userID, CurrencyChips, unitPrice*quantity); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO fishing_bait (guild_id, user_id, bait, quantity) VALUES (?, ?, ?, ?)
+		ON CONFLICT(guild_id, user_id, bait) DO UPDATE SET quantity = quantity + excluded.quantity`,
+		guildID, userID, baitID, quantity); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO fishing_profiles (guild_id, user_id, bait) VALUES (?, ?, ?)
+		ON CONFLICT(guild_id, user_id) DO UPDATE SET bait = excluded.bait`, guildID, userID, baitID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// EquipFishingGear は、所持している竿とエサを装備します。
+// rodID が空ならデフォルトの竿、baitID が空ならエサなしになります。
+func (s *DBStore) EquipFishingGear(guildID, userID, rodID, baitID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rodID != "" {
+		var owned int
+		if err := s.db.QueryRow("SELECT COUNT(*) FROM fishing_rods WHERE guild_id = ? AND user_id = ? AND rod = ?", guildID, userID, rodID).Scan(&owned); err != nil {
+			return err
+		}
+		if owned == 0 {
+			return ErrItemNotOwned
+		}
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO fishing_profiles (guild_id, user_id, rod, bait) VALUES (?, ?, ?, ?)
+		ON CONFLICT(guild_id, user_id) DO UPDATE SET rod = excluded.rod, bait = excluded.bait`,
+		guildID, userID, rodID, baitID)
+	return err
+}
+
+// RecordFishCatch は、釣りの代金の引き落とし・エサの消費・釣果の保存・図鑑の更新を1つのトランザクションで行います。
+// firstCatch はその魚を初めて釣ったか、newRecord は自己ベストのサイズを更新したかを表します。
+func (s *DBStore) RecordFishCatch(catch *FishCatch, cost int64, baitID string) (firstCatch, newRecord bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := debitBalance(tx, catch.GuildID, catch.UserID, CurrencyChips, cost); err != nil {
+		return false, false, err
+	}
+	if baitID != "" {
+		res, err := tx.Exec("UPDATE fishing_bait SET quantity = quantity - 1 WHERE guild_id = ? AND user_id = ? AND bait = ? AND quantity >= 1",
+			catch.GuildID, catch.UserID, baitID)
+		if err != nil {
+			return false, false, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return false, false, ErrItemNotOwned
+		}
+	}
+
+	catch.CaughtAt = time.Now()
+	res, err := tx.Exec("INSERT INTO fish_catches (guild_id, user_id, fish_id, location, size, value, caught_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		catch.GuildID, catch.UserID, catch.FishID, catch.Location, catch.Size, catch.Value, catch.CaughtAt)
+	if err != nil {
+		return false, false, err
+	}
+	if catch.ID, err = res.LastInsertId(); err != nil {
+		return false, false, err
+	}
+
+	var bestSize float64
+	err = tx.QueryRow("SELECT best_size FROM fish_collection WHERE guild_id = ? AND user_id = ? AND fish_id = ?",
+		catch.GuildID, catch.UserID, catch.FishID).Scan(&bestSize)
+	switch {
+	case err == sql.ErrNoRows:
+		firstCatch, newRecord = true, true
+		_, err = tx.Exec("INSERT INTO fish_collection (guild_id, user_id, fish_id, count, best_size, first_size, first_caught_at) VALUES (?, ?, ?, 1, ?, ?, ?)",
+			catch.GuildID, catch.UserID, catch.FishID, catch.Size, catch.Size, catch.CaughtAt)
+	case err == nil:
+		newRecord = catch.Size > bestSize
+		_, err = tx.Exec("UPDATE fish_collection SET count = count + 1, best_size = MAX(best_size, ?) WHERE guild_id = ? AND user_id = ? AND fish_id = ?",
+			catch.Size, catch.GuildID, catch.UserID, catch.FishID)
+	}
+	if err != nil {
+		return false, false, err
+	}
+
+	return firstCatch, newRecord, tx.Commit()
+}
+
+// GetFishCatches は、まだ売っていない釣果を新しい順に返します。
+func (s *DBStore) GetFishCatches(guildID, userID string) ([]FishCatch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query("SELECT id, guild_id, user_id, fish_id, location, size, value, caught_at FROM fish_catches WHERE guild_id = ? AND user_id = ? ORDER BY caught_at DESC",
+		guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var catches []FishCatch
+	for rows.Next() {
+		var c FishCatch
+		if err := rows.Scan(&c.ID, &c.GuildID, &c.UserID, &c.FishID, &c.Location, &c.Size, &c.Value, &c.CaughtAt); err != nil {
+			return nil, err
+		}
+		catches = append(catches, c)
+	}
+	return catches, rows.Err()
+}
+
+// SellFishCatches は、釣果を売却してチップに換えます。fishID が空ならすべての釣果を売却します。
+func (s *DBStore) SellFishCatches(guildID, userID, fishID string) (count, total int64, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	filter := "guild_id = ? AND user_id = ?"
+	args := []any{guildID, userID}
+	if fishID != "" {
+		filter += " AND fish_id = ?"
+		args = append(args, fishID)
+	}
+
+	if err := tx.QueryRow("SELECT COUNT(*), COALESCE(SUM(value), 0) FROM fish_catches WHERE "+filter, args...).Scan(&count, &total); err != nil {
+		return 0, 0, err
+	}
+	if count == 0 {
+		return 0, 0, nil
+	}
+	if _, err := tx.Exec("DELETE FROM fish_catches WHERE "+filter, args...); err != nil {
+		return 0, 0, err
+	}
+	if err := creditBalance(tx, guildID, userID, CurrencyChips, total); err != nil {
+		return 0, 0, err
+	}
+	return count, total, tx.Commit()
+}
+
+// GetFishCollection は、ユーザーの図鑑を魚IDをキーにして返します。
+func (s *DBStore) GetFishCollection(guildID, userID string) (map[string]FishCollectionEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query("SELECT fish_id, count, best_size, first_size, first_caught_at FROM fish_collection WHERE guild_id = ? AND user_id = ?", guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collection := make(map[string]FishCollectionEntry)
+	for rows.Next() {
+		var e FishCollectionEntry
+		if err := rows.Scan(&e.FishID, &e.Count, &e.BestSize, &e.FirstSize, &e.FirstCaughtAt); err != nil {
+			return nil, err
+		}
+		collection[e.FishID] = e
+	}
+	return collection, rows.Err()
+}
@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// 賭けの制限に触れたことを表すエラー
+var (
+	ErrSelfExcluded      = errors.New("self-excluded from gambling")
+	ErrCasinoCooldown    = errors.New("casino cooldown active")
+	ErrDailyWagerLimit   = errors.New("daily wager limit reached")
+	ErrWeeklyWagerLimit  = errors.New("weekly wager limit reached")
+	ErrDailyLossLimit    = errors.New("daily loss limit reached")
+	ErrWeeklyLossLimit   = errors.New("weekly loss limit reached")
+	ErrExclusionTooShort = errors.New("self-exclusion cannot be shortened")
+)
+
+// gamblingActivityRetention は、賭けの履歴を保持する期間です。週間の制限に必要な分だけ残します。
+const gamblingActivityRetention = 8 * 24 * time.Hour
+
+// GamblingLimits は、ユーザーが自分で設定した賭けの制限です。上限が0の項目は無制限です。
+// 1日・1週間は、それぞれ直近24時間・直近7日間で数えます。
+type GamblingLimits struct {
+	GuildID          string
+	UserID           string
+	DailyLossLimit   int64
+	WeeklyLossLimit  int64
+	DailyWagerLimit  int64
+	WeeklyWagerLimit int64
+	Cooldown         time.Duration // 1回のゲームが終わってから次に賭けられるまでの時間
+	ExcludedUntil    sql.NullTime  // 自己排除の期限
+}
+
+// Excluded は、now の時点で自己排除中かを返します。
+func (l *GamblingLimits) Excluded(now time.Time) bool {
+	return l.ExcludedUntil.Valid && now.Before(l.ExcludedUntil.Time)
+}
+
+// GamblingActivity は、制限の判定に使う直近の賭けの集計です。Lost は負けた額から勝った額を引いた値です。
+type GamblingActivity struct {
+	DailyWagered  int64
+	DailyLost     int64
+	WeeklyWagered int64
+	WeeklyLost    int64
+	LastRoundAt   sql.NullTime
+}
+
+// Check は、activity の状態から bet チップを賭けられるかを確認し、触れた制限のエラーを返します。
+// 損失の上限は、この賭けに負けた場合の損失で判定します。
+func (l *GamblingLimits) Check(activity *GamblingActivity, bet int64, now time.Time) error {
+	switch {
+	case l.Excluded(now):
+		return ErrSelfExcluded
+	case l.Cooldown > 0 && activity.LastRoundAt.Valid && now.Before(activity.LastRoundAt.Time.Add(l.Cooldown)):
+		return ErrCasinoCooldown
+	case l.DailyWagerLimit > 0 && activity.DailyWagered+bet > l.DailyWagerLimit:
+		return ErrDailyWagerLimit
+	case l.WeeklyWagerLimit > 0 && activity.WeeklyWagered+bet > l.WeeklyWagerLimit:
+		return ErrWeeklyWagerLimit
+	case l.DailyLossLimit > 0 && activity.DailyLost+bet > l.DailyLossLimit:
+		return ErrDailyLossLimit
+	case l.WeeklyLossLimit > 0 && activity.WeeklyLost+bet > l.WeeklyLossLimit:
+		return ErrWeeklyLossLimit
+	}
+	return nil
+}
+
+// GetGamblingLimits は、ユーザーの賭けの制限を返します。設定がなければすべて無制限の値を返します。
+func (s *DBStore) GetGamblingLimits(guildID, userID string) (*GamblingLimits, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	limits := &GamblingLimits{GuildID: guildID, UserID: userID}
+	var cooldownSeconds int64
+	err := s.db.QueryRow(`SELECT daily_loss_limit, weekly_loss_limit, daily_wager_limit, weekly_wager_limit, cooldown_seconds, excluded_until
+		FROM gambling_limits WHERE guild_id = ? AND user_id = ?`, guildID, userID).
+		Scan(&limits.DailyLossLimit, &limits.WeeklyLossLimit, &limits.DailyWagerLimit, &limits.WeeklyWagerLimit, &cooldownSeconds, &limits.ExcludedUntil)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	limits.Cooldown = time.Duration(cooldownSeconds) * time.Second
+	return limits, nil
+}
+
+// SetGamblingLimits は、賭けの上限とクールダウンを保存します。自己排除の期限は変更しません。
+func (s *DBStore) SetGamblingLimits(limits *GamblingLimits) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`
+		INSERT INTO gambling_limits (guild_id, user_id, daily_loss_limit, weekly_loss_limit, daily_wager_limit, weekly_wager_limit, cooldown_seconds)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(guild_id, user_id) DO UPDATE SET
+			daily_loss_limit = excluded.daily_loss_limit,
+			weekly_loss_limit = excluded.weekly_loss_limit,
+			daily_wager_limit = excluded.daily_wager_limit,
+			weekly_wager_limit = excluded.weekly_wager_limit,
+			cooldown_seconds = excluded.cooldown_seconds`,
+		limits.GuildID, limits.UserID, limits.DailyLossLimit, limits.WeeklyLossLimit, limits.DailyWagerLimit, limits.WeeklyWagerLimit,
+		int64(limits.Cooldown/time.Second))
+	return err
+}
+
+// SelfExclude は、until までユーザーをカジノゲームから自己排除します。
+// 自己排除は短縮できないため、現在の期限より前の until を指定すると ErrExclusionTooShort を返します。
+func (s *DBStore) SelfExclude(guildID, userID string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var current sql.NullTime
+	err = tx.QueryRow("SELECT excluded_until FROM gambling_limits WHERE guild_id = ? AND user_id = ?", guildID, userID).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if current.Valid && until.Before(current.Time) {
+		return ErrExclusionTooShort
+	}
+	if _, err := tx.Exec(`INSERT INTO gambling_limits (guild_id, user_id, excluded_until) VALUES (?, ?, ?)
+		ON CONFLICT(guild_id, user_id) DO UPDATE SET excluded_until = excluded.excluded_until`,
+		guildID, userID, until.UTC()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetActiveExclusions は、now の時点で自己排除中のユーザーを期限の早い順に返します。
+func (s *DBStore) GetActiveExclusions(guildID string, now time.Time) ([]GamblingLimits, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query("SELECT user_id, excluded_until FROM gambling_limits WHERE guild_id = ? AND excluded_until > ? ORDER BY excluded_until",
+		guildID, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exclusions []GamblingLimits
+	for rows.Next() {
+		l := GamblingLimits{GuildID: guildID}
+		if err := rows.Scan(&l.UserID, &l.ExcludedUntil); err != nil {
+			return nil, err
+		}
+		exclusions = append(exclusions, l)
+	}
+	return exclusions, rows.Err()
+}
+
+// GetGamblingActivity は、now から見た直近24時間と直近7日間の賭けの集計を返します。
+// 預かり中のチップは進行中のゲームの賭け金で、まだ記録されていないため、賭け金と損失の両方に加えます。
+func (s *DBStore) GetGamblingActivity(guildID, userID string, now time.Time) (*GamblingActivity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	day, week := now.Add(-24*time.Hour).UTC(), now.Add(-7*24*time.Hour).UTC()
+	activity := &GamblingActivity{}
+	err := s.db.QueryRow(`SELECT
+			COALESCE(SUM(CASE WHEN created_at >= ? THEN wagered END), 0),
+			COALESCE(SUM(CASE WHEN created_at >= ? THEN wagered - returned END), 0),
+			COALESCE(SUM(wagered), 0),
+			COALESCE(SUM(wagered - returned), 0)
+		FROM gambling_activity WHERE guild_id = ? AND user_id = ? AND created_at >= ?`,
+		day, day, guildID, userID, week).
+		Scan(&activity.DailyWagered, &activity.DailyLost, &activity.WeeklyWagered, &activity.WeeklyLost)
+	if err != nil {
+		return nil, err
+	}
+	var held int64
+	if err := s.db.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM escrows WHERE guild_id = ? AND user_id = ?", guildID, userID).Scan(&held); err != nil {
+		return nil, err
+	}
+	activity.DailyWagered += held
+	activity.DailyLost += held
+	activity.WeeklyWagered += held
+	activity.WeeklyLost += held
+	err = s.db.QueryRow("SELECT created_at FROM gambling_activity WHERE guild_id = ? AND user_id = ? ORDER BY id DESC LIMIT 1", guildID, userID).
+		Scan(&activity.LastRoundAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return activity, nil
+}
+
+// recordGamblingActivity は、制限の判定のためにゲーム1回分の賭けを記録し、古い記録を削除します。
+func recordGamblingActivity(tx *sql.Tx, guildID, userID, game string, wagered, returned int64) error {
+	now := time.Now().UTC()
+	if _, err := tx.Exec("INSERT INTO gambling_activity (guild_id, user_id, game, wagered, returned, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		guildID, userID, game, wagered, returned, now); err != nil {
+		return err
+	}
+	_, err := tx.Exec("DELETE FROM gambling_activity WHERE guild_id = ? AND user_id = ? AND created_at < ?", guildID, userID, now.Add(-gamblingActivityRetention))
+	return err
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// 預かり中の賭け金は、ゲームの結果が記録される前から制限の判定に含まれる
+func TestGamblingActivityCountsOpenEscrow(t *testing.T) {
+	store := newTestStore(t)
+	chips(t, store, "alice")
+
+	if _, err := store.HoldEscrow("guild", "alice", EscrowRoulette, "channel", 300); err != nil {
+		t.Fatalf("HoldEscrow: %v", err)
+	}
+	activity, err := store.GetGamblingActivity("guild", "alice", time.Now())
+	if err != nil {
+		t.Fatalf("GetGamblingActivity: %v", err)
+	}
+	want := GamblingActivity{DailyWagered: 300, DailyLost: 300, WeeklyWagered: 300, WeeklyLost: 300}
+	if *activity != want {
+		t.Errorf("activity = %+v, want %+v", *activity, want)
+	}
+
+	limits := &GamblingLimits{DailyLossLimit: 500}
+	if err := limits.Check(activity, 201, time.Now()); !errors.Is(err, ErrDailyLossLimit) {
+		t.Errorf("Check(201) = %v, want ErrDailyLossLimit", err)
+	}
+
+	// 精算した後は、記録された結果だけが残る
+	if err := store.SettleEscrows("guild", EscrowRoulette, "channel", map[string]int64{"alice": 100}); err != nil {
+		t.Fatalf("SettleEscrows: %v", err)
+	}
+	if err := store.RecordGameRound("guild", "alice", GameRoulette, 300, 100); err != nil {
+		t.Fatalf("RecordGameRound: %v", err)
+	}
+	activity, err = store.GetGamblingActivity("guild", "alice", time.Now())
+	if err != nil {
+		t.Fatalf("GetGamblingActivity: %v", err)
+	}
+	if activity.DailyWagered != 300 || activity.DailyLost != 200 {
+		t.Errorf("after settle: wagered %d lost %d, want 300 and 200", activity.DailyWagered, activity.DailyLost)
+	}
+}
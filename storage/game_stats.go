@@ -0,0 +1,130 @@
+package storage
+
+import "database/sql"
+
+// 統計を記録するゲームの種類
+const (
+	GameSlots     = "slots"
+	GameVideoSlot = "video_slots"
+	GameBlackjack = "blackjack"
+	GameCoinflip  = "coinflip"
+	GameHiLow     = "hilow"
+	GameHorseRace = "horserace"
+	GameQuiz      = "quiz"
+	GameFish      = "fish"
+	GamePoker     = "poker"
+	GameRoulette  = "roulette"
+	GameDuel      = "duel"
+	GameCrash     = "crash"
+	GameMines     = "mines"
+)
+
+// GameStats は、ゲームごとの賭けの統計です。
+// サーバー全体の集計では、CurrentStreak は使われず、Players に遊んだユーザー数が入ります。
+type GameStats struct {
+	Game          string
+	Rounds        int64
+	Wagered       int64 // 賭けたチップの合計
+	Returned      int64 // 配当や返金で戻ったチップの合計
+	BiggestWin    int64 // 1回の最大の勝ち額 (配当 - 賭け金)
+	CurrentStreak int64 // 現在の連勝数
+	LongestStreak int64 // 最長の連勝数
+	Players       int64
+}
+
+// Net は、ユーザーから見た収支を返します。
+func (g GameStats) Net() int64 {
+	return g.Returned - g.Wagered
+}
+
+// HouseEdge は、賭け金に対する胴元の取り分の割合を返します。
+func (g GameStats) HouseEdge() float64 {
+	if g.Wagered == 0 {
+		return 0
+	}
+	return float64(g.Wagered-g.Returned) / float64(g.Wagered)
+}
+
+// RecordGameRound は、ゲーム1回分の賭け金と戻りを統計に加算します。
+// 戻りが賭け金を上回れば勝ちとして連勝数を伸ばし、下回れば連勝を途切れさせます。引き分けは連勝数を変えません。
+// 賭けの制限の判定に使う直近の履歴にも記録され、賭け金は両替市場の活動量に加算されます。
+func (s *DBStore) RecordGameRound(guildID, userID, game string, wagered, returned int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	profit := returned - wagered
+	var win, loss int64
+	switch {
+	case profit > 0:
+		win = 1
+	case profit < 0:
+		loss = 1
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO game_stats (guild_id, user_id, game, rounds, wagered, returned, biggest_win, current_streak, longest_streak)
+		VALUES (?, ?, ?, 1, ?, ?, ?, ?, ?)
+		ON CONFLICT(guild_id, user_id, game) DO UPDATE SET
+			rounds = rounds + 1,
+			wagered = wagered + excluded.wagered,
+			returned = returned + excluded.returned,
+			biggest_win = MAX(biggest_win, excluded.biggest_win),
+			current_streak = CASE WHEN ? = 1 THEN current_streak + 1 WHEN ? = 1 THEN 0 ELSE current_streak END,
+			longest_streak = MAX(longest_streak, CASE WHEN ? = 1 THEN current_streak + 1 ELSE 0 END)`,
+		guildID, userID, game, wagered, returned, max(profit, 0), win, win, win, loss, win); err != nil {
+		return err
+	}
+	if err := recordGamblingActivity(tx, guildID, userID, game, wagered, returned); err != nil {
+		return err
+	}
+	if err := recordCasinoVolume(tx, guildID, wagered); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetGameStats は、ユーザーのゲームごとの統計を返します。
+func (s *DBStore) GetGameStats(guildID, userID string) ([]GameStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`SELECT game, rounds, wagered, returned, biggest_win, current_streak, longest_streak, 1
+		FROM game_stats WHERE guild_id = ? AND user_id = ? ORDER BY wagered DESC`, guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanGameStats(rows)
+}
+
+// GetGuildGameStats は、サーバー全体のゲームごとの統計を返します。
+func (s *DBStore) GetGuildGameStats(guildID string) ([]GameStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`SELECT game, SUM(rounds), SUM(wagered), SUM(returned), MAX(biggest_win), 0, MAX(longest_streak), COUNT(*)
+		FROM game_stats WHERE guild_id = ? GROUP BY game ORDER BY SUM(wagered) DESC`, guildID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanGameStats(rows)
+}
+
+// scanGameStats は、GameStats の列順で選択した行を読み取ります。
+func scanGameStats(rows *sql.Rows) ([]GameStats, error) {
+	var stats []GameStats
+	for rows.Next() {
+		var g GameStats
+		if err := rows.Scan(&g.Game, &g.Rounds, &g.Wagered, &g.Returned, &g.BiggestWin, &g.CurrentStreak, &g.LongestStreak, &g.Players); err != nil {
+			return nil, err
+		}
+		stats = append(stats, g)
+	}
+	return stats, rows.Err()
+}
@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// 競走馬の年齢
+const (
+	HorseDebutAge     = 2 // デビュー時の年齢
+	HorseRetireAge    = 9 // この年齢になると引退する
+	HorseRacesPerYear = 8 // 何レース走ると1歳年を取るか
+)
+
+// Horse は、サーバーの厩舎にいる競走馬です。
+// 能力値はいずれも1〜100で、年齢による衰えはレースのたびに計算します。
+type Horse struct {
+	ID          int64
+	GuildID     string
+	Name        string
+	Emoji       string
+	Speed       int // 最高速度
+	Stamina     int // 終盤の粘り
+	Consistency int // 走りのムラの少なさ
+	Starts      int
+	Wins        int
+	Top3        int // 3着以内に入った回数
+	Retired     bool
+	CreatedAt   time.Time
+}
+
+// Age は、出走回数から求めた馬の年齢です。
+func (h *Horse) Age() int {
+	return HorseDebutAge + h.Starts/HorseRacesPerYear
+}
+
+// HorseResult は、1頭の1レース分の着順です。
+type HorseResult struct {
+	HorseID   int64
+	Position  int // 1着なら1
+	FieldSize int // 出走頭数
+	RacedAt   time.Time
+}
+
+// 定時レースの設定の既定値
+const (
+	DefaultHorseRaceSpec           = "0 21 * * *"
+	DefaultHorseRaceBettingMinutes = 3
+)
+
+// HorseRaceSchedule は、サーバーごとの定時レースの設定です。
+type HorseRaceSchedule struct {
+	Enabled        bool   `json:"enabled"`
+	ChannelID      string `json:"channel_id"`      // レースを開催するチャンネル
+	Spec           string `json:"spec"`            // 開催時刻 (cron 形式, サーバーのタイムゾーン)
+	BettingMinutes int    `json:"betting_minutes"` // ベット受付を締め切るまでの時間
+}
+
+// ApplyDefaults は、未設定の項目を既定値で埋めます。
+func (c *HorseRaceSchedule) ApplyDefaults() {
+	if c.Spec == "" {
+		c.Spec = DefaultHorseRaceSpec
+	}
+	if c.BettingMinutes == 0 {
+		c.BettingMinutes = DefaultHorseRaceBettingMinutes
+	}
+}
+
+const horseColumns = "id, guild_id, name, emoji, speed, stamina, consistency, starts, wins, top3, retired, created_at"
+
+func scanHorse(row rowScanner) (*Horse, error) {
+	h := &Horse{}
+	err := row.Scan(&h.ID, &h.GuildID, &h.Name, &h.Emoji, &h.Speed, &h.Stamina, &h.Consistency, &h.Starts, &h.Wins, &h.Top3, &h.Retired, &h.CreatedAt)
+	return h, err
+}
+
+// GetStable は、サーバーの引退していない馬を古い順に返します。
+func (s *DBStore) GetStable(guildID string) ([]Horse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query("SELECT "+horseColumns+" FROM horses WHERE guild_id = ? AND retired = 0 ORDER BY id", guildID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var horses []Horse
+	for rows.Next() {
+		h, err := scanHorse(rows)
+		if err != nil {
+			return nil, err
+		}
+		horses = append(horses, *h)
+	}
+	return horses, rows.Err()
+}
+
+// GetHorseByName は、名前で馬を探します。引退した馬も含め、同じ名前の馬がいれば新しい方を返します。
+// 見つからなければ nil を返します。
+func (s *DBStore) GetHorseByName(guildID, name string) (*Horse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	h, err := scanHorse(s.db.QueryRow("SELECT "+horseColumns+" FROM horses WHERE guild_id = ? AND name = ? ORDER BY id DESC LIMIT 1", guildID, name))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return h, err
+}
+
+// AddHorse は、厩舎に新しい馬を加え、h.ID を設定します。
+func (s *DBStore) AddHorse(h *Horse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h.CreatedAt = time.Now().UTC()
+	result, err := s.db.Exec("INSERT INTO horses (guild_id, name, emoji, speed, stamina, consistency, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		h.GuildID, h.Name, h.Emoji, h.Speed, h.Stamina, h.Consistency, h.CreatedAt)
+	if err != nil {
+		return err
+	}
+	h.ID, err = result.LastInsertId()
+	return err
+}
+
+// RetireHorse は、馬を引退させます。成績は履歴として残ります。
+func (s *DBStore) RetireHorse(guildID string, horseID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec("UPDATE horses SET retired = 1 WHERE guild_id = ? AND id = ?", guildID, horseID)
+	return err
+}
+
+// RecordHorseRace は、1レース分の全馬の着順を保存し、通算成績を更新します。
+func (s *DBStore) RecordHorseRace(guildID string, results []HorseResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, r := range results {
+		if _, err := tx.Exec("INSERT INTO horse_results (guild_id, horse_id, position, field_size, raced_at) VALUES (?, ?, ?, ?, ?)",
+			guildID, r.HorseID, r.Position, r.FieldSize, r.RacedAt); err != nil {
+			return err
+		}
+		win, top3 := 0, 0
+		if r.Position == 1 {
+			win = 1
+		}
+		if r.Position <= 3 {
+			top3 = 1
+		}
+		if _, err := tx.Exec("UPDATE horses SET starts = starts + 1, wins = wins + ?, top3 = top3 + ? WHERE guild_id = ? AND id = ?",
+			win, top3, guildID, r.HorseID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetHorseResults は、馬の直近 limit レースの着順を新しい順に返します。
+func (s *DBStore) GetHorseResults(guildID string, horseID int64, limit int) ([]HorseResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query("SELECT horse_id, position, field_size, raced_at FROM horse_results WHERE guild_id = ? AND horse_id = ? ORDER BY id DESC LIMIT ?",
+		guildID, horseID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []HorseResult
+	for rows.Next() {
+		var r HorseResult
+		if err := rows.Scan(&r.HorseID, &r.Position, &r.FieldSize, &r.RacedAt); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// GetHorseRaceSchedules は、定時レースの設定があるすべてのサーバーの設定を返します。
+func (s *DBStore) GetHorseRaceSchedules() (map[string]HorseRaceSchedule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query("SELECT guild_id, horserace_schedule FROM guilds WHERE horserace_schedule IS NOT NULL AND horserace_schedule != '' AND horserace_schedule != '{}'")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := make(map[string]HorseRaceSchedule)
+	for rows.Next() {
+		var guildID, configJSON string
+		if err := rows.Scan(&guildID, &configJSON); err != nil {
+			return nil, err
+		}
+		var schedule HorseRaceSchedule
+		if err := json.Unmarshal([]byte(configJSON), &schedule); err != nil {
+			continue
+		}
+		schedule.ApplyDefaults()
+		schedules[guildID] = schedule
+	}
+	return schedules, rows.Err()
+}
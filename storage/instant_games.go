@@ -0,0 +1,48 @@
+package storage
+
+import "fmt"
+
+// インスタントゲームのハウスエッジの既定値
+const (
+	DefaultCrashHouseEdge float64 = 0.01
+	DefaultMinesHouseEdge float64 = 0.01
+)
+
+// インスタントゲームの設定に許される値の範囲
+var (
+	HouseEdgeRange  = EconomyRange{0.001, 0.1}
+	MinesCountRange = EconomyRange{1, 24}
+)
+
+// InstantGamesConfig は、サーバーごとのクラッシュとマインズの設定です。
+// 未設定の項目 (ゼロ値) には既定値が使われます。
+type InstantGamesConfig struct {
+	CrashHouseEdge float64 `json:"crash_house_edge"` // 還元率は 1 - ハウスエッジ
+	MinesHouseEdge float64 `json:"mines_house_edge"`
+}
+
+// ApplyDefaults は、未設定の項目を既定値で埋めます。
+func (c *InstantGamesConfig) ApplyDefaults() {
+	if c.CrashHouseEdge == 0 {
+		c.CrashHouseEdge = DefaultCrashHouseEdge
+	}
+	if c.MinesHouseEdge == 0 {
+		c.MinesHouseEdge = DefaultMinesHouseEdge
+	}
+}
+
+// Validate は、すべての項目が許容範囲内にあるかを確認します。
+func (c *InstantGamesConfig) Validate() error {
+	for _, f := range []struct {
+		name  string
+		value float64
+	}{
+		{"crash_house_edge", c.CrashHouseEdge},
+		{"mines_house_edge", c.MinesHouseEdge},
+	} {
+		if f.value < HouseEdgeRange.Min || f.value > HouseEdgeRange.Max {
+			return fmt.Errorf("%s must be between %g and %g", f.name, HouseEdgeRange.Min, HouseEdgeRange.Max)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,327 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrTicketLimit は、1ラウンドで購入できるチケットの上限を超えることを表します。
+var ErrTicketLimit = errors.New("lottery ticket limit reached")
+
+// 宝くじの取引履歴の種類
+const (
+	TxLotteryTicket = "lottery_ticket"
+	TxLotteryWin    = "lottery_win"
+)
+
+// 宝くじのラウンドの状態
+const (
+	LotteryRoundOpen  = "open"
+	LotteryRoundDrawn = "drawn"
+)
+
+// 宝くじの設定の既定値
+const (
+	DefaultLotteryTicketPrice int64  = 100
+	DefaultLotteryMaxNumber   int64  = 100
+	DefaultLotteryMaxTickets  int64  = 10
+	DefaultLotteryDrawTime    string = "21:00"
+)
+
+// LotteryConfig は、サーバーごとの宝くじの設定です。
+type LotteryConfig struct {
+	Enabled     bool   `json:"enabled"`
+	ChannelID   string `json:"channel_id"` // 抽選結果を発表するチャンネル
+	DrawTime    string `json:"draw_time"`  // 毎日の抽選時刻 (HH:MM)
+	TicketPrice int64  `json:"ticket_price"`
+	MaxNumber   int64  `json:"max_number"`  // 選べる番号の上限 (1〜MaxNumber)
+	MaxTickets  int64  `json:"max_tickets"` // 1人が1ラウンドで買えるチケットの上限
+}
+
+// ApplyDefaults は、未設定の項目を既定値で埋めます。
+func (c *LotteryConfig) ApplyDefaults() {
+	if c.DrawTime == "" {
+		c.DrawTime = DefaultLotteryDrawTime
+	}
+	if c.TicketPrice == 0 {
+		c.TicketPrice = DefaultLotteryTicketPrice
+	}
+	if c.MaxNumber == 0 {
+		c.MaxNumber = DefaultLotteryMaxNumber
+	}
+	if c.MaxTickets == 0 {
+		c.MaxTickets = DefaultLotteryMaxTickets
+	}
+}
+
+// LotteryRound は、宝くじの1回分です。
+type LotteryRound struct {
+	ID            int64
+	GuildID       string
+	Status        string
+	Pot           int64 // 繰り越しを含む賞金の総額
+	Rollover      int64 // 前のラウンドから繰り越された額
+	TicketCount   int64
+	WinningNumber sql.NullInt64
+	PayoutPerWin  int64 // 当選チケット1枚あたりの賞金
+	WinnerCount   int64 // 当選チケットの枚数
+	OpenedAt      time.Time
+	DrawnAt       sql.NullTime
+}
+
+// LotteryTicket は、購入された宝くじのチケット1枚です。
+type LotteryTicket struct {
+	ID       int64
+	RoundID  int64
+	GuildID  string
+	UserID   string
+	Number   int64
+	Price    int64
+	BoughtAt time.Time
+}
+
+// LotteryWinner は、抽選で当選したユーザーと受け取った賞金です。
+type LotteryWinner struct {
+	UserID  string
+	Tickets int64
+	Payout  int64
+}
+
+// LotteryDraw は、抽選の結果です。
+type LotteryDraw struct {
+	Round     *LotteryRound // 抽選したラウンド
+	Winners   []LotteryWinner
+	NextRound *LotteryRound // 繰り越し先の新しいラウンド
+}
+
+const lotteryRoundColumns = "id, guild_id, status, pot, rollover, ticket_count, winning_number, payout_per_win, winner_count, opened_at, drawn_at"
+
+func scanLotteryRound(row rowScanner) (*LotteryRound, error) {
+	var r LotteryRound
+	if err := row.Scan(&r.ID, &r.GuildID, &r.Status, &r.Pot, &r.Rollover, &r.TicketCount, &r.WinningNumber,
+		&r.PayoutPerWin, &r.WinnerCount, &r.OpenedAt, &r.DrawnAt); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// openLotteryRound は、開催中のラウンドを返します。なければ nil を返します。
+func openLotteryRound(q queryRower, guildID string) (*LotteryRound, error) {
+	round, err := scanLotteryRound(q.QueryRow("SELECT "+lotteryRoundColumns+" FROM lottery_rounds WHERE guild_id = ? AND status = ?", guildID, LotteryRoundOpen))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return round, err
+}
+
+// createLotteryRound は、rollover を賞金の初期値とする新しいラウンドを開始します。
+func createLotteryRound(tx *sql.Tx, guildID string, rollover int64) (*LotteryRound, error) {
+	round := &LotteryRound{GuildID: guildID, Status: LotteryRoundOpen, Pot: rollover, Rollover: rollover, OpenedAt: time.Now().UTC()}
+	res, err := tx.Exec("INSERT INTO lottery_rounds (guild_id, status, pot, rollover, opened_at) VALUES (?, ?, ?, ?, ?)",
+		round.GuildID, round.Status, round.Pot, round.Rollover, round.OpenedAt)
+	if err != nil {
+		return nil, err
+	}
+	round.ID, err = res.LastInsertId()
+	return round, err
+}
+
+// GetLotteryConfigs は、宝くじの設定があるすべてのサーバーの設定を返します。
+func (s *DBStore) GetLotteryConfigs() (map[string]LotteryConfig, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query("SELECT guild_id, lottery_config FROM guilds WHERE lottery_config IS NOT NULL AND lottery_config != '' AND lottery_config != '{}'")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	configs := make(map[string]LotteryConfig)
+	for rows.Next() {
+		var guildID, configJSON string
+		if err := rows.Scan(&guildID, &configJSON); err != nil {
+			return nil, err
+		}
+		var config LotteryConfig
+		if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+			continue
+		}
+		config.ApplyDefaults()
+		configs[guildID] = config
+	}
+	return configs, rows.Err()
+}
+
+// GetOpenLotteryRound は、開催中のラウンドを返します。開催中のラウンドがなければ nil を返します。
+func (s *DBStore) GetOpenLotteryRound(guildID string) (*LotteryRound, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return openLotteryRound(s.db, guildID)
+}
+
+// BuyLotteryTickets は、開催中のラウンドのチケットを numbers の番号で1枚ずつ購入します。
+// 開催中のラウンドがなければ新しく開始します。代金はすべて賞金に加算されます。
+func (s *DBStore) BuyLotteryTickets(guildID, userID string, numbers []int64, price, maxTickets int64) (*LotteryRound, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	round, err := openLotteryRound(tx, guildID)
+	if err != nil {
+		return nil, err
+	}
+	if round == nil {
+		if round, err = createLotteryRound(tx, guildID, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	var owned int64
+	if err := tx.QueryRow("SELECT COUNT(*) FROM lottery_tickets WHERE round_id = ? AND user_id = ?", round.ID, userID).Scan(&owned); err != nil {
+		return nil, err
+	}
+	if owned+int64(len(numbers)) > maxTickets {
+		return nil, ErrTicketLimit
+	}
+
+	cost := price * int64(len(numbers))
+	if err := debitBalance(tx, guildID, userID, CurrencyChips, cost); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	for _, number := range numbers {
+		if _, err := tx.Exec("INSERT INTO lottery_tickets (round_id, guild_id, user_id, number, price, bought_at) VALUES (?, ?, ?, ?, ?, ?)",
+			round.ID, guildID, userID, number, price, now); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.Exec("UPDATE lottery_rounds SET pot = pot + ?, ticket_count = ticket_count + ? WHERE id = ?", cost, len(numbers), round.ID); err != nil {
+		return nil, err
+	}
+	if err := logTransaction(tx, guildID, userID, TxLotteryTicket, CurrencyChips, -cost, ""); err != nil {
+		return nil, err
+	}
+	round.Pot += cost
+	round.TicketCount += int64(len(numbers))
+	return round, tx.Commit()
+}
+
+// GetLotteryTickets は、ラウンドでユーザーが購入したチケットを返します。
+func (s *DBStore) GetLotteryTickets(roundID int64, userID string) ([]LotteryTicket, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query("SELECT id, round_id, guild_id, user_id, number, price, bought_at FROM lottery_tickets WHERE round_id = ? AND user_id = ? ORDER BY number",
+		roundID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tickets []LotteryTicket
+	for rows.Next() {
+		var t LotteryTicket
+		if err := rows.Scan(&t.ID, &t.RoundID, &t.GuildID, &t.UserID, &t.Number, &t.Price, &t.BoughtAt); err != nil {
+			return nil, err
+		}
+		tickets = append(tickets, t)
+	}
+	return tickets, rows.Err()
+}
+
+// DrawLottery は、開催中のラウンドを winningNumber で抽選します。
+// 当選チケットで賞金を等分し、割り切れない端数と当選者がいない場合の賞金は次のラウンドに繰り越されます。
+// 開催中のラウンドがなければ nil を返します。
+func (s *DBStore) DrawLottery(guildID string, winningNumber int64) (*LotteryDraw, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	round, err := openLotteryRound(tx, guildID)
+	if err != nil || round == nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query("SELECT user_id, COUNT(*) FROM lottery_tickets WHERE round_id = ? AND number = ? GROUP BY user_id ORDER BY COUNT(*) DESC", round.ID, winningNumber)
+	if err != nil {
+		return nil, err
+	}
+	draw := &LotteryDraw{Round: round}
+	for rows.Next() {
+		var w LotteryWinner
+		if err := rows.Scan(&w.UserID, &w.Tickets); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		draw.Winners = append(draw.Winners, w)
+		round.WinnerCount += w.Tickets
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rollover := round.Pot
+	if round.WinnerCount > 0 {
+		round.PayoutPerWin = round.Pot / round.WinnerCount
+		rollover = round.Pot - round.PayoutPerWin*round.WinnerCount
+		for idx := range draw.Winners {
+			w := &draw.Winners[idx]
+			w.Payout = round.PayoutPerWin * w.Tickets
+			if err := creditBalance(tx, guildID, w.UserID, CurrencyChips, w.Payout); err != nil {
+				return nil, err
+			}
+			if err := logTransaction(tx, guildID, w.UserID, TxLotteryWin, CurrencyChips, w.Payout, ""); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	round.Status = LotteryRoundDrawn
+	round.WinningNumber = sql.NullInt64{Int64: winningNumber, Valid: true}
+	round.DrawnAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	if _, err := tx.Exec("UPDATE lottery_rounds SET status = ?, winning_number = ?, payout_per_win = ?, winner_count = ?, drawn_at = ? WHERE id = ?",
+		round.Status, round.WinningNumber, round.PayoutPerWin, round.WinnerCount, round.DrawnAt, round.ID); err != nil {
+		return nil, err
+	}
+	if draw.NextRound, err = createLotteryRound(tx, guildID, rollover); err != nil {
+		return nil, err
+	}
+	return draw, tx.Commit()
+}
+
+// GetLotteryHistory は、抽選済みのラウンドを新しい順に返します。
+func (s *DBStore) GetLotteryHistory(guildID string, limit int) ([]LotteryRound, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query("SELECT "+lotteryRoundColumns+" FROM lottery_rounds WHERE guild_id = ? AND status = ? ORDER BY id DESC LIMIT ?",
+		guildID, LotteryRoundDrawn, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rounds []LotteryRound
+	for rows.Next() {
+		r, err := scanLotteryRound(rows)
+		if err != nil {
+			return nil, err
+		}
+		rounds = append(rounds, *r)
+	}
+	return rounds, rows.Err()
+}
@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrAlreadyClaimed は、報酬を今の期間内に受け取り済みであることを表します。
+var ErrAlreadyClaimed = errors.New("reward already claimed")
+
+// 定期報酬の種類
+const (
+	RewardDaily   = "daily"
+	RewardWeekly  = "weekly"
+	RewardMonthly = "monthly"
+)
+
+// デイリーボーナスのリマインダーの通知方法
+const (
+	ReminderOff     = ""
+	ReminderDM      = "dm"
+	ReminderChannel = "channel"
+)
+
+// RewardStatus は、ユーザーの定期報酬の受け取り状況です。
+type RewardStatus struct {
+	GuildID           string
+	UserID            string
+	LastDaily         sql.NullTime
+	LastWeekly        sql.NullTime
+	LastMonthly       sql.NullTime
+	DailyStreak       int64
+	BestStreak        int64
+	Reminder          string // ReminderOff / ReminderDM / ReminderChannel
+	ReminderChannelID string
+	Reminded          bool // 今回の受け取り可能期間で通知済みかどうか
+}
+
+const rewardStatusQuery = `
+	SELECT c.guild_id, c.user_id, c.last_daily, r.last_weekly, r.last_monthly,
+		COALESCE(r.daily_streak, 0), COALESCE(r.best_streak, 0), COALESCE(r.reminder, ''), COALESCE(r.reminder_channel_id, ''), COALESCE(r.reminded, 0)
+	FROM casino_data c LEFT JOIN reward_claims r ON r.guild_id = c.guild_id AND r.user_id = c.user_id`
+
+func scanRewardStatus(row rowScanner) (*RewardStatus, error) {
+	var st RewardStatus
+	if err := row.Scan(&st.GuildID, &st.UserID, &st.LastDaily, &st.LastWeekly, &st.LastMonthly,
+		&st.DailyStreak, &st.BestStreak, &st.Reminder, &st.ReminderChannelID, &st.Reminded); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// GetRewardStatus は、ユーザーの定期報酬の受け取り状況を返します。
+func (s *DBStore) GetRewardStatus(guildID, userID string) (*RewardStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ensureCasinoAccount(s.db, guildID, userID); err != nil {
+		return nil, err
+	}
+	return scanRewardStatus(s.db.QueryRow(rewardStatusQuery+" WHERE c.guild_id = ? AND c.user_id = ?", guildID, userID))
+}
+
+// ClaimReward は、kind の報酬として amount PPC を付与します。
+// 前回の受け取りが periodStart より後なら ErrAlreadyClaimed を返し、何も変更しません。
+// デイリーの場合は streak を連続ログイン日数として記録します。
+func (s *DBStore) ClaimReward(guildID, userID, kind string, amount int64, periodStart time.Time, streak int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := ensureCasinoAccount(tx, guildID, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT OR IGNORE INTO reward_claims (guild_id, user_id) VALUES (?, ?)", guildID, userID); err != nil {
+		return err
+	}
+
+	var last sql.NullTime
+	var query string
+	switch kind {
+	case RewardDaily:
+		query = "SELECT last_daily FROM casino_data WHERE guild_id = ? AND user_id = ?"
+	case RewardWeekly:
+		query = "SELECT last_weekly FROM reward_claims WHERE guild_id = ? AND user_id = ?"
+	case RewardMonthly:
+		query = "SELECT last_monthly FROM reward_claims WHERE guild_id = ? AND user_id = ?"
+	default:
+		return errors.New("unknown reward kind")
+	}
+	if err := tx.QueryRow(query, guildID, userID).Scan(&last); err != nil {
+		return err
+	}
+	// ストアのロックを保持したまま確認と更新を行うため、同時に実行されても二重に受け取ることはない
+	if last.Valid && !last.Time.Before(periodStart) {
+		return ErrAlreadyClaimed
+	}
+
+	now := time.Now().UTC()
+	switch kind {
+	case RewardDaily:
+		if _, err := tx.Exec("UPDATE casino_data SET last_daily = ? WHERE guild_id = ? AND user_id = ?", now, guildID, userID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE reward_claims SET daily_streak = ?, best_streak = MAX(best_streak, ?), reminded = 0 WHERE guild_id = ? AND user_id = ?",
+			streak, streak, guildID, userID); err != nil {
+			return err
+		}
+	case RewardWeekly:
+		if _, err := tx.Exec("UPDATE reward_claims SET last_weekly = ? WHERE guild_id = ? AND user_id = ?", now, guildID, userID); err != nil {
+			return err
+		}
+	case RewardMonthly:
+		if _, err := tx.Exec("UPDATE reward_claims SET last_monthly = ? WHERE guild_id = ? AND user_id = ?", now, guildID, userID); err != nil {
+			return err
+		}
+	}
+
+	if err := creditBalance(tx, guildID, userID, CurrencyPPC, amount); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetDailyReminder は、デイリーボーナスのリマインダーの通知方法を設定します。
+func (s *DBStore) SetDailyReminder(guildID, userID, mode, channelID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`
+		INSERT INTO reward_claims (guild_id, user_id, reminder, reminder_channel_id) VALUES (?, ?, ?, ?)
+		ON CONFLICT(guild_id, user_id) DO UPDATE SET reminder = excluded.reminder, reminder_channel_id = excluded.reminder_channel_id`,
+		guildID, userID, mode, channelID)
+	return err
+}
+
+// GetPendingDailyReminders は、リマインダーを有効にしていて、まだ通知していないユーザーを返します。
+func (s *DBStore) GetPendingDailyReminders() ([]RewardStatus, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(rewardStatusQuery + " WHERE r.reminder != '' AND r.reminded = 0")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statuses []RewardStatus
+	for rows.Next() {
+		st, err := scanRewardStatus(rows)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, *st)
+	}
+	return statuses, rows.Err()
+}
+
+// MarkDailyReminded は、今回の受け取り可能期間の通知を送信済みにします。
+func (s *DBStore) MarkDailyReminded(guildID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec("UPDATE reward_claims SET reminded = 1 WHERE guild_id = ? AND user_id = ?", guildID, userID)
+	return err
+}
@@ -0,0 +1,353 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrItemNotFound は、指定したアイテムがショップに存在しないことを表します。
+var ErrItemNotFound = errors.New("item not found")
+
+// ErrOutOfStock は、アイテムの在庫が足りず購入できなかったことを表します。
+var ErrOutOfStock = errors.New("item out of stock")
+
+// ErrPurchaseTooLarge は、購入代金が大きすぎて計算できないことを表します。
+var ErrPurchaseTooLarge = errors.New("purchase too large")
+
+// ErrItemNotOwned は、インベントリに指定した数のアイテムがないことを表します。
+var ErrItemNotOwned = errors.New("item not owned")
+
+// 価格の通貨
+const (
+	CurrencyChips = "chips"
+	CurrencyPPC   = "ppc"
+)
+
+// 消費アイテムの効果
+const (
+	ItemEffectNone  = ""      // 効果なし（コレクション用）
+	ItemEffectChips = "chips" // 使用するとチップを獲得
+	ItemEffectPPC   = "ppc"   // 使用するとPPCを獲得
+)
+
+// UnlimitedStock は、在庫制限のないアイテムの Stock 値です。
+const UnlimitedStock int64 = -1
+
+// MaxPurchaseQuantity は、1回の購入で買えるアイテムの個数の上限です。
+const MaxPurchaseQuantity = 1000
+
+// ShopItem は、サーバーのショップで販売されるアイテムです。
+type ShopItem struct {
+	ID          int64
+	GuildID     string
+	Name        string
+	Description string
+	Price       int64
+	Currency    string // CurrencyChips or CurrencyPPC
+	Stock       int64  // 残り在庫 (UnlimitedStock で無制限)
+	RoleID      string // 購入時に付与するロール (空なら付与しない)
+	Consumable  bool   // /use で消費できるかどうか
+	Effect      string
+	EffectValue int64
+	CreatedAt   time.Time
+}
+
+// InventoryItem は、ユーザーが所持しているアイテムとその個数です。
+type InventoryItem struct {
+	Item     ShopItem
+	Quantity int64
+}
+
+const shopItemColumns = "id, guild_id, name, description, price, currency, stock, role_id, consumable, effect, effect_value, created_at"
+
+func scanShopItem(row rowScanner) (*ShopItem, error) {
+	var item ShopItem
+	if err := row.Scan(&item.ID, &item.GuildID, &item.Name, &item.Description, &item.Price, &item.Currency, &item.Stock,
+		&item.RoleID, &item.Consumable, &item.Effect, &item.EffectValue, &item.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// balanceColumn は、通貨に対応する casino_data のカラム名を返します。
+func balanceColumn(currency string) (string, error) {
+	switch currency {
+	case CurrencyChips:
+		return "chips", nil
+	case CurrencyPPC:
+		return "pepecoin_balance", nil
+	}
+	return "", errors.New("unknown currency")
+}
+
+// debitBalance は、残高が足りる場合に限り amount を引き落とします。
+// 条件付きUPDATEで引き落とすため、同時に実行されても残高がマイナスになることはありません。
+func debitBalance(tx *sql.Tx, guildID, userID, currency string, amount int64) error {
+	column, err := balanceColumn(currency)
+	if err != nil {
+		return err
+	}
+	if err := ensureCasinoAccount(tx, guildID, userID); err != nil {
+		return err
+	}
+	if err := checkNotFrozen(tx, guildID, userID); err != nil {
+		return err
+	}
+	res, err := tx.Exec("UPDATE casino_data SET "+column+" = "+column+" - ? WHERE guild_id = ? AND user_id = ? AND "+column+" >= ?",
+		amount, guildID, userID, amount)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrInsufficientFunds
+	}
+	return nil
+}
+
+// creditBalance は、amount を残高に加算します。凍結された口座にも加算します。
+// 延滞中のローンがある場合は、先に返済に充当した残りを加算します。
+func creditBalance(tx *sql.Tx, guildID, userID, currency string, amount int64) error {
+	column, err := balanceColumn(currency)
+	if err != nil {
+		return err
+	}
+	if err := ensureCasinoAccount(tx, guildID, userID); err != nil {
+		return err
+	}
+	collected, err := collectOverdueLoan(tx, guildID, userID, currency, amount)
+	if err != nil {
+		return err
+	}
+	amount -= collected
+	_, err = tx.Exec("UPDATE casino_data SET "+column+" = "+column+" + ? WHERE guild_id = ? AND user_id = ?", amount, guildID, userID)
+	return err
+}
+
+// CreateShopItem は、ショップにアイテムを追加し、採番したIDを item に設定します。
+func (s *DBStore) CreateShopItem(item *ShopItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item.CreatedAt = time.Now()
+	res, err := s.db.Exec(`INSERT INTO shop_items (guild_id, name, description, price, currency, stock, role_id, consumable, effect, effect_value, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		item.GuildID, item.Name, item.Description, item.Price, item.Currency, item.Stock, item.RoleID, item.Consumable, item.Effect, item.EffectValue, item.CreatedAt)
+	if err != nil {
+		return err
+	}
+	item.ID, err = res.LastInsertId()
+	return err
+}
+
+// DeleteShopItem は、ショップからアイテムを取り下げます。所持済みのアイテムはインベントリから削除されます。
+func (s *DBStore) DeleteShopItem(guildID string, itemID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.Exec("DELETE FROM shop_items WHERE guild_id = ? AND id = ?", guildID, itemID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrItemNotFound
+	}
+	if _, err := tx.Exec("DELETE FROM inventories WHERE guild_id = ? AND item_id = ?", guildID, itemID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetShopItems は、サーバーのショップに並んでいるアイテムを価格順に返します。
+func (s *DBStore) GetShopItems(guildID string) ([]ShopItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query("SELECT "+shopItemColumns+" FROM shop_items WHERE guild_id = ? ORDER BY currency, price, id", guildID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []ShopItem
+	for rows.Next() {
+		item, err := scanShopItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *item)
+	}
+	return items, rows.Err()
+}
+
+// GetShopItemByName は、名前（大文字小文字を区別しない）でアイテムを検索します。
+func (s *DBStore) GetShopItemByName(guildID, name string) (*ShopItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	item, err := scanShopItem(s.db.QueryRow("SELECT "+shopItemColumns+" FROM shop_items WHERE guild_id = ? AND name = ? COLLATE NOCASE", guildID, name))
+	if err == sql.ErrNoRows {
+		return nil, ErrItemNotFound
+	}
+	return item, err
+}
+
+// PurchaseShopItem は、代金の引き落とし・在庫の減少・インベントリへの追加を1つのトランザクションで行います。
+// 残高や在庫が足りなければ何も変更せずに ErrInsufficientFunds / ErrOutOfStock を返します。
+// 代金が int64 に収まらない場合は ErrPurchaseTooLarge を返します。
+func (s *DBStore) PurchaseShopItem(guildID, userID string, itemID, quantity int64) (*ShopItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	item, err := scanShopItem(tx.QueryRow("SELECT "+shopItemColumns+" FROM shop_items WHERE guild_id = ? AND id = ?", guildID, itemID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrItemNotFound
+		}
+		return nil, err
+	}
+	if quantity <= 0 || (item.Price > 0 && quantity > math.MaxInt64/item.Price) {
+		return nil, ErrPurchaseTooLarge
+	}
+
+	if item.Stock != UnlimitedStock {
+		res, err := tx.Exec("UPDATE shop_items SET stock = stock - ? WHERE id = ? AND stock >= ?", quantity, item.ID, quantity)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil, ErrOutOfStock
+		}
+		item.Stock -= quantity
+	}
+
+	if err := debitBalance(tx, guildID, userID, item.Currency, item.Price*quantity); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO inventories (guild_id, user_id, item_id, quantity) VALUES (?, ?, ?, ?)
+		ON CONFLICT(guild_id, user_id, item_id) DO UPDATE SET quantity = quantity + excluded.quantity`,
+		guildID, userID, item.ID, quantity); err != nil {
+		return nil, err
+	}
+
+	return item, tx.Commit()
+}
+
+// GetInventory は、ユーザーの所持アイテムを返します。
+func (s *DBStore) GetInventory(guildID, userID string) ([]InventoryItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`
+		SELECT i.id, i.guild_id, i.name, i.description, i.price, i.currency, i.stock, i.role_id, i.consumable, i.effect, i.effect_value, i.created_at, inv.quantity
+		FROM inventories inv JOIN shop_items i ON i.id = inv.item_id
+		WHERE inv.guild_id = ? AND inv.user_id = ? AND inv.quantity > 0
+		ORDER BY i.name`, guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var inventory []InventoryItem
+	for rows.Next() {
+		var entry InventoryItem
+		item := &entry.Item
+		if err := rows.Scan(&item.ID, &item.GuildID, &item.Name, &item.Description, &item.Price, &item.Currency, &item.Stock,
+			&item.RoleID, &item.Consumable, &item.Effect, &item.EffectValue, &item.CreatedAt, &entry.Quantity); err != nil {
+			return nil, err
+		}
+		inventory = append(inventory, entry)
+	}
+	return inventory, rows.Err()
+}
+
+// removeInventoryItem は、所持数が足りる場合に限りアイテムを quantity 個減らします。
+func removeInventoryItem(tx *sql.Tx, guildID, userID string, itemID, quantity int64) error {
+	res, err := tx.Exec("UPDATE inventories SET quantity = quantity - ? WHERE guild_id = ? AND user_id = ? AND item_id = ? AND quantity >= ?",
+		quantity, guildID, userID, itemID, quantity)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrItemNotOwned
+	}
+	_, err = tx.Exec("DELETE FROM inventories WHERE guild_id = ? AND user_id = ? AND item_id = ? AND quantity <= 0", guildID, userID, itemID)
+	return err
+}
+
+// UseInventoryItem は、消費アイテムを1つ使用し、その効果（チップやPPCの付与）を同じトランザクションで反映します。
+func (s *DBStore) UseInventoryItem(guildID, userID string, itemID int64) (*ShopItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	item, err := scanShopItem(tx.QueryRow("SELECT "+shopItemColumns+" FROM shop_items WHERE guild_id = ? AND id = ?", guildID, itemID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrItemNotFound
+		}
+		return nil, err
+	}
+	if !item.Consumable {
+		return nil, errors.New("item is not consumable")
+	}
+
+	if err := removeInventoryItem(tx, guildID, userID, item.ID, 1); err != nil {
+		return nil, err
+	}
+
+	switch item.Effect {
+	case ItemEffectChips:
+		err = creditBalance(tx, guildID, userID, CurrencyChips, item.EffectValue)
+	case ItemEffectPPC:
+		err = creditBalance(tx, guildID, userID, CurrencyPPC, item.EffectValue)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return item, tx.Commit()
+}
+
+// TransferInventoryItem は、アイテムを別のユーザーに quantity 個譲渡します。
+func (s *DBStore) TransferInventoryItem(guildID, fromUserID, toUserID string, itemID, quantity int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := removeInventoryItem(tx, guildID, fromUserID, itemID, quantity); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO inventories (guild_id, user_id, item_id, quantity) VALUES (?, ?, ?, ?)
+		ON CONFLICT(guild_id, user_id, item_id) DO UPDATE SET quantity = quantity + excluded.quantity`,
+		guildID, toUserID, itemID, quantity); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+)
+
+// 代金が桁あふれする個数の購入は、在庫も残高も変えずに拒否される
+func TestPurchaseShopItemRejectsOverflowingPrice(t *testing.T) {
+	store := newTestStore(t)
+	start := chips(t, store, "alice")
+	item := &ShopItem{GuildID: "guild", Name: "badge", Price: 2048, Currency: CurrencyChips, Stock: UnlimitedStock}
+	if err := store.CreateShopItem(item); err != nil {
+		t.Fatalf("CreateShopItem: %v", err)
+	}
+
+	for _, quantity := range []int64{1 << 53, 1 << 52, 0, -1} {
+		if _, err := store.PurchaseShopItem("guild", "alice", item.ID, quantity); !errors.Is(err, ErrPurchaseTooLarge) {
+			t.Errorf("PurchaseShopItem(%d) err = %v, want ErrPurchaseTooLarge", quantity, err)
+		}
+	}
+	if inventory, _ := store.GetInventory("guild", "alice"); len(inventory) != 0 {
+		t.Errorf("inventory = %+v, want empty", inventory)
+	}
+	if got := chips(t, store, "alice"); got != start {
+		t.Errorf("chips = %d, want %d", got, start)
+	}
+
+	// 上限内の個数は通常どおり残高で判定される
+	if _, err := store.PurchaseShopItem("guild", "alice", item.ID, MaxPurchaseQuantity); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("PurchaseShopItem(%d) err = %v, want ErrInsufficientFunds", MaxPurchaseQuantity, err)
+	}
+}
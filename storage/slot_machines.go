@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// SlotMachine は、サーバーごとに定義できるスロットマシンです。
+// リールと配当表は definition 列にJSONで保存します。
+type SlotMachine struct {
+	GuildID string `json:"-"`
+	Name    string `json:"-"`
+	// Reels は、各リールに並ぶ絵柄です。同じ絵柄を多く並べるほど出やすくなります。
+	Reels [][]string `json:"reels"`
+	// ThreeOfAKind と TwoOfAKind は、絵柄ごとの配当倍率です。
+	ThreeOfAKind map[string]float64 `json:"three_of_a_kind"`
+	TwoOfAKind   map[string]float64 `json:"two_of_a_kind"`
+	// JackpotSymbol が3つ揃うと、配当表の代わりにジャックポットを獲得します。
+	JackpotSymbol string `json:"jackpot_symbol"`
+	// JackpotContribution は、賭け金のうちジャックポットに積み立てる割合です。0 の場合は経済設定の値を使います。
+	JackpotContribution float64   `json:"jackpot_contribution"`
+	UpdatedBy           string    `json:"-"`
+	UpdatedAt           time.Time `json:"-"`
+}
+
+// VideoSlotsConfig は、サーバーのビデオスロットの設定です。
+type VideoSlotsConfig struct {
+	Theme string `json:"theme"` // 空の場合は既定のテーマ
+}
+
+func scanSlotMachine(row rowScanner) (*SlotMachine, error) {
+	m := &SlotMachine{}
+	var definition string
+	if err := row.Scan(&m.GuildID, &m.Name, &definition, &m.UpdatedBy, &m.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(definition), m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// GetSlotMachine は、サーバーのスロットマシンを返します。定義されていなければ nil を返します。
+func (s *DBStore) GetSlotMachine(guildID, name string) (*SlotMachine, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m, err := scanSlotMachine(s.db.QueryRow("SELECT guild_id, name, definition, updated_by, updated_at FROM slot_machines WHERE guild_id = ? AND name = ?", guildID, name))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return m, err
+}
+
+// GetSlotMachines は、サーバーのスロットマシンを名前順に返します。
+func (s *DBStore) GetSlotMachines(guildID string) ([]SlotMachine, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query("SELECT guild_id, name, definition, updated_by, updated_at FROM slot_machines WHERE guild_id = ? ORDER BY name", guildID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var machines []SlotMachine
+	for rows.Next() {
+		m, err := scanSlotMachine(rows)
+		if err != nil {
+			return nil, err
+		}
+		machines = append(machines, *m)
+	}
+	return machines, rows.Err()
+}
+
+// SaveSlotMachine は、スロットマシンを作成し、同じ名前のものがあれば置き換えます。
+func (s *DBStore) SaveSlotMachine(m *SlotMachine) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	definition, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO slot_machines (guild_id, name, definition, updated_by, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(guild_id, name) DO UPDATE SET definition = excluded.definition, updated_by = excluded.updated_by, updated_at = excluded.updated_at`,
+		m.GuildID, m.Name, string(definition), m.UpdatedBy, time.Now().UTC())
+	return err
+}
+
+// DeleteSlotMachine は、スロットマシンを削除します。削除した場合は true を返します。
+func (s *DBStore) DeleteSlotMachine(guildID, name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.db.Exec("DELETE FROM slot_machines WHERE guild_id = ? AND name = ?", guildID, name)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,324 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrInsufficientFunds は、残高不足で引き落としができなかったことを表します。
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// ErrInsufficientShares は、保有株数が足りず注文を出せなかったことを表します。
+var ErrInsufficientShares = errors.New("insufficient shares")
+
+// ErrOrderTooLarge は、注文の代金が大きすぎて計算できないことを表します。
+var ErrOrderTooLarge = errors.New("order too large")
+
+// ErrOrderNotFound は、取り消し対象の注文が見つからないか既に終了していることを表します。
+var ErrOrderNotFound = errors.New("order not found")
+
+// OrderSide は注文の売買区分です。
+type OrderSide string
+
+const (
+	OrderSideBid OrderSide = "bid" // 買い注文
+	OrderSideAsk OrderSide = "ask" // 売り注文
+)
+
+// 注文の状態
+const (
+	OrderStatusOpen      = "open"
+	OrderStatusFilled    = "filled"
+	OrderStatusCancelled = "cancelled"
+)
+
+// StockOrder は、プレイヤー間取引の指値注文です。
+// 買い注文は価格×数量のPPCを、売り注文は株をそれぞれ注文時に預かります。
+type StockOrder struct {
+	ID          int64
+	CompanyCode string
+	UserID      string
+	GuildID     string // 代金の受け渡しを行うサーバー
+	Side        OrderSide
+	Price       int64 // 1株あたりの指値 (PPC)
+	Quantity    int64
+	Remaining   int64
+	Status      string
+	CreatedAt   time.Time
+}
+
+// StockTrade は、プレイヤー間で成立した約定です。
+type StockTrade struct {
+	ID            int64
+	CompanyCode   string
+	BuyOrderID    int64
+	SellOrderID   int64
+	BuyerID       string
+	BuyerGuildID  string
+	SellerID      string
+	SellerGuildID string
+	BidPrice      int64 // 買い注文の指値（差額返金の計算に使用）
+	Price         int64 // 約定価格
+	Quantity      int64
+	ExecutedAt    time.Time
+}
+
+const stockOrderColumns = "id, company_code, user_id, guild_id, side, price, quantity, remaining, status, created_at"
+
+func scanStockOrder(row rowScanner) (*StockOrder, error) {
+	var o StockOrder
+	if err := row.Scan(&o.ID, &o.CompanyCode, &o.UserID, &o.GuildID, &o.Side, &o.Price, &o.Quantity, &o.Remaining, &o.Status, &o.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// 1件の注文で指定できる指値と株数の上限
+const (
+	MaxOrderPrice    = 1_000_000_000
+	MaxOrderQuantity = 1_000_000_000
+)
+
+// orderCost は、price × quantity を返します。int64 に収まらない場合は ErrOrderTooLarge を返します。
+func orderCost(price, quantity int64) (int64, error) {
+	if price < 0 || quantity < 0 || (quantity > 0 && price > math.MaxInt64/quantity) {
+		return 0, ErrOrderTooLarge
+	}
+	return price * quantity, nil
+}
+
+// CreateStockOrder は、代金または株を預かったうえで注文を登録し、採番したIDを order に設定します。
+func (s *DBStore) CreateStockOrder(order *StockOrder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	cost, err := orderCost(order.Price, order.Quantity)
+	if err != nil {
+		return err
+	}
+	if err := checkNotFrozen(tx, order.GuildID, order.UserID); err != nil {
+		return err
+	}
+	switch order.Side {
+	case OrderSideBid:
+		if err := ensureCasinoAccount(tx, order.GuildID, order.UserID); err != nil {
+			return err
+		}
+		res, err := tx.Exec("UPDATE casino_data SET pepecoin_balance = pepecoin_balance - ? WHERE guild_id = ? AND user_id = ? AND pepecoin_balance >= ?",
+			cost, order.GuildID, order.UserID, cost)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrInsufficientFunds
+		}
+	case OrderSideAsk:
+		res, err := tx.Exec("UPDATE stocks_portfolios SET shares = shares - ? WHERE user_id = ? AND company_code = ? AND shares >= ?",
+			order.Quantity, order.UserID, order.CompanyCode, order.Quantity)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrInsufficientShares
+		}
+	default:
+		return errors.New("unknown order side")
+	}
+
+	order.Remaining = order.Quantity
+	order.Status = OrderStatusOpen
+	order.CreatedAt = time.Now()
+	res, err := tx.Exec("INSERT INTO stock_orders (company_code, user_id, guild_id, side, price, quantity, remaining, status, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		order.CompanyCode, order.UserID, order.GuildID, order.Side, order.Price, order.Quantity, order.Remaining, order.Status, order.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if order.ID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CancelStockOrder は、ユーザー自身の未約定の注文を取り消し、預かっていた残りを返却します。
+func (s *DBStore) CancelStockOrder(orderID int64, userID string) (*StockOrder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	order, err := scanStockOrder(tx.QueryRow("SELECT "+stockOrderColumns+" FROM stock_orders WHERE id = ? AND user_id = ? AND status = ?", orderID, userID, OrderStatusOpen))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+
+	if err := refundStockOrder(tx, order); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("UPDATE stock_orders SET status = ? WHERE id = ?", OrderStatusCancelled, order.ID); err != nil {
+		return nil, err
+	}
+	order.Status = OrderStatusCancelled
+
+	return order, tx.Commit()
+}
+
+// refundStockOrder は、注文の未約定分として預かっていたPPCまたは株を持ち主に戻します。
+func refundStockOrder(tx *sql.Tx, order *StockOrder) error {
+	if order.Remaining <= 0 {
+		return nil
+	}
+	if order.Side == OrderSideBid {
+		return creditBalance(tx, order.GuildID, order.UserID, CurrencyPPC, order.Price*order.Remaining)
+	}
+	_, err := tx.Exec(`
+		INSERT INTO stocks_portfolios (user_id, company_code, shares, guild_id) VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, company_code) DO UPDATE SET shares = shares + excluded.shares`,
+		order.UserID, order.CompanyCode, order.Remaining, order.GuildID)
+	return err
+}
+
+// SettleStockTrades は、マッチングエンジンが出した約定を1つのトランザクションで反映します。
+// 買い手には株と指値との差額を、売り手には代金を渡し、両方の注文の残数量を減らします。
+func (s *DBStore) SettleStockTrades(trades []StockTrade) error {
+	if len(trades) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now()
+	for i := range trades {
+		t := &trades[i]
+		t.ExecutedAt = now
+
+		// 買い手: 株を受け取り、指値より安く買えた分の代金を返金
+		if _, err := tx.Exec(`
+			INSERT INTO stocks_portfolios (user_id, company_code, shares, guild_id) VALUES (?, ?, ?, ?)
+			ON CONFLICT(user_id, company_code) DO UPDATE SET shares = shares + excluded.shares, guild_id = excluded.guild_id`,
+			t.BuyerID, t.CompanyCode, t.Quantity, t.BuyerGuildID); err != nil {
+			return err
+		}
+		if t.BidPrice > t.Price {
+			improvement, err := orderCost(t.BidPrice-t.Price, t.Quantity)
+			if err != nil {
+				return err
+			}
+			if err := creditBalance(tx, t.BuyerGuildID, t.BuyerID, CurrencyPPC, improvement); err != nil {
+				return err
+			}
+		}
+
+		// 売り手: 代金を受け取る
+		proceeds, err := orderCost(t.Price, t.Quantity)
+		if err != nil {
+			return err
+		}
+		if err := creditBalance(tx, t.SellerGuildID, t.SellerID, CurrencyPPC, proceeds); err != nil {
+			return err
+		}
+
+		for _, orderID := range []int64{t.BuyOrderID, t.SellOrderID} {
+			if _, err := tx.Exec(`
+				UPDATE stock_orders
+				SET remaining = remaining - ?, status = CASE WHEN remaining - ? <= 0 THEN ? ELSE status END
+				WHERE id = ?`, t.Quantity, t.Quantity, OrderStatusFilled, orderID); err != nil {
+				return err
+			}
+		}
+
+		res, err := tx.Exec(`
+			INSERT INTO stock_trades (company_code, buy_order_id, sell_order_id, buyer_id, seller_id, price, quantity, executed_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			t.CompanyCode, t.BuyOrderID, t.SellOrderID, t.BuyerID, t.SellerID, t.Price, t.Quantity, t.ExecutedAt)
+		if err != nil {
+			return err
+		}
+		if t.ID, err = res.LastInsertId(); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE companies SET last_trade_price = ? WHERE code = ?", t.Price, t.CompanyCode); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetOpenStockOrders returns every open order, oldest first, to rebuild the order books.
+func (s *DBStore) GetOpenStockOrders() ([]StockOrder, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.queryStockOrders("SELECT "+stockOrderColumns+" FROM stock_orders WHERE status = ? ORDER BY id", OrderStatusOpen)
+}
+
+// GetUserOpenStockOrders returns a user's open orders.
+func (s *DBStore) GetUserOpenStockOrders(userID string) ([]StockOrder, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.queryStockOrders("SELECT "+stockOrderColumns+" FROM stock_orders WHERE user_id = ? AND status = ? ORDER BY id", userID, OrderStatusOpen)
+}
+
+func (s *DBStore) queryStockOrders(query string, args ...any) ([]StockOrder, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []StockOrder
+	for rows.Next() {
+		o, err := scanStockOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *o)
+	}
+	return orders, nil
+}
+
+// GetRecentStockTrades returns the latest trades of a company.
+func (s *DBStore) GetRecentStockTrades(companyCode string, limit int) ([]StockTrade, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`
+		SELECT id, company_code, buy_order_id, sell_order_id, buyer_id, seller_id, price, quantity, executed_at
+		FROM stock_trades WHERE company_code = ? ORDER BY id DESC LIMIT ?`, companyCode, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var trades []StockTrade
+	for rows.Next() {
+		var t StockTrade
+		if err := rows.Scan(&t.ID, &t.CompanyCode, &t.BuyOrderID, &t.SellOrderID, &t.BuyerID, &t.SellerID, &t.Price, &t.Quantity, &t.ExecutedAt); err != nil {
+			return nil, err
+		}
+		trades = append(trades, t)
+	}
+	return trades, nil
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+)
+
+// 代金が int64 に収まらない注文は、何も預からずに拒否される
+func TestCreateStockOrderRejectsOverflowingCost(t *testing.T) {
+	store := newTestStore(t)
+	chips(t, store, "alice")
+
+	tests := []struct {
+		name            string
+		price, quantity int64
+	}{
+		{"wraps to zero", 1 << 32, 1 << 32},
+		{"wraps negative", 1 << 40, 1 << 30},
+		{"just over the limit", 1 << 62, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := &StockOrder{CompanyCode: "LUNA", UserID: "alice", GuildID: "guild", Side: OrderSideBid, Price: tt.price, Quantity: tt.quantity}
+			if err := store.CreateStockOrder(order); !errors.Is(err, ErrOrderTooLarge) {
+				t.Errorf("CreateStockOrder(%d × %d) err = %v, want ErrOrderTooLarge", tt.price, tt.quantity, err)
+			}
+		})
+	}
+	if orders, _ := store.GetUserOpenStockOrders("alice"); len(orders) != 0 {
+		t.Errorf("open orders = %+v, want none", orders)
+	}
+}
+
+// 約定の受け渡しでも、桁あふれする金額は入金せずに失敗する
+func TestSettleStockTradesRejectsOverflowingAmounts(t *testing.T) {
+	store := newTestStore(t)
+	trade := StockTrade{
+		CompanyCode: "LUNA", BuyerID: "alice", BuyerGuildID: "guild", SellerID: "bob", SellerGuildID: "guild",
+		BidPrice: 1 << 32, Price: 1, Quantity: 1 << 32,
+	}
+	if err := store.SettleStockTrades([]StockTrade{trade}); !errors.Is(err, ErrOrderTooLarge) {
+		t.Errorf("SettleStockTrades err = %v, want ErrOrderTooLarge", err)
+	}
+}
@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrShortInOtherGuild は、同じ銘柄の空売りポジションを別のサーバーで建てていることを表します。
+var ErrShortInOtherGuild = errors.New("short position is held in another guild")
+
+// ShortPosition は、ユーザーの空売りポジションです。
+// 売却代金と追加の証拠金は Collateral としてポジションに預けられ、買い戻し時に精算されます。
+type ShortPosition struct {
+	UserID      string
+	GuildID     string // 証拠金を預けたサーバー
+	CompanyCode string
+	Shares      int64
+	EntryPrice  float64 // 平均売建価格
+	Collateral  int64
+	FeesPaid    int64
+	MarginCall  bool // 追証の通知済みかどうか
+	OpenedAt    time.Time
+}
+
+const shortPositionColumns = "user_id, guild_id, company_code, shares, entry_price, collateral, fees_paid, margin_call, opened_at"
+
+func scanShortPosition(row rowScanner) (*ShortPosition, error) {
+	var p ShortPosition
+	if err := row.Scan(&p.UserID, &p.GuildID, &p.CompanyCode, &p.Shares, &p.EntryPrice, &p.Collateral, &p.FeesPaid, &p.MarginCall, &p.OpenedAt); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// OpenShortPosition は、price で shares 株を空売りします。
+// 売却代金に加えて代金×initialMargin のPPCを証拠金として預かり、ポジションに積み増します。
+// 証拠金は建てたサーバーの残高で精算するため、別のサーバーで建てている銘柄には ErrShortInOtherGuild を返します。
+func (s *DBStore) OpenShortPosition(guildID, userID, companyCode string, shares int64, price, initialMargin float64) (*ShortPosition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	proceeds := int64(price * float64(shares))
+	margin := int64(math.Ceil(float64(proceeds) * initialMargin))
+
+	if err := ensureCasinoAccount(tx, guildID, userID); err != nil {
+		return nil, err
+	}
+	if err := checkNotFrozen(tx, guildID, userID); err != nil {
+		return nil, err
+	}
+	var heldIn string
+	err = tx.QueryRow("SELECT guild_id FROM stock_shorts WHERE user_id = ? AND company_code = ?", userID, companyCode).Scan(&heldIn)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if err == nil && heldIn != guildID {
+		return nil, ErrShortInOtherGuild
+	}
+	res, err := tx.Exec("UPDATE casino_data SET pepecoin_balance = pepecoin_balance - ? WHERE guild_id = ? AND user_id = ? AND pepecoin_balance >= ?",
+		margin, guildID, userID, margin)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrInsufficientFunds
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO stock_shorts (user_id, guild_id, company_code, shares, entry_price, collateral, opened_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, company_code) DO UPDATE SET
+			entry_price = (entry_price * shares + excluded.entry_price * excluded.shares) / (shares + excluded.shares),
+			shares = shares + excluded.shares,
+			collateral = collateral + excluded.collateral`,
+		userID, guildID, companyCode, shares, price, proceeds+margin, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	position, err := scanShortPosition(tx.QueryRow("SELECT "+shortPositionColumns+" FROM stock_shorts WHERE user_id = ? AND company_code = ?", userID, companyCode))
+	if err != nil {
+		return nil, err
+	}
+	return position, tx.Commit()
+}
+
+// CoverShortPosition は、price で shares 株を買い戻し、対応する証拠金を精算します。
+// 戻り値はユーザーの残高に加算（負なら減算）されたPPCです。
+// forced が false の場合、精算額の不足を残高で賄えなければ ErrInsufficientFunds を返します。
+// forced が true（強制決済）の場合、不足分は残高の範囲で回収し、残りは切り捨てます。
+// 口座が凍結されている場合、強制決済以外は ErrAccountFrozen を返します。
+func (s *DBStore) CoverShortPosition(userID, companyCode string, shares int64, price float64, forced bool) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	position, err := scanShortPosition(tx.QueryRow("SELECT "+shortPositionColumns+" FROM stock_shorts WHERE user_id = ? AND company_code = ?", userID, companyCode))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrInsufficientShares
+		}
+		return 0, err
+	}
+	if shares > position.Shares {
+		return 0, ErrInsufficientShares
+	}
+
+	released := position.Collateral * shares / position.Shares
+	cost := int64(math.Ceil(price * float64(shares)))
+	settlement := released - cost
+
+	if err := ensureCasinoAccount(tx, position.GuildID, userID); err != nil {
+		return 0, err
+	}
+	if !forced {
+		if err := checkNotFrozen(tx, position.GuildID, userID); err != nil {
+			return 0, err
+		}
+	}
+	if settlement < 0 {
+		var balance int64
+		if err := tx.QueryRow("SELECT pepecoin_balance FROM casino_data WHERE guild_id = ? AND user_id = ?", position.GuildID, userID).Scan(&balance); err != nil {
+			return 0, err
+		}
+		if balance < -settlement {
+			if !forced {
+				return 0, ErrInsufficientFunds
+			}
+			settlement = -max(balance, 0)
+		}
+	}
+	if settlement > 0 {
+		err = creditBalance(tx, position.GuildID, userID, CurrencyPPC, settlement)
+	} else {
+		_, err = tx.Exec("UPDATE casino_data SET pepecoin_balance = pepecoin_balance + ? WHERE guild_id = ? AND user_id = ?", settlement, position.GuildID, userID)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if shares == position.Shares {
+		_, err = tx.Exec("DELETE FROM stock_shorts WHERE user_id = ? AND company_code = ?", userID, companyCode)
+	} else {
+		_, err = tx.Exec("UPDATE stock_shorts SET shares = shares - ?, collateral = collateral - ? WHERE user_id = ? AND company_code = ?", shares, released, userID, companyCode)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return settlement, tx.Commit()
+}
+
+// AddShortCollateral は、残高から amount PPC を空売りポジションの証拠金に追加します。
+func (s *DBStore) AddShortCollateral(userID, companyCode string, amount int64) (*ShortPosition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	position, err := scanShortPosition(tx.QueryRow("SELECT "+shortPositionColumns+" FROM stock_shorts WHERE user_id = ? AND company_code = ?", userID, companyCode))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInsufficientShares
+		}
+		return nil, err
+	}
+
+	if err := checkNotFrozen(tx, position.GuildID, userID); err != nil {
+		return nil, err
+	}
+	res, err := tx.Exec("UPDATE casino_data SET pepecoin_balance = pepecoin_balance - ? WHERE guild_id = ? AND user_id = ? AND pepecoin_balance >= ?",
+		amount, position.GuildID, userID, amount)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrInsufficientFunds
+	}
+	if _, err := tx.Exec("UPDATE stock_shorts SET collateral = collateral + ? WHERE user_id = ? AND company_code = ?", amount, userID, companyCode); err != nil {
+		return nil, err
+	}
+	position.Collateral += amount
+
+	return position, tx.Commit()
+}
+
+// ChargeShortBorrowFees は、全空売りポジションから時価×rate の貸株料を証拠金から差し引きます。
+func (s *DBStore) ChargeShortBorrowFees(prices map[string]float64, rate float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// 1株でも建てていれば最低1PPCは徴収する
+	stmt, err := tx.Prepare(`
+		UPDATE stock_shorts
+		SET collateral = collateral - MAX(1, CAST(shares * ? * ? AS INTEGER)),
+			fees_paid = fees_paid + MAX(1, CAST(shares * ? * ? AS INTEGER))
+		WHERE company_code = ? AND shares > 0`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for code, price := range prices {
+		if _, err := stmt.Exec(price, rate, price, rate, code); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SetShortMarginCall records whether a margin call has been sent for a position.
+func (s *DBStore) SetShortMarginCall(userID, companyCode string, called bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.db.Exec("UPDATE stock_shorts SET margin_call = ? WHERE user_id = ? AND company_code = ?", called, userID, companyCode)
+	return err
+}
+
+// GetUserShortPositions returns all short positions of a user.
+func (s *DBStore) GetUserShortPositions(userID string) ([]ShortPosition, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.queryShortPositions("SELECT "+shortPositionColumns+" FROM stock_shorts WHERE user_id = ?", userID)
+}
+
+// GetAllShortPositions returns every open short position.
+func (s *DBStore) GetAllShortPositions() ([]ShortPosition, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.queryShortPositions("SELECT " + shortPositionColumns + " FROM stock_shorts")
+}
+
+func (s *DBStore) queryShortPositions(query string, args ...any) ([]ShortPosition, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var positions []ShortPosition
+	for rows.Next() {
+		p, err := scanShortPosition(rows)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, *p)
+	}
+	return positions, nil
+}
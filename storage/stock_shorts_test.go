@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+)
+
+// givePPC は、テスト用に userID の guildID での PPC 残高を amount にします。
+func givePPC(t *testing.T, store *DBStore, guildID, userID string, amount int64) {
+	t.Helper()
+	if err := store.AdjustEconomy(&EconomyAuditEntry{GuildID: guildID, Action: EcoActionSet, Currency: CurrencyPPC, Amount: amount}, []string{userID}); err != nil {
+		t.Fatalf("AdjustEconomy: %v", err)
+	}
+}
+
+func shortPosition(t *testing.T, store *DBStore, userID, companyCode string) ShortPosition {
+	t.Helper()
+	positions, err := store.GetUserShortPositions(userID)
+	if err != nil {
+		t.Fatalf("GetUserShortPositions: %v", err)
+	}
+	for _, p := range positions {
+		if p.CompanyCode == companyCode {
+			return p
+		}
+	}
+	t.Fatalf("no short position for %s %s", userID, companyCode)
+	return ShortPosition{}
+}
+
+// 証拠金は建てたサーバーで精算するため、同じ銘柄を別のサーバーから積み増すことはできない
+func TestOpenShortPositionRejectsOtherGuild(t *testing.T) {
+	store := newTestStore(t)
+	givePPC(t, store, "first", "alice", 10000)
+	givePPC(t, store, "second", "alice", 10000)
+
+	if _, err := store.OpenShortPosition("first", "alice", "LUNA", 10, 100, 0.5); err != nil {
+		t.Fatalf("OpenShortPosition: %v", err)
+	}
+	if _, err := store.OpenShortPosition("second", "alice", "LUNA", 10, 100, 0.5); !errors.Is(err, ErrShortInOtherGuild) {
+		t.Fatalf("OpenShortPosition from another guild: err = %v, want ErrShortInOtherGuild", err)
+	}
+	position, err := store.OpenShortPosition("first", "alice", "LUNA", 10, 120, 0.5)
+	if err != nil {
+		t.Fatalf("OpenShortPosition in the same guild: %v", err)
+	}
+	if position.GuildID != "first" || position.Shares != 20 || position.EntryPrice != 110 || position.Collateral != 1500+1800 {
+		t.Errorf("position = %+v, want 20 shares at 110 with 3300 collateral in first", *position)
+	}
+}
+
+func TestChargeShortBorrowFees(t *testing.T) {
+	tests := []struct {
+		name   string
+		shares int64
+		price  float64
+		rate   float64
+		fee    int64
+	}{
+		{"fee below one is rounded up to one", 10, 100, 0.0002, 1},
+		{"fractional fee is truncated", 1000, 256, 0.0002, 51},
+		{"high rate", 10, 100, 0.01, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			givePPC(t, store, "guild", "alice", 1_000_000)
+			opened, err := store.OpenShortPosition("guild", "alice", "LUNA", tt.shares, tt.price, 0.5)
+			if err != nil {
+				t.Fatalf("OpenShortPosition: %v", err)
+			}
+
+			// 2回徴収すると、手数料も2回分積み上がる
+			for range 2 {
+				if err := store.ChargeShortBorrowFees(map[string]float64{"LUNA": tt.price, "OTHER": 1}, tt.rate); err != nil {
+					t.Fatalf("ChargeShortBorrowFees: %v", err)
+				}
+			}
+			position := shortPosition(t, store, "alice", "LUNA")
+			if got, want := position.Collateral, opened.Collateral-2*tt.fee; got != want {
+				t.Errorf("collateral = %d, want %d", got, want)
+			}
+			if got, want := position.FeesPaid, 2*tt.fee; got != want {
+				t.Errorf("fees paid = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestCoverShortPositionSettlement(t *testing.T) {
+	// 100 PPC で10株を売り建て、証拠金は 1500 PPC。建てた後の残高は balance
+	tests := []struct {
+		name    string
+		balance int64
+		price   float64
+		forced  bool
+		want    int64
+		wantErr error
+	}{
+		{"profit is credited", 0, 80, false, 1500 - 800, nil},
+		{"small loss is taken from the collateral", 1000, 130, false, 1500 - 1300, nil},
+		{"loss beyond collateral is paid from the balance", 1000, 180, false, 1500 - 1800, nil},
+		{"loss beyond the balance is refused", 100, 180, false, 0, ErrInsufficientFunds},
+		{"forced cover collects only what the balance holds", 100, 180, true, -100, nil},
+		{"forced cover with an empty balance", 0, 180, true, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			givePPC(t, store, "guild", "alice", 500+tt.balance)
+			if _, err := store.OpenShortPosition("guild", "alice", "LUNA", 10, 100, 0.5); err != nil {
+				t.Fatalf("OpenShortPosition: %v", err)
+			}
+
+			settlement, err := store.CoverShortPosition("alice", "LUNA", 10, tt.price, tt.forced)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CoverShortPosition err = %v, want %v", err, tt.wantErr)
+			}
+			if settlement != tt.want {
+				t.Errorf("settlement = %d, want %d", settlement, tt.want)
+			}
+			data, _ := store.GetCasinoData("guild", "alice")
+			if got, want := data.PepeCoinBalance, tt.balance+settlement; got != want {
+				t.Errorf("balance = %d, want %d", got, want)
+			}
+		})
+	}
+}